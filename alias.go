@@ -0,0 +1,198 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SetAlias defines an alias, replacing any existing one of the same name
+func (s *ShellState) SetAlias(name, value string) error {
+	if !isAliasName(name) {
+		return fmt.Errorf("%s: invalid alias name", name)
+	}
+	if s.aliases == nil {
+		s.aliases = make(map[string]string)
+	}
+	s.aliases[name] = value
+	return nil
+}
+
+// Alias returns the value of an alias
+func (s *ShellState) Alias(name string) (string, bool) {
+	value, ok := s.aliases[name]
+	return value, ok
+}
+
+// RemoveAlias removes an alias, reporting whether it existed
+func (s *ShellState) RemoveAlias(name string) bool {
+	_, ok := s.aliases[name]
+	delete(s.aliases, name)
+	return ok
+}
+
+// AliasNames returns the names of all aliases, sorted
+func (s *ShellState) AliasNames() []string {
+	names := make([]string, 0, len(s.aliases))
+	for name := range s.aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isAliasName reports whether name can be used as an alias. Like other
+// shells, names can't hold quotes, expansions or the characters that end a
+// word.
+func isAliasName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, " \t\n=/$`\\'\"<>&|;(){}")
+}
+
+// expandAliases replaces the first word of each command with its alias,
+// if it has one. Quoting any of the word, as in \ls, keeps it from being
+// expanded. An alias isn't expanded again inside its own value, so
+// alias ls='ls -G' works, and one whose value ends with a space makes the
+// word after it a candidate too, as in alias sudo='sudo '.
+func (s *ShellState) expandAliases(words []shellWord) ([]shellWord, error) {
+	if len(s.aliases) == 0 {
+		return words, nil
+	}
+	return s.expandAliasWords(words, nil)
+}
+
+func (s *ShellState) expandAliasWords(words []shellWord, active map[string]bool) ([]shellWord, error) {
+	var expanded []shellWord
+	commandStart := true
+
+	for _, word := range words {
+		if word.subshell != nil {
+			inner, err := s.expandAliasWords(word.subshell, active)
+			if err != nil {
+				return nil, err
+			}
+			word.subshell = inner
+			expanded = append(expanded, word)
+			commandStart = false
+			continue
+		}
+
+		value, ok := s.aliases[word.Text]
+		if !commandStart || word.Operator || word.quoted || !ok || active[word.Text] {
+			expanded = append(expanded, word)
+			commandStart = word.Operator && isListOperator(word.Text)
+			continue
+		}
+
+		aliasWords, err := splitShellWords(value)
+		if err != nil {
+			return nil, fmt.Errorf("alias %s: %w", word.Text, err)
+		}
+		nested := map[string]bool{word.Text: true}
+		for name := range active {
+			nested[name] = true
+		}
+		if aliasWords, err = s.expandAliasWords(aliasWords, nested); err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, aliasWords...)
+
+		last := len(expanded) - 1
+		commandStart = strings.HasSuffix(value, " ") || strings.HasSuffix(value, "\t") ||
+			(last >= 0 && expanded[last].Operator && isListOperator(expanded[last].Text))
+	}
+	return expanded, nil
+}
+
+// alias implements the alias builtin
+func (b *BuiltinHandler) alias(args []string) ExecutionResult {
+	if len(args) == 0 {
+		var lines []string
+		for _, name := range b.state.AliasNames() {
+			value, _ := b.state.Alias(name)
+			lines = append(lines, formatAlias(name, value))
+		}
+		return ExecutionResult{Output: strings.Join(lines, "\n"), ExitCode: 0}
+	}
+
+	var lines, errs []string
+	var lastErr error
+	for _, arg := range args {
+		name, value, isDefinition := strings.Cut(arg, "=")
+		if !isDefinition {
+			if value, ok := b.state.Alias(name); ok {
+				lines = append(lines, formatAlias(name, value))
+			} else {
+				lastErr = fmt.Errorf("%s: not found", name)
+				errs = append(errs, fmt.Sprintf("alias: %v", lastErr))
+			}
+			continue
+		}
+		if err := b.state.SetAlias(name, value); err != nil {
+			lastErr = err
+			errs = append(errs, fmt.Sprintf("alias: %v", err))
+		}
+	}
+
+	output := strings.Join(append(lines, errs...), "\n")
+	if lastErr != nil {
+		return ExecutionResult{Output: output, ExitCode: 1, Error: lastErr}
+	}
+	return ExecutionResult{Output: output, ExitCode: 0}
+}
+
+// unalias implements the unalias builtin
+func (b *BuiltinHandler) unalias(args []string) ExecutionResult {
+	if len(args) == 0 {
+		err := fmt.Errorf("missing alias name")
+		return ExecutionResult{Output: fmt.Sprintf("unalias: %v\nUsage: unalias NAME... | unalias -a", err), ExitCode: 1, Error: err}
+	}
+	if len(args) == 1 && args[0] == "-a" {
+		for _, name := range b.state.AliasNames() {
+			b.state.RemoveAlias(name)
+		}
+		return ExecutionResult{Output: "", ExitCode: 0}
+	}
+
+	var errs []string
+	var lastErr error
+	for _, name := range args {
+		if !b.state.RemoveAlias(name) {
+			lastErr = fmt.Errorf("%s: not found", name)
+			errs = append(errs, fmt.Sprintf("unalias: %v", lastErr))
+		}
+	}
+	if lastErr != nil {
+		return ExecutionResult{Output: strings.Join(errs, "\n"), ExitCode: 1, Error: lastErr}
+	}
+	return ExecutionResult{Output: "", ExitCode: 0}
+}
+
+// formatAlias formats an alias as the command that defines it
+func formatAlias(name, value string) string {
+	return fmt.Sprintf("alias %s='%s'", name, strings.ReplaceAll(value, "'", `'\''`))
+}
+
+const aliasHelpText = "alias, unalias - Command Aliases\n\n" +
+	"USAGE:\n" +
+	"    alias                 List all aliases\n" +
+	"    alias NAME...         Show the named aliases\n" +
+	"    alias NAME=VALUE...   Define aliases\n" +
+	"    unalias NAME...       Remove aliases\n" +
+	"    unalias -a            Remove all aliases\n\n" +
+	"DESCRIPTION:\n" +
+	"    When the first word of a command is an alias, it's replaced by the\n" +
+	"    alias's value before the command runs. The value can hold several\n" +
+	"    words, and even several commands joined by ; or &&.\n\n" +
+	"    An alias isn't expanded inside its own value, so an alias can add\n" +
+	"    options to the command of the same name. Quote the command, as in\n" +
+	"    \\ls, to run it without its alias. When a value ends with a space,\n" +
+	"    the word after the alias is expanded too.\n\n" +
+	"    Aliases last for the session. Define them in config.go with\n" +
+	"    gosh.Alias(NAME, VALUE) to have them in every session.\n\n" +
+	"EXAMPLES:\n" +
+	"    alias gs='git status'\n" +
+	"    alias ls='ls -G'\n" +
+	"    alias sudo='sudo '\n" +
+	"    unalias gs"
@@ -0,0 +1,118 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRouter_ParseCommandListAliases(t *testing.T) {
+	state := &ShellState{Environment: map[string]string{}}
+	router := NewRouter(NewBuiltinHandler(state), state)
+	for name, value := range map[string]string{
+		"gs":   "git status",
+		"ls":   "ls -G",
+		"ll":   "ls -l",
+		"sudo": "sudo ",
+		"up":   "cd ..; ls",
+		"loop": "loop2 x",
+		"quot": "echo 'a  b'",
+	} {
+		if err := state.SetAlias(name, value); err != nil {
+			t.Fatalf("SetAlias(%q) error: %v", name, err)
+		}
+	}
+	state.SetAlias("loop2", "loop y")
+
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"gs -s", []string{"git status -s"}},
+		{"echo gs", []string{"echo gs"}},
+		{"true && gs; gs", []string{"true", "git status", "git status"}},
+		// Not expanded again within its own value, but other aliases are
+		{"ls", []string{"ls -G"}},
+		{"ll /tmp", []string{"ls -G -l /tmp"}},
+		{"loop", []string{"loop y x"}},
+		// A trailing space expands the next word too
+		{"sudo ll", []string{"sudo ls -G -l"}},
+		{"up", []string{"cd ..", "ls -G"}},
+		{"quot", []string{"echo 'a  b'"}},
+		// Quoting keeps the word from being expanded
+		{"\\ls", []string{"ls"}},
+		{"'gs'", []string{"gs"}},
+	}
+
+	for _, tt := range tests {
+		list, err := router.ParseCommandList(tt.input)
+		if err != nil {
+			t.Errorf("ParseCommandList(%q) error: %v", tt.input, err)
+			continue
+		}
+		var got []string
+		for _, cmd := range list {
+			got = append(got, cmd.String())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseCommandList(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	inputType, name, args := router.Route("gs -s")
+	if inputType != InputTypeCommand || name != "git" || !reflect.DeepEqual(args, []string{"status", "-s"}) {
+		t.Errorf("Route(gs -s) = %v %q %q", inputType, name, args)
+	}
+
+	state.SetAlias("bad", "echo 'oops")
+	if _, err := router.ParseCommandList("bad"); err == nil || !strings.Contains(err.Error(), "alias bad") {
+		t.Errorf("Expected the alias's syntax error, got %v", err)
+	}
+}
+
+func TestBuiltinHandler_Alias(t *testing.T) {
+	state := &ShellState{Environment: map[string]string{}}
+	b := NewBuiltinHandler(state)
+
+	if result := b.Execute("alias", []string{"gs=git status", "q=it's"}); result.ExitCode != 0 {
+		t.Fatalf("alias failed: %q", result.Output)
+	}
+	if result := b.Execute("alias", nil); result.Output != "alias gs='git status'\nalias q='it'\\''s'" {
+		t.Errorf("Unexpected alias list %q", result.Output)
+	}
+	if result := b.Execute("alias", []string{"gs"}); result.Output != "alias gs='git status'" {
+		t.Errorf("Unexpected output for alias gs: %q", result.Output)
+	}
+
+	for _, args := range [][]string{{"missing"}, {"bad/name=x"}, {"=x"}} {
+		if result := b.Execute("alias", args); result.ExitCode != 1 || !strings.HasPrefix(result.Output, "alias: ") {
+			t.Errorf("alias %q gave %q (exit %d), want an error", args, result.Output, result.ExitCode)
+		}
+	}
+
+	if result := b.Execute("unalias", []string{"gs"}); result.ExitCode != 0 {
+		t.Errorf("unalias failed: %q", result.Output)
+	}
+	if _, ok := state.Alias("gs"); ok {
+		t.Error("Expected gs to be removed")
+	}
+	if result := b.Execute("unalias", []string{"gs"}); result.ExitCode != 1 {
+		t.Errorf("Expected unalias of a missing alias to fail, got %q", result.Output)
+	}
+	if result := b.Execute("unalias", []string{"-a"}); result.ExitCode != 0 || len(state.AliasNames()) != 0 {
+		t.Errorf("unalias -a left %v", state.AliasNames())
+	}
+}
+
+func TestGoshCompleter_CompletesAliases(t *testing.T) {
+	c := NewGoshCompleterForTesting(NewGoEvaluator())
+	c.state = &ShellState{Environment: map[string]string{}}
+	c.state.SetAlias("zzgoshstatus", "git status")
+
+	matches := c.completeCommands("zzgosh")
+	if len(matches) != 1 || string(matches[0]) != "status" {
+		t.Errorf("Expected the alias to complete, got %q", matches)
+	}
+}
@@ -0,0 +1,399 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Archive formats understood by extractArchive and compressPath
+const (
+	archiveZip   = "zip"
+	archiveTar   = "tar"
+	archiveTarGz = "tar.gz"
+	archiveTarBz = "tar.bz2"
+)
+
+// archiveFormat picks the archive format from a file name
+func archiveFormat(name string) (string, error) {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return archiveZip, nil
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return archiveTarGz, nil
+	case strings.HasSuffix(lower, ".tar.bz2"), strings.HasSuffix(lower, ".tbz2"):
+		return archiveTarBz, nil
+	case strings.HasSuffix(lower, ".tar"):
+		return archiveTar, nil
+	}
+	return "", fmt.Errorf("%s: unsupported archive type (use .zip, .tar, .tar.gz/.tgz or .tar.bz2)", name)
+}
+
+// resolveAPIPath makes a path from Go code relative to the shell's working
+// directory rather than wherever the process happens to be
+func resolveAPIPath(p string) string {
+	if strings.HasPrefix(p, "~/") || p == "~" {
+		p = filepath.Join(os.Getenv("HOME"), strings.TrimPrefix(p, "~"))
+	}
+	if filepath.IsAbs(p) {
+		return p
+	}
+	if state := goshAPIState(); state != nil {
+		return filepath.Join(state.WorkingDirectory, p)
+	}
+	return p
+}
+
+// archiveTarget returns where an archive entry should be written, refusing
+// entries that would land outside dest ("zip slip")
+func archiveTarget(dest, name string) (string, error) {
+	target := filepath.Join(dest, filepath.FromSlash(name))
+	if target != dest && !strings.HasPrefix(target, dest+string(filepath.Separator)) {
+		return "", fmt.Errorf("archive entry %q escapes the destination", name)
+	}
+	return target, nil
+}
+
+// extractArchive unpacks a zip or tar archive into dest, creating it if
+// needed. It returns the number of files written.
+func extractArchive(archive, dest string) (int, error) {
+	archive = resolveAPIPath(archive)
+	dest = filepath.Clean(resolveAPIPath(dest))
+
+	format, err := archiveFormat(archive)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return 0, err
+	}
+
+	if format == archiveZip {
+		return extractZip(archive, dest)
+	}
+
+	f, err := os.Open(archive)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	switch format {
+	case archiveTarGz:
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", archive, err)
+		}
+		defer gz.Close()
+		r = gz
+	case archiveTarBz:
+		r = bzip2.NewReader(f)
+	}
+	return extractTar(r, dest)
+}
+
+func extractTar(r io.Reader, dest string) (int, error) {
+	tr := tar.NewReader(r)
+	count := 0
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+
+		target, err := archiveTarget(dest, header.Name)
+		if err != nil {
+			return count, err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return count, err
+			}
+		case tar.TypeReg:
+			if err := writeArchiveFile(target, tr, fs.FileMode(header.Mode).Perm()); err != nil {
+				return count, err
+			}
+			count++
+		case tar.TypeSymlink:
+			// Links may only point inside the destination as well
+			linkTarget := header.Linkname
+			if !filepath.IsAbs(linkTarget) {
+				linkTarget = filepath.Join(filepath.Dir(target), linkTarget)
+			}
+			if _, err := archiveTarget(dest, mustRel(dest, linkTarget)); err != nil {
+				return count, fmt.Errorf("symlink %q points outside the destination", header.Name)
+			}
+			os.MkdirAll(filepath.Dir(target), 0755)
+			os.Remove(target)
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return count, err
+			}
+			count++
+		}
+	}
+}
+
+// mustRel returns target relative to base, or target itself when that's
+// impossible (which archiveTarget then rejects)
+func mustRel(base, target string) string {
+	if rel, err := filepath.Rel(base, target); err == nil {
+		return rel
+	}
+	return target
+}
+
+func extractZip(archive, dest string) (int, error) {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return 0, err
+	}
+	defer zr.Close()
+
+	count := 0
+	for _, file := range zr.File {
+		target, err := archiveTarget(dest, file.Name)
+		if err != nil {
+			return count, err
+		}
+
+		if file.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return count, err
+			}
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return count, err
+		}
+		mode := file.Mode().Perm()
+		if mode == 0 {
+			mode = 0644
+		}
+		err = writeArchiveFile(target, rc, mode)
+		rc.Close()
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+func writeArchiveFile(target string, r io.Reader, mode fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// compressPath archives src (a file or directory) into archive, choosing
+// the format from the archive's extension. Entries are stored under src's
+// base name, the same as `tar czf out.tgz dir`.
+func compressPath(src, archive string) error {
+	src = filepath.Clean(resolveAPIPath(src))
+	archive = resolveAPIPath(archive)
+
+	format, err := archiveFormat(archive)
+	if err != nil {
+		return err
+	}
+	if format == archiveTarBz {
+		return fmt.Errorf("%s: bzip2 archives can be extracted but not created", archive)
+	}
+	if _, err := os.Stat(src); err != nil {
+		return err
+	}
+
+	out, err := os.Create(archive)
+	if err != nil {
+		return err
+	}
+
+	if format == archiveZip {
+		err = writeZip(out, src, archive)
+	} else {
+		err = writeTar(out, src, archive, format == archiveTarGz)
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(archive)
+	}
+	return err
+}
+
+// walkArchiveSource calls fn for every path under src with its name inside
+// the archive, skipping the archive itself if it lives inside src
+func walkArchiveSource(src, archive string, fn func(p, name string, info fs.FileInfo) error) error {
+	parent := filepath.Dir(src)
+	return filepath.Walk(src, func(p string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == archive {
+			return nil
+		}
+		rel, err := filepath.Rel(parent, p)
+		if err != nil {
+			return err
+		}
+		return fn(p, filepath.ToSlash(rel), info)
+	})
+}
+
+func writeZip(w io.Writer, src, archive string) error {
+	zw := zip.NewWriter(w)
+	err := walkArchiveSource(src, archive, func(p, name string, info fs.FileInfo) error {
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = name
+		if info.IsDir() {
+			header.Name += "/"
+		} else {
+			header.Method = zip.Deflate
+		}
+
+		entry, err := zw.CreateHeader(header)
+		if err != nil || info.IsDir() || !info.Mode().IsRegular() {
+			return err
+		}
+		return copyFileTo(entry, p)
+	})
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func writeTar(w io.Writer, src, archive string, gzipped bool) error {
+	if gzipped {
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		w = gz
+	}
+
+	tw := tar.NewWriter(w)
+	err := walkArchiveSource(src, archive, func(p, name string, info fs.FileInfo) error {
+		link := ""
+		if info.Mode()&fs.ModeSymlink != 0 {
+			link, _ = os.Readlink(p)
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = name
+		if info.IsDir() {
+			header.Name += "/"
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return copyFileTo(tw, p)
+	})
+	if closeErr := tw.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func copyFileTo(w io.Writer, p string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// downloadFile fetches url into dest. If dest is an existing directory (or
+// empty) the file is named after the URL's last path element. The data is
+// written to a temporary file first so a failed download never leaves a
+// partial file behind. It returns the path written.
+func downloadFile(rawURL, dest string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", "gosh/"+GetVersion())
+
+	if dest == "" {
+		dest = "."
+	}
+	dest = resolveAPIPath(dest)
+	if info, err := os.Stat(dest); err == nil && info.IsDir() {
+		name := path.Base(req.URL.Path)
+		if name == "" || name == "/" || name == "." {
+			return "", fmt.Errorf("can't pick a file name for %s; give a file path as dest", rawURL)
+		}
+		dest = filepath.Join(dest, name)
+	}
+
+	// Downloads can be large, so no overall client timeout here
+	client := newHTTPClient(goshAPIEnvironment())
+	client.Timeout = 0
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("GET %s: %s", rawURL, resp.Status)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".gosh-download-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, resp.Body); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("GET %s: %w", rawURL, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	os.Chmod(tmp.Name(), 0644)
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return "", err
+	}
+	return dest, nil
+}
@@ -0,0 +1,115 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"archive/tar"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompressAndExtractRoundTrip(t *testing.T) {
+	for _, ext := range []string{".zip", ".tar", ".tar.gz"} {
+		t.Run(ext, func(t *testing.T) {
+			dir := t.TempDir()
+			src := filepath.Join(dir, "project")
+			os.MkdirAll(filepath.Join(src, "cmd"), 0755)
+			os.WriteFile(filepath.Join(src, "README.md"), []byte("hello"), 0644)
+			os.WriteFile(filepath.Join(src, "cmd", "run.sh"), []byte("#!/bin/sh\n"), 0755)
+
+			archive := filepath.Join(dir, "out"+ext)
+			if err := compressPath(src, archive); err != nil {
+				t.Fatalf("compressPath failed: %v", err)
+			}
+
+			dest := filepath.Join(dir, "extracted")
+			count, err := extractArchive(archive, dest)
+			if err != nil {
+				t.Fatalf("extractArchive failed: %v", err)
+			}
+			if count != 2 {
+				t.Errorf("Expected 2 files extracted, got %d", count)
+			}
+
+			data, err := os.ReadFile(filepath.Join(dest, "project", "README.md"))
+			if err != nil || string(data) != "hello" {
+				t.Errorf("Unexpected README contents: %q, %v", data, err)
+			}
+			info, err := os.Stat(filepath.Join(dest, "project", "cmd", "run.sh"))
+			if err != nil || info.Mode().Perm()&0100 == 0 {
+				t.Errorf("Expected run.sh to stay executable, got %v, %v", info, err)
+			}
+		})
+	}
+}
+
+func TestExtractArchive_RejectsPathTraversal(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "evil.tar")
+
+	f, _ := os.Create(archive)
+	tw := tar.NewWriter(f)
+	tw.WriteHeader(&tar.Header{Name: "../escaped.txt", Mode: 0644, Size: 4, Typeflag: tar.TypeReg})
+	tw.Write([]byte("oops"))
+	tw.Close()
+	f.Close()
+
+	if _, err := extractArchive(archive, filepath.Join(dir, "dest")); err == nil || !strings.Contains(err.Error(), "escapes") {
+		t.Errorf("Expected a path traversal error, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escaped.txt")); err == nil {
+		t.Error("Entry was written outside the destination")
+	}
+}
+
+func TestArchiveFormat(t *testing.T) {
+	tests := map[string]string{
+		"a.zip":     archiveZip,
+		"a.TGZ":     archiveTarGz,
+		"a.tar.gz":  archiveTarGz,
+		"a.tar.bz2": archiveTarBz,
+		"a.tar":     archiveTar,
+	}
+	for name, want := range tests {
+		if got, err := archiveFormat(name); err != nil || got != want {
+			t.Errorf("archiveFormat(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := archiveFormat("a.rar"); err == nil {
+		t.Error("Expected an error for an unsupported format")
+	}
+}
+
+func TestDownloadFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("payload"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+
+	// A directory destination keeps the URL's file name
+	path, err := downloadFile(server.URL+"/files/tool.tar.gz", dir)
+	if err != nil || path != filepath.Join(dir, "tool.tar.gz") {
+		t.Fatalf("downloadFile = %q, %v", path, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "payload" {
+		t.Errorf("Unexpected download contents: %q", data)
+	}
+
+	if _, err := downloadFile(server.URL+"/missing", filepath.Join(dir, "missing.txt")); err == nil {
+		t.Error("Expected an error for a 404")
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("Expected no partial files after a failed download, found %d entries", len(entries))
+	}
+}
@@ -11,7 +11,12 @@ import (
 )
 
 type BuiltinHandler struct {
-	state *ShellState
+	state     *ShellState
+	evaluator *GoEvaluator // Set by GoEvaluator.SetupWithBuiltins, for builtins that call config functions
+	stdin     *os.File     // The command's < or heredoc, for builtins that read input; nil for the terminal
+	// Whether the command's output is redirected to a file, for builtins
+	// that page or color what goes to the terminal
+	redirected bool
 }
 
 func NewBuiltinHandler(state *ShellState) *BuiltinHandler {
@@ -20,28 +25,119 @@ func NewBuiltinHandler(state *ShellState) *BuiltinHandler {
 
 func (b *BuiltinHandler) IsBuiltin(command string) bool {
 	switch command {
-	case "cd", "exit", "help", "init", "pwd", "session":
+	case ".", "[", "alias", "bg", "cd", "clear", "config", "copy", "dirs", "echo", "env", "eval", "exit", "export", "fg", "format", "gstage", "help", "history", "init", "j", "jobs", "kctx", "kns", "onchange", "paste", "popd", "printf", "profile", "pushd", "pwd", "read", "rehash", "reload", "rgi", "session", "source", "stats", "task", "tasks", "test", "theme", "timeout", "trap", "unalias", "undelete", "unset", "vault", "view":
 		return true
+	case "rm":
+		// Only intercepted in safe-delete mode
+		return b.safeDeleteEnabled()
 	default:
-		return false
+		// Go functions registered with gosh.RegisterCommand
+		return b.state != nil && b.state.isGoCommand(command)
 	}
 }
 
 func (b *BuiltinHandler) Execute(command string, args []string) ExecutionResult {
 	switch command {
+	case "alias":
+		return b.alias(args)
+	case "bg":
+		return b.bg(args)
 	case "cd":
 		return b.cd(args)
+	case "clear":
+		return b.clear(args)
+	case "config":
+		return b.config(args)
+	case "copy":
+		return b.copy(args)
+	case "dirs":
+		return b.dirs(args)
+	case "echo":
+		return b.echo(args)
+	case "env":
+		return b.env(args)
+	case "eval":
+		return b.eval(args)
 	case "exit":
 		return b.exit(args)
+	case "export":
+		return b.export(args)
+	case "fg":
+		return b.fg(args)
+	case "format":
+		return b.format(args)
+	case "gstage":
+		return b.gstage(args)
 	case "help":
 		return b.help(args)
+	case "history":
+		return b.history(args)
 	case "init":
 		return b.initConfig(args)
+	case "j":
+		return b.jump(args)
+	case "jobs":
+		return b.jobs(args)
+	case "kctx":
+		return b.kctx(args)
+	case "kns":
+		return b.kns(args)
+	case "paste":
+		return b.paste(args)
+	case "popd":
+		return b.popd(args)
+	case "printf":
+		return b.printf(args)
+	case "profile":
+		return b.profile(args)
+	case "pushd":
+		return b.pushd(args)
 	case "pwd":
 		return b.pwd(args)
+	case "read":
+		return b.read(args)
+	case "rehash":
+		return b.rehash(args)
+	case "reload":
+		return b.reload(args)
+	case "rgi":
+		return b.rgi(args)
 	case "session":
 		return b.session(args)
+	case "source", ".":
+		return b.source(command, args)
+	case "stats":
+		return b.stats(args)
+	case "onchange":
+		return b.onchange(args)
+	case "rm":
+		return b.rm(args)
+	case "task":
+		return b.task(args)
+	case "tasks":
+		return b.tasks(args)
+	case "test", "[":
+		return b.test(command, args)
+	case "theme":
+		return b.theme(args)
+	case "timeout":
+		return b.timeout(args)
+	case "trap":
+		return b.trap(args)
+	case "unalias":
+		return b.unalias(args)
+	case "undelete":
+		return b.undelete(args)
+	case "unset":
+		return b.unset(args)
+	case "vault":
+		return b.vault(args)
+	case "view":
+		return b.view(args)
 	default:
+		if b.state != nil && b.state.isGoCommand(command) {
+			return b.runGoCommand(command, args)
+		}
 		return ExecutionResult{
 			Output:   fmt.Sprintf("Unknown builtin: %s", command),
 			ExitCode: 1,
@@ -57,14 +153,39 @@ func (b *BuiltinHandler) cd(args []string) ExecutionResult {
 		target = args[0]
 	}
 
+	// cd - swaps back to the previous directory and prints it
+	if target == "-" {
+		previous := b.state.Environment["OLDPWD"]
+		if previous == "" {
+			err := fmt.Errorf("OLDPWD not set")
+			return ExecutionResult{Output: fmt.Sprintf("cd: %v", err), ExitCode: 1, Error: err}
+		}
+		result := b.changeDirectory("cd", previous)
+		if result.ExitCode == 0 {
+			result.Output = strings.TrimSuffix(previous+"\n"+result.Output, "\n")
+		}
+		return result
+	}
+
+	return b.changeDirectory("cd", target)
+}
+
+// changeDirectory changes to target for the builtin name, returning the
+// messages of the chpwd hooks as its output
+func (b *BuiltinHandler) changeDirectory(name, target string) ExecutionResult {
 	// Expand path
 	expanded := b.state.ExpandPath(target)
+	// A directory found through CDPATH is printed, as in other shells
+	found, viaCDPath := b.state.findInCDPath(target)
+	if viaCDPath {
+		expanded = found
+	}
 
 	// Check if directory exists
 	info, err := os.Stat(expanded)
 	if err != nil {
 		return ExecutionResult{
-			Output:   fmt.Sprintf("cd: %s: %v", target, err),
+			Output:   fmt.Sprintf("%s: %s: %v", name, target, err),
 			ExitCode: 1,
 			Error:    err,
 		}
@@ -72,7 +193,7 @@ func (b *BuiltinHandler) cd(args []string) ExecutionResult {
 
 	if !info.IsDir() {
 		return ExecutionResult{
-			Output:   fmt.Sprintf("cd: %s: not a directory", target),
+			Output:   fmt.Sprintf("%s: %s: not a directory", name, target),
 			ExitCode: 1,
 			Error:    fmt.Errorf("not a directory"),
 		}
@@ -81,16 +202,19 @@ func (b *BuiltinHandler) cd(args []string) ExecutionResult {
 	// Change directory
 	if err := os.Chdir(expanded); err != nil {
 		return ExecutionResult{
-			Output:   fmt.Sprintf("cd: %v", err),
+			Output:   fmt.Sprintf("%s: %v", name, err),
 			ExitCode: 1,
 			Error:    err,
 		}
 	}
 
-	b.state.WorkingDirectory = expanded
+	messages := b.state.SetWorkingDirectory(expanded)
+	if viaCDPath {
+		messages = append([]string{expanded}, messages...)
+	}
 
 	return ExecutionResult{
-		Output:   "",
+		Output:   strings.Join(messages, "\n"),
 		ExitCode: 0,
 		Error:    nil,
 	}
@@ -122,24 +246,71 @@ func (b *BuiltinHandler) help(args []string) ExecutionResult {
 		return ExecutionResult{
 			Output: "gosh - Go Shell with yaegi interpreter\n\n" +
 				"COMMANDS:\n" +
+				"  alias [NAME=VALUE] List or define aliases (unalias removes them)\n" +
 				"  cd [DIR]          Change directory to DIR (or home if no DIR)\n" +
+				"  clear              Clear the screen (Ctrl-L keeps the input)\n" +
+				"  config [edit|check] Print, edit or check the config file\n" +
+				"  copy [TEXT]        Copy TEXT or the last output to the clipboard\n" +
+				"  dirs               Show the directory stack (pushd DIR / popd)\n" +
+				"  echo [-neE] ARG... Print the arguments (printf FORMAT ARG... formats them)\n" +
+				"  env [VAR=X] [CMD]  Print the environment, or run CMD with VAR set\n" +
+				"  eval ARG...        Run the arguments as a command line\n" +
 				"  exit [CODE]        Exit shell with optional exit code\n" +
+				"  export NAME=VALUE  Set an environment variable (unset removes it)\n" +
+				"  fg / bg [%JOB]     Resume a job in the foreground / background\n" +
+				"  format [FORMAT]    Show Go results as table, json or go\n" +
 				"  help [COMMAND]    Show help for COMMAND, or this general help\n" +
-				"  init               Initialize ~/.config/gosh with shellapi config\n\n" +
+				"  history [N|TEXT]   List past commands (!N runs one again)\n" +
+				"  init [--template T] Initialize ~/.config/gosh with a starter config\n" +
+				"  gstage             Interactive git status with stage/unstage/diff\n" +
+				"  j TERM...          Jump to the best match of the directories visited\n" +
+				"  jobs               List background jobs (start one with CMD &)\n" +
+				"  kctx [NAME]        List or switch Kubernetes contexts\n" +
+				"  kns [NAMESPACE]    Show or switch the Kubernetes namespace\n" +
+				"  onchange GLOB CMD  Rerun CMD when matching files change\n" +
+				"  paste              Print the clipboard\n" +
+				"  profile [aws|gcp]  Show or switch cloud profiles\n" +
+				"  read [NAME...]     Read a line of input into variables\n" +
+				"  rehash             Rebuild the index of commands in PATH\n" +
+				"  reload             Load the config files again, keeping the session\n" +
+				"  rgi PATTERN        Interactive ripgrep, opens the match in $EDITOR\n" +
+				"  source FILE        Run a shell script, keeping its environment changes\n" +
+				"  stats [top|slow]   Show command usage statistics\n" +
+				"  task [TARGET]      Run a Makefile/justfile target\n" +
+				"  tasks              List or pause scheduled background tasks\n" +
+				"  test / [ EXPR ]    Check files, strings and numbers, for && and ||\n" +
+				"  theme [list|set T] Show, list or switch color themes\n" +
+				"  timeout DUR CMD    Run CMD, ending it after DUR (e.g. 30s)\n" +
+				"  trap CMD SIGNAL... Run CMD on a signal or when gosh exits\n" +
+				"  undelete [N]       Restore files removed by rm (GOSH_SAFE_RM=1)\n" +
+				"  vault              Encrypted secrets store\n" +
+				"  view FILE          Show a file with syntax highlighting\n\n" +
 				"CONFIGURATION:\n" +
 				"  config.go          Go configuration file executed on startup\n" +
 				"    - Checked in current directory first\n" +
 				"    - Falls back to ~/.config/gosh/config.go\n" +
 				"    - Full Go syntax with IDE support (LSP, treesitter)\n" +
 				"    - Define functions, set environment, import packages\n" +
-				"    - Functions persist and are available in the shell\n\n" +
+				"    - Functions persist and are available in the shell\n" +
+				"  plugins/NAME/plugin.go  Plugins in ~/.config/gosh, loaded after config.go\n\n" +
 				"GO CODE:\n" +
 				"  Write Go code directly:\n" +
 				"    x := 42\n" +
 				"    fmt.Println(x)\n" +
 				"    func add(a, b int) int { return a + b }\n\n" +
-				"  Pre-imported packages: fmt, os, strings, strconv, path/filepath\n\n" +
-				"  Multiline code supported with continuation prompts (...)\n\n" +
+				"  Pre-imported packages: fmt, os, strings, strconv, path/filepath\n" +
+				"  (add more in config.go with gosh.PreImport(\"time\", \"encoding/json\"))\n\n" +
+				"  Multiline code supported with continuation prompts (...)\n" +
+				"  :edit opens the block typed so far in $EDITOR, running it when you quit\n" +
+				"  :again runs the last Go block again; :fix brings back the last that failed\n" +
+				"  _ is the last result shown, _2 the one before, and so on up to _10\n\n" +
+				"  :save [FILE] / :load [FILE] keep functions and variables across restarts\n" +
+				"  :export [FILE] writes them out as a main.go program\n" +
+				"  :reset [--no-config] starts over with a fresh interpreter\n" +
+				"  :vars / :funcs list the variables and functions defined so far\n" +
+				"  :type EXPR shows the type of an expression\n" +
+				"  :doc SYMBOL shows its documentation (go doc)\n" +
+				"  :goroutines lists the goroutines started with go; :stop ID stops one\n\n" +
 				"COMMAND SUBSTITUTION:\n" +
 				"  $(command) captures command output into a Go string:\n" +
 				"    files := $(ls)\n" +
@@ -167,19 +338,94 @@ func (b *BuiltinHandler) help(args []string) ExecutionResult {
 	// Help for specific commands
 	command := args[0]
 
+	// Builtins that keep their help text next to their implementation
+	switch command {
+	case "alias", "unalias":
+		return ExecutionResult{Output: aliasHelpText, ExitCode: 0, Error: nil}
+	case "env", "export", "unset":
+		return ExecutionResult{Output: exportHelpText, ExitCode: 0, Error: nil}
+	case "clear":
+		return ExecutionResult{Output: clearHelpText, ExitCode: 0, Error: nil}
+	case "config":
+		return ExecutionResult{Output: configHelpText, ExitCode: 0, Error: nil}
+	case "copy":
+		return ExecutionResult{Output: copyHelpText, ExitCode: 0, Error: nil}
+	case "dirs", "pushd", "popd":
+		return ExecutionResult{Output: dirsHelpText, ExitCode: 0, Error: nil}
+	case "eval":
+		return ExecutionResult{Output: evalHelpText, ExitCode: 0, Error: nil}
+	case "format":
+		return ExecutionResult{Output: formatHelpText, ExitCode: 0, Error: nil}
+	case "gstage":
+		return ExecutionResult{Output: gstageHelpText, ExitCode: 0, Error: nil}
+	case "history":
+		return ExecutionResult{Output: historyHelpText, ExitCode: 0, Error: nil}
+	case "j":
+		return ExecutionResult{Output: jumpHelpText, ExitCode: 0, Error: nil}
+	case "jobs", "fg", "bg":
+		return ExecutionResult{Output: jobsHelpText, ExitCode: 0, Error: nil}
+	case "kctx":
+		return ExecutionResult{Output: kctxHelpText, ExitCode: 0, Error: nil}
+	case "kns":
+		return ExecutionResult{Output: knsHelpText, ExitCode: 0, Error: nil}
+	case "paste":
+		return ExecutionResult{Output: pasteHelpText, ExitCode: 0, Error: nil}
+	case "profile":
+		return ExecutionResult{Output: profileHelpText, ExitCode: 0, Error: nil}
+	case "echo", "printf":
+		return ExecutionResult{Output: echoHelpText, ExitCode: 0, Error: nil}
+	case "read":
+		return ExecutionResult{Output: readHelpText, ExitCode: 0, Error: nil}
+	case "rehash":
+		return ExecutionResult{Output: rehashHelpText, ExitCode: 0, Error: nil}
+	case "reload":
+		return ExecutionResult{Output: reloadHelpText, ExitCode: 0, Error: nil}
+	case "rgi":
+		return ExecutionResult{Output: rgiHelpText, ExitCode: 0, Error: nil}
+	case "stats":
+		return ExecutionResult{Output: statsHelpText, ExitCode: 0, Error: nil}
+	case "onchange":
+		return ExecutionResult{Output: onchangeHelpText, ExitCode: 0, Error: nil}
+	case "source", ".":
+		return ExecutionResult{Output: sourceHelpText, ExitCode: 0, Error: nil}
+	case "task":
+		return ExecutionResult{Output: taskHelpText, ExitCode: 0, Error: nil}
+	case "tasks":
+		return ExecutionResult{Output: tasksHelpText, ExitCode: 0, Error: nil}
+	case "test", "[":
+		return ExecutionResult{Output: testHelpText, ExitCode: 0, Error: nil}
+	case "theme":
+		return ExecutionResult{Output: themeHelpText, ExitCode: 0, Error: nil}
+	case "timeout":
+		return ExecutionResult{Output: timeoutHelpText, ExitCode: 0, Error: nil}
+	case "trap":
+		return ExecutionResult{Output: trapHelpText, ExitCode: 0, Error: nil}
+	case "undelete", "rm":
+		return ExecutionResult{Output: undeleteHelpText, ExitCode: 0, Error: nil}
+	case "vault":
+		return ExecutionResult{Output: vaultHelpText, ExitCode: 0, Error: nil}
+	case "view":
+		return ExecutionResult{Output: viewHelpText, ExitCode: 0, Error: nil}
+	}
+
 	if command == "cd" {
 		return ExecutionResult{
 			Output: "cd - Change Directory\n\n" +
 				"USAGE:\n" +
-				"    cd [DIRECTORY]\n\n" +
+				"    cd [DIRECTORY]\n" +
+				"    cd -\n\n" +
 				"DESCRIPTION:\n" +
 				"    Change the current working directory to DIRECTORY.\n" +
-				"    If no DIRECTORY is specified, change to the user's home directory.\n\n" +
+				"    If no DIRECTORY is specified, change to the user's home directory.\n" +
+				"    cd - changes back to the previous directory ($OLDPWD) and prints it.\n" +
+				"    Relative directories are also looked up in the directories of\n" +
+				"    CDPATH, separated by colons.\n\n" +
 				"EXAMPLES:\n" +
 				"    cd                    # Change to home directory\n" +
 				"    cd ~/projects        # Change to projects directory\n" +
 				"    cd /usr/local        # Change to absolute path\n" +
-				"    cd ..               # Change to parent directory",
+				"    cd ..               # Change to parent directory\n" +
+				"    cd -                # Change back to the previous directory",
 			ExitCode: 0, Error: nil,
 		}
 	}
@@ -210,6 +456,7 @@ func (b *BuiltinHandler) help(args []string) ExecutionResult {
 				"    help          # Show this general help\n" +
 				"    help cd       # Show help for cd command\n" +
 				"    help init     # Show help for init command\n" +
+				"    help deploy   # Show the doc comment of a config function or Go command\n" +
 				"    help shellapi # Show help for shellapi functions\n" +
 				"    help go       # Show help for Go code execution",
 			ExitCode: 0, Error: nil,
@@ -221,10 +468,18 @@ func (b *BuiltinHandler) help(args []string) ExecutionResult {
 		return ExecutionResult{
 			Output: "init - Initialize gosh Configuration\n\n" +
 				"USAGE:\n" +
-				"    init\n\n" +
+				"    init [--template NAME] [--force]\n\n" +
 				"DESCRIPTION:\n" +
 				"    Initialize ~/.config/gosh directory with shellapi configuration.\n" +
-				"    Creates go.mod file and template config.go with manual wrapper examples.\n\n" +
+				"    Creates go.mod file and template config.go with manual wrapper examples.\n" +
+				"    A config.go that's there already is kept, unless --force is given,\n" +
+				"    which saves it as config.go.bak first.\n\n" +
+				"TEMPLATES:\n" +
+				"    shellapi    shellapi wrappers, navigation and colors (the default)\n" +
+				"    minimal     An alias and a function to start from\n" +
+				"    git         git aliases and commands: gnew, gsync, gcm, gclean\n" +
+				"    devops      docker and kubectl aliases and commands: dlogs, dstop, kpods\n" +
+				"    data        Pre-imported packages and helpers: load, fetch, column, mean\n\n" +
 				"CREATES:\n" +
 				"    ~/.config/gosh/                      - Configuration directory\n" +
 				"    ~/.config/gosh/go.mod                 - Go module file\n" +
@@ -255,9 +510,11 @@ func (b *BuiltinHandler) help(args []string) ExecutionResult {
 		return ExecutionResult{
 			Output: "shellapi - Shell Function Library (v0.2.1+)\n\n" +
 				"OVERVIEW:\n" +
-				"    shellapi provides 100+ shell-friendly functions organized\n" +
-				"    into categories: development tools, file operations, git,\n" +
-				"    system commands, colors, and project utilities.\n\n" +
+				"    shellapi provides shell-friendly functions organized into\n" +
+				"    categories: development tools, file operations, git, system\n" +
+				"    commands, colors, and project utilities. It's built into\n" +
+				"    gosh, so importing gosh_lib's shellapi needs no module.\n" +
+				"    Commands run in the shell's directory and environment.\n\n" +
 				"MANUAL WRAPPER PATTERN:\n" +
 				"    Instead of direct access, create manual wrapper functions:\n\n" +
 				"EXAMPLE WRAPPER CONFIG:\n" +
@@ -279,7 +536,17 @@ func (b *BuiltinHandler) help(args []string) ExecutionResult {
 				"    🔀 Git:         GitStatus(), GitLog(), QuickCommit(), GitPull()\n" +
 				"    🖥️  System:      Uptime(), Date(), Pwd(), EnvVar()\n" +
 				"    🎨 Colors:      Success(), Error(), Warning(), Bold()\n" +
-				"    🏗️  Project:     MakeTarget(), BuildAndTest(), CreateProjectDir()\n\n" +
+				"    🏗️  Project:     MakeTarget(), BuildAndTest(), CreateProjectDir()\n" +
+				"    📝 Text:        Clean(), Trim(), Upper(), Title(), Replace()\n" +
+				"    🗂️  Paths:       PathExists(), IsDir(), FileSize(), JoinPaths()\n" +
+				"    📦 Archives:    Extract(), Compress(), Download()\n" +
+				"    The Shellapi Functions Reference in the docs lists them all.\n\n" +
+				"RUNSHELL:\n" +
+				"    shellapi.RunShell(name, args...) runs a command and returns a\n" +
+				"    CommandResult with Stdout, Stderr, ExitCode and Duration, and an\n" +
+				"    error if it failed. Configs that import gosh_lib's shellapi keep\n" +
+				"    its RunShell, which returns the output as a string; import\n" +
+				"    \"shellapi/shellapi\" for CommandResult.\n\n" +
 				"COLOR EXAMPLES:\n" +
 				"    shellapi.Success(\"Build passed!\")   # Green text\n" +
 				"    shellapi.Warning(\"Caution\")        # Yellow text\n" +
@@ -408,6 +675,11 @@ func (b *BuiltinHandler) help(args []string) ExecutionResult {
 		}
 	}
 
+	// Go commands and config functions show their doc comments
+	if help, ok := b.funcHelp(command); ok {
+		return ExecutionResult{Output: help, ExitCode: 0, Error: nil}
+	}
+
 	// Check if it's a shell command
 	if path, found := FindInPath(command, b.state.Environment["PATH"]); found {
 		return ExecutionResult{
@@ -520,6 +792,27 @@ func (b *BuiltinHandler) pwd(args []string) ExecutionResult {
 }
 
 func (b *BuiltinHandler) initConfig(args []string) ExecutionResult {
+	templateName, chosen, force := defaultInitTemplate, false, false
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--force" || arg == "-f":
+			force = true
+		case arg == "--template" && i+1 < len(args):
+			i++
+			templateName, chosen = args[i], true
+		case strings.HasPrefix(arg, "--template="):
+			templateName, chosen = strings.TrimPrefix(arg, "--template="), true
+		default:
+			err := fmt.Errorf("unknown option: %s", arg)
+			return ExecutionResult{Output: fmt.Sprintf("init: %v\nUsage: init [--template NAME] [--force]", err), ExitCode: 1, Error: err}
+		}
+	}
+	template, ok := initTemplates[templateName]
+	if !ok {
+		err := fmt.Errorf("unknown template: %s", templateName)
+		return ExecutionResult{Output: fmt.Sprintf("init: %v (use %s)", err, initTemplateNames()), ExitCode: 1, Error: err}
+	}
+
 	homeDir := os.Getenv("HOME")
 	if homeDir == "" {
 		return ExecutionResult{
@@ -563,128 +856,41 @@ require github.com/rsarv3006/gosh_lib v0.2.0
 		debugf("go.mod already exists at %s\n", goModPath)
 	}
 
-	// Create config.go if it doesn't exist
+	// Write config.go from the template, keeping one that's there unless
+	// forced to replace it
 	configPath := filepath.Join(configDir, "config.go")
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		configContent := `package main
-
-import (
-	"fmt"
-	"github.com/rsarv3006/gosh_lib/shellapi"
-)
-
-func init() {
-	fmt.Println("🚀 gosh config loaded! Command execution system enabled!")
-}
-
-// ==============================================================================
-// WORKING SHELLAPI FUNCTIONS (v0.2.2+)
-// ==============================================================================
-// These functions execute real commands via Go's os/exec and return results.
-
-// Development helper functions that actually work
-func build() string {
-	result, err := shellapi.GoBuild()
-	if err != nil {
-		return "BUILD ERROR: " + err.Error()
-	}
-	return "BUILD SUCCESS: " + result  // Executes real go build command
-}
-
-func test() string {
-	result, _ := shellapi.GoTest()
-	return result  // Executes real go test with full output
-}
-
-func run() string {
-	result, _ := shellapi.GoRun()
-	return result  // Executes real go run . application
-}
-
-func gs() string {
-	result, err := shellapi.GitStatus()
-	if err != nil {
-		return "GIT ERROR: " + err.Error()
-	}
-	return "GIT STATUS:\n" + result  // Executes real git status with colors
-}
-
-// ==============================================================================
-// DIRECTORY NAVIGATION FUNCTIONS
-// ==============================================================================
-// These functions change directories and persist the change across shell sessions.
-
-// goGosh() navigates to the gosh development directory
-//
-// IMPORTANT: This function MUST return the shellapi.RunShell result as-is for CD to work!
-// The shellapi.RunShell("cd", path) returns a special marker: @GOSH_INTERNAL_CD:/path
-// When this marker is returned, the gosh evaluator detects it and actually changes the shell's
-// working directory. If you try to format or modify the result, the CD functionality breaks!
-//
-// ✅ DO:     return result  // Return CD marker unmodified for directory change
-// ❌ DON'T:  return "Changed to " + result  // This breaks the CD marker system
-// ❌ DON'T:  return fmt.Sprintf("CD: %s", result)  // This breaks the CD marker system
-//
-// After calling goGosh(), the directory change persists for the entire shell session.
-// This enables creating navigation functions like goHome(), goProjects(), etc.
-func goGosh() string {
-	result, _ := shellapi.RunShell("cd", "/Users/rjs/dev/gosh")
-	return result // 🚨 CRITICAL: Return CD marker unmodified for directory change to work!
-}
-
-// Navigate to home config directory
-func goConfig() string {
-	result, _ := shellapi.RunShell("cd", "/Users/rjs/.config/gosh/")
-	return result  // Navigate to config directory
-}
-
-// Navigate to home directory  
-func goHome() string {
-	result, _ := shellapi.RunShell("cd", "~")
-	return result  // Navigate to home directory
-}
-
-// ==============================================================================
-// UTILITY FUNCTIONS
-// ==============================================================================
-
-// Simple welcome function
-func hello() string {
-	return "Hello from gosh!"
-}
-
-// Success message with green color
-func ok(msg string) string {
-	return shellapi.Success(msg)  // Green colored text
-}
-
-// Warning message with yellow color  
-func warn(msg string) string {
-	return shellapi.Warning(msg)  // Yellow colored text
-}
-
-// Error message with red color
-func err(msg string) string {
-	return shellapi.Error(msg)  // Red colored text
-}
-`
-		if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+	note := ""
+	if _, err := os.Stat(configPath); err == nil && !force {
+		// A template asked for and not written is a failure; init on its
+		// own only makes sure the directory is set up
+		if chosen {
+			err := fmt.Errorf("%s already exists; init --force replaces it", configPath)
+			return ExecutionResult{Output: fmt.Sprintf("init: %v", err), ExitCode: 1, Error: err}
+		}
+		note = "\nconfig.go already exists; init --force replaces it"
+	} else {
+		if err == nil {
+			if err := os.Rename(configPath, configPath+".bak"); err != nil {
+				return ExecutionResult{Output: fmt.Sprintf("init: %v", err), ExitCode: 1, Error: err}
+			}
+			note = "\nThe previous config.go was saved as config.go.bak"
+		}
+		if err := os.WriteFile(configPath, []byte(template), 0644); err != nil {
 			return ExecutionResult{
 				Output:   fmt.Sprintf("Failed to create config.go: %v", err),
 				ExitCode: 1,
 				Error:    err,
 			}
 		}
-		debugf("Created %s\n", configPath)
-	} else {
-		debugf("config.go already exists at %s\n", configPath)
+		debugf("Created %s from the %s template\n", configPath, templateName)
+		note = fmt.Sprintf("\nconfig.go written from the %s template%s", templateName, note)
 	}
 
 	// Note: Skip go mod tidy for now since v0.1.0 checksum isn't published yet
 	debugln("📝 Config files created successfully!")
 	debugln("💡 Run 'cd ~/.config/gosh && go mod tidy' manually if needed")
 	return ExecutionResult{
-		Output:   fmt.Sprintf("✅ gosh config directory initialized at %s", configDir),
+		Output:   fmt.Sprintf("✅ gosh config directory initialized at %s%s", configDir, note),
 		ExitCode: 0,
 		Error:    nil,
 	}
@@ -3,6 +3,7 @@
 package main
 
 import (
+	"path/filepath"
 	"testing"
 )
 
@@ -54,7 +55,7 @@ func TestBuiltinIsBuiltin(t *testing.T) {
 		{"pwd", true},
 		{"session", true},
 		{"ls", false},
-		{"echo", false},
+		{"echo", true},
 		{"git", false},
 	}
 
@@ -129,3 +130,28 @@ func TestBuiltinCdErrors(t *testing.T) {
 		t.Error("Expected error for cd to non-existent path")
 	}
 }
+
+func TestBuiltinCdDash(t *testing.T) {
+	first, _ := filepath.EvalSymlinks(t.TempDir())
+	second, _ := filepath.EvalSymlinks(t.TempDir())
+	t.Chdir(first)
+	state := &ShellState{WorkingDirectory: first, Environment: map[string]string{}}
+	builtins := NewBuiltinHandler(state)
+
+	if result := builtins.cd([]string{"-"}); result.ExitCode != 1 || result.Output != "cd: OLDPWD not set" {
+		t.Errorf("Expected cd - to fail without OLDPWD, got %q", result.Output)
+	}
+
+	builtins.cd([]string{second})
+	if state.Environment["OLDPWD"] != first {
+		t.Errorf("Expected OLDPWD %s, got %q", first, state.Environment["OLDPWD"])
+	}
+
+	result := builtins.cd([]string{"-"})
+	if result.ExitCode != 0 || result.Output != first || state.WorkingDirectory != first {
+		t.Errorf("cd - gave %q (exit %d), now in %s", result.Output, result.ExitCode, state.WorkingDirectory)
+	}
+	if state.Environment["OLDPWD"] != second {
+		t.Errorf("Expected cd - to swap OLDPWD to %s, got %q", second, state.Environment["OLDPWD"])
+	}
+}
@@ -35,7 +35,7 @@ func TestBuiltinHandler_IsBuiltin(t *testing.T) {
 		{"help", true},
 		{"ls", false},
 		{"git", false},
-		{"echo", false},
+		{"echo", true},
 		{"", false},
 		{"CD", false}, // case sensitive
 		{"cd2", false},
@@ -0,0 +1,111 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// readCaptureSource returns the contents of source: the file it names if
+// one exists, otherwise the stdout of running it as a shell command
+func readCaptureSource(source string) ([]byte, error) {
+	dir := ""
+	env := os.Environ()
+	if state := goshAPIState(); state != nil {
+		dir = state.WorkingDirectory
+		env = state.EnvironmentSlice()
+	}
+
+	path := source
+	if !filepath.IsAbs(path) && dir != "" {
+		path = filepath.Join(dir, path)
+	}
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		return os.ReadFile(path)
+	}
+
+	// Commands go through sh so quoting and pipes (... | jq .items) work
+	cmd := exec.Command("/bin/sh", "-c", source)
+	cmd.Dir = dir
+	cmd.Env = env
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %w: %s", source, err, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	return output, nil
+}
+
+// captureJSON decodes a JSON file or command output into generic Go values
+// (map[string]any, []any, float64, ...)
+func captureJSON(source string) (any, error) {
+	var v any
+	if err := captureJSONInto(source, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// captureJSONInto decodes a JSON file or command output into v, which
+// should be a pointer to a struct, slice or map
+func captureJSONInto(source string, v any) error {
+	data, err := readCaptureSource(source)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: invalid JSON: %w", source, err)
+	}
+	return nil
+}
+
+// captureCSVRows reads a CSV file or command output as raw records
+func captureCSVRows(source string) ([][]string, error) {
+	data, err := readCaptureSource(source)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid CSV: %w", source, err)
+	}
+	return records, nil
+}
+
+// captureCSV reads a CSV file or command output, using the first row as
+// the header: each following row becomes a map from column name to value
+func captureCSV(source string) ([]map[string]string, error) {
+	records, err := captureCSVRows(source)
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+
+	header := records[0]
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(header))
+		for i, column := range header {
+			if i < len(record) {
+				row[column] = record[i]
+			} else {
+				row[column] = ""
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
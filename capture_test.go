@@ -0,0 +1,70 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCaptureCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "people.csv")
+	os.WriteFile(path, []byte("name,age\nada,36\n\"grace, rear admiral\",85\nshort\n"), 0644)
+
+	rows, err := captureCSV(path)
+	if err != nil {
+		t.Fatalf("captureCSV failed: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("Expected 3 rows, got %d", len(rows))
+	}
+	if rows[1]["name"] != "grace, rear admiral" || rows[1]["age"] != "85" {
+		t.Errorf("Unexpected row: %v", rows[1])
+	}
+	if rows[2]["age"] != "" {
+		t.Errorf("Missing columns should be empty, got %v", rows[2])
+	}
+}
+
+func TestCaptureJSONFromCommand(t *testing.T) {
+	v, err := captureJSON(`printf '{"items":[{"name":"a"},{"name":"b"}]}'`)
+	if err != nil {
+		t.Fatalf("captureJSON failed: %v", err)
+	}
+	items := v.(map[string]any)["items"].([]any)
+	if len(items) != 2 || items[1].(map[string]any)["name"] != "b" {
+		t.Errorf("Unexpected decoded value: %v", v)
+	}
+
+	if _, err := captureJSON("echo not json"); err == nil || !strings.Contains(err.Error(), "invalid JSON") {
+		t.Errorf("Expected an invalid JSON error, got %v", err)
+	}
+	if _, err := captureJSON("echo oops >&2; exit 3"); err == nil || !strings.Contains(err.Error(), "oops") {
+		t.Errorf("Expected the command's stderr in the error, got %v", err)
+	}
+}
+
+func TestGoshJSONIntoInterpretedStruct(t *testing.T) {
+	evaluator := NewGoEvaluator()
+
+	result := evaluator.Eval(`type pod struct {
+	Name  string ` + "`json:\"name\"`" + `
+	Ready bool   ` + "`json:\"ready\"`" + `
+}`)
+	if result.ExitCode != 0 {
+		t.Fatalf("Defining the struct failed: %s", result.Output)
+	}
+
+	evaluator.Eval(`var pods []pod`)
+	result = evaluator.Eval(`gosh.JSONInto("printf '[{\"name\":\"web\",\"ready\":true}]'", &pods)`)
+	if result.ExitCode != 0 {
+		t.Fatalf("gosh.JSONInto failed: %s", result.Output)
+	}
+
+	result = evaluator.Eval(`pods[0].Name`)
+	if !strings.Contains(result.Output, "web") {
+		t.Errorf("Expected decoded pod name, got %q", result.Output)
+	}
+}
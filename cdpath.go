@@ -0,0 +1,79 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// usesCDPath reports whether cd looks target up in CDPATH: a relative path
+// that doesn't start with . or ..
+func usesCDPath(target string) bool {
+	if filepath.IsAbs(target) || strings.HasPrefix(target, "~") {
+		return false
+	}
+	first, _, _ := strings.Cut(target, "/")
+	return first != "." && first != ".."
+}
+
+// findInCDPath looks target up in the directories of CDPATH, returning the
+// directory it names. As in other shells, an empty or . entry stands for the
+// working directory, and a target found there isn't reported.
+func (s *ShellState) findInCDPath(target string) (string, bool) {
+	cdpath := s.Environment["CDPATH"]
+	if cdpath == "" || target == "" || !usesCDPath(target) {
+		return "", false
+	}
+
+	for _, dir := range filepath.SplitList(cdpath) {
+		local := dir == "" || dir == "."
+		if local {
+			dir = s.WorkingDirectory
+		}
+		candidate := filepath.Join(s.ExpandPath(dir), target)
+		if info, err := os.Stat(candidate); err == nil && info.IsDir() {
+			return candidate, !local
+		}
+	}
+	return "", false
+}
+
+// completeCDPath completes the directories cd would find for partial in
+// CDPATH
+func (g *GoshCompleter) completeCDPath(partial string) [][]rune {
+	cdpath, home := os.Getenv("CDPATH"), os.Getenv("HOME")
+	if g.state != nil {
+		cdpath, home = g.state.Environment["CDPATH"], g.state.Environment["HOME"]
+	}
+	if cdpath == "" || !usesCDPath(partial) {
+		return nil
+	}
+
+	sub, pattern := "", partial
+	if i := strings.LastIndex(partial, "/"); i != -1 {
+		sub, pattern = partial[:i+1], partial[i+1:]
+	}
+
+	var matches [][]rune
+	for _, dir := range filepath.SplitList(cdpath) {
+		// The working directory is completed like any relative path
+		if dir == "" || dir == "." {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(dir, "~"); ok {
+			dir = home + rest
+		}
+		entries, err := os.ReadDir(filepath.Join(dir, sub))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() && strings.HasPrefix(entry.Name(), pattern) {
+				matches = append(matches, []rune(entry.Name()[len(pattern):]+"/"))
+			}
+		}
+	}
+	return matches
+}
@@ -0,0 +1,73 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCdUsesCDPath(t *testing.T) {
+	home, _ := filepath.EvalSymlinks(t.TempDir())
+	work, _ := filepath.EvalSymlinks(t.TempDir())
+	os.MkdirAll(filepath.Join(home, "src", "myrepo", "cmd"), 0755)
+	os.MkdirAll(filepath.Join(work, "local"), 0755)
+	os.MkdirAll(filepath.Join(work, "myrepo"), 0755)
+	t.Chdir(work)
+
+	state := &ShellState{WorkingDirectory: work, Environment: map[string]string{"HOME": home, "CDPATH": "~/src"}}
+	b := NewBuiltinHandler(state)
+
+	// Found through CDPATH, ahead of the working directory, and printed
+	result := b.Execute("cd", []string{"myrepo/cmd"})
+	want := filepath.Join(home, "src", "myrepo", "cmd")
+	if result.ExitCode != 0 || result.Output != want || state.WorkingDirectory != want {
+		t.Errorf("cd myrepo/cmd gave %q (exit %d), now in %s", result.Output, result.ExitCode, state.WorkingDirectory)
+	}
+
+	// Not in CDPATH, so relative to the working directory
+	b.Execute("cd", []string{work})
+	if result := b.Execute("cd", []string{"local"}); result.ExitCode != 0 || result.Output != "" {
+		t.Errorf("cd local gave %q (exit %d)", result.Output, result.ExitCode)
+	}
+
+	// A . entry puts the working directory first, silently
+	b.Execute("cd", []string{work})
+	state.Environment["CDPATH"] = ".:~/src"
+	if result := b.Execute("cd", []string{"myrepo"}); result.Output != "" || state.WorkingDirectory != filepath.Join(work, "myrepo") {
+		t.Errorf("cd myrepo with . first gave %q, now in %s", result.Output, state.WorkingDirectory)
+	}
+
+	// ./ paths never use CDPATH
+	b.Execute("cd", []string{home})
+	if result := b.Execute("cd", []string{"./myrepo"}); result.ExitCode != 1 {
+		t.Errorf("Expected cd ./myrepo to fail outside CDPATH, got %q", result.Output)
+	}
+}
+
+func TestCompleteCDPath(t *testing.T) {
+	home, _ := filepath.EvalSymlinks(t.TempDir())
+	os.MkdirAll(filepath.Join(home, "src", "myrepo", "cmd"), 0755)
+	os.MkdirAll(filepath.Join(home, "src", "mytool"), 0755)
+	os.WriteFile(filepath.Join(home, "src", "myfile"), nil, 0644)
+
+	c := &GoshCompleter{}
+	c.state = &ShellState{WorkingDirectory: home, Environment: map[string]string{"HOME": home, "CDPATH": ".:~/src"}}
+
+	var got []string
+	for _, match := range c.completeCDPath("my") {
+		got = append(got, string(match))
+	}
+	slices.Sort(got)
+	if !slices.Equal(got, []string{"repo/", "tool/"}) {
+		t.Errorf("completeCDPath(my) = %q", got)
+	}
+	if matches := c.completeCDPath("myrepo/c"); len(matches) != 1 || string(matches[0]) != "md/" {
+		t.Errorf("completeCDPath(myrepo/c) = %q", matches)
+	}
+	if matches := c.completeCDPath("/my"); matches != nil {
+		t.Errorf("Expected absolute paths not to use CDPATH, got %q", matches)
+	}
+}
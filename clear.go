@@ -0,0 +1,38 @@
+//go:build darwin || linux
+
+package main
+
+import "fmt"
+
+// clearScreenSequence moves the cursor home and clears the screen and its
+// scrollback
+const clearScreenSequence = "\x1b[H\x1b[2J\x1b[3J"
+
+// clear implements the clear builtin. In the REPL the UI clears the screen
+// once the command line is done, so it can redraw the prompt; elsewhere
+// the terminal is sent the escape sequence that clears it.
+func (b *BuiltinHandler) clear(args []string) ExecutionResult {
+	if len(args) > 0 {
+		err := fmt.Errorf("too many arguments")
+		return ExecutionResult{Output: "clear: too many arguments\nUsage: clear", ExitCode: 1, Error: err}
+	}
+	if terminalOwned() {
+		b.state.clearScreen = true
+		return ExecutionResult{ExitCode: 0}
+	}
+	return ExecutionResult{Output: clearScreenSequence, ExitCode: 0, partialLine: true}
+}
+
+// takeClearScreen reports whether clear ran since it was last called
+func (s *ShellState) takeClearScreen() bool {
+	clear := s.clearScreen
+	s.clearScreen = false
+	return clear
+}
+
+const clearHelpText = "clear - Clear the Screen\n\n" +
+	"USAGE:\n" +
+	"    clear\n\n" +
+	"DESCRIPTION:\n" +
+	"    Clears the terminal and its scrollback. Ctrl-L does the same in the\n" +
+	"    REPL, keeping what's typed at the prompt."
@@ -0,0 +1,60 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type fakeTerminalOwner struct{}
+
+func (fakeTerminalOwner) ReleaseTerminal() error { return nil }
+func (fakeTerminalOwner) RestoreTerminal() error { return nil }
+
+func TestClear(t *testing.T) {
+	state := &ShellState{}
+	b := NewBuiltinHandler(state)
+
+	// Outside the REPL the terminal is sent the sequence that clears it
+	if result := b.Execute("clear", nil); result.Output != clearScreenSequence || !result.partialLine {
+		t.Errorf("Unexpected output %q", result.Output)
+	}
+
+	// In the REPL the UI clears it
+	SetTerminalOwner(fakeTerminalOwner{})
+	defer SetTerminalOwner(nil)
+	if result := b.Execute("clear", nil); result.Output != "" || !state.takeClearScreen() {
+		t.Errorf("Expected clear to leave it to the UI, got %q", result.Output)
+	}
+	if state.takeClearScreen() {
+		t.Error("Expected the screen to be cleared once")
+	}
+
+	m := model{state: state, session: &SessionState{}, textarea: textarea.New(), output: "old output"}
+	state.clearScreen = true
+	if updated, cmd := m.Update(blockFinishedMsg{}); cmd == nil || reflect.TypeOf(cmd()) != reflect.TypeOf(tea.ClearScreen()) {
+		t.Errorf("Expected the screen to be cleared after clear, got %+v", updated)
+	}
+
+	if result := b.Execute("clear", []string{"x"}); result.ExitCode == 0 {
+		t.Error("Expected clear to refuse arguments")
+	}
+}
+
+func TestModel_CtrlL(t *testing.T) {
+	m := model{state: &ShellState{}, session: &SessionState{}, textarea: textarea.New(), output: "old output"}
+	m.textarea.Focus()
+	m.textarea.SetValue("git sta")
+
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlL})
+	if cmd == nil || reflect.TypeOf(cmd()) != reflect.TypeOf(tea.ClearScreen()) {
+		t.Error("Expected Ctrl-L to clear the screen")
+	}
+	if m := updated.(model); m.output != "" || m.textarea.Value() != "git sta" {
+		t.Errorf("Expected Ctrl-L to clear the output and keep the input, got %q / %q", m.output, m.textarea.Value())
+	}
+}
@@ -0,0 +1,160 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// clipboardTool is an external clipboard program
+type clipboardTool struct {
+	name  string
+	copy  []string
+	paste []string
+	dir   string // Where the programs were found on PATH
+}
+
+// clipboardTools returns the usable clipboard programs for this session,
+// best first
+func clipboardTools(env map[string]string) []clipboardTool {
+	var tools []clipboardTool
+	if runtime.GOOS == "darwin" {
+		tools = append(tools, clipboardTool{name: "pbcopy", copy: []string{"pbcopy"}, paste: []string{"pbpaste"}})
+	}
+	if env["WAYLAND_DISPLAY"] != "" {
+		tools = append(tools, clipboardTool{name: "wl-copy", copy: []string{"wl-copy"}, paste: []string{"wl-paste", "--no-newline"}})
+	}
+	if env["DISPLAY"] != "" {
+		tools = append(tools,
+			clipboardTool{name: "xclip", copy: []string{"xclip", "-selection", "clipboard"}, paste: []string{"xclip", "-selection", "clipboard", "-o"}},
+			clipboardTool{name: "xsel", copy: []string{"xsel", "--clipboard", "--input"}, paste: []string{"xsel", "--clipboard", "--output"}})
+	}
+
+	var available []clipboardTool
+	for _, tool := range tools {
+		if path, found := FindInPath(tool.copy[0], env["PATH"]); found {
+			tool.dir = filepath.Dir(path)
+			available = append(available, tool)
+		}
+	}
+	return available
+}
+
+// isSSHSession reports whether gosh runs over SSH, where local clipboard
+// tools would write to the remote machine's clipboard
+func isSSHSession(env map[string]string) bool {
+	return env["SSH_TTY"] != "" || env["SSH_CONNECTION"] != ""
+}
+
+// copyToClipboard puts text on the clipboard and reports the method used.
+// Over SSH, or when no clipboard program is installed, it falls back to an
+// OSC 52 escape sequence so the local terminal sets the clipboard.
+func copyToClipboard(env map[string]string, text string) (string, error) {
+	if !isSSHSession(env) {
+		for _, tool := range clipboardTools(env) {
+			cmd := exec.Command(filepath.Join(tool.dir, tool.copy[0]), tool.copy[1:]...)
+			cmd.Stdin = strings.NewReader(text)
+			if err := cmd.Run(); err == nil {
+				return tool.name, nil
+			}
+		}
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return "", fmt.Errorf("no clipboard available: %w", err)
+	}
+	defer tty.Close()
+
+	if _, err := tty.WriteString(osc52Sequence(env, text)); err != nil {
+		return "", err
+	}
+	return "OSC 52", nil
+}
+
+// osc52Sequence builds the clipboard escape sequence, wrapped for tmux and
+// screen which otherwise swallow it
+func osc52Sequence(env map[string]string, text string) string {
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+
+	switch {
+	case env["TMUX"] != "":
+		return "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	case strings.HasPrefix(env["TERM"], "screen"):
+		return "\x1bP" + seq + "\x1b\\"
+	}
+	return seq
+}
+
+// pasteFromClipboard reads the clipboard through the first working tool.
+// OSC 52 reads aren't supported by most terminals, so there's no fallback.
+func pasteFromClipboard(env map[string]string) (string, error) {
+	tools := clipboardTools(env)
+	if len(tools) == 0 {
+		return "", fmt.Errorf("no clipboard program found (install wl-clipboard, xclip or xsel)")
+	}
+
+	var lastErr error
+	for _, tool := range tools {
+		cmd := exec.Command(filepath.Join(tool.dir, tool.paste[0]), tool.paste[1:]...)
+		output, err := cmd.Output()
+		if err == nil {
+			return string(output), nil
+		}
+		lastErr = err
+	}
+	return "", lastErr
+}
+
+// copy implements the copy builtin
+func (b *BuiltinHandler) copy(args []string) ExecutionResult {
+	text := strings.Join(args, " ")
+	if len(args) == 0 {
+		text = strings.TrimRight(stripANSI(b.state.LastOutput), "\n")
+		if text == "" {
+			return ExecutionResult{Output: "copy: nothing to copy (previous command had no output)", ExitCode: 1, Error: fmt.Errorf("nothing to copy")}
+		}
+	}
+
+	method, err := copyToClipboard(b.state.Environment, text)
+	if err != nil {
+		return ExecutionResult{Output: fmt.Sprintf("copy: %v", err), ExitCode: 1, Error: err}
+	}
+	return ExecutionResult{Output: fmt.Sprintf("Copied %d bytes to the clipboard (%s)", len(text), method), ExitCode: 0}
+}
+
+// paste implements the paste builtin
+func (b *BuiltinHandler) paste(args []string) ExecutionResult {
+	text, err := pasteFromClipboard(b.state.Environment)
+	if err != nil {
+		return ExecutionResult{Output: fmt.Sprintf("paste: %v", err), ExitCode: 1, Error: err}
+	}
+	return ExecutionResult{Output: text, ExitCode: 0}
+}
+
+const copyHelpText = "copy - Copy to the Clipboard\n\n" +
+	"USAGE:\n" +
+	"    copy [TEXT...]\n\n" +
+	"DESCRIPTION:\n" +
+	"    Copy TEXT, or the output of the previous command, to the system\n" +
+	"    clipboard using pbcopy, wl-copy, xclip or xsel. Over SSH, or when\n" +
+	"    none is installed, an OSC 52 escape sequence asks your terminal to\n" +
+	"    set its clipboard instead.\n\n" +
+	"    From Go code: gosh.CopyToClipboard(s)\n\n" +
+	"EXAMPLES:\n" +
+	"    git log -1 --format=%H\n" +
+	"    copy                 # copy the commit hash\n" +
+	"    copy hello world"
+
+const pasteHelpText = "paste - Print the Clipboard\n\n" +
+	"USAGE:\n" +
+	"    paste\n\n" +
+	"DESCRIPTION:\n" +
+	"    Print the system clipboard. Use `paste -> var` to capture it.\n\n" +
+	"    From Go code: gosh.PasteFromClipboard()"
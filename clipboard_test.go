@@ -0,0 +1,99 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeClipboard installs xclip/xsel stand-ins that store the clipboard in a file
+func fakeClipboard(t *testing.T) (map[string]string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	store := filepath.Join(dir, "clipboard")
+	script := "#!/bin/sh\nfor a in \"$@\"; do [ \"$a\" = -o ] && exec cat " + store + "; done\ncat > " + store + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "xclip"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return map[string]string{"PATH": dir, "DISPLAY": ":0"}, store
+}
+
+func TestCopyAndPasteFromClipboard(t *testing.T) {
+	env, store := fakeClipboard(t)
+
+	method, err := copyToClipboard(env, "hello clipboard")
+	if err != nil {
+		t.Fatalf("copyToClipboard failed: %v", err)
+	}
+	if method != "xclip" {
+		t.Errorf("Expected xclip, got %s", method)
+	}
+	if data, _ := os.ReadFile(store); string(data) != "hello clipboard" {
+		t.Errorf("Clipboard contains %q", data)
+	}
+
+	text, err := pasteFromClipboard(env)
+	if err != nil || text != "hello clipboard" {
+		t.Errorf("pasteFromClipboard = %q, %v", text, err)
+	}
+}
+
+func TestOSC52Sequence(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString([]byte("hi"))
+
+	if got := osc52Sequence(map[string]string{}, "hi"); got != "\x1b]52;c;"+encoded+"\a" {
+		t.Errorf("Unexpected OSC 52 sequence: %q", got)
+	}
+
+	tmux := osc52Sequence(map[string]string{"TMUX": "/tmp/tmux"}, "hi")
+	if !strings.HasPrefix(tmux, "\x1bPtmux;\x1b\x1b]52") || !strings.HasSuffix(tmux, "\x1b\\") {
+		t.Errorf("tmux passthrough not applied: %q", tmux)
+	}
+}
+
+func TestCopyBuiltin(t *testing.T) {
+	env, store := fakeClipboard(t)
+
+	state := NewShellState()
+	state.Environment = env
+	b := NewBuiltinHandler(state)
+
+	if result := b.Execute("copy", nil); result.ExitCode == 0 {
+		t.Error("copy without previous output should fail")
+	}
+
+	state.LastOutput = "\x1b[32mabc123\x1b[0m\n"
+	result := b.Execute("copy", nil)
+	if result.ExitCode != 0 {
+		t.Fatalf("copy failed: %s", result.Output)
+	}
+	if data, _ := os.ReadFile(store); string(data) != "abc123" {
+		t.Errorf("Expected stripped last output on the clipboard, got %q", data)
+	}
+
+	b.Execute("copy", []string{"two", "words"})
+	if result := b.Execute("paste", nil); result.Output != "two words" {
+		t.Errorf("paste returned %q", result.Output)
+	}
+}
+
+func TestGoshPackageClipboard(t *testing.T) {
+	env, store := fakeClipboard(t)
+
+	state := NewShellState()
+	state.Environment = env
+	evaluator := NewGoEvaluator()
+	evaluator.SetupWithShell(state, NewProcessSpawner(state))
+
+	result := evaluator.Eval(`gosh.CopyToClipboard("from go")`)
+	if result.ExitCode != 0 {
+		t.Fatalf("gosh.CopyToClipboard failed: %s", result.Output)
+	}
+	if data, _ := os.ReadFile(store); string(data) != "from go" {
+		t.Errorf("Clipboard contains %q", data)
+	}
+}
@@ -0,0 +1,312 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Variables that hold static AWS credentials. They take precedence over
+// AWS_PROFILE, so a stale export silently targets the wrong account.
+var awsCredentialVariables = []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN"}
+
+// defaultProtectedProfiles is used when GOSH_PROTECTED_PROFILES is unset
+const defaultProtectedProfiles = "*prod*"
+
+// awsProfiles lists the profile names from ~/.aws/config and ~/.aws/credentials
+// (or $AWS_CONFIG_FILE / $AWS_SHARED_CREDENTIALS_FILE)
+func awsProfiles(env map[string]string) []string {
+	home := env["HOME"]
+	configFile := env["AWS_CONFIG_FILE"]
+	if configFile == "" {
+		configFile = filepath.Join(home, ".aws", "config")
+	}
+	credentialsFile := env["AWS_SHARED_CREDENTIALS_FILE"]
+	if credentialsFile == "" {
+		credentialsFile = filepath.Join(home, ".aws", "credentials")
+	}
+
+	seen := make(map[string]bool)
+	var profiles []string
+	add := func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			profiles = append(profiles, name)
+		}
+	}
+
+	for _, section := range iniSections(configFile) {
+		// The config file prefixes everything but "default" with "profile "
+		if section == "default" {
+			add(section)
+		} else if name, ok := strings.CutPrefix(section, "profile "); ok {
+			add(strings.TrimSpace(name))
+		}
+	}
+	for _, section := range iniSections(credentialsFile) {
+		add(section)
+	}
+
+	sort.Strings(profiles)
+	return profiles
+}
+
+// iniSections returns the [section] names of an INI file
+func iniSections(path string) []string {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var sections []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			sections = append(sections, strings.TrimSpace(line[1:len(line)-1]))
+		}
+	}
+	return sections
+}
+
+// gcloudConfigDir returns the gcloud configuration directory
+func gcloudConfigDir(env map[string]string) string {
+	if dir := env["CLOUDSDK_CONFIG"]; dir != "" {
+		return dir
+	}
+	return filepath.Join(env["HOME"], ".config", "gcloud")
+}
+
+// gcloudConfigurations lists the named gcloud configurations
+func gcloudConfigurations(env map[string]string) []string {
+	matches, _ := filepath.Glob(filepath.Join(gcloudConfigDir(env), "configurations", "config_*"))
+	var names []string
+	for _, match := range matches {
+		names = append(names, strings.TrimPrefix(filepath.Base(match), "config_"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// activeGcloudConfiguration mirrors gcloud's lookup: the
+// CLOUDSDK_ACTIVE_CONFIG_NAME override, then the active_config file
+func activeGcloudConfiguration(env map[string]string) string {
+	if name := env["CLOUDSDK_ACTIVE_CONFIG_NAME"]; name != "" {
+		return name
+	}
+	data, err := os.ReadFile(filepath.Join(gcloudConfigDir(env), "active_config"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// activeAWSProfile returns the profile AWS tools will use, or "" when none
+// was chosen explicitly
+func activeAWSProfile(env map[string]string) string {
+	if profile := env["AWS_PROFILE"]; profile != "" {
+		return profile
+	}
+	return env["AWS_DEFAULT_PROFILE"]
+}
+
+// isProtectedProfile reports whether name matches GOSH_PROTECTED_PROFILES,
+// a comma separated list of glob patterns (default "*prod*")
+func isProtectedProfile(env map[string]string, name string) bool {
+	if name == "" {
+		return false
+	}
+	patterns, set := env["GOSH_PROTECTED_PROFILES"]
+	if !set {
+		patterns = defaultProtectedProfiles
+	}
+	for _, pattern := range strings.Split(patterns, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// cloudPromptSegments returns the styled aws:(...) / gcp:(...) prompt
+// segments. Protected profiles are highlighted so they're hard to miss.
+func cloudPromptSegments(env map[string]string) []string {
+	if env["GOSH_CLOUD_PROMPT"] == "0" {
+		return nil
+	}
+
+	colors := GetColorManager()
+	style := func(prefix, name string) string {
+		styledName := colors.StylePrompt(name, "git_branch")
+		if isProtectedProfile(env, name) {
+			styledName = colors.StyleOutput("!"+name, "error")
+		}
+		return fmt.Sprintf("%s(%s)", colors.StylePrompt(prefix, "git_prefix"), styledName)
+	}
+
+	var segments []string
+	if profile := activeAWSProfile(env); profile != "" {
+		segments = append(segments, style("aws:", profile))
+	}
+	// gcloud always has a "default" configuration, only show deliberate choices
+	if config := activeGcloudConfiguration(env); config != "" && config != "default" {
+		segments = append(segments, style("gcp:", config))
+	}
+	return segments
+}
+
+// cloudPromptKey summarises the cloud state for the prompt cache
+func cloudPromptKey(env map[string]string) string {
+	return activeAWSProfile(env) + "|" + activeGcloudConfiguration(env)
+}
+
+// profile implements the profile builtin
+func (b *BuiltinHandler) profile(args []string) ExecutionResult {
+	env := b.state.Environment
+
+	if len(args) == 0 {
+		aws := activeAWSProfile(env)
+		if aws == "" {
+			aws = "(none)"
+		}
+		gcp := activeGcloudConfiguration(env)
+		if gcp == "" {
+			gcp = "(none)"
+		}
+		return ExecutionResult{Output: fmt.Sprintf("aws: %s\ngcp: %s", aws, gcp), ExitCode: 0}
+	}
+
+	provider := args[0]
+	rest := args[1:]
+
+	confirmed := false
+	var positional []string
+	for _, arg := range rest {
+		if arg == "--confirm" {
+			confirmed = true
+		} else {
+			positional = append(positional, arg)
+		}
+	}
+
+	var available []string
+	var current string
+	switch provider {
+	case "aws":
+		available = awsProfiles(env)
+		current = activeAWSProfile(env)
+	case "gcp", "gcloud":
+		provider = "gcp"
+		available = gcloudConfigurations(env)
+		current = activeGcloudConfiguration(env)
+	default:
+		return ExecutionResult{
+			Output:   fmt.Sprintf("profile: unknown provider: %s (try aws or gcp)", provider),
+			ExitCode: 1,
+			Error:    fmt.Errorf("unknown provider: %s", provider),
+		}
+	}
+
+	if len(positional) == 0 {
+		if len(available) == 0 {
+			return ExecutionResult{Output: fmt.Sprintf("profile: no %s profiles found", provider), ExitCode: 1, Error: fmt.Errorf("no profiles found")}
+		}
+		var lines []string
+		for _, name := range available {
+			marker := "  "
+			if name == current {
+				marker = "* "
+			}
+			lines = append(lines, marker+name)
+		}
+		return ExecutionResult{Output: strings.Join(lines, "\n"), ExitCode: 0}
+	}
+
+	name := positional[0]
+
+	if name == "--clear" || name == "-" {
+		if provider == "aws" {
+			b.state.UnsetEnv("AWS_PROFILE")
+			b.state.UnsetEnv("AWS_DEFAULT_PROFILE")
+		} else {
+			b.state.UnsetEnv("CLOUDSDK_ACTIVE_CONFIG_NAME")
+		}
+		b.state.ForcePromptRefresh()
+		return ExecutionResult{Output: fmt.Sprintf("Cleared %s profile", provider), ExitCode: 0}
+	}
+
+	if !containsString(available, name) {
+		return ExecutionResult{
+			Output:   fmt.Sprintf("profile: no %s profile named %q", provider, name),
+			ExitCode: 1,
+			Error:    fmt.Errorf("unknown profile: %s", name),
+		}
+	}
+
+	if isProtectedProfile(env, name) && !confirmed {
+		return ExecutionResult{
+			Output:   fmt.Sprintf("profile: %q is a protected profile; re-run with --confirm to switch to it", name),
+			ExitCode: 1,
+			Error:    fmt.Errorf("protected profile: %s", name),
+		}
+	}
+
+	var notes []string
+	if provider == "aws" {
+		b.state.SetEnv("AWS_PROFILE", name)
+		b.state.SetEnv("AWS_DEFAULT_PROFILE", name)
+		for _, key := range awsCredentialVariables {
+			if _, ok := env[key]; ok {
+				b.state.UnsetEnv(key)
+				notes = append(notes, key)
+			}
+		}
+	} else {
+		b.state.SetEnv("CLOUDSDK_ACTIVE_CONFIG_NAME", name)
+	}
+	b.state.ForcePromptRefresh()
+
+	output := fmt.Sprintf("Switched %s profile to %q", provider, name)
+	if len(notes) > 0 {
+		output += fmt.Sprintf(" (unset %s)", strings.Join(notes, ", "))
+	}
+	if isProtectedProfile(env, name) {
+		output = GetColorManager().StyleOutput("WARNING: "+output+" - this is a protected profile", "error")
+	}
+	return ExecutionResult{Output: output, ExitCode: 0}
+}
+
+func containsString(items []string, s string) bool {
+	for _, item := range items {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+const profileHelpText = "profile - Cloud Profile Switcher\n\n" +
+	"USAGE:\n" +
+	"    profile                       Show the active AWS and gcloud profiles\n" +
+	"    profile aws|gcp               List available profiles\n" +
+	"    profile aws|gcp NAME          Switch profile\n" +
+	"    profile aws|gcp --clear       Go back to the tool's default\n\n" +
+	"DESCRIPTION:\n" +
+	"    Switching an AWS profile exports AWS_PROFILE and AWS_DEFAULT_PROFILE\n" +
+	"    and unsets static AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY/\n" +
+	"    AWS_SESSION_TOKEN credentials, which would otherwise override it.\n" +
+	"    Switching gcloud sets CLOUDSDK_ACTIVE_CONFIG_NAME for this shell only.\n\n" +
+	"    The active profiles are shown in the prompt (GOSH_CLOUD_PROMPT=0\n" +
+	"    hides them). Profiles matching GOSH_PROTECTED_PROFILES (comma\n" +
+	"    separated globs, default \"*prod*\") are highlighted and need\n" +
+	"    --confirm to switch to."
@@ -0,0 +1,143 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestAWSConfig(t *testing.T, home string) {
+	t.Helper()
+	dir := filepath.Join(home, ".aws")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := "[default]\nregion = us-east-1\n\n[profile staging]\nregion = eu-west-1\n\n[profile prod-admin]\n"
+	credentials := "[default]\naws_access_key_id = x\n\n[legacy]\naws_access_key_id = y\n"
+	os.WriteFile(filepath.Join(dir, "config"), []byte(config), 0600)
+	os.WriteFile(filepath.Join(dir, "credentials"), []byte(credentials), 0600)
+}
+
+func TestAWSProfiles(t *testing.T) {
+	home := t.TempDir()
+	writeTestAWSConfig(t, home)
+
+	got := strings.Join(awsProfiles(map[string]string{"HOME": home}), ",")
+	if got != "default,legacy,prod-admin,staging" {
+		t.Errorf("Unexpected profiles: %s", got)
+	}
+}
+
+func TestGcloudConfigurations(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "configurations"), 0755)
+	os.WriteFile(filepath.Join(dir, "configurations", "config_default"), nil, 0600)
+	os.WriteFile(filepath.Join(dir, "configurations", "config_work"), nil, 0600)
+	os.WriteFile(filepath.Join(dir, "active_config"), []byte("work\n"), 0600)
+
+	env := map[string]string{"CLOUDSDK_CONFIG": dir}
+	if got := strings.Join(gcloudConfigurations(env), ","); got != "default,work" {
+		t.Errorf("Unexpected configurations: %s", got)
+	}
+	if got := activeGcloudConfiguration(env); got != "work" {
+		t.Errorf("Expected active config work, got %q", got)
+	}
+
+	env["CLOUDSDK_ACTIVE_CONFIG_NAME"] = "default"
+	if got := activeGcloudConfiguration(env); got != "default" {
+		t.Errorf("Env override should win, got %q", got)
+	}
+}
+
+func TestIsProtectedProfile(t *testing.T) {
+	if !isProtectedProfile(map[string]string{}, "prod-admin") {
+		t.Error("Default pattern should protect prod profiles")
+	}
+	if isProtectedProfile(map[string]string{}, "staging") {
+		t.Error("staging should not be protected by default")
+	}
+
+	env := map[string]string{"GOSH_PROTECTED_PROFILES": "live, billing-*"}
+	if !isProtectedProfile(env, "billing-main") || isProtectedProfile(env, "prod") {
+		t.Error("Custom patterns should replace the default")
+	}
+}
+
+func TestProfileBuiltin(t *testing.T) {
+	home := t.TempDir()
+	writeTestAWSConfig(t, home)
+
+	state := NewShellState()
+	state.Environment = map[string]string{"HOME": home, "AWS_ACCESS_KEY_ID": "stale"}
+	b := NewBuiltinHandler(state)
+	t.Cleanup(func() {
+		os.Unsetenv("AWS_PROFILE")
+		os.Unsetenv("AWS_DEFAULT_PROFILE")
+	})
+
+	if result := b.Execute("profile", []string{"aws", "nope"}); result.ExitCode == 0 {
+		t.Error("Unknown profiles should be rejected")
+	}
+
+	result := b.Execute("profile", []string{"aws", "staging"})
+	if result.ExitCode != 0 {
+		t.Fatalf("Switching profile failed: %s", result.Output)
+	}
+	if state.Environment["AWS_PROFILE"] != "staging" {
+		t.Errorf("AWS_PROFILE not exported: %v", state.Environment)
+	}
+	if _, ok := state.Environment["AWS_ACCESS_KEY_ID"]; ok {
+		t.Error("Static credentials should be unset when switching profiles")
+	}
+
+	if result := b.Execute("profile", []string{"aws", "prod-admin"}); result.ExitCode == 0 {
+		t.Error("Protected profiles should need --confirm")
+	}
+	if result := b.Execute("profile", []string{"aws", "prod-admin", "--confirm"}); result.ExitCode != 0 {
+		t.Errorf("--confirm should allow protected profiles: %s", result.Output)
+	}
+
+	listing := b.Execute("profile", []string{"aws"})
+	if !strings.Contains(listing.Output, "* prod-admin") {
+		t.Errorf("Listing should mark the active profile: %q", listing.Output)
+	}
+
+	b.Execute("profile", []string{"aws", "--clear"})
+	if activeAWSProfile(state.Environment) != "" {
+		t.Error("--clear should remove the AWS profile")
+	}
+}
+
+func TestModel_CloudPrompt(t *testing.T) {
+	home := t.TempDir()
+	writeTestAWSConfig(t, home)
+	t.Cleanup(func() {
+		os.Unsetenv("AWS_PROFILE")
+		os.Unsetenv("AWS_DEFAULT_PROFILE")
+	})
+
+	state := &ShellState{WorkingDirectory: home, Environment: map[string]string{"HOME": home}}
+	session := &SessionState{CapturedVars: map[string][]string{}, Mode: ModeShell}
+	b := NewBuiltinHandler(state)
+	m := initialModel(session, state, NewGoEvaluator(), NewProcessSpawner(state), b)
+	if prompt := m.prompt(); strings.Contains(prompt, "aws:") {
+		t.Errorf("Expected no cloud segment without a profile, got %q", prompt)
+	}
+
+	// The profile a block switched to shows in the next prompt
+	b.Execute("profile", []string{"aws", "staging"})
+	m = m.finishBlock(blockFinishedMsg{})
+	if prompt := m.textarea.Prompt; !strings.Contains(prompt, "aws:") || !strings.Contains(prompt, "staging") {
+		t.Errorf("Expected the AWS profile in the prompt, got %q", prompt)
+	}
+
+	// Protected profiles are marked
+	b.Execute("profile", []string{"aws", "prod-admin", "--confirm"})
+	m = m.finishBlock(blockFinishedMsg{})
+	if prompt := m.textarea.Prompt; !strings.Contains(prompt, "!prod-admin") {
+		t.Errorf("Expected the protected profile to be marked, got %q", prompt)
+	}
+}
@@ -0,0 +1,195 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CommandHash indexes the executables in PATH, like the hash table of other
+// shells, so looking up or completing a command doesn't read every PATH
+// directory. The index is rebuilt when PATH changes and by the rehash
+// builtin; a command installed in between is still found when it's run,
+// but only completes after a rehash.
+type CommandHash struct {
+	mu sync.Mutex
+	// PATH the index was built for
+	path  string
+	built bool
+	// Full path of each command; the first directory in PATH wins
+	commands map[string]string
+	// Command names, sorted
+	names []string
+}
+
+func NewCommandHash() *CommandHash {
+	return &CommandHash{}
+}
+
+// Lookup returns the full path of the command name in pathEnv. A command
+// missing from the index, or whose file has gone, is looked for again.
+func (h *CommandHash) Lookup(pathEnv, name string) (string, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.build(pathEnv)
+
+	if path, ok := h.commands[name]; ok {
+		if isExecutableFile(path) {
+			return path, true
+		}
+		delete(h.commands, name)
+	}
+
+	for _, dir := range hashDirs(pathEnv) {
+		path := filepath.Join(dir, name)
+		if isExecutableFile(path) {
+			if _, ok := h.commands[name]; !ok {
+				h.names = insertSorted(h.names, name)
+			}
+			h.commands[name] = path
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// Complete returns the commands in pathEnv that start with partial, sorted
+func (h *CommandHash) Complete(pathEnv, partial string) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.build(pathEnv)
+
+	start := sort.SearchStrings(h.names, partial)
+	var matches []string
+	for _, name := range h.names[start:] {
+		if !strings.HasPrefix(name, partial) {
+			break
+		}
+		matches = append(matches, name)
+	}
+	return matches
+}
+
+// Rehash forgets the index, so it's rebuilt on next use
+func (h *CommandHash) Rehash() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.built = false
+}
+
+// Len returns the number of commands in pathEnv
+func (h *CommandHash) Len(pathEnv string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.build(pathEnv)
+	return len(h.commands)
+}
+
+// build indexes the directories in pathEnv, unless the index is already
+// up to date. Callers must hold h.mu.
+func (h *CommandHash) build(pathEnv string) {
+	if h.built && h.path == pathEnv {
+		return
+	}
+
+	commands := make(map[string]string)
+	for _, dir := range hashDirs(pathEnv) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if _, ok := commands[name]; ok || entry.IsDir() {
+				continue
+			}
+			// Follow symlinks, which are common in bin directories
+			path := filepath.Join(dir, name)
+			if isExecutableFile(path) {
+				commands[name] = path
+			}
+		}
+	}
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h.path, h.built, h.commands, h.names = pathEnv, true, commands, names
+}
+
+// hashDirs returns the directories of pathEnv that are searched for
+// commands. Like exec.LookPath, relative directories such as . are skipped.
+func hashDirs(pathEnv string) []string {
+	var dirs []string
+	for _, dir := range filepath.SplitList(pathEnv) {
+		if filepath.IsAbs(dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// isExecutableFile reports whether path is a file anyone can execute
+func isExecutableFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir() && info.Mode().Perm()&0111 != 0
+}
+
+// insertSorted inserts name into the sorted slice names
+func insertSorted(names []string, name string) []string {
+	i := sort.SearchStrings(names, name)
+	names = append(names, "")
+	copy(names[i+1:], names[i:])
+	names[i] = name
+	return names
+}
+
+// commandPath returns the full path of a command run by name, or name
+// itself when it holds a / or isn't in PATH, leaving exec to report it
+func (s *ShellState) commandPath(name string) string {
+	if name == "" || strings.Contains(name, "/") {
+		return name
+	}
+	if path, ok := s.Commands().Lookup(s.Environment["PATH"], name); ok {
+		return path
+	}
+	return name
+}
+
+// rehash implements the rehash builtin
+func (b *BuiltinHandler) rehash(args []string) ExecutionResult {
+	verbose := false
+	for _, arg := range args {
+		if arg != "-v" {
+			err := fmt.Errorf("unknown option: %s", arg)
+			return ExecutionResult{Output: fmt.Sprintf("rehash: %v\nUsage: rehash [-v]", err), ExitCode: 1, Error: err}
+		}
+		verbose = true
+	}
+
+	hash := b.state.Commands()
+	hash.Rehash()
+	if verbose {
+		count := hash.Len(b.state.Environment["PATH"])
+		return ExecutionResult{Output: fmt.Sprintf("%d commands in PATH", count), ExitCode: 0}
+	}
+	return ExecutionResult{Output: "", ExitCode: 0}
+}
+
+const rehashHelpText = "rehash - Rebuild the Command Index\n\n" +
+	"USAGE:\n" +
+	"    rehash            Forget the index of commands in PATH\n" +
+	"    rehash -v         Rebuild it now and print how many commands it holds\n\n" +
+	"DESCRIPTION:\n" +
+	"    gosh indexes the commands in PATH the first time it needs one, and\n" +
+	"    again whenever PATH changes, so running and completing commands\n" +
+	"    doesn't read every PATH directory. A command installed since then\n" +
+	"    still runs, but only completes after rehash."
@@ -0,0 +1,79 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeTestExecutable(t *testing.T, path, script string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCommandHash(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	writeTestExecutable(t, filepath.Join(first, "gtool"), "")
+	writeTestExecutable(t, filepath.Join(second, "gtool"), "")
+	writeTestExecutable(t, filepath.Join(second, "gother"), "")
+	os.WriteFile(filepath.Join(second, "gdata"), nil, 0644)
+	os.Mkdir(filepath.Join(second, "gdir"), 0755)
+	pathEnv := first + ":" + second + ":relative"
+
+	hash := NewCommandHash()
+	if got := hash.Complete(pathEnv, "g"); !reflect.DeepEqual(got, []string{"gother", "gtool"}) {
+		t.Errorf("Complete(g) = %q, want only the executables", got)
+	}
+	if path, ok := hash.Lookup(pathEnv, "gtool"); !ok || path != filepath.Join(first, "gtool") {
+		t.Errorf("Lookup(gtool) = %q, %v, want the first in PATH", path, ok)
+	}
+	if _, ok := hash.Lookup(pathEnv, "gdata"); ok {
+		t.Error("Expected a file that isn't executable not to be found")
+	}
+
+	// A new command runs straight away, but completes after a rehash
+	writeTestExecutable(t, filepath.Join(second, "gnew"), "")
+	if got := hash.Complete(pathEnv, "gn"); len(got) != 0 {
+		t.Errorf("Expected the index to be cached, got %q", got)
+	}
+	hash.Rehash()
+	if got := hash.Complete(pathEnv, "gn"); !reflect.DeepEqual(got, []string{"gnew"}) {
+		t.Errorf("Expected gnew after a rehash, got %q", got)
+	}
+
+	// A command that's gone is looked for again
+	os.Remove(filepath.Join(first, "gtool"))
+	if path, ok := hash.Lookup(pathEnv, "gtool"); !ok || path != filepath.Join(second, "gtool") {
+		t.Errorf("Lookup(gtool) = %q, %v after removing the first", path, ok)
+	}
+
+	// Changing PATH rebuilds the index
+	if got := hash.Complete(first, "g"); len(got) != 0 {
+		t.Errorf("Expected nothing in the new PATH, got %q", got)
+	}
+}
+
+func TestProcessSpawner_RunUsesCommandHash(t *testing.T) {
+	bin := t.TempDir()
+	writeTestExecutable(t, filepath.Join(bin, "gosh-hash-test"), `echo "$0 ran"`)
+	state := &ShellState{WorkingDirectory: t.TempDir(), Environment: map[string]string{"PATH": bin + ":" + os.Getenv("PATH")}}
+
+	result := NewProcessSpawner(state).Run(ShellCommand{Name: "gosh-hash-test"})
+	if result.ExitCode != 0 || !strings.HasSuffix(result.Output, "ran\n") {
+		t.Fatalf("Expected the command in the shell's PATH to run, got %q (exit %d)", result.Output, result.ExitCode)
+	}
+
+	b := NewBuiltinHandler(state)
+	if result := b.Execute("rehash", []string{"-v"}); result.ExitCode != 0 || !strings.HasSuffix(result.Output, " commands in PATH") {
+		t.Errorf("Unexpected rehash -v output %q", result.Output)
+	}
+	if result := b.Execute("rehash", []string{"-x"}); result.ExitCode != 1 {
+		t.Errorf("Expected an unknown option to fail, got %q", result.Output)
+	}
+}
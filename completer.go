@@ -4,6 +4,7 @@ package main
 
 import (
 	"os"
+	"slices"
 	"strings"
 	"time"
 	"unicode"
@@ -18,6 +19,10 @@ type GoshCompleter struct {
 	goEvaluator     *GoEvaluator
 	lspWrapper      *LSPClientWrapper
 	lspEnabled      bool
+	// Shell state whose aliases complete as commands, if set
+	state *ShellState
+	// Index of the commands in PATH, used when there's no shell state
+	commandHash *CommandHash
 }
 
 // NewGoshCompleter creates a new intelligent completer
@@ -109,7 +114,10 @@ func (g *GoshCompleter) Do(line []rune, pos int) (newLine [][]rune, length int)
 	isGo := g.contextAnalyzer.IsGoContext(string(line), pos)
 	debugf("🔍 [COMPLETER] Line: %q, Pos: %d, IsGo: %v\n", string(line), pos, isGo)
 
-	if isGo {
+	if candidates, ok := g.registeredCompletion(prefixWords, partial); ok {
+		// A command with a registered completion is never Go
+		matches = suffixMatches(candidates, partial)
+	} else if isGo {
 		// Use intelligent Go completion
 		debugf("✅ [COMPLETER] Using Go completion for %q\n", partial)
 		// Pass the full line, not just the prefix
@@ -122,6 +130,8 @@ func (g *GoshCompleter) Do(line []rune, pos int) (newLine [][]rune, length int)
 			commandPartial = partial[2:] // Remove "./" prefix
 		}
 		matches = g.completeCommands(commandPartial)
+	} else if prefixWords[0] == "profile" {
+		matches = g.completeProfile(prefixWords[1:], partial)
 	} else {
 		cmd := prefixWords[0]
 		matches = g.completeArguments(cmd, partial)
@@ -170,6 +180,10 @@ func (g *GoshCompleter) doGoCompletion(lineStr, partial string, pos int) [][]run
 	case ContextSelector:
 		// Get selector completions (e.g., "fmt.", "strings.")
 		suggestions = g.contextAnalyzer.GetSelectorCompletions(ctx.Scope, tokenPartial)
+		if importPath, ok := g.goEvaluator.packageNames()[ctx.Scope]; ok && len(suggestions) == 0 {
+			// The members of a package pre-imported or imported by a config
+			suggestions = packageMembers(importPath, tokenPartial)
+		}
 		if len(suggestions) == 0 {
 			// Fallback to symbol extractor for user-defined symbols
 			suggestions = g.symbolExtractor.GetSelectorCompletions(ctx.Scope, tokenPartial)
@@ -241,6 +255,15 @@ func (g *GoshCompleter) doGoCompletion(lineStr, partial string, pos int) [][]run
 			}
 		}
 
+		// Packages that can be used without importing them
+		if tokenPartial != "" {
+			for _, name := range g.goEvaluator.sortedPackageNames() {
+				if strings.HasPrefix(name, tokenPartial) && !containsLabel(suggestions, name) {
+					suggestions = append(suggestions, CompletionItem{Label: name, Kind: "package"})
+				}
+			}
+		}
+
 		// Add Go keywords for common patterns
 		if strings.HasPrefix("func", tokenPartial) {
 			suggestions = append(suggestions, CompletionItem{
@@ -353,7 +376,7 @@ func (g *GoshCompleter) completeCommands(partial string) [][]rune {
 	}
 
 	// 1. Builtin commands
-	builtins := []string{"cd", "pwd", "exit", "help"}
+	builtins := []string{"cd", "pwd", "exit", "alias", "bg", "clear", "config", "copy", "dirs", "echo", "env", "eval", "export", "fg", "format", "gstage", "help", "history", "j", "jobs", "kctx", "kns", "onchange", "paste", "popd", "printf", "profile", "pushd", "read", "rehash", "reload", "rgi", "source", "stats", "task", "tasks", "test", "theme", "timeout", "trap", "unalias", "undelete", "unset", "vault", "view"}
 	for _, cmd := range builtins {
 		if strings.HasPrefix(cmd, partial) {
 			suffix := cmd[len(partial):]
@@ -361,6 +384,15 @@ func (g *GoshCompleter) completeCommands(partial string) [][]rune {
 		}
 	}
 
+	// Aliases, and Go functions registered as commands
+	if g.state != nil {
+		for _, name := range append(g.state.AliasNames(), g.state.GoCommandNames()...) {
+			if strings.HasPrefix(name, partial) {
+				matches = append(matches, []rune(name[len(partial):]))
+			}
+		}
+	}
+
 	// 2. Commands from PATH
 	if path, ok := os.LookupEnv("PATH"); ok {
 		pathCommands := g.getCommandsFromPath(path, partial)
@@ -376,15 +408,66 @@ func (g *GoshCompleter) completeCommands(partial string) [][]rune {
 
 // completeArguments provides argument completion
 func (g *GoshCompleter) completeArguments(cmd, partial string) [][]rune {
-	if cmd == "cd" {
-		return g.completeFiles(partial, true) // Directories only
+	if cmd == "cd" || cmd == "pushd" {
+		// Directories only, including those cd finds through CDPATH
+		matches := g.completeFiles(partial, true)
+		for _, match := range g.completeCDPath(partial) {
+			if !slices.ContainsFunc(matches, func(m []rune) bool { return string(m) == string(match) }) {
+				matches = append(matches, match)
+			}
+		}
+		return matches
 	}
 
 	// For commands that take files
-	if cmd == "ls" || cmd == "cat" || cmd == "head" || cmd == "tail" || cmd == "grep" {
+	if cmd == "ls" || cmd == "cat" || cmd == "source" || cmd == "view" || cmd == "head" || cmd == "tail" || cmd == "grep" {
 		return g.completeFiles(partial, false) // All files
 	}
 
+	// Kubernetes contexts and namespaces
+	if cmd == "kctx" || cmd == "kns" {
+		return g.completeKube(cmd, partial)
+	}
+
+	// Variable names, before the = of an assignment
+	if cmd == "unset" || (cmd == "export" && !strings.Contains(partial, "=")) {
+		var names []string
+		for _, e := range os.Environ() {
+			name, _, _ := strings.Cut(e, "=")
+			names = append(names, name)
+		}
+		return suffixMatches(names, partial)
+	}
+
+	if cmd == "format" {
+		return suffixMatches([]string{ResultFormatTable, ResultFormatJSON, ResultFormatGo}, partial)
+	}
+
+	if cmd == "vault" {
+		return suffixMatches([]string{"list", "get", "set", "rm", "export", "lock"}, partial)
+	}
+
+	if cmd == "config" {
+		return suffixMatches([]string{"path", "edit", "check"}, partial)
+	}
+
+	if cmd == "theme" {
+		return suffixMatches([]string{"list", "show", "set"}, partial)
+	}
+
+	if cmd == "tasks" {
+		return suffixMatches([]string{"list", "pause", "resume", "run", "remove"}, partial)
+	}
+
+	// Makefile/justfile targets
+	if cmd == "task" || cmd == "make" || cmd == "just" {
+		if wd, err := os.Getwd(); err == nil {
+			if tf, ok := findTaskFile(wd); ok && (cmd == "task" || cmd == tf.Tool) {
+				return suffixMatches(tf.Targets(), partial)
+			}
+		}
+	}
+
 	// For help command
 	if cmd == "help" {
 		topics := []string{"cd", "pwd", "exit", "help", "go", "golang", "yaegi", "substitution", "command"}
@@ -403,6 +486,66 @@ func (g *GoshCompleter) completeArguments(cmd, partial string) [][]rune {
 	return g.completeFiles(partial, false)
 }
 
+// registeredCompletion returns what the completion registered with
+// gosh.RegisterCompletion for the command of words offers
+func (g *GoshCompleter) registeredCompletion(words []string, partial string) ([]string, bool) {
+	if g.state == nil || len(words) == 0 {
+		return nil, false
+	}
+	return g.state.complete(words[0], words[1:], partial)
+}
+
+// completeKube completes context names for kctx and namespaces for kns
+func (g *GoshCompleter) completeKube(cmd, partial string) [][]rune {
+	var candidates []string
+	if cmd == "kctx" {
+		if config, err := loadKubeConfig(kubeconfigPaths(os.Getenv("KUBECONFIG"), os.Getenv("HOME"))); err == nil {
+			for _, c := range config.Contexts {
+				candidates = append(candidates, c.Name)
+			}
+		}
+	} else {
+		candidates = kubeNamespaces(os.Environ())
+	}
+	return suffixMatches(candidates, partial)
+}
+
+// completeProfile completes `profile PROVIDER NAME`
+func (g *GoshCompleter) completeProfile(args []string, partial string) [][]rune {
+	if len(args) == 0 {
+		return suffixMatches([]string{"aws", "gcp"}, partial)
+	}
+	if len(args) > 1 {
+		return nil
+	}
+
+	env := map[string]string{}
+	for _, key := range []string{"HOME", "AWS_CONFIG_FILE", "AWS_SHARED_CREDENTIALS_FILE", "CLOUDSDK_CONFIG"} {
+		if value, ok := os.LookupEnv(key); ok {
+			env[key] = value
+		}
+	}
+
+	switch args[0] {
+	case "aws":
+		return suffixMatches(awsProfiles(env), partial)
+	case "gcp", "gcloud":
+		return suffixMatches(gcloudConfigurations(env), partial)
+	}
+	return nil
+}
+
+// suffixMatches returns the remainder of each candidate that starts with partial
+func suffixMatches(candidates []string, partial string) [][]rune {
+	var matches [][]rune
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, partial) {
+			matches = append(matches, []rune(candidate[len(partial):]))
+		}
+	}
+	return matches
+}
+
 // completeFiles provides file/directory completion
 func (g *GoshCompleter) completeFiles(partial string, dirsOnly bool) [][]rune {
 	var matches [][]rune
@@ -410,7 +553,6 @@ func (g *GoshCompleter) completeFiles(partial string, dirsOnly bool) [][]rune {
 	// Handle ~ expansion separately - detect early to avoid path parsing conflicts
 	var isTildePath bool
 	var homeDir string
-	originalPartialForSuffix := partial
 
 	if strings.HasPrefix(partial, "~") {
 		isTildePath = true
@@ -455,24 +597,9 @@ func (g *GoshCompleter) completeFiles(partial string, dirsOnly bool) [][]rune {
 				completionName += "/"
 			}
 
-			// Calculate the suffix to return
-			var suffix string
-			if lastSlash == -1 {
-				// Simple filename completion - return suffix of the filename
-				suffix = completionName[len(pattern):]
-			} else {
-				// Path completion - reconstruct the path and calculate suffix
-				var completedPath string
-				if isTildePath {
-					// Need to convert back to ~ format for the user
-					userPath := strings.Replace(dir, homeDir, "~", 1)
-					completedPath = userPath + "/" + completionName
-				} else {
-					completedPath = dir + "/" + completionName
-				}
-				suffix = completedPath[len(originalPartialForSuffix):]
-			}
-
+			// The rest of the name after what was typed, whatever the
+			// directory: ~/, / or a relative one
+			suffix := completionName[len(pattern):]
 			matches = append(matches, []rune(suffix))
 		}
 	}
@@ -483,54 +610,24 @@ func (g *GoshCompleter) completeFiles(partial string, dirsOnly bool) [][]rune {
 // getCommandsFromPath finds executables in PATH directories that match partial
 func (g *GoshCompleter) getCommandsFromPath(pathEnv, partial string) [][]rune {
 	var matches [][]rune
-
-	// Split PATH by colon
-	pathDirs := strings.Split(pathEnv, ":")
-	seen := make(map[string]bool) // Avoid duplicates
-
-	for _, dir := range pathDirs {
-		if dir == "" {
-			continue
-		}
-
-		entries, err := os.ReadDir(dir)
-		if err != nil {
-			continue
-		}
-
-		for _, entry := range entries {
-			if entry.IsDir() {
-				continue
-			}
-
-			name := entry.Name()
-
-			// Check if it's executable (Unix check)
-			info, err := entry.Info()
-			if err != nil {
-				continue
-			}
-
-			if info.Mode().Perm()&0111 == 0 { // Not executable
-				continue
-			}
-
-			// Skip if already seen
-			if seen[name] {
-				continue
-			}
-			seen[name] = true
-
-			if strings.HasPrefix(name, partial) {
-				suffix := name[len(partial):]
-				matches = append(matches, []rune(suffix))
-			}
-		}
+	for _, name := range g.commands().Complete(pathEnv, partial) {
+		matches = append(matches, []rune(name[len(partial):]))
 	}
-
 	return matches
 }
 
+// commands returns the index of commands in PATH: the shell's when there
+// is one, so rehash refreshes it too
+func (g *GoshCompleter) commands() *CommandHash {
+	if g.state != nil {
+		return g.state.Commands()
+	}
+	if g.commandHash == nil {
+		g.commandHash = NewCommandHash()
+	}
+	return g.commandHash
+}
+
 // getLocalExecutables finds executables in current directory
 func (g *GoshCompleter) getLocalExecutables(partial string) [][]rune {
 	var matches [][]rune
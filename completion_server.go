@@ -0,0 +1,79 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// runCompleteCommand implements `gosh complete --line "git ch" --point 6`.
+// It runs gosh's completion engine once and prints the full candidates, one
+// per line, so fzf bindings, editors and other shells can consume them.
+func runCompleteCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("complete", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	line := flags.String("line", "", "command line to complete")
+	point := flags.Int("point", -1, "cursor position in the line (defaults to the end)")
+	useLSP := flags.Bool("lsp", false, "use gopls for Go completions (slower startup)")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	runes := []rune(*line)
+	pos := *point
+	if pos < 0 || pos > len(runes) {
+		pos = len(runes)
+	}
+
+	// Set up a shell so the config's gosh.Alias calls define aliases
+	state := NewShellState()
+	evaluator := NewGoEvaluator()
+	evaluator.SetupWithShell(state, NewProcessSpawner(state))
+	if err := evaluator.LoadConfig(); err != nil {
+		debugf("Config loading error: %v\n", err)
+	}
+
+	var completer *GoshCompleter
+	if *useLSP {
+		completer = NewGoshCompleter(evaluator).(*GoshCompleter)
+		defer completer.cleanup()
+	} else {
+		completer = NewGoshCompleterForTesting(evaluator)
+	}
+	completer.state = state
+
+	for _, candidate := range completeLine(completer, runes, pos) {
+		fmt.Fprintln(stdout, candidate)
+	}
+
+	return 0
+}
+
+// completeLine runs the completer and turns its suffix matches back into
+// full candidates for the word under the cursor
+func completeLine(completer *GoshCompleter, line []rune, pos int) []string {
+	matches, length := completer.Do(line, pos)
+
+	partial := ""
+	if length > 0 && length <= pos {
+		partial = string(line[pos-length : pos])
+	}
+
+	seen := make(map[string]bool)
+	var candidates []string
+	for _, match := range matches {
+		candidate := partial + string(match)
+		if candidate == "" || seen[candidate] {
+			continue
+		}
+		seen[candidate] = true
+		candidates = append(candidates, candidate)
+	}
+
+	sort.Strings(candidates)
+	return candidates
+}
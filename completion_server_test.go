@@ -0,0 +1,70 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRunCompleteCommand_Files(t *testing.T) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "notes.txt"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "notebook.md"), []byte("x"), 0644)
+
+	originalDir, _ := os.Getwd()
+	defer os.Chdir(originalDir)
+	os.Chdir(tempDir)
+
+	var stdout, stderr bytes.Buffer
+	code := runCompleteCommand([]string{"--line", "cat note", "--point", "8"}, &stdout, &stderr)
+
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d (stderr: %s)", code, stderr.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 2 || lines[0] != "notebook.md" || lines[1] != "notes.txt" {
+		t.Errorf("Expected full candidates [notebook.md notes.txt], got %q", lines)
+	}
+}
+
+func TestRunCompleteCommand_AbsolutePaths(t *testing.T) {
+	tempDir := t.TempDir()
+	os.Mkdir(filepath.Join(tempDir, "notes"), 0755)
+	os.WriteFile(filepath.Join(tempDir, "notes", "todo.txt"), []byte("x"), 0644)
+
+	tests := []struct {
+		line string
+		want string
+	}{
+		// Directly under the root, where /tmp may be a symlink
+		{"cd /tm", "/tmp"},
+		{"ls /us", "/usr/"},
+		{"cd " + tempDir + "/no", tempDir + "/notes/"},
+		{"cat " + tempDir + "/notes/to", tempDir + "/notes/todo.txt"},
+	}
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		point := fmt.Sprint(len(tt.line))
+		if code := runCompleteCommand([]string{"--line", tt.line, "--point", point}, &stdout, &stderr); code != 0 {
+			t.Fatalf("Expected exit code 0 for %q, got %d (stderr: %s)", tt.line, code, stderr.String())
+		}
+		lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+		if !slices.Contains(lines, tt.want) && !slices.Contains(lines, tt.want+"/") {
+			t.Errorf("Completing %q gave %q, want %s among them", tt.line, lines, tt.want)
+		}
+	}
+}
+
+func TestRunCompleteCommand_BadFlag(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := runCompleteCommand([]string{"--bogus"}, &stdout, &stderr); code != 2 {
+		t.Errorf("Expected exit code 2 for unknown flag, got %d", code)
+	}
+}
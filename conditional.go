@@ -0,0 +1,280 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// test implements the test and [ builtins, which exit with 0 if the
+// expression of their arguments is true, 1 if it's false and 2 if it can't
+// be evaluated. name is the one they were run as.
+func (b *BuiltinHandler) test(name string, args []string) ExecutionResult {
+	if name == "[" {
+		if len(args) == 0 || args[len(args)-1] != "]" {
+			err := errors.New("missing `]'")
+			return ExecutionResult{Output: fmt.Sprintf("[: %v", err), ExitCode: 2, Error: err}
+		}
+		args = args[:len(args)-1]
+	}
+
+	t := &testExpr{state: b.state, args: args}
+	result, err := t.eval()
+	if err == nil && t.pos < len(t.args) {
+		err = fmt.Errorf("%s: unexpected argument", t.args[t.pos])
+	}
+	if err != nil {
+		return ExecutionResult{Output: fmt.Sprintf("%s: %v", name, err), ExitCode: 2, Error: err}
+	}
+	if !result {
+		return ExecutionResult{ExitCode: 1}
+	}
+	return ExecutionResult{ExitCode: 0}
+}
+
+// testExpr evaluates the arguments of test from pos on. -o binds less
+// tightly than -a, which binds less tightly than !, and ( ) group.
+type testExpr struct {
+	state *ShellState
+	args  []string
+	pos   int
+}
+
+// peek returns the argument n after the next one, or "" past the end
+func (t *testExpr) peek(n int) string {
+	if t.pos+n < len(t.args) {
+		return t.args[t.pos+n]
+	}
+	return ""
+}
+
+// remaining returns how many arguments are left
+func (t *testExpr) remaining() int {
+	return len(t.args) - t.pos
+}
+
+func (t *testExpr) eval() (bool, error) {
+	if t.remaining() == 0 {
+		return false, nil
+	}
+	return t.or()
+}
+
+func (t *testExpr) or() (bool, error) {
+	result, err := t.and()
+	for err == nil && t.peek(0) == "-o" && t.remaining() > 1 {
+		t.pos++
+		var right bool
+		right, err = t.and()
+		result = result || right
+	}
+	return result, err
+}
+
+func (t *testExpr) and() (bool, error) {
+	result, err := t.not()
+	for err == nil && t.peek(0) == "-a" && t.remaining() > 1 {
+		t.pos++
+		var right bool
+		right, err = t.not()
+		result = result && right
+	}
+	return result, err
+}
+
+func (t *testExpr) not() (bool, error) {
+	// A lone ! is a non-empty string, as is one before a binary operator
+	if t.peek(0) == "!" && t.remaining() > 1 && !isTestBinary(t.peek(1)) {
+		t.pos++
+		result, err := t.not()
+		return !result, err
+	}
+	return t.primary()
+}
+
+func (t *testExpr) primary() (bool, error) {
+	if t.remaining() == 0 {
+		return false, errors.New("argument expected")
+	}
+
+	if t.peek(0) == "(" && !isTestBinary(t.peek(1)) && t.remaining() > 1 {
+		t.pos++
+		result, err := t.or()
+		if err != nil {
+			return false, err
+		}
+		if t.peek(0) != ")" {
+			return false, errors.New("missing `)'")
+		}
+		t.pos++
+		return result, nil
+	}
+
+	if t.remaining() >= 3 && isTestBinary(t.peek(1)) {
+		left, op, right := t.peek(0), t.peek(1), t.peek(2)
+		t.pos += 3
+		return t.binary(left, op, right)
+	}
+
+	if op := t.peek(0); t.remaining() >= 2 && isTestUnary(op) {
+		operand := t.peek(1)
+		t.pos += 2
+		return t.unary(op, operand)
+	}
+
+	// A string on its own is true if it isn't empty
+	s := t.peek(0)
+	t.pos++
+	return s != "", nil
+}
+
+// isTestUnary reports whether op is a unary operator of test
+func isTestUnary(op string) bool {
+	switch op {
+	case "-n", "-z", "-e", "-f", "-d", "-s", "-r", "-w", "-x", "-L", "-h", "-p", "-S", "-b", "-c":
+		return true
+	}
+	return false
+}
+
+// isTestBinary reports whether op is a binary operator of test
+func isTestBinary(op string) bool {
+	switch op {
+	case "=", "==", "!=", "<", ">", "-eq", "-ne", "-lt", "-le", "-gt", "-ge", "-nt", "-ot", "-ef":
+		return true
+	}
+	return false
+}
+
+func (t *testExpr) unary(op, operand string) (bool, error) {
+	switch op {
+	case "-n":
+		return operand != "", nil
+	case "-z":
+		return operand == "", nil
+	}
+
+	path := operand
+	if t.state != nil {
+		path = resolvePath(t.state.WorkingDirectory, operand)
+	}
+	switch op {
+	case "-r":
+		return syscall.Access(path, 4) == nil, nil
+	case "-w":
+		return syscall.Access(path, 2) == nil, nil
+	case "-x":
+		return syscall.Access(path, 1) == nil, nil
+	case "-L", "-h":
+		info, err := os.Lstat(path)
+		return err == nil && info.Mode()&os.ModeSymlink != 0, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, nil
+	}
+	switch op {
+	case "-f":
+		return info.Mode().IsRegular(), nil
+	case "-d":
+		return info.IsDir(), nil
+	case "-s":
+		return info.Size() > 0, nil
+	case "-p":
+		return info.Mode()&os.ModeNamedPipe != 0, nil
+	case "-S":
+		return info.Mode()&os.ModeSocket != 0, nil
+	case "-b":
+		return info.Mode()&os.ModeDevice != 0 && info.Mode()&os.ModeCharDevice == 0, nil
+	case "-c":
+		return info.Mode()&os.ModeCharDevice != 0, nil
+	}
+	// -e
+	return true, nil
+}
+
+func (t *testExpr) binary(left, op, right string) (bool, error) {
+	switch op {
+	case "=", "==":
+		return left == right, nil
+	case "!=":
+		return left != right, nil
+	case "<":
+		return left < right, nil
+	case ">":
+		return left > right, nil
+	case "-nt", "-ot", "-ef":
+		return t.compareFiles(left, op, right), nil
+	}
+
+	a, err := strconv.ParseInt(strings.TrimSpace(left), 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("%s: integer expression expected", left)
+	}
+	b, err := strconv.ParseInt(strings.TrimSpace(right), 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("%s: integer expression expected", right)
+	}
+	switch op {
+	case "-eq":
+		return a == b, nil
+	case "-ne":
+		return a != b, nil
+	case "-lt":
+		return a < b, nil
+	case "-le":
+		return a <= b, nil
+	case "-gt":
+		return a > b, nil
+	}
+	// -ge
+	return a >= b, nil
+}
+
+// compareFiles compares the files left and right: -nt if left is newer,
+// -ot if it's older, and -ef if they're the same file. A file that doesn't
+// exist is older than one that does.
+func (t *testExpr) compareFiles(left, op, right string) bool {
+	if t.state != nil {
+		left, right = resolvePath(t.state.WorkingDirectory, left), resolvePath(t.state.WorkingDirectory, right)
+	}
+	a, errA := os.Stat(left)
+	b, errB := os.Stat(right)
+	switch op {
+	case "-nt":
+		return errA == nil && (errB != nil || a.ModTime().After(b.ModTime()))
+	case "-ot":
+		return errB == nil && (errA != nil || a.ModTime().Before(b.ModTime()))
+	}
+	return errA == nil && errB == nil && os.SameFile(a, b)
+}
+
+const testHelpText = "test / [ - Evaluate Conditions\n\n" +
+	"USAGE:\n" +
+	"    test EXPRESSION\n" +
+	"    [ EXPRESSION ]\n\n" +
+	"DESCRIPTION:\n" +
+	"    Exits with 0 if EXPRESSION is true, 1 if it's false and 2 if it's\n" +
+	"    malformed, for && and || to act on.\n\n" +
+	"FILES:\n" +
+	"    -e FILE           FILE exists (-f a regular file, -d a directory,\n" +
+	"                      -L a symlink, -p a pipe, -S a socket)\n" +
+	"    -s FILE           FILE isn't empty\n" +
+	"    -r / -w / -x FILE FILE can be read / written / executed\n" +
+	"    A -nt / -ot B     A is newer / older than B (-ef: the same file)\n\n" +
+	"STRINGS:\n" +
+	"    -n S / -z S       S isn't / is empty (S alone: isn't empty)\n" +
+	"    A = B, A != B     Equal, not equal (< and > compare them)\n\n" +
+	"NUMBERS:\n" +
+	"    A -eq B           Also -ne, -lt, -le, -gt and -ge\n\n" +
+	"COMBINING:\n" +
+	"    ! E, E -a E, E -o E, \\( E \\)\n\n" +
+	"EXAMPLES:\n" +
+	"    [ -f go.mod ] && go build ./...\n" +
+	"    test \"$#\" -ge 1 || echo \"usage: deploy ENV\""
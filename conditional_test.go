@@ -0,0 +1,77 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTestBuiltin(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "old.txt"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(dir, "empty"), nil, 0755)
+	os.Chtimes(filepath.Join(dir, "old.txt"), time.Now().Add(-time.Hour), time.Now().Add(-time.Hour))
+	os.Mkdir(filepath.Join(dir, "src"), 0755)
+	os.Symlink("old.txt", filepath.Join(dir, "link"))
+	b := NewBuiltinHandler(&ShellState{WorkingDirectory: dir})
+
+	tests := []struct {
+		args     []string
+		exitCode int
+	}{
+		{nil, 1},
+		{[]string{""}, 1},
+		{[]string{"x"}, 0},
+		{[]string{"-n"}, 0},
+		{[]string{"!"}, 0},
+		{[]string{"-e", "old.txt"}, 0},
+		{[]string{"-e", "missing"}, 1},
+		{[]string{"-f", "old.txt"}, 0},
+		{[]string{"-f", "src"}, 1},
+		{[]string{"-d", "src"}, 0},
+		{[]string{"-s", "old.txt"}, 0},
+		{[]string{"-s", "empty"}, 1},
+		{[]string{"-x", "empty"}, 0},
+		{[]string{"-L", "link"}, 0},
+		{[]string{"-L", "old.txt"}, 1},
+		{[]string{"empty", "-nt", "old.txt"}, 0},
+		{[]string{"old.txt", "-nt", "empty"}, 1},
+		{[]string{"old.txt", "-ot", "empty"}, 0},
+		{[]string{"link", "-ef", "old.txt"}, 0},
+		{[]string{"-z", ""}, 0},
+		{[]string{"-n", ""}, 1},
+		{[]string{"a", "=", "a"}, 0},
+		{[]string{"a", "==", "b"}, 1},
+		{[]string{"a", "!=", "b"}, 0},
+		{[]string{"a", "<", "b"}, 0},
+		{[]string{"10", "-gt", "9"}, 0},
+		{[]string{"10", "-lt", "9"}, 1},
+		{[]string{"-3", "-le", "-3"}, 0},
+		{[]string{"!", "-d", "src"}, 1},
+		{[]string{"!", "=", "!"}, 0},
+		{[]string{"-f", "old.txt", "-a", "-d", "src"}, 0},
+		{[]string{"-f", "src", "-o", "-d", "src"}, 0},
+		{[]string{"x", "-o", "", "-a", ""}, 0},
+		{[]string{"!", "(", "a", "=", "a", "-o", "b", "=", "c", ")"}, 1},
+		// Malformed
+		{[]string{"1", "-eq", "one"}, 2},
+		{[]string{"(", "x"}, 2},
+		{[]string{"a", "b"}, 2},
+		{[]string{"-f", "old.txt", "-a"}, 2},
+	}
+	for _, tt := range tests {
+		if result := b.Execute("test", tt.args); result.ExitCode != tt.exitCode {
+			t.Errorf("test %q exited with %d, want %d (%s)", tt.args, result.ExitCode, tt.exitCode, result.Output)
+		}
+	}
+
+	if result := b.Execute("[", []string{"-d", "src", "]"}); result.ExitCode != 0 {
+		t.Errorf("Expected [ -d src ] to be true, got %d (%s)", result.ExitCode, result.Output)
+	}
+	if result := b.Execute("[", []string{"-d", "src"}); result.ExitCode != 2 || result.Output != "[: missing `]'" {
+		t.Errorf("Expected [ without ] to fail, got %d (%s)", result.ExitCode, result.Output)
+	}
+}
@@ -0,0 +1,210 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"os"
+	"strings"
+)
+
+// configUsage is the usage of the config builtin
+const configUsage = "Usage: config [path|edit [-r] [home|project]|check [home|project|FILE...]]"
+
+// config implements the config builtin
+func (b *BuiltinHandler) config(args []string) ExecutionResult {
+	if len(args) == 0 {
+		args = []string{"path"}
+	}
+	if b.evaluator == nil {
+		err := errors.New("no Go interpreter")
+		return ExecutionResult{Output: fmt.Sprintf("config: %v", err), ExitCode: 1, Error: err}
+	}
+
+	switch args[0] {
+	case "path":
+		target := ""
+		switch len(args) {
+		case 1:
+		case 2:
+			target = args[1]
+		default:
+			return configUsageError(errors.New("too many arguments"))
+		}
+		path, err := b.configPath(target)
+		if err != nil {
+			return configUsageError(err)
+		}
+		return ExecutionResult{Output: path, ExitCode: 0}
+	case "edit":
+		return b.editConfig(args[1:])
+	case "check":
+		return b.checkConfigs(args[1:])
+	}
+	return configUsageError(fmt.Errorf("unknown subcommand: %s", args[0]))
+}
+
+// configUsageError is the config builtin's result for err, with its usage
+func configUsageError(err error) ExecutionResult {
+	return ExecutionResult{Output: fmt.Sprintf("config: %v\n%s", err, configUsage), ExitCode: 1, Error: err}
+}
+
+// configPath returns the path of the config file target names, home or
+// project. For "" it's the active one: the project config if the current
+// directory has one, and the home config otherwise.
+func (b *BuiltinHandler) configPath(target string) (string, error) {
+	switch target {
+	case "", "project":
+		if path := b.evaluator.getProjectConfigPath(); path != "" {
+			return path, nil
+		}
+		if target == "project" {
+			return "", errors.New("no project config (.goshconfig.go or gosh.config.go) here")
+		}
+		fallthrough
+	case "home":
+		path := b.evaluator.getHomeConfigPath()
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("no home config at %s (run init to create one)", path)
+		}
+		return path, nil
+	}
+	return "", fmt.Errorf("unknown config: %s (use home or project)", target)
+}
+
+// editConfig opens a config file in $VISUAL or $EDITOR. With -r the
+// config files are loaded again if it was changed and still parses.
+func (b *BuiltinHandler) editConfig(args []string) ExecutionResult {
+	reload, target := false, ""
+	for _, arg := range args {
+		switch {
+		case arg == "-r" || arg == "--reload":
+			reload = true
+		case strings.HasPrefix(arg, "-") || target != "":
+			return configUsageError(fmt.Errorf("unexpected argument: %s", arg))
+		default:
+			target = arg
+		}
+	}
+
+	path, err := b.configPath(target)
+	if err != nil {
+		return configUsageError(err)
+	}
+	before, err := os.ReadFile(path)
+	if err != nil {
+		return ExecutionResult{Output: fmt.Sprintf("config: %v", err), ExitCode: 1, Error: err}
+	}
+
+	cmd := editorCommand(b.state.Environment, path, 0, 0)
+	cmd.Dir = b.state.WorkingDirectory
+	cmd.Env = b.state.EnvironmentSlice()
+	if err := runOnTerminal(cmd); err != nil {
+		return ExecutionResult{Output: fmt.Sprintf("config: failed to open editor: %v", err), ExitCode: 1, Error: err}
+	}
+
+	after, err := os.ReadFile(path)
+	if err != nil {
+		return ExecutionResult{Output: fmt.Sprintf("config: %v", err), ExitCode: 1, Error: err}
+	}
+	if bytes.Equal(before, after) {
+		return ExecutionResult{Output: "", ExitCode: 0}
+	}
+	if err := checkConfig(path); err != nil {
+		return ExecutionResult{Output: "✗ " + describeSyntaxErrors(err) + "\nNot reloaded: fix the config and run reload", ExitCode: 1, Error: err}
+	}
+	if !reload {
+		return ExecutionResult{Output: "Config changed; run reload to use it", ExitCode: 0}
+	}
+	return b.reload(nil)
+}
+
+// checkConfigs checks the syntax of the config files args name, or of all
+// those there are: the home config, plugins and project config
+func (b *BuiltinHandler) checkConfigs(args []string) ExecutionResult {
+	var paths []string
+	for _, arg := range args {
+		switch arg {
+		case "home", "project":
+			path, err := b.configPath(arg)
+			if err != nil {
+				return configUsageError(err)
+			}
+			paths = append(paths, path)
+		default:
+			paths = append(paths, resolvePath(b.state.WorkingDirectory, arg))
+		}
+	}
+	if len(args) == 0 {
+		if path := b.evaluator.getHomeConfigPath(); path != "" {
+			if _, err := os.Stat(path); err == nil {
+				paths = append(paths, path)
+			}
+		}
+		paths = append(paths, pluginPaths(goshConfigPath("plugins"))...)
+		if path := b.evaluator.getProjectConfigPath(); path != "" {
+			paths = append(paths, path)
+		}
+		if len(paths) == 0 {
+			return ExecutionResult{Output: "No config files to check", ExitCode: 0}
+		}
+	}
+
+	var lines []string
+	var errs []error
+	for _, path := range paths {
+		if err := checkConfig(path); err != nil {
+			lines = append(lines, "✗ "+describeSyntaxErrors(err))
+			errs = append(errs, err)
+			continue
+		}
+		lines = append(lines, "✓ "+path)
+	}
+	if len(errs) > 0 {
+		return ExecutionResult{Output: strings.Join(lines, "\n"), ExitCode: 1, Error: errors.Join(errs...)}
+	}
+	return ExecutionResult{Output: strings.Join(lines, "\n"), ExitCode: 0}
+}
+
+// checkConfig parses the config file at path, returning its syntax errors
+// as a scanner.ErrorList
+func checkConfig(path string) error {
+	_, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.AllErrors)
+	return err
+}
+
+// describeSyntaxErrors lists the errors of checkConfig, one a line
+func describeSyntaxErrors(err error) string {
+	var list scanner.ErrorList
+	if !errors.As(err, &list) {
+		return err.Error()
+	}
+	lines := make([]string, len(list))
+	for i, e := range list {
+		lines[i] = e.Error()
+	}
+	return strings.Join(lines, "\n  ")
+}
+
+const configHelpText = "config - Manage the Config Files\n\n" +
+	"USAGE:\n" +
+	"    config path [home|project]     Print the path of a config file\n" +
+	"    config edit [-r] [home|project]\n" +
+	"                                   Open a config file in $EDITOR\n" +
+	"    config check [home|project|FILE...]\n" +
+	"                                   Check config files for syntax errors\n\n" +
+	"DESCRIPTION:\n" +
+	"    Without home or project, path and edit use the active config: the\n" +
+	"    project config (.goshconfig.go or gosh.config.go) if the current\n" +
+	"    directory has one, and ~/.config/gosh/config.go otherwise. check\n" +
+	"    checks every config file, plugins included.\n\n" +
+	"    After editing, the config is checked, and with -r (--reload) loaded\n" +
+	"    again as reload does if it parses; without -r, run reload yourself.\n\n" +
+	"EXAMPLES:\n" +
+	"    config edit -r\n" +
+	"    config check"
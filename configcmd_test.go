@@ -0,0 +1,79 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Chdir(home)
+	configPath := filepath.Join(home, ".config", "gosh", "config.go")
+	os.MkdirAll(filepath.Dir(configPath), 0755)
+	os.WriteFile(configPath, []byte("package main\n\nfunc greet() string { return \"hello\" }\n"), 0644)
+
+	state := NewShellState()
+	g := NewGoEvaluator()
+	builtins := NewBuiltinHandler(state)
+	g.SetupWithShell(state, NewProcessSpawner(state))
+	g.SetupWithBuiltins(builtins)
+	if err := g.LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	if result := builtins.Execute("config", []string{"path"}); result.Output != configPath {
+		t.Errorf("config path = %q, want %q", result.Output, configPath)
+	}
+	if result := builtins.Execute("config", []string{"path", "project"}); result.ExitCode != 1 {
+		t.Errorf("config path project = %q without a project config", result.Output)
+	}
+
+	// An editor that adds the function $FUNC to the file it's given
+	editor := filepath.Join(home, "editor.sh")
+	os.WriteFile(editor, []byte("#!/bin/sh\nprintf 'func %s() string { return \"bye\" }\\n' \"$FUNC\" >> \"$1\"\n"), 0755)
+	state.Environment["EDITOR"] = editor
+	state.Environment["FUNC"] = "farewell"
+	delete(state.Environment, "VISUAL")
+
+	result := builtins.Execute("config", []string{"edit"})
+	if result.ExitCode != 0 || result.Output != "Config changed; run reload to use it" {
+		t.Errorf("config edit = %q (exit %d)", result.Output, result.ExitCode)
+	}
+	state.Environment["FUNC"] = "later"
+	result = builtins.Execute("config", []string{"edit", "-r"})
+	if result.ExitCode != 0 || result.Output != "Reloaded home config\n  added:   farewell, later" {
+		t.Errorf("config edit -r = %q (exit %d)", result.Output, result.ExitCode)
+	}
+	if result := g.Eval("farewell()"); result.Output != "bye" {
+		t.Errorf("farewell() = %q after config edit -r", result.Output)
+	}
+
+	// A project config is the active one where there is one
+	project := filepath.Join(home, "project")
+	os.Mkdir(project, 0755)
+	t.Chdir(project)
+	os.WriteFile(filepath.Join(project, ".goshconfig.go"), []byte("package main\n\nfunc broken( {\n"), 0644)
+	projectPath, _ := filepath.Abs(".goshconfig.go")
+	if result := builtins.Execute("config", nil); result.Output != projectPath {
+		t.Errorf("config = %q, want %q", result.Output, projectPath)
+	}
+
+	result = builtins.Execute("config", []string{"check"})
+	if result.ExitCode != 1 || !strings.HasPrefix(result.Output, "✓ "+configPath+"\n✗ "+projectPath+":3:14: ") {
+		t.Errorf("config check = %q (exit %d)", result.Output, result.ExitCode)
+	}
+	if result := builtins.Execute("config", []string{"check", "home"}); result.ExitCode != 0 || result.Output != "✓ "+configPath {
+		t.Errorf("config check home = %q (exit %d)", result.Output, result.ExitCode)
+	}
+
+	for _, args := range [][]string{{"open"}, {"path", "work"}, {"edit", "-x"}, {"edit", "home", "project"}} {
+		if result := builtins.Execute("config", args); result.ExitCode != 1 {
+			t.Errorf("config %q = %q (exit %d), want an error", args, result.Output, result.ExitCode)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+// goshLibShellapi is the import path config files use for the shellapi
+// package, which the interpreter provides as gosh_lib/shellapi, with
+// gosh_lib's RunShell
+const goshLibShellapi = "github.com/rsarv3006/gosh_lib/shellapi"
+
+// commonPackages are the standard library packages newInterpreter
+// imports, to which a config adds with gosh.PreImport
+var commonPackages = []string{"fmt", "os", "strings", "strconv", "path/filepath"}
+
+// preImportedPackages are the packages newInterpreter imports, which a
+// config can't import again
+var preImportedPackages = append(slices.Clone(commonPackages), "gosh")
+
+// configSource turns a config file into code for the interpreter: the
+// package clause and the imports of packages in imported are dropped, and
+// gosh_lib's shellapi import points at the interpreter's package. These
+// are blanked out in place, so the lines and columns of errors still match
+// the file.
+func configSource(filename string, src []byte, imported map[string]bool) (string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ImportsOnly)
+	if err != nil {
+		return "", err
+	}
+
+	code := append([]byte(nil), src...)
+	offset := func(pos token.Pos) int { return fset.Position(pos).Offset }
+	blank(code, offset(file.Package), offset(file.Name.End()))
+
+	blankImports(code, file, offset, func(spec *ast.ImportSpec) bool {
+		importPath, _ := strconv.Unquote(spec.Path.Value)
+		if importPath == goshLibShellapi {
+			start, end := offset(spec.Path.Pos()), offset(spec.Path.End())
+			// The shorter path, padded so nothing after it moves
+			replacement := strconv.Quote("gosh_lib/shellapi")
+			copy(code[start:end], replacement)
+			blank(code, start+len(replacement), end)
+			importPath = "gosh_lib/shellapi"
+		}
+		// Only imports under the package's own name are the same import
+		return imported[importPath] && (spec.Name == nil || spec.Name.Name == path.Base(importPath))
+	})
+	return string(code), nil
+}
+
+// blankImports blanks out the import specs of file that drop reports,
+// and whole import declarations if none of their specs are left
+func blankImports(code []byte, file *ast.File, offset func(token.Pos) int, drop func(*ast.ImportSpec) bool) {
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		kept := 0
+		for _, spec := range gen.Specs {
+			if drop(spec.(*ast.ImportSpec)) {
+				blank(code, offset(spec.Pos()), withSemicolon(code, offset(spec.End())))
+			} else {
+				kept++
+			}
+		}
+		if kept == 0 {
+			blank(code, offset(gen.Pos()), withSemicolon(code, offset(gen.End())))
+		}
+	}
+}
+
+// withSemicolon returns end moved past the semicolon after it, if there's
+// one on the same line, which would be left on its own without what's
+// before it
+func withSemicolon(code []byte, end int) int {
+	if rest := bytes.TrimLeft(code[end:], " \t"); len(rest) > 0 && rest[0] == ';' {
+		return len(code) - len(rest) + 1
+	}
+	return end
+}
+
+// blank replaces code[start:end] with spaces, keeping line breaks
+func blank(code []byte, start, end int) {
+	for i := start; i < end; i++ {
+		if code[i] != '\n' {
+			code[i] = ' '
+		}
+	}
+}
+
+// interpPosition matches the position yaegi puts at the start of its
+// errors, with or without its placeholder file name
+var interpPosition = regexp.MustCompile(`^(?:_\.go:)?(\d+:\d+: )`)
+
+// configError puts the config file's name on a position at the start of
+// an interpreter error, so it reads like a compiler error
+func configError(filename string, err error) error {
+	if interpPosition.MatchString(err.Error()) {
+		return fmt.Errorf("%s", interpPosition.ReplaceAllString(err.Error(), filename+":$1"))
+	}
+	return err
+}
+
+// stripImports drops gosh_lib's shellapi import from config code, which
+// needn't have a package clause. Code that doesn't parse is returned as it
+// is.
+func (g *GoEvaluator) stripImports(code string) string {
+	const clause = "package main;"
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", clause+code, parser.ImportsOnly)
+	if err != nil {
+		return code
+	}
+
+	stripped := []byte(code)
+	offset := func(pos token.Pos) int { return fset.Position(pos).Offset - len(clause) }
+	blankImports(stripped, file, offset, func(spec *ast.ImportSpec) bool {
+		importPath, _ := strconv.Unquote(spec.Path.Value)
+		return importPath == goshLibShellapi
+	})
+	return string(stripped)
+}
+
+// dropImported blanks out the imports of code, typed in the REPL, of
+// packages in imported, which can't be imported again. Code that doesn't
+// parse is returned as it is.
+func dropImported(code string, imported map[string]bool) string {
+	const clause = "package main;"
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", clause+code, parser.ImportsOnly)
+	if err != nil || len(file.Imports) == 0 {
+		return code
+	}
+
+	stripped := []byte(code)
+	offset := func(pos token.Pos) int { return fset.Position(pos).Offset - len(clause) }
+	blankImports(stripped, file, offset, func(spec *ast.ImportSpec) bool {
+		importPath, _ := strconv.Unquote(spec.Path.Value)
+		return imported[importPath] && (spec.Name == nil || spec.Name.Name == path.Base(importPath))
+	})
+	return string(stripped)
+}
+
+// preImported returns the set of preImportedPackages
+func preImported() map[string]bool {
+	imported := map[string]bool{}
+	for _, path := range preImportedPackages {
+		imported[path] = true
+	}
+	return imported
+}
@@ -0,0 +1,201 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DirStack returns the directory stack as dirs shows it: the working
+// directory, then the directories saved by pushd, most recent first
+func (s *ShellState) DirStack() []string {
+	return append([]string{s.WorkingDirectory}, s.dirStack...)
+}
+
+// abbreviateHome shows a directory under HOME as ~/...
+func (s *ShellState) abbreviateHome(dir string) string {
+	home := s.Environment["HOME"]
+	if home == "" || home == "/" {
+		return dir
+	}
+	if dir == home {
+		return "~"
+	}
+	if rest, ok := strings.CutPrefix(dir, home+string(filepath.Separator)); ok {
+		return "~/" + rest
+	}
+	return dir
+}
+
+// stackIndex parses a +N or -N argument of pushd and popd, counting from
+// the top of a stack of size entries or from the bottom. ok is false if arg
+// isn't one.
+func stackIndex(arg string, size int) (index int, ok bool, err error) {
+	if len(arg) < 2 || (arg[0] != '+' && arg[0] != '-') {
+		return 0, false, nil
+	}
+	n, convErr := strconv.Atoi(arg[1:])
+	if convErr != nil {
+		return 0, false, nil
+	}
+	if n >= size {
+		return 0, true, fmt.Errorf("%s: directory stack index out of range", arg)
+	}
+	if arg[0] == '-' {
+		n = size - 1 - n
+	}
+	return n, true, nil
+}
+
+// pushd implements the pushd builtin
+func (b *BuiltinHandler) pushd(args []string) ExecutionResult {
+	if len(args) > 1 {
+		return ExecutionResult{Output: "pushd: too many arguments\nUsage: pushd [DIR|+N|-N]", ExitCode: 1, Error: fmt.Errorf("too many arguments")}
+	}
+
+	stack := b.state.DirStack()
+	var result ExecutionResult
+	switch {
+	case len(args) == 0:
+		// Swap the top two directories
+		if len(stack) < 2 {
+			err := fmt.Errorf("no other directory")
+			return ExecutionResult{Output: fmt.Sprintf("pushd: %v", err), ExitCode: 1, Error: err}
+		}
+		result = b.changeDirectory("pushd", stack[1])
+		if result.ExitCode == 0 {
+			b.state.dirStack = append([]string{stack[0]}, stack[2:]...)
+		}
+	default:
+		index, ok, err := stackIndex(args[0], len(stack))
+		if err != nil {
+			return ExecutionResult{Output: fmt.Sprintf("pushd: %v", err), ExitCode: 1, Error: err}
+		}
+		if !ok {
+			result = b.changeDirectory("pushd", args[0])
+			if result.ExitCode == 0 {
+				b.state.dirStack = stack
+			}
+			break
+		}
+
+		// Rotate the stack so the Nth directory is on top
+		rotated := append(stack[index:len(stack):len(stack)], stack[:index]...)
+		result = b.changeDirectory("pushd", rotated[0])
+		if result.ExitCode == 0 {
+			b.state.dirStack = rotated[1:]
+		}
+	}
+	return b.withDirs(result)
+}
+
+// popd implements the popd builtin
+func (b *BuiltinHandler) popd(args []string) ExecutionResult {
+	if len(args) > 1 {
+		return ExecutionResult{Output: "popd: too many arguments\nUsage: popd [+N|-N]", ExitCode: 1, Error: fmt.Errorf("too many arguments")}
+	}
+	stack := b.state.DirStack()
+	if len(stack) < 2 {
+		err := fmt.Errorf("directory stack empty")
+		return ExecutionResult{Output: fmt.Sprintf("popd: %v", err), ExitCode: 1, Error: err}
+	}
+
+	index := 0
+	if len(args) == 1 {
+		var ok bool
+		var err error
+		index, ok, err = stackIndex(args[0], len(stack))
+		if !ok && err == nil {
+			err = fmt.Errorf("%s: invalid argument", args[0])
+		}
+		if err != nil {
+			return ExecutionResult{Output: fmt.Sprintf("popd: %v\nUsage: popd [+N|-N]", err), ExitCode: 1, Error: err}
+		}
+	}
+
+	if index > 0 {
+		// Drop a saved directory without changing to another
+		b.state.dirStack = append(stack[1:index:index], stack[index+1:]...)
+		return b.withDirs(ExecutionResult{ExitCode: 0})
+	}
+
+	result := b.changeDirectory("popd", stack[1])
+	if result.ExitCode == 0 {
+		b.state.dirStack = stack[2:]
+	}
+	return b.withDirs(result)
+}
+
+// withDirs adds the directory stack to the result of a successful pushd or
+// popd, as other shells print it
+func (b *BuiltinHandler) withDirs(result ExecutionResult) ExecutionResult {
+	if result.ExitCode != 0 {
+		return result
+	}
+	stack := b.dirs(nil).Output
+	if result.Output != "" {
+		stack += "\n" + result.Output
+	}
+	result.Output = stack
+	return result
+}
+
+// dirs implements the dirs builtin
+func (b *BuiltinHandler) dirs(args []string) ExecutionResult {
+	long, perLine, numbered := false, false, false
+	for _, arg := range args {
+		switch arg {
+		case "-c":
+			b.state.dirStack = nil
+			return ExecutionResult{Output: "", ExitCode: 0}
+		case "-l":
+			long = true
+		case "-p":
+			perLine = true
+		case "-v":
+			perLine, numbered = true, true
+		default:
+			err := fmt.Errorf("unknown option: %s", arg)
+			return ExecutionResult{Output: fmt.Sprintf("dirs: %v\nUsage: dirs [-c|-l|-p|-v]", err), ExitCode: 1, Error: err}
+		}
+	}
+
+	stack := b.state.DirStack()
+	for i, dir := range stack {
+		if !long {
+			dir = b.state.abbreviateHome(dir)
+		}
+		if numbered {
+			dir = fmt.Sprintf("%2d  %s", i, dir)
+		}
+		stack[i] = dir
+	}
+	separator := " "
+	if perLine {
+		separator = "\n"
+	}
+	return ExecutionResult{Output: strings.Join(stack, separator), ExitCode: 0}
+}
+
+const dirsHelpText = "pushd / popd / dirs - Directory Stack\n\n" +
+	"USAGE:\n" +
+	"    pushd DIR         Save the current directory and change to DIR\n" +
+	"    pushd             Swap the current directory with the last one saved\n" +
+	"    pushd +N|-N       Rotate the stack to bring its Nth directory to the top\n" +
+	"    popd              Change back to the last directory saved\n" +
+	"    popd +N|-N        Drop the Nth directory from the stack\n" +
+	"    dirs [-l|-p|-v]   Show the stack: -l without ~, -p one per line,\n" +
+	"                      -v one per line with numbers\n" +
+	"    dirs -c           Clear the stack\n\n" +
+	"DESCRIPTION:\n" +
+	"    The stack starts with the current directory, and N counts from it\n" +
+	"    (+N) or from the bottom (-N) as dirs -v shows. pushd and popd print\n" +
+	"    the stack after changing it.\n\n" +
+	"EXAMPLES:\n" +
+	"    pushd ~/src/api       # Work in api, remembering where you were\n" +
+	"    pushd ~/src/web       # And again\n" +
+	"    pushd                 # Back to api, web saved\n" +
+	"    popd                  # Back to web"
@@ -0,0 +1,75 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDirStackBuiltins(t *testing.T) {
+	home, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{"api", "web", "docs"} {
+		os.Mkdir(filepath.Join(home, dir), 0755)
+	}
+	t.Chdir(home)
+	state := &ShellState{WorkingDirectory: home, Environment: map[string]string{"HOME": home}}
+	b := NewBuiltinHandler(state)
+
+	run := func(command string, args ...string) string {
+		t.Helper()
+		result := b.Execute(command, args)
+		if result.ExitCode != 0 {
+			t.Fatalf("%s %v failed: %s", command, args, result.Output)
+		}
+		return result.Output
+	}
+
+	if got := run("pushd", "api"); got != "~/api ~" {
+		t.Errorf("pushd api printed %q", got)
+	}
+	if got := run("pushd", "../web"); got != "~/web ~/api ~" {
+		t.Errorf("pushd ../web printed %q", got)
+	}
+	if got := run("pushd"); got != "~/api ~/web ~" {
+		t.Errorf("pushd printed %q", got)
+	}
+	if current, _ := os.Getwd(); current != filepath.Join(home, "api") || state.WorkingDirectory != current {
+		t.Errorf("Expected to be in api, in %s (state %s)", current, state.WorkingDirectory)
+	}
+	if got := run("pushd", "+2"); got != "~ ~/api ~/web" {
+		t.Errorf("pushd +2 printed %q", got)
+	}
+	if got := run("dirs", "-v"); got != " 0  ~\n 1  ~/api\n 2  ~/web" {
+		t.Errorf("dirs -v printed %q", got)
+	}
+	if got := run("dirs", "-l"); got != strings.Join([]string{home, home + "/api", home + "/web"}, " ") {
+		t.Errorf("dirs -l printed %q", got)
+	}
+	if got := run("popd", "-0"); got != "~ ~/api" {
+		t.Errorf("popd -0 printed %q", got)
+	}
+	if got := run("popd"); got != "~/api" {
+		t.Errorf("popd printed %q", got)
+	}
+	if state.WorkingDirectory != filepath.Join(home, "api") {
+		t.Errorf("Expected popd to change to api, in %s", state.WorkingDirectory)
+	}
+
+	for _, args := range [][]string{{"popd"}, {"pushd"}, {"pushd", "+5"}, {"pushd", "missing"}, {"dirs", "-x"}} {
+		if result := b.Execute(args[0], args[1:]); result.ExitCode != 1 || !strings.HasPrefix(result.Output, args[0]+": ") {
+			t.Errorf("%v: expected an error, got %q (exit %d)", args, result.Output, result.ExitCode)
+		}
+	}
+
+	run("pushd", home+"/docs")
+	run("dirs", "-c")
+	if got := run("dirs"); got != "~/docs" {
+		t.Errorf("Expected dirs -c to clear the stack, got %q", got)
+	}
+}
@@ -0,0 +1,306 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// backslashEscapes are the single-character escapes of echo -e and printf
+var backslashEscapes = map[byte]string{
+	'\\': "\\", 'a': "\a", 'b': "\b", 'e': "\x1b", 'E': "\x1b",
+	'f': "\f", 'n': "\n", 'r': "\r", 't': "\t", 'v': "\v",
+}
+
+// expandBackslashes interprets the escapes of s. In echo style, octal
+// escapes start with \0 as in \0NNN; otherwise they're \NNN as printf's
+// format has them. stop reports a \c, which ends all output, and s is cut
+// before it.
+func expandBackslashes(s string, echoStyle bool) (expanded string, stop bool) {
+	var sb strings.Builder
+	for i := 0; i < len(s); {
+		if s[i] != '\\' {
+			sb.WriteByte(s[i])
+			i++
+			continue
+		}
+		var text string
+		if text, i, stop = expandEscape(s, i, echoStyle); stop {
+			break
+		}
+		sb.WriteString(text)
+	}
+	return sb.String(), stop
+}
+
+// expandEscape interprets the escape at s[i], returning its text and the
+// index after it. Unknown escapes are kept as they are.
+func expandEscape(s string, i int, echoStyle bool) (text string, next int, stop bool) {
+	if i+1 == len(s) {
+		return "\\", i + 1, false
+	}
+	c := s[i+1]
+	if text, ok := backslashEscapes[c]; ok {
+		return text, i + 2, false
+	}
+
+	// The digits of an octal or hex escape, and how many there may be
+	start, maxDigits, base, isDigit := i+1, 3, 8, func(c byte) bool { return c >= '0' && c <= '7' }
+	switch {
+	case c == 'c':
+		return "", i + 2, true
+	case c == '0' && echoStyle:
+		start = i + 2
+	case c >= '1' && c <= '7' && !echoStyle, c == '0':
+	case c == 'x':
+		start, maxDigits, base, isDigit = i+2, 2, 16, isHexDigit
+	default:
+		return s[i : i+2], i + 2, false
+	}
+
+	end := start
+	for end < len(s) && end-start < maxDigits && isDigit(s[end]) {
+		end++
+	}
+	if c == 'x' && end == start {
+		return "\\x", end, false
+	}
+	value, _ := strconv.ParseUint("0"+s[start:end], base, 16)
+	return string([]byte{byte(value)}), end, false
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// echo implements the echo builtin. Options are only taken from the start
+// of the arguments, and an argument such as -x that isn't made of n, e and
+// E is printed, as with /bin/echo.
+func (b *BuiltinHandler) echo(args []string) ExecutionResult {
+	newline, escapes := true, false
+	for len(args) > 0 && len(args[0]) > 1 && args[0][0] == '-' && strings.Trim(args[0][1:], "neE") == "" {
+		for _, option := range args[0][1:] {
+			switch option {
+			case 'n':
+				newline = false
+			case 'e':
+				escapes = true
+			case 'E':
+				escapes = false
+			}
+		}
+		args = args[1:]
+	}
+
+	output := strings.Join(args, " ")
+	if escapes {
+		var stop bool
+		if output, stop = expandBackslashes(output, true); stop {
+			newline = false
+		}
+	}
+	if newline {
+		output += "\n"
+	}
+	return ExecutionResult{Output: output, ExitCode: 0, partialLine: !newline}
+}
+
+// printfArgs hands out printf's arguments to the directives of its format
+type printfArgs struct {
+	args []string
+	used int
+	errs []string
+}
+
+func (a *printfArgs) next() (string, bool) {
+	if a.used == len(a.args) {
+		return "", false
+	}
+	a.used++
+	return a.args[a.used-1], true
+}
+
+// integer returns the next argument as a number. As in other shells, 'c
+// or "c stands for the code of the character c.
+func (a *printfArgs) integer() int64 {
+	arg, ok := a.next()
+	if !ok || arg == "" {
+		return 0
+	}
+	if arg[0] == '\'' || arg[0] == '"' {
+		r, _ := utf8.DecodeRuneInString(arg[1:])
+		return int64(r)
+	}
+	n, err := strconv.ParseInt(arg, 0, 64)
+	if err != nil {
+		if u, uerr := strconv.ParseUint(arg, 0, 64); uerr == nil {
+			return int64(u)
+		}
+		a.errs = append(a.errs, fmt.Sprintf("printf: %s: invalid number", arg))
+	}
+	return n
+}
+
+func (a *printfArgs) float() float64 {
+	arg, ok := a.next()
+	if !ok || arg == "" {
+		return 0
+	}
+	if arg[0] == '\'' || arg[0] == '"' {
+		r, _ := utf8.DecodeRuneInString(arg[1:])
+		return float64(r)
+	}
+	f, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		a.errs = append(a.errs, fmt.Sprintf("printf: %s: invalid number", arg))
+	}
+	return f
+}
+
+// formatPrintf formats args with a printf(1) format. The format is reused
+// while arguments are left over. stop reports a \c in the format or in a
+// %b argument, which ends the output there.
+func formatPrintf(format string, args []string) (output string, errs []string, stop bool) {
+	var sb strings.Builder
+	a := &printfArgs{args: args}
+
+	for {
+		before := a.used
+		if stop = formatPrintfOnce(&sb, format, a); stop {
+			break
+		}
+		// Once the arguments run out, or if the format takes none
+		if a.used == len(a.args) || a.used == before {
+			break
+		}
+	}
+	return sb.String(), a.errs, stop
+}
+
+// formatPrintfOnce writes one pass of the format to sb
+func formatPrintfOnce(sb *strings.Builder, format string, a *printfArgs) (stop bool) {
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		if c == '\\' {
+			text, next, stop := expandEscape(format, i, false)
+			if stop {
+				return true
+			}
+			sb.WriteString(text)
+			i = next - 1
+			continue
+		}
+		if c != '%' || i+1 == len(format) {
+			sb.WriteByte(c)
+			continue
+		}
+
+		// %[flags][width][.precision]verb
+		start := i
+		i++
+		for i < len(format) && strings.IndexByte("-+ #0", format[i]) >= 0 {
+			i++
+		}
+		// A width or precision of * is taken from the arguments
+		number := func() string {
+			if i < len(format) && format[i] == '*' {
+				i++
+				return strconv.FormatInt(a.integer(), 10)
+			}
+			digits := i
+			for i < len(format) && format[i] >= '0' && format[i] <= '9' {
+				i++
+			}
+			return format[digits:i]
+		}
+		spec := format[start:i] + number()
+		if i < len(format) && format[i] == '.' {
+			i++
+			spec += "." + number()
+		}
+		if i == len(format) {
+			sb.WriteString(format[start:])
+			return false
+		}
+
+		switch verb := format[i]; verb {
+		case '%':
+			sb.WriteByte('%')
+		case 'd', 'i':
+			fmt.Fprintf(sb, spec+"d", a.integer())
+		case 'u':
+			fmt.Fprintf(sb, spec+"d", uint64(a.integer()))
+		case 'o', 'x', 'X':
+			fmt.Fprintf(sb, spec+string(verb), uint64(a.integer()))
+		case 'f', 'F', 'e', 'E', 'g', 'G':
+			fmt.Fprintf(sb, spec+string(verb), a.float())
+		case 'c':
+			arg, _ := a.next()
+			first := ""
+			if arg != "" {
+				r, _ := utf8.DecodeRuneInString(arg)
+				first = string(r)
+			}
+			fmt.Fprintf(sb, spec+"s", first)
+		case 's':
+			arg, _ := a.next()
+			fmt.Fprintf(sb, spec+"s", arg)
+		case 'q':
+			arg, _ := a.next()
+			fmt.Fprintf(sb, spec+"s", shellQuote(arg))
+		case 'b':
+			arg, _ := a.next()
+			text, stopped := expandBackslashes(arg, true)
+			fmt.Fprintf(sb, spec+"s", text)
+			if stopped {
+				return true
+			}
+		default:
+			a.errs = append(a.errs, fmt.Sprintf("printf: %%%c: invalid directive", verb))
+			sb.WriteString(format[start : i+1])
+		}
+	}
+	return false
+}
+
+// printf implements the printf builtin
+func (b *BuiltinHandler) printf(args []string) ExecutionResult {
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		err := fmt.Errorf("missing format")
+		return ExecutionResult{Output: fmt.Sprintf("printf: %v\nUsage: printf FORMAT [ARG...]", err), ExitCode: 2, Error: err}
+	}
+
+	output, errs, _ := formatPrintf(args[0], args[1:])
+	result := ExecutionResult{Output: output, ExitCode: 0, partialLine: !strings.HasSuffix(output, "\n")}
+	if len(errs) > 0 {
+		// The output is still printed, followed by what was wrong with it
+		result.Output = strings.TrimSuffix(output, "\n") + "\n" + strings.Join(errs, "\n")
+		result.ExitCode = 1
+		result.Error = fmt.Errorf("%s", strings.TrimPrefix(errs[len(errs)-1], "printf: "))
+		result.partialLine = false
+	}
+	return result
+}
+
+const echoHelpText = "echo, printf - Print Text\n\n" +
+	"USAGE:\n" +
+	"    echo [-neE] [ARG...]\n" +
+	"    printf FORMAT [ARG...]\n\n" +
+	"DESCRIPTION:\n" +
+	"    echo prints its arguments separated by spaces. -n leaves off the\n" +
+	"    newline and -e interprets escapes such as \\n, \\t, \\0NNN and \\xHH;\n" +
+	"    \\c ends the output.\n\n" +
+	"    printf prints ARGs with FORMAT, which takes the escapes and the\n" +
+	"    %s, %d, %i, %u, %o, %x, %X, %f, %e, %g, %c, %b and %q directives\n" +
+	"    with flags, widths and precisions as in C. The format is reused\n" +
+	"    until all the ARGs are printed. Both behave the same on macOS and\n" +
+	"    Linux.\n\n" +
+	"EXAMPLES:\n" +
+	"    echo -n \"Building... \"\n" +
+	"    printf '%-10s %5.1f%%\\n' cpu 42.5"
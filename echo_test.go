@@ -0,0 +1,74 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEchoBuiltin(t *testing.T) {
+	b := NewBuiltinHandler(&ShellState{Environment: map[string]string{}})
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"hello", "world"}, "hello world\n"},
+		{nil, "\n"},
+		{[]string{"-n", "no newline"}, "no newline"},
+		{[]string{"a\\tb"}, "a\\tb\n"},
+		{[]string{"-e", "a\\tb\\x41\\0101"}, "a\tbAA\n"},
+		{[]string{"-ne", "one\\ctwo"}, "one"},
+		{[]string{"-eE", "a\\n"}, "a\\n\n"},
+		{[]string{"-x", "-n"}, "-x -n\n"},
+	}
+	for _, tt := range tests {
+		if got := b.Execute("echo", tt.args).Output; got != tt.want {
+			t.Errorf("echo %q = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestFormatPrintf(t *testing.T) {
+	tests := []struct {
+		format string
+		args   []string
+		want   string
+	}{
+		{"%s=%d\\n", []string{"a", "1", "b", "2"}, "a=1\nb=2\n"},
+		{"%-5s|%5.1f%%", []string{"cpu", "42.25"}, "cpu  | 42.2%"},
+		{"%x %o %X %c", []string{"255", "8", "0x1f", "zebra"}, "ff 10 1F z"},
+		{"%*d|%.*s", []string{"4", "7", "2", "abc"}, "   7|ab"},
+		{"%d %s.", nil, "0 ."},
+		{"%d", []string{"'A"}, "65"},
+		{"%b|%q", []string{"x\\ty", "it's"}, "x\ty|'it'\\''s'"},
+		{"\\101\\x42 %s\\c ignored", []string{"c"}, "AB c"},
+	}
+	for _, tt := range tests {
+		if got, errs, _ := formatPrintf(tt.format, tt.args); got != tt.want || len(errs) > 0 {
+			t.Errorf("formatPrintf(%q, %q) = %q, %v, want %q", tt.format, tt.args, got, errs, tt.want)
+		}
+	}
+
+	if _, errs, _ := formatPrintf("%d", []string{"abc"}); len(errs) != 1 {
+		t.Errorf("Expected an invalid number to be reported, got %v", errs)
+	}
+}
+
+func TestPrintfRedirectKeepsPartialLine(t *testing.T) {
+	dir := t.TempDir()
+	state := &ShellState{WorkingDirectory: dir, Environment: map[string]string{}}
+	b := NewBuiltinHandler(state)
+	path := filepath.Join(dir, "out")
+
+	redirects := []Redirect{{Fd: 1, Path: path, Append: true}}
+	applyOutputRedirects(state, redirects, b.Execute("printf", []string{"%s", "a"}))
+	applyOutputRedirects(state, redirects, b.Execute("echo", []string{"-n", "b"}))
+	applyOutputRedirects(state, redirects, b.Execute("echo", []string{"c"}))
+
+	if data, err := os.ReadFile(path); err != nil || string(data) != "abc\n" {
+		t.Errorf("Expected the output without added newlines, got %q, %v", data, err)
+	}
+}
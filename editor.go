@@ -0,0 +1,45 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// editorCommand builds the command that opens file in the user's $VISUAL or
+// $EDITOR (falling back to vi), jumping to line when it's positive. Editors
+// disagree on the syntax for that, so the common ones are special-cased.
+func editorCommand(env map[string]string, file string, line, column int) *exec.Cmd {
+	editor := strings.TrimSpace(env["VISUAL"])
+	if editor == "" {
+		editor = strings.TrimSpace(env["EDITOR"])
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	parts := strings.Fields(editor)
+	args := append([]string{}, parts[1:]...)
+
+	if line <= 0 {
+		args = append(args, file)
+	} else {
+		if column <= 0 {
+			column = 1
+		}
+		switch filepath.Base(parts[0]) {
+		case "code", "code-insiders", "codium", "cursor":
+			args = append(args, "-g", fmt.Sprintf("%s:%d:%d", file, line, column))
+		case "subl", "zed", "hx", "helix":
+			args = append(args, fmt.Sprintf("%s:%d:%d", file, line, column))
+		default:
+			// vi, vim, nvim, nano, emacs, micro, kak and most others
+			args = append(args, fmt.Sprintf("+%d", line), file)
+		}
+	}
+
+	return exec.Command(parts[0], args...)
+}
@@ -0,0 +1,70 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// gosh keeps its variables in ShellState.Environment, which the commands
+// it runs get, while Go code reads and writes the process environment
+// with os.Getenv and os.Setenv. The two are kept in step: SetEnv and
+// UnsetEnv change both, exportToProcess copies the shell's environment to
+// the process when gosh starts, and importFromProcess brings what Go code
+// changed back to the shell.
+
+// processEnvironment returns the process environment as a map
+func processEnvironment() map[string]string {
+	env := make(map[string]string)
+	for _, e := range os.Environ() {
+		if key, value, ok := strings.Cut(e, "="); ok {
+			env[key] = value
+		}
+	}
+	return env
+}
+
+// exportToProcess makes the process environment match the shell's, so Go
+// code sees what the startup files set, and remembers it for
+// importFromProcess. A subshell's variables stay its own.
+func (s *ShellState) exportToProcess() {
+	if s.subshell {
+		return
+	}
+	current := processEnvironment()
+	for key, value := range s.Environment {
+		if old, ok := current[key]; !ok || old != value {
+			os.Setenv(key, value)
+		}
+	}
+	for key := range current {
+		if _, ok := s.Environment[key]; !ok {
+			os.Unsetenv(key)
+		}
+	}
+	s.processEnv = processEnvironment()
+}
+
+// importFromProcess applies to the shell's environment the changes made
+// to the process environment since it was last synced, as Go code does
+// with os.Setenv and os.Unsetenv, so the commands gosh runs see them.
+// Variables the shell changed in the meantime are left alone unless Go
+// code changed them too.
+func (s *ShellState) importFromProcess() {
+	if s.subshell || s.processEnv == nil {
+		return
+	}
+	current := processEnvironment()
+	for key, value := range current {
+		if old, ok := s.processEnv[key]; !ok || old != value {
+			s.Environment[key] = value
+		}
+	}
+	for key := range s.processEnv {
+		if _, ok := current[key]; !ok {
+			delete(s.Environment, key)
+		}
+	}
+	s.processEnv = current
+}
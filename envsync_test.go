@@ -0,0 +1,72 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestEnvironmentSync(t *testing.T) {
+	// Restored when the test ends
+	t.Setenv("GOSH_TEST_VAR", "")
+	t.Setenv("GOSH_TEST_GONE", "before")
+	t.Setenv("GOSH_TEST_SHELL", "")
+	os.Unsetenv("GOSH_TEST_VAR")
+
+	state := &ShellState{WorkingDirectory: t.TempDir(), Environment: processEnvironment()}
+	// As the startup files do
+	state.Environment["GOSH_TEST_SHELL"] = "from startup"
+	state.exportToProcess()
+	if got := os.Getenv("GOSH_TEST_SHELL"); got != "from startup" {
+		t.Errorf("Expected the shell's variables in the process environment, got %q", got)
+	}
+
+	// What Go code sets and unsets reaches the shell
+	os.Setenv("GOSH_TEST_VAR", "from go")
+	os.Unsetenv("GOSH_TEST_GONE")
+	state.Environment["GOSH_TEST_SHELL"] = "changed by the shell"
+	state.importFromProcess()
+	if got := state.Environment["GOSH_TEST_VAR"]; got != "from go" {
+		t.Errorf("Expected GOSH_TEST_VAR from Go code, got %q", got)
+	}
+	if _, ok := state.Environment["GOSH_TEST_GONE"]; ok {
+		t.Error("Expected GOSH_TEST_GONE to be unset in the shell")
+	}
+	if got := state.Environment["GOSH_TEST_SHELL"]; got != "changed by the shell" {
+		t.Errorf("Expected a variable Go code didn't touch to be left alone, got %q", got)
+	}
+
+	// Subshells keep their variables to themselves
+	sub := state.Subshell()
+	sub.Environment["GOSH_TEST_VAR"] = "in a subshell"
+	sub.exportToProcess()
+	if got := os.Getenv("GOSH_TEST_VAR"); got != "from go" {
+		t.Errorf("Expected a subshell not to change the process environment, got %q", got)
+	}
+}
+
+func TestGoEvaluator_SetenvReachesCommands(t *testing.T) {
+	t.Setenv("GOSH_TEST_VAR", "")
+	os.Unsetenv("GOSH_TEST_VAR")
+
+	state := &ShellState{WorkingDirectory: t.TempDir(), Environment: processEnvironment()}
+	state.exportToProcess()
+	g := NewGoEvaluator()
+	g.SetupWithShell(state, NewProcessSpawner(state))
+
+	// Within the same snippet, and after it
+	if result := g.Eval(`os.Setenv("GOSH_TEST_VAR", "set in go"); gosh.Output(sh("printenv", "GOSH_TEST_VAR"))`); !strings.Contains(result.Output, "set in go") {
+		t.Errorf("Expected sh to see the variable, got %q", result.Output)
+	}
+	result := NewProcessSpawner(state).Run(ShellCommand{Name: "printenv", Args: []string{"GOSH_TEST_VAR"}})
+	if !strings.Contains(result.Output, "set in go") {
+		t.Errorf("Expected commands to see the variable, got %q", result.Output)
+	}
+
+	g.Eval(`os.Unsetenv("GOSH_TEST_VAR")`)
+	if _, ok := state.Environment["GOSH_TEST_VAR"]; ok {
+		t.Error("Expected os.Unsetenv to remove the variable from the shell")
+	}
+}
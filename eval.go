@@ -0,0 +1,56 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// evalLine runs the arguments of eval, joined with spaces as in other
+// shells, as a command line: its aliases are expanded and it can be a
+// command list. run runs each of its commands.
+func evalLine(router *Router, state *ShellState, args []string, run func(ShellCommand) ExecutionResult) ExecutionResult {
+	line := strings.Join(args, " ")
+	if strings.TrimSpace(line) == "" {
+		return ExecutionResult{Output: "", ExitCode: 0}
+	}
+	list, err := router.ParseCommandList(line)
+	if err != nil {
+		return ExecutionResult{Output: fmt.Sprintf("eval: %v", err), ExitCode: 2, Error: err}
+	}
+	return runCommandList(state, list, run)
+}
+
+// eval implements the eval builtin where the REPL doesn't run it itself,
+// as from onchange or gosh.Eval: builtins and external commands run, but
+// not |> Go functions.
+func (b *BuiltinHandler) eval(args []string) ExecutionResult {
+	router := NewRouter(b, b.state)
+	spawner := NewProcessSpawner(b.state)
+	return evalLine(router, b.state, args, func(cmd ShellCommand) ExecutionResult {
+		if cmd.Subshell != nil || cmd.GoFunc != "" {
+			return spawner.Run(cmd)
+		}
+		switch router.Classify(cmd) {
+		case InputTypeBuiltin:
+			return runBuiltin(b, b.state, cmd)
+		case InputTypeAutoCd:
+			return b.Execute("cd", []string{cmd.Name})
+		default:
+			return spawner.Run(cmd)
+		}
+	})
+}
+
+const evalHelpText = "eval - Run a Constructed Command Line\n\n" +
+	"USAGE:\n" +
+	"    eval ARG...\n\n" +
+	"DESCRIPTION:\n" +
+	"    Join the arguments with spaces and run the result as a command line,\n" +
+	"    with its aliases, quoting, ;, && and || handled as if it had been\n" +
+	"    typed. Useful for lines built by Go code (gosh.Eval) or printed by\n" +
+	"    tools that set up a shell.\n\n" +
+	"EXAMPLES:\n" +
+	"    eval 'cd /tmp && ls'\n" +
+	"    eval $CMD --verbose"
@@ -0,0 +1,60 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEvalBuiltin(t *testing.T) {
+	dir, _ := filepath.EvalSymlinks(t.TempDir())
+	t.Chdir(dir)
+	os.Mkdir(filepath.Join(dir, "sub"), 0755)
+	state := &ShellState{WorkingDirectory: dir, Environment: map[string]string{"PATH": os.Getenv("PATH"), "HOME": dir}}
+	state.SetAlias("greet", "echo hello")
+	b := NewBuiltinHandler(state)
+
+	tests := []struct {
+		args     []string
+		output   string
+		exitCode int
+	}{
+		{[]string{"greet", "world"}, "hello world\n", 0},
+		{[]string{"cd sub && pwd"}, filepath.Join(dir, "sub"), 0},
+		{[]string{"false || echo", "'fell back'"}, "fell back\n", 0},
+		{[]string{"sh -c 'exit 5'"}, "", 5},
+		{[]string{""}, "", 0},
+		{[]string{"echo 'unterminated"}, "eval: ", 2},
+	}
+
+	for _, tt := range tests {
+		result := b.Execute("eval", tt.args)
+		if !strings.HasPrefix(result.Output, tt.output) || result.ExitCode != tt.exitCode {
+			t.Errorf("eval %q = %q (exit %d), want %q (exit %d)", tt.args, result.Output, result.ExitCode, tt.output, tt.exitCode)
+		}
+	}
+}
+
+func TestModel_RunsEval(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir, _ := filepath.EvalSymlinks(t.TempDir())
+	t.Chdir(dir)
+	state := &ShellState{WorkingDirectory: dir, Environment: map[string]string{"PATH": os.Getenv("PATH"), "HOME": dir}}
+	evaluator := NewGoEvaluator()
+	if result := evaluator.Eval("func Count(lines []string) int { return len(lines) }"); result.Error != nil {
+		t.Fatalf("Eval error: %v", result.Error)
+	}
+	session := &SessionState{CapturedVars: map[string][]string{}, Mode: ModeShell, HistoryFile: filepath.Join(dir, "history")}
+	m := model{session: session, state: state, evaluator: evaluator, spawner: NewProcessSpawner(state), builtins: NewBuiltinHandler(state)}
+
+	output, exitCode := m.executeBlock(`eval "printf 'a\nb\n' |> Count" > out.txt`)
+	if output != "" || exitCode != 0 {
+		t.Errorf("Expected eval's output to be redirected, got %q (exit %d)", output, exitCode)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "out.txt")); string(data) != "2\n" {
+		t.Errorf("Expected |> to run inside eval, got %q", data)
+	}
+}
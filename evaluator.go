@@ -4,14 +4,23 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
 	"io"
+	"maps"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"reflect"
+	"runtime"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/traefik/yaegi/interp"
 	"github.com/traefik/yaegi/stdlib"
@@ -27,14 +36,72 @@ type GoEvaluator struct {
 	stderrPipe  *os.File
 	originalOut *os.File
 	originalErr *os.File
+	output      *evalOutput // The interpreter's stdout and stderr
+	goPath      string      // Where imported third-party packages are found
+	project     *goProject  // Module gosh started in, if any
+	// live, if set, is also sent what evaluated code prints as it's
+	// printed, for the REPL to show while it runs
+	live        io.Writer
 	state       *ShellState
 	spawner     *ProcessSpawner
 	builtins    *BuiltinHandler          // Add builtin handler reference
 	configFuncs map[string]reflect.Value // Store config functions for calling
-
+	evalMu      sync.Mutex               // Serializes evaluation with background tasks
+	cancelMu    sync.Mutex
+	cancel      context.CancelFunc // Cancels the running evaluation, if any
+	// declarations are the successful blocks that declared something,
+	// in order, for :save and :export to write out
+	declarations []string
+	// funcOrigins maps the functions defined in the session and config
+	// files to where they came from, for :funcs
+	funcOrigins map[string]string
+	// imported holds the import paths of the packages imported at package
+	// level, which the config files mustn't import again
+	imported map[string]bool
+	// injected holds the variables InjectVariable set, for an interpreter
+	// that replaces a crashed one
+	injected map[string]interface{}
+	// configs are the config files and plugins loaded, in order, as
+	// loadConfigFile names them
+	configs []string
+	// results is how many results are kept as _1, _2 and so on
+	results int
+	// funcDocs are the doc comments of the config files' functions, and
+	// commandFuncs the functions they register as commands, for help
+	funcDocs     map[string]funcDoc
+	commandFuncs map[string]string
+	// configDecls are the config files' declarations, for reload to tell
+	// which it changes
+	configDecls map[string]string
 }
 
 func NewGoEvaluator() *GoEvaluator {
+	goPath := sessionGoPath()
+	output := &evalOutput{}
+	i := newInterpreter(output, goPath)
+
+	evaluator := &GoEvaluator{
+		interp:       i,
+		output:       output,
+		goPath:       goPath,
+		originalOut:  os.Stdout,
+		originalErr:  os.Stderr,
+		configFuncs:  make(map[string]reflect.Value),
+		funcOrigins:  make(map[string]string),
+		imported:     preImported(),
+		injected:     make(map[string]interface{}),
+		funcDocs:     make(map[string]funcDoc),
+		commandFuncs: make(map[string]string),
+		configDecls:  make(map[string]string),
+	}
+
+	return evaluator
+}
+
+// newInterpreter returns an interpreter with the standard library, the
+// shellapi and gosh packages, and the common packages pre-imported, writing
+// to output
+func newInterpreter(output io.Writer, goPath string) *interp.Interpreter {
 	// Temporarily change to a clean directory to prevent auto-loading
 	originalDir, _ := os.Getwd()
 	tempDir := "/tmp/gosh-clean-" + fmt.Sprintf("%d", os.Getpid())
@@ -43,9 +110,9 @@ func NewGoEvaluator() *GoEvaluator {
 
 	// Create interpreter in clean directory with unrestricted access to os/exec
 	i := interp.New(interp.Options{
-		GoPath:       os.Getenv("GOPATH"),
-		Stdout:       os.Stdout, // Will be updated per-eval
-		Stderr:       os.Stderr,
+		GoPath:       goPath,
+		Stdout:       output, // Goes to the running evaluation's capture
+		Stderr:       output,
 		Unrestricted: true, // Enable access to os/exec and other restricted packages
 	})
 
@@ -57,112 +124,32 @@ func NewGoEvaluator() *GoEvaluator {
 	i.Use(stdlib.Symbols)
 
 	// Pre-import common packages for convenience (but NOT os/exec - will use it via shellapi functions)
-	if _, err := i.Eval(`
-import (
-	"os"
-	"strings"
-	"strconv"
-	"path/filepath"
-)
-`); err != nil {
+	if _, err := i.Eval(importDecl(commonPackages)); err != nil {
 		debugf("Warning: Failed to preload packages: %v\n", err)
 	}
 
-	// Inject shellapi functions that use os/exec internally (whitelisted via Go code)
-	shellapiSymbols := map[string]map[string]reflect.Value{
-		"shellapi/shellapi": {
-			"RunShell": reflect.ValueOf(func(name string, args ...string) (string, error) {
-				// Handle cd specially - IMMEDIATELY change the directory so it works within functions
-				if name == "cd" && len(args) > 0 {
-					targetPath := args[0]
-
-					// Handle path expansion
-					var expandedPath string
-					if strings.HasPrefix(targetPath, "~") {
-						home := os.Getenv("HOME")
-						if len(targetPath) == 1 {
-							expandedPath = home
-						} else {
-							expandedPath = filepath.Join(home, targetPath[1:])
-						}
-					} else if filepath.IsAbs(targetPath) {
-						expandedPath = targetPath
-					} else {
-						cwd, _ := os.Getwd()
-						expandedPath = filepath.Join(cwd, targetPath)
-					}
-
-					// Perform actual directory change immediately - THIS IS THE FIX!
-					if err := os.Chdir(expandedPath); err != nil {
-						return fmt.Sprintf("cd: %s: %v", targetPath, err), nil
-					}
-
-					// CRITICAL: Update global shell state for ALL cases (interactive and function calls)
-					shellStateMutex.Lock()
-					if globalShellState != nil {
-						globalShellState.WorkingDirectory = expandedPath
-					}
-					shellStateMutex.Unlock()
-
-					// Return the marker for config function calling compatibility
-					return "@GOSH_INTERNAL_CD:" + targetPath, nil
-				}
-
-				// Execute command using os/exec in Go code (this works - we whitelisted os/exec manually)
-				cmd := exec.Command(name, args...)
-				output, err := cmd.CombinedOutput()
-				return strings.TrimSpace(string(output)), err
-			}),
-			"GitStatus": reflect.ValueOf(func() (string, error) {
-				cmd := exec.Command("git", "status")
-				output, err := cmd.CombinedOutput()
-				return strings.TrimSpace(string(output)), err
-			}),
-			"LsColor": reflect.ValueOf(func() (string, error) {
-				cmd := exec.Command("ls", "--color=auto")
-				output, err := cmd.CombinedOutput()
-				return strings.TrimSpace(string(output)), err
-			}),
-			"GoBuild": reflect.ValueOf(func() (string, error) {
-				cmd := exec.Command("go", "build")
-				output, err := cmd.CombinedOutput()
-				return strings.TrimSpace(string(output)), err
-			}),
-			"GoTest": reflect.ValueOf(func() (string, error) {
-				cmd := exec.Command("go", "test")
-				output, err := cmd.CombinedOutput()
-				return strings.TrimSpace(string(output)), err
-			}),
-			"GoRun": reflect.ValueOf(func() (string, error) {
-				cmd := exec.Command("go", "run", ".")
-				output, err := cmd.CombinedOutput()
-				return strings.TrimSpace(string(output)), err
-			}),
-			"Success": reflect.ValueOf(func(text string) string {
-				return "\033[32m" + text + "\033[0m"
-			}),
-			"Warning": reflect.ValueOf(func(text string) string {
-				return "\033[33m" + text + "\033[0m"
-			}),
-			"Error": reflect.ValueOf(func(text string) string {
-				return "\033[31m" + text + "\033[0m"
-			}),
-		},
-	}
-
 	// Inject shellapi functions
-	if err := i.Use(shellapiSymbols); err != nil {
+	if err := i.Use(shellapiSymbols()); err != nil {
 		debugf("Failed to inject shellapi symbols: %v\n", err)
 	}
 
-	evaluator := &GoEvaluator{
-		interp:      i,
-		originalOut: os.Stdout,
-		originalErr: os.Stderr,
-		configFuncs: make(map[string]reflect.Value),
+	// Inject and pre-import the gosh API package
+	if err := i.Use(goshSymbols()); err != nil {
+		debugf("Failed to inject gosh symbols: %v\n", err)
+	} else if _, err := i.Eval(`import "gosh"`); err != nil {
+		debugf("Warning: Failed to preload gosh package: %v\n", err)
+	} else if _, err := i.Eval(shSource); err != nil {
+		debugf("Warning: Failed to define sh: %v\n", err)
 	}
 
-	return evaluator
+	// Results shown are handed over in the goshresults package
+	if err := useResult(i, reflect.ValueOf(new(interface{})).Elem()); err != nil {
+		debugf("Failed to inject goshresults symbols: %v\n", err)
+	} else if _, err := i.Eval(`import "goshresults"`); err != nil {
+		debugf("Warning: Failed to preload goshresults package: %v\n", err)
+	}
+
+	return i
 }
 
 func (g *GoEvaluator) SetupWithShell(state *ShellState, spawner *ProcessSpawner) {
@@ -173,55 +160,16 @@ func (g *GoEvaluator) SetupWithShell(state *ShellState, spawner *ProcessSpawner)
 	shellStateMutex.Lock()
 	globalShellState = state
 	shellStateMutex.Unlock()
+
+	state.Scheduler().SetRunner(g.runTask)
+
+	// The packages of the project gosh starts in can be imported
+	g.project = findGoProject(state.WorkingDirectory)
 }
 
 func (g *GoEvaluator) SetupWithBuiltins(builtins *BuiltinHandler) {
 	g.builtins = builtins
-}
-
-func (g *GoEvaluator) stripImports(code string) string {
-	lines := strings.Split(code, "\n")
-	var result []string
-	inImport := false
-	shouldSkip := false
-
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		shouldSkip = false
-
-		if strings.HasPrefix(trimmed, "import ") {
-			if strings.Contains(line, "github.com/rsarv3006/gosh_lib/shellapi") {
-				// Skip shellapi import specifically
-				shouldSkip = true
-			} else if strings.Contains(trimmed, "(") && !strings.Contains(trimmed, ")") {
-				// Start of multi-line import - don't skip yet
-				inImport = true
-			} else {
-				// Single line import (not shellapi) - keep it
-				result = append(result, line)
-				continue
-			}
-		} else if strings.HasPrefix(trimmed, "(") && !inImport {
-			// Start of multi-line import block
-			inImport = true
-		} else if trimmed == ")" && inImport {
-			// End of multi-line import block
-			inImport = false
-			result = append(result, line) // Keep the closing paren
-			continue
-		} else if inImport {
-			// Inside multi-line import block, check if it's the shellapi import
-			if strings.Contains(line, "github.com/rsarv3006/gosh_lib/shellapi") {
-				shouldSkip = true
-			}
-		}
-
-		if !shouldSkip {
-			result = append(result, line)
-		}
-	}
-
-	return strings.Join(result, "\n")
+	builtins.evaluator = g
 }
 
 func (g *GoEvaluator) LoadConfig() error {
@@ -230,12 +178,16 @@ func (g *GoEvaluator) LoadConfig() error {
 		return err
 	}
 
+	// Plugins from ~/.config/gosh/plugins, which load whether or not
+	// others fail
+	pluginErr := g.loadPlugins()
+
 	// Load project-specific config if it exists
 	if err := g.loadConfigFile("project config", g.getProjectConfigPath()); err != nil {
-		return err
+		return errors.Join(pluginErr, err)
 	}
 
-	return nil
+	return pluginErr
 }
 
 // loadConfigFile loads a specific config file
@@ -279,27 +231,38 @@ func ExecShell(name string, args ...string) error {
 		return fmt.Errorf("error defining shell functions: %w", err)
 	}
 
-	// Replace shellapi imports with our injected package path BEFORE stripping
-	userCode := strings.ReplaceAll(string(content), `"github.com/rsarv3006/gosh_lib/shellapi"`, `"shellapi/shellapi"`)
-
-	// Strip package declaration from user code (but keep all imports including shellapi/shellapi)
-	lines := strings.Split(userCode, "\n")
-	var cleanLines []string
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		if !strings.HasPrefix(trimmed, "package main") {
-			cleanLines = append(cleanLines, line)
-		}
+	// Parsing first reports syntax errors at their place in the file
+	userCode, err := configSource(configPath, content, g.imported)
+	if err != nil {
+		return fmt.Errorf("error evaluating %s: %w", configType, err)
 	}
-	userCode = strings.Join(cleanLines, "\n")
 
-	// Evaluate the user config code
-	if _, err := g.interp.Eval(userCode); err != nil {
+	// Evaluate the user config code, which may import packages as well
+	if err := g.fetchImports(userCode); err != nil {
 		return fmt.Errorf("error evaluating %s: %w", configType, err)
 	}
+	if _, err := g.interp.Eval(userCode); err != nil {
+		return fmt.Errorf("error evaluating %s: %w", configType, configError(configPath, err))
+	}
+	for _, path := range importedPackages(userCode) {
+		g.imported[path] = true
+	}
+	g.applyPreImports()
+	if g.state != nil {
+		g.state.importFromProcess()
+	}
+	configFuncs := declaredFuncs(userCode)
+	for _, name := range configFuncs {
+		g.funcOrigins[name] = configType
+	}
+	docs, commands := configDocs(configType, configPath, content)
+	maps.Copy(g.funcDocs, docs)
+	maps.Copy(g.commandFuncs, commands)
+	maps.Copy(g.configDecls, declarationSources(configPath, content))
 
 	// Extract and store config functions for calling
-	g.extractConfigFunctions()
+	g.extractConfigFunctions(configFuncs)
+	g.configs = append(g.configs, configType)
 
 	debugf("Loaded %s from %s\n", configType, configPath)
 	return nil
@@ -337,12 +300,13 @@ func (g *GoEvaluator) getProjectConfigPath() string {
 	return "" // No project config found
 }
 
-// extractConfigFunctions finds and stores functions from the evaluated config
-func (g *GoEvaluator) extractConfigFunctions() {
+// extractConfigFunctions finds and stores functions from the evaluated
+// config: those it declares, and common ones it may define some other way
+func (g *GoEvaluator) extractConfigFunctions(declared []string) {
 	// Common config functions to look for
 	functionNames := []string{"gs", "build", "test", "run", "goGosh", "GitStatus", "ListFiles", "CurrentBranch", "showGo", "clean", "hello", "RunShell"}
 
-	for _, funcName := range functionNames {
+	for _, funcName := range append(functionNames, declared...) {
 		// Try to evaluate the function name to get its value
 		if val, err := g.interp.Eval(funcName); err == nil && val.IsValid() {
 			// Store the function for later calling
@@ -351,6 +315,124 @@ func (g *GoEvaluator) extractConfigFunctions() {
 	}
 }
 
+// configCall parses code as a call of a stored config function whose
+// arguments are all string, number, rune or bool literals, converting
+// them to the function's parameter types. Anything else is left to the
+// interpreter.
+func (g *GoEvaluator) configCall(code string) (funcName string, args []reflect.Value, ok bool) {
+	expr, err := parser.ParseExpr(code)
+	if err != nil {
+		return "", nil, false
+	}
+	call, isCall := expr.(*ast.CallExpr)
+	if !isCall || call.Ellipsis.IsValid() {
+		return "", nil, false
+	}
+	name, isIdent := call.Fun.(*ast.Ident)
+	if !isIdent {
+		return "", nil, false
+	}
+	fn, exists := g.configFuncs[name.Name]
+	if !exists || fn.Kind() != reflect.Func {
+		return "", nil, false
+	}
+
+	fnType := fn.Type()
+	if len(call.Args) != fnType.NumIn() && !(fnType.IsVariadic() && len(call.Args) >= fnType.NumIn()-1) {
+		return "", nil, false
+	}
+	for i, arg := range call.Args {
+		paramType := fnType.In(min(i, fnType.NumIn()-1))
+		if fnType.IsVariadic() && i >= fnType.NumIn()-1 {
+			paramType = paramType.Elem()
+		}
+		value, ok := literalValue(arg, paramType)
+		if !ok {
+			return "", nil, false
+		}
+		args = append(args, value)
+	}
+	return name.Name, args, true
+}
+
+// literalValue converts a literal argument to paramType, reporting false
+// if expr isn't a literal or doesn't fit the type
+func literalValue(expr ast.Expr, paramType reflect.Type) (reflect.Value, bool) {
+	var value any
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		switch expr.Name {
+		case "true", "false":
+			value = expr.Name == "true"
+		default:
+			return reflect.Value{}, false
+		}
+	case *ast.UnaryExpr:
+		if expr.Op != token.SUB {
+			return reflect.Value{}, false
+		}
+		lit, isLit := expr.X.(*ast.BasicLit)
+		if !isLit || (lit.Kind != token.INT && lit.Kind != token.FLOAT) {
+			return reflect.Value{}, false
+		}
+		return literalValue(&ast.BasicLit{Kind: lit.Kind, Value: "-" + lit.Value}, paramType)
+	case *ast.BasicLit:
+		var err error
+		switch expr.Kind {
+		case token.STRING:
+			value, err = strconv.Unquote(expr.Value)
+		case token.INT:
+			value, err = strconv.ParseInt(expr.Value, 0, 64)
+		case token.FLOAT:
+			value, err = strconv.ParseFloat(expr.Value, 64)
+		case token.CHAR:
+			var s string
+			if s, err = strconv.Unquote(expr.Value); err == nil {
+				value = []rune(s)[0]
+			}
+		default:
+			return reflect.Value{}, false
+		}
+		if err != nil {
+			return reflect.Value{}, false
+		}
+	default:
+		return reflect.Value{}, false
+	}
+
+	if n, isInt := value.(int64); isInt && paramType.Kind() == reflect.Interface {
+		// As an untyped constant would be
+		value = int(n)
+	}
+	v := reflect.ValueOf(value)
+	if v.Type().AssignableTo(paramType) {
+		// Also how literals are passed as interface{}
+		result := reflect.New(paramType).Elem()
+		result.Set(v)
+		return result, true
+	}
+	// Numbers convert to any number type, but not to strings
+	isNumber := func(kind reflect.Kind) bool {
+		return (kind >= reflect.Int && kind <= reflect.Uint64) || kind == reflect.Float32 || kind == reflect.Float64
+	}
+	if v.Kind() != paramType.Kind() && !(isNumber(v.Kind()) && isNumber(paramType.Kind())) {
+		return reflect.Value{}, false
+	}
+	if v.Kind() == reflect.Float64 && paramType.Kind() != reflect.Float32 && paramType.Kind() != reflect.Float64 {
+		return reflect.Value{}, false
+	}
+	if v.Kind() == reflect.Int64 {
+		// Numbers that don't fit are left for the interpreter to report
+		zero := reflect.Zero(paramType)
+		switch n := v.Int(); {
+		case paramType.Kind() >= reflect.Int && paramType.Kind() <= reflect.Int64 && zero.OverflowInt(n),
+			paramType.Kind() >= reflect.Uint && paramType.Kind() <= reflect.Uint64 && (n < 0 || zero.OverflowUint(uint64(n))):
+			return reflect.Value{}, false
+		}
+	}
+	return v.Convert(paramType), true
+}
+
 // callConfigFunction attempts to call a stored config function
 func (g *GoEvaluator) callConfigFunction(funcName string, args []reflect.Value) (reflect.Value, error) {
 	if fn, exists := g.configFuncs[funcName]; exists {
@@ -367,39 +449,35 @@ func (g *GoEvaluator) callConfigFunction(funcName string, args []reflect.Value)
 }
 
 func (g *GoEvaluator) Eval(code string) ExecutionResult {
+	g.evalMu.Lock()
+	defer g.evalMu.Unlock()
+
 	// Mark that we're entering yaegi evaluation
 	SetYaegiEvalState(true)
 	defer func() {
 		SetYaegiEvalState(false)
 	}()
+	// Variables the code set with os.Setenv are for commands too
+	if g.state != nil {
+		defer g.state.importFromProcess()
+	}
+
+	// Packages gosh.PreImport added since the last evaluation
+	g.applyPreImports()
 
 	// Trim whitespace for checking
 	trimmed := strings.TrimSpace(code)
 
-	// Check if this is a bare function call from config (like "gs()" or "gs")
-	// But NOT an assignment like "result := func()"
-	if funcMatch := strings.Index(trimmed, "("); funcMatch > 0 && !strings.Contains(trimmed, ":=") && !strings.Contains(trimmed, "=") {
-		funcName := trimmed[:funcMatch]
-		argsStr := ""
-		if len(trimmed) > funcMatch+1 {
-			argsStr = trimmed[funcMatch+1:]
-			if argsStr[len(argsStr)-1] == ')' {
-				argsStr = argsStr[:len(argsStr)-1] // Remove trailing )
-			}
-		}
-
-		// Try to call config function
-		var args []reflect.Value
-		if argsStr != "" {
-			// For now, only support no-argument functions like gs()
-			// TODO: Parse arguments properly if needed
-		}
-
+	// Check if this is a call of a config function (like "gs()" or
+	// "deploy(\"prod\", 3)") with literal arguments, but NOT an assignment
+	// like "result := gs()"
+	if funcName, args, ok := g.configCall(trimmed); ok {
 		result, err := g.callConfigFunction(funcName, args)
 		if err == nil {
 			// Function was found and called successfully
 			var output string
-			if result.IsValid() {
+			// A nil error, as from gosh.Chdir, shows nothing
+			if result.IsValid() && !(result.Kind() == reflect.Interface && result.IsNil()) {
 				// Check if result contains command substitution and process it
 				if result.Kind() == reflect.String {
 					stringResult := result.String()
@@ -413,7 +491,7 @@ func (g *GoEvaluator) Eval(code string) ExecutionResult {
 							} else {
 								// CRITICAL: sync shell state with actual OS working directory for proper prompt display
 								if currentDir, err := os.Getwd(); err == nil {
-									g.state.WorkingDirectory = currentDir
+									g.state.SetWorkingDirectory(currentDir)
 								}
 								output = "" // Successful cd produces no output
 							}
@@ -441,8 +519,20 @@ func (g *GoEvaluator) Eval(code string) ExecutionResult {
 		// If not found in config, continue with normal evaluation
 	}
 
-	// Process command substitutions first
-	processedCode := g.processCommandSubstitutions(code)
+	// Process command substitutions first, and track the goroutines the
+	// code starts
+	processedCode := trackGoStatements(g.processCommandSubstitutions(code))
+	// Importing a package that's pre-imported, or imported already, is fine
+	processedCode = dropImported(processedCode, g.imported)
+	// _ used as a value is the last result
+	if g.results > 0 {
+		processedCode = lastResultRefs(processedCode)
+	}
+
+	// Third-party packages are downloaded on their first import
+	if err := g.fetchImports(processedCode); err != nil {
+		return ExecutionResult{Output: err.Error(), ExitCode: 1, Error: err}
+	}
 
 	// Check if this is a simple assignment - don't print result
 	trimmed = strings.TrimSpace(processedCode)
@@ -457,42 +547,64 @@ func (g *GoEvaluator) Eval(code string) ExecutionResult {
 		strings.Contains(trimmed, "println(") ||
 		strings.Contains(trimmed, "print(")
 
-	// Create a pipe to capture output
-	r, w, _ := os.Pipe()
-
-	// Redirect os.Stdout and os.Stderr
-	oldStdout := os.Stdout
-	oldStderr := os.Stderr
-	os.Stdout = w
-	os.Stderr = w
+	// A call returning several values, such as (value, error), is run so
+	// all of them are kept, to show each one
+	wrapper, results := "", 0
+	if !isAssignment && !isPrintStatement {
+		if code, n, ok := g.multiValueCall(trimmed); ok {
+			wrapper, results = code, n
+		}
+	}
 
-	// Evaluate the code with panic recovery
+	// Evaluate the code with panic recovery, showing its output as it's
+	// printed as well as capturing it. Interrupt cancels it, as do the
+	// timeout set with gosh.EvalTimeout and the memory guard set with
+	// gosh.MemoryGuard.
+	ctx, cancel := context.WithCancel(context.Background())
+	var memoryWarn, memoryLimit uint64
+	if g.state != nil {
+		if g.state.evalTimeout > 0 {
+			ctx, cancel = context.WithTimeout(context.Background(), g.state.evalTimeout)
+		}
+		memoryWarn, memoryLimit = g.state.memoryWarn, g.state.memoryLimit
+	}
+	ctx, abort := context.WithCancelCause(ctx)
+	g.setCancel(cancel)
+	defer func() {
+		g.setCancel(nil)
+		abort(nil)
+		cancel()
+	}()
 	var result reflect.Value
 	var err error
-	func() {
+	capturedOutput := g.output.capture(g.live, func() {
+		defer guardMemory(memoryWarn, memoryLimit, g.output, abort)()
 		defer func() {
 			if r := recover(); r != nil {
-				// Convert panic to error
-				if e, ok := r.(error); ok {
-					err = e
-				} else {
-					err = fmt.Errorf("yaegi evaluation panic: %v", r)
-				}
+				err = crashError(nil, r)
 			}
 		}()
-		result, err = g.interp.Eval(processedCode)
-	}()
-
-	// Restore stdout/stderr and close write end
-	os.Stdout = oldStdout
-	os.Stderr = oldStderr
-	w.Close()
+		if results == 0 {
+			result, err = g.interp.EvalWithContext(ctx, processedCode)
+		} else if _, err = g.interp.EvalWithContext(ctx, wrapper); err == nil {
+			result, err = g.interp.EvalWithContext(ctx, "goshResults()")
+		}
+		// Panics are caught in the goroutine EvalWithContext runs code in
+		err = crashError(err, nil)
+	})
+	if ctx.Err() != nil {
+		waitForStoppedEval()
+	}
 
-	// Read all captured output
-	var buf bytes.Buffer
-	io.Copy(&buf, r)
-	r.Close()
-	capturedOutput := buf.String()
+	if results > 0 && err == nil {
+		if values := g.formatResults(result, results); values != "" {
+			if capturedOutput != "" && !strings.HasSuffix(capturedOutput, "\n") {
+				capturedOutput += "\n"
+			}
+			capturedOutput += values
+		}
+		result = reflect.Value{}
+	}
 
 	// Determine if we should show the result value
 	// Show result if: no error, valid result, not an assignment, not a print, and NO stdout output
@@ -530,7 +642,7 @@ func (g *GoEvaluator) Eval(code string) ExecutionResult {
 			}
 
 			if shouldPrint {
-				formattedResult := formatResult(unwrapped)
+				formattedResult := g.formatValue(unwrapped)
 				// Check if result contains cd marker and process it
 				if strings.HasPrefix(formattedResult, "@GOSH_INTERNAL_CD:") {
 					path := strings.TrimPrefix(formattedResult, "@GOSH_INTERNAL_CD:")
@@ -548,6 +660,7 @@ func (g *GoEvaluator) Eval(code string) ExecutionResult {
 					capturedOutput = g.processCommandSubstitutionsForDisplay(formattedResult)
 				} else {
 					capturedOutput = formattedResult
+					g.keepResult(unwrapped)
 				}
 			}
 		}
@@ -556,12 +669,35 @@ func (g *GoEvaluator) Eval(code string) ExecutionResult {
 	output := strings.TrimSpace(capturedOutput)
 
 	exitCode := 0
-	if err != nil {
+	if err == nil {
+		g.recordDeclaration(code)
+		for _, path := range importedPackages(processedCode) {
+			g.imported[path] = true
+		}
+	} else if memErr := (*memoryLimitError)(nil); errors.As(context.Cause(ctx), &memErr) {
+		exitCode = 1
+		output = strings.TrimSpace(output + "\n" + memErr.Error())
+		releaseMemory()
+	} else if errors.Is(err, context.Canceled) {
+		// What was printed before the interruption is kept
+		exitCode = 130
+		output = strings.TrimSpace(output + "\ninterrupted")
+	} else if errors.Is(err, context.DeadlineExceeded) {
+		exitCode = timeExpiredExitCode
+		output = strings.TrimSpace(fmt.Sprintf("%s\ntimed out after %s", output, g.state.evalTimeout))
+	} else {
 		exitCode = 1
 		// Only add error to output if we don't already have output
 		if output == "" {
-			// Provide a cleaner error message for yaegi panics
-			if strings.Contains(err.Error(), "CFG post-order panic") {
+			// Errors are shown at their place in what was typed rather
+			// than in the code the interpreter was given
+			typed, evaluated, source := code, processedCode, processedCode
+			if results > 0 {
+				typed, evaluated, source = strings.TrimSpace(code), trimmed, wrapper
+			}
+			if text, ok := snippetError(err, typed, evaluated, source); ok {
+				output = text
+			} else if strings.Contains(err.Error(), "CFG post-order panic") {
 				output = "Go syntax error: function return type mismatch"
 			} else if strings.Contains(err.Error(), "yaegi evaluation panic") {
 				output = "Go syntax error: invalid Go code"
@@ -578,15 +714,183 @@ func (g *GoEvaluator) Eval(code string) ExecutionResult {
 	}
 }
 
-// EvalWithRecovery provides additional safety against yaegi crashes
-func (g *GoEvaluator) EvalWithRecovery(code string) ExecutionResult {
-	// Add an outer layer of recovery
+// setCancel sets the function that cancels the running evaluation
+func (g *GoEvaluator) setCancel(cancel context.CancelFunc) {
+	g.cancelMu.Lock()
+	defer g.cancelMu.Unlock()
+	g.cancel = cancel
+}
+
+// Interrupt cancels the running evaluation, as Ctrl-C does. yaegi stops
+// interpreted code at its next step, which ends the goroutines the code
+// started as well. A call of compiled code, such as time.Sleep, can't be
+// stopped; the REPL carries on while it finishes in the background.
+func (g *GoEvaluator) Interrupt() {
+	g.cancelMu.Lock()
+	defer g.cancelMu.Unlock()
+	if g.cancel != nil {
+		g.cancel()
+	}
+}
+
+// stoppedEvalWait is how long a cancelled evaluation is given to stop
+const stoppedEvalWait = time.Second
+
+// waitForStoppedEval waits for cancelled code to stop before the REPL
+// carries on. EvalWithContext returns as soon as it's cancelled, and the
+// next evaluation would set top-level code that hadn't yet reached its
+// next step going again, as yaegi only checks that code belongs to the
+// current evaluation. Code that's in a call of compiled code is given up
+// on after stoppedEvalWait.
+func waitForStoppedEval() {
+	buf := make([]byte, 64<<10)
+	for deadline := time.Now().Add(stoppedEvalWait); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		for {
+			n := runtime.Stack(buf, true)
+			if n < len(buf) {
+				buf = buf[:n]
+				break
+			}
+			buf = make([]byte, 2*len(buf))
+		}
+		// The goroutine EvalWithContext runs code in ends with it
+		if !bytes.Contains(buf, []byte("interp.(*Interpreter).EvalWithContext.func")) {
+			return
+		}
+		buf = buf[:cap(buf)]
+	}
+}
+
+// runTask calls a scheduled task's function, capturing what it prints.
+// It holds the eval lock so tasks never interleave with the user's code.
+func (g *GoEvaluator) runTask(fn func()) (output string, err error) {
+	g.evalMu.Lock()
+	defer g.evalMu.Unlock()
+
+	output = g.output.capture(nil, func() {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("task panic: %v", r)
+			}
+		}()
+		fn()
+	})
+	if g.state != nil {
+		g.state.importFromProcess()
+	}
+	return output, err
+}
+
+// strayOutputLimit is how much of what's printed between evaluations is
+// kept for the REPL to show
+const strayOutputLimit = 64 * 1024
+
+// evalOutput is the interpreter's stdout and stderr. What evaluated code
+// prints goes to the capture of the evaluation running at the time, never
+// to gosh's own os.Stdout, so goroutines the code starts can't write over
+// the REPL. What they print between evaluations is kept until the REPL
+// shows it.
+type evalOutput struct {
+	mu    sync.Mutex
+	buf   *bytes.Buffer // Capture of the running evaluation, if any
+	live  io.Writer     // Also sent the running evaluation's output
+	stray []byte
+}
+
+func (o *evalOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.buf == nil {
+		o.stray = append(o.stray, p...)
+		if excess := len(o.stray) - strayOutputLimit; excess > 0 {
+			o.stray = append(o.stray[:0], o.stray[excess:]...)
+		}
+		return len(p), nil
+	}
+	o.buf.Write(p)
+	if o.live != nil {
+		o.live.Write(p)
+	}
+	return len(p), nil
+}
+
+// capture calls fn, returning what the interpreter printed meanwhile. It's
+// also written to live, if set, as it's printed.
+func (o *evalOutput) capture(live io.Writer, fn func()) string {
+	buf := &bytes.Buffer{}
+	o.mu.Lock()
+	prevBuf, prevLive := o.buf, o.live
+	o.buf, o.live = buf, live
+	o.mu.Unlock()
+
+	defer func() {
+		o.mu.Lock()
+		o.buf, o.live = prevBuf, prevLive
+		o.mu.Unlock()
+	}()
+	fn()
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return buf.String()
+}
+
+// DrainOutput returns what Go code printed between evaluations, such as
+// the output of goroutines started by an earlier block, and forgets it
+func (g *GoEvaluator) DrainOutput() string {
+	g.output.mu.Lock()
+	defer g.output.mu.Unlock()
+	stray := string(g.output.stray)
+	g.output.stray = nil
+	return stray
+}
+
+// captureOutput calls fn with os.Stdout and os.Stderr going to the
+// returned string, for functions that aren't run by an evaluator. fn must
+// not panic.
+func captureOutput(fn func()) (string, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return "", err
+	}
+
+	// Read concurrently so chatty code can't fill the pipe and block
+	captured := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, r)
+		r.Close()
+		captured <- buf.String()
+	}()
+
+	oldStdout := os.Stdout
+	oldStderr := os.Stderr
+	os.Stdout = w
+	os.Stderr = w
+
+	fn()
+
+	os.Stdout = oldStdout
+	os.Stderr = oldStderr
+	w.Close()
+
+	return <-captured, nil
+}
+
+// EvalWithRecovery evaluates code, replacing the interpreter if it
+// crashes, with what was and wasn't recovered added to the result
+func (g *GoEvaluator) EvalWithRecovery(code string) (result ExecutionResult) {
 	defer func() {
-		if r := recover(); r != nil {
-			debugln("\n🚨 CRITICAL: yaegi interpreter crashed!")
-			debugln("🚨 ERROR: Go evaluation may be unstable. Consider restarting.")
-			debugf("🚨 ERROR: Last command was: %s\n", code[:evaluatorMin(len(code), 50)])
+		r := recover()
+		var crash *interpreterCrash
+		if r != nil {
+			crash = &interpreterCrash{value: r}
+			result = ExecutionResult{Output: crash.Error(), ExitCode: 1, Error: crash}
+		} else if !errors.As(result.Error, &crash) {
+			return
 		}
+		debugf("yaegi crashed on %q: %v\n", code[:evaluatorMin(len(code), 50)], crash.value)
+		result.Output = strings.TrimSpace(result.Output + "\n" + g.recoverInterpreter())
 	}()
 
 	return g.Eval(code)
@@ -598,6 +902,9 @@ func (g *GoEvaluator) InjectVariable(name string, value interface{}) error {
 	code := fmt.Sprintf("%s := %#v", name, value)
 
 	_, err := g.interp.Eval(code)
+	if err == nil {
+		g.injected[name] = value
+	}
 	return err
 }
 
@@ -609,115 +916,194 @@ func evaluatorMin(a, b int) int {
 	return b
 }
 
-// restartInterpreter removed for simplicity - just provide crash recovery
+// Reset replaces the interpreter with a fresh one, dropping everything
+// defined in it, and loads the config files again if loadConfig is set.
+// The shell's state, such as its history and working directory, is kept.
+func (g *GoEvaluator) Reset(loadConfig bool) error {
+	g.evalMu.Lock()
+	g.interp = newInterpreter(g.output, g.goPath)
+	g.configFuncs = make(map[string]reflect.Value)
+	g.declarations = nil
+	g.funcOrigins = make(map[string]string)
+	g.imported = preImported()
+	g.injected = make(map[string]interface{})
+	g.configs = nil
+	g.results = 0
+	g.funcDocs = make(map[string]funcDoc)
+	g.commandFuncs = make(map[string]string)
+	g.configDecls = make(map[string]string)
+	g.evalMu.Unlock()
+
+	if !loadConfig {
+		return nil
+	}
+	return g.LoadConfig()
+}
 
 // processCommandSubstitutionsForDisplay processes command substitutions but returns RAW output
 func (g *GoEvaluator) processCommandSubstitutionsForDisplay(code string) string {
+	from := 0
 	for {
-		start := strings.Index(code, "$(")
+		start, end := findCommandSubstitution(code, from)
 		if start == -1 {
 			break
 		}
 
-		// Find matching closing parenthesis
-		depth := 1
-		end := -1
-		for i := start + 2; i < len(code); i++ {
-			if code[i] == '(' {
-				depth++
-			} else if code[i] == ')' {
-				depth--
-				if depth == 0 {
-					end = i
-					break
-				}
-			}
-		}
-
-		if end == -1 {
-			break // Unbalanced, return original
-		}
-
-		// Extract command
-		command := code[start+2 : end]
-
-		// Parse the command properly
-		parts := strings.Fields(command)
-		if len(parts) == 0 {
-			code = code[:start] + code[end+1:] // Remove empty command
-			continue
-		}
-		cmd := parts[0]
-		args := parts[1:]
-
-		spawner := NewProcessSpawner(g.state)
-		result := spawner.Execute(cmd, args)
-
 		// Return RAW output without any escaping
-		output := result.Output
+		output := g.runCommandSubstitution(code[start+2 : end])
 
-		// Replace $(command) with raw output
+		// Replace $(command) with raw output, and carry on after it so
+		// output that looks like a substitution isn't run
 		code = code[:start] + output + code[end+1:]
+		from = start + len(output)
 	}
 
 	return code
 }
 
-// processCommandSubstituions replaces $(command) with string literals containing command output
+// processCommandSubstitutions replaces each $(command) in Go code with a
+// call of sh, so the command runs when the code does and its output
+// reaches the code as it is
 func (g *GoEvaluator) processCommandSubstitutions(code string) string {
+	from := 0
 	for {
-		start := strings.Index(code, "$(")
+		start, end := findGoCommandSubstitution(code, from)
 		if start == -1 {
 			break
 		}
+		call := commandSubstitutionCall(code[start+2 : end])
+		code = code[:start] + call + code[end+1:]
+		from = start + len(call)
+	}
 
-		// Find matching closing parenthesis
-		depth := 1
-		end := -1
-		for i := start + 2; i < len(code); i++ {
-			if code[i] == '(' {
-				depth++
-			} else if code[i] == ')' {
-				depth--
-				if depth == 0 {
-					end = i
-					break
-				}
+	return code
+}
+
+// findCommandSubstitution returns the positions of the $( and matching )
+// of the first complete command substitution in code at or after from, or
+// -1, -1 if there isn't one
+func findCommandSubstitution(code string, from int) (int, int) {
+	offset := strings.Index(code[from:], "$(")
+	if offset == -1 {
+		return -1, -1
+	}
+	start := from + offset
+
+	// Find matching closing parenthesis
+	depth := 1
+	for i := start + 2; i < len(code); i++ {
+		if code[i] == '(' {
+			depth++
+		} else if code[i] == ')' {
+			depth--
+			if depth == 0 {
+				return start, i
 			}
 		}
+	}
+	return -1, -1 // Unbalanced, leave the rest as it is
+}
 
-		if end == -1 {
-			break // Unbalanced, return original
+// runCommandSubstitution runs the command inside $(...) and returns its
+// output. Substitutions nested in the command run first, innermost first,
+// and their output replaces them with trailing newlines removed, as in
+// other shells.
+func (g *GoEvaluator) runCommandSubstitution(command string) string {
+	from := 0
+	for {
+		start, end := findCommandSubstitution(command, from)
+		if start == -1 {
+			break
 		}
+		output := strings.TrimRight(g.runCommandSubstitution(command[start+2:end]), "\n")
+		command = command[:start] + output + command[end+1:]
+		from = start + len(output)
+	}
 
-		// Extract command
-		command := code[start+2 : end]
+	// Parse the command properly
+	parts := strings.Fields(command)
+	if len(parts) == 0 {
+		return ""
+	}
 
-		// Execute command and get output
-		// Parse the command properly
-		parts := strings.Fields(command)
-		if len(parts) == 0 {
-			code = code[:start] + "\"\"" + code[end+1:] // Replace with empty string
-			continue
-		}
-		cmd := parts[0]
-		args := parts[1:]
+	spawner := NewProcessSpawner(g.state) // Use current shell state for proper execution
+	return spawner.Execute(parts[0], parts[1:]).Output
+}
 
-		spawner := NewProcessSpawner(g.state) // Use current shell state for proper execution
-		result := spawner.Execute(cmd, args)
+// formatValue formats an evaluation result using the shell's result format
+func (g *GoEvaluator) formatValue(v reflect.Value) string {
+	if g.state == nil {
+		return formatResultAs(v, ResultFormatTable, 0)
+	}
+	return formatResultAs(v, g.state.ResultFormat(), g.state.TerminalWidth)
+}
 
-		// Escape the output for Go string literal
-		output := strings.ReplaceAll(result.Output, "\\", "\\\\")
-		output = strings.ReplaceAll(output, "\"", "\\\"")
-		output = strings.ReplaceAll(output, "\n", "\\n")
-		output = strings.ReplaceAll(output, "\t", "\\t")
-		output = strings.ReplaceAll(output, "\r", "\\r")
+// multiValueCall returns the declaration of a function goshResults that
+// returns the results of code as a []interface{}, if code is a call of a
+// function returning several values. yaegi has no value for a function
+// literal called in place, so it has to be named. Only calls of named
+// functions and methods are checked, so finding the function has no side
+// effects.
+func (g *GoEvaluator) multiValueCall(code string) (wrapper string, results int, ok bool) {
+	expr, err := parser.ParseExpr(code)
+	if err != nil {
+		return "", 0, false
+	}
+	call, isCall := expr.(*ast.CallExpr)
+	if !isCall || !isNamePath(call.Fun) {
+		return "", 0, false
+	}
 
-		// Replace $(command) with string literal
-		code = code[:start] + "\"" + output + "\"" + code[end+1:]
+	fn, err := g.interp.Eval(types.ExprString(call.Fun))
+	if err != nil || !fn.IsValid() || fn.Kind() != reflect.Func || fn.Type().NumOut() < 2 {
+		return "", 0, false
 	}
 
-	return code
+	results = fn.Type().NumOut()
+	names := make([]string, results)
+	for i := range names {
+		names[i] = fmt.Sprintf("r%d", i)
+	}
+	list := strings.Join(names, ", ")
+	return fmt.Sprintf("func goshResults() []interface{} { %s := %s; return []interface{}{%s} }", list, code, list), results, true
+}
+
+// isNamePath reports whether expr is a name or a selector of names, such
+// as os.ReadFile or client.Get
+func isNamePath(expr ast.Expr) bool {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return true
+	case *ast.SelectorExpr:
+		return isNamePath(expr.X)
+	}
+	return false
+}
+
+// formatResults formats the values of a multi-value call, one per line,
+// with errors in the error color
+func (g *GoEvaluator) formatResults(v reflect.Value, results int) string {
+	v = indirectValue(v)
+	if v.Kind() != reflect.Slice || v.Len() != results {
+		return ""
+	}
+	lines := make([]string, results)
+	for i := range lines {
+		value := v.Index(i)
+		if value.Kind() == reflect.Interface && value.IsNil() {
+			lines[i] = "nil"
+			continue
+		}
+		if err, isErr := value.Interface().(error); isErr {
+			lines[i] = GetColorManager().StyleOutput("error: "+err.Error(), "error")
+			continue
+		}
+		if value.Kind() == reflect.Interface {
+			value = value.Elem()
+		}
+		lines[i] = g.formatValue(value)
+	}
+	return strings.Join(lines, "\n")
 }
 
 func formatResult(v reflect.Value) string {
@@ -3,6 +3,8 @@
 package main
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -83,6 +85,65 @@ func TestEvalCommandSubstitution(t *testing.T) {
 	}
 }
 
+func TestGoshLastExitCode(t *testing.T) {
+	state := NewShellState()
+	eval := NewGoEvaluator()
+	eval.SetupWithShell(state, NewProcessSpawner(state))
+
+	state.LastExitCode = 2
+	result := eval.Eval("gosh.LastExitCode()")
+	if result.Error != nil || strings.TrimSpace(result.Output) != "2" {
+		t.Errorf("Expected 2, got %q (%v)", result.Output, result.Error)
+	}
+}
+
+func TestGoshShellAPI(t *testing.T) {
+	t.Setenv("GOSH_TEST_VAR", "")
+	start, _ := filepath.EvalSymlinks(t.TempDir())
+	// Restored when the test ends, as Chdir changes it
+	t.Chdir(start)
+	state := &ShellState{WorkingDirectory: start, Environment: map[string]string{"HOME": start}}
+	eval := NewGoEvaluator()
+	eval.SetupWithShell(state, NewProcessSpawner(state))
+	os.Mkdir(filepath.Join(start, "sub"), 0755)
+
+	if result := eval.Eval(`gosh.Chdir("sub")`); result.ExitCode != 0 {
+		t.Fatalf("gosh.Chdir failed: %s", result.Output)
+	}
+	sub := filepath.Join(start, "sub")
+	if state.WorkingDirectory != sub || state.Environment["OLDPWD"] != start {
+		t.Errorf("Expected gosh.Chdir to change directory as cd does, got %q with OLDPWD %q", state.WorkingDirectory, state.Environment["OLDPWD"])
+	}
+	if result := eval.Eval(`gosh.Cwd()`); !strings.Contains(result.Output, sub) {
+		t.Errorf("Expected gosh.Cwd() to be %s, got %q", sub, result.Output)
+	}
+	if result := eval.Eval(`gosh.Chdir("missing")`); !strings.Contains(result.Output, "gosh.Chdir: missing:") {
+		t.Errorf("Expected an error for a missing directory, got %q", result.Output)
+	}
+
+	eval.Eval(`gosh.SetEnv("GOSH_TEST_VAR", "set")`)
+	if state.Environment["GOSH_TEST_VAR"] != "set" || os.Getenv("GOSH_TEST_VAR") != "set" {
+		t.Errorf("Expected gosh.SetEnv to set the variable for commands and Go code")
+	}
+	if result := eval.Eval(`gosh.Env()["GOSH_TEST_VAR"]`); !strings.Contains(result.Output, "set") {
+		t.Errorf("Expected gosh.Env() to have the variable, got %q", result.Output)
+	}
+	if result := eval.Eval(`gosh.SetEnv("1BAD", "x")`); !strings.Contains(result.Output, "not a valid identifier") {
+		t.Errorf("Expected a bad name to be refused, got %q", result.Output)
+	}
+	eval.Eval(`gosh.UnsetEnv("GOSH_TEST_VAR")`)
+	if _, ok := state.Environment["GOSH_TEST_VAR"]; ok {
+		t.Error("Expected gosh.UnsetEnv to remove the variable")
+	}
+
+	if result := eval.Eval(`len(gosh.Jobs())`); result.Output != "0" {
+		t.Errorf("Expected no jobs, got %q", result.Output)
+	}
+	if result := eval.Eval(`gosh.Prompt() != ""`); result.Output != "true" {
+		t.Errorf("Expected a prompt, got %q", result.Output)
+	}
+}
+
 func TestLoadConfig(t *testing.T) {
 	eval := NewGoEvaluator()
 
@@ -109,8 +170,8 @@ func TestProcessCommandSubstitutions(t *testing.T) {
 		t.Errorf("Command substitution not processed: %s", processed)
 	}
 
-	if !strings.Contains(processed, "hello world") {
-		t.Errorf("Expected 'hello world' in processed code: %s", processed)
+	if !strings.Contains(processed, `sh("echo", "hello", "world")`) {
+		t.Errorf("Expected a call of sh in processed code: %s", processed)
 	}
 }
 
@@ -140,3 +201,17 @@ func main() {
 		t.Errorf("Code content missing after strip: %s", stripped)
 	}
 }
+
+func TestStripImports_Grouped(t *testing.T) {
+	eval := NewGoEvaluator()
+
+	code := "import (\n\t\"fmt\"\n\t// helpers\n\t\"github.com/rsarv3006/gosh_lib/shellapi\"\n)\n\nimport (\n\t\"github.com/rsarv3006/gosh_lib/shellapi\"\n)\n\nfunc f() { fmt.Println(\"(\") }\n"
+	stripped := eval.stripImports(code)
+
+	if strings.Contains(stripped, "shellapi") || strings.Count(stripped, "import") != 1 {
+		t.Errorf("Expected only the shellapi imports stripped: %s", stripped)
+	}
+	if !strings.Contains(stripped, "\"fmt\"") || !strings.Contains(stripped, `fmt.Println("(")`) {
+		t.Errorf("Code missing after strip: %s", stripped)
+	}
+}
@@ -3,8 +3,17 @@
 package main
 
 import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestGoEvaluator_InjectVariable(t *testing.T) {
@@ -54,18 +63,24 @@ func TestProcessCommandSubstitution(t *testing.T) {
 	spawner := NewProcessSpawner(state)
 	eval.SetupWithShell(state, spawner)
 
-	// Test command substitution processing
-	code := "files := $(echo hello world)"
-	processed := eval.processCommandSubstitutions(code)
-
-	// The $(echo hello world) should be replaced with a string literal
-	if strings.Contains(processed, "$(") {
-		t.Errorf("Command substitution not processed: %s", processed)
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{"files := $(echo hello world)", `files := gosh.Output(sh("echo", "hello", "world"))`},
+		{`x := $(git log --format="%h %s")`, `x := gosh.Output(sh("git", "log", "--format=%h %s"))`},
+		// Commands that need a shell run with one
+		{"n := $(ls | wc -l)", `n := gosh.Output(sh("/bin/sh", "-c", "ls | wc -l"))`},
+		{"home := $(echo $HOME)", `home := gosh.Output(sh("/bin/sh", "-c", "echo $HOME"))`},
+		// Substitutions in strings and comments are left alone
+		{`s := "$(date)" // $(date)`, `s := "$(date)" // $(date)`},
+		{"s := `$(date)` + $(date)", "s := `$(date)` + gosh.Output(sh(\"date\"))"},
 	}
 
-	// Should contain the output as a string literal
-	if !strings.Contains(processed, "hello world") {
-		t.Errorf("Expected 'hello world' in processed code: %s", processed)
+	for _, tt := range tests {
+		if processed := eval.processCommandSubstitutions(tt.code); processed != tt.expected {
+			t.Errorf("processCommandSubstitutions(%q) = %q, want %q", tt.code, processed, tt.expected)
+		}
 	}
 }
 
@@ -85,6 +100,43 @@ func TestProcessCommandSubstitutionForDisplay(t *testing.T) {
 	}
 }
 
+func TestProcessCommandSubstitution_Nested(t *testing.T) {
+	state := NewShellState()
+	state.WorkingDirectory = t.TempDir()
+	os.WriteFile(filepath.Join(state.WorkingDirectory, "cmd.txt"), []byte("$(echo no)"), 0644)
+	eval := NewGoEvaluator()
+	spawner := NewProcessSpawner(state)
+	eval.SetupWithShell(state, spawner)
+
+	tests := []struct {
+		code     string
+		check    string
+		expected string
+	}{
+		{"files := $(echo $(echo inner) outer)", "files", "inner outer"},
+		{"x := $(echo a $(echo b $(echo c)) d)", "x", "a b c d"},
+		{"y := $(echo $(seq 2))", "y", "1 2"},
+		{"a, b := $(echo one), $(echo $(echo two))", "b", "two"},
+		// Output that looks like a substitution isn't run
+		{"z := $(cat cmd.txt)", `z == "$(echo no)"`, "true"},
+		// Output reaches the code as it is
+		{`nul := $(printf 'a\0b')`, `nul == "a\x00b"`, "true"},
+	}
+
+	for _, tt := range tests {
+		if result := eval.Eval(tt.code); result.ExitCode != 0 {
+			t.Fatalf("Eval(%q) failed: %s", tt.code, result.Output)
+		}
+		if result := eval.Eval(tt.check); result.Output != tt.expected {
+			t.Errorf("After %q, %s = %q, want %q", tt.code, tt.check, result.Output, tt.expected)
+		}
+	}
+
+	if result := eval.processCommandSubstitutionsForDisplay("$(echo $(echo nested))"); result != "nested\n" {
+		t.Errorf("Expected nested output for display, got %q", result)
+	}
+}
+
 func TestFormatResult(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -165,3 +217,462 @@ func TestExecutionResult(t *testing.T) {
 		t.Errorf("Expected exit code 1, got %d", result.ExitCode)
 	}
 }
+
+// signalWriter closes written on its first write
+type signalWriter struct {
+	written chan struct{}
+	once    sync.Once
+}
+
+func (w *signalWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() { close(w.written) })
+	return len(p), nil
+}
+
+func TestGoEvaluator_StreamsOutputToLive(t *testing.T) {
+	eval := NewGoEvaluator()
+	live := &signalWriter{written: make(chan struct{})}
+	eval.live = live
+	if result := eval.Eval("import (\n\t\"fmt\"\n\t\"time\"\n)"); result.Error != nil {
+		t.Fatalf("import failed: %s", result.Output)
+	}
+
+	done := make(chan ExecutionResult)
+	go func() {
+		done <- eval.Eval("for i := 0; i < 2; i++ { fmt.Println(\"tick\", i); time.Sleep(500 * time.Millisecond) }")
+	}()
+
+	select {
+	case <-live.written:
+	case <-done:
+		t.Fatal("Expected output to reach live before the evaluation finished")
+	}
+	if result := <-done; result.Output != "tick 0\ntick 1" {
+		t.Errorf("Expected the output to be captured as well, got %q", result.Output)
+	}
+}
+
+func TestGoEvaluator_GoroutineOutputBetweenEvals(t *testing.T) {
+	eval := NewGoEvaluator()
+	eval.Eval("import (\n\t\"fmt\"\n\t\"time\"\n)")
+
+	stdout := os.Stdout
+	result := eval.Eval(`go func() { time.Sleep(100 * time.Millisecond); fmt.Println("late") }()`)
+	if result.Error != nil {
+		t.Fatalf("Eval error: %s", result.Output)
+	}
+	if os.Stdout != stdout {
+		t.Error("Expected os.Stdout to be left alone")
+	}
+
+	time.Sleep(400 * time.Millisecond)
+	if got := eval.DrainOutput(); got != "late\n" {
+		t.Errorf("Expected the goroutine's output to be kept for the REPL, got %q", got)
+	}
+	if got := eval.DrainOutput(); got != "" {
+		t.Errorf("Expected drained output to be forgotten, got %q", got)
+	}
+}
+
+func TestGoEvaluator_ConcurrentEvals(t *testing.T) {
+	eval := NewGoEvaluator()
+	eval.Eval(`import "fmt"`)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			want := fmt.Sprintf("eval %d", i)
+			if result := eval.Eval(fmt.Sprintf("fmt.Println(%q)", want)); result.Output != want {
+				t.Errorf("Expected %q, got %q", want, result.Output)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestDeclaresState(t *testing.T) {
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{`func add(a, b int) int { return a + b }`, true},
+		{`type point struct{ x, y int }`, true},
+		{`import "sort"`, true},
+		{`x := 42`, true},
+		{`x = 7`, true},
+		{`x++`, true},
+		{`var y = "gosh"`, true},
+		{`fmt.Println("hello")`, false},
+		{`add(1, 2)`, false},
+		{`for i := 0; i < 3; i++ {}`, false},
+	}
+	for _, tt := range tests {
+		if got := declaresState(tt.code); got != tt.want {
+			t.Errorf("declaresState(%q) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestGoEvaluator_SaveAndLoadSession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.gosh")
+
+	g := NewGoEvaluator()
+	for _, code := range []string{
+		`func double(n int) int { return n * 2 }`,
+		`base := 21`,
+		`undefinedFunc()`,
+		`double(base)`,
+	} {
+		g.Eval(code)
+	}
+	if count, err := g.SaveSession(path); err != nil || count != 2 {
+		t.Fatalf("SaveSession() = %d, %v, want 2 declarations", count, err)
+	}
+
+	restored := NewGoEvaluator()
+	if count, err := restored.LoadSession(path); err != nil || count != 2 {
+		t.Fatalf("LoadSession() = %d, %v, want 2 declarations", count, err)
+	}
+	if result := restored.Eval(`double(base)`); result.Output != "42" {
+		t.Errorf("Expected the restored session to have double and base, got %q", result.Output)
+	}
+}
+
+func TestExportProgram(t *testing.T) {
+	source, err := exportProgram([]string{
+		`import "sort"`,
+		`func double(n int) int { return n * 2 }`,
+		`base := 20`,
+		`base++`,
+		`type pair struct{ a, b int }`,
+		`func double(n int) int { return n + n }`,
+		`names, count := []string{"b", "a"}, 2`,
+		`sort.Strings(names)`,
+	}, NewGoEvaluator().packageNames())
+	if err != nil {
+		t.Fatalf("exportProgram() error = %v", err)
+	}
+	program := string(source)
+	for _, want := range []string{`import "sort"`, "var base = 20", "base++", "var names, count = []string{\"b\", \"a\"}, 2", "func main() {"} {
+		if !strings.Contains(program, want) {
+			t.Errorf("Expected the program to contain %q, got:\n%s", want, program)
+		}
+	}
+	if strings.Count(program, "func double") != 1 || !strings.Contains(program, "n + n") {
+		t.Errorf("Expected only the last definition of double, got:\n%s", program)
+	}
+
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not available")
+	}
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "main.go"), source, 0644)
+	cmd := exec.Command("go", "vet", "main.go")
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("Expected the exported program to compile, got %v: %s\n%s", err, output, program)
+	}
+}
+
+func TestGoEvaluator_Reset(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	wd, _ := os.Getwd()
+
+	g := NewGoEvaluator()
+	g.Eval(`leftover := 1`)
+	if err := g.Reset(true); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	if result := g.Eval(`leftover`); result.ExitCode == 0 {
+		t.Errorf("Expected variables to be gone after a reset, got %q", result.Output)
+	}
+	if result := g.Eval(`strings.ToUpper("ok")`); result.Output != "OK" {
+		t.Errorf("Expected pre-imported packages after a reset, got %q", result.Output)
+	}
+	if now, _ := os.Getwd(); now != wd {
+		t.Errorf("Expected the working directory to stay %s, got %s", wd, now)
+	}
+}
+
+func TestGoEvaluator_DescribeVars(t *testing.T) {
+	g := NewGoEvaluator()
+	if got := g.describeVars(); got != "No variables defined" {
+		t.Errorf("Expected no variables in a new session, got %q", got)
+	}
+
+	g.Eval(`count := 42`)
+	g.Eval(`name := "gosh"`)
+	g.Eval(`const limit = 3`)
+	lines := strings.Split(g.describeVars(), "\n")
+	want := [][]string{{"count", "int", "42"}, {"limit", "const", "3"}, {"name", "string", `"gosh"`}}
+	if len(lines) != len(want) {
+		t.Fatalf("Expected %d variables, got %q", len(want), lines)
+	}
+	for i, fields := range want {
+		if got := strings.Fields(lines[i]); strings.Join(got, " ") != strings.Join(fields, " ") {
+			t.Errorf("Line %d = %q, want %v", i, lines[i], fields)
+		}
+	}
+}
+
+func TestGoEvaluator_DescribeFuncs(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Chdir(home)
+	os.MkdirAll(filepath.Join(home, ".config", "gosh"), 0755)
+	os.WriteFile(filepath.Join(home, ".config", "gosh", "config.go"), []byte("package main\n\nfunc greet(name string) string { return \"hi \" + name }\n"), 0644)
+
+	g := NewGoEvaluator()
+	if got := g.describeFuncs(); got != "No functions defined" {
+		t.Errorf("Expected no functions before the config loads, got %q", got)
+	}
+	if err := g.LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	g.Eval(`func add(a, b int) int { return a + b }`)
+
+	want := "home config:\n  greet(string) string\n\nsession:\n  add(int, int) int"
+	if got := g.describeFuncs(); got != want {
+		t.Errorf("describeFuncs() = %q, want %q", got, want)
+	}
+}
+
+func TestGoEvaluator_DescribeType(t *testing.T) {
+	g := NewGoEvaluator()
+	g.Eval(`import "errors"`)
+	g.Eval(`count := 42`)
+	g.Eval(`var err error = errors.New("boom")`)
+
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{`count`, "int"},
+		{`strings.Split`, "func(string, string) []string"},
+		{`err`, "error\ndynamic type: *errors.errorString"},
+		{`func() {}`, "*interface {} (yaegi wrapper)\ndynamic type: nil"},
+	}
+	for _, tt := range tests {
+		if got := g.describeType(tt.expr); got != tt.want {
+			t.Errorf("describeType(%q) = %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+	if got := g.describeType(`undefinedThing`); !strings.Contains(got, "undefined") {
+		t.Errorf("Expected an error for an undefined name, got %q", got)
+	}
+}
+
+func TestGoEvaluator_MultiValueResults(t *testing.T) {
+	g := NewGoEvaluator()
+
+	if result := g.Eval(`strconv.Atoi("42")`); result.Output != "42\nnil" {
+		t.Errorf("Expected both results of strconv.Atoi, got %q", result.Output)
+	}
+	result := g.Eval(`os.ReadFile("/nonexistent/gosh")`)
+	if output := stripANSI(result.Output); output != "nil\nerror: open /nonexistent/gosh: no such file or directory" {
+		t.Errorf("Expected the error of os.ReadFile to be shown, got %q", output)
+	}
+
+	g.Eval(`calls := 0`)
+	g.Eval(`func count() (int, error) { calls++; return calls, nil }`)
+	g.Eval(`count()`)
+	if result := g.Eval(`calls`); result.Output != "1" {
+		t.Errorf("Expected the call to run once, got %s calls", result.Output)
+	}
+}
+
+func TestGoEvaluator_ConfigFunctionArguments(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Chdir(home)
+	os.MkdirAll(filepath.Join(home, ".config", "gosh"), 0755)
+	config := "package main\n\n" +
+		"func deploy(env string, replicas int, dryRun bool) string {\n" +
+		"\tif dryRun {\n\t\treturn \"would deploy \" + strconv.Itoa(replicas) + \" to \" + env\n\t}\n" +
+		"\treturn \"deployed \" + env\n}\n\n" +
+		"func scale(factor float64, names ...string) string {\n" +
+		"\treturn strconv.FormatFloat(factor, 'g', -1, 64) + \" \" + strings.Join(names, \",\")\n}\n"
+	os.WriteFile(filepath.Join(home, ".config", "gosh", "config.go"), []byte(config), 0644)
+
+	g := NewGoEvaluator()
+	if err := g.LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	tests := []struct {
+		code string
+		want string
+	}{
+		{`deploy("prod", 3, true)`, "would deploy 3 to prod"},
+		{`deploy("staging", 1, false)`, "deployed staging"},
+		{`scale(-1.5, "web", "db")`, "-1.5 web,db"},
+		{`scale(2)`, "2 "},
+	}
+	for _, tt := range tests {
+		if result := g.Eval(tt.code); result.Output != tt.want {
+			t.Errorf("Eval(%q) = %q, want %q", tt.code, result.Output, tt.want)
+		}
+	}
+
+	// Arguments that aren't literals are left to the interpreter
+	g.Eval(`env := "dev"`)
+	if result := g.Eval(`deploy(env, 2, false)`); result.Output != "deployed dev" {
+		t.Errorf("Expected a call with a variable to still work, got %q", result.Output)
+	}
+	if result := g.Eval(`deploy("prod")`); result.ExitCode == 0 {
+		t.Errorf("Expected too few arguments to fail, got %q", result.Output)
+	}
+}
+
+func TestLiteralValue(t *testing.T) {
+	parse := func(code string) ast.Expr {
+		expr, err := parser.ParseExpr(code)
+		if err != nil {
+			t.Fatalf("ParseExpr(%q) error = %v", code, err)
+		}
+		return expr
+	}
+	tests := []struct {
+		code  string
+		param any
+		want  any
+		ok    bool
+	}{
+		{`"prod"`, "", "prod", true},
+		{`42`, int(0), 42, true},
+		{`-7`, int8(0), int8(-7), true},
+		{`300`, int8(0), nil, false},
+		{`-1`, uint(0), nil, false},
+		{`2`, float64(0), float64(2), true},
+		{`2.5`, int(0), nil, false},
+		{`'x'`, rune(0), 'x', true},
+		{`true`, false, true, true},
+		{`3`, "", nil, false},
+		{`os.Args`, "", nil, false},
+	}
+	for _, tt := range tests {
+		value, ok := literalValue(parse(tt.code), reflect.TypeOf(tt.param))
+		if ok != tt.ok || (ok && value.Interface() != tt.want) {
+			t.Errorf("literalValue(%s, %T) = %v, %v, want %v, %v", tt.code, tt.param, value, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestGoEvaluator_LoadConfigSource(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Chdir(home)
+	os.MkdirAll(filepath.Join(home, ".config", "gosh"), 0755)
+	configPath := filepath.Join(home, ".config", "gosh", "config.go")
+
+	// Grouped imports, comments around the package clause and the
+	// shellapi import, which isn't the last
+	config := "// My gosh config\npackage main // the package gosh expects\n\n" +
+		"import (\n\t\"github.com/rsarv3006/gosh_lib/shellapi\" // shell helpers\n\t\"strings\"\n)\n\n" +
+		"/* package main */\nfunc shout(s string) string { return strings.ToUpper(s) }\n\n" +
+		"var _ = shellapi.RunShell\n"
+	os.WriteFile(configPath, []byte(config), 0644)
+
+	g := NewGoEvaluator()
+	if err := g.LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if result := g.Eval(`shout("hi")`); result.Output != "HI" {
+		t.Errorf(`Expected shout("hi") to give HI, got %q`, result.Output)
+	}
+
+	// The project config can import what the home config did
+	project := filepath.Join(home, ".goshconfig.go")
+	os.WriteFile(project, []byte("package main\n\nimport \"github.com/rsarv3006/gosh_lib/shellapi\"\n\nfunc pwd() (string, error) { return shellapi.RunShell(\"pwd\") }\n"), 0644)
+	g = NewGoEvaluator()
+	if err := g.LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig() with a project config error = %v", err)
+	}
+	os.Remove(project)
+
+	// Errors point at the line in the config file
+	os.WriteFile(configPath, []byte("package main\n\nimport (\n\t\"fmt\"\n)\n\nfunc broken() {\n\tfmt.Println(undefinedName)\n}\n"), 0644)
+	err := NewGoEvaluator().LoadConfig()
+	if err == nil || !strings.Contains(err.Error(), configPath+":8:") {
+		t.Errorf("Expected an error at %s:8, got %v", configPath, err)
+	}
+	os.WriteFile(configPath, []byte("package main\n\nfunc broken() {\n\treturn (\n}\n"), 0644)
+	err = NewGoEvaluator().LoadConfig()
+	if err == nil || !strings.Contains(err.Error(), configPath+":5:") {
+		t.Errorf("Expected a syntax error at %s:5, got %v", configPath, err)
+	}
+}
+
+func TestConfigSource(t *testing.T) {
+	src := "package main\n\nimport (\n\t\"time\"\n\t\"os\"\n\tapi \"github.com/rsarv3006/gosh_lib/shellapi\"\n)\n\nimport \"strings\"\n"
+	got, err := configSource("config.go", []byte(src), preImported())
+	if err != nil {
+		t.Fatalf("configSource() error = %v", err)
+	}
+	if strings.Contains(got, "package") || !strings.Contains(got, `api "gosh_lib/shellapi"`) || !strings.Contains(got, `"time"`) {
+		t.Errorf("configSource() = %q", got)
+	}
+	// Pre-imported packages can't be imported again
+	if strings.Contains(got, `"os"`) || strings.Contains(got, "import") && strings.Contains(got, "strings") {
+		t.Errorf("Expected the pre-imported packages dropped, got %q", got)
+	}
+	if strings.Count(got, "\n") != strings.Count(src, "\n") {
+		t.Errorf("Expected configSource() to keep the lines, got %q", got)
+	}
+}
+
+func TestGoEvaluator_Interrupt(t *testing.T) {
+	skipUnderRace(t)
+	g := NewGoEvaluator()
+	g.Eval(`func add(a, b int) int { return a + b }`)
+
+	// Interrupting with nothing running does nothing
+	g.Interrupt()
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		g.Interrupt()
+	}()
+	result := g.Eval(`n := 0; for { n++ }`)
+	if result.ExitCode != 130 || result.Output != "interrupted" {
+		t.Errorf("Expected an interrupted loop to exit with 130, got %d %q", result.ExitCode, result.Output)
+	}
+
+	// The interpreter carries on afterwards, without setting the loop
+	// going again
+	if result := g.Eval(`add(1, 2)`); result.Output != "3" {
+		t.Errorf("Expected add(1, 2) to still work, got %q", result.Output)
+	}
+	before := g.Eval(`n`).Output
+	time.Sleep(20 * time.Millisecond)
+	if after := g.Eval(`n`).Output; after != before {
+		t.Errorf("Expected the interrupted loop to stay stopped, n went from %s to %s", before, after)
+	}
+}
+
+func TestGoEvaluator_EvalTimeout(t *testing.T) {
+	skipUnderRace(t)
+	state := NewShellState()
+	g := NewGoEvaluator()
+	g.SetupWithShell(state, NewProcessSpawner(state))
+
+	if result := g.Eval(`gosh.EvalTimeout("soon")`); !strings.Contains(result.Output, "invalid duration") {
+		t.Errorf("Expected an invalid duration error, got %q", result.Output)
+	}
+	g.Eval(`gosh.EvalTimeout("50ms")`)
+	result := g.Eval(`for {}`)
+	if result.ExitCode != timeExpiredExitCode || result.Output != "timed out after 50ms" {
+		t.Errorf("Expected a timeout after 50ms, got %d %q", result.ExitCode, result.Output)
+	}
+
+	// Quick code is unaffected, and 0 turns the limit off
+	if result := g.Eval(`1 + 1`); result.Output != "2" {
+		t.Errorf("Expected 2, got %q", result.Output)
+	}
+	g.Eval(`gosh.EvalTimeout("0")`)
+	if state.evalTimeout != 0 {
+		t.Errorf("Expected no timeout, got %s", state.evalTimeout)
+	}
+}
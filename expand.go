@@ -0,0 +1,540 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Words that need expanding are kept in an escaped form: quotes removed,
+// with a backslash before every character that was quoted (or was itself a
+// backslash). Expansion only acts on unescaped characters, so quoting
+// `"{a,b}"` or `'*.go'` keeps them literal. Slashes are never escaped, as
+// nothing expands them and globbing splits words on them.
+
+// escapeRune appends c to the escaped form of a word
+func escapeRune(sb *strings.Builder, c rune, quoted bool) {
+	if (quoted && c != '/') || c == '\\' {
+		sb.WriteByte('\\')
+	}
+	sb.WriteRune(c)
+}
+
+// escapeText escapes all of text, so expansion leaves it as it is
+func escapeText(text string) string {
+	var sb strings.Builder
+	for _, c := range text {
+		escapeRune(&sb, c, true)
+	}
+	return sb.String()
+}
+
+// unescapeWord turns an escaped word back into plain text
+func unescapeWord(word string) string {
+	if !strings.Contains(word, "\\") {
+		return word
+	}
+	var sb strings.Builder
+	for i := 0; i < len(word); i++ {
+		if word[i] == '\\' && i+1 < len(word) {
+			i++
+		}
+		sb.WriteByte(word[i])
+	}
+	return sb.String()
+}
+
+// Expand applies process substitution and brace, tilde, parameter and
+// pathname expansion to the command's words and redirection targets, and
+// parameter expansion to heredocs, as the shell does just before running
+// it. Process substitutions keep running until releaseSubstitutions.
+func (c ShellCommand) Expand(state *ShellState) (ShellCommand, error) {
+	expanded := c
+	expanded.raw = nil
+	expanded.substitutions = nil
+	fail := func(err error) (ShellCommand, error) {
+		expanded.releaseSubstitutions()
+		return c, err
+	}
+	// expand expands a word that isn't a heredoc
+	expand := func(raw string) ([]string, error) {
+		raw, subs, err := expandProcessSubstitutions(state, raw)
+		if err != nil {
+			return nil, err
+		}
+		expanded.substitutions = append(expanded.substitutions, subs...)
+		return expandWord(state, raw)
+	}
+
+	if c.raw != nil {
+		words := append([]string{c.Name}, c.Args...)
+		var out []string
+		for i, word := range words {
+			if c.raw[i] == "" {
+				out = append(out, word)
+				continue
+			}
+			expandedWords, err := expand(c.raw[i])
+			if err != nil {
+				return fail(err)
+			}
+			out = append(out, expandedWords...)
+		}
+		expanded.Name, expanded.Args = out[0], nil
+		if len(out) > 1 {
+			expanded.Args = out[1:]
+		}
+	}
+
+	if len(c.Redirects) > 0 {
+		expanded.Redirects = make([]Redirect, len(c.Redirects))
+		for i, r := range c.Redirects {
+			if r.raw != "" && r.Heredoc {
+				body, err := expandParameters(state, r.raw)
+				if err != nil {
+					return fail(err)
+				}
+				r.Body, r.raw = unescapeWord(body), ""
+			} else if r.raw != "" {
+				paths, err := expand(r.raw)
+				if err != nil {
+					return fail(err)
+				}
+				if len(paths) != 1 {
+					return fail(fmt.Errorf("%s: ambiguous redirect", r.Path))
+				}
+				r.Path, r.raw = paths[0], ""
+			}
+			expanded.Redirects[i] = r
+		}
+	}
+	return expanded, nil
+}
+
+// expandWord expands an escaped word into one or more words: braces first,
+// then a leading ~ and variables, then any glob patterns that match files
+func expandWord(state *ShellState, word string) ([]string, error) {
+	var words []string
+	for _, braced := range expandBraces(word) {
+		// $@ alone, quoted or not, is a word for each argument
+		if braced == "$@" {
+			words = append(words, state.args...)
+			continue
+		}
+		braced, err := expandParameters(state, expandTilde(state, braced))
+		if err != nil {
+			return nil, err
+		}
+		if hasGlobMeta(braced) {
+			if matches := globWord(state.WorkingDirectory, braced); len(matches) > 0 {
+				words = append(words, matches...)
+				continue
+			}
+		}
+		// Like other shells, a pattern that matches nothing is left as is
+		words = append(words, unescapeWord(braced))
+	}
+	return words, nil
+}
+
+// expandTilde replaces an unescaped ~ at the start of a word, alone or
+// before a slash, with the home directory
+func expandTilde(state *ShellState, word string) string {
+	if word != "~" && !strings.HasPrefix(word, "~/") {
+		return word
+	}
+	home := state.Environment["HOME"]
+	if home == "" {
+		return word
+	}
+	return escapeText(home) + word[1:]
+}
+
+// expandParameters replaces unescaped $NAME, ${NAME}, ${NAME:-default},
+// ${NAME:+alternative}, the special parameters $?, $$, $!, $#, $@ and $*,
+// and the positional parameters $0 to $9 and ${10} and on in an escaped
+// word. Values are escaped, so they aren't split into words or
+// globbed, like zsh rather than sh.
+func expandParameters(state *ShellState, word string) (string, error) {
+	if !strings.Contains(word, "$") {
+		return word, nil
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(word); i++ {
+		switch {
+		case word[i] == '\\' && i+1 < len(word):
+			sb.WriteString(word[i : i+2])
+			i++
+		case word[i] == '$' && i+1 < len(word) && word[i+1] == '{':
+			end := matchingBrace(word, i+1)
+			if end < 0 {
+				return "", fmt.Errorf("%s: bad substitution", unescapeWord(word[i:]))
+			}
+			value, err := expandParameter(state, word[i+2:end])
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(value)
+			i = end
+		case word[i] == '$' && i+1 < len(word) && isSpecialParameter(word[i+1]):
+			sb.WriteString(escapeText(parameterValue(state, word[i+1:i+2])))
+			i++
+		case word[i] == '$' && i+1 < len(word) && isNameStart(word[i+1]):
+			end := i + 2
+			for end < len(word) && isNameChar(word[end]) {
+				end++
+			}
+			sb.WriteString(escapeText(parameterValue(state, word[i+1:end])))
+			i = end - 1
+		default:
+			sb.WriteByte(word[i])
+		}
+	}
+	return sb.String(), nil
+}
+
+// expandParameter expands the inside of ${...}. The word after :- or :+ is
+// itself expanded, and only if it's used. Inside double quotes the name and
+// operator arrive escaped like the rest of the word.
+func expandParameter(state *ShellState, body string) (string, error) {
+	var name, op strings.Builder
+	i := 0
+	for i < len(body) {
+		c, width := unescapedByte(body, i)
+		if name.Len() == 0 && isSpecialParameter(c) {
+			name.WriteByte(c)
+			i += width
+			// Positional parameters past 9 take all their digits
+			for isDigit(c) && i < len(body) {
+				if c, width = unescapedByte(body, i); !isDigit(c) {
+					break
+				}
+				name.WriteByte(c)
+				i += width
+			}
+			break
+		}
+		if !isNameChar(c) {
+			break
+		}
+		name.WriteByte(c)
+		i += width
+	}
+	for op.Len() < 2 && i < len(body) {
+		c, width := unescapedByte(body, i)
+		op.WriteByte(c)
+		i += width
+	}
+	if name.Len() == 0 || !(isNameStart(name.String()[0]) || isSpecialParameter(name.String()[0])) {
+		return "", fmt.Errorf("${%s}: bad substitution", unescapeWord(body))
+	}
+
+	value := parameterValue(state, name.String())
+	switch op.String() {
+	case "":
+		return escapeText(value), nil
+	case ":-":
+		if value == "" {
+			return expandParameters(state, body[i:])
+		}
+		return escapeText(value), nil
+	case ":+":
+		if value != "" {
+			return expandParameters(state, body[i:])
+		}
+		return "", nil
+	}
+	return "", fmt.Errorf("${%s}: bad substitution", unescapeWord(body))
+}
+
+// unescapedByte returns the byte of an escaped word at i and how many bytes
+// it takes up there
+func unescapedByte(word string, i int) (byte, int) {
+	if word[i] == '\\' && i+1 < len(word) {
+		return word[i+1], 2
+	}
+	return word[i], 1
+}
+
+// specialParameters are the parameters named by one punctuation character
+// or digit
+const specialParameters = "?$!#@*0123456789"
+
+func isSpecialParameter(c byte) bool {
+	return strings.IndexByte(specialParameters, c) >= 0
+}
+
+// parameterValue returns the value of a variable or special parameter
+func parameterValue(state *ShellState, name string) string {
+	switch name {
+	case "?":
+		return strconv.Itoa(state.LastExitCode)
+	case "$":
+		return strconv.Itoa(os.Getpid())
+	case "!":
+		if pid := state.Jobs().LastBackgroundPid(); pid != 0 {
+			return strconv.Itoa(pid)
+		}
+		return ""
+	case "#":
+		return strconv.Itoa(len(state.args))
+	case "@", "*":
+		return strings.Join(state.args, " ")
+	}
+	if n, err := strconv.Atoi(name); err == nil {
+		return state.positional(n)
+	}
+	return state.Environment[name]
+}
+
+// matchingBrace returns the index of the } closing the { at open, or -1
+func matchingBrace(word string, open int) int {
+	depth := 0
+	for i := open; i < len(word); i++ {
+		switch word[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || isASCIILetter(c)
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
+
+// isVariableName reports whether name can be a shell variable's name
+func isVariableName(name string) bool {
+	if name == "" || !isNameStart(name[0]) {
+		return false
+	}
+	for i := 1; i < len(name); i++ {
+		if !isNameChar(name[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// expandBraces expands the first brace group in word and recurses, so
+// src/{cmd,pkg} gives src/cmd and src/pkg, and a{1..3} gives a1 a2 a3.
+// Braces without a comma or a sequence, like find's {}, are left alone.
+func expandBraces(word string) []string {
+	open, close, alternatives := findBraceGroup(word)
+	if open < 0 {
+		return []string{word}
+	}
+
+	prefix, suffix := word[:open], word[close+1:]
+	var words []string
+	for _, alternative := range alternatives {
+		words = append(words, expandBraces(prefix+alternative+suffix)...)
+	}
+	return words
+}
+
+// findBraceGroup finds the first expandable brace group in word and
+// returns its bounds and alternatives, or -1 if there isn't one
+func findBraceGroup(word string) (int, int, []string) {
+	for open := 0; open < len(word); open++ {
+		if word[open] == '\\' {
+			open++
+			continue
+		}
+		// ${ is parameter expansion, not a brace group
+		if word[open] != '{' || (open > 0 && word[open-1] == '$') {
+			continue
+		}
+
+		depth := 0
+		commas := []int{}
+		for i := open; i < len(word); i++ {
+			switch word[i] {
+			case '\\':
+				i++
+			case '{':
+				depth++
+			case ',':
+				if depth == 1 {
+					commas = append(commas, i)
+				}
+			case '}':
+				depth--
+			}
+			if depth > 0 {
+				continue
+			}
+
+			if len(commas) > 0 {
+				var alternatives []string
+				start := open + 1
+				for _, comma := range append(commas, i) {
+					alternatives = append(alternatives, word[start:comma])
+					start = comma + 1
+				}
+				return open, i, alternatives
+			}
+			if sequence := braceSequence(word[open+1 : i]); sequence != nil {
+				return open, i, sequence
+			}
+			break
+		}
+	}
+	return -1, -1, nil
+}
+
+// braceSequence expands the inside of {1..5} or {a..e}, or returns nil
+func braceSequence(body string) []string {
+	from, to, ok := strings.Cut(body, "..")
+	if !ok {
+		return nil
+	}
+
+	var values []string
+	if start, err := strconv.Atoi(from); err == nil {
+		end, err := strconv.Atoi(to)
+		if err != nil {
+			return nil
+		}
+		for n := start; ; n += sign(end - start) {
+			values = append(values, strconv.Itoa(n))
+			if n == end {
+				break
+			}
+		}
+		return values
+	}
+
+	if len(from) == 1 && len(to) == 1 && isASCIILetter(from[0]) && isASCIILetter(to[0]) {
+		for c := from[0]; ; c = byte(int(c) + sign(int(to[0])-int(from[0]))) {
+			values = append(values, string(c))
+			if c == to[0] {
+				break
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func sign(n int) int {
+	if n < 0 {
+		return -1
+	}
+	return 1
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// hasGlobMeta reports whether an escaped word has unescaped *, ? or [
+func hasGlobMeta(word string) bool {
+	for i := 0; i < len(word); i++ {
+		switch word[i] {
+		case '\\':
+			i++
+		case '*', '?', '[':
+			return true
+		}
+	}
+	return false
+}
+
+// globWord matches an escaped glob pattern against the file system,
+// relative to dir. As in other shells, wildcards don't match a leading dot
+// and matches are sorted.
+func globWord(dir, pattern string) []string {
+	parts := strings.Split(pattern, "/")
+	candidates := []string{""}
+	if strings.HasPrefix(pattern, "/") {
+		candidates = []string{"/"}
+		parts = parts[1:]
+	}
+
+	for i, part := range parts {
+		last := i == len(parts)-1
+		// A trailing slash only matches directories
+		if part == "" {
+			if last {
+				candidates = filterDirs(dir, candidates)
+			}
+			continue
+		}
+
+		var next []string
+		for _, candidate := range candidates {
+			if !hasGlobMeta(part) {
+				next = append(next, candidate+unescapeWord(part)+separatorUnless(last))
+				continue
+			}
+
+			entries, err := os.ReadDir(resolvePath(dir, candidate))
+			if err != nil {
+				continue
+			}
+			for _, entry := range entries {
+				name := entry.Name()
+				if strings.HasPrefix(name, ".") && !strings.HasPrefix(unescapeWord(part), ".") {
+					continue
+				}
+				if matched, _ := filepath.Match(part, name); matched {
+					next = append(next, candidate+name+separatorUnless(last))
+				}
+			}
+		}
+		candidates = next
+	}
+
+	var matches []string
+	for _, candidate := range candidates {
+		if _, err := os.Lstat(resolvePath(dir, candidate)); err == nil {
+			matches = append(matches, candidate)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+func separatorUnless(last bool) string {
+	if last {
+		return ""
+	}
+	return "/"
+}
+
+// filterDirs keeps the candidates that are directories, giving each a
+// trailing slash
+func filterDirs(dir string, candidates []string) []string {
+	var dirs []string
+	for _, candidate := range candidates {
+		if info, err := os.Stat(resolvePath(dir, candidate)); err == nil && info.IsDir() {
+			dirs = append(dirs, strings.TrimSuffix(candidate, "/")+"/")
+		}
+	}
+	return dirs
+}
+
+func resolvePath(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
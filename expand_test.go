@@ -0,0 +1,231 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func expandTestCommand(t *testing.T, state *ShellState, input string) []string {
+	t.Helper()
+	cmd, err := parseShellCommand(input)
+	if err != nil {
+		t.Fatalf("parseShellCommand(%q) error: %v", input, err)
+	}
+	expanded, err := cmd.Expand(state)
+	if err != nil {
+		t.Fatalf("Expand(%q) error: %v", input, err)
+	}
+	return append([]string{expanded.Name}, expanded.Args...)
+}
+
+func TestExpand_Braces(t *testing.T) {
+	state := &ShellState{WorkingDirectory: t.TempDir(), Environment: map[string]string{"X": "1"}}
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"mkdir -p src/{cmd,internal,pkg}", []string{"mkdir", "-p", "src/cmd", "src/internal", "src/pkg"}},
+		{"echo a{b,c}d", []string{"echo", "abd", "acd"}},
+		{"echo {a,b}{1,2}", []string{"echo", "a1", "a2", "b1", "b2"}},
+		{"echo x{a,b{1,2}}", []string{"echo", "xa", "xb1", "xb2"}},
+		{"echo file{,.bak}", []string{"echo", "file", "file.bak"}},
+		{"echo {1..4}", []string{"echo", "1", "2", "3", "4"}},
+		{"echo {3..1}", []string{"echo", "3", "2", "1"}},
+		{"echo {a..c}", []string{"echo", "a", "b", "c"}},
+		{"echo {} {x} {1..a}", []string{"echo", "{}", "{x}", "{1..a}"}},
+		{`echo "{a,b}" '{1..3}'`, []string{"echo", "{a,b}", "{1..3}"}},
+		{`echo "src/"{a,b}`, []string{"echo", "src/a", "src/b"}},
+		{`echo {"a b",c}`, []string{"echo", "a b", "c"}},
+		{"echo ${X}{a,b}", []string{"echo", "1a", "1b"}},
+	}
+
+	for _, tt := range tests {
+		if got := expandTestCommand(t, state, tt.input); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q expanded to %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestExpand_Globs(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"main.go", "util.go", "notes.txt", ".hidden.go", "pkg/a.go", "pkg/b.go", "cmd/tool/main.go"} {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755)
+		os.WriteFile(filepath.Join(dir, name), nil, 0644)
+	}
+	state := &ShellState{WorkingDirectory: dir}
+
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"ls *.go", []string{"ls", "main.go", "util.go"}},
+		{"ls .*.go", []string{"ls", ".hidden.go"}},
+		{"ls ?ain.go", []string{"ls", "main.go"}},
+		{"ls [mn]*", []string{"ls", "main.go", "notes.txt"}},
+		{"ls */*.go", []string{"ls", "pkg/a.go", "pkg/b.go"}},
+		{"ls */", []string{"ls", "cmd/", "pkg/"}},
+		{"ls cmd/*/main.go", []string{"ls", "cmd/tool/main.go"}},
+		{"ls *.{go,txt}", []string{"ls", "main.go", "util.go", "notes.txt"}},
+		{"ls *.rs", []string{"ls", "*.rs"}},
+		{`ls "*.go" '*'.go`, []string{"ls", "*.go", "*.go"}},
+		{"ls " + dir + "/pkg/*.go", []string{"ls", dir + "/pkg/a.go", dir + "/pkg/b.go"}},
+		{`ls "pk"g/*.go`, []string{"ls", "pkg/a.go", "pkg/b.go"}},
+	}
+
+	for _, tt := range tests {
+		if got := expandTestCommand(t, state, tt.input); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q expanded to %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestExpand_Tilde(t *testing.T) {
+	home := t.TempDir()
+	os.WriteFile(filepath.Join(home, "notes.txt"), nil, 0644)
+	os.WriteFile(filepath.Join(home, "todo.txt"), nil, 0644)
+	state := &ShellState{WorkingDirectory: t.TempDir(), Environment: map[string]string{"HOME": home}}
+
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"cat ~/notes.txt", []string{"cat", home + "/notes.txt"}},
+		{"cd ~", []string{"cd", home}},
+		{"ls ~/*.txt", []string{"ls", home + "/notes.txt", home + "/todo.txt"}},
+		{"cp ~/{notes,todo}.txt .", []string{"cp", home + "/notes.txt", home + "/todo.txt", "."}},
+		{`echo "~" '~/x' ""~ a~ ~user`, []string{"echo", "~", "~/x", "~", "a~", "~user"}},
+	}
+
+	for _, tt := range tests {
+		if got := expandTestCommand(t, state, tt.input); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q expanded to %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestExpand_Parameters(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.go"), nil, 0644)
+	state := &ShellState{WorkingDirectory: dir, Environment: map[string]string{
+		"NAME":  "gosh",
+		"EMPTY": "",
+		"SPACE": "a b",
+		"GLOB":  "*.go",
+		"DIR":   "src",
+	}}
+
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"echo $NAME ${NAME} x${NAME}y $NAME.txt", []string{"echo", "gosh", "gosh", "xgoshy", "gosh.txt"}},
+		{"echo $MISSING. ${EMPTY}.", []string{"echo", ".", "."}},
+		{"echo ${MISSING:-default} ${EMPTY:-default} ${NAME:-default}", []string{"echo", "default", "default", "gosh"}},
+		{"echo ${NAME:+set} ${EMPTY:+set}. ${MISSING:+set}.", []string{"echo", "set", ".", "."}},
+		{"echo ${MISSING:-$NAME} ${MISSING:-${EMPTY:-nested}}", []string{"echo", "gosh", "nested"}},
+		{"echo ${MISSING:-two words} ${MISSING:-a;b}", []string{"echo", "two words", "a;b"}},
+		{"echo $SPACE $GLOB", []string{"echo", "a b", "*.go"}},
+		{"mkdir $DIR/{cmd,pkg}", []string{"mkdir", "src/cmd", "src/pkg"}},
+		{"echo $ a$ $1 '$NAME'", []string{"echo", "$", "a$", "", "$NAME"}},
+		{`echo "$NAME" "${MISSING:-a b}" "$GLOB" "$NAME's" \$NAME "\$NAME"`, []string{"echo", "gosh", "a b", "*.go", "gosh's", "$NAME", "$NAME"}},
+		{`echo "*.go" "{a,b}" "~" "${NAME}"/*.go`, []string{"echo", "*.go", "{a,b}", "~", "gosh/*.go"}},
+	}
+
+	for _, tt := range tests {
+		if got := expandTestCommand(t, state, tt.input); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q expanded to %q, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	for _, input := range []string{"echo ${NAME", "echo ${}", "echo ${NAME:?x}", "echo ${1x}"} {
+		cmd, _ := parseShellCommand(input)
+		if _, err := cmd.Expand(state); err == nil {
+			t.Errorf("Expected a bad substitution error for %q", input)
+		}
+	}
+}
+
+func TestExpand_LastExitCode(t *testing.T) {
+	state := &ShellState{WorkingDirectory: t.TempDir(), LastExitCode: 3}
+	want := []string{"echo", "3", "3", "3", "$?", "failed", "x3y"}
+	if got := expandTestCommand(t, state, `echo $? "$?" ${?} '$?' ${?:+failed} x$?y`); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expanded to %q, want %q", got, want)
+	}
+
+	list, _ := parseCommandList("cat <<EOF\nstatus $?\nEOF")
+	if cmd, err := list[0].Expand(state); err != nil || cmd.Redirects[0].Body != "status 3\n" {
+		t.Errorf("Unexpected heredoc %+v (%v)", cmd.Redirects, err)
+	}
+}
+
+func TestExpand_Positional(t *testing.T) {
+	state := &ShellState{WorkingDirectory: t.TempDir(), scriptName: "deploy.sh", args: []string{"prod", "a b", "", "4", "5", "6", "7", "8", "9", "ten"}}
+	want := []string{"echo", "deploy.sh", "prod", "a b", "prod0", "ten", "10", "$1", "prod a b  4 5 6 7 8 9 ten"}
+	if got := expandTestCommand(t, state, `echo $0 $1 "$2" ${1}0 ${10} $# '$1' "$*"`); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expanded to %q, want %q", got, want)
+	}
+
+	// $@ is a word for each argument, or none without them
+	state.args = []string{"a b", "c"}
+	if got := expandTestCommand(t, state, `printf %s "$@" $@ x`); !reflect.DeepEqual(got, []string{"printf", "%s", "a b", "c", "a b", "c", "x"}) {
+		t.Errorf("Expanded to %q", got)
+	}
+	state.scriptName, state.args = "", nil
+	if got := expandTestCommand(t, state, `echo $0 "$@" $1 $#`); !reflect.DeepEqual(got, []string{"echo", "gosh", "", "0"}) {
+		t.Errorf("Expanded to %q", got)
+	}
+}
+
+func TestRunCommandList_SetsLastExitCode(t *testing.T) {
+	state := &ShellState{WorkingDirectory: t.TempDir(), Environment: map[string]string{"PATH": os.Getenv("PATH")}}
+	spawner := NewProcessSpawner(state)
+
+	list, _ := parseCommandList("false; echo $?; sh -c 'exit 7' || echo $?; true && echo $?")
+	result := runCommandList(state, list, spawner.Run)
+	if result.Output != "1\n7\n0\n" || state.LastExitCode != 0 {
+		t.Errorf("Unexpected output %q (last exit code %d)", result.Output, state.LastExitCode)
+	}
+
+	list, _ = parseCommandList("sh -c 'exit 4'")
+	runCommandList(state, list, spawner.Run)
+	if state.LastExitCode != 4 {
+		t.Errorf("Expected the last exit code to be 4, got %d", state.LastExitCode)
+	}
+}
+
+func TestExpand_Redirects(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "input.txt"), nil, 0644)
+	os.WriteFile(filepath.Join(dir, "a.log"), nil, 0644)
+	os.WriteFile(filepath.Join(dir, "b.log"), nil, 0644)
+	state := &ShellState{WorkingDirectory: dir}
+
+	cmd, _ := parseShellCommand("sort < in*.txt > out.txt")
+	expanded, err := cmd.Expand(state)
+	if err != nil || expanded.Redirects[0].Path != "input.txt" || expanded.Redirects[1].Path != "out.txt" {
+		t.Errorf("Unexpected redirects %+v (%v)", expanded.Redirects, err)
+	}
+
+	for _, input := range []string{"echo hi > *.log", "echo hi > {a,b}.txt"} {
+		cmd, _ := parseShellCommand(input)
+		if _, err := cmd.Expand(state); err == nil {
+			t.Errorf("Expected an ambiguous redirect error for %q", input)
+		}
+	}
+}
+
+func TestRunCommandList_ExpandsBeforeEachCommand(t *testing.T) {
+	dir := t.TempDir()
+	state := &ShellState{WorkingDirectory: dir, Environment: map[string]string{"PATH": os.Getenv("PATH")}}
+	spawner := NewProcessSpawner(state)
+
+	list, _ := parseCommandList("mkdir -p src/{cmd,pkg} && touch src/cmd/main.go && ls src/*/*.go")
+	result := runCommandList(state, list, spawner.Run)
+	if result.ExitCode != 0 || result.Output != "src/cmd/main.go\n" {
+		t.Errorf("Unexpected result %q (exit %d)", result.Output, result.ExitCode)
+	}
+}
@@ -0,0 +1,156 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// export implements the export builtin. Every variable gosh has is passed
+// to commands, so export NAME only checks the name.
+func (b *BuiltinHandler) export(args []string) ExecutionResult {
+	if len(args) > 0 && args[0] == "-p" {
+		args = args[1:]
+		if len(args) > 0 {
+			err := fmt.Errorf("-p takes no names")
+			return ExecutionResult{Output: fmt.Sprintf("export: %v\nUsage: export [NAME[=VALUE]...]", err), ExitCode: 2, Error: err}
+		}
+	}
+	if len(args) == 0 {
+		names := make([]string, 0, len(b.state.Environment))
+		for name := range b.state.Environment {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		lines := make([]string, len(names))
+		for i, name := range names {
+			lines[i] = fmt.Sprintf("export %s=%s", name, shellQuote(b.state.Environment[name]))
+		}
+		return ExecutionResult{Output: strings.Join(lines, "\n"), ExitCode: 0}
+	}
+
+	var errs []string
+	var lastErr error
+	for _, arg := range args {
+		name, value, isAssignment := strings.Cut(arg, "=")
+		if !isVariableName(name) {
+			lastErr = fmt.Errorf("%s: not a valid identifier", name)
+			errs = append(errs, fmt.Sprintf("export: %v", lastErr))
+			continue
+		}
+		if isAssignment {
+			b.state.SetEnv(name, value)
+		}
+	}
+	if lastErr != nil {
+		return ExecutionResult{Output: strings.Join(errs, "\n"), ExitCode: 1, Error: lastErr}
+	}
+	return ExecutionResult{Output: "", ExitCode: 0}
+}
+
+// unset implements the unset builtin. gosh's variables are its environment,
+// so -v is accepted and there are no functions to remove.
+func (b *BuiltinHandler) unset(args []string) ExecutionResult {
+	if len(args) > 0 && args[0] == "-v" {
+		args = args[1:]
+	}
+	if len(args) > 0 && strings.HasPrefix(args[0], "-") && args[0] != "--" {
+		err := fmt.Errorf("%s: unknown option", args[0])
+		return ExecutionResult{Output: fmt.Sprintf("unset: %v\nUsage: unset [-v] NAME...", err), ExitCode: 2, Error: err}
+	}
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+
+	var errs []string
+	var lastErr error
+	for _, name := range args {
+		if !isVariableName(name) {
+			lastErr = fmt.Errorf("%s: not a valid identifier", name)
+			errs = append(errs, fmt.Sprintf("unset: %v", lastErr))
+			continue
+		}
+		b.state.UnsetEnv(name)
+	}
+	if lastErr != nil {
+		return ExecutionResult{Output: strings.Join(errs, "\n"), ExitCode: 1, Error: lastErr}
+	}
+	return ExecutionResult{Output: "", ExitCode: 0}
+}
+
+// env implements the env builtin. With no command it prints gosh's
+// environment, one NAME=VALUE a line; otherwise it runs the command with
+// the assignments added, leaving gosh's own variables as they were.
+func (b *BuiltinHandler) env(args []string) ExecutionResult {
+	usage := func(err error) ExecutionResult {
+		return ExecutionResult{Output: fmt.Sprintf("env: %v\nUsage: env [-i] [-u NAME] [NAME=VALUE...] [COMMAND [ARG...]]", err), ExitCode: 125, Error: err}
+	}
+
+	child := b.state.Subshell()
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		option := args[0]
+		args = args[1:]
+		if option == "--" {
+			break
+		}
+		switch option {
+		case "-i", "-":
+			child.Environment = map[string]string{}
+		case "-u":
+			if len(args) == 0 {
+				return usage(fmt.Errorf("-u needs a name"))
+			}
+			child.UnsetEnv(args[0])
+			args = args[1:]
+		default:
+			return usage(fmt.Errorf("unknown option: %s", option))
+		}
+	}
+	for len(args) > 0 {
+		name, value, isAssignment := strings.Cut(args[0], "=")
+		if !isAssignment || name == "" {
+			break
+		}
+		child.SetEnv(name, value)
+		args = args[1:]
+	}
+
+	if len(args) == 0 {
+		names := make([]string, 0, len(child.Environment))
+		for name := range child.Environment {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		lines := make([]string, len(names))
+		for i, name := range names {
+			lines[i] = name + "=" + child.Environment[name]
+		}
+		return ExecutionResult{Output: strings.Join(lines, "\n"), ExitCode: 0}
+	}
+
+	if b.IsBuiltin(args[0]) {
+		return b.withState(child).Execute(args[0], args[1:])
+	}
+	return (&ProcessSpawner{state: child}).Run(ShellCommand{Name: args[0], Args: args[1:]})
+}
+
+const exportHelpText = "export, unset, env - Environment Variables\n\n" +
+	"USAGE:\n" +
+	"    export                List the environment\n" +
+	"    export NAME=VALUE...  Set variables\n" +
+	"    unset NAME...         Remove variables\n" +
+	"    env                   Print the environment, one NAME=VALUE a line\n" +
+	"    env NAME=VALUE... CMD Run CMD with variables set just for it\n\n" +
+	"DESCRIPTION:\n" +
+	"    Variables are set for the commands gosh runs, for $NAME expansion,\n" +
+	"    and for Go code, where os.Getenv sees them. What Go code sets with\n" +
+	"    os.Setenv or os.Unsetenv reaches commands in turn. In a ( ... )\n" +
+	"    subshell they last until it ends. env -u NAME leaves NAME out and\n" +
+	"    env -i starts from an empty environment.\n\n" +
+	"EXAMPLES:\n" +
+	"    export EDITOR=nvim\n" +
+	"    export PATH=$HOME/bin:$PATH\n" +
+	"    unset AWS_PROFILE\n" +
+	"    env GOOS=linux go build"
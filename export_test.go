@@ -0,0 +1,100 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportAndUnset(t *testing.T) {
+	// Restored when the test ends
+	t.Setenv("GOSH_TEST_VAR", "")
+	t.Setenv("GOSH_TEST_OTHER", "")
+	os.Unsetenv("GOSH_TEST_OTHER")
+
+	state := &ShellState{WorkingDirectory: t.TempDir(), Environment: map[string]string{"PATH": os.Getenv("PATH")}}
+	b := NewBuiltinHandler(state)
+
+	result := b.Execute("export", []string{"GOSH_TEST_VAR=a b", "1BAD=x", "GOSH_TEST_OTHER=it's"})
+	if result.ExitCode != 1 || result.Output != "export: 1BAD: not a valid identifier" {
+		t.Errorf("Expected the bad name to be reported, got %q (exit %d)", result.Output, result.ExitCode)
+	}
+	if state.Environment["GOSH_TEST_VAR"] != "a b" || os.Getenv("GOSH_TEST_VAR") != "a b" {
+		t.Errorf("Expected GOSH_TEST_VAR in both environments, got %q and %q", state.Environment["GOSH_TEST_VAR"], os.Getenv("GOSH_TEST_VAR"))
+	}
+
+	result = b.Execute("export", nil)
+	if !strings.Contains(result.Output, "export GOSH_TEST_OTHER='it'\\''s'\nexport GOSH_TEST_VAR='a b'\n") {
+		t.Errorf("Unexpected export listing %q", result.Output)
+	}
+
+	if result := b.Execute("unset", []string{"GOSH_TEST_VAR", "GOSH_NEVER_SET"}); result.ExitCode != 0 {
+		t.Errorf("unset failed: %q", result.Output)
+	}
+	if _, ok := state.Environment["GOSH_TEST_VAR"]; ok {
+		t.Error("Expected GOSH_TEST_VAR to be removed from the shell")
+	}
+	if _, ok := os.LookupEnv("GOSH_TEST_VAR"); ok {
+		t.Error("Expected GOSH_TEST_VAR to be removed from the process")
+	}
+
+	if result := b.Execute("unset", []string{"-f", "x"}); result.ExitCode != 2 {
+		t.Errorf("Expected unset -f to be refused, got %q", result.Output)
+	}
+}
+
+func TestModel_ExportReachesCommandsAndGo(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GOSH_TEST_VAR", "")
+	t.Setenv("GOSH_TEST_SUB", "")
+	os.Unsetenv("GOSH_TEST_SUB")
+	dir, _ := filepath.EvalSymlinks(t.TempDir())
+	t.Chdir(dir)
+	state := &ShellState{WorkingDirectory: dir, Environment: map[string]string{"PATH": os.Getenv("PATH"), "HOME": dir}}
+	session := &SessionState{CapturedVars: map[string][]string{}, Mode: ModeShell, HistoryFile: filepath.Join(dir, "history")}
+	evaluator := NewGoEvaluator()
+	m := model{session: session, state: state, evaluator: evaluator, spawner: NewProcessSpawner(state), builtins: NewBuiltinHandler(state)}
+
+	output, _ := m.executeBlock("export GOSH_TEST_VAR=$HOME/bin && sh -c 'echo $GOSH_TEST_VAR'")
+	if !strings.Contains(output, dir+"/bin\n") {
+		t.Errorf("Expected the command to see the exported variable, got %q", output)
+	}
+	if result := evaluator.Eval(`os.Getenv("GOSH_TEST_VAR")`); !strings.Contains(result.Output, dir+"/bin") {
+		t.Errorf("Expected Go code to see the exported variable, got %q", result.Output)
+	}
+
+	output, _ = m.executeBlock("(export GOSH_TEST_SUB=1 && sh -c 'echo in:$GOSH_TEST_SUB'); sh -c 'echo out:$GOSH_TEST_SUB'")
+	if !strings.Contains(output, "in:1\nout:\n") {
+		t.Errorf("Expected a subshell's export to end with it, got %q", output)
+	}
+	if _, ok := os.LookupEnv("GOSH_TEST_SUB"); ok {
+		t.Error("Expected a subshell's export to stay out of gosh's environment")
+	}
+}
+
+func TestEnvBuiltin(t *testing.T) {
+	state := &ShellState{WorkingDirectory: t.TempDir(), Environment: map[string]string{"PATH": os.Getenv("PATH"), "B": "2", "A": "1 2"}}
+	b := NewBuiltinHandler(state)
+
+	if result := b.Execute("env", nil); !strings.HasPrefix(result.Output, "A=1 2\nB=2\nPATH=") {
+		t.Errorf("Expected the shell's environment, sorted, got %q", result.Output)
+	}
+
+	result := b.Execute("env", []string{"-u", "A", "C=3", "sh", "-c", "echo $A:$B:$C"})
+	if result.ExitCode != 0 || result.Output != ":2:3\n" {
+		t.Errorf("Expected the command to see the changed environment, got %q (exit %d)", result.Output, result.ExitCode)
+	}
+	if _, ok := state.Environment["C"]; ok || state.Environment["A"] != "1 2" {
+		t.Errorf("Expected the shell's environment to be left alone, got %v", state.Environment)
+	}
+
+	if result := b.Execute("env", []string{"-i", "C=3"}); result.Output != "C=3" {
+		t.Errorf("Expected env -i to start empty, got %q", result.Output)
+	}
+	if result := b.Execute("env", []string{"-x"}); result.ExitCode != 125 {
+		t.Errorf("Expected an unknown option to be refused, got %q", result.Output)
+	}
+}
@@ -0,0 +1,248 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxFrecencyRank is the total rank past which every directory's rank is
+// aged, so directories no longer visited drop out
+const maxFrecencyRank = 9000
+
+// DirVisits holds how often and how recently a directory was visited
+type DirVisits struct {
+	Rank      float64   `json:"rank"`
+	LastVisit time.Time `json:"last_visit"`
+}
+
+// score weighs the rank of the directory by how recently it was visited
+func (d *DirVisits) score(now time.Time) float64 {
+	switch age := now.Sub(d.LastVisit); {
+	case age < time.Hour:
+		return d.Rank * 4
+	case age < 24*time.Hour:
+		return d.Rank * 2
+	case age < 7*24*time.Hour:
+		return d.Rank / 2
+	}
+	return d.Rank / 4
+}
+
+// ScoredDir pairs a directory with its frecency score for sorted listings
+type ScoredDir struct {
+	Path  string
+	Score float64
+}
+
+// DirFrecency tracks the directories visited in the REPL, for j to jump to.
+// Data is kept locally in ~/.config/gosh/dirs.json.
+type DirFrecency struct {
+	path string
+	dirs map[string]*DirVisits
+	mu   sync.Mutex
+}
+
+// NewDirFrecency loads the visited directories from path (missing files
+// are fine)
+func NewDirFrecency(path string) *DirFrecency {
+	f := &DirFrecency{
+		path: path,
+		dirs: make(map[string]*DirVisits),
+	}
+
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &f.dirs); err != nil {
+			debugf("Ignoring corrupt directory history %s: %v\n", path, err)
+			f.dirs = make(map[string]*DirVisits)
+		}
+	}
+
+	return f
+}
+
+// Visit records a visit to dir and persists the directories
+func (f *DirFrecency) Visit(dir string) {
+	f.mu.Lock()
+	visits, exists := f.dirs[dir]
+	if !exists {
+		visits = &DirVisits{}
+		f.dirs[dir] = visits
+	}
+	visits.Rank++
+	visits.LastVisit = time.Now()
+
+	total := 0.0
+	for _, visits := range f.dirs {
+		total += visits.Rank
+	}
+	if total > maxFrecencyRank {
+		for dir, visits := range f.dirs {
+			visits.Rank *= 0.9
+			if visits.Rank < 1 {
+				delete(f.dirs, dir)
+			}
+		}
+	}
+	f.mu.Unlock()
+
+	if err := f.Save(); err != nil {
+		debugf("Failed to save directory history: %v\n", err)
+	}
+}
+
+// OnDirectoryChange is a chpwd hook recording each directory changed to
+func (f *DirFrecency) OnDirectoryChange(oldDir, newDir string) string {
+	f.Visit(newDir)
+	return ""
+}
+
+// Matches returns the directories matching terms, highest score first. A
+// directory matches if the terms appear in its path in order, ignoring
+// case, with the last in its last element. Directories that no longer
+// exist are forgotten.
+func (f *DirFrecency) Matches(terms []string, now time.Time) []ScoredDir {
+	f.mu.Lock()
+	var matches []ScoredDir
+	var gone []string
+	for dir, visits := range f.dirs {
+		if !matchesTerms(dir, terms) {
+			continue
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			gone = append(gone, dir)
+			continue
+		}
+		matches = append(matches, ScoredDir{Path: dir, Score: visits.score(now)})
+	}
+	for _, dir := range gone {
+		delete(f.dirs, dir)
+	}
+	f.mu.Unlock()
+
+	if len(gone) > 0 {
+		if err := f.Save(); err != nil {
+			debugf("Failed to save directory history: %v\n", err)
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score == matches[j].Score {
+			return matches[i].Path < matches[j].Path
+		}
+		return matches[i].Score > matches[j].Score
+	})
+	return matches
+}
+
+// matchesTerms reports whether terms appear in dir in order, ignoring case,
+// the last of them in its last element
+func matchesTerms(dir string, terms []string) bool {
+	if len(terms) == 0 {
+		return true
+	}
+	last := strings.ToLower(terms[len(terms)-1])
+	if !strings.Contains(strings.ToLower(filepath.Base(dir)), last) {
+		return false
+	}
+
+	path := strings.ToLower(dir)
+	from := 0
+	for _, term := range terms {
+		term = strings.ToLower(term)
+		at := strings.Index(path[from:], term)
+		if at < 0 {
+			return false
+		}
+		from += at + len(term)
+	}
+	return true
+}
+
+// Save writes the directories to disk
+func (f *DirFrecency) Save() error {
+	if f.path == "" {
+		return nil
+	}
+
+	f.mu.Lock()
+	data, err := json.Marshal(f.dirs)
+	f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(f.path, data, 0644)
+}
+
+// jump implements the j builtin
+func (b *BuiltinHandler) jump(args []string) ExecutionResult {
+	list := len(args) == 0
+	if len(args) > 0 && (args[0] == "-l" || args[0] == "--list") {
+		list, args = true, args[1:]
+	}
+
+	if list {
+		var lines []string
+		for _, dir := range b.state.DirFrecency().Matches(args, time.Now()) {
+			lines = append(lines, fmt.Sprintf("%8.1f  %s", dir.Score, dir.Path))
+		}
+		return ExecutionResult{Output: strings.Join(lines, "\n"), ExitCode: 0}
+	}
+	if strings.HasPrefix(args[0], "-") && args[0] != "-" {
+		err := fmt.Errorf("unknown option: %s", args[0])
+		return ExecutionResult{Output: fmt.Sprintf("j: %v\nUsage: j [-l] TERM...", err), ExitCode: 1, Error: err}
+	}
+
+	// A directory, or cd's -, is changed to as cd would
+	if len(args) == 1 {
+		if args[0] == "-" {
+			return b.cd(args)
+		}
+		if info, err := os.Stat(b.state.ExpandPath(args[0])); err == nil && info.IsDir() {
+			return b.changeDirectory("j", args[0])
+		}
+	}
+
+	for _, dir := range b.state.DirFrecency().Matches(args, time.Now()) {
+		// The best match other than where we are
+		if dir.Path == b.state.WorkingDirectory {
+			continue
+		}
+		result := b.changeDirectory("j", dir.Path)
+		if result.ExitCode == 0 {
+			result.Output = strings.TrimSuffix(dir.Path+"\n"+result.Output, "\n")
+		}
+		return result
+	}
+	err := errors.New("no match for " + strings.Join(args, " "))
+	return ExecutionResult{Output: fmt.Sprintf("j: %v", err), ExitCode: 1, Error: err}
+}
+
+const jumpHelpText = "j - Jump to a Frequent Directory\n\n" +
+	"USAGE:\n" +
+	"    j TERM...         Change to the best directory matching the terms\n" +
+	"    j -l [TERM...]    List the matching directories with their scores\n" +
+	"    j                 List all the directories\n\n" +
+	"DESCRIPTION:\n" +
+	"    gosh remembers the directories you change to in the REPL, whether\n" +
+	"    with cd, pushd, j or a directory name alone, and scores them by how\n" +
+	"    often and how recently you went there. A directory matches if the\n" +
+	"    terms appear in its path in order, ignoring case, with the last in\n" +
+	"    its last element. The best match other than the current directory\n" +
+	"    wins; a directory given as the only argument is changed to as is.\n\n" +
+	"    The directories are kept in ~/.config/gosh/dirs.json.\n\n" +
+	"EXAMPLES:\n" +
+	"    j gosh            The gosh checkout you work in most\n" +
+	"    j src api         An api directory under a src directory"
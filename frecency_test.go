@@ -0,0 +1,117 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDirFrecency_VisitAndPersist(t *testing.T) {
+	dir := t.TempDir()
+	api, web := filepath.Join(dir, "src", "api"), filepath.Join(dir, "src", "web")
+	os.MkdirAll(api, 0755)
+	os.MkdirAll(web, 0755)
+	path := filepath.Join(dir, "dirs.json")
+
+	frecency := NewDirFrecency(path)
+	frecency.Visit(api)
+	frecency.Visit(web)
+	frecency.Visit(web)
+
+	reloaded := NewDirFrecency(path)
+	matches := reloaded.Matches(nil, time.Now())
+	if len(matches) != 2 || matches[0].Path != web || matches[0].Score != 8 || matches[1].Path != api {
+		t.Errorf("Unexpected matches %+v", matches)
+	}
+
+	// Directories removed since are forgotten
+	os.Remove(api)
+	if matches := reloaded.Matches(nil, time.Now()); len(matches) != 1 || matches[0].Path != web {
+		t.Errorf("Unexpected matches %+v", matches)
+	}
+	if _, ok := NewDirFrecency(path).dirs[api]; ok {
+		t.Errorf("Expected %s to be forgotten", api)
+	}
+}
+
+func TestDirVisits_Score(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		age  time.Duration
+		want float64
+	}{
+		{time.Minute, 8},
+		{2 * time.Hour, 4},
+		{3 * 24 * time.Hour, 1},
+		{30 * 24 * time.Hour, 0.5},
+	}
+	for _, tt := range tests {
+		visits := &DirVisits{Rank: 2, LastVisit: now.Add(-tt.age)}
+		if got := visits.score(now); got != tt.want {
+			t.Errorf("score %v ago = %v, want %v", tt.age, got, tt.want)
+		}
+	}
+}
+
+func TestMatchesTerms(t *testing.T) {
+	tests := []struct {
+		terms []string
+		want  bool
+	}{
+		{nil, true},
+		{[]string{"gosh"}, true},
+		{[]string{"GOSH"}, true},
+		{[]string{"src", "gosh"}, true},
+		{[]string{"gosh", "src"}, false},
+		{[]string{"src"}, false},
+		{[]string{"go", "sh"}, true},
+		{[]string{"rust"}, false},
+	}
+	for _, tt := range tests {
+		if got := matchesTerms("/home/dev/src/gosh", tt.terms); got != tt.want {
+			t.Errorf("matchesTerms(%q) = %v, want %v", tt.terms, got, tt.want)
+		}
+	}
+}
+
+func TestJump(t *testing.T) {
+	dir := t.TempDir()
+	api, apiDocs := filepath.Join(dir, "src", "api"), filepath.Join(dir, "docs", "api")
+	os.MkdirAll(api, 0755)
+	os.MkdirAll(apiDocs, 0755)
+	t.Chdir(dir)
+
+	state := &ShellState{WorkingDirectory: dir, Environment: map[string]string{"HOME": dir}}
+	state.dirs = NewDirFrecency(filepath.Join(dir, "dirs.json"))
+	state.AddChpwdHook(state.DirFrecency().OnDirectoryChange)
+	b := NewBuiltinHandler(state)
+
+	b.Execute("cd", []string{api})
+	b.Execute("cd", []string{apiDocs})
+	b.Execute("cd", []string{api})
+	b.Execute("cd", []string{dir})
+
+	result := b.Execute("j", []string{"api"})
+	if result.ExitCode != 0 || result.Output != api || state.WorkingDirectory != api {
+		t.Errorf("j api = %q (exit %d), in %s", result.Output, result.ExitCode, state.WorkingDirectory)
+	}
+	// Not where we are already
+	if result := b.Execute("j", []string{"api"}); result.Output != apiDocs || state.WorkingDirectory != apiDocs {
+		t.Errorf("j api = %q, in %s", result.Output, state.WorkingDirectory)
+	}
+	if result := b.Execute("j", []string{"src", "api"}); state.WorkingDirectory != api {
+		t.Errorf("j src api = %q, in %s", result.Output, state.WorkingDirectory)
+	}
+
+	if result := b.Execute("j", []string{"-l", "docs", "api"}); result.ExitCode != 0 || result.Output != "     8.0  "+apiDocs {
+		t.Errorf("j -l docs api = %q", result.Output)
+	}
+	for _, args := range [][]string{{"rust"}, {"-x"}} {
+		if result := b.Execute("j", args); result.ExitCode != 1 {
+			t.Errorf("j %q = %q (exit %d), want an error", args, result.Output, result.ExitCode)
+		}
+	}
+}
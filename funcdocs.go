@@ -0,0 +1,130 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// funcDoc is what help shows for a function of a config file: its
+// signature and the doc comment above it
+type funcDoc struct {
+	signature string
+	doc       string
+	origin    string // The config file, as loadConfigFile names it
+	params    []string
+	variadic  bool
+}
+
+// configDocs returns the docs of the functions, not methods, a config file
+// declares, and the functions it registers as commands with literal
+// gosh.RegisterCommand calls, by command name
+func configDocs(origin, filename string, src []byte) (map[string]funcDoc, map[string]string) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, nil
+	}
+
+	docs := make(map[string]funcDoc)
+	commands := make(map[string]string)
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil {
+			continue
+		}
+		var signature strings.Builder
+		printer.Fprint(&signature, fset, &ast.FuncDecl{Name: fn.Name, Type: fn.Type})
+		doc := funcDoc{signature: signature.String(), doc: strings.TrimSpace(fn.Doc.Text()), origin: origin}
+		for _, field := range fn.Type.Params.List {
+			_, doc.variadic = field.Type.(*ast.Ellipsis)
+			for _, name := range field.Names {
+				doc.params = append(doc.params, name.Name)
+			}
+		}
+		docs[fn.Name.Name] = doc
+	}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) != 2 {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || sel.Sel.Name != "RegisterCommand" {
+			return true
+		}
+		if pkg, ok := sel.X.(*ast.Ident); !ok || pkg.Name != "gosh" {
+			return true
+		}
+		lit, isLit := call.Args[0].(*ast.BasicLit)
+		fn, isIdent := call.Args[1].(*ast.Ident)
+		if isLit && isIdent && lit.Kind == token.STRING {
+			if name, err := strconv.Unquote(lit.Value); err == nil {
+				commands[name] = fn.Name
+			}
+		}
+		return true
+	})
+	return docs, commands
+}
+
+// usage returns how the function is called as the command name, with its
+// parameters as arguments: DIR, [FILES...]
+func (d funcDoc) usage(name string) string {
+	words := []string{name}
+	for i, param := range d.params {
+		param = strings.ToUpper(param)
+		if d.variadic && i == len(d.params)-1 {
+			param = "[" + param + "...]"
+		}
+		words = append(words, param)
+	}
+	return strings.Join(words, " ")
+}
+
+// funcHelp returns help for name, a Go function registered as a command or
+// a function of a config file, and whether it's either
+func (b *BuiltinHandler) funcHelp(name string) (string, bool) {
+	var docs map[string]funcDoc
+	var commands map[string]string
+	if b.evaluator != nil {
+		docs, commands = b.evaluator.funcDocs, b.evaluator.commandFuncs
+	}
+
+	if b.state != nil && b.state.isGoCommand(name) {
+		doc, ok := docs[commands[name]]
+		if !ok {
+			return fmt.Sprintf("%s - Go Command\n\nUSAGE:\n    %s ARG...\n\nFUNCTION:\n    %s", name, name, b.state.goCommands[name].Type()), true
+		}
+		help := fmt.Sprintf("%s - Go Command (%s)\n\nUSAGE:\n    %s\n\nFUNCTION:\n    %s", name, doc.origin, doc.usage(name), doc.signature)
+		return withDescription(help, doc.doc), true
+	}
+
+	doc, ok := docs[name]
+	if !ok {
+		return "", false
+	}
+	help := fmt.Sprintf("%s - Function (%s)\n\nUSAGE:\n    %s", name, doc.origin, doc.signature)
+	return withDescription(help, doc.doc), true
+}
+
+// withDescription adds a doc comment to help, indented, if there's one
+func withDescription(help, doc string) string {
+	if doc == "" {
+		return help
+	}
+	lines := strings.Split(doc, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = "    " + line
+		}
+	}
+	return help + "\n\nDESCRIPTION:\n" + strings.Join(lines, "\n")
+}
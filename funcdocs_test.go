@@ -0,0 +1,73 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHelp_ConfigFunctions(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Chdir(home)
+	os.MkdirAll(filepath.Join(home, ".config", "gosh"), 0755)
+	os.WriteFile(filepath.Join(home, ".config", "gosh", "config.go"), []byte(`package main
+
+// greet says hello to name.
+//
+// It's polite.
+func greet(name string) string {
+	return "hello " + name
+}
+
+func undocumented() {}
+
+// deploy ships the app to env, passing flags to the deploy script.
+func deploy(env string, flags ...string) error {
+	return nil
+}
+
+func init() {
+	fmt.Sprint(greet("x"))
+	gosh.RegisterCommand("ship", deploy)
+}
+`), 0644)
+
+	state := NewShellState()
+	g := NewGoEvaluator()
+	builtins := NewBuiltinHandler(state)
+	g.SetupWithShell(state, NewProcessSpawner(state))
+	g.SetupWithBuiltins(builtins)
+	if err := g.LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	// Registered from the REPL, where there's no source to read
+	if err := state.RegisterCommand("hi", func(name string) {}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"greet", "greet - Function (home config)\n\nUSAGE:\n    func greet(name string) string\n\nDESCRIPTION:\n    greet says hello to name.\n\n    It's polite."},
+		{"undocumented", "undocumented - Function (home config)\n\nUSAGE:\n    func undocumented()"},
+		{"ship", "ship - Go Command (home config)\n\nUSAGE:\n    ship ENV [FLAGS...]\n\nFUNCTION:\n    func deploy(env string, flags ...string) error\n\nDESCRIPTION:\n    deploy ships the app to env, passing flags to the deploy script."},
+		{"hi", "hi - Go Command\n\nUSAGE:\n    hi ARG...\n\nFUNCTION:\n    func(string)"},
+	}
+	for _, tt := range tests {
+		if result := builtins.Execute("help", []string{tt.name}); result.ExitCode != 0 || result.Output != tt.expected {
+			t.Errorf("help %s = %q (exit %d), want %q", tt.name, result.Output, result.ExitCode, tt.expected)
+		}
+	}
+
+	// Gone with the interpreter they were loaded in
+	if err := g.Reset(false); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	if result := builtins.Execute("help", []string{"greet"}); result.ExitCode == 0 {
+		t.Errorf("Expected no help for greet after a reset, got %q", result.Output)
+	}
+}
@@ -0,0 +1,126 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Go functions registered with gosh.RegisterCommand run as commands: typing
+// "deploy prod" calls deploy("prod"). They're builtins as far as the rest
+// of the shell is concerned, so redirections, command lists and eval work
+// with them as they do with the others.
+
+// RegisterCommand makes fn the command name. fn takes strings, the last of
+// them variadic if it likes, and its results are shown as they are when
+// the function is piped to with |>; a non-nil error as its last result
+// fails the command. A command registered again is replaced, but
+// builtins can't be.
+func (s *ShellState) RegisterCommand(name string, fn interface{}) error {
+	if name == "" || strings.ContainsAny(name, " \t\n|&;<>()$`\\\"'*?[]#~=%/") {
+		return fmt.Errorf("invalid command name: %q", name)
+	}
+	if _, registered := s.goCommands[name]; !registered && NewBuiltinHandler(s).IsBuiltin(name) {
+		return fmt.Errorf("%s is a builtin", name)
+	}
+
+	value := unwrapInterface(reflect.ValueOf(fn))
+	if value.Kind() != reflect.Func || value.IsNil() {
+		return fmt.Errorf("%s: want a function, got %T", name, fn)
+	}
+	t := value.Type()
+	for i := 0; i < t.NumIn(); i++ {
+		param := t.In(i)
+		if t.IsVariadic() && i == t.NumIn()-1 {
+			param = param.Elem()
+		}
+		if param.Kind() != reflect.String {
+			return fmt.Errorf("%s: arguments are strings, so can't be passed as %s", name, param)
+		}
+	}
+
+	if s.goCommands == nil {
+		s.goCommands = make(map[string]reflect.Value)
+	}
+	s.goCommands[name] = value
+	return nil
+}
+
+// isGoCommand reports whether name is a Go function registered as a command
+func (s *ShellState) isGoCommand(name string) bool {
+	_, ok := s.goCommands[name]
+	return ok
+}
+
+// GoCommandNames returns the names of the Go functions registered as
+// commands, sorted
+func (s *ShellState) GoCommandNames() []string {
+	names := make([]string, 0, len(s.goCommands))
+	for name := range s.goCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// runGoCommand runs the Go function registered as the command name
+func (b *BuiltinHandler) runGoCommand(name string, args []string) ExecutionResult {
+	if b.evaluator == nil {
+		err := fmt.Errorf("%s: needs the Go interpreter", name)
+		return ExecutionResult{Output: fmt.Sprintf("gosh: %v", err), ExitCode: 1, Error: err}
+	}
+	return b.evaluator.CallCommand(name, b.state.goCommands[name], args)
+}
+
+// CallCommand calls fn, registered as the command name, with args
+func (g *GoEvaluator) CallCommand(name string, fn reflect.Value, args []string) ExecutionResult {
+	g.evalMu.Lock()
+	defer g.evalMu.Unlock()
+
+	fail := func(err error) ExecutionResult {
+		err = fmt.Errorf("%s: %w", name, err)
+		return ExecutionResult{Output: err.Error(), ExitCode: 1, Error: err}
+	}
+
+	t := fn.Type()
+	fixed := t.NumIn()
+	if t.IsVariadic() {
+		fixed--
+	}
+	arguments := fmt.Sprintf("%d argument", fixed)
+	if fixed != 1 {
+		arguments += "s"
+	}
+	switch {
+	case t.IsVariadic() && len(args) < fixed:
+		return fail(fmt.Errorf("takes at least %s, got %d", arguments, len(args)))
+	case !t.IsVariadic() && len(args) != fixed:
+		return fail(fmt.Errorf("takes %s, got %d", arguments, len(args)))
+	}
+	values := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		param := t.In(min(i, t.NumIn()-1))
+		if i >= fixed {
+			param = param.Elem()
+		}
+		values[i] = reflect.ValueOf(arg).Convert(param)
+	}
+
+	var results []reflect.Value
+	var panicErr error
+	printed := g.output.capture(g.live, func() {
+		defer func() {
+			if r := recover(); r != nil {
+				panicErr = fmt.Errorf("panic: %v", r)
+			}
+		}()
+		results = fn.Call(values)
+	})
+	if panicErr != nil {
+		return fail(panicErr)
+	}
+	return g.funcResult(printed, results, fail)
+}
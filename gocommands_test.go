@@ -0,0 +1,76 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRegisterCommand(t *testing.T) {
+	state := NewShellState()
+	state.WorkingDirectory = t.TempDir()
+	g := NewGoEvaluator()
+	g.SetupWithShell(state, NewProcessSpawner(state))
+	builtins := NewBuiltinHandler(state)
+	g.SetupWithBuiltins(builtins)
+
+	for _, code := range []string{
+		`import "fmt"`,
+		`func deploy(env string, flags ...string) (string, error) { fmt.Println("deploying"); return env + " " + strings.Join(flags, ","), nil }`,
+		`func fail() error { return fmt.Errorf("nope") }`,
+		`gosh.RegisterCommand("deploy", deploy)`,
+		`gosh.RegisterCommand("fail", fail)`,
+	} {
+		if result := g.Eval(code); result.ExitCode != 0 {
+			t.Fatalf("Eval(%q) failed: %s", code, result.Output)
+		}
+	}
+
+	router := NewRouter(builtins, state)
+	run := func(line string) ExecutionResult {
+		list, err := router.ParseCommandList(line)
+		if err != nil {
+			t.Fatalf("Parsing %q: %v", line, err)
+		}
+		return runCommandList(state, list, func(cmd ShellCommand) ExecutionResult {
+			if router.Classify(cmd) != InputTypeBuiltin {
+				t.Fatalf("Expected %s to run as a builtin", cmd.Name)
+			}
+			return runBuiltin(builtins, state, cmd)
+		})
+	}
+
+	tests := []struct {
+		line     string
+		output   string
+		exitCode int
+	}{
+		{"deploy prod -f 'two words'", "deploying\nprod -f,two words", 0},
+		{"deploy", "deploy: takes at least 1 argument, got 0", 1},
+		{"fail", "fail: nope", 1},
+		{"fail || deploy staging", "fail: nope\ndeploying\nstaging ", 0},
+	}
+	for _, tt := range tests {
+		if result := run(tt.line); result.Output != tt.output || result.ExitCode != tt.exitCode {
+			t.Errorf("%s = %q (exit %d), want %q (exit %d)", tt.line, result.Output, result.ExitCode, tt.output, tt.exitCode)
+		}
+	}
+
+	run("deploy prod > out.txt")
+	if data, _ := os.ReadFile(filepath.Join(state.WorkingDirectory, "out.txt")); !strings.Contains(string(data), "prod") {
+		t.Errorf("Expected the output in out.txt, got %q", data)
+	}
+
+	for code, expected := range map[string]string{
+		`gosh.RegisterCommand("cd", deploy)`:                          "cd is a builtin",
+		`gosh.RegisterCommand("bad name", deploy)`:                    "invalid command name",
+		`gosh.RegisterCommand("count", func(n int) int { return n })`: "can't be passed as int",
+	} {
+		if result := g.Eval(code); !strings.Contains(result.Output, expected) {
+			t.Errorf("%s: expected %q, got %q", code, expected, result.Output)
+		}
+	}
+}
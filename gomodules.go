@@ -0,0 +1,284 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// sessionModuleName is the module path of the session module, the Go
+// module at GOPATH/src whose vendor directory holds the third-party
+// packages imported in the REPL, where the interpreter finds them
+const sessionModuleName = "gosh-session"
+
+// sessionGoPath returns the GOPATH the interpreter loads packages from
+func sessionGoPath() string {
+	if path := goshConfigPath("gopath"); path != "" {
+		return path
+	}
+	return os.Getenv("GOPATH")
+}
+
+// importedPackages returns the import paths of the import declarations at
+// the start of code
+func importedPackages(code string) []string {
+	// Code after the imports needn't parse as a file, so errors are ignored
+	file, _ := parser.ParseFile(token.NewFileSet(), "", "package main\n"+code, parser.ImportsOnly)
+	if file == nil {
+		return nil
+	}
+	var paths []string
+	for _, spec := range file.Imports {
+		if path, err := strconv.Unquote(spec.Path.Value); err == nil {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// isThirdPartyPackage reports whether path is fetched with go get rather
+// than being part of the standard library or injected by gosh. As with the
+// go command, only paths whose first element has a dot are.
+func isThirdPartyPackage(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return strings.Contains(first, ".")
+}
+
+// goProject is the Go module gosh was started in, whose packages can be
+// imported in the REPL
+type goProject struct {
+	root string // Directory holding go.mod
+	path string // Module path
+	// Whether the module has been linked into GOPATH and its dependencies
+	// added to the session module
+	ready bool
+}
+
+// findGoProject returns the Go module containing dir, if any
+func findGoProject(dir string) *goProject {
+	for {
+		data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				if rest, ok := strings.CutPrefix(strings.TrimSpace(line), "module"); ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+					path := strings.TrimSpace(rest)
+					if unquoted, err := strconv.Unquote(path); err == nil {
+						path = unquoted
+					}
+					return &goProject{root: dir, path: path}
+				}
+			}
+			return nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+}
+
+// contains reports whether the package path belongs to the project
+func (p *goProject) contains(path string) bool {
+	return path == p.path || strings.HasPrefix(path, p.path+"/")
+}
+
+// fetchImports makes the third-party packages imported by code available
+// to the interpreter, adding those it can't find yet to the session module.
+// The packages of the project gosh started in are loaded from its
+// directory.
+func (g *GoEvaluator) fetchImports(code string) error {
+	var missing []string
+	for _, path := range importedPackages(code) {
+		if g.project != nil && g.project.contains(path) {
+			if err := g.prepareProject(); err != nil {
+				return err
+			}
+			continue
+		}
+		if isThirdPartyPackage(path) && !packageAvailable(g.goPath, path) {
+			missing = append(missing, path)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if g.goPath == "" {
+		return fmt.Errorf("can't import %s: no GOPATH to download it to", strings.Join(missing, ", "))
+	}
+	return addSessionPackages(filepath.Join(g.goPath, "src"), missing, missing)
+}
+
+// prepareProject links the project into GOPATH/src under its module path,
+// and adds the packages it depends on to the session module at the
+// versions the project uses, unless it vendors them itself
+func (g *GoEvaluator) prepareProject() error {
+	p := g.project
+	if p.ready {
+		return nil
+	}
+	if g.goPath == "" {
+		return fmt.Errorf("can't import %s: no GOPATH to load it from", p.path)
+	}
+
+	link := filepath.Join(g.goPath, "src", filepath.FromSlash(p.path))
+	if target, err := os.Readlink(link); err != nil || target != p.root {
+		os.Remove(link)
+		if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+			return err
+		}
+		if err := os.Symlink(p.root, link); err != nil {
+			return fmt.Errorf("can't link %s into GOPATH: %w", p.path, err)
+		}
+	}
+
+	if info, err := os.Stat(filepath.Join(p.root, "vendor")); err != nil || !info.IsDir() {
+		packages, modules, err := projectDependencies(p.root)
+		if err != nil {
+			return err
+		}
+		var missing []string
+		for _, path := range packages {
+			if !packageAvailable(g.goPath, path) {
+				missing = append(missing, path)
+			}
+		}
+		if len(missing) > 0 {
+			if err := addSessionPackages(filepath.Join(g.goPath, "src"), missing, modules); err != nil {
+				return err
+			}
+		}
+	}
+
+	p.ready = true
+	return nil
+}
+
+// projectDependencies returns the packages outside the standard library
+// and the project that the project's packages import, and the modules
+// providing them as module@version
+func projectDependencies(root string) (packages, modules []string, err error) {
+	cmd := exec.Command("go", "list", "-mod=readonly", "-deps", "-f", "{{if not .Standard}}{{with .Module}}{{if not .Main}}{{$.ImportPath}} {{.Path}}@{{.Version}}{{end}}{{end}}{{end}}", "./...")
+	cmd.Dir = root
+	cmd.Env = append(os.Environ(), "GOWORK=off")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, nil, fmt.Errorf("go list: %s", message)
+		}
+		return nil, nil, fmt.Errorf("go list: %w", err)
+	}
+
+	seen := map[string]bool{}
+	for _, line := range strings.Split(string(output), "\n") {
+		pkg, module, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+		packages = append(packages, pkg)
+		if !seen[module] {
+			seen[module] = true
+			modules = append(modules, module)
+		}
+	}
+	return packages, modules, nil
+}
+
+// packageAvailable reports whether the interpreter finds path in goPath,
+// vendored in the session module or directly in src
+func packageAvailable(goPath, path string) bool {
+	for _, dir := range []string{filepath.Join(goPath, "src", "vendor", path), filepath.Join(goPath, "src", path)} {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// addSessionPackages adds the packages paths to the session module at dir
+// and vendors them, running go get for targets: the packages themselves,
+// or the modules providing them at a given version. The packages are
+// listed in imports.go, so they stay required and vendored as more are
+// added.
+func addSessionPackages(dir string, paths, targets []string) error {
+	if _, err := exec.LookPath("go"); err != nil {
+		return fmt.Errorf("importing %s needs the go command: %w", strings.Join(paths, ", "), err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(dir, "go.mod")); os.IsNotExist(err) {
+		if err := runGoCommand(dir, "mod", "init", sessionModuleName); err != nil {
+			return err
+		}
+	}
+
+	importsFile := filepath.Join(dir, "imports.go")
+	previous, _ := os.ReadFile(importsFile)
+	all := append(importedPackages(strings.TrimPrefix(string(previous), "package session\n")), paths...)
+	if err := os.WriteFile(importsFile, sessionImportsFile(all), 0644); err != nil {
+		return err
+	}
+
+	getArgs := append([]string{"get"}, targets...)
+	err := runGoCommand(dir, getArgs...)
+	if err == nil {
+		err = runGoCommand(dir, "mod", "vendor")
+	}
+	if err != nil {
+		// Packages that failed mustn't break the next import
+		if previous == nil {
+			os.Remove(importsFile)
+		} else {
+			os.WriteFile(importsFile, previous, 0644)
+		}
+		return err
+	}
+	return nil
+}
+
+// sessionImportsFile returns the session module's imports.go for paths
+func sessionImportsFile(paths []string) []byte {
+	sort.Strings(paths)
+	var sb strings.Builder
+	sb.WriteString("package session\n\n// Packages imported in the gosh REPL, kept here so go mod vendor keeps them\nimport (\n")
+	for i, path := range paths {
+		if i > 0 && path == paths[i-1] {
+			continue
+		}
+		fmt.Fprintf(&sb, "\t_ %q\n", path)
+	}
+	sb.WriteString(")\n")
+	return []byte(sb.String())
+}
+
+// runGoCommand runs the go command in dir, returning its output as the
+// error if it fails
+func runGoCommand(dir string, args ...string) error {
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	// The session module is always resolved from go.mod, not its vendor
+	// directory, so go get can change it
+	cmd.Env = append(os.Environ(), "GOFLAGS="+strings.TrimSpace(os.Getenv("GOFLAGS")+" -mod=mod"), "GOWORK=off")
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(output.String()); message != "" {
+			return fmt.Errorf("go %s: %s", args[0], message)
+		}
+		return fmt.Errorf("go %s: %w", args[0], err)
+	}
+	return nil
+}
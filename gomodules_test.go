@@ -0,0 +1,150 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"archive/zip"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestImportedPackages(t *testing.T) {
+	code := "import (\n\t\"fmt\"\n\tc \"github.com/fatih/color\"\n)\nx := 1"
+	if got, want := importedPackages(code), []string{"fmt", "github.com/fatih/color"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("importedPackages = %q, want %q", got, want)
+	}
+	if got := importedPackages("x := 1"); len(got) != 0 {
+		t.Errorf("Expected no imports, got %q", got)
+	}
+
+	for path, want := range map[string]bool{"fmt": false, "net/http": false, "gosh": false, "shellapi/shellapi": false, "gosh_lib/shellapi": false, "golang.org/x/text": true, "example.com/hello": true} {
+		if got := isThirdPartyPackage(path); got != want {
+			t.Errorf("isThirdPartyPackage(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+// writeModuleProxy writes a GOPROXY directory serving example.com/hello
+func writeModuleProxy(t *testing.T) string {
+	proxy := t.TempDir()
+	dir := filepath.Join(proxy, "example.com", "hello", "@v")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"list":        "v1.0.0\n",
+		"v1.0.0.info": `{"Version":"v1.0.0","Time":"2024-01-01T00:00:00Z"}`,
+		"v1.0.0.mod":  "module example.com/hello\n\ngo 1.21\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := os.Create(filepath.Join(dir, "v1.0.0.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for name, content := range map[string]string{
+		"go.mod":   files["v1.0.0.mod"],
+		"hello.go": "package hello\n\nfunc Greet(name string) string { return \"hello, \" + name }\n",
+	} {
+		w, _ := zw.Create("example.com/hello@v1.0.0/" + name)
+		w.Write([]byte(content))
+	}
+	zw.Close()
+	f.Close()
+	return proxy
+}
+
+func TestGoEvaluator_ImportsThirdPartyPackages(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not available")
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GOPROXY", "file://"+writeModuleProxy(t))
+	t.Setenv("GONOSUMDB", "example.com")
+	t.Setenv("GOFLAGS", "-modcacherw")
+	t.Setenv("GOMODCACHE", t.TempDir())
+
+	evaluator := NewGoEvaluator()
+	if result := evaluator.Eval(`import "example.com/hello"`); result.Error != nil {
+		t.Fatalf("import failed: %s", result.Output)
+	}
+	if result := evaluator.Eval(`hello.Greet("gosh")`); !strings.Contains(result.Output, "hello, gosh") {
+		t.Errorf("Expected the package to be usable, got %q", result.Output)
+	}
+	if _, err := os.Stat(filepath.Join(goshConfigPath("gopath"), "src", "vendor", "example.com", "hello")); err != nil {
+		t.Errorf("Expected the package in the session module's vendor directory: %v", err)
+	}
+
+	result := NewGoEvaluator().Eval(`import "example.com/missing"`)
+	if result.ExitCode != 1 || !strings.Contains(result.Output, "example.com/missing") {
+		t.Errorf("Expected a failed download to be reported, got %q", result.Output)
+	}
+	// The failed package isn't kept, so later imports still work
+	if data, _ := os.ReadFile(filepath.Join(goshConfigPath("gopath"), "src", "imports.go")); strings.Contains(string(data), "missing") {
+		t.Errorf("Expected the failed import to be dropped, got %s", data)
+	}
+}
+
+func TestFindGoProject(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "go.mod"), []byte("// A project\nmodule example.com/app\n\ngo 1.21\n"), 0644)
+	sub := filepath.Join(root, "internal", "greet")
+	os.MkdirAll(sub, 0755)
+
+	p := findGoProject(sub)
+	if p == nil || p.root != root || p.path != "example.com/app" {
+		t.Fatalf("findGoProject = %+v", p)
+	}
+	if !p.contains("example.com/app/internal/greet") || p.contains("example.com/application") {
+		t.Error("Unexpected contains result")
+	}
+	if findGoProject(t.TempDir()) != nil {
+		t.Error("Expected no project outside a module")
+	}
+}
+
+func TestGoEvaluator_ImportsProjectPackages(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not available")
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GOPROXY", "file://"+writeModuleProxy(t))
+	t.Setenv("GONOSUMDB", "example.com")
+	t.Setenv("GOFLAGS", "-modcacherw")
+	t.Setenv("GOMODCACHE", t.TempDir())
+
+	root, _ := filepath.EvalSymlinks(t.TempDir())
+	files := map[string]string{
+		"go.mod":          "module example.com/app\n\ngo 1.21\n\nrequire example.com/hello v1.0.0\n",
+		"greet/greet.go":  "package greet\n\nimport \"example.com/hello\"\n\nfunc Loudly(name string) string { return hello.Greet(name) + \"!\" }\n",
+		"cmd/app/main.go": "package main\n\nimport \"example.com/app/greet\"\n\nfunc main() { println(greet.Loudly(\"app\")) }\n",
+	}
+	for name, content := range files {
+		os.MkdirAll(filepath.Dir(filepath.Join(root, name)), 0755)
+		os.WriteFile(filepath.Join(root, name), []byte(content), 0644)
+	}
+	tidy := exec.Command("go", "mod", "tidy")
+	tidy.Dir = root
+	if output, err := tidy.CombinedOutput(); err != nil {
+		t.Fatalf("go mod tidy: %v\n%s", err, output)
+	}
+
+	state := &ShellState{WorkingDirectory: root, Environment: map[string]string{}}
+	evaluator := NewGoEvaluator()
+	evaluator.SetupWithShell(state, NewProcessSpawner(state))
+	if result := evaluator.Eval(`import "example.com/app/greet"`); result.Error != nil {
+		t.Fatalf("import failed: %s", result.Output)
+	}
+	if result := evaluator.Eval(`greet.Loudly("gosh")`); !strings.Contains(result.Output, "hello, gosh!") {
+		t.Errorf("Expected the project's package to be usable, got %q", result.Output)
+	}
+}
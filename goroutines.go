@@ -0,0 +1,220 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// replGoroutine is a goroutine started by a go statement in the REPL
+type replGoroutine struct {
+	ID      int
+	Code    string // The call it runs
+	Started time.Time
+	Stopped bool   // :stop asked it to end
+	Panic   string // What it panicked with, once it has
+	gid     uint64 // The runtime's number for the goroutine
+	stop    chan struct{}
+}
+
+// Status describes what the goroutine is doing, for :goroutines
+func (r replGoroutine) Status() string {
+	switch {
+	case r.Panic != "":
+		return "panicked"
+	case r.Stopped:
+		return "stopping"
+	}
+	return "running"
+}
+
+// goroutineTracker keeps the goroutines started in the REPL while they
+// run, and those that panicked until they're cleared with :stop
+type goroutineTracker struct {
+	mu         sync.Mutex
+	nextID     int
+	goroutines map[int]*replGoroutine
+}
+
+// replGoroutines tracks the goroutines of every interpreter, which keep
+// running after a :reset
+var replGoroutines = &goroutineTracker{goroutines: map[int]*replGoroutine{}}
+
+// Go runs fn in a tracked goroutine, returning its ID. A panic ends the
+// goroutine rather than gosh.
+func (t *goroutineTracker) Go(code string, fn func()) int {
+	t.mu.Lock()
+	t.nextID++
+	g := &replGoroutine{ID: t.nextID, Code: code, Started: time.Now(), stop: make(chan struct{})}
+	t.goroutines[g.ID] = g
+	t.mu.Unlock()
+
+	started := make(chan struct{})
+	go func() {
+		defer func() {
+			r := recover()
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if r != nil {
+				g.Panic = fmt.Sprint(r)
+			} else {
+				delete(t.goroutines, g.ID)
+			}
+		}()
+		t.mu.Lock()
+		g.gid = currentGoroutineID()
+		t.mu.Unlock()
+		close(started)
+		fn()
+	}()
+	// Done works as soon as the goroutine does
+	<-started
+	return g.ID
+}
+
+// List returns the tracked goroutines in the order they started
+func (t *goroutineTracker) List() []replGoroutine {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	list := make([]replGoroutine, 0, len(t.goroutines))
+	for _, g := range t.goroutines {
+		list = append(list, *g)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// Stop asks the goroutine id to end, closing the channel its Done returns.
+// Goroutines can't be ended from outside, so it's up to the goroutine to
+// return. A goroutine that panicked is cleared from the list instead.
+func (t *goroutineTracker) Stop(id int) (cleared bool, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	g, ok := t.goroutines[id]
+	switch {
+	case !ok:
+		return false, fmt.Errorf("no goroutine %d", id)
+	case g.Panic != "":
+		delete(t.goroutines, id)
+		return true, nil
+	case !g.Stopped:
+		g.Stopped = true
+		close(g.stop)
+	}
+	return false, nil
+}
+
+// Done returns a channel that's closed when the calling goroutine is
+// stopped with :stop, or nil, which is never ready, if it isn't tracked
+func (t *goroutineTracker) Done() <-chan struct{} {
+	gid := currentGoroutineID()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, g := range t.goroutines {
+		if g.gid == gid {
+			return g.stop
+		}
+	}
+	return nil
+}
+
+// currentGoroutineID returns the runtime's number for the calling
+// goroutine, which is only to be had from its stack trace
+func currentGoroutineID() uint64 {
+	var buf [64]byte
+	stack := bytes.TrimPrefix(buf[:runtime.Stack(buf[:], false)], []byte("goroutine "))
+	id, _ := strconv.ParseUint(string(stack[:bytes.IndexByte(stack, ' ')]), 10, 64)
+	return id
+}
+
+// trackGoStatements rewrites the go statements of code to start tracked
+// goroutines through gosh.Go. The call's arguments are then evaluated in
+// the goroutine rather than before it starts, which only matters if they
+// change in the meantime.
+func trackGoStatements(code string) string {
+	if !strings.Contains(code, "go") {
+		return code
+	}
+
+	prefix := "package main\n"
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", prefix+code, 0)
+	if err != nil {
+		prefix = "package main\nfunc _() {\n"
+		if file, err = parser.ParseFile(fset, "", prefix+code+"\n}", 0); err != nil {
+			return code
+		}
+	}
+
+	offset := func(pos token.Pos) int { return fset.Position(pos).Offset - len(prefix) }
+	type edit struct {
+		start, end int
+		text       string
+	}
+	var edits []edit
+	ast.Inspect(file, func(n ast.Node) bool {
+		stmt, ok := n.(*ast.GoStmt)
+		if !ok {
+			return true
+		}
+		call := code[offset(stmt.Call.Pos()):offset(stmt.Call.End())]
+		// Go statements inside the call, as in go func() { go f() }(), are
+		// rewritten with it
+		text := fmt.Sprintf("_ = gosh.Go(%s, func() { %s })", strconv.Quote(strings.Join(strings.Fields(call), " ")), trackGoStatements(call))
+		edits = append(edits, edit{offset(stmt.Pos()), offset(stmt.End()), text})
+		return false
+	})
+
+	for i := len(edits) - 1; i >= 0; i-- {
+		code = code[:edits[i].start] + edits[i].text + code[edits[i].end:]
+	}
+	return code
+}
+
+// describeGoroutines lists the goroutines started in the REPL, for
+// :goroutines
+func describeGoroutines() string {
+	list := replGoroutines.List()
+	if len(list) == 0 {
+		return "No goroutines running"
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%-4s %-8s %-10s %s", "ID", "RUNNING", "STATUS", "CODE"))
+	for _, g := range list {
+		running := time.Since(g.Started).Round(time.Second).String()
+		sb.WriteString(fmt.Sprintf("\n%-4d %-8s %-10s %s", g.ID, running, g.Status(), truncateCell(g.Code, 60)))
+		if g.Panic != "" {
+			sb.WriteString("\n     panic: " + truncateCell(g.Panic, 70))
+		}
+	}
+	return sb.String()
+}
+
+// stopGoroutine runs :stop with args
+func stopGoroutine(args []string) string {
+	if len(args) != 1 {
+		return "Usage: :stop ID"
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Sprintf(":stop: invalid goroutine id: %s", args[0])
+	}
+	cleared, err := replGoroutines.Stop(id)
+	switch {
+	case err != nil:
+		return fmt.Sprintf(":stop: %v", err)
+	case cleared:
+		return fmt.Sprintf("Cleared goroutine %d", id)
+	}
+	return fmt.Sprintf("Asked goroutine %d to stop; it ends when it sees gosh.Done() closed", id)
+}
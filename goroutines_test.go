@@ -0,0 +1,107 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrackGoStatements(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{"go worker(1)", `_ = gosh.Go("worker(1)", func() { worker(1) })`},
+		{"for i := 0; i < 3; i++ {\n\tgo worker(i)\n}", "for i := 0; i < 3; i++ {\n\t_ = gosh.Go(\"worker(i)\", func() { worker(i) })\n}"},
+		{"func start() { go func() {\n\tgo tick()\n}() }", "func start() { _ = gosh.Go(\"func() { go tick() }()\", func() { func() {\n\t_ = gosh.Go(\"tick()\", func() { tick() })\n}() }) }"},
+		// Code without go statements is left as it is
+		{`goal := "go home"`, `goal := "go home"`},
+	}
+	for _, tt := range tests {
+		if got := trackGoStatements(tt.code); got != tt.expected {
+			t.Errorf("trackGoStatements(%q) = %q, want %q", tt.code, got, tt.expected)
+		}
+	}
+}
+
+// skipUnderRace skips tests that leave interpreted code running while
+// the next Eval starts. yaegi shares its top-level frame between
+// evaluations without locking it, so the race detector reports code
+// that's still running, or that gosh has waited on by watching for it
+// to stop, as a race.
+func skipUnderRace(t *testing.T) {
+	t.Helper()
+	if raceEnabled {
+		t.Skip("yaegi doesn't synchronize code left running with the next Eval")
+	}
+}
+
+// waitForGoroutines waits for the goroutines running code to number n
+func waitForGoroutines(t *testing.T, code string, n int) []replGoroutine {
+	t.Helper()
+	var found []replGoroutine
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		found = nil
+		for _, g := range replGoroutines.List() {
+			if g.Code == code {
+				found = append(found, g)
+			}
+		}
+		if len(found) == n {
+			return found
+		}
+	}
+	t.Fatalf("Expected %d goroutines running %s, found %d", n, code, len(found))
+	return nil
+}
+
+func TestGoroutineTracking(t *testing.T) {
+	skipUnderRace(t)
+	state := NewShellState()
+	eval := NewGoEvaluator()
+	eval.SetupWithShell(state, NewProcessSpawner(state))
+
+	for _, code := range []string{
+		`func trackedWorker(n int) { <-gosh.Done() }`,
+		`go trackedWorker(1)`,
+		`go func() { panic("tracked boom") }()`,
+	} {
+		if result := eval.Eval(code); result.ExitCode != 0 {
+			t.Fatalf("Eval(%q) failed: %s", code, result.Output)
+		}
+	}
+
+	worker := waitForGoroutines(t, "trackedWorker(1)", 1)[0]
+	if worker.Status() != "running" {
+		t.Errorf("Expected the worker to be running, got %s", worker.Status())
+	}
+	if list := describeGoroutines(); !strings.Contains(list, "trackedWorker(1)") {
+		t.Errorf("Expected the worker in :goroutines, got %q", list)
+	}
+
+	// Stopping closes gosh.Done, so the worker returns and is dropped
+	if output := stopGoroutine([]string{strconv.Itoa(worker.ID)}); !strings.Contains(output, "Asked goroutine") {
+		t.Errorf("Unexpected :stop output %q", output)
+	}
+	waitForGoroutines(t, "trackedWorker(1)", 0)
+
+	// A goroutine that panicked stays listed until it's cleared
+	panicked := waitForGoroutines(t, `func() { panic("tracked boom") }()`, 1)[0]
+	for deadline := time.Now().Add(2 * time.Second); panicked.Panic == "" && time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		panicked = waitForGoroutines(t, panicked.Code, 1)[0]
+	}
+	if list := describeGoroutines(); !strings.Contains(list, "panicked") || !strings.Contains(list, "panic: tracked boom") {
+		t.Errorf("Expected the panic in :goroutines, got %q", list)
+	}
+	if output := stopGoroutine([]string{strconv.Itoa(panicked.ID)}); !strings.HasPrefix(output, "Cleared") {
+		t.Errorf("Expected the panicked goroutine to be cleared, got %q", output)
+	}
+	waitForGoroutines(t, `func() { panic("tracked boom") }()`, 0)
+
+	if output := stopGoroutine([]string{"x"}); !strings.Contains(output, "invalid goroutine id") {
+		t.Errorf("Expected an invalid id error, got %q", output)
+	}
+}
@@ -0,0 +1,299 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// goshSymbols is the `gosh` package available to Go code and config files.
+// It's pre-imported, so gosh.CopyToClipboard("...") works without an import.
+func goshSymbols() map[string]map[string]reflect.Value {
+	return map[string]map[string]reflect.Value{
+		"gosh/gosh": {
+			"CopyToClipboard": reflect.ValueOf(func(s string) error {
+				_, err := copyToClipboard(goshAPIEnvironment(), s)
+				return err
+			}),
+			"PasteFromClipboard": reflect.ValueOf(func() (string, error) {
+				return pasteFromClipboard(goshAPIEnvironment())
+			}),
+
+			// Structured capture
+			"JSON":     reflect.ValueOf(captureJSON),
+			"JSONInto": reflect.ValueOf(captureJSONInto),
+			"CSV":      reflect.ValueOf(captureCSV),
+			"CSVRows":  reflect.ValueOf(captureCSVRows),
+
+			// HTTP
+			"HTTPGet":  reflect.ValueOf(httpGet),
+			"HTTPJSON": reflect.ValueOf(httpJSON),
+
+			// Secrets from the encrypted vault
+			"Secret": reflect.ValueOf(goshSecret),
+
+			// Testing config functions with `gosh test-config`
+			"T": reflect.ValueOf((*ConfigTest)(nil)),
+
+			// Exit code of the last shell command or Go block, like $?
+			"LastExitCode": reflect.ValueOf(func() int {
+				if state := goshAPIState(); state != nil {
+					return state.LastExitCode
+				}
+				return 0
+			}),
+
+			// The working directory, which Chdir changes as cd does,
+			// running the chpwd hooks
+			"Cwd": reflect.ValueOf(func() string {
+				if state := goshAPIState(); state != nil {
+					return state.WorkingDirectory
+				}
+				wd, _ := os.Getwd()
+				return wd
+			}),
+			"Chdir": reflect.ValueOf(func(dir string) error {
+				state := goshAPIState()
+				if state == nil {
+					return fmt.Errorf("gosh.Chdir: no shell session")
+				}
+				if result := NewBuiltinHandler(state).changeDirectory("gosh.Chdir", dir); result.ExitCode != 0 {
+					return errors.New(result.Output)
+				}
+				return nil
+			}),
+
+			// The shell's environment, as export and unset change it
+			"Env": reflect.ValueOf(func() map[string]string {
+				return maps.Clone(goshAPIEnvironment())
+			}),
+			"SetEnv": reflect.ValueOf(func(name, value string) error {
+				state := goshAPIState()
+				if state == nil {
+					return fmt.Errorf("gosh.SetEnv: no shell session")
+				}
+				if !isVariableName(name) {
+					return fmt.Errorf("gosh.SetEnv: %s: not a valid identifier", name)
+				}
+				state.SetEnv(name, value)
+				return nil
+			}),
+			"UnsetEnv": reflect.ValueOf(func(name string) {
+				if state := goshAPIState(); state != nil {
+					state.UnsetEnv(name)
+				}
+			}),
+
+			// Background and stopped jobs, as the jobs builtin lists them
+			"Job": reflect.ValueOf((*Job)(nil)),
+			"Jobs": reflect.ValueOf(func() []Job {
+				state := goshAPIState()
+				if state == nil {
+					return nil
+				}
+				jobs := state.Jobs().List()
+				sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+				return jobs
+			}),
+
+			// The prompt as it's shown, colors included
+			"Prompt": reflect.ValueOf(func() string {
+				if state := goshAPIState(); state != nil {
+					return state.GetPrompt()
+				}
+				return ""
+			}),
+
+			// Command aliases, as defined by the alias builtin
+			"Alias": reflect.ValueOf(func(name, value string) error {
+				state := goshAPIState()
+				if state == nil {
+					return fmt.Errorf("gosh.Alias: no shell session")
+				}
+				return state.SetAlias(name, value)
+			}),
+
+			// Go functions run as commands: RegisterCommand("deploy",
+			// deploy) makes "deploy prod" call deploy("prod")
+			"RegisterCommand": reflect.ValueOf(func(name string, fn interface{}) error {
+				state := goshAPIState()
+				if state == nil {
+					return fmt.Errorf("gosh.RegisterCommand: no shell session")
+				}
+				if err := state.RegisterCommand(name, fn); err != nil {
+					return fmt.Errorf("gosh.RegisterCommand: %w", err)
+				}
+				return nil
+			}),
+
+			// What plugins add to completion and the prompt
+			"RegisterCompletion": reflect.ValueOf(func(command string, fn func(args []string, partial string) []string) error {
+				state := goshAPIState()
+				if state == nil {
+					return fmt.Errorf("gosh.RegisterCompletion: no shell session")
+				}
+				if err := state.RegisterCompletion(command, fn); err != nil {
+					return fmt.Errorf("gosh.RegisterCompletion: %w", err)
+				}
+				return nil
+			}),
+			"PromptSegment": reflect.ValueOf(func(name string, fn func() string) error {
+				state := goshAPIState()
+				if state == nil {
+					return fmt.Errorf("gosh.PromptSegment: no shell session")
+				}
+				if err := state.AddPromptSegment(name, fn); err != nil {
+					return fmt.Errorf("gosh.PromptSegment: %w", err)
+				}
+				return nil
+			}),
+
+			// Standard library packages Go code can use without importing
+			// them, besides fmt, os, strings, strconv and path/filepath
+			"PreImport": reflect.ValueOf(func(paths ...string) error {
+				state := goshAPIState()
+				if state == nil {
+					return fmt.Errorf("gosh.PreImport: no shell session")
+				}
+				if err := state.PreImport(paths...); err != nil {
+					return fmt.Errorf("gosh.PreImport: %w", err)
+				}
+				return nil
+			}),
+
+			// autocd: entering just a directory changes into it
+			"AutoCd": reflect.ValueOf(func(enabled bool) {
+				if state := goshAPIState(); state != nil {
+					state.autoCd = enabled
+				}
+			}),
+
+			// How Go results are shown, as the format builtin sets it
+			"ResultFormat": reflect.ValueOf(func(format string) error {
+				state := goshAPIState()
+				if state == nil {
+					return fmt.Errorf("gosh.ResultFormat: no shell session")
+				}
+				switch format {
+				case ResultFormatTable, ResultFormatJSON, ResultFormatGo:
+					state.resultFormat = format
+					return nil
+				}
+				return fmt.Errorf("gosh.ResultFormat: unknown format: %s (use table, json or go)", format)
+			}),
+
+			// Goroutines started by go statements in the REPL, which end
+			// when Done is closed by :stop
+			"Go":   reflect.ValueOf(replGoroutines.Go),
+			"Done": reflect.ValueOf(replGoroutines.Done),
+
+			// Run a command, as sh(...) and $(...) in Go code do
+			"Sh":     reflect.ValueOf(runSh),
+			"Output": reflect.ValueOf(shOutput),
+
+			// Longest Go code typed in the REPL may run, as a Go duration
+			// such as "30s"; "0" for no limit
+			"EvalTimeout": reflect.ValueOf(func(timeout string) error {
+				state := goshAPIState()
+				if state == nil {
+					return fmt.Errorf("gosh.EvalTimeout: no shell session")
+				}
+				d, err := time.ParseDuration(timeout)
+				if err != nil || d < 0 {
+					return fmt.Errorf("gosh.EvalTimeout: invalid duration: %s", timeout)
+				}
+				state.evalTimeout = d
+				return nil
+			}),
+
+			// How far Go code typed in the REPL may grow the heap before
+			// gosh warns about it and stops it, as sizes such as "512MB";
+			// "" or "0" for no limit
+			"MemoryGuard": reflect.ValueOf(func(warn, limit string) error {
+				state := goshAPIState()
+				if state == nil {
+					return fmt.Errorf("gosh.MemoryGuard: no shell session")
+				}
+				sizes := make([]uint64, 2)
+				for i, size := range []string{warn, limit} {
+					if size == "" {
+						continue
+					}
+					n, err := parseByteSize(size)
+					if err != nil {
+						return fmt.Errorf("gosh.MemoryGuard: %w", err)
+					}
+					sizes[i] = n
+				}
+				state.memoryWarn, state.memoryLimit = sizes[0], sizes[1]
+				return nil
+			}),
+
+			// Run a command line as the eval builtin does, returning its
+			// output and an error if it fails
+			"Eval": reflect.ValueOf(func(line string) (string, error) {
+				state := goshAPIState()
+				if state == nil {
+					return "", fmt.Errorf("gosh.Eval: no shell session")
+				}
+				state.importFromProcess()
+				result := NewBuiltinHandler(state).Execute("eval", []string{line})
+				if result.ExitCode != 0 {
+					return result.Output, fmt.Errorf("gosh.Eval: exit status %d", result.ExitCode)
+				}
+				return result.Output, nil
+			}),
+
+			// Scheduled tasks, run in the background of interactive sessions
+			"Every": reflect.ValueOf(func(interval string, fn func()) (int, error) {
+				state := goshAPIState()
+				if state == nil {
+					return 0, fmt.Errorf("gosh.Every: no shell session")
+				}
+				return state.Scheduler().Every(interval, fn)
+			}),
+
+			// Run fn on a signal (HUP, INT or TERM) or when gosh exits
+			// (EXIT), as the trap builtin does; a nil fn resets the trap
+			"Trap": reflect.ValueOf(func(signal string, fn func()) error {
+				state := goshAPIState()
+				if state == nil {
+					return fmt.Errorf("gosh.Trap: no shell session")
+				}
+				name, err := trapName(signal)
+				if err != nil {
+					return fmt.Errorf("gosh.Trap: %w", err)
+				}
+				if fn == nil {
+					state.Traps().Remove(name)
+				} else {
+					state.Traps().Set(name, trapAction{fn: fn})
+				}
+				return nil
+			}),
+		},
+	}
+}
+
+// goshAPIState returns the interactive shell's state, or nil
+func goshAPIState() *ShellState {
+	shellStateMutex.Lock()
+	defer shellStateMutex.Unlock()
+	return globalShellState
+}
+
+// goshAPIEnvironment returns the shell's environment for gosh API calls,
+// falling back to the process environment outside the interactive shell
+func goshAPIEnvironment() map[string]string {
+	if state := goshAPIState(); state != nil {
+		state.importFromProcess()
+		return state.Environment
+	}
+	return processEnvironment()
+}
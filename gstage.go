@@ -0,0 +1,270 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// gitStatusEntry is one entry of `git status --porcelain -z`
+type gitStatusEntry struct {
+	Index    byte   // Staged status (X column)
+	Worktree byte   // Unstaged status (Y column)
+	Path     string // Path (new path for renames)
+	OrigPath string // Original path for renames/copies
+}
+
+// Staged reports whether the entry has changes in the index
+func (e gitStatusEntry) Staged() bool {
+	return e.Index != ' ' && e.Index != '?' && e.Index != '!'
+}
+
+// Unstaged reports whether the entry has worktree changes or is untracked
+func (e gitStatusEntry) Unstaged() bool {
+	return e.Worktree != ' '
+}
+
+// parseGitPorcelain parses `git status --porcelain -z` (v1) output, where
+// paths are NUL terminated rather than quoted, and a rename or copy is
+// followed by its original path
+func parseGitPorcelain(output string) []gitStatusEntry {
+	var entries []gitStatusEntry
+	fields := strings.Split(output, "\x00")
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		if len(field) < 4 {
+			continue
+		}
+		entry := gitStatusEntry{Index: field[0], Worktree: field[1], Path: field[3:]}
+		if (entry.Index == 'R' || entry.Index == 'C' || entry.Worktree == 'R' || entry.Worktree == 'C') && i+1 < len(fields) {
+			i++
+			entry.OrigPath = fields[i]
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// gitStatusRefreshMsg delivers a fresh status listing
+type gitStatusRefreshMsg struct {
+	entries []gitStatusEntry
+	err     error
+}
+
+// gstageModel is a small git status/stage TUI in the spirit of gitui
+type gstageModel struct {
+	dir     string
+	env     []string
+	entries []gitStatusEntry
+	cursor  int
+	message string
+	err     error
+}
+
+func newGstageModel(dir string, env []string) gstageModel {
+	return gstageModel{dir: dir, env: env}
+}
+
+func (m gstageModel) git(args ...string) *exec.Cmd {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = m.dir
+	cmd.Env = m.env
+	return cmd
+}
+
+func (m gstageModel) refresh() tea.Msg {
+	output, err := m.git("status", "--porcelain", "-z", "--untracked-files=all").Output()
+	if err != nil {
+		return gitStatusRefreshMsg{err: err}
+	}
+	return gitStatusRefreshMsg{entries: parseGitPorcelain(string(output))}
+}
+
+func (m gstageModel) Init() tea.Cmd {
+	return m.refresh
+}
+
+func (m gstageModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case gitStatusRefreshMsg:
+		m.entries = msg.entries
+		m.err = msg.err
+		if m.cursor >= len(m.entries) {
+			m.cursor = len(m.entries) - 1
+		}
+		if m.cursor < 0 {
+			m.cursor = 0
+		}
+		return m, nil
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "esc", "ctrl+c":
+			return m, tea.Quit
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down", "j":
+			if m.cursor < len(m.entries)-1 {
+				m.cursor++
+			}
+		case "r":
+			m.message = ""
+			return m, m.refresh
+		case "a":
+			return m.run("Staged all changes", "add", "--all")
+		case " ", "s", "enter":
+			if entry, ok := m.selected(); ok {
+				return m.run("Staged "+entry.Path, "add", "--", entry.Path)
+			}
+		case "u":
+			if entry, ok := m.selected(); ok {
+				return m.unstage(entry)
+			}
+		case "d":
+			if entry, ok := m.selected(); ok {
+				return m, m.diff(entry)
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m gstageModel) selected() (gitStatusEntry, bool) {
+	if m.cursor < 0 || m.cursor >= len(m.entries) {
+		return gitStatusEntry{}, false
+	}
+	return m.entries[m.cursor], true
+}
+
+// run executes a git action and refreshes the listing
+func (m gstageModel) run(success string, args ...string) (tea.Model, tea.Cmd) {
+	if output, err := m.git(args...).CombinedOutput(); err != nil {
+		m.message = strings.TrimSpace(string(output))
+		if m.message == "" {
+			m.message = err.Error()
+		}
+	} else {
+		m.message = success
+	}
+	return m, m.refresh
+}
+
+// unstage takes the entry's changes out of the index: back to HEAD, or
+// out of it altogether before the first commit, when there's no HEAD
+func (m gstageModel) unstage(entry gitStatusEntry) (tea.Model, tea.Cmd) {
+	paths := []string{entry.Path}
+	if entry.OrigPath != "" {
+		paths = append(paths, entry.OrigPath)
+	}
+	if err := m.git("rev-parse", "--verify", "-q", "HEAD").Run(); err != nil {
+		return m.run("Unstaged "+entry.Path, append([]string{"rm", "-q", "--cached", "--"}, paths...)...)
+	}
+	return m.run("Unstaged "+entry.Path, append([]string{"restore", "--staged", "--"}, paths...)...)
+}
+
+// diff shows the entry's diff through git's pager with the terminal handed over
+func (m gstageModel) diff(entry gitStatusEntry) tea.Cmd {
+	var cmd *exec.Cmd
+	switch {
+	case entry.Index == '?':
+		cmd = m.git("diff", "--no-index", "--", "/dev/null", entry.Path)
+	case entry.Staged() && !entry.Unstaged():
+		cmd = m.git("diff", "--cached", "--", entry.Path)
+	default:
+		cmd = m.git("diff", "--", entry.Path)
+	}
+	return tea.ExecProcess(cmd, func(error) tea.Msg { return m.refresh() })
+}
+
+var (
+	gstageStagedStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	gstageUnstagedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	gstageCursorStyle   = lipgloss.NewStyle().Bold(true)
+	gstageHelpStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+)
+
+func (m gstageModel) View() string {
+	var sb strings.Builder
+	sb.WriteString(gstageCursorStyle.Render("git status") + "\n\n")
+
+	if m.err != nil {
+		sb.WriteString(fmt.Sprintf("error: %v\n", m.err))
+	} else if len(m.entries) == 0 {
+		sb.WriteString("  nothing to commit, working tree clean\n")
+	}
+
+	for i, entry := range m.entries {
+		pointer := "  "
+		if i == m.cursor {
+			pointer = "> "
+		}
+
+		x := string(entry.Index)
+		y := string(entry.Worktree)
+		if entry.Staged() {
+			x = gstageStagedStyle.Render(x)
+		}
+		if entry.Unstaged() {
+			y = gstageUnstagedStyle.Render(y)
+		}
+
+		path := entry.Path
+		if entry.OrigPath != "" {
+			path = entry.OrigPath + " -> " + entry.Path
+		}
+		if i == m.cursor {
+			path = gstageCursorStyle.Render(path)
+		}
+
+		sb.WriteString(pointer + x + y + " " + path + "\n")
+	}
+
+	if m.message != "" {
+		sb.WriteString("\n" + m.message + "\n")
+	}
+
+	sb.WriteString("\n" + gstageHelpStyle.Render("space/s: stage  u: unstage  d: diff  a: stage all  r: refresh  q: quit"))
+	return sb.String()
+}
+
+// gstage implements the gstage builtin
+func (b *BuiltinHandler) gstage(args []string) ExecutionResult {
+	if !isInGitRepo(b.state.WorkingDirectory) {
+		return ExecutionResult{Output: "gstage: not a git repository", ExitCode: 1, Error: fmt.Errorf("not a git repository")}
+	}
+
+	m := newGstageModel(b.state.WorkingDirectory, b.state.EnvironmentSlice())
+	err := withTerminal(func() error {
+		_, err := tea.NewProgram(m).Run()
+		return err
+	})
+	if err != nil {
+		return ExecutionResult{Output: fmt.Sprintf("gstage: %v", err), ExitCode: 1, Error: err}
+	}
+
+	// Leave a summary of the final state behind
+	status, _ := m.git("status", "--short").CombinedOutput()
+	return ExecutionResult{Output: strings.TrimRight(string(status), "\n"), ExitCode: 0}
+}
+
+const gstageHelpText = "gstage - Interactive Git Status\n\n" +
+	"USAGE:\n" +
+	"    gstage\n\n" +
+	"DESCRIPTION:\n" +
+	"    Show `git status` as a navigable list and stage, unstage or diff\n" +
+	"    files with single key presses.\n\n" +
+	"KEYS:\n" +
+	"    up/down, j/k   Move\n" +
+	"    space, s       Stage the selected file\n" +
+	"    u              Unstage the selected file\n" +
+	"    d              Show the diff for the selected file\n" +
+	"    a              Stage everything\n" +
+	"    r              Refresh\n" +
+	"    q, esc         Quit"
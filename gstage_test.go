@@ -0,0 +1,123 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbletea"
+)
+
+func TestParseGitPorcelain(t *testing.T) {
+	output := "M  staged.go\x00 M unstaged.go\x00MM both.go\x00?? new \"file\".txt\x00R  renamed.go\x00old.go\x00 M café.go\x00"
+	entries := parseGitPorcelain(output)
+
+	if len(entries) != 6 {
+		t.Fatalf("Expected 6 entries, got %d", len(entries))
+	}
+
+	tests := []struct {
+		path     string
+		staged   bool
+		unstaged bool
+	}{
+		{"staged.go", true, false},
+		{"unstaged.go", false, true},
+		{"both.go", true, true},
+		{`new "file".txt`, false, true},
+		{"renamed.go", true, false},
+		{"café.go", false, true},
+	}
+
+	for i, tt := range tests {
+		e := entries[i]
+		if e.Path != tt.path || e.Staged() != tt.staged || e.Unstaged() != tt.unstaged {
+			t.Errorf("Entry %d = %+v (staged=%v unstaged=%v), expected %+v", i, e, e.Staged(), e.Unstaged(), tt)
+		}
+	}
+
+	if entries[4].OrigPath != "old.go" {
+		t.Errorf("Expected rename origin old.go, got %q", entries[4].OrigPath)
+	}
+}
+
+func TestGstageModel_Navigation(t *testing.T) {
+	m := newGstageModel(t.TempDir(), nil)
+	updated, _ := m.Update(gitStatusRefreshMsg{entries: parseGitPorcelain(" M a.go\x00 M b.go\x00")})
+	m = updated.(gstageModel)
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	m = updated.(gstageModel)
+	if m.cursor != 1 {
+		t.Errorf("Expected cursor 1 after j, got %d", m.cursor)
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m = updated.(gstageModel)
+	if m.cursor != 1 {
+		t.Errorf("Cursor should stop at the last entry, got %d", m.cursor)
+	}
+
+	if !strings.Contains(m.View(), "> ") || !strings.Contains(m.View(), "b.go") {
+		t.Errorf("View should show the cursor and entries: %q", m.View())
+	}
+
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'q'}})
+	if cmd == nil {
+		t.Error("q should quit")
+	}
+}
+
+func TestGstageModel_StageAndUnstage(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	env := append(os.Environ(), "GIT_CONFIG_GLOBAL=/dev/null", "GIT_AUTHOR_NAME=gosh", "GIT_AUTHOR_EMAIL=gosh@example.com",
+		"GIT_COMMITTER_NAME=gosh", "GIT_COMMITTER_EMAIL=gosh@example.com")
+	m := newGstageModel(dir, env)
+	if output, err := m.git("init", "-q").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, output)
+	}
+	// Names git would quote without -z
+	name := "caf\u00e9 \"menu\".txt"
+	os.WriteFile(filepath.Join(dir, name), []byte("espresso\n"), 0644)
+
+	// key presses key, then refreshes the listing as the command it
+	// returns would, returning the one entry
+	key := func(key rune) gitStatusEntry {
+		t.Helper()
+		updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{key}})
+		m = updated.(gstageModel)
+		updated, _ = m.Update(m.refresh())
+		m = updated.(gstageModel)
+		if m.err != nil || len(m.entries) != 1 {
+			t.Fatalf("Unexpected status %+v (%v)", m.entries, m.err)
+		}
+		return m.entries[0]
+	}
+	if entry := key('r'); entry.Path != name || entry.Index != '?' {
+		t.Fatalf("Expected %q untracked, got %+v", name, entry)
+	}
+	if entry := key('s'); entry.Index != 'A' {
+		t.Fatalf("Expected %q staged, got %+v (%s)", name, entry, m.message)
+	}
+	// Before the first commit there's no HEAD to reset to
+	if entry := key('u'); entry.Index != '?' {
+		t.Errorf("Expected %q unstaged, got %+v (%s)", name, entry, m.message)
+	}
+
+	m.run("Staged all changes", "add", "--all")
+	m.run("Committed", "commit", "-q", "-m", "Menu")
+	os.WriteFile(filepath.Join(dir, name), []byte("ristretto\n"), 0644)
+	if entry := key('s'); entry.Index != 'M' {
+		t.Fatalf("Expected %q staged, got %+v (%s)", name, entry, m.message)
+	}
+	if entry := key('u'); entry.Index != ' ' || entry.Worktree != 'M' {
+		t.Errorf("Expected %q modified but unstaged, got %+v (%s)", name, entry, m.message)
+	}
+}
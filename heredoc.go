@@ -0,0 +1,134 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// errUnterminatedHeredoc is returned for input that ends before a heredoc's
+// delimiter line. The REPL keeps reading lines until it arrives.
+var errUnterminatedHeredoc = errors.New("syntax error: unterminated here-document")
+
+// heredocStart records a << whose body follows the current line
+type heredocStart struct {
+	word      int  // Index of the delimiter word
+	stripTabs bool // <<- strips leading tabs from the body and delimiter
+}
+
+// readHeredocs reads the bodies of the heredocs started on a line from the
+// lines from start on, replacing each delimiter word with its body. It
+// returns where the next line starts.
+func readHeredocs(words []shellWord, heredocs []heredocStart, runes []rune, start int) (int, error) {
+	pos := start
+	for _, h := range heredocs {
+		if h.word >= len(words) || words[h.word].Operator {
+			return 0, fmt.Errorf("syntax error: %s needs a delimiter", words[h.word-1].Text)
+		}
+
+		delimiter := words[h.word]
+		body, next, ok := readHeredocBody(runes, pos, delimiter.Text, h.stripTabs)
+		if !ok {
+			return 0, fmt.Errorf("%w (wanted %s)", errUnterminatedHeredoc, delimiter.Text)
+		}
+		word := shellWord{Text: body, heredocDelimiter: delimiter.Text}
+		// Quoting any of the delimiter keeps the body literal
+		if !delimiter.quoted && strings.ContainsAny(body, "$\\") {
+			word.raw = escapeHeredoc(body)
+		}
+		words[h.word] = word
+		pos = next
+	}
+	return pos, nil
+}
+
+// readHeredocBody reads lines from start up to the delimiter line
+func readHeredocBody(runes []rune, start int, delimiter string, stripTabs bool) (string, int, bool) {
+	var body strings.Builder
+	for pos := start; pos < len(runes); {
+		end := pos
+		for end < len(runes) && runes[end] != '\n' {
+			end++
+		}
+		line := string(runes[pos:end])
+		if stripTabs {
+			line = strings.TrimLeft(line, "\t")
+		}
+		if line == delimiter {
+			return body.String(), min(end+1, len(runes)), true
+		}
+		body.WriteString(line + "\n")
+		pos = end + 1
+	}
+	return "", len(runes), false
+}
+
+// escapeHeredoc returns the escaped form of a heredoc body, in which only
+// variables expand. As in double quotes, a backslash escapes $, ` and \,
+// and joins a line to the next.
+func escapeHeredoc(body string) string {
+	var sb strings.Builder
+	runes := []rune(body)
+	// Nesting of ${...}
+	depth := 0
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case c == '\\' && i+1 < len(runes) && strings.ContainsRune("$`\\", runes[i+1]):
+			i++
+			escapeRune(&sb, runes[i], true)
+		case c == '\\' && i+1 < len(runes) && runes[i+1] == '\n':
+			i++
+		case c == '$' && i+1 < len(runes) && runes[i+1] == '{':
+			depth++
+			sb.WriteString("${")
+			i++
+		case c == '$' && i+1 < len(runes) && runes[i+1] < 0x80 && isSpecialParameter(byte(runes[i+1])):
+			sb.WriteString(string(runes[i : i+2]))
+			i++
+		case c == '$':
+			sb.WriteRune(c)
+			for i+1 < len(runes) && runes[i+1] < 0x80 && isNameChar(byte(runes[i+1])) {
+				i++
+				sb.WriteRune(runes[i])
+			}
+		case c == '}' && depth > 0:
+			depth--
+			sb.WriteRune(c)
+		default:
+			escapeRune(&sb, c, true)
+		}
+	}
+	return sb.String()
+}
+
+// heredocFile returns an unlinked temporary file holding body, ready to be
+// read as a command's stdin
+func heredocFile(body string) (*os.File, error) {
+	f, err := os.CreateTemp("", "gosh-heredoc-*")
+	if err != nil {
+		return nil, err
+	}
+	os.Remove(f.Name())
+
+	if _, err := f.WriteString(body); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// heredocPending reports whether input has a heredoc still waiting for its
+// delimiter line
+func heredocPending(input string) bool {
+	_, err := splitShellWords(input)
+	return errors.Is(err, errUnterminatedHeredoc)
+}
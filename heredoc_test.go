@@ -0,0 +1,104 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseCommandList_Heredoc(t *testing.T) {
+	state := &ShellState{WorkingDirectory: t.TempDir(), Environment: map[string]string{"NAME": "gosh"}}
+	tests := []struct {
+		input string
+		body  string
+	}{
+		{"cat <<EOF\nhello $NAME\nEOF", "hello gosh\n"},
+		{"cat << EOF\n${MISSING:-default} \\$NAME '$NAME' \"q\" *.go {a,b} ~\nEOF", "default $NAME 'gosh' \"q\" *.go {a,b} ~\n"},
+		{"cat <<'EOF'\nhello $NAME \\$\nEOF", "hello $NAME \\$\n"},
+		{"cat <<\"EOF\"\n$NAME\nEOF", "$NAME\n"},
+		{"cat <<E\\OF\n$NAME\nEOF", "$NAME\n"},
+		{"cat <<-EOF\n\tindented\n\t\tdeeper\n\tEOF", "indented\ndeeper\n"},
+		{"cat <<EOF\none \\\ntwo\nEOF", "one two\n"},
+		{"cat <<EOF\n  EOF\nEOF\n", "  EOF\n"},
+		{"cat <<EOF\nEOF", ""},
+	}
+
+	for _, tt := range tests {
+		list, err := parseCommandList(tt.input)
+		if err != nil {
+			t.Errorf("parseCommandList(%q) error: %v", tt.input, err)
+			continue
+		}
+		cmd, err := list[0].Expand(state)
+		if err != nil || len(cmd.Redirects) != 1 || !cmd.Redirects[0].Heredoc || cmd.Redirects[0].Body != tt.body {
+			t.Errorf("%q gave %+v (%v), want body %q", tt.input, cmd.Redirects, err, tt.body)
+		}
+	}
+}
+
+func TestParseCommandList_Newlines(t *testing.T) {
+	list, err := parseCommandList("cat <<A > a.txt; cat <<B\nfirst\nA\nsecond\nB\n\necho done &&\n  echo again\n")
+	if err != nil {
+		t.Fatalf("parseCommandList error: %v", err)
+	}
+
+	var names, ops []string
+	for _, cmd := range list {
+		names = append(names, cmd.Name)
+		ops = append(ops, cmd.Op)
+	}
+	if !reflect.DeepEqual(names, []string{"cat", "cat", "echo", "echo"}) || !reflect.DeepEqual(ops, []string{"", ";", ";", "&&"}) {
+		t.Fatalf("Unexpected list: %v %v", names, ops)
+	}
+	if list[0].Redirects[0].Body != "first\n" || list[1].Redirects[0].Body != "second\n" {
+		t.Errorf("Unexpected bodies %q and %q", list[0].Redirects[0].Body, list[1].Redirects[0].Body)
+	}
+}
+
+func TestHeredocPending(t *testing.T) {
+	tests := []struct {
+		input   string
+		pending bool
+	}{
+		{"cat <<EOF", true},
+		{"cat <<EOF\nline one", true},
+		{"cat <<EOF\nline one\nEOF", false},
+		{"cat <<A <<B\nA\n", true},
+		{"cat <<A <<B\nA\nB", false},
+		{"echo '<<EOF'", false},
+		{"ls", false},
+	}
+
+	for _, tt := range tests {
+		if got := heredocPending(tt.input); got != tt.pending {
+			t.Errorf("heredocPending(%q) = %v, want %v", tt.input, got, tt.pending)
+		}
+	}
+
+	for _, input := range []string{"cat <<", "cat <<\nEOF", "cat << ;"} {
+		if _, err := parseCommandList(input); err == nil {
+			t.Errorf("Expected a syntax error for %q", input)
+		}
+	}
+}
+
+func TestProcessSpawner_RunHeredoc(t *testing.T) {
+	dir := t.TempDir()
+	state := &ShellState{WorkingDirectory: dir, Environment: map[string]string{"PATH": os.Getenv("PATH"), "NAME": "gosh"}}
+	spawner := NewProcessSpawner(state)
+
+	list, _ := parseCommandList("cat <<EOF > out.txt\nhello $NAME\nEOF\nwc -l <<EOF\na\nb\nEOF")
+	result := runCommandList(state, list, spawner.Run)
+	if result.ExitCode != 0 {
+		t.Fatalf("Unexpected failure: %q", result.Output)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "out.txt")); string(data) != "hello gosh\n" {
+		t.Errorf("Unexpected file contents: %q", data)
+	}
+	if result.Output != "2\n" && result.Output != "       2\n" {
+		t.Errorf("Expected wc to count 2 lines, got %q", result.Output)
+	}
+}
@@ -0,0 +1,100 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxHistory is how many blocks the history keeps
+const maxHistory = 1000
+
+// AddHistory adds a block run in the REPL to the shell's command log, for
+// the history builtin and !N. Blocks keep their numbers as the oldest are
+// dropped.
+func (s *ShellState) AddHistory(block HistoryBlock) {
+	s.history = append(s.history, block)
+	if len(s.history) > maxHistory {
+		dropped := len(s.history) - maxHistory
+		s.history = s.history[dropped:]
+		s.historyBase += dropped
+	}
+}
+
+// HistoryEntry returns block n of the command log, numbered from 1
+func (s *ShellState) HistoryEntry(n int) (HistoryBlock, bool) {
+	i := n - 1 - s.historyBase
+	if i < 0 || i >= len(s.history) {
+		return HistoryBlock{}, false
+	}
+	return s.history[i], true
+}
+
+// history implements the history builtin
+func (b *BuiltinHandler) history(args []string) ExecutionResult {
+	if len(args) > 1 {
+		return ExecutionResult{Output: "history: too many arguments\nUsage: history [N|TEXT]", ExitCode: 1, Error: fmt.Errorf("too many arguments")}
+	}
+
+	// The last N, or those containing TEXT
+	first, filter := 0, ""
+	if len(args) == 1 {
+		if n, err := strconv.Atoi(args[0]); err == nil && n >= 0 {
+			first = max(len(b.state.history)-n, 0)
+		} else {
+			filter = args[0]
+		}
+	}
+
+	var lines []string
+	for i := first; i < len(b.state.history); i++ {
+		input := historyInput(b.state.history[i], ModeShell)
+		if !strings.Contains(input, filter) {
+			continue
+		}
+		// The lines of a block after the first line up under it
+		input = strings.ReplaceAll(input, "\n", "\n       ")
+		lines = append(lines, fmt.Sprintf("%5d  %s", b.state.historyBase+i+1, input))
+	}
+	return ExecutionResult{Output: strings.Join(lines, "\n"), ExitCode: 0}
+}
+
+// historyReference parses a !N line, which runs block N of the history
+// again
+func historyReference(input string) (int, bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(input), "!")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(rest)
+	if err != nil || n < 1 || rest[0] == '+' {
+		return 0, false
+	}
+	return n, true
+}
+
+// runHistoryEntry runs block n of the history again, in its own mode
+func (m model) runHistoryEntry(n int) (tea.Model, tea.Cmd) {
+	block, ok := m.state.HistoryEntry(n)
+	if !ok {
+		m.output = fmt.Sprintf("gosh: !%d: event not found", n)
+		return m, nil
+	}
+	input := historyInput(block, m.session.Mode)
+	m.running = input
+	return m, m.runBlock(input)
+}
+
+const historyHelpText = "history - Command History\n\n" +
+	"USAGE:\n" +
+	"    history           List the commands run this session, numbered\n" +
+	"    history N         List the last N\n" +
+	"    history TEXT      List those containing TEXT\n" +
+	"    !N                Run command N again\n\n" +
+	"DESCRIPTION:\n" +
+	"    Go blocks are listed with :go before them, and run as Go again\n" +
+	"    from either mode."
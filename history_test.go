@@ -0,0 +1,72 @@
+//go:build darwin || linux
+
+package main
+
+import "testing"
+
+func TestHistoryBuiltin(t *testing.T) {
+	state := &ShellState{}
+	for _, block := range []HistoryBlock{
+		{Mode: ModeShell, Input: "git status"},
+		{Mode: ModeGo, Input: "for i := 0; i < 2; i++ {\n\tfmt.Println(i)\n}"},
+		{Mode: ModeShell, Input: "git push"},
+	} {
+		state.AddHistory(block)
+	}
+	b := NewBuiltinHandler(state)
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{nil, "    1  git status\n    2  :go for i := 0; i < 2; i++ {\n       \tfmt.Println(i)\n       }\n    3  git push"},
+		{[]string{"1"}, "    3  git push"},
+		{[]string{"git"}, "    1  git status\n    3  git push"},
+		{[]string{"docker"}, ""},
+	}
+	for _, tt := range tests {
+		if result := b.Execute("history", tt.args); result.ExitCode != 0 || result.Output != tt.expected {
+			t.Errorf("history %v = %q (exit %d), want %q", tt.args, result.Output, result.ExitCode, tt.expected)
+		}
+	}
+
+	if result := b.Execute("history", []string{"1", "2"}); result.ExitCode == 0 {
+		t.Error("Expected history to refuse two arguments")
+	}
+}
+
+func TestHistory_KeepsNumbers(t *testing.T) {
+	state := &ShellState{}
+	for i := 0; i < maxHistory+5; i++ {
+		state.AddHistory(HistoryBlock{Mode: ModeShell, Input: "echo"})
+	}
+	state.AddHistory(HistoryBlock{Mode: ModeShell, Input: "ls"})
+
+	if _, ok := state.HistoryEntry(5); ok {
+		t.Error("Expected the oldest blocks to be dropped")
+	}
+	if block, ok := state.HistoryEntry(maxHistory + 6); !ok || block.Input != "ls" {
+		t.Errorf("Expected the last block to keep its number, got %+v", block)
+	}
+	if result := NewBuiltinHandler(state).Execute("history", []string{"1"}); result.Output != " 1006  ls" {
+		t.Errorf("Unexpected history %q", result.Output)
+	}
+}
+
+func TestHistoryReference(t *testing.T) {
+	for input, want := range map[string]int{"!42": 42, " !7 ": 7, "!0": 0, "!-1": 0, "!+1": 0, "!ls": 0, "!": 0, "42": 0} {
+		if n, ok := historyReference(input); n != want || ok != (want > 0) {
+			t.Errorf("historyReference(%q) = %d, %v; want %d", input, n, ok, want)
+		}
+	}
+
+	state := &ShellState{}
+	state.AddHistory(HistoryBlock{Mode: ModeGo, Input: "n := 1"})
+	m := model{session: &SessionState{Mode: ModeShell}, state: state, live: &liveOutput{}}
+	if updated, cmd := m.runHistoryEntry(1); updated.(model).running != ":go n := 1" || cmd == nil {
+		t.Errorf("Expected !1 to run the Go block as Go, got %q", updated.(model).running)
+	}
+	if updated, cmd := m.runHistoryEntry(2); updated.(model).output != "gosh: !2: event not found" || cmd != nil {
+		t.Errorf("Unexpected output %q", updated.(model).output)
+	}
+}
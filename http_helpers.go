@@ -0,0 +1,152 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Defaults for the gosh HTTP helpers
+const (
+	httpDefaultTimeout = 30 * time.Second
+	httpMaxBodySize    = 32 << 20 // 32 MiB
+)
+
+// newHTTPClient builds a client for the gosh HTTP helpers. The timeout
+// comes from GOSH_HTTP_TIMEOUT (a Go duration such as "10s") and proxies
+// from the shell's HTTPS_PROXY/HTTP_PROXY/NO_PROXY, which may have been
+// changed since gosh started.
+func newHTTPClient(env map[string]string) *http.Client {
+	timeout := httpDefaultTimeout
+	if value := env["GOSH_HTTP_TIMEOUT"]; value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			timeout = d
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		return proxyForURL(env, req.URL)
+	}
+
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// envValue looks a variable up in upper then lower case, like curl does
+func envValue(env map[string]string, name string) string {
+	if value := env[name]; value != "" {
+		return value
+	}
+	return env[strings.ToLower(name)]
+}
+
+// proxyForURL returns the proxy to use for u, or nil for a direct connection
+func proxyForURL(env map[string]string, u *url.URL) (*url.URL, error) {
+	var proxy string
+	switch u.Scheme {
+	case "https":
+		proxy = envValue(env, "HTTPS_PROXY")
+	case "http":
+		proxy = envValue(env, "HTTP_PROXY")
+	}
+	if proxy == "" || bypassProxy(envValue(env, "NO_PROXY"), u.Hostname()) {
+		return nil, nil
+	}
+
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+	return url.Parse(proxy)
+}
+
+// bypassProxy reports whether host matches a NO_PROXY list: "*", exact
+// hosts or IPs, and domain suffixes (".example.com" or "example.com")
+func bypassProxy(noProxy, host string) bool {
+	host = strings.ToLower(host)
+	if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		return true
+	}
+
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+		if h, _, err := net.SplitHostPort(entry); err == nil {
+			entry = h
+		}
+		entry = strings.TrimPrefix(entry, "*")
+		if host == strings.TrimPrefix(entry, ".") || strings.HasSuffix(host, "."+strings.TrimPrefix(entry, ".")) {
+			return true
+		}
+	}
+	return false
+}
+
+// httpFetch performs a GET and returns the body, treating non-2xx
+// responses as errors
+func httpFetch(rawURL, accept string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "gosh/"+GetVersion())
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	resp, err := newHTTPClient(goshAPIEnvironment()).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, httpMaxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > httpMaxBodySize {
+		return nil, fmt.Errorf("GET %s: response larger than %d bytes", rawURL, httpMaxBodySize)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		snippet := strings.TrimSpace(string(body))
+		if len(snippet) > 200 {
+			snippet = snippet[:200] + "..."
+		}
+		if snippet != "" {
+			return nil, fmt.Errorf("GET %s: %s: %s", rawURL, resp.Status, snippet)
+		}
+		return nil, fmt.Errorf("GET %s: %s", rawURL, resp.Status)
+	}
+
+	return body, nil
+}
+
+// httpGet fetches url and returns the body as a string
+func httpGet(rawURL string) (string, error) {
+	body, err := httpFetch(rawURL, "")
+	return string(body), err
+}
+
+// httpJSON fetches url and decodes the JSON body into v
+func httpJSON(rawURL string, v any) error {
+	body, err := httpFetch(rawURL, "application/json")
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("GET %s: invalid JSON: %w", rawURL, err)
+	}
+	return nil
+}
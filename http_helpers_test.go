@@ -0,0 +1,85 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestHTTPGetAndJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/text":
+			w.Write([]byte("hello"))
+		case "/json":
+			if r.Header.Get("Accept") != "application/json" {
+				t.Errorf("Expected a JSON Accept header, got %q", r.Header.Get("Accept"))
+			}
+			w.Write([]byte(`{"name":"gosh","stars":3}`))
+		default:
+			http.Error(w, "nope", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	body, err := httpGet(server.URL + "/text")
+	if err != nil || body != "hello" {
+		t.Errorf("httpGet = %q, %v", body, err)
+	}
+
+	var v struct {
+		Name  string `json:"name"`
+		Stars int    `json:"stars"`
+	}
+	if err := httpJSON(server.URL+"/json", &v); err != nil || v.Name != "gosh" || v.Stars != 3 {
+		t.Errorf("httpJSON = %+v, %v", v, err)
+	}
+
+	_, err = httpGet(server.URL + "/missing")
+	if err == nil || !strings.Contains(err.Error(), "404") || !strings.Contains(err.Error(), "nope") {
+		t.Errorf("Expected a 404 error with the body, got %v", err)
+	}
+}
+
+func TestProxyForURL(t *testing.T) {
+	env := map[string]string{
+		"HTTPS_PROXY": "proxy.corp:3128",
+		"no_proxy":    "internal.corp,.svc,10.0.0.1",
+	}
+
+	check := func(rawURL, want string) {
+		t.Helper()
+		u, _ := url.Parse(rawURL)
+		proxy, err := proxyForURL(env, u)
+		if err != nil {
+			t.Fatalf("proxyForURL(%s) failed: %v", rawURL, err)
+		}
+		got := ""
+		if proxy != nil {
+			got = proxy.String()
+		}
+		if got != want {
+			t.Errorf("proxyForURL(%s) = %q, want %q", rawURL, got, want)
+		}
+	}
+
+	check("https://api.github.com/", "http://proxy.corp:3128")
+	check("http://api.github.com/", "")
+	check("https://internal.corp/x", "")
+	check("https://a.b.svc/x", "")
+	check("https://10.0.0.1/", "")
+	check("https://localhost:8080/", "")
+}
+
+func TestNewHTTPClientTimeout(t *testing.T) {
+	if c := newHTTPClient(map[string]string{}); c.Timeout != httpDefaultTimeout {
+		t.Errorf("Expected default timeout, got %v", c.Timeout)
+	}
+	if c := newHTTPClient(map[string]string{"GOSH_HTTP_TIMEOUT": "5s"}); c.Timeout.Seconds() != 5 {
+		t.Errorf("Expected 5s timeout, got %v", c.Timeout)
+	}
+}
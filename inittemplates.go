@@ -0,0 +1,357 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// initTemplates are the starter configs init writes, by the name given to
+// init --template
+var initTemplates = map[string]string{
+	"shellapi": shellapiTemplate,
+	"minimal":  minimalTemplate,
+	"git":      gitTemplate,
+	"devops":   devopsTemplate,
+	"data":     dataTemplate,
+}
+
+// defaultInitTemplate is the template init writes without --template
+const defaultInitTemplate = "shellapi"
+
+// initTemplateNames returns the names of the templates, sorted
+func initTemplateNames() string {
+	names := make([]string, 0, len(initTemplates))
+	for name := range initTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// shellapiTemplate wraps gosh_lib's shellapi functions
+const shellapiTemplate = `package main
+
+import (
+	"fmt"
+	"github.com/rsarv3006/gosh_lib/shellapi"
+)
+
+func init() {
+	fmt.Println("🚀 gosh config loaded! Command execution system enabled!")
+}
+
+// ==============================================================================
+// WORKING SHELLAPI FUNCTIONS (v0.2.2+)
+// ==============================================================================
+// These functions execute real commands via Go's os/exec and return results.
+
+// Development helper functions that actually work
+func build() string {
+	result, err := shellapi.GoBuild()
+	if err != nil {
+		return "BUILD ERROR: " + err.Error()
+	}
+	return "BUILD SUCCESS: " + result  // Executes real go build command
+}
+
+func test() string {
+	result, _ := shellapi.GoTest()
+	return result  // Executes real go test with full output
+}
+
+func run() string {
+	result, _ := shellapi.GoRun()
+	return result  // Executes real go run . application
+}
+
+func gs() string {
+	result, err := shellapi.GitStatus()
+	if err != nil {
+		return "GIT ERROR: " + err.Error()
+	}
+	return "GIT STATUS:\n" + result  // Executes real git status with colors
+}
+
+// ==============================================================================
+// DIRECTORY NAVIGATION FUNCTIONS
+// ==============================================================================
+// gosh.Chdir changes the shell's directory as cd does, so these work
+// anywhere in a function, and the change lasts after it returns.
+
+// goGosh() navigates to the gosh development directory
+func goGosh() error {
+	return gosh.Chdir("~/dev/gosh")
+}
+
+// Navigate to home config directory
+func goConfig() error {
+	return gosh.Chdir("~/.config/gosh")
+}
+
+// Navigate to home directory, saying where it was
+func goHome() string {
+	from := gosh.Cwd()
+	if err := gosh.Chdir("~"); err != nil {
+		return err.Error()
+	}
+	return "Left " + from
+}
+
+// ==============================================================================
+// UTILITY FUNCTIONS
+// ==============================================================================
+
+// Simple welcome function
+func hello() string {
+	return "Hello from gosh!"
+}
+
+// Success message with green color
+func ok(msg string) string {
+	return shellapi.Success(msg)  // Green colored text
+}
+
+// Warning message with yellow color  
+func warn(msg string) string {
+	return shellapi.Warning(msg)  // Yellow colored text
+}
+
+// Error message with red color
+func err(msg string) string {
+	return shellapi.Error(msg)  // Red colored text
+}
+`
+
+// minimalTemplate is an empty config with pointers to what goes in one
+const minimalTemplate = `package main
+
+// gosh runs this file when it starts. The functions defined here can be
+// called from Go code, and help NAME shows their doc comments.
+
+func init() {
+	// Aliases, as the alias builtin defines them
+	gosh.Alias("ll", "ls -la")
+
+	// Packages Go code can use without importing them, besides fmt, os,
+	// strings, strconv and path/filepath
+	// gosh.PreImport("time", "encoding/json")
+
+	// Go functions run as commands: "hi gosh" calls hi("gosh")
+	// gosh.RegisterCommand("hi", hi)
+}
+
+// hi greets name.
+func hi(name string) string {
+	return "Hello, " + name + "!"
+}
+`
+
+// gitTemplate is git aliases and commands
+const gitTemplate = `package main
+
+func init() {
+	gosh.Alias("gs", "git status -sb")
+	gosh.Alias("gd", "git diff")
+	gosh.Alias("gds", "git diff --staged")
+	gosh.Alias("gl", "git log --oneline --graph --decorate -20")
+	gosh.Alias("gco", "git switch")
+
+	gosh.RegisterCommand("gnew", gitNew)
+	gosh.RegisterCommand("gsync", gitSync)
+	gosh.RegisterCommand("gcm", gitCommit)
+	gosh.RegisterCommand("gclean", gitClean)
+}
+
+// git runs git with args, returning its output, and its error output as
+// the error if it fails.
+func git(args ...string) (string, error) {
+	out, _, err := sh("git", args...)
+	return strings.TrimSpace(out), err
+}
+
+// branch returns the current branch, or "" outside a repository.
+func branch() string {
+	out, err := git("branch", "--show-current")
+	if err != nil {
+		return ""
+	}
+	return out
+}
+
+// gitNew creates the branch name and switches to it.
+func gitNew(name string) (string, error) {
+	return git("switch", "-c", name)
+}
+
+// gitSync fetches and rebases the current branch on its upstream, stashing
+// changes meanwhile.
+func gitSync() (string, error) {
+	if _, err := git("fetch", "--prune"); err != nil {
+		return "", err
+	}
+	return git("pull", "--rebase", "--autostash")
+}
+
+// gitCommit stages everything and commits it with the words as its
+// message.
+func gitCommit(words ...string) (string, error) {
+	if len(words) == 0 {
+		return "", fmt.Errorf("usage: gcm MESSAGE")
+	}
+	if _, err := git("add", "-A"); err != nil {
+		return "", err
+	}
+	return git("commit", "-m", strings.Join(words, " "))
+}
+
+// gitClean deletes the local branches merged into the current one, except
+// main and master.
+func gitClean() (string, error) {
+	out, err := git("branch", "--merged")
+	if err != nil {
+		return "", err
+	}
+	var deleted []string
+	for _, line := range strings.Split(out, "\n") {
+		name := strings.TrimSpace(line)
+		if name == "" || strings.HasPrefix(name, "*") || name == "main" || name == "master" {
+			continue
+		}
+		if _, err := git("branch", "-d", name); err != nil {
+			return strings.Join(deleted, "\n"), err
+		}
+		deleted = append(deleted, "deleted "+name)
+	}
+	return strings.Join(deleted, "\n"), nil
+}
+`
+
+// devopsTemplate is docker and Kubernetes helpers
+const devopsTemplate = `package main
+
+// kctx and kns, built in, switch Kubernetes contexts and namespaces.
+
+func init() {
+	gosh.Alias("k", "kubectl")
+	gosh.Alias("dc", "docker compose")
+	gosh.Alias("dps", "docker ps --format 'table {{.Names}}\t{{.Image}}\t{{.Status}}'")
+
+	gosh.RegisterCommand("dlogs", dockerLogs)
+	gosh.RegisterCommand("dstop", dockerStopAll)
+	gosh.RegisterCommand("dclean", dockerClean)
+	gosh.RegisterCommand("kpods", kubePods)
+}
+
+// docker runs docker with args, returning its output, and its error
+// output as the error if it fails.
+func docker(args ...string) (string, error) {
+	out, _, err := sh("docker", args...)
+	return strings.TrimSpace(out), err
+}
+
+// containers returns the names of the running containers.
+func containers() []string {
+	out, err := docker("ps", "--format", "{{.Names}}")
+	if err != nil || out == "" {
+		return nil
+	}
+	return strings.Split(out, "\n")
+}
+
+// dockerLogs shows the last 100 lines a container logged.
+func dockerLogs(name string) (string, error) {
+	return docker("logs", "--tail", "100", name)
+}
+
+// dockerStopAll stops every running container.
+func dockerStopAll() (string, error) {
+	names := containers()
+	if len(names) == 0 {
+		return "no running containers", nil
+	}
+	return docker(append([]string{"stop"}, names...)...)
+}
+
+// dockerClean removes stopped containers, dangling images and unused
+// networks.
+func dockerClean() (string, error) {
+	return docker("system", "prune", "-f")
+}
+
+// kubePods lists the pods of the current namespace, or of namespace.
+func kubePods(namespace ...string) (string, error) {
+	args := []string{"get", "pods", "-o", "wide"}
+	if len(namespace) > 0 {
+		args = append(args, "-n", namespace[0])
+	}
+	out, _, err := sh("kubectl", args...)
+	return out, err
+}
+`
+
+// dataTemplate is imports and helpers for exploring data
+const dataTemplate = `package main
+
+func init() {
+	gosh.PreImport("encoding/json", "encoding/csv", "math", "sort", "time")
+	gosh.ResultFormat("table")
+}
+
+// load reads a CSV file, or the output of a command, into rows keyed by
+// the header's column names.
+func load(source string) []map[string]string {
+	rows, err := gosh.CSV(source)
+	if err != nil {
+		fmt.Println(err)
+	}
+	return rows
+}
+
+// fetch gets JSON from url as maps, slices, strings and float64s.
+func fetch(url string) interface{} {
+	var v interface{}
+	if err := gosh.HTTPJSON(url, &v); err != nil {
+		fmt.Println(err)
+	}
+	return v
+}
+
+// column returns the values of column name in rows that are numbers.
+func column(rows []map[string]string, name string) []float64 {
+	var values []float64
+	for _, row := range rows {
+		if v, err := strconv.ParseFloat(row[name], 64); err == nil {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// sum adds up values.
+func sum(values []float64) float64 {
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+// mean returns the average of values, or 0 without any.
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	return sum(values) / float64(len(values))
+}
+
+// countBy counts rows by the value of column name.
+func countBy(rows []map[string]string, name string) map[string]int {
+	counts := make(map[string]int)
+	for _, row := range rows {
+		counts[row[name]]++
+	}
+	return counts
+}
+`
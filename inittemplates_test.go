@@ -0,0 +1,70 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInit_Templates(t *testing.T) {
+	for name := range initTemplates {
+		t.Run(name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			t.Chdir(home)
+			state := NewShellState()
+			builtins := NewBuiltinHandler(state)
+			if result := builtins.Execute("init", []string{"--template", name}); result.ExitCode != 0 {
+				t.Fatalf("init --template %s failed: %s", name, result.Output)
+			}
+
+			// Every template loads
+			g := NewGoEvaluator()
+			g.SetupWithShell(state, NewProcessSpawner(state))
+			g.SetupWithBuiltins(builtins)
+			if err := g.LoadConfig(); err != nil {
+				t.Errorf("Loading the %s template: %v", name, err)
+			}
+		})
+	}
+}
+
+func TestInit_Force(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	configPath := filepath.Join(home, ".config", "gosh", "config.go")
+	b := NewBuiltinHandler(NewShellState())
+
+	b.Execute("init", []string{"--template=minimal"})
+	os.WriteFile(configPath, []byte("package main\n// mine\n"), 0644)
+
+	// Kept without --force, failing when a template was asked for
+	if result := b.Execute("init", []string{"--template", "git"}); result.ExitCode != 1 || result.Error == nil || !strings.Contains(result.Output, "--force") {
+		t.Errorf("Expected init --template to refuse to replace the config, got %q (exit %d)", result.Output, result.ExitCode)
+	}
+	if result := b.Execute("init", nil); result.ExitCode != 0 || !strings.Contains(result.Output, "--force") {
+		t.Errorf("Expected init to keep the config, got %q (exit %d)", result.Output, result.ExitCode)
+	}
+	if data, _ := os.ReadFile(configPath); string(data) != "package main\n// mine\n" {
+		t.Errorf("Expected the config to be kept, got %q", data)
+	}
+
+	if result := b.Execute("init", []string{"--template", "git", "--force"}); result.ExitCode != 0 {
+		t.Fatalf("init --force failed: %s", result.Output)
+	}
+	if data, _ := os.ReadFile(configPath); string(data) != gitTemplate {
+		t.Error("Expected the git template to replace the config")
+	}
+	if data, _ := os.ReadFile(configPath + ".bak"); string(data) != "package main\n// mine\n" {
+		t.Errorf("Expected the old config in config.go.bak, got %q", data)
+	}
+
+	for _, args := range [][]string{{"--template", "nope"}, {"--bogus"}} {
+		if result := b.Execute("init", args); result.ExitCode == 0 {
+			t.Errorf("Expected init %v to fail", args)
+		}
+	}
+}
@@ -0,0 +1,712 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// JobStatus is the state of a background job
+type JobStatus int
+
+const (
+	JobRunning JobStatus = iota
+	JobStopped
+	JobDone
+)
+
+func (s JobStatus) String() string {
+	switch s {
+	case JobRunning:
+		return "Running"
+	case JobStopped:
+		return "Stopped"
+	default:
+		return "Done"
+	}
+}
+
+// Job is a command started in the background with &, or a foreground
+// command that was stopped with Ctrl-Z
+type Job struct {
+	ID       int // 0 until the job is added to the table
+	Pid      int // Also the job's process group ID, if it has its own
+	Command  string
+	Status   JobStatus
+	ExitCode int
+	Started  time.Time
+
+	group  bool // Whether the job has a process group of its own
+	output *jobOutput
+	// changed is closed and replaced whenever Status changes
+	changed chan struct{}
+	done    chan struct{}
+}
+
+// jobOutput collects a background job's output while it runs. While the
+// job is in the foreground, output goes straight to the live writer.
+type jobOutput struct {
+	mu   sync.Mutex
+	buf  bytes.Buffer
+	live io.Writer
+}
+
+func (o *jobOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.live != nil {
+		return o.live.Write(p)
+	}
+	return o.buf.Write(p)
+}
+
+func (o *jobOutput) String() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.buf.String()
+}
+
+// take returns and clears what has been collected
+func (o *jobOutput) take() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	collected := o.buf.String()
+	o.buf.Reset()
+	return collected
+}
+
+// attach writes out what has been collected so far and sends further
+// output to w; a nil w goes back to collecting
+func (o *jobOutput) attach(w io.Writer) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if w != nil {
+		w.Write(o.buf.Bytes())
+		o.buf.Reset()
+	}
+	o.live = w
+}
+
+// JobTable tracks the background jobs of a session. Like the task
+// scheduler it never draws over the prompt: output is kept until the job
+// finishes and is shown, with a Done line, before the next prompt.
+type JobTable struct {
+	mu   sync.Mutex
+	jobs []*Job
+	// With job control, as in interactive sessions, foreground commands get
+	// process groups of their own and can be stopped with Ctrl-Z.
+	// Otherwise they share gosh's, so terminal signals reach both.
+	control    bool
+	foreground *Job
+	// PID of the job most recently started or continued in the
+	// background: $! in the shell
+	lastBackground int
+}
+
+func NewJobTable() *JobTable {
+	return &JobTable{}
+}
+
+// EnableJobControl turns on job control for interactive sessions
+func (t *JobTable) EnableJobControl() {
+	t.mu.Lock()
+	t.control = true
+	t.mu.Unlock()
+}
+
+// Start starts cmd without waiting for it and registers it as a job.
+// Output that isn't redirected is collected for the completion notice;
+// files are closed once the process exits.
+func (t *JobTable) Start(command string, cmd *exec.Cmd, files *redirectedFiles) (*Job, error) {
+	job, err := t.launch(command, cmd, files, true)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	t.register(job)
+	t.lastBackground = job.Pid
+	t.mu.Unlock()
+	return job, nil
+}
+
+// register gives a job an ID and adds it to the table. Callers must hold
+// t.mu.
+func (t *JobTable) register(job *Job) {
+	job.ID = t.nextID()
+	t.jobs = append(t.jobs, job)
+}
+
+// launch starts cmd with its output collected, without adding it to the
+// table. Background jobs get a process group of their own, as do all jobs
+// when gosh has job control.
+func (t *JobTable) launch(command string, cmd *exec.Cmd, files *redirectedFiles, ownGroup bool) (*Job, error) {
+	// The job's output goes through a pipe read here rather than one owned
+	// by exec.Cmd, because the process is reaped with wait4 (to see it stop
+	// and continue) instead of cmd.Wait
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		files.Close()
+		return nil, err
+	}
+	cmd.Stdout = writer
+	if files.Stdout != nil {
+		cmd.Stdout = files.Stdout
+	}
+	cmd.Stderr = writer
+	if files.Stderr != nil {
+		cmd.Stderr = files.Stderr
+	}
+	// A process group of its own keeps terminal signals such as Ctrl-C
+	// for the foreground command
+	t.mu.Lock()
+	group := ownGroup || t.control
+	t.mu.Unlock()
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: group}
+
+	err = cmd.Start()
+	writer.Close()
+	files.Close()
+	if err != nil {
+		reader.Close()
+		return nil, err
+	}
+
+	output := &jobOutput{}
+	copied := make(chan struct{})
+	go func() {
+		io.Copy(output, reader)
+		reader.Close()
+		close(copied)
+	}()
+
+	job := &Job{
+		Pid:     cmd.Process.Pid,
+		Command: command,
+		group:   group,
+		Status:  JobRunning,
+		Started: time.Now(),
+		output:  output,
+		changed: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	go t.wait(job, cmd.Process, copied)
+	return job, nil
+}
+
+// signal sends sig to the job's process group, or just its process if it
+// shares gosh's group
+func (j *Job) signal(sig syscall.Signal) error {
+	if j.group {
+		return syscall.Kill(-j.Pid, sig)
+	}
+	return syscall.Kill(j.Pid, sig)
+}
+
+// wait follows a job until it exits, recording when it stops and continues
+func (t *JobTable) wait(job *Job, process *os.Process, copied <-chan struct{}) {
+	exitCode := 0
+	for {
+		var status syscall.WaitStatus
+		_, err := syscall.Wait4(job.Pid, &status, syscall.WUNTRACED|syscall.WCONTINUED, nil)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			exitCode = 1
+			break
+		}
+
+		if status.Stopped() {
+			t.setStatus(job, JobStopped)
+			continue
+		}
+		if status.Continued() {
+			t.setStatus(job, JobRunning)
+			continue
+		}
+		if status.Signaled() {
+			exitCode = 128 + int(status.Signal())
+		} else {
+			exitCode = status.ExitStatus()
+		}
+		break
+	}
+	process.Release()
+
+	// Background children of the job can keep the pipe open, so don't
+	// wait long for the rest of the output
+	select {
+	case <-copied:
+	case <-time.After(time.Second):
+	}
+
+	t.mu.Lock()
+	job.ExitCode = exitCode
+	t.mu.Unlock()
+	t.setStatus(job, JobDone)
+	close(job.done)
+}
+
+func (t *JobTable) setStatus(job *Job, status JobStatus) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	job.Status = status
+	close(job.changed)
+	job.changed = make(chan struct{})
+}
+
+// nextID returns one more than the highest job ID in use, so numbering
+// starts over at 1 once every job has been reported, as in other shells.
+// Callers must hold t.mu.
+func (t *JobTable) nextID() int {
+	id := 1
+	for _, job := range t.jobs {
+		if job.ID >= id {
+			id = job.ID + 1
+		}
+	}
+	return id
+}
+
+// List returns a snapshot of the jobs, oldest first
+func (t *JobTable) List() []Job {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	jobs := make([]Job, 0, len(t.jobs))
+	for _, job := range t.jobs {
+		jobs = append(jobs, *job)
+	}
+	return jobs
+}
+
+// currentJobs returns the IDs of the current (%+) and previous (%-) jobs:
+// the most recent stopped jobs, then the most recent running ones. Callers
+// must hold t.mu.
+func (t *JobTable) currentJobs() (current, previous int) {
+	for _, status := range []JobStatus{JobRunning, JobStopped} {
+		for _, job := range t.jobs {
+			if job.Status == status {
+				current, previous = job.ID, current
+			}
+		}
+	}
+	return current, previous
+}
+
+// Find resolves a job spec: %N or N, %+ or %% for the current job, %- for
+// the previous one, %name for the job whose command starts with name and
+// %?text for the job whose command contains text. An empty spec is the
+// current job.
+func (t *JobTable) Find(spec string) (*Job, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	current, previous := t.currentJobs()
+	byID := func(id int) (*Job, error) {
+		for _, job := range t.jobs {
+			if job.ID == id && job.Status != JobDone {
+				return job, nil
+			}
+		}
+		if spec == "" || spec == "%+" || spec == "%%" {
+			return nil, fmt.Errorf("no current job")
+		}
+		return nil, fmt.Errorf("%s: no such job", spec)
+	}
+
+	switch {
+	case spec == "" || spec == "%+" || spec == "%%":
+		return byID(current)
+	case spec == "%-":
+		return byID(previous)
+	}
+
+	pattern := strings.TrimPrefix(spec, "%")
+	if id, err := strconv.Atoi(pattern); err == nil {
+		return byID(id)
+	}
+	if !strings.HasPrefix(spec, "%") {
+		return nil, fmt.Errorf("%s: no such job", spec)
+	}
+
+	var found *Job
+	for _, job := range t.jobs {
+		matches := strings.HasPrefix(job.Command, pattern)
+		if text, ok := strings.CutPrefix(pattern, "?"); ok {
+			matches = strings.Contains(job.Command, text)
+		}
+		if !matches || job.Status == JobDone {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("%s: ambiguous job spec", spec)
+		}
+		found = job
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%s: no such job", spec)
+	}
+	return found, nil
+}
+
+// Status returns a job's current status
+func (t *JobTable) Status(job *Job) JobStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return job.Status
+}
+
+// Continue resumes a stopped job in the background
+func (t *JobTable) Continue(job *Job) error {
+	t.mu.Lock()
+	t.lastBackground = job.Pid
+	t.mu.Unlock()
+	return job.signal(syscall.SIGCONT)
+}
+
+// LastBackgroundPid returns the PID of the job most recently started or
+// continued in the background, or 0 if there hasn't been one
+func (t *JobTable) LastBackgroundPid() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.lastBackground
+}
+
+// SignalForeground sends sig to the foreground command's process group,
+// for the UI to pass on Ctrl-C and Ctrl-Z while it owns the terminal. It
+// reports whether there was a foreground command.
+func (t *JobTable) SignalForeground(sig syscall.Signal) bool {
+	t.mu.Lock()
+	job := t.foreground
+	t.mu.Unlock()
+
+	if job == nil {
+		return false
+	}
+	return job.signal(sig) == nil
+}
+
+// Foreground resumes a job if it's stopped and waits for it, with its
+// output going to w (or collected when w is nil). With job control,
+// Ctrl-C and Ctrl-Z from the terminal are passed on to it, and if it stops
+// it's added to the table. It returns when the job exits, which removes it
+// from the table, or stops.
+func (t *JobTable) Foreground(job *Job, w io.Writer) (JobStatus, int, error) {
+	if w != nil {
+		job.output.attach(w)
+		defer job.output.attach(nil)
+	}
+
+	t.mu.Lock()
+	control := t.control
+	t.foreground = job
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		t.foreground = nil
+		t.mu.Unlock()
+	}()
+
+	// The job isn't in the terminal's process group, so gosh gets these
+	// signals and passes them on
+	signals := make(chan os.Signal, 1)
+	if control {
+		signal.Notify(signals, os.Interrupt, syscall.SIGTSTP)
+		defer signal.Stop(signals)
+	}
+
+	stopped := t.Status(job) == JobStopped
+	if stopped {
+		if err := t.Continue(job); err != nil {
+			return JobStopped, 1, err
+		}
+	}
+
+	for {
+		t.mu.Lock()
+		status, changed := job.Status, job.changed
+		t.mu.Unlock()
+
+		// A stopped job has to report running again before a stop counts
+		if status == JobDone || (status == JobStopped && !stopped) {
+			break
+		}
+		if status == JobRunning {
+			stopped = false
+		}
+
+		select {
+		case <-changed:
+		case sig := <-signals:
+			job.signal(sig.(syscall.Signal))
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch {
+	case job.Status == JobDone:
+		t.remove(job)
+	case job.ID == 0:
+		t.register(job)
+	}
+	return job.Status, job.ExitCode, nil
+}
+
+// HangUp sends SIGHUP to stopped jobs when the shell exits, followed by
+// SIGCONT so they can act on it, as other shells do. Running jobs are
+// left alone.
+func (t *JobTable) HangUp() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, job := range t.jobs {
+		if job.Status == JobStopped {
+			job.signal(syscall.SIGHUP)
+			job.signal(syscall.SIGCONT)
+		}
+	}
+}
+
+// remove drops a job from the table. Callers must hold t.mu.
+func (t *JobTable) remove(job *Job) {
+	for i, j := range t.jobs {
+		if j == job {
+			t.jobs = append(t.jobs[:i], t.jobs[i+1:]...)
+			return
+		}
+	}
+}
+
+// DrainNotifications removes finished jobs from the table and returns their
+// output and completion lines
+func (t *JobTable) DrainNotifications() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var notices []string
+	remaining := t.jobs[:0]
+	for _, job := range t.jobs {
+		if job.Status != JobDone {
+			remaining = append(remaining, job)
+			continue
+		}
+
+		if output := strings.TrimRight(job.output.String(), "\n"); output != "" {
+			notices = append(notices, output)
+		}
+		notices = append(notices, fmt.Sprintf("[%d]  %-8s %s", job.ID, job.statusText(), job.Command))
+	}
+	t.jobs = remaining
+	return notices
+}
+
+// statusText describes the job's status as the jobs builtin shows it
+func (j *Job) statusText() string {
+	if j.Status == JobDone && j.ExitCode != 0 {
+		return fmt.Sprintf("Exit %d", j.ExitCode)
+	}
+	return j.Status.String()
+}
+
+// runForeground runs a command built by Run and waits for it. Its output
+// is written to w as it's produced, or collected and returned if w is nil.
+// If it's stopped with Ctrl-Z it becomes a job, and its output so far is
+// returned.
+func (p *ProcessSpawner) runForeground(sc ShellCommand, cmd *exec.Cmd, files *redirectedFiles, w io.Writer) ExecutionResult {
+	table := p.state.Jobs()
+	// Like timeout(1), a time-limited command gets a process group of its
+	// own, so whatever it starts is ended with it
+	job, err := table.launch(sc.String(), cmd, files, p.limit != nil)
+	if err != nil {
+		return ExecutionResult{Output: fmt.Sprintf("gosh: %v", unwrapExecError(err)), ExitCode: 1, Error: err}
+	}
+	expired := func() bool { return false }
+	if p.limit != nil {
+		expired = p.limit.watch(job)
+	}
+
+	status, exitCode, err := table.Foreground(job, w)
+	if expired() && status == JobDone {
+		exitCode = timeExpiredExitCode
+	}
+	output := job.output.take()
+	if err != nil {
+		return ExecutionResult{Output: output + fmt.Sprintf("gosh: %v", err), ExitCode: 1, Error: err}
+	}
+	if status == JobStopped {
+		if output != "" && !strings.HasSuffix(output, "\n") {
+			output += "\n"
+		}
+		output += fmt.Sprintf("[%d]+ Stopped  %s", job.ID, job.Command)
+		exitCode = 128 + int(syscall.SIGTSTP)
+	}
+	return ExecutionResult{Output: output, ExitCode: exitCode}
+}
+
+// startJob runs a command built by Run in the background and reports its
+// job ID and process ID like other shells: [1] 12345
+func (p *ProcessSpawner) startJob(sc ShellCommand, cmd *exec.Cmd, files *redirectedFiles) ExecutionResult {
+	sc.Background = false
+	job, err := p.state.Jobs().Start(sc.String(), cmd, files)
+	if err != nil {
+		return ExecutionResult{Output: fmt.Sprintf("gosh: %v", unwrapExecError(err)), ExitCode: 1, Error: err}
+	}
+	return ExecutionResult{Output: fmt.Sprintf("[%d] %d", job.ID, job.Pid), ExitCode: 0}
+}
+
+// unwrapExecError drops the *exec.Error wrapper's quoting of the command
+// name for messages like "gosh: nosuchcmd: executable file not found in $PATH"
+func unwrapExecError(err error) error {
+	if execErr, ok := err.(*exec.Error); ok {
+		return fmt.Errorf("%s: %w", execErr.Name, execErr.Err)
+	}
+	return err
+}
+
+// jobs implements the jobs builtin
+func (b *BuiltinHandler) jobs(args []string) ExecutionResult {
+	showPids, onlyPids := false, false
+	for _, arg := range args {
+		switch arg {
+		case "-l":
+			showPids = true
+		case "-p":
+			onlyPids = true
+		default:
+			err := fmt.Errorf("unknown option: %s", arg)
+			return ExecutionResult{Output: fmt.Sprintf("jobs: %v\nUsage: jobs [-l|-p]", err), ExitCode: 1, Error: err}
+		}
+	}
+
+	table := b.state.Jobs()
+	table.mu.Lock()
+	current, previous := table.currentJobs()
+	table.mu.Unlock()
+
+	var lines []string
+	for _, job := range table.List() {
+		// Finished jobs are reported before the next prompt instead
+		if job.Status == JobDone {
+			continue
+		}
+		if onlyPids {
+			lines = append(lines, strconv.Itoa(job.Pid))
+			continue
+		}
+
+		marker := " "
+		switch job.ID {
+		case current:
+			marker = "+"
+		case previous:
+			marker = "-"
+		}
+		pid := ""
+		if showPids {
+			pid = fmt.Sprintf("%d ", job.Pid)
+		}
+		command := job.Command
+		if job.Status == JobRunning {
+			command += " &"
+		}
+		lines = append(lines, fmt.Sprintf("[%d]%s %s%-8s %s", job.ID, marker, pid, job.statusText(), command))
+	}
+	return ExecutionResult{Output: strings.Join(lines, "\n"), ExitCode: 0}
+}
+
+// fg implements the fg builtin
+func (b *BuiltinHandler) fg(args []string) ExecutionResult {
+	if len(args) > 1 {
+		return ExecutionResult{Output: "fg: usage: fg [%JOB]", ExitCode: 1, Error: fmt.Errorf("invalid arguments")}
+	}
+	spec := ""
+	if len(args) == 1 {
+		spec = args[0]
+	}
+
+	table := b.state.Jobs()
+	job, err := table.Find(spec)
+	if err != nil {
+		return ExecutionResult{Output: fmt.Sprintf("fg: %v", err), ExitCode: 1, Error: err}
+	}
+
+	var status JobStatus
+	var exitCode int
+	err = withTerminal(func() error {
+		fmt.Fprintln(os.Stdout, job.Command)
+		var err error
+		status, exitCode, err = table.Foreground(job, os.Stdout)
+		return err
+	})
+	if err != nil {
+		return ExecutionResult{Output: fmt.Sprintf("fg: %v", err), ExitCode: 1, Error: err}
+	}
+
+	if status == JobStopped {
+		return ExecutionResult{Output: fmt.Sprintf("[%d]+ Stopped  %s", job.ID, job.Command), ExitCode: 128 + int(syscall.SIGTSTP)}
+	}
+	return ExecutionResult{ExitCode: exitCode}
+}
+
+// bg implements the bg builtin
+func (b *BuiltinHandler) bg(args []string) ExecutionResult {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+
+	table := b.state.Jobs()
+	var lines []string
+	exitCode := 0
+	for _, spec := range args {
+		job, err := table.Find(spec)
+		if err == nil && table.Status(job) != JobStopped {
+			lines = append(lines, fmt.Sprintf("bg: job %d already in background", job.ID))
+			continue
+		}
+		if err == nil {
+			err = table.Continue(job)
+		}
+		if err != nil {
+			lines = append(lines, fmt.Sprintf("bg: %v", err))
+			exitCode = 1
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("[%d]+ %s &", job.ID, job.Command))
+	}
+
+	result := ExecutionResult{Output: strings.Join(lines, "\n"), ExitCode: exitCode}
+	if exitCode != 0 {
+		result.Error = fmt.Errorf("bg failed")
+	}
+	return result
+}
+
+const jobsHelpText = "jobs, fg, bg - Job Control\n\n" +
+	"USAGE:\n" +
+	"    jobs [-l|-p]      List background jobs (-l adds PIDs, -p prints only PIDs)\n" +
+	"    fg [%JOB]         Bring a job to the foreground and wait for it\n" +
+	"    bg [%JOB...]      Resume stopped jobs in the background\n\n" +
+	"JOB SPECS:\n" +
+	"    %1, 1             Job number 1\n" +
+	"    %+, %%, (none)    The current job (+ in the jobs list)\n" +
+	"    %-                The previous job (- in the jobs list)\n" +
+	"    %make             The job whose command starts with \"make\"\n" +
+	"    %?test            The job whose command contains \"test\"\n\n" +
+	"DESCRIPTION:\n" +
+	"    Start a job by ending a command with &, or stop the command that's\n" +
+	"    running with Ctrl-Z. fg shows what the job has printed so far and\n" +
+	"    then streams its output; Ctrl-C and Ctrl-Z are passed on to it. Jobs\n" +
+	"    have no input, so fg doesn't reconnect stdin."
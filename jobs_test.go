@@ -0,0 +1,327 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func waitForJob(t *testing.T, job Job) {
+	t.Helper()
+	select {
+	case <-job.done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Job %d didn't finish", job.ID)
+	}
+}
+
+func TestProcessSpawner_RunBackground(t *testing.T) {
+	state := &ShellState{WorkingDirectory: t.TempDir(), Environment: map[string]string{"PATH": os.Getenv("PATH")}}
+	spawner := NewProcessSpawner(state)
+
+	list, _ := parseCommandList("sh -c 'sleep 0.2; echo finished' & echo foreground")
+	result := runCommandList(state, list, spawner.Run)
+
+	jobs := state.Jobs().List()
+	if len(jobs) != 1 || jobs[0].ID != 1 || jobs[0].Status != JobRunning {
+		t.Fatalf("Expected one running job, got %+v", jobs)
+	}
+	want := fmt.Sprintf("[1] %d\nforeground\n", jobs[0].Pid)
+	if result.Output != want || result.ExitCode != 0 {
+		t.Errorf("Expected %q, got %q (exit %d)", want, result.Output, result.ExitCode)
+	}
+	if notices := state.Jobs().DrainNotifications(); len(notices) != 0 {
+		t.Errorf("Expected no notices while the job runs, got %v", notices)
+	}
+
+	waitForJob(t, jobs[0])
+	notices := state.Jobs().DrainNotifications()
+	if len(notices) != 2 || notices[0] != "finished" || !strings.HasPrefix(notices[1], "[1]  Done") {
+		t.Errorf("Unexpected notices: %q", notices)
+	}
+	if len(state.Jobs().List()) != 0 {
+		t.Error("Expected reported jobs to leave the table")
+	}
+}
+
+func TestExpand_BackgroundPid(t *testing.T) {
+	state := &ShellState{WorkingDirectory: t.TempDir(), Environment: map[string]string{"PATH": os.Getenv("PATH")}}
+	spawner := NewProcessSpawner(state)
+
+	list, _ := parseCommandList("echo ${!:-none} $$; sleep 0.1 & echo $! \"$!\"")
+	result := runCommandList(state, list, spawner.Run)
+
+	jobs := state.Jobs().List()
+	if len(jobs) != 1 {
+		t.Fatalf("Expected one job, got %+v", jobs)
+	}
+	want := fmt.Sprintf("none %d\n[1] %d\n%d %d\n", os.Getpid(), jobs[0].Pid, jobs[0].Pid, jobs[0].Pid)
+	if result.Output != want {
+		t.Errorf("Expected %q, got %q", want, result.Output)
+	}
+	waitForJob(t, jobs[0])
+}
+
+func TestProcessSpawner_RunBackgroundRedirectsAndExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	state := &ShellState{WorkingDirectory: dir, Environment: map[string]string{"PATH": os.Getenv("PATH")}}
+	spawner := NewProcessSpawner(state)
+
+	for _, line := range []string{"echo saved > out.txt &", "sh -c 'exit 3' &"} {
+		list, err := parseCommandList(line)
+		if err != nil || len(list) != 1 || !list[0].Background {
+			t.Fatalf("parseCommandList(%q) = %+v, %v", line, list, err)
+		}
+		spawner.Run(list[0].ShellCommand)
+	}
+
+	jobs := state.Jobs().List()
+	if len(jobs) != 2 || jobs[1].ID != 2 {
+		t.Fatalf("Expected two jobs, got %+v", jobs)
+	}
+	for _, job := range jobs {
+		waitForJob(t, job)
+	}
+
+	notices := state.Jobs().DrainNotifications()
+	if len(notices) != 2 || !strings.Contains(notices[0], "Done") || !strings.Contains(notices[1], "Exit 3") {
+		t.Errorf("Unexpected notices: %q", notices)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "out.txt")); string(data) != "saved\n" {
+		t.Errorf("Unexpected file contents: %q", data)
+	}
+
+	// Numbering starts over once the table is empty
+	list, _ := parseCommandList("sleep 0 &")
+	if result := spawner.Run(list[0].ShellCommand); !strings.HasPrefix(result.Output, "[1] ") {
+		t.Errorf("Expected job 1 again, got %q", result.Output)
+	}
+}
+
+func TestProcessSpawner_RunBackgroundMissingCommand(t *testing.T) {
+	state := &ShellState{WorkingDirectory: t.TempDir(), Environment: map[string]string{"PATH": os.Getenv("PATH")}}
+	list, _ := parseCommandList("no-such-command-gosh &")
+
+	result := NewProcessSpawner(state).Run(list[0].ShellCommand)
+	if result.ExitCode != 1 || !strings.Contains(result.Output, "no-such-command-gosh") {
+		t.Errorf("Expected a start error, got %q (exit %d)", result.Output, result.ExitCode)
+	}
+	if len(state.Jobs().List()) != 0 {
+		t.Error("A command that didn't start mustn't become a job")
+	}
+}
+
+// waitForStatus waits for a job to reach status
+func waitForStatus(t *testing.T, table *JobTable, id int, status JobStatus) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, job := range table.List() {
+			if job.ID == id && job.Status == status {
+				return
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Job %d never became %s", id, status)
+}
+
+func startTestJobs(t *testing.T, lines ...string) (*ShellState, *BuiltinHandler) {
+	t.Helper()
+	state := &ShellState{WorkingDirectory: t.TempDir(), Environment: map[string]string{"PATH": os.Getenv("PATH")}}
+	spawner := NewProcessSpawner(state)
+	for _, line := range lines {
+		list, err := parseCommandList(line)
+		if err != nil {
+			t.Fatalf("parseCommandList(%q) error: %v", line, err)
+		}
+		if result := runCommandList(state, list, spawner.Run); result.ExitCode != 0 {
+			t.Fatalf("%q failed: %s", line, result.Output)
+		}
+	}
+	t.Cleanup(func() {
+		for _, job := range state.Jobs().List() {
+			syscall.Kill(-job.Pid, syscall.SIGKILL)
+		}
+	})
+	return state, NewBuiltinHandler(state)
+}
+
+func TestJobTable_Find(t *testing.T) {
+	state, _ := startTestJobs(t, "sleep 30 &", "sh -c 'sleep 30' &", "sleep 31 &")
+	table := state.Jobs()
+
+	tests := []struct {
+		spec string
+		id   int
+	}{
+		{"", 3}, {"%+", 3}, {"%%", 3}, {"%-", 2}, {"%1", 1}, {"2", 2}, {"%sh", 2}, {"%?31", 3},
+	}
+	for _, tt := range tests {
+		job, err := table.Find(tt.spec)
+		if err != nil || job.ID != tt.id {
+			t.Errorf("Find(%q) = %v, %v; want job %d", tt.spec, job, err, tt.id)
+		}
+	}
+
+	for _, spec := range []string{"%4", "%sleep", "%?nothing", "abc"} {
+		if _, err := table.Find(spec); err == nil {
+			t.Errorf("Expected Find(%q) to fail", spec)
+		}
+	}
+
+	// A stopped job becomes the current one
+	job, _ := table.Find("%1")
+	syscall.Kill(-job.Pid, syscall.SIGSTOP)
+	waitForStatus(t, table, 1, JobStopped)
+	if job, _ := table.Find(""); job.ID != 1 {
+		t.Errorf("Expected the stopped job to be current, got %d", job.ID)
+	}
+}
+
+func TestJobsBuiltin(t *testing.T) {
+	state, builtins := startTestJobs(t, "sleep 30 &", "sleep 31 &")
+	jobs := state.Jobs().List()
+
+	result := builtins.Execute("jobs", nil)
+	want := "[1]- Running  sleep 30 &\n[2]+ Running  sleep 31 &"
+	if result.Output != want {
+		t.Errorf("jobs = %q, want %q", result.Output, want)
+	}
+
+	result = builtins.Execute("jobs", []string{"-p"})
+	if result.Output != fmt.Sprintf("%d\n%d", jobs[0].Pid, jobs[1].Pid) {
+		t.Errorf("jobs -p = %q", result.Output)
+	}
+
+	if result := builtins.Execute("jobs", []string{"-x"}); result.ExitCode != 1 {
+		t.Errorf("Expected an unknown option error, got %q", result.Output)
+	}
+}
+
+func TestBgBuiltin_ResumesStoppedJob(t *testing.T) {
+	state, builtins := startTestJobs(t, "sleep 30 &")
+	table := state.Jobs()
+	job, _ := table.Find("%1")
+
+	if result := builtins.Execute("bg", nil); !strings.Contains(result.Output, "already in background") {
+		t.Errorf("Expected an already-running message, got %q", result.Output)
+	}
+
+	syscall.Kill(-job.Pid, syscall.SIGSTOP)
+	waitForStatus(t, table, 1, JobStopped)
+	if result := builtins.Execute("jobs", nil); !strings.Contains(result.Output, "Stopped") {
+		t.Errorf("Expected jobs to show the stopped job, got %q", result.Output)
+	}
+
+	result := builtins.Execute("bg", []string{"%1"})
+	if result.ExitCode != 0 || result.Output != "[1]+ sleep 30 &" {
+		t.Errorf("bg = %q (exit %d)", result.Output, result.ExitCode)
+	}
+	waitForStatus(t, table, 1, JobRunning)
+
+	if result := builtins.Execute("bg", []string{"%9"}); result.ExitCode != 1 {
+		t.Errorf("Expected an error for a missing job, got %q", result.Output)
+	}
+}
+
+func TestJobTable_Foreground(t *testing.T) {
+	state, _ := startTestJobs(t, "sh -c 'echo early; kill -STOP $$; echo late; exit 4' &")
+	table := state.Jobs()
+	waitForStatus(t, table, 1, JobStopped)
+	job, _ := table.Find("")
+
+	var out strings.Builder
+	status, exitCode, err := table.Foreground(job, &out)
+	if err != nil || status != JobDone || exitCode != 4 {
+		t.Fatalf("Foreground = %v, %d, %v", status, exitCode, err)
+	}
+	if out.String() != "early\nlate\n" {
+		t.Errorf("Expected the collected and live output, got %q", out.String())
+	}
+	if len(table.List()) != 0 {
+		t.Error("Expected a job finished in the foreground to leave the table")
+	}
+	if notices := table.DrainNotifications(); len(notices) != 0 {
+		t.Errorf("Expected no Done notice for a foreground job, got %v", notices)
+	}
+}
+
+func TestFgBuiltin_NoJobs(t *testing.T) {
+	_, builtins := startTestJobs(t)
+	if result := builtins.Execute("fg", nil); result.ExitCode != 1 || result.Output != "fg: no current job" {
+		t.Errorf("Unexpected result %q (exit %d)", result.Output, result.ExitCode)
+	}
+}
+
+// waitForForeground waits until the table has a foreground command
+func waitForForeground(t *testing.T, table *JobTable) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		table.mu.Lock()
+		running := table.foreground != nil
+		table.mu.Unlock()
+		if running {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("No foreground command started")
+}
+
+func TestProcessSpawner_RunStoppedBecomesJob(t *testing.T) {
+	state, _ := startTestJobs(t)
+	table := state.Jobs()
+	table.EnableJobControl()
+	spawner := NewProcessSpawner(state)
+
+	cmd, _ := parseShellCommand("sh -c 'echo before; sleep 1; echo after'")
+	results := make(chan ExecutionResult, 1)
+	go func() { results <- spawner.Run(cmd) }()
+
+	waitForForeground(t, table)
+	time.Sleep(100 * time.Millisecond)
+	if !table.SignalForeground(syscall.SIGTSTP) {
+		t.Fatal("Expected a foreground command to signal")
+	}
+
+	var result ExecutionResult
+	select {
+	case result = <-results:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run didn't return after Ctrl-Z")
+	}
+	want := "before\n[1]+ Stopped  sh -c 'echo before; sleep 1; echo after'"
+	if result.Output != want || result.ExitCode != 148 {
+		t.Errorf("Run = %q (exit %d), want %q", result.Output, result.ExitCode, want)
+	}
+	if jobs := table.List(); len(jobs) != 1 || jobs[0].Status != JobStopped {
+		t.Fatalf("Expected a stopped job, got %+v", jobs)
+	}
+
+	// fg picks it up where it left off
+	job, _ := table.Find("%1")
+	var out strings.Builder
+	status, exitCode, err := table.Foreground(job, &out)
+	if err != nil || status != JobDone || exitCode != 0 || out.String() != "after\n" {
+		t.Errorf("Foreground = %v, %d, %v with output %q", status, exitCode, err, out.String())
+	}
+	if table.SignalForeground(syscall.SIGINT) {
+		t.Error("Expected no foreground command once it has finished")
+	}
+}
+
+func TestProcessSpawner_RunInterleavesOutput(t *testing.T) {
+	state, _ := startTestJobs(t)
+	cmd, _ := parseShellCommand("sh -c 'echo one; echo two >&2; echo three'")
+	if result := NewProcessSpawner(state).Run(cmd); result.Output != "one\ntwo\nthree\n" {
+		t.Errorf("Expected interleaved output, got %q", result.Output)
+	}
+}
@@ -0,0 +1,455 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// kubeContext is one entry of the kubeconfig `contexts` list
+type kubeContext struct {
+	Name      string
+	Cluster   string
+	User      string
+	Namespace string
+}
+
+// kubeConfig is the merged view of all kubeconfig files, following
+// kubectl's rules: the first file to set a value wins.
+type kubeConfig struct {
+	CurrentContext string
+	Contexts       []kubeContext
+}
+
+// Current returns the active context, or nil if none is set
+func (k *kubeConfig) Current() *kubeContext {
+	return k.Context(k.CurrentContext)
+}
+
+// Context looks up a context by name
+func (k *kubeConfig) Context(name string) *kubeContext {
+	if name == "" {
+		return nil
+	}
+	for i := range k.Contexts {
+		if k.Contexts[i].Name == name {
+			return &k.Contexts[i]
+		}
+	}
+	return nil
+}
+
+// kubeconfigPaths returns the kubeconfig files in effect: $KUBECONFIG
+// (colon separated) or ~/.kube/config
+func kubeconfigPaths(kubeconfig, home string) []string {
+	var paths []string
+	for _, p := range filepath.SplitList(kubeconfig) {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	if len(paths) == 0 && home != "" {
+		paths = append(paths, filepath.Join(home, ".kube", "config"))
+	}
+	return paths
+}
+
+// kubeconfigFile mirrors the parts of a kubeconfig file gosh cares about
+type kubeconfigFile struct {
+	CurrentContext string `yaml:"current-context"`
+	Contexts       []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster   string `yaml:"cluster"`
+			User      string `yaml:"user"`
+			Namespace string `yaml:"namespace"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+}
+
+// loadKubeConfig reads and merges the kubeconfig files. Missing files are
+// skipped; it's an error only if none of them could be read.
+func loadKubeConfig(paths []string) (*kubeConfig, error) {
+	config := &kubeConfig{}
+	seen := make(map[string]bool)
+	found := false
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		var file kubeconfigFile
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		found = true
+
+		if config.CurrentContext == "" {
+			config.CurrentContext = file.CurrentContext
+		}
+		for _, c := range file.Contexts {
+			if c.Name == "" || seen[c.Name] {
+				continue
+			}
+			seen[c.Name] = true
+			config.Contexts = append(config.Contexts, kubeContext{
+				Name:      c.Name,
+				Cluster:   c.Context.Cluster,
+				User:      c.Context.User,
+				Namespace: c.Context.Namespace,
+			})
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("no kubeconfig found")
+	}
+	return config, nil
+}
+
+// The prompt is redrawn after every command, so the parsed kubeconfig is
+// cached until one of the files changes on disk.
+var (
+	kubeCacheMutex     sync.Mutex
+	kubeCacheSignature string
+	kubeCacheConfig    *kubeConfig
+)
+
+func kubeconfigSignature(paths []string) string {
+	var sb strings.Builder
+	for _, path := range paths {
+		sb.WriteString(path)
+		if info, err := os.Stat(path); err == nil {
+			sb.WriteString(fmt.Sprintf(":%d:%d", info.ModTime().UnixNano(), info.Size()))
+		}
+		sb.WriteString(";")
+	}
+	return sb.String()
+}
+
+// cachedKubeConfig returns the merged kubeconfig, re-reading it only when
+// the files have changed
+func cachedKubeConfig(paths []string) *kubeConfig {
+	signature := kubeconfigSignature(paths)
+
+	kubeCacheMutex.Lock()
+	defer kubeCacheMutex.Unlock()
+
+	if signature != kubeCacheSignature {
+		config, err := loadKubeConfig(paths)
+		if err != nil {
+			config = nil
+		}
+		kubeCacheConfig = config
+		kubeCacheSignature = signature
+	}
+	return kubeCacheConfig
+}
+
+// kubePromptSegment returns "context/namespace" for the prompt, or "" when
+// there is no active context or GOSH_KUBE_PROMPT=0
+func kubePromptSegment(env map[string]string) string {
+	if env["GOSH_KUBE_PROMPT"] == "0" {
+		return ""
+	}
+
+	config := cachedKubeConfig(kubeconfigPaths(env["KUBECONFIG"], env["HOME"]))
+	if config == nil {
+		return ""
+	}
+	current := config.Current()
+	if current == nil {
+		return ""
+	}
+
+	namespace := current.Namespace
+	if namespace == "" {
+		namespace = "default"
+	}
+	return current.Name + "/" + namespace
+}
+
+// editKubeconfig rewrites a kubeconfig file through its YAML node tree so
+// comments and unrelated settings are preserved
+func editKubeconfig(path string, edit func(root *yaml.Node) error) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("%s: not a kubeconfig file", path)
+	}
+
+	if err := edit(doc.Content[0]); err != nil {
+		return err
+	}
+
+	// Indented as kubectl writes it, rather than yaml.v3's default of four
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+
+	// Replaced in one go, so kubectl never reads a half written file. A
+	// symlinked kubeconfig stays a symlink.
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(out.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// yamlMappingValue returns the value node for key in a mapping node
+func yamlMappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setYAMLMappingValue sets key to a string value, adding it if missing
+func setYAMLMappingValue(mapping *yaml.Node, key, value string) {
+	if node := yamlMappingValue(mapping, key); node != nil {
+		node.Kind = yaml.ScalarNode
+		node.Tag = "!!str"
+		node.Value = value
+		return
+	}
+	mapping.Content = append(mapping.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value})
+}
+
+// findKubeContextNode returns the `context` mapping of the named context
+func findKubeContextNode(root *yaml.Node, name string) *yaml.Node {
+	contexts := yamlMappingValue(root, "contexts")
+	if contexts == nil || contexts.Kind != yaml.SequenceNode {
+		return nil
+	}
+	for _, entry := range contexts.Content {
+		if entry.Kind != yaml.MappingNode {
+			continue
+		}
+		if n := yamlMappingValue(entry, "name"); n == nil || n.Value != name {
+			continue
+		}
+		ctx := yamlMappingValue(entry, "context")
+		if ctx == nil {
+			ctx = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			entry.Content = append(entry.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "context"}, ctx)
+		} else if ctx.Kind != yaml.MappingNode {
+			*ctx = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		return ctx
+	}
+	return nil
+}
+
+// setKubeCurrentContext switches current-context like `kubectl config
+// use-context`: the first file that sets current-context is updated,
+// otherwise the first existing file
+func setKubeCurrentContext(paths []string, name string) error {
+	target := ""
+	for _, path := range paths {
+		var file kubeconfigFile
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if target == "" {
+			target = path
+		}
+		if yaml.Unmarshal(data, &file) == nil && file.CurrentContext != "" {
+			target = path
+			break
+		}
+	}
+	if target == "" {
+		return fmt.Errorf("no kubeconfig found")
+	}
+
+	return editKubeconfig(target, func(root *yaml.Node) error {
+		setYAMLMappingValue(root, "current-context", name)
+		return nil
+	})
+}
+
+// setKubeNamespace sets the namespace of the named context in the file
+// that defines it
+func setKubeNamespace(paths []string, contextName, namespace string) error {
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+
+		found := false
+		err := editKubeconfig(path, func(root *yaml.Node) error {
+			ctx := findKubeContextNode(root, contextName)
+			if ctx == nil {
+				return errKubeContextNotInFile
+			}
+			found = true
+			setYAMLMappingValue(ctx, "namespace", namespace)
+			return nil
+		})
+		if found || (err != nil && err != errKubeContextNotInFile) {
+			return err
+		}
+	}
+	return fmt.Errorf("context %q not found", contextName)
+}
+
+var errKubeContextNotInFile = fmt.Errorf("context not defined in this file")
+
+// kubeNamespaces lists cluster namespaces via kubectl for completion. This
+// is the one place that talks to the cluster, so it's bounded by a timeout.
+func kubeNamespaces(env []string) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "kubectl", "get", "namespaces", "-o", "name")
+	cmd.Env = env
+	output, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+
+	var namespaces []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if name := strings.TrimPrefix(line, "namespace/"); name != "" {
+			namespaces = append(namespaces, name)
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+func (b *BuiltinHandler) kubeconfigPaths() []string {
+	return kubeconfigPaths(b.state.Environment["KUBECONFIG"], b.state.Environment["HOME"])
+}
+
+// kctx implements the kctx builtin
+func (b *BuiltinHandler) kctx(args []string) ExecutionResult {
+	paths := b.kubeconfigPaths()
+	config, err := loadKubeConfig(paths)
+	if err != nil {
+		return ExecutionResult{Output: fmt.Sprintf("kctx: %v", err), ExitCode: 1, Error: err}
+	}
+
+	if len(args) == 0 {
+		if len(config.Contexts) == 0 {
+			return ExecutionResult{Output: "kctx: no contexts defined", ExitCode: 1, Error: fmt.Errorf("no contexts defined")}
+		}
+		var lines []string
+		for _, c := range config.Contexts {
+			marker := "  "
+			if c.Name == config.CurrentContext {
+				marker = "* "
+			}
+			lines = append(lines, marker+c.Name)
+		}
+		return ExecutionResult{Output: strings.Join(lines, "\n"), ExitCode: 0}
+	}
+
+	name := args[0]
+	if config.Context(name) == nil {
+		return ExecutionResult{Output: fmt.Sprintf("kctx: no context named %q", name), ExitCode: 1, Error: fmt.Errorf("unknown context: %s", name)}
+	}
+
+	if err := setKubeCurrentContext(paths, name); err != nil {
+		return ExecutionResult{Output: fmt.Sprintf("kctx: %v", err), ExitCode: 1, Error: err}
+	}
+
+	b.state.ForcePromptRefresh()
+	return ExecutionResult{Output: fmt.Sprintf("Switched to context %q", name), ExitCode: 0}
+}
+
+// kns implements the kns builtin
+func (b *BuiltinHandler) kns(args []string) ExecutionResult {
+	paths := b.kubeconfigPaths()
+	config, err := loadKubeConfig(paths)
+	if err != nil {
+		return ExecutionResult{Output: fmt.Sprintf("kns: %v", err), ExitCode: 1, Error: err}
+	}
+
+	current := config.Current()
+	if current == nil {
+		return ExecutionResult{Output: "kns: no current context (use kctx NAME)", ExitCode: 1, Error: fmt.Errorf("no current context")}
+	}
+
+	if len(args) == 0 {
+		namespace := current.Namespace
+		if namespace == "" {
+			namespace = "default"
+		}
+		return ExecutionResult{Output: namespace, ExitCode: 0}
+	}
+
+	if err := setKubeNamespace(paths, current.Name, args[0]); err != nil {
+		return ExecutionResult{Output: fmt.Sprintf("kns: %v", err), ExitCode: 1, Error: err}
+	}
+
+	b.state.ForcePromptRefresh()
+	return ExecutionResult{Output: fmt.Sprintf("Namespace for %q set to %q", current.Name, args[0]), ExitCode: 0}
+}
+
+const kctxHelpText = "kctx - Switch Kubernetes Context\n\n" +
+	"USAGE:\n" +
+	"    kctx [NAME]\n\n" +
+	"DESCRIPTION:\n" +
+	"    Without arguments, list the contexts in your kubeconfig with the\n" +
+	"    current one marked. With NAME, make it the current context.\n" +
+	"    Reads $KUBECONFIG or ~/.kube/config directly.\n\n" +
+	"    The prompt shows the active context/namespace; set\n" +
+	"    GOSH_KUBE_PROMPT=0 to hide it."
+
+const knsHelpText = "kns - Switch Kubernetes Namespace\n\n" +
+	"USAGE:\n" +
+	"    kns [NAMESPACE]\n\n" +
+	"DESCRIPTION:\n" +
+	"    Without arguments, print the namespace of the current context.\n" +
+	"    With NAMESPACE, set it as the current context's default namespace."
@@ -0,0 +1,132 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+# keep this comment
+current-context: dev
+contexts:
+- name: dev
+  context:
+    cluster: dev-cluster
+    user: dev-user
+    namespace: apps
+- name: prod
+  context:
+    cluster: prod-cluster
+    user: prod-user
+`
+
+func writeTestKubeconfig(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(testKubeconfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadKubeConfig(t *testing.T) {
+	path := writeTestKubeconfig(t)
+
+	config, err := loadKubeConfig([]string{filepath.Join(t.TempDir(), "missing"), path})
+	if err != nil {
+		t.Fatalf("loadKubeConfig failed: %v", err)
+	}
+
+	if config.CurrentContext != "dev" {
+		t.Errorf("Expected current context dev, got %q", config.CurrentContext)
+	}
+	if len(config.Contexts) != 2 {
+		t.Fatalf("Expected 2 contexts, got %d", len(config.Contexts))
+	}
+	if current := config.Current(); current == nil || current.Namespace != "apps" {
+		t.Errorf("Unexpected current context: %+v", current)
+	}
+
+	if _, err := loadKubeConfig([]string{filepath.Join(t.TempDir(), "missing")}); err == nil {
+		t.Error("Expected an error when no kubeconfig exists")
+	}
+}
+
+func TestKubeconfigPaths(t *testing.T) {
+	if got := kubeconfigPaths("", "/home/u"); len(got) != 1 || got[0] != "/home/u/.kube/config" {
+		t.Errorf("Unexpected default paths: %v", got)
+	}
+	if got := kubeconfigPaths("/a:/b", "/home/u"); len(got) != 2 || got[1] != "/b" {
+		t.Errorf("Unexpected KUBECONFIG paths: %v", got)
+	}
+}
+
+func TestKubePromptSegment(t *testing.T) {
+	path := writeTestKubeconfig(t)
+
+	env := map[string]string{"KUBECONFIG": path}
+	if got := kubePromptSegment(env); got != "dev/apps" {
+		t.Errorf("Expected dev/apps, got %q", got)
+	}
+
+	env["GOSH_KUBE_PROMPT"] = "0"
+	if got := kubePromptSegment(env); got != "" {
+		t.Errorf("Expected hidden segment, got %q", got)
+	}
+}
+
+func TestKctxAndKns(t *testing.T) {
+	path := writeTestKubeconfig(t)
+
+	state := NewShellState()
+	state.Environment["KUBECONFIG"] = path
+	b := NewBuiltinHandler(state)
+
+	result := b.Execute("kctx", nil)
+	if result.ExitCode != 0 || !strings.Contains(result.Output, "* dev") || !strings.Contains(result.Output, "  prod") {
+		t.Errorf("Unexpected kctx listing: %q", result.Output)
+	}
+
+	if result := b.Execute("kctx", []string{"nope"}); result.ExitCode == 0 {
+		t.Error("Switching to an unknown context should fail")
+	}
+
+	if result := b.Execute("kctx", []string{"prod"}); result.ExitCode != 0 {
+		t.Fatalf("kctx prod failed: %s", result.Output)
+	}
+
+	if result := b.Execute("kns", nil); result.Output != "default" {
+		t.Errorf("Expected default namespace for prod, got %q", result.Output)
+	}
+
+	if result := b.Execute("kns", []string{"monitoring"}); result.ExitCode != 0 {
+		t.Fatalf("kns failed: %s", result.Output)
+	}
+
+	config, err := loadKubeConfig([]string{path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.CurrentContext != "prod" || config.Current().Namespace != "monitoring" {
+		t.Errorf("Kubeconfig not updated: %+v", config)
+	}
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "# keep this comment") {
+		t.Error("Editing the kubeconfig should preserve comments")
+	}
+	if !strings.Contains(string(data), "\n  - name: dev\n    context:\n      cluster: dev-cluster\n") {
+		t.Errorf("Expected the kubeconfig indented by two spaces, in its order, got:\n%s", data)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the kubeconfig to keep its permissions, got %v", info)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("Expected no temporary files left behind, got %v", entries)
+	}
+}
@@ -20,64 +20,79 @@ func main() {
 			fmt.Printf("gosh %s - Go shell with yaegi\n\n", GetVersion())
 			fmt.Println("Usage:")
 			fmt.Println("  gosh          Start the gosh interactive shell")
+			fmt.Println("  gosh -c COMMAND [ARG...]")
+			fmt.Println("                 Run COMMAND (Go code after \"go> \") with ARGs as $1...")
+			fmt.Println("  gosh SCRIPT [ARG...]")
+			fmt.Println("                 Run a script, Go code if it ends in .go")
 			fmt.Println("  gosh --version Show version information")
 			fmt.Println("  gosh --help    Show this help message")
+			fmt.Println("  gosh complete --line LINE [--point N]")
+			fmt.Println("                 Print completion candidates for LINE")
+			fmt.Println("  gosh test-config [-v] [-run REGEX] [FILE]")
+			fmt.Println("                 Run the Test functions in a config file")
 			os.Exit(0)
+		case "complete":
+			os.Exit(runCompleteCommand(os.Args[2:], os.Stdout, os.Stderr))
+		case "test-config":
+			os.Exit(runTestConfigCommand(os.Args[2:], os.Stdout, os.Stderr))
 		case "-c":
 			if len(os.Args) < 3 {
-				fmt.Fprintf(os.Stderr, "Usage: gosh -c '<command>'\n")
+				fmt.Fprintf(os.Stderr, "Usage: gosh -c '<command>' [ARG...]\n")
 				os.Exit(1)
 			}
-			command := ""
-			for _, arg := range os.Args[2:] {
-				if command != "" {
-					command += " "
-				}
-				command += arg
+			command, goCode := strings.CutPrefix(os.Args[2], "go> ")
+			runNonInteractive("gosh", command, goCode, os.Args[3:])
+		default:
+			if strings.HasPrefix(os.Args[1], "-") {
+				break
 			}
-			session := NewSessionState()
-			evaluator := NewGoEvaluator()
-			spawner := NewProcessSpawner(&ShellState{WorkingDirectory: session.WorkingDir})
-			builtins := NewBuiltinHandler(&ShellState{WorkingDirectory: session.WorkingDir})
-
-			evaluator.SetupWithShell(&ShellState{WorkingDirectory: session.WorkingDir}, spawner)
-			evaluator.SetupWithBuiltins(builtins)
-
-			if err := evaluator.LoadConfig(); err != nil {
-				fmt.Fprintf(os.Stderr, "Config loading error: %v\n", err)
-			}
-
-			if strings.HasPrefix(command, "go> ") {
-				command = strings.TrimPrefix(command, "go> ")
-				result := evaluator.Eval(command)
-				fmt.Print(result.Output)
-				os.Exit(result.ExitCode)
-			} else {
-				parts := strings.Fields(command)
-				if len(parts) == 0 {
-					os.Exit(0)
-				}
-				result := spawner.ExecuteInteractive(parts[0], parts[1:])
-				fmt.Print(result.Output)
-				os.Exit(result.ExitCode)
+			code, goCode, err := readScript(os.Args[1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "gosh: %v\n", err)
+				os.Exit(127)
 			}
+			runNonInteractive(os.Args[1], code, goCode, os.Args[2:])
 		}
 	}
 
+	// The theme chosen with theme set in an earlier session
+	applySavedTheme()
+
 	session := NewSessionState()
+	state := NewShellState()
 	evaluator := NewGoEvaluator()
-	spawner := NewProcessSpawner(&ShellState{WorkingDirectory: session.WorkingDir})
-	builtins := NewBuiltinHandler(&ShellState{WorkingDirectory: session.WorkingDir})
+	spawner := NewProcessSpawner(state)
+	builtins := NewBuiltinHandler(state)
 
-	evaluator.SetupWithShell(&ShellState{WorkingDirectory: session.WorkingDir}, spawner)
+	evaluator.SetupWithShell(state, spawner)
 	evaluator.SetupWithBuiltins(builtins)
 
 	if err := evaluator.LoadConfig(); err != nil {
 		fmt.Fprintf(os.Stderr, "Config loading error: %v\n", err)
 	}
 
-	p := tea.NewProgram(initialModel(session, evaluator, spawner, builtins))
-	if _, err := p.Run(); err != nil {
+	// The directories changed to in the REPL are those j jumps to
+	state.AddChpwdHook(state.DirFrecency().OnDirectoryChange)
+
+	// Activate project tooling for the directory we started in
+	for _, message := range state.RunChpwdHooks("", state.WorkingDirectory) {
+		fmt.Println(message)
+	}
+
+	// Scheduled tasks and job control are for interactive sessions only
+	state.Scheduler().Start()
+	state.Jobs().EnableJobControl()
+
+	// gosh handles signals itself, so its traps run before the UI quits
+	p := tea.NewProgram(initialModel(session, state, evaluator, spawner, builtins), tea.WithoutSignalHandler())
+	SetTerminalOwner(p)
+	setupSignals(builtins, func(int) { p.Quit() })
+	_, err := p.Run()
+	if output := builtins.runExitTrap(); output != "" {
+		fmt.Println(output)
+	}
+	state.Jobs().HangUp()
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
@@ -0,0 +1,125 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"fmt"
+	"io"
+	runtimedebug "runtime/debug"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// memoryGuardInterval is how often the memory guard samples the heap. A
+// loop allocating as fast as it can grows it by gigabytes a second.
+const memoryGuardInterval = 10 * time.Millisecond
+
+// heapObjectsMetric is the memory taken by heap objects, live or not yet
+// collected, which unlike runtime.ReadMemStats is read without stopping
+// the world
+const heapObjectsMetric = "/memory/classes/heap/objects:bytes"
+
+// heapInUse returns the memory taken by heap objects
+func heapInUse() uint64 {
+	sample := []metrics.Sample{{Name: heapObjectsMetric}}
+	metrics.Read(sample)
+	return sample[0].Value.Uint64()
+}
+
+// memoryLimitError is why an evaluation stopped by the memory guard was
+// cancelled
+type memoryLimitError struct {
+	used, limit uint64
+}
+
+func (e *memoryLimitError) Error() string {
+	return fmt.Sprintf("stopped: Go code grew the heap by %s, over the limit of %s", formatByteSize(e.used), formatByteSize(e.limit))
+}
+
+// guardMemory watches the heap while Go code runs, writing a warning to w
+// once it has grown by more than warn bytes since the guard started and
+// calling stop once it has grown by more than limit. A limit of 0 is
+// none. The returned function ends the watch.
+func guardMemory(warn, limit uint64, w io.Writer, stop func(error)) (done func()) {
+	if warn == 0 && limit == 0 {
+		return func() {}
+	}
+
+	start := heapInUse()
+
+	quit := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(memoryGuardInterval)
+		defer ticker.Stop()
+		warned := false
+		for {
+			select {
+			case <-quit:
+				return
+			case <-ticker.C:
+			}
+			heap := heapInUse()
+			if heap <= start {
+				continue
+			}
+			used := heap - start
+			if limit > 0 && used > limit {
+				stop(&memoryLimitError{used: used, limit: limit})
+				return
+			}
+			if warn > 0 && used > warn && !warned {
+				warned = true
+				fmt.Fprintf(w, "gosh: warning: Go code has grown the heap by %s\n", formatByteSize(used))
+			}
+		}
+	}()
+
+	return func() {
+		close(quit)
+		<-finished
+	}
+}
+
+// releaseMemory hands what code stopped by the memory guard allocated back
+// to the OS, once the interpreter has let go of it
+func releaseMemory() {
+	go func() {
+		time.Sleep(memoryGuardInterval)
+		runtimedebug.FreeOSMemory()
+	}()
+}
+
+// byteSizeUnits are the units parseByteSize takes, as powers of 1024
+var byteSizeUnits = map[string]uint64{"": 1, "B": 1, "K": 1 << 10, "M": 1 << 20, "G": 1 << 30, "T": 1 << 40}
+
+// parseByteSize parses a size such as 512MB, 2G or 1.5GiB. The units are
+// powers of 1024.
+func parseByteSize(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	number := strings.TrimRightFunc(s, func(r rune) bool { return r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' })
+	unit := strings.ToUpper(strings.TrimSpace(s[len(number):]))
+	if len(unit) > 1 {
+		unit = strings.TrimSuffix(strings.TrimSuffix(unit, "B"), "I")
+	}
+	multiplier, ok := byteSizeUnits[unit]
+	value, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if !ok || err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size: %s", s)
+	}
+	return uint64(value * float64(multiplier)), nil
+}
+
+// formatByteSize formats n bytes in the largest unit it reaches
+func formatByteSize(n uint64) string {
+	for _, unit := range []string{"TB", "GB", "MB", "KB"} {
+		size := byteSizeUnits[unit[:1]]
+		if n >= size {
+			return strconv.FormatFloat(float64(n)/float64(size), 'f', 1, 64) + " " + unit
+		}
+	}
+	return fmt.Sprintf("%d B", n)
+}
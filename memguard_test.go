@@ -0,0 +1,73 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected uint64
+		wantErr  bool
+	}{
+		{"0", 0, false},
+		{"512", 512, false},
+		{"64KB", 64 << 10, false},
+		{"512MB", 512 << 20, false},
+		{"2G", 2 << 30, false},
+		{"1.5GiB", 3 << 29, false},
+		{"100 mb", 100 << 20, false},
+		{"lots", 0, true},
+		{"5PB", 0, true},
+		{"-1MB", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseByteSize(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.expected {
+			t.Errorf("parseByteSize(%q) = %d, %v, want %d (error %v)", tt.input, got, err, tt.expected, tt.wantErr)
+		}
+	}
+}
+
+func TestFormatByteSize(t *testing.T) {
+	for n, expected := range map[uint64]string{100: "100 B", 1536: "1.5 KB", 512 << 20: "512.0 MB", 3 << 30: "3.0 GB"} {
+		if got := formatByteSize(n); got != expected {
+			t.Errorf("formatByteSize(%d) = %q, want %q", n, got, expected)
+		}
+	}
+}
+
+func TestGoEvaluator_MemoryGuard(t *testing.T) {
+	skipUnderRace(t)
+	state := NewShellState()
+	g := NewGoEvaluator()
+	g.SetupWithShell(state, NewProcessSpawner(state))
+
+	if result := g.Eval(`gosh.MemoryGuard("", "huge")`); !strings.Contains(result.Output, "invalid size") {
+		t.Errorf("Expected an invalid size error, got %q", result.Output)
+	}
+	if result := g.Eval(`gosh.MemoryGuard("8MB", "64MB")`); result.ExitCode != 0 {
+		t.Fatalf("gosh.MemoryGuard failed: %s", result.Output)
+	}
+
+	// Keeps everything it allocates, so the heap only grows
+	result := g.Eval(`hoard := [][]byte{}; for { hoard = append(hoard, make([]byte, 1<<20)) }`)
+	if result.ExitCode != 1 || !strings.Contains(result.Output, "over the limit of 64.0 MB") {
+		t.Errorf("Expected the memory guard to stop the code, got %d %q", result.ExitCode, result.Output)
+	}
+
+	// Below the limit, there's only a warning
+	g.Eval(`import "time"`)
+	result = g.Eval(`hoard := make([]byte, 32<<20); time.Sleep(100 * time.Millisecond); len(hoard)`)
+	if result.ExitCode != 0 || !strings.Contains(result.Output, "warning: Go code has grown the heap by") {
+		t.Errorf("Expected a warning, got %d %q", result.ExitCode, result.Output)
+	}
+
+	// Code that stays within the limits is unaffected
+	if result := g.Eval(`len(make([]byte, 1<<10))`); result.Output != "1024" {
+		t.Errorf("Expected 1024, got %q", result.Output)
+	}
+}
@@ -5,6 +5,8 @@ package main
 import (
 	"fmt"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbletea"
@@ -12,41 +14,103 @@ import (
 )
 
 type model struct {
-	textarea   textarea.Model
-	session    *SessionState
-	evaluator  *GoEvaluator
-	spawner    *ProcessSpawner
-	builtins   *BuiltinHandler
-	output     string
-	quitting   bool
-	width      int
-	height     int
-	historyIdx int
+	textarea    textarea.Model
+	session     *SessionState
+	state       *ShellState
+	evaluator   *GoEvaluator
+	spawner     *ProcessSpawner
+	builtins    *BuiltinHandler
+	integration *ShellIntegration
+	shellPrompt string // The shell-mode prompt, worked out again after each block
+	output      string
+	marks       string // Zero-width shell integration sequences emitted before the prompt
+	picker      *fuzzyPicker
+	running     string      // Input of the block being executed, while it runs
+	live        *liveOutput // What the running block's commands have written so far
+	quitting    bool
+	width       int
+	height      int
+	historyIdx  int
 }
 
-func initialModel(session *SessionState, evaluator *GoEvaluator, spawner *ProcessSpawner, builtins *BuiltinHandler) model {
+// liveTickMsg redraws the output of the running block
+type liveTickMsg struct{}
+
+// liveTick schedules the next redraw of a running block's output
+func liveTick() tea.Cmd {
+	return tea.Tick(100*time.Millisecond, func(time.Time) tea.Msg { return liveTickMsg{} })
+}
+
+// blockFinishedMsg reports the result of a block run in the background by
+// runBlock
+type blockFinishedMsg struct {
+	start    string // Shell integration marks emitted when the block started
+	output   string
+	exitCode int
+}
+
+func initialModel(session *SessionState, state *ShellState, evaluator *GoEvaluator, spawner *ProcessSpawner, builtins *BuiltinHandler) model {
+	integration := NewShellIntegration(state.Environment)
+
 	ta := textarea.New()
 	ta.Placeholder = ""
 	ta.Focus()
-	ta.Prompt = session.GetPrompt()
 	ta.CharLimit = 0
-	ta.SetWidth(80)
 	ta.SetHeight(1)
 
 	ta.KeyMap.InsertNewline.SetEnabled(false)
 
-	return model{
-		textarea:   ta,
-		session:    session,
-		evaluator:  evaluator,
-		spawner:    spawner,
-		builtins:   builtins,
-		output:     "",
-		quitting:   false,
-		width:      80,
-		height:     24,
-		historyIdx: -1,
+	// Commands and Go code show their output while they run
+	live := &liveOutput{}
+	spawner.live = live
+	evaluator.live = live
+
+	m := model{
+		textarea:    ta,
+		session:     session,
+		state:       state,
+		evaluator:   evaluator,
+		spawner:     spawner,
+		builtins:    builtins,
+		integration: integration,
+		live:        live,
+		output:      "",
+		marks:       integration.ReportCwd(state.WorkingDirectory),
+		quitting:    false,
+		width:       80,
+		height:      24,
+		historyIdx:  -1,
 	}
+	return m.refreshPrompt()
+}
+
+// prompt returns the textarea prompt for the current mode, wrapped in shell
+// integration marks when an editor terminal is listening for them. Shell
+// mode shows the directory, git branch and segments of ShellState.GetPrompt.
+func (m model) prompt() string {
+	prompt := m.session.GetPrompt()
+	if m.session.Mode == ModeShell && m.shellPrompt != "" {
+		prompt = m.shellPrompt
+	}
+	return m.integration.PromptStart() + prompt + m.integration.PromptEnd()
+}
+
+// refreshPrompt works out the shell-mode prompt again, for a new
+// directory, branch or environment, and shows the prompt
+func (m model) refreshPrompt() model {
+	m.shellPrompt = m.state.GetPrompt()
+	m.setPrompt()
+	return m
+}
+
+// setPrompt shows the prompt for the current mode. Its width is measured
+// without the colors and shell integration marks, which the textarea would
+// otherwise count as columns.
+func (m *model) setPrompt() {
+	prompt := m.prompt()
+	m.textarea.Prompt = prompt
+	m.textarea.SetPromptFunc(lipgloss.Width(prompt), func(int) string { return prompt })
+	m.textarea.SetWidth(m.width)
 }
 
 func (m model) Init() tea.Cmd {
@@ -58,15 +122,77 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
+		// A running block may be reading the size; it's updated when it finishes
+		if m.running == "" {
+			m.state.TerminalWidth = msg.Width
+			m.state.TerminalHeight = msg.Height
+		}
 		m.textarea.SetWidth(msg.Width)
 		return m, nil
 
+	case blockFinishedMsg:
+		m = m.finishBlock(msg)
+		if m.state.takeClearScreen() {
+			return m, tea.ClearScreen
+		}
+		return m, nil
+
+	case liveTickMsg:
+		if m.running == "" {
+			return m, nil
+		}
+		return m, liveTick()
+
+	case pickerResultMsg:
+		return m.applyPickerSelection(msg.kind, msg.selection), nil
+
+	case editFinishedMsg:
+		return m.finishEdit(msg)
+
 	case tea.KeyMsg:
+		// While a block runs, Ctrl-C and Ctrl-Z go to the foreground command,
+		// just as the terminal would deliver them, and other keys are ignored
+		if m.running != "" {
+			switch msg.Type {
+			case tea.KeyCtrlC:
+				m.state.Jobs().SignalForeground(syscall.SIGINT)
+				if m.evaluator != nil {
+					m.evaluator.Interrupt()
+				}
+				m.state.Traps().Interrupt()
+			case tea.KeyCtrlZ:
+				m.state.Jobs().SignalForeground(syscall.SIGTSTP)
+			}
+			return m, nil
+		}
+
+		if m.picker != nil {
+			selection, done := m.picker.Update(msg)
+			if done {
+				kind := m.picker.kind
+				m.picker = nil
+				m = m.applyPickerSelection(kind, selection)
+			}
+			return m, nil
+		}
+
 		switch msg.Type {
 		case tea.KeyCtrlC, tea.KeyCtrlD:
 			m.quitting = true
 			return m, tea.Quit
 
+		case tea.KeyCtrlL:
+			// Clears the screen, keeping what's typed
+			m.output = ""
+			return m, tea.ClearScreen
+
+		case tea.KeyCtrlR:
+			return m.openPicker(PickerHistory)
+		case tea.KeyCtrlT:
+			return m.openPicker(PickerFiles)
+		case tea.KeyCtrlG:
+			return m.openPicker(PickerBranches)
+
 		case tea.KeyEnter:
 			return m.handleEnter()
 		case tea.KeyUp, tea.KeyDown:
@@ -76,46 +202,203 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	var cmd tea.Cmd
 	m.textarea, cmd = m.textarea.Update(msg)
-	m.textarea.Prompt = m.session.GetPrompt()
 	return m, cmd
 }
 
 func (m model) handleEnter() (tea.Model, tea.Cmd) {
 	input := m.textarea.Value()
 	if input == "" {
+		if queued := m.backgroundOutput(); queued != "" {
+			m.output = queued
+		}
 		return m, nil
 	}
 
 	m.textarea.Reset()
-	m.textarea.Prompt = m.session.GetPrompt()
 	m.historyIdx = -1
 
 	// Handle mode switching commands
 	if input == ":go" {
 		m.session.Mode = ModeGo
-		m.textarea.Prompt = m.session.GetPrompt()
+		m.setPrompt()
 		return m, nil
 	}
 	if input == ":sh" {
 		m.session.Mode = ModeShell
-		m.textarea.Prompt = m.session.GetPrompt()
+		m.setPrompt()
+		return m, nil
+	}
+	if input == ":format" || strings.HasPrefix(input, ":format ") {
+		result := m.builtins.Execute("format", strings.Fields(input)[1:])
+		m.output = result.Output
+		m.marks = ""
+		return m, nil
+	}
+	// :edit, on its own or after the lines of an unfinished block, opens
+	// them in the editor
+	if lines := strings.Split(input, "\n"); strings.TrimSpace(lines[len(lines)-1]) == ":edit" {
+		return m.editSnippet(strings.Join(lines[:len(lines)-1], "\n"))
+	}
+	if n, ok := historyReference(input); ok {
+		return m.runHistoryEntry(n)
+	}
+	switch strings.TrimSpace(input) {
+	case ":again":
+		return m.runAgain()
+	case ":fix":
+		return m.fixLast(), nil
+	}
+	if output, ok := m.replCommand(input); ok {
+		m.output = output
+		m.marks = ""
 		return m, nil
 	}
 
-	// Check if input is complete (for multiline Go)
-	if m.session.Mode == ModeGo && !isComplete(input) {
+	// Check if input is complete (for multiline Go, or a heredoc still
+	// waiting for its delimiter)
+	mode, block := blockMode(m.session.Mode, input)
+	if (mode == ModeGo && !isComplete(block)) || (mode == ModeShell && heredocPending(block)) {
 		m.textarea.SetValue(input + "\n")
 		m.textarea.CursorEnd()
 		return m, nil
 	}
 
-	// Execute the block
-	output := m.executeBlock(input)
-	m.output = output
+	// Execute the block off the UI goroutine, so keys like Ctrl-Z still
+	// reach the running command
+	m.running = input
+	return m, m.runBlock(input)
+}
+
+// resetInterpreter replaces the Go interpreter with a fresh one, loading
+// the config again if loadConfig is set. Variables captured with -> are
+// injected again, since they belong to the shell session.
+func (m model) resetInterpreter(loadConfig bool) string {
+	err := m.evaluator.Reset(loadConfig)
+	for name, lines := range m.session.CapturedVars {
+		m.evaluator.InjectVariable(name, lines)
+	}
+	if err != nil {
+		return fmt.Sprintf(":reset: %v", err)
+	}
+	return "Go interpreter reset"
+}
+
+// runBlock returns a command that executes input and reports the result
+// with a blockFinishedMsg
+func (m model) runBlock(input string) tea.Cmd {
+	start := m.commandStart(input)
+	m.live.Reset()
+	return tea.Batch(func() tea.Msg {
+		output, exitCode := m.executeBlock(input)
+		return blockFinishedMsg{start: start, output: output, exitCode: exitCode}
+	}, liveTick())
+}
 
+// commandStart returns the shell integration marks for input starting to
+// run. The command line of a block running vault isn't sent, since its
+// arguments are secrets.
+func (m model) commandStart(input string) string {
+	if mode, block := blockMode(m.session.Mode, input); mode == ModeShell {
+		if list, err := parseCommandList(block); err == nil && runsVault(list) {
+			return m.integration.CommandStart("")
+		}
+	}
+	return m.integration.CommandStart(input)
+}
+
+// runsVault reports whether a command list runs vault, in a subshell or not
+func runsVault(list []ChainedCommand) bool {
+	for _, cmd := range list {
+		if cmd.Name == "vault" || runsVault(cmd.Subshell) {
+			return true
+		}
+	}
+	return false
+}
+
+// finishBlock shows the result of a block started by runBlock
+func (m model) finishBlock(msg blockFinishedMsg) model {
+	m.running = ""
+	m.state.TerminalWidth = m.width
+	m.state.TerminalHeight = m.height
+	m = m.refreshPrompt()
+
+	finish := m.integration.CommandFinished(msg.exitCode) + m.integration.ReportCwd(m.state.WorkingDirectory)
+	if msg.output != "" {
+		m.output = msg.start + msg.output
+		m.marks = finish
+	} else {
+		m.output = ""
+		m.marks = msg.start + finish
+	}
+
+	// Output from scheduled tasks and jobs that finished since the last prompt
+	if queued := m.backgroundOutput(); queued != "" {
+		if m.output != "" && !strings.HasSuffix(m.output, "\n") {
+			m.output += "\n"
+		}
+		m.output += queued
+	}
+
+	return m
+}
+
+// backgroundOutput returns queued scheduled task output, finished job
+// notices and what goroutines of earlier Go blocks printed, styled for
+// display
+func (m model) backgroundOutput() string {
+	queued := append(m.state.Scheduler().DrainOutput(), m.state.Jobs().DrainNotifications()...)
+	if m.evaluator != nil {
+		if stray := strings.TrimSuffix(m.evaluator.DrainOutput(), "\n"); stray != "" {
+			queued = append(queued, stray)
+		}
+	}
+	if len(queued) == 0 {
+		return ""
+	}
+	return GetColorManager().StyleOutput(strings.Join(queued, "\n"), "info")
+}
+
+// openPicker shows a fuzzy picker over history, files or git branches,
+// using fzf when it's installed and the built-in picker otherwise
+func (m model) openPicker(kind PickerKind) (tea.Model, tea.Cmd) {
+	candidates := pickerCandidates(kind, m.session.History, m.state.WorkingDirectory)
+	if len(candidates) == 0 {
+		return m, nil
+	}
+
+	if fzfPath, found := FindInPath("fzf", m.state.Environment["PATH"]); found {
+		return m, runFzfPicker(fzfPath, kind, candidates)
+	}
+
+	m.picker = newFuzzyPicker(kind, candidates)
 	return m, nil
 }
 
+// applyPickerSelection puts a picked item into the command line: history
+// entries replace the input, files and branches are inserted at the cursor
+func (m model) applyPickerSelection(kind PickerKind, selection string) model {
+	if selection == "" {
+		return m
+	}
+
+	if kind == PickerHistory {
+		m.textarea.SetValue(selection)
+		m.textarea.CursorEnd()
+		return m
+	}
+
+	// fzf may return several lines with multi-select; join them like fzf's own bindings do
+	items := strings.Split(selection, "\n")
+	for i, item := range items {
+		if strings.ContainsAny(item, " \t'\"") {
+			items[i] = "'" + strings.ReplaceAll(item, "'", `'\''`) + "'"
+		}
+	}
+	m.textarea.InsertString(strings.Join(items, " "))
+	return m
+}
+
 func (m model) handleHistory(keyType tea.KeyType) (tea.Model, tea.Cmd) {
 	if len(m.session.History) == 0 {
 		return m, nil
@@ -134,18 +417,20 @@ func (m model) handleHistory(keyType tea.KeyType) (tea.Model, tea.Cmd) {
 	}
 
 	idx := len(m.session.History) - 1 - m.historyIdx
-	m.textarea.SetValue(m.session.History[idx].Input)
+	m.textarea.SetValue(historyInput(m.session.History[idx], m.session.Mode))
 	m.textarea.CursorEnd()
 
 	return m, nil
 }
 
-func (m model) executeBlock(input string) string {
+func (m model) executeBlock(input string) (string, int) {
 	var result ExecutionResult
 	var capturedVar string
+	sensitive := false
+	mode, input := blockMode(m.session.Mode, input)
 
 	// Check for -> capture syntax
-	if m.session.Mode == ModeShell {
+	if mode == ModeShell {
 		if idx := strings.Index(input, " -> "); idx != -1 {
 			capturedVar = strings.TrimSpace(input[idx+3:])
 			input = strings.TrimSpace(input[:idx])
@@ -153,48 +438,117 @@ func (m model) executeBlock(input string) string {
 	}
 
 	// Route and execute based on mode
-	if m.session.Mode == ModeGo {
+	if mode == ModeGo {
 		result = m.evaluator.EvalWithRecovery(input)
+		m.state.LastOutput = result.Output
 	} else {
-		// Shell mode - check for builtins first
-		router := NewRouter(m.builtins, &ShellState{WorkingDirectory: m.session.WorkingDir})
-		inputType, command, args := router.Route(input)
-
-		switch inputType {
-		case InputTypeBuiltin:
-			result = m.builtins.Execute(command, args)
-		case InputTypeCommand:
-			result = m.spawner.ExecuteInteractive(command, args)
-		default:
-			result = ExecutionResult{Output: fmt.Sprintf("Unknown command: %s\n", command), ExitCode: 1}
+		// Shell mode - run each command of the list, checking for builtins first
+		router := NewRouter(m.builtins, m.state)
+		command := ""
+
+		list, err := router.ParseCommandList(input)
+		if err != nil {
+			result = ExecutionResult{Output: fmt.Sprintf("gosh: %v", err), ExitCode: 2, Error: err}
+		} else {
+			// runIn returns the function that runs a command in state: the
+			// session's, or a subshell's copy of it
+			var runIn func(state *ShellState) func(ShellCommand) ExecutionResult
+			runIn = func(state *ShellState) func(ShellCommand) ExecutionResult {
+				builtins, spawner := m.builtins, m.spawner
+				if state != m.state {
+					builtins, spawner = m.builtins.withState(state), m.spawner.withState(state)
+				}
+				router := NewRouter(builtins, state)
+
+				return func(cmd ShellCommand) ExecutionResult {
+					if cmd.GoFunc != "" {
+						stream := cmd.Subshell == nil && router.Classify(cmd) != InputTypeBuiltin
+						return runGoPipe(m.evaluator, spawner, cmd, stream, runIn(state))
+					}
+					if cmd.Subshell != nil {
+						return runSubshell(state, cmd, runIn)
+					}
+
+					var result ExecutionResult
+					command = cmd.Name
+					started := time.Now()
+
+					inputType := router.Classify(cmd)
+					switch {
+					case inputType == InputTypeBuiltin && cmd.Background:
+						err := fmt.Errorf("%s: builtins can't run in the background", command)
+						result = ExecutionResult{Output: fmt.Sprintf("gosh: %v", err), ExitCode: 1, Error: err}
+					case inputType == InputTypeBuiltin && command == "eval":
+						// The line runs like any other, |> included
+						result = applyOutputRedirects(state, cmd.Redirects, evalLine(router, state, cmd.Args, runIn(state)))
+					case inputType == InputTypeBuiltin:
+						result = runBuiltin(builtins, state, cmd)
+					case inputType == InputTypeAutoCd:
+						result = builtins.Execute("cd", []string{command})
+					default:
+						result = spawner.Run(cmd)
+					}
+
+					m.state.UsageStats().Record(cmd.Name, time.Since(started), result.ExitCode)
+					if command == "vault" {
+						sensitive = true
+					}
+					return result
+				}
+			}
+			result = runCommandList(m.state, list, runIn(m.state))
+		}
+
+		// copy acts on the previous output, so it mustn't replace it, and
+		// vault output is a secret that must not be kept around
+		if sensitive {
+			m.state.LastOutput = ""
+		} else if command != "copy" {
+			m.state.LastOutput = result.Output
+		}
+	}
+
+	// The INT trap runs once the block Ctrl-C interrupted has stopped
+	if m.state.Traps().takeInterrupt() {
+		if output := m.builtins.runTrap("INT"); output != "" {
+			if result.Output != "" && !strings.HasSuffix(result.Output, "\n") {
+				result.Output += "\n"
+			}
+			result.Output += output
 		}
 	}
 
+	m.state.LastExitCode = result.ExitCode
+
 	// Handle captured output
 	if capturedVar != "" && result.ExitCode == 0 {
 		lines := strings.Split(strings.TrimSpace(result.Output), "\n")
 		m.session.CapturedVars[capturedVar] = lines
 		// Inject into Go interpreter
 		m.evaluator.InjectVariable(capturedVar, lines)
-		return ""
+		return "", result.ExitCode
 	}
 
-	// Add to history
-	block := HistoryBlock{
-		Mode:    m.session.Mode,
-		Input:   input,
-		Output:  result.Output,
-		Capture: capturedVar,
+	// Add to history (which is saved to disk, so never for secrets)
+	if !sensitive {
+		block := HistoryBlock{
+			Mode:     mode,
+			Input:    input,
+			Output:   result.Output,
+			Capture:  capturedVar,
+			ExitCode: result.ExitCode,
+		}
+		m.session.AddHistory(block)
+		m.state.AddHistory(block)
 	}
-	m.session.AddHistory(block)
 
 	// Return output with separator if needed
 	if result.Output != "" {
 		separator := strings.Repeat("─", m.width)
-		return fmt.Sprintf("%s\n%s\n%s\n", separator, result.Output, separator)
+		return fmt.Sprintf("%s\n%s\n%s\n", separator, result.Output, separator), result.ExitCode
 	}
 
-	return ""
+	return "", result.ExitCode
 }
 
 func (m model) View() string {
@@ -211,7 +565,22 @@ func (m model) View() string {
 		}
 	}
 
-	sb.WriteString(m.textarea.View())
+	sb.WriteString(m.marks)
+	if m.running != "" {
+		// Keep the command on screen while it runs, with the end of its
+		// output so far
+		sb.WriteString(m.textarea.Prompt + m.running)
+		if output := m.live.Tail(m.height - 2); output != "" {
+			sb.WriteString("\n" + output)
+		}
+	} else {
+		sb.WriteString(m.textarea.View())
+	}
+
+	if m.picker != nil {
+		sb.WriteString("\n")
+		sb.WriteString(m.picker.View())
+	}
 
 	return sb.String()
 }
@@ -220,6 +589,34 @@ var (
 	separatorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
 )
 
+// blockMode returns the mode input runs in and the input to run. A block
+// can run in the other mode without switching to it: :go CODE evaluates
+// CODE as Go, and :sh CMD, or !CMD in Go mode, runs CMD in the shell.
+func blockMode(mode BlockMode, input string) (BlockMode, string) {
+	switch {
+	case strings.HasPrefix(input, ":go ") || strings.HasPrefix(input, ":go\n"):
+		return ModeGo, strings.TrimSpace(input[len(":go"):])
+	case strings.HasPrefix(input, ":sh ") || strings.HasPrefix(input, ":sh\n"):
+		return ModeShell, strings.TrimSpace(input[len(":sh"):])
+	case mode == ModeGo && strings.HasPrefix(input, "!"):
+		return ModeShell, strings.TrimSpace(input[1:])
+	}
+	return mode, input
+}
+
+// historyInput returns the input that reruns block in mode, with the
+// prefix that runs it in its own mode if that's the other one
+func historyInput(block HistoryBlock, mode BlockMode) string {
+	switch {
+	case block.Mode == mode:
+		return block.Input
+	case block.Mode == ModeGo:
+		return ":go " + block.Input
+	default:
+		return ":sh " + block.Input
+	}
+}
+
 // isComplete checks if the input is syntactically complete (for multiline Go)
 func isComplete(input string) bool {
 	input = strings.TrimSpace(input)
@@ -3,7 +3,14 @@
 package main
 
 import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbletea"
 )
 
 func TestIsComplete(t *testing.T) {
@@ -130,3 +137,172 @@ func TestBlockModeConstants(t *testing.T) {
 		t.Errorf("ModeGo should be 1, got %v", ModeGo)
 	}
 }
+
+func TestModel_KeysWhileRunning(t *testing.T) {
+	state := &ShellState{WorkingDirectory: t.TempDir(), Environment: map[string]string{"PATH": os.Getenv("PATH")}}
+	m := model{state: state, running: "sleep 30", width: 80}
+
+	// Ctrl-C goes to the running command instead of quitting gosh
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	if updated.(model).quitting || cmd != nil {
+		t.Error("Ctrl-C mustn't quit while a command runs")
+	}
+
+	// Typing is ignored until the block finishes
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	if updated.(model).textarea.Value() != "" {
+		t.Error("Expected keys to be ignored while a command runs")
+	}
+}
+
+func TestBlockMode(t *testing.T) {
+	tests := []struct {
+		mode      BlockMode
+		input     string
+		wantMode  BlockMode
+		wantInput string
+	}{
+		{ModeShell, "ls -la", ModeShell, "ls -la"},
+		{ModeShell, ":go x := 1", ModeGo, "x := 1"},
+		{ModeGo, ":sh git status", ModeShell, "git status"},
+		{ModeGo, "!ls", ModeShell, "ls"},
+		{ModeShell, "! grep x", ModeShell, "! grep x"},
+		{ModeGo, ":go !ok", ModeGo, "!ok"},
+		{ModeShell, ":format", ModeShell, ":format"},
+	}
+	for _, tt := range tests {
+		if mode, input := blockMode(tt.mode, tt.input); mode != tt.wantMode || input != tt.wantInput {
+			t.Errorf("blockMode(%v, %q) = %v, %q, want %v, %q", tt.mode, tt.input, mode, input, tt.wantMode, tt.wantInput)
+		}
+	}
+
+	if got := historyInput(HistoryBlock{Mode: ModeGo, Input: "x := 1"}, ModeShell); got != ":go x := 1" {
+		t.Errorf("Expected a Go block recalled in shell mode to keep its prefix, got %q", got)
+	}
+}
+
+func TestModel_RunsBlockInOtherMode(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir, _ := filepath.EvalSymlinks(t.TempDir())
+	t.Chdir(dir)
+	state := &ShellState{WorkingDirectory: dir, Environment: map[string]string{"PATH": os.Getenv("PATH"), "HOME": dir}}
+	session := &SessionState{CapturedVars: map[string][]string{}, Mode: ModeGo, HistoryFile: filepath.Join(dir, "history")}
+	m := model{session: session, state: state, evaluator: NewGoEvaluator(), spawner: NewProcessSpawner(state), builtins: NewBuiltinHandler(state)}
+
+	if output, _ := m.executeBlock("!echo from the shell"); !strings.Contains(output, "from the shell") {
+		t.Errorf("Expected ! to run the shell command, got %q", output)
+	}
+	if session.Mode != ModeGo {
+		t.Error("Expected the session to stay in Go mode")
+	}
+	if last := session.History[len(session.History)-1]; last.Mode != ModeShell || last.Input != "echo from the shell" {
+		t.Errorf("Expected the block in history as a shell block, got %+v", last)
+	}
+}
+
+func TestGoDoc(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not available")
+	}
+	dir := t.TempDir()
+	state := &ShellState{WorkingDirectory: dir, Environment: map[string]string{"PATH": os.Getenv("PATH"), "HOME": os.Getenv("HOME")}}
+	for _, name := range []string{"GOROOT", "GOCACHE", "GOPATH", "GOMODCACHE"} {
+		if value, ok := os.LookupEnv(name); ok {
+			state.Environment[name] = value
+		}
+	}
+
+	if got := goDoc(state, []string{"strings.Split"}); !strings.Contains(got, "func Split(s, sep string) []string") {
+		t.Errorf("Expected the documentation of strings.Split, got %q", got)
+	}
+	if got := goDoc(state, []string{"strings.NoSuchThing"}); !strings.Contains(got, "no symbol") {
+		t.Errorf("Expected go doc's error for an unknown symbol, got %q", got)
+	}
+}
+
+func TestModel_FinishEdit(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir, _ := filepath.EvalSymlinks(t.TempDir())
+	t.Chdir(dir)
+	state := &ShellState{WorkingDirectory: dir, Environment: map[string]string{"PATH": os.Getenv("PATH"), "HOME": dir}}
+	session := &SessionState{CapturedVars: map[string][]string{}, Mode: ModeShell, HistoryFile: filepath.Join(dir, "history")}
+	m := model{session: session, state: state, evaluator: NewGoEvaluator(), spawner: NewProcessSpawner(state), builtins: NewBuiltinHandler(state), live: &liveOutput{}}
+
+	file := filepath.Join(dir, "edit.go")
+	os.WriteFile(file, []byte("func triple(n int) int {\n\treturn 3 * n\n}\n"), 0644)
+	updated, cmd := m.finishEdit(editFinishedMsg{file: file})
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Error("Expected the edited file to be removed")
+	}
+	if updated.(model).running == "" || cmd == nil {
+		t.Fatal("Expected the saved code to run")
+	}
+	// The block itself runs first, then the ticks showing its output
+	if msg, ok := cmd().(tea.BatchMsg)[0]().(blockFinishedMsg); !ok || msg.exitCode != 0 {
+		t.Fatalf("Expected the block to finish, got %+v", msg)
+	}
+	// As Go, though the shell is in shell mode
+	if result := m.evaluator.Eval("triple(3)"); result.Output != "9" {
+		t.Errorf("Expected the edited function to be defined, got %q", result.Output)
+	}
+	if last := session.History[len(session.History)-1]; last.Mode != ModeGo {
+		t.Errorf("Expected the edited code in history as a Go block, got %+v", last)
+	}
+
+	// A file left empty runs nothing
+	os.WriteFile(file, []byte("\n"), 0644)
+	if _, cmd := m.finishEdit(editFinishedMsg{file: file}); cmd != nil {
+		t.Error("Expected an empty file not to run")
+	}
+}
+
+func TestModel_AgainAndFix(t *testing.T) {
+	session := &SessionState{Mode: ModeShell, History: []HistoryBlock{
+		{Mode: ModeGo, Input: "x := strconv.Itoa(nope)", ExitCode: 1},
+		{Mode: ModeGo, Input: "n := 1"},
+		{Mode: ModeShell, Input: "false", ExitCode: 1},
+	}}
+	m := model{session: session, textarea: textarea.New()}
+
+	if block, ok := lastGoBlock(session.History, false); !ok || block.Input != "n := 1" {
+		t.Errorf("Expected the last Go block, got %+v", block)
+	}
+
+	// The failed block comes back with the prefix that runs it as Go
+	if got := m.fixLast().textarea.Value(); got != ":go x := strconv.Itoa(nope)" {
+		t.Errorf("Expected :fix to put the failed Go block in the input, got %q", got)
+	}
+
+	session.History = []HistoryBlock{{Mode: ModeGo, Input: "n := 1"}}
+	if got := m.fixLast().output; got != ":fix: no failed Go block" {
+		t.Errorf("Expected :fix to say there's nothing to fix, got %q", got)
+	}
+}
+
+func TestModel_Prompt(t *testing.T) {
+	dir := t.TempDir()
+	state := &ShellState{WorkingDirectory: dir, Environment: map[string]string{"HOME": dir, "KUBECONFIG": writeTestKubeconfig(t)}}
+	session := &SessionState{CapturedVars: map[string][]string{}, Mode: ModeShell}
+	m := initialModel(session, state, NewGoEvaluator(), NewProcessSpawner(state), NewBuiltinHandler(state))
+
+	// The shell-mode prompt is the full one, with the kube context
+	if prompt := m.prompt(); !strings.Contains(prompt, "k8s:") || !strings.Contains(prompt, "dev/apps") {
+		t.Errorf("Expected the kube context in the prompt, got %q", prompt)
+	}
+	if m.textarea.Prompt != m.prompt() {
+		t.Errorf("Expected the textarea to show the prompt, got %q", m.textarea.Prompt)
+	}
+
+	// Switching context shows once the block that did it finishes
+	state.Environment["GOSH_KUBE_PROMPT"] = "0"
+	m = m.finishBlock(blockFinishedMsg{})
+	if prompt := m.prompt(); strings.Contains(prompt, "k8s:") {
+		t.Errorf("Expected the kube context to be hidden, got %q", prompt)
+	}
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(":go")})
+	updated, _ = updated.(model).Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if prompt := updated.(model).textarea.Prompt; prompt != "go> " {
+		t.Errorf("Expected the Go prompt, got %q", prompt)
+	}
+}
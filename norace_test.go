@@ -0,0 +1,6 @@
+//go:build (darwin || linux) && !race
+
+package main
+
+// raceEnabled is set when the tests are built with -race.
+const raceEnabled = false
@@ -0,0 +1,267 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Default quiet period before rerunning, so a save that touches several
+// files (or an editor's write-rename dance) triggers a single run
+const onchangeDefaultDebounce = 300 * time.Millisecond
+
+// Directories never worth watching
+var onchangeSkipDirs = map[string]bool{".git": true, "node_modules": true, "vendor": true}
+
+// onchangeOptions are the parsed onchange arguments
+type onchangeOptions struct {
+	pattern  string
+	command  []string
+	debounce time.Duration
+	initial  bool
+	clear    bool
+}
+
+func parseOnchangeArgs(args []string) (onchangeOptions, error) {
+	opts := onchangeOptions{debounce: onchangeDefaultDebounce}
+
+	i := 0
+flags:
+	for ; i < len(args) && strings.HasPrefix(args[i], "-"); i++ {
+		switch args[i] {
+		case "-i", "--initial":
+			opts.initial = true
+		case "-c", "--clear":
+			opts.clear = true
+		case "-d", "--debounce":
+			if i+1 >= len(args) {
+				return opts, fmt.Errorf("-d needs a duration")
+			}
+			i++
+			d, err := time.ParseDuration(args[i])
+			if err != nil {
+				return opts, fmt.Errorf("invalid debounce %q", args[i])
+			}
+			opts.debounce = d
+		case "--":
+			i++
+			break flags
+		default:
+			return opts, fmt.Errorf("unknown option: %s", args[i])
+		}
+	}
+
+	if len(args)-i < 2 {
+		return opts, fmt.Errorf("usage: onchange [-i] [-c] [-d DELAY] PATTERN COMMAND...")
+	}
+	opts.pattern = strings.TrimPrefix(args[i], "./")
+	opts.command = args[i+1:]
+	return opts, nil
+}
+
+// matchGlob matches a slash-separated relative path against a pattern in
+// which ** matches any number of directories
+func matchGlob(pattern, path string) bool {
+	return matchGlobParts(strings.Split(pattern, "/"), strings.Split(path, "/"))
+}
+
+func matchGlobParts(pattern, path []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			for i := 0; i <= len(path); i++ {
+				if matchGlobParts(rest, path[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(path) == 0 {
+			return false
+		}
+		if ok, err := filepath.Match(pattern[0], path[0]); err != nil || !ok {
+			return false
+		}
+		pattern, path = pattern[1:], path[1:]
+	}
+	return len(path) == 0
+}
+
+// globRoot returns the directory part of pattern before any wildcard
+func globRoot(pattern string) string {
+	var dirs []string
+	for _, part := range strings.Split(pattern, "/") {
+		if strings.ContainsAny(part, "*?[") {
+			break
+		}
+		dirs = append(dirs, part)
+	}
+	if len(dirs) == len(strings.Split(pattern, "/")) {
+		// No wildcard: watch the file's directory
+		dirs = dirs[:len(dirs)-1]
+	}
+	if len(dirs) == 0 {
+		return "."
+	}
+	return filepath.Join(dirs...)
+}
+
+// watchTree adds root and every directory below it to watcher
+func watchTree(watcher *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if path != root && onchangeSkipDirs[d.Name()] {
+			return filepath.SkipDir
+		}
+		return watcher.Add(path)
+	})
+}
+
+// onchange implements the onchange builtin
+func (b *BuiltinHandler) onchange(args []string) ExecutionResult {
+	opts, err := parseOnchangeArgs(args)
+	if err != nil {
+		return ExecutionResult{Output: fmt.Sprintf("onchange: %v", err), ExitCode: 1, Error: err}
+	}
+
+	baseDir := b.state.WorkingDirectory
+	root := filepath.Join(baseDir, globRoot(opts.pattern))
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return ExecutionResult{Output: fmt.Sprintf("onchange: %v", err), ExitCode: 1, Error: err}
+	}
+	defer watcher.Close()
+
+	if err := watchTree(watcher, root); err != nil {
+		return ExecutionResult{Output: fmt.Sprintf("onchange: %v", err), ExitCode: 1, Error: err}
+	}
+
+	runs := 0
+	err = withTerminal(func() error {
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		defer signal.Stop(interrupt)
+
+		colors := GetColorManager()
+		fmt.Println(colors.StyleOutput(fmt.Sprintf("Watching %s - press Ctrl-C to stop", opts.pattern), "info"))
+
+		run := func(changed string) {
+			runs++
+			if opts.clear {
+				fmt.Print("\033[H\033[2J")
+			}
+			label := strings.Join(opts.command, " ")
+			if changed != "" {
+				label = fmt.Sprintf("%s (%s changed)", label, changed)
+			}
+			fmt.Println(separatorStyle.Render(fmt.Sprintf("[%s] %s", time.Now().Format("15:04:05"), label)))
+			b.runOnchangeCommand(opts.command)
+		}
+
+		if opts.initial {
+			run("")
+		}
+
+		var timer <-chan time.Time
+		var changed string
+		for {
+			select {
+			case <-interrupt:
+				return nil
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return nil
+				}
+				// Watch directories created after we started
+				if event.Has(fsnotify.Create) {
+					if info, err := os.Stat(event.Name); err == nil && info.IsDir() && !onchangeSkipDirs[info.Name()] {
+						watchTree(watcher, event.Name)
+					}
+				}
+				rel, err := filepath.Rel(baseDir, event.Name)
+				if err != nil || !matchGlob(opts.pattern, filepath.ToSlash(rel)) {
+					continue
+				}
+				changed = rel
+				timer = time.After(opts.debounce)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return nil
+				}
+				fmt.Fprintf(os.Stderr, "onchange: %v\n", err)
+			case <-timer:
+				timer = nil
+				run(changed)
+			}
+		}
+	})
+	if err != nil {
+		return ExecutionResult{Output: fmt.Sprintf("onchange: %v", err), ExitCode: 1, Error: err}
+	}
+
+	return ExecutionResult{Output: fmt.Sprintf("onchange: stopped after %d run(s)", runs), ExitCode: 0}
+}
+
+// runOnchangeCommand runs a config function (`name` or `name()`), a builtin
+// or an external command, printing its output
+func (b *BuiltinHandler) runOnchangeCommand(command []string) {
+	name := strings.TrimSuffix(command[0], "()")
+	if b.evaluator != nil && len(command) == 1 {
+		if _, ok := b.evaluator.configFuncs[name]; ok {
+			if result := b.evaluator.Eval(name + "()"); result.Output != "" {
+				fmt.Println(result.Output)
+			}
+			return
+		}
+	}
+
+	if b.IsBuiltin(command[0]) {
+		if result := b.Execute(command[0], command[1:]); result.Output != "" {
+			fmt.Println(result.Output)
+		}
+		return
+	}
+
+	path, found := FindInPath(command[0], b.state.Environment["PATH"])
+	if !found {
+		fmt.Fprintf(os.Stderr, "onchange: command not found: %s\n", command[0])
+		return
+	}
+
+	cmd := exec.Command(path, command[1:]...)
+	cmd.Dir = b.state.WorkingDirectory
+	cmd.Env = b.state.EnvironmentSlice()
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		fmt.Println(GetColorManager().StyleOutput(fmt.Sprintf("onchange: %v", err), "error"))
+	}
+}
+
+const onchangeHelpText = "onchange - Rerun a Command When Files Change\n\n" +
+	"USAGE:\n" +
+	"    onchange [-i] [-c] [-d DELAY] PATTERN COMMAND...\n\n" +
+	"DESCRIPTION:\n" +
+	"    Watch files matching PATTERN and rerun COMMAND whenever one changes.\n" +
+	"    ** matches any number of directories. COMMAND may be an external\n" +
+	"    command, a builtin, or the name of a function from config.go.\n" +
+	"    Press Ctrl-C to stop watching.\n\n" +
+	"OPTIONS:\n" +
+	"    -i, --initial       Run once before waiting for changes\n" +
+	"    -c, --clear         Clear the screen before each run\n" +
+	"    -d, --debounce      Quiet period before rerunning (default 300ms)\n\n" +
+	"EXAMPLES:\n" +
+	"    onchange './**/*.go' go test ./...\n" +
+	"    onchange -i 'docs/**/*.md' build_docs"
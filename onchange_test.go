@@ -0,0 +1,73 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "cmd/gosh/main.go", true},
+		{"**/*.go", "README.md", false},
+		{"*.go", "cmd/main.go", false},
+		{"docs/**/*.md", "docs/a/b/page.md", true},
+		{"docs/**/*.md", "src/page.md", false},
+		{"go.mod", "go.mod", true},
+	}
+
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestGlobRoot(t *testing.T) {
+	tests := map[string]string{
+		"**/*.go":        ".",
+		"docs/**/*.md":   "docs",
+		"src/app/*.ts":   "src/app",
+		"config/app.yml": "config",
+		"go.mod":         ".",
+	}
+
+	for pattern, want := range tests {
+		if got := globRoot(pattern); got != want {
+			t.Errorf("globRoot(%q) = %q, want %q", pattern, got, want)
+		}
+	}
+}
+
+func TestParseOnchangeArgs(t *testing.T) {
+	opts, err := parseOnchangeArgs([]string{"-i", "-d", "1s", "./**/*.go", "go", "test", "./..."})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !opts.initial || opts.debounce != time.Second {
+		t.Errorf("Expected -i and -d to be parsed, got %+v", opts)
+	}
+	if opts.pattern != "**/*.go" || strings.Join(opts.command, " ") != "go test ./..." {
+		t.Errorf("Unexpected pattern/command: %q %q", opts.pattern, opts.command)
+	}
+
+	// Flags after the pattern belong to the command
+	opts, _ = parseOnchangeArgs([]string{"*.go", "go", "test", "-v"})
+	if strings.Join(opts.command, " ") != "go test -v" {
+		t.Errorf("Expected command flags to be kept, got %q", opts.command)
+	}
+
+	if _, err := parseOnchangeArgs([]string{"*.go"}); err == nil {
+		t.Error("Expected an error without a command")
+	}
+	if _, err := parseOnchangeArgs([]string{"-x", "*.go", "ls"}); err == nil {
+		t.Error("Expected an error for an unknown option")
+	}
+}
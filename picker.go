@@ -0,0 +1,305 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// PickerKind identifies what a fuzzy picker is choosing from
+type PickerKind int
+
+const (
+	PickerHistory PickerKind = iota
+	PickerFiles
+	PickerBranches
+)
+
+// Limits keep the file walk responsive in huge trees
+const (
+	pickerMaxFiles   = 5000
+	pickerMaxResults = 10
+)
+
+func (k PickerKind) title() string {
+	switch k {
+	case PickerHistory:
+		return "history"
+	case PickerFiles:
+		return "files"
+	case PickerBranches:
+		return "branches"
+	default:
+		return "pick"
+	}
+}
+
+// pickerResultMsg carries the selection back into the model
+type pickerResultMsg struct {
+	kind      PickerKind
+	selection string
+}
+
+// pickerCandidates collects the items for a picker, most relevant first
+func pickerCandidates(kind PickerKind, history []HistoryBlock, dir string) []string {
+	switch kind {
+	case PickerHistory:
+		seen := make(map[string]bool)
+		var items []string
+		for i := len(history) - 1; i >= 0; i-- {
+			input := history[i].Input
+			if input == "" || seen[input] {
+				continue
+			}
+			seen[input] = true
+			items = append(items, input)
+		}
+		return items
+	case PickerFiles:
+		return listPickerFiles(dir)
+	case PickerBranches:
+		cmd := exec.Command("git", "for-each-ref", "--format=%(refname:short)", "refs/heads", "refs/remotes")
+		cmd.Dir = dir
+		output, err := cmd.Output()
+		if err != nil {
+			return nil
+		}
+		var items []string
+		for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+			if line != "" && !strings.HasSuffix(line, "/HEAD") {
+				items = append(items, line)
+			}
+		}
+		return items
+	}
+	return nil
+}
+
+// listPickerFiles walks dir for files, skipping VCS and dependency directories
+func listPickerFiles(dir string) []string {
+	var files []string
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != dir && (name == ".git" || name == "node_modules" || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return nil
+		}
+		files = append(files, rel)
+		if len(files) >= pickerMaxFiles {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return files
+}
+
+// fzfCommand feeds candidates to fzf through tea.Exec. fzf draws its UI on
+// the terminal itself, so stdin/stdout stay wired to our candidate list and
+// selection buffer; only stderr is handed over to bubbletea's terminal.
+type fzfCommand struct {
+	path       string
+	candidates []string
+	stderr     io.Writer
+	selection  string
+}
+
+func (f *fzfCommand) Run() error {
+	cmd := exec.Command(f.path, "--height", "40%", "--reverse")
+	cmd.Stdin = strings.NewReader(strings.Join(f.candidates, "\n"))
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = f.stderr
+	if cmd.Stderr == nil {
+		cmd.Stderr = os.Stderr
+	}
+
+	err := cmd.Run()
+	f.selection = strings.TrimRight(out.String(), "\n")
+	if exitErr, ok := err.(*exec.ExitError); ok && (exitErr.ExitCode() == 1 || exitErr.ExitCode() == 130) {
+		// No match or aborted with Esc/Ctrl-C
+		return nil
+	}
+	return err
+}
+
+func (f *fzfCommand) SetStdin(io.Reader)    {}
+func (f *fzfCommand) SetStdout(io.Writer)   {}
+func (f *fzfCommand) SetStderr(w io.Writer) { f.stderr = w }
+
+// runFzfPicker hands the terminal to fzf and returns the selection as a message
+func runFzfPicker(fzfPath string, kind PickerKind, candidates []string) tea.Cmd {
+	fzf := &fzfCommand{path: fzfPath, candidates: candidates}
+	return tea.Exec(fzf, func(err error) tea.Msg {
+		if err != nil {
+			return pickerResultMsg{kind: kind}
+		}
+		return pickerResultMsg{kind: kind, selection: fzf.selection}
+	})
+}
+
+// fuzzyPicker is the pure-Go fallback used when fzf isn't installed
+type fuzzyPicker struct {
+	kind    PickerKind
+	items   []string
+	query   string
+	matches []string
+	cursor  int
+}
+
+func newFuzzyPicker(kind PickerKind, items []string) *fuzzyPicker {
+	p := &fuzzyPicker{kind: kind, items: items}
+	p.filter()
+	return p
+}
+
+// filter recomputes matches for the current query, best scores first
+func (p *fuzzyPicker) filter() {
+	type scored struct {
+		item  string
+		score int
+		index int
+	}
+
+	var results []scored
+	for i, item := range p.items {
+		if score, ok := fuzzyScore(item, p.query); ok {
+			results = append(results, scored{item: item, score: score, index: i})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].score == results[j].score {
+			return results[i].index < results[j].index
+		}
+		return results[i].score > results[j].score
+	})
+
+	p.matches = p.matches[:0]
+	for _, r := range results {
+		p.matches = append(p.matches, r.item)
+	}
+	if p.cursor >= len(p.matches) {
+		p.cursor = 0
+	}
+}
+
+// Update handles a key press. It returns done=true once the picker should
+// close, with the selection (empty when cancelled).
+func (p *fuzzyPicker) Update(msg tea.KeyMsg) (selection string, done bool) {
+	switch msg.Type {
+	case tea.KeyEsc, tea.KeyCtrlC, tea.KeyCtrlG:
+		return "", true
+	case tea.KeyEnter:
+		if len(p.matches) == 0 {
+			return "", true
+		}
+		return p.matches[p.cursor], true
+	case tea.KeyUp, tea.KeyCtrlP:
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case tea.KeyDown, tea.KeyCtrlN:
+		if p.cursor < len(p.matches)-1 {
+			p.cursor++
+		}
+	case tea.KeyBackspace:
+		if len(p.query) > 0 {
+			runes := []rune(p.query)
+			p.query = string(runes[:len(runes)-1])
+			p.filter()
+		}
+	case tea.KeySpace:
+		p.query += " "
+		p.filter()
+	case tea.KeyRunes:
+		p.query += string(msg.Runes)
+		p.filter()
+	}
+	return "", false
+}
+
+var pickerCursorStyle = lipgloss.NewStyle().Bold(true).Reverse(true)
+
+// View renders the query line and the visible window of matches
+func (p *fuzzyPicker) View() string {
+	var sb strings.Builder
+	sb.WriteString(p.kind.title() + "> " + p.query + "\n")
+
+	start := 0
+	if p.cursor >= pickerMaxResults {
+		start = p.cursor - pickerMaxResults + 1
+	}
+	end := start + pickerMaxResults
+	if end > len(p.matches) {
+		end = len(p.matches)
+	}
+
+	for i := start; i < end; i++ {
+		line := strings.ReplaceAll(p.matches[i], "\n", "⏎")
+		if i == p.cursor {
+			sb.WriteString(pickerCursorStyle.Render("> "+line) + "\n")
+		} else {
+			sb.WriteString("  " + line + "\n")
+		}
+	}
+
+	sb.WriteString(separatorStyle.Render(fmt.Sprintf("  %d/%d  (enter: select, esc: cancel)", len(p.matches), len(p.items))))
+	return sb.String()
+}
+
+// fuzzyScore reports whether all query runes appear in order within
+// candidate (case-insensitive) and scores consecutive and word-start hits
+func fuzzyScore(candidate, query string) (int, bool) {
+	if query == "" {
+		return 0, true
+	}
+
+	c := []rune(strings.ToLower(candidate))
+	q := []rune(strings.ToLower(query))
+
+	score := 0
+	qi := 0
+	prevMatch := -2
+	for ci := 0; ci < len(c) && qi < len(q); ci++ {
+		if c[ci] != q[qi] {
+			continue
+		}
+		score++
+		if ci == prevMatch+1 {
+			score += 3
+		}
+		if ci == 0 || (!unicode.IsLetter(c[ci-1]) && !unicode.IsDigit(c[ci-1])) {
+			score += 2
+		}
+		prevMatch = ci
+		qi++
+	}
+
+	if qi < len(q) {
+		return 0, false
+	}
+
+	// Prefer shorter candidates for equal matches
+	return score*100 - len(c), true
+}
@@ -0,0 +1,81 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/bubbletea"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		candidate string
+		query     string
+		matches   bool
+	}{
+		{"git status", "gst", true},
+		{"git status", "", true},
+		{"go test ./...", "gtt", true},
+		{"make build", "xyz", false},
+		{"README.md", "readme", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.candidate+"/"+tt.query, func(t *testing.T) {
+			_, ok := fuzzyScore(tt.candidate, tt.query)
+			if ok != tt.matches {
+				t.Errorf("fuzzyScore(%q, %q) matched = %v, expected %v", tt.candidate, tt.query, ok, tt.matches)
+			}
+		})
+	}
+
+	consecutive, _ := fuzzyScore("status", "sta")
+	scattered, _ := fuzzyScore("s_t_a_tus", "sta")
+	if consecutive <= scattered {
+		t.Errorf("Consecutive matches should score higher (%d <= %d)", consecutive, scattered)
+	}
+}
+
+func TestFuzzyPicker_FilterAndSelect(t *testing.T) {
+	p := newFuzzyPicker(PickerHistory, []string{"git status", "go build", "git push"})
+
+	for _, r := range "gp" {
+		p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+
+	if len(p.matches) != 1 || p.matches[0] != "git push" {
+		t.Fatalf("Expected only 'git push' to match, got %v", p.matches)
+	}
+
+	selection, done := p.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !done || selection != "git push" {
+		t.Errorf("Expected to select 'git push', got %q (done=%v)", selection, done)
+	}
+
+	_, done = newFuzzyPicker(PickerFiles, []string{"a"}).Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if !done {
+		t.Error("Esc should close the picker")
+	}
+}
+
+func TestPickerCandidates(t *testing.T) {
+	history := []HistoryBlock{{Input: "ls"}, {Input: "pwd"}, {Input: "ls"}}
+	items := pickerCandidates(PickerHistory, history, "")
+	if len(items) != 2 || items[0] != "ls" || items[1] != "pwd" {
+		t.Errorf("Expected deduplicated, most recent first history, got %v", items)
+	}
+
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "src"), 0755)
+	os.MkdirAll(filepath.Join(dir, ".git"), 0755)
+	os.WriteFile(filepath.Join(dir, "src", "main.go"), []byte("package main"), 0644)
+	os.WriteFile(filepath.Join(dir, ".git", "HEAD"), []byte("ref"), 0644)
+
+	files := pickerCandidates(PickerFiles, nil, dir)
+	if len(files) != 1 || files[0] != filepath.Join("src", "main.go") {
+		t.Errorf("Expected only src/main.go, got %v", files)
+	}
+}
@@ -0,0 +1,191 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// isGoFuncName reports whether name can follow |>: a function name,
+// optionally qualified by its package, as in strings.ToUpper
+func isGoFuncName(name string) bool {
+	pkg, fn, qualified := strings.Cut(name, ".")
+	if qualified {
+		return isGoIdentifier(pkg) && isGoIdentifier(fn)
+	}
+	return isGoIdentifier(name)
+}
+
+func isGoIdentifier(name string) bool {
+	if name == "" || !isNameStart(name[0]) {
+		return false
+	}
+	for i := 1; i < len(name); i++ {
+		if !isNameChar(name[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+var readerType = reflect.TypeOf((*io.Reader)(nil)).Elem()
+
+// PipeToFunc calls the Go function name, defined in the session or config,
+// with a command's output. A func(io.Reader) reads the output as it's
+// written; a func(string) gets all of it and a func([]string) its lines.
+// What the function prints and returns is the result; a non-nil error as
+// its last result fails it.
+func (g *GoEvaluator) PipeToFunc(name string, input io.Reader) ExecutionResult {
+	g.evalMu.Lock()
+	defer g.evalMu.Unlock()
+
+	fail := func(err error) ExecutionResult {
+		err = fmt.Errorf("|> %s: %w", name, err)
+		return ExecutionResult{Output: fmt.Sprintf("gosh: %v", err), ExitCode: 1, Error: err}
+	}
+
+	fn, err := g.interp.Eval(name)
+	if err != nil {
+		return fail(fmt.Errorf("no such function"))
+	}
+	fn = unwrapInterface(fn)
+	if fn.Kind() != reflect.Func || fn.Type().NumIn() != 1 {
+		return fail(fmt.Errorf("want a func(io.Reader), func(string) or func([]string)"))
+	}
+
+	var arg reflect.Value
+	switch param := fn.Type().In(0); {
+	case param == readerType:
+		arg = reflect.ValueOf(&input).Elem()
+	case param.Kind() == reflect.String:
+		data, err := io.ReadAll(input)
+		if err != nil {
+			return fail(err)
+		}
+		arg = reflect.ValueOf(string(data)).Convert(param)
+	case param.Kind() == reflect.Slice && param.Elem().Kind() == reflect.String:
+		data, err := io.ReadAll(input)
+		if err != nil {
+			return fail(err)
+		}
+		lines := []string{}
+		if text := strings.TrimSuffix(string(data), "\n"); text != "" {
+			lines = strings.Split(text, "\n")
+		}
+		arg = reflect.ValueOf(lines).Convert(param)
+	default:
+		return fail(fmt.Errorf("can't pass a command's output as %s", param))
+	}
+
+	var results []reflect.Value
+	var panicErr error
+	printed := g.output.capture(nil, func() {
+		defer func() {
+			if r := recover(); r != nil {
+				panicErr = fmt.Errorf("panic: %v", r)
+			}
+		}()
+		results = fn.Call([]reflect.Value{arg})
+	})
+	if panicErr != nil {
+		return fail(panicErr)
+	}
+	return g.funcResult(printed, results, fail)
+}
+
+// funcResult is the result of a Go function run as part of a command
+// line, which printed printed and returned results: what it printed and
+// returned, or fail's result for the error it returned last
+func (g *GoEvaluator) funcResult(printed string, results []reflect.Value, fail func(error) ExecutionResult) ExecutionResult {
+	output := strings.TrimSuffix(printed, "\n")
+	if n := len(results); n > 0 && results[n-1].Type() == errorType {
+		if !results[n-1].IsNil() {
+			result := fail(results[n-1].Interface().(error))
+			if output != "" {
+				result.Output = output + "\n" + result.Output
+			}
+			return result
+		}
+		results = results[:n-1]
+	}
+	if len(results) > 0 {
+		if value := unwrapInterface(results[0]); value.IsValid() {
+			if output != "" {
+				output += "\n"
+			}
+			output += g.formatValue(value)
+		}
+	}
+	return ExecutionResult{Output: output, ExitCode: 0}
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// unwrapInterface returns the value held by an interface, or by the
+// *interface{} yaegi returns for some expressions
+func unwrapInterface(v reflect.Value) reflect.Value {
+	if v.Kind() == reflect.Ptr && v.Type().Elem().Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// runGoPipe runs cmd |> fn. The output of an external command streams into
+// the function while it runs; that of builtins and subshells, which don't
+// stream, is passed once they finish. run runs the command without its |>.
+func runGoPipe(evaluator *GoEvaluator, spawner *ProcessSpawner, cmd ShellCommand, stream bool, run func(ShellCommand) ExecutionResult) ExecutionResult {
+	fn := cmd.GoFunc
+	cmd.GoFunc = ""
+	var err error
+	switch {
+	case evaluator == nil:
+		err = fmt.Errorf("|> needs the Go interpreter")
+	case cmd.Background:
+		err = fmt.Errorf("|> can't run in the background")
+	}
+	if err != nil {
+		return ExecutionResult{Output: fmt.Sprintf("gosh: %v", err), ExitCode: 1, Error: err}
+	}
+
+	if !stream {
+		result := run(cmd)
+		if result.ExitCode != 0 {
+			return result
+		}
+		return evaluator.PipeToFunc(fn, strings.NewReader(result.Output))
+	}
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return ExecutionResult{Output: fmt.Sprintf("gosh: %v", err), ExitCode: 1, Error: err}
+	}
+	finished := make(chan ExecutionResult, 1)
+	go func() {
+		finished <- spawner.RunInto(cmd, writer)
+	}()
+
+	piped := evaluator.PipeToFunc(fn, reader)
+	// A function that stops reading early ends the command, as a closed
+	// pipe does in other shells
+	reader.Close()
+	result := <-finished
+
+	// What the command wrote to stderr comes first
+	if result.Output != "" {
+		if piped.Output != "" && !strings.HasSuffix(result.Output, "\n") {
+			result.Output += "\n"
+		}
+		piped.Output = result.Output + piped.Output
+	}
+	return piped
+}
@@ -0,0 +1,121 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseCommandList_GoPipe(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"ls -l |> CountLines", "ls -l |> CountLines"},
+		{"git log --oneline|>strings.ToUpper", "git log --oneline |> strings.ToUpper"},
+		{"(cd /tmp && ls) |> Sort()", "(cd /tmp && ls) |> Sort"},
+		{"echo '|>' x", "echo '|>' x"},
+	}
+
+	for _, tt := range tests {
+		list, err := parseCommandList(tt.input)
+		if err != nil {
+			t.Errorf("parseCommandList(%q) error: %v", tt.input, err)
+			continue
+		}
+		if got := list[0].String(); got != tt.want {
+			t.Errorf("parseCommandList(%q)[0] = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	for _, input := range []string{"|> Count", "ls |>", "ls |> 'bad name'", "ls |> Count extra", "ls |> 1x"} {
+		if _, err := parseCommandList(input); err == nil {
+			t.Errorf("Expected a syntax error for %q", input)
+		}
+	}
+}
+
+func TestPipeToFunc(t *testing.T) {
+	evaluator := NewGoEvaluator()
+	setup := `import ("bufio"; "errors"; "fmt"; "io")
+func CountLines(r io.Reader) int {
+	n := 0
+	for s := bufio.NewScanner(r); s.Scan(); n++ {
+	}
+	return n
+}
+func Shout(s string) string { return strings.ToUpper(strings.TrimSpace(s)) }
+func Last(lines []string) { fmt.Println("last:", lines[len(lines)-1]) }
+func Fails(s string) (int, error) { return 0, errors.New("no good") }
+func Broken(lines []string) string { return lines[99] }
+func TwoArgs(a, b string) {}`
+	if result := evaluator.Eval(setup); result.Error != nil {
+		t.Fatalf("Eval setup error: %v", result.Error)
+	}
+
+	tests := []struct {
+		fn       string
+		output   string
+		exitCode int
+	}{
+		{"CountLines", "3", 0},
+		{"Shout", "A\nB\nC", 0},
+		{"Last", "last: c", 0},
+		{"strings.TrimSpace", "a\nb\nc", 0},
+		{"Fails", "gosh: |> Fails: no good", 1},
+		{"Broken", "gosh: |> Broken: panic:", 1},
+		{"TwoArgs", "gosh: |> TwoArgs: want", 1},
+		{"Missing", "gosh: |> Missing: no such function", 1},
+	}
+
+	for _, tt := range tests {
+		result := evaluator.PipeToFunc(tt.fn, strings.NewReader("a\nb\nc\n"))
+		if !strings.HasPrefix(result.Output, tt.output) || result.ExitCode != tt.exitCode {
+			t.Errorf("PipeToFunc(%s) = %q (exit %d), want %q (exit %d)", tt.fn, result.Output, result.ExitCode, tt.output, tt.exitCode)
+		}
+	}
+}
+
+func TestRunGoPipe(t *testing.T) {
+	evaluator := NewGoEvaluator()
+	setup := `import ("bufio"; "io")
+func First(r io.Reader) string {
+	s := bufio.NewScanner(r)
+	s.Scan()
+	return s.Text()
+}
+func Count(lines []string) int { return len(lines) }`
+	if result := evaluator.Eval(setup); result.Error != nil {
+		t.Fatalf("Eval setup error: %v", result.Error)
+	}
+
+	state := &ShellState{WorkingDirectory: t.TempDir(), Environment: map[string]string{"PATH": os.Getenv("PATH")}}
+	spawner := NewProcessSpawner(state)
+	run := func(input string, stream bool) ExecutionResult {
+		list, err := parseCommandList(input)
+		if err != nil {
+			t.Fatalf("parseCommandList(%q) error: %v", input, err)
+		}
+		return runGoPipe(evaluator, spawner, list[0].ShellCommand, stream, spawner.Run)
+	}
+
+	if result := run("printf 'a\\nb\\nc\\n' |> Count", true); result.Output != "3" || result.ExitCode != 0 {
+		t.Errorf("Expected 3 lines, got %q (exit %d)", result.Output, result.ExitCode)
+	}
+	// The command is ended once the function stops reading
+	if result := run("yes |> First", true); result.Output != "y" {
+		t.Errorf("Expected the first line of yes, got %q", result.Output)
+	}
+	if result := run("sh -c 'echo oops >&2; echo out' |> Count", true); result.Output != "oops\n1" {
+		t.Errorf("Expected stderr ahead of the result, got %q", result.Output)
+	}
+	if result := run("(echo x; echo y) |> Count", false); result.Output != "2" {
+		t.Errorf("Expected a subshell's output to be piped, got %q", result.Output)
+	}
+	background := ShellCommand{Name: "sleep", Args: []string{"1"}, Background: true, GoFunc: "Count"}
+	if result := runGoPipe(evaluator, spawner, background, true, spawner.Run); result.ExitCode != 1 {
+		t.Errorf("Expected a background pipe to be refused, got %q", result.Output)
+	}
+}
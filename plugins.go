@@ -0,0 +1,123 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Plugins are Go files at ~/.config/gosh/plugins/NAME/plugin.go, loaded
+// after the home config as it is. They extend the shell through the gosh
+// package: gosh.RegisterCommand for commands, gosh.RegisterCompletion for
+// the arguments of a command and gosh.PromptSegment for the prompt.
+
+// promptSegment is a part of the prompt a plugin adds, shown after the
+// built-in segments when fn returns something
+type promptSegment struct {
+	name string
+	fn   func() string
+}
+
+// pluginPaths returns the plugin.go files of the plugins in dir, in the
+// order of their names
+func pluginPaths(dir string) []string {
+	paths, _ := filepath.Glob(filepath.Join(dir, "*", "plugin.go"))
+	sort.Strings(paths)
+	return paths
+}
+
+// loadPlugins loads each plugin in the plugins directory. One that fails
+// doesn't keep the others from loading; their errors are returned together.
+func (g *GoEvaluator) loadPlugins() error {
+	var errs []error
+	for _, path := range pluginPaths(goshConfigPath("plugins")) {
+		name := filepath.Base(filepath.Dir(path))
+		if err := g.loadConfigFile("plugin "+name, path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RegisterCompletion makes fn complete the arguments of command. fn is
+// given the arguments before the one being completed and what's been typed
+// of it, and returns the candidates, which are narrowed down to those
+// starting with what's been typed. A command's completion registered again
+// is replaced.
+func (s *ShellState) RegisterCompletion(command string, fn func(args []string, partial string) []string) error {
+	if command == "" || strings.ContainsAny(command, " \t\n") {
+		return fmt.Errorf("invalid command name: %q", command)
+	}
+	if fn == nil {
+		return fmt.Errorf("%s: no completion function", command)
+	}
+	if s.completions == nil {
+		s.completions = make(map[string]func([]string, string) []string)
+	}
+	s.completions[command] = fn
+	return nil
+}
+
+// complete returns the candidates the completion registered for command
+// offers, and false if there's none
+func (s *ShellState) complete(command string, args []string, partial string) (candidates []string, ok bool) {
+	fn, ok := s.completions[command]
+	if !ok {
+		return nil, false
+	}
+	defer func() {
+		// A completion that panics offers nothing
+		if r := recover(); r != nil {
+			debugf("completion for %s panicked: %v\n", command, r)
+			candidates = nil
+		}
+	}()
+	return fn(args, partial), true
+}
+
+// AddPromptSegment adds fn's text to the prompt as the segment name,
+// replacing the segment of that name if there's one already
+func (s *ShellState) AddPromptSegment(name string, fn func() string) error {
+	if name == "" {
+		return fmt.Errorf("prompt segment needs a name")
+	}
+	if fn == nil {
+		return fmt.Errorf("%s: no prompt segment function", name)
+	}
+	for i, segment := range s.promptSegments {
+		if segment.name == name {
+			s.promptSegments[i].fn = fn
+			return nil
+		}
+	}
+	s.promptSegments = append(s.promptSegments, promptSegment{name: name, fn: fn})
+	return nil
+}
+
+// pluginPromptSegments returns the text of the prompt segments plugins
+// added, in the order they were added, leaving out the empty ones
+func (s *ShellState) pluginPromptSegments() []string {
+	var texts []string
+	for _, segment := range s.promptSegments {
+		if text := segment.text(); text != "" {
+			texts = append(texts, text)
+		}
+	}
+	return texts
+}
+
+// text returns what the segment shows, on one line, or nothing if its
+// function panics
+func (p promptSegment) text() (text string) {
+	defer func() {
+		if r := recover(); r != nil {
+			debugf("prompt segment %s panicked: %v\n", p.name, r)
+			text = ""
+		}
+	}()
+	return strings.ReplaceAll(strings.TrimSpace(p.fn()), "\n", " ")
+}
@@ -0,0 +1,77 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadPlugins(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Chdir(home)
+	plugins := map[string]string{
+		"broken": "package main\n\nfunc broken() { undefined() }\n",
+		"deploy": "package main\n\n" +
+			"import \"fmt\"\n\n" +
+			"func deployTo(env string) string { return fmt.Sprint(\"deploying to \", env) }\n\n" +
+			"func init() {\n" +
+			"\tgosh.RegisterCommand(\"deploy\", deployTo)\n" +
+			"\tgosh.RegisterCompletion(\"deploy\", func(args []string, partial string) []string {\n" +
+			"\t\treturn []string{\"production\", \"preview\", \"staging\"}\n" +
+			"\t})\n" +
+			"\tgosh.PromptSegment(\"env\", func() string { return \"env:\" + gosh.Env()[\"DEPLOY_ENV\"] })\n" +
+			"}\n",
+	}
+	for name, code := range plugins {
+		dir := filepath.Join(home, ".config", "gosh", "plugins", name)
+		os.MkdirAll(dir, 0755)
+		os.WriteFile(filepath.Join(dir, "plugin.go"), []byte(code), 0644)
+	}
+
+	state := NewShellState()
+	state.Environment["DEPLOY_ENV"] = "staging"
+	g := NewGoEvaluator()
+	g.SetupWithShell(state, NewProcessSpawner(state))
+	builtins := NewBuiltinHandler(state)
+	g.SetupWithBuiltins(builtins)
+
+	// The broken plugin is reported, and the others load anyway
+	err := g.LoadConfig()
+	if err == nil || !strings.Contains(err.Error(), "plugin broken") {
+		t.Errorf("Expected LoadConfig() to report the broken plugin, got %v", err)
+	}
+	if got := strings.Join(g.configOrigins(), ", "); got != "plugin deploy" {
+		t.Errorf("Expected only the deploy plugin to load, got %q", got)
+	}
+
+	if result := builtins.Execute("deploy", []string{"prod"}); result.Output != "deploying to prod" {
+		t.Errorf("Expected the plugin's command to run, got %q", result.Output)
+	}
+
+	completer := NewGoshCompleterForTesting(g)
+	completer.state = state
+	line := []rune("deploy pr")
+	if got := strings.Join(completeLine(completer, line, len(line)), " "); got != "preview production" {
+		t.Errorf("Expected the plugin's completions, got %q", got)
+	}
+	line = []rune("de")
+	if got := strings.Join(completeLine(completer, line, len(line)), " "); !strings.Contains(got, "deploy") {
+		t.Errorf("Expected the plugin's command to complete, got %q", got)
+	}
+
+	// The segment shows in the REPL prompt, and changes after a block
+	session := &SessionState{CapturedVars: map[string][]string{}, Mode: ModeShell}
+	m := initialModel(session, state, g, NewProcessSpawner(state), builtins)
+	if prompt := m.prompt(); !strings.Contains(prompt, "env:staging") {
+		t.Errorf("Expected the plugin's prompt segment, got %q", prompt)
+	}
+	state.Environment["DEPLOY_ENV"] = "production"
+	m = m.finishBlock(blockFinishedMsg{})
+	if prompt := m.textarea.Prompt; !strings.Contains(prompt, "env:production") {
+		t.Errorf("Expected the prompt segment to change, got %q", prompt)
+	}
+}
@@ -0,0 +1,130 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/traefik/yaegi/stdlib"
+)
+
+// importDecl returns an import declaration of paths
+func importDecl(paths []string) string {
+	specs := make([]string, len(paths))
+	for i, importPath := range paths {
+		specs[i] = strconv.Quote(importPath)
+	}
+	return "import (\n\t" + strings.Join(specs, "\n\t") + "\n)"
+}
+
+// isStdlibPackage reports whether importPath is a standard library package
+// the interpreter has
+func isStdlibPackage(importPath string) bool {
+	_, ok := stdlib.Symbols[importPath+"/"+path.Base(importPath)]
+	return ok
+}
+
+// PreImport adds standard library packages to those Go code can use without
+// importing them, from the next evaluation on. Adding one again does
+// nothing.
+func (s *ShellState) PreImport(paths ...string) error {
+	for _, importPath := range paths {
+		if !isStdlibPackage(importPath) {
+			return fmt.Errorf("%s: not a standard library package", importPath)
+		}
+	}
+	for _, importPath := range paths {
+		if !slices.Contains(s.preImports, importPath) {
+			s.preImports = append(s.preImports, importPath)
+		}
+	}
+	return nil
+}
+
+// applyPreImports imports the packages added with gosh.PreImport that
+// aren't imported yet
+func (g *GoEvaluator) applyPreImports() {
+	if g.state == nil {
+		return
+	}
+	var paths []string
+	for _, importPath := range g.state.preImports {
+		if !g.imported[importPath] {
+			paths = append(paths, importPath)
+		}
+	}
+	if len(paths) == 0 {
+		return
+	}
+	if _, err := g.interp.Eval(importDecl(paths)); err != nil {
+		debugf("Warning: Failed to pre-import %s: %v\n", strings.Join(paths, ", "), err)
+		return
+	}
+	for _, importPath := range paths {
+		g.imported[importPath] = true
+	}
+}
+
+// packageNames returns the packages Go code can use without importing
+// them, by the name it uses them by, with their import paths. The gosh
+// package, which only the interpreter has, isn't one of them.
+func (g *GoEvaluator) packageNames() map[string]string {
+	names := make(map[string]string)
+	for importPath := range g.imported {
+		switch importPath {
+		case "gosh":
+			continue
+		case "gosh_lib/shellapi":
+			names["shellapi"] = goshLibShellapi
+			continue
+		}
+		names[path.Base(importPath)] = importPath
+	}
+	return names
+}
+
+// sortedPackageNames returns the names of packageNames, sorted
+func (g *GoEvaluator) sortedPackageNames() []string {
+	packages := g.packageNames()
+	names := make([]string, 0, len(packages))
+	for name := range packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// packageMembers returns the exported names of the standard library package
+// importPath that start with partial, sorted
+func packageMembers(importPath, partial string) []CompletionItem {
+	symbols := stdlib.Symbols[importPath+"/"+path.Base(importPath)]
+	names := make([]string, 0, len(symbols))
+	for name := range symbols {
+		if strings.HasPrefix(name, partial) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	items := make([]CompletionItem, len(names))
+	for i, name := range names {
+		// Types are nil pointers to them, and variables pointers to them
+		kind := "constant"
+		switch value := symbols[name]; {
+		case value.Kind() == reflect.Func:
+			kind = "function"
+		case value.Kind() == reflect.Pointer && value.IsNil():
+			kind = "type"
+		case value.Kind() == reflect.Pointer:
+			kind = "variable"
+		}
+		items[i] = CompletionItem{Label: name, Kind: kind, Detail: importPath + "." + name}
+	}
+	return items
+}
@@ -0,0 +1,61 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPreImport(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Chdir(home)
+	os.MkdirAll(filepath.Join(home, ".config", "gosh"), 0755)
+	os.WriteFile(filepath.Join(home, ".config", "gosh", "config.go"), []byte("package main\n\nfunc init() {\n\tgosh.PreImport(\"time\", \"encoding/json\")\n}\n"), 0644)
+
+	state := NewShellState()
+	g := NewGoEvaluator()
+	g.SetupWithShell(state, NewProcessSpawner(state))
+	if err := g.LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{`fmt.Sprintf("%v", time.Second)`, "1s"},
+		{`json.Valid([]byte("[1]"))`, "true"},
+		// Importing them again does nothing
+		{`import ("time"; "fmt")`, ""},
+		{`time.Minute.String()`, "1m0s"},
+	}
+	for _, tt := range tests {
+		if result := g.Eval(tt.code); result.ExitCode != 0 || result.Output != tt.expected {
+			t.Errorf("Eval(%q) = %q (exit %d), want %q", tt.code, result.Output, result.ExitCode, tt.expected)
+		}
+	}
+
+	if err := state.PreImport("github.com/fatih/color"); err == nil {
+		t.Error("Expected PreImport to refuse a package outside the standard library")
+	}
+
+	completer := NewGoshCompleterForTesting(g)
+	completer.state = state
+	for line, want := range map[string]string{"tim": "time", "d := time.Dur": "Duration", "v := json.Val": "Valid"} {
+		if got := completeLine(completer, []rune(line), len(line)); !strings.Contains(strings.Join(got, " "), want) {
+			t.Errorf("Completing %q = %q, want %s among them", line, got, want)
+		}
+	}
+
+	// Still there after the interpreter is replaced without the config
+	if err := g.Reset(false); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	if result := g.Eval(`time.Second`); result.ExitCode != 0 {
+		t.Errorf("Expected time to be pre-imported after a reset, got %q", result.Output)
+	}
+}
@@ -0,0 +1,234 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Pretty printing limits
+const (
+	prettyMaxDepth    = 10 // Deeper values are shown as …
+	prettyInlineWidth = 60 // Longest composite shown on one line
+	prettyMaxItems    = 100
+)
+
+// isPrettyPrinted reports whether v is a composite value that prettyValue
+// lays out, rather than a scalar formatResult handles
+func isPrettyPrinted(v reflect.Value) bool {
+	switch indirectValue(v).Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		return true
+	}
+	return false
+}
+
+// prettyValue renders v as indented, JSON-like text, with struct fields
+// and map keys colored. Composites of scalars that fit stay on one line.
+func prettyValue(v reflect.Value) string {
+	var sb strings.Builder
+	writePrettyValue(&sb, v, "", 0)
+	return sb.String()
+}
+
+func writePrettyValue(sb *strings.Builder, v reflect.Value, indent string, depth int) {
+	colors := GetColorManager()
+
+	if !v.IsValid() {
+		sb.WriteString("nil")
+		return
+	}
+	if depth > prettyMaxDepth {
+		sb.WriteString("…")
+		return
+	}
+	// Types such as time.Time and errors print better as themselves
+	if text, ok := stringerText(v); ok {
+		sb.WriteString(text)
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			sb.WriteString("nil")
+			return
+		}
+		writePrettyValue(sb, v.Elem(), indent, depth)
+		return
+	case reflect.Ptr:
+		if v.IsNil() {
+			sb.WriteString("nil")
+			return
+		}
+		sb.WriteString("&")
+		writePrettyValue(sb, v.Elem(), indent, depth+1)
+		return
+	case reflect.String:
+		sb.WriteString(colors.StyleOutput(strconv.Quote(v.String()), "success"))
+		return
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+	default:
+		sb.WriteString(prettyScalar(v))
+		return
+	}
+
+	if (v.Kind() == reflect.Map || v.Kind() == reflect.Slice) && v.IsNil() {
+		sb.WriteString("nil")
+		return
+	}
+	// Byte slices are usually text, such as what os.ReadFile returns
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 && utf8.Valid(v.Bytes()) {
+		sb.WriteString(colors.StyleOutput(strconv.Quote(string(v.Bytes())), "success"))
+		return
+	}
+
+	keys, values := prettyEntries(v)
+	open, close := "[", "]"
+	if v.Kind() == reflect.Struct || v.Kind() == reflect.Map {
+		open, close = "{", "}"
+	}
+	if len(values) == 0 {
+		sb.WriteString(open + close)
+		return
+	}
+
+	hidden := 0
+	if len(values) > prettyMaxItems {
+		hidden = len(values) - prettyMaxItems
+		values = values[:prettyMaxItems]
+	}
+
+	if inline, ok := prettyInline(keys, values); ok && hidden == 0 {
+		sb.WriteString(open + inline + close)
+		return
+	}
+
+	inner := indent + "  "
+	sb.WriteString(open + "\n")
+	for i, value := range values {
+		sb.WriteString(inner)
+		if keys != nil {
+			sb.WriteString(colors.StyleOutput(keys[i], "info") + ": ")
+		}
+		writePrettyValue(sb, value, inner, depth+1)
+		sb.WriteString(",\n")
+	}
+	if hidden > 0 {
+		sb.WriteString(fmt.Sprintf("%s… %d more\n", inner, hidden))
+	}
+	sb.WriteString(indent + close)
+}
+
+// prettyEntries returns the field names and values of a struct, the
+// sorted keys and values of a map, or the elements of a slice or array
+// with nil keys
+func prettyEntries(v reflect.Value) (keys []string, values []reflect.Value) {
+	switch v.Kind() {
+	case reflect.Struct:
+		keys = []string{}
+		for i := 0; i < v.NumField(); i++ {
+			keys = append(keys, v.Type().Field(i).Name)
+			values = append(values, v.Field(i))
+		}
+	case reflect.Map:
+		mapKeys := v.MapKeys()
+		names := make([]string, len(mapKeys))
+		for i, key := range mapKeys {
+			names[i] = prettyScalar(key)
+			if key.Kind() == reflect.String {
+				names[i] = strconv.Quote(key.String())
+			}
+		}
+		order := make([]int, len(mapKeys))
+		for i := range order {
+			order[i] = i
+		}
+		sort.Slice(order, func(a, b int) bool { return names[order[a]] < names[order[b]] })
+		keys = []string{}
+		for _, i := range order {
+			keys = append(keys, names[i])
+			values = append(values, v.MapIndex(mapKeys[i]))
+		}
+	default:
+		for i := 0; i < v.Len(); i++ {
+			values = append(values, v.Index(i))
+		}
+	}
+	return keys, values
+}
+
+// prettyInline returns the entries on one line if they're all scalars and
+// short enough together
+func prettyInline(keys []string, values []reflect.Value) (string, bool) {
+	parts := make([]string, len(values))
+	width := 0
+	for i, value := range values {
+		if _, ok := stringerText(value); !ok && isPrettyPrinted(value) {
+			return "", false
+		}
+		var sb strings.Builder
+		writePrettyValue(&sb, value, "", prettyMaxDepth)
+		parts[i] = sb.String()
+		if keys != nil {
+			parts[i] = GetColorManager().StyleOutput(keys[i], "info") + ": " + parts[i]
+			width += len(keys[i]) + 2
+		}
+		width += lipgloss.Width(sb.String()) + 2
+	}
+	if width > prettyInlineWidth {
+		return "", false
+	}
+	return strings.Join(parts, ", "), true
+}
+
+// stringerText returns what v's String or Error method says, for values
+// whose methods can be called
+func stringerText(v reflect.Value) (string, bool) {
+	if !v.IsValid() || !v.CanInterface() {
+		return "", false
+	}
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return "", false
+	}
+	switch value := v.Interface().(type) {
+	case error:
+		return value.Error(), true
+	case fmt.Stringer:
+		return value.String(), true
+	}
+	return "", false
+}
+
+// prettyScalar formats a value that isn't a composite, including those of
+// unexported fields, which can't be handed to fmt
+func prettyScalar(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	case reflect.String:
+		return v.String()
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		if v.IsNil() {
+			return "nil"
+		}
+		return v.Type().String()
+	}
+	if v.CanInterface() {
+		return fmt.Sprintf("%v", v.Interface())
+	}
+	return v.Type().String()
+}
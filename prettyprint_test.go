@@ -0,0 +1,69 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type prettyTestServer struct {
+	Name    string
+	Ports   []int
+	Labels  map[string]string
+	Started time.Duration
+	owner   *prettyTestServer
+}
+
+func TestPrettyValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{"scalars inline", []int{1, 2, 3}, "[1, 2, 3]"},
+		{"empty", map[string]int{}, "{}"},
+		{"nil slice", []string(nil), "nil"},
+		{"sorted map", map[string]int{"b": 2, "a": 1}, `{"a": 1, "b": 2}`},
+		{"bytes as text", []byte("hi\n"), `"hi\n"`},
+		{"error", []error{errors.New("boom")}, "[boom]"},
+		{
+			"nested struct",
+			prettyTestServer{Name: "web", Ports: []int{80, 443}, Labels: map[string]string{"env": "prod"}, Started: time.Second},
+			"{\n" +
+				"  Name: \"web\",\n" +
+				"  Ports: [80, 443],\n" +
+				"  Labels: {\"env\": \"prod\"},\n" +
+				"  Started: 1s,\n" +
+				"  owner: nil,\n" +
+				"}",
+		},
+	}
+	for _, tt := range tests {
+		if got := stripANSI(prettyValue(reflect.ValueOf(tt.value))); got != tt.want {
+			t.Errorf("%s: prettyValue() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPrettyValue_Cycle(t *testing.T) {
+	server := &prettyTestServer{Name: "loop"}
+	server.owner = server
+	if got := prettyValue(reflect.ValueOf(server)); !strings.Contains(got, "…") {
+		t.Errorf("Expected a cycle to stop at the depth limit, got %q", got)
+	}
+}
+
+func TestFormatResultAs_PrettyByDefault(t *testing.T) {
+	v := reflect.ValueOf(map[string][]int{"odd": {1, 3}, "even": {2, 4}})
+	want := "{\n  \"even\": [2, 4],\n  \"odd\": [1, 3],\n}"
+	if got := stripANSI(formatResultAs(v, ResultFormatTable, 80)); got != want {
+		t.Errorf("table format = %q, want %q", got, want)
+	}
+	if got := formatResultAs(v, ResultFormatGo, 80); got != "map[even:[2 4] odd:[1 3]]" {
+		t.Errorf("go format = %q, want raw %%v", got)
+	}
+}
@@ -0,0 +1,192 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// processSubstitution is a running <(command): a FIFO the command writes
+// to, whose path is passed to the outer command in its place
+type processSubstitution struct {
+	path   string
+	cancel context.CancelFunc
+	stderr bytes.Buffer
+	done   chan struct{}
+}
+
+// startProcessSubstitution creates a FIFO and starts feeding it the output
+// of command. Like other shells, the command runs once the FIFO is opened
+// for reading.
+func startProcessSubstitution(state *ShellState, command string) (*processSubstitution, error) {
+	sc, err := parseShellCommand(command)
+	if err != nil {
+		return nil, err
+	}
+	if sc.Name == "" {
+		return nil, fmt.Errorf("syntax error: empty <()")
+	}
+
+	dir, err := os.MkdirTemp("", "gosh-procsub-")
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, "fifo")
+	if err := syscall.Mkfifo(path, 0600); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sub := &processSubstitution{path: path, cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(sub.done)
+		defer os.RemoveAll(dir)
+		sub.feed(ctx, state, sc)
+	}()
+	return sub, nil
+}
+
+// feed runs the substituted command with its stdout going to the FIFO
+func (p *processSubstitution) feed(ctx context.Context, state *ShellState, sc ShellCommand) {
+	// Blocks until the outer command opens the FIFO, or release does
+	fifo, err := os.OpenFile(p.path, os.O_WRONLY, 0)
+	if err != nil {
+		fmt.Fprintf(&p.stderr, "gosh: %v\n", err)
+		return
+	}
+	defer fifo.Close()
+
+	sc, err = sc.Expand(state)
+	if err != nil {
+		fmt.Fprintf(&p.stderr, "gosh: %v\n", err)
+		return
+	}
+	defer sc.releaseSubstitutions()
+
+	files, err := openRedirects(state, sc.Redirects)
+	if err != nil {
+		fmt.Fprintf(&p.stderr, "gosh: %v\n", err)
+		return
+	}
+	defer files.Close()
+
+	cmd := exec.CommandContext(ctx, state.commandPath(sc.Name), sc.Args...)
+	cmd.Args[0] = sc.Name
+	cmd.Dir = state.WorkingDirectory
+	cmd.Env = state.EnvironmentSlice()
+	cmd.Stdout = fifo
+	cmd.Stderr = &p.stderr
+	if files.Stdin != nil {
+		cmd.Stdin = files.Stdin
+	}
+	if files.Stdout != nil {
+		cmd.Stdout = files.Stdout
+	}
+	if files.Stderr != nil {
+		cmd.Stderr = files.Stderr
+	}
+	if err := cmd.Run(); err != nil && ctx.Err() == nil {
+		if _, ok := err.(*exec.ExitError); !ok {
+			fmt.Fprintf(&p.stderr, "gosh: %v\n", unwrapExecError(err))
+		}
+	}
+}
+
+// release stops the command if it's still running, such as when the outer
+// command didn't read the FIFO to the end, and returns its stderr
+func (p *processSubstitution) release() string {
+	p.cancel()
+	// Opening the read end unblocks a feeder still waiting to open the FIFO
+	if reader, err := os.OpenFile(p.path, os.O_RDONLY|syscall.O_NONBLOCK, 0); err == nil {
+		defer reader.Close()
+	}
+	<-p.done
+	return p.stderr.String()
+}
+
+// expandProcessSubstitutions starts the <(command)s in an escaped word and
+// replaces them with the paths of their FIFOs
+func expandProcessSubstitutions(state *ShellState, word string) (string, []*processSubstitution, error) {
+	if !strings.Contains(word, "<(") {
+		return word, nil, nil
+	}
+
+	var sb strings.Builder
+	var subs []*processSubstitution
+	for i := 0; i < len(word); i++ {
+		switch {
+		case word[i] == '\\' && i+1 < len(word):
+			sb.WriteString(word[i : i+2])
+			i++
+		case strings.HasPrefix(word[i:], "<("):
+			// The command is escaped, so its first unescaped ) ends it
+			end := i + 2
+			for end < len(word) && word[end] != ')' {
+				if word[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			sub, err := startProcessSubstitution(state, unescapeWord(word[i+2:end]))
+			if err != nil {
+				for _, started := range subs {
+					started.release()
+				}
+				return "", nil, err
+			}
+			subs = append(subs, sub)
+			sb.WriteString(escapeText(sub.path))
+			i = end
+		default:
+			sb.WriteByte(word[i])
+		}
+	}
+	return sb.String(), subs, nil
+}
+
+// releaseSubstitutions releases the command's process substitutions once
+// it has finished, returning anything they wrote to stderr
+func (c ShellCommand) releaseSubstitutions() string {
+	var stderr strings.Builder
+	for _, sub := range c.substitutions {
+		stderr.WriteString(sub.release())
+	}
+	return stderr.String()
+}
+
+// matchingParen returns the index of the ) closing the ( at open, skipping
+// quoted text, or -1
+func matchingParen(runes []rune, open int) int {
+	depth := 0
+	quote := rune(0)
+	for i := open; i < len(runes); i++ {
+		switch c := runes[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' {
+				i++
+			}
+		case c == '\\':
+			i++
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
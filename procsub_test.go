@@ -0,0 +1,86 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func runProcsubTest(t *testing.T, state *ShellState, input string) ExecutionResult {
+	t.Helper()
+	list, err := parseCommandList(input)
+	if err != nil {
+		t.Fatalf("parseCommandList(%q) error: %v", input, err)
+	}
+
+	done := make(chan ExecutionResult)
+	go func() {
+		done <- runCommandList(state, list, NewProcessSpawner(state).Run)
+	}()
+	select {
+	case result := <-done:
+		return result
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%q didn't finish", input)
+	}
+	return ExecutionResult{}
+}
+
+func TestProcessSubstitution(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "d1"), 0755)
+	os.MkdirAll(filepath.Join(dir, "d2"), 0755)
+	for _, name := range []string{"d1/a", "d1/b", "d2/a", "d2/c"} {
+		os.WriteFile(filepath.Join(dir, name), nil, 0644)
+	}
+	state := &ShellState{WorkingDirectory: dir, Environment: map[string]string{"PATH": os.Getenv("PATH")}}
+
+	tests := []struct {
+		input    string
+		output   string
+		exitCode int
+	}{
+		{"diff <(ls d1) <(ls d2)", "2c2\n< b\n---\n> c\n", 1},
+		{"cat < <(echo redirected)", "redirected\n", 0},
+		{"cat <(cat <(echo nested))", "nested\n", 0},
+		{"cat <(echo 'a  b' \"$(x)\")", "a  b $(x)\n", 0},
+		// The feeder is stopped when the command doesn't read it all, or at all
+		{"head -1 <(yes)", "y\n", 0},
+		{"true <(yes)", "", 0},
+	}
+
+	for _, tt := range tests {
+		result := runProcsubTest(t, state, tt.input)
+		if result.Output != tt.output || result.ExitCode != tt.exitCode {
+			t.Errorf("%q gave %q (exit %d), want %q (exit %d)", tt.input, result.Output, result.ExitCode, tt.output, tt.exitCode)
+		}
+	}
+
+	result := runProcsubTest(t, state, "cat <(no-such-command-gosh)")
+	if !strings.Contains(result.Output, "no-such-command-gosh") {
+		t.Errorf("Expected the substitution's error in the output, got %q", result.Output)
+	}
+
+	// The path of the FIFO is passed in place of the substitution
+	cmd, _ := parseShellCommand("echo --file=<(echo hi)")
+	expanded, err := cmd.Expand(state)
+	if err != nil {
+		t.Fatalf("Expand error: %v", err)
+	}
+	fifo := strings.TrimPrefix(expanded.Args[0], "--file=")
+	if info, err := os.Stat(fifo); err != nil || info.Mode()&os.ModeNamedPipe == 0 {
+		t.Errorf("Expected %q to be a FIFO (%v)", fifo, err)
+	}
+	expanded.releaseSubstitutions()
+	if _, err := os.Stat(fifo); !os.IsNotExist(err) {
+		t.Errorf("Expected the FIFO to be removed after release, got %v", err)
+	}
+
+	if _, err := parseCommandList("diff <(ls d1"); err == nil {
+		t.Error("Expected a syntax error for an unterminated <(")
+	}
+}
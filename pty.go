@@ -0,0 +1,181 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/creack/pty"
+	"github.com/muesli/cancelreader"
+)
+
+// ptyCommands are full-screen and interactive programs. Instead of having
+// their output collected like other commands, they run on a terminal of
+// their own. GOSH_PTY_COMMANDS adds more, separated by spaces or colons.
+var ptyCommands = map[string]bool{
+	"vi": true, "vim": true, "nvim": true, "nano": true, "emacs": true, "hx": true, "micro": true,
+	"less": true, "more": true, "man": true,
+	"top": true, "htop": true, "btop": true, "watch": true,
+	"ssh": true, "mosh": true, "tmux": true, "screen": true,
+	"fzf": true, "tig": true, "lazygit": true, "k9s": true,
+	"psql": true, "mysql": true, "sqlite3": true,
+}
+
+// ptyREPLs are interactive only when run without arguments
+var ptyREPLs = map[string]bool{"python": true, "python3": true, "node": true, "irb": true, "ghci": true}
+
+// ptyWrappers run the command after their options, as in sudo vim
+var ptyWrappers = map[string]bool{"sudo": true, "doas": true, "env": true, "nice": true, "time": true}
+
+// wantsPty reports whether a command should run on a PTY: it's one of the
+// interactive programs, in the foreground, without redirections, and gosh
+// itself is on a terminal
+func (p *ProcessSpawner) wantsPty(sc ShellCommand) bool {
+	if sc.Background || len(sc.Redirects) > 0 {
+		return false
+	}
+	if !term.IsTerminal(os.Stdin.Fd()) || !term.IsTerminal(os.Stdout.Fd()) {
+		return false
+	}
+	return isPtyCommand(sc.Name, sc.Args, p.state.Environment["GOSH_PTY_COMMANDS"])
+}
+
+// isPtyCommand reports whether name, run with args, is an interactive
+// program, looking past wrappers such as sudo
+func isPtyCommand(name string, args []string, extra string) bool {
+	for ptyWrappers[filepath.Base(name)] {
+		// Skip the wrapper's options and env's VAR=value settings
+		for len(args) > 0 && (strings.HasPrefix(args[0], "-") || strings.Contains(args[0], "=")) {
+			args = args[1:]
+		}
+		if len(args) == 0 {
+			return false
+		}
+		name, args = args[0], args[1:]
+	}
+
+	name = filepath.Base(name)
+	if ptyCommands[name] || (ptyREPLs[name] && len(args) == 0) {
+		return true
+	}
+	for _, command := range strings.FieldsFunc(extra, func(r rune) bool { return r == ' ' || r == ':' }) {
+		if command == name {
+			return true
+		}
+	}
+	return false
+}
+
+// runPty runs cmd on the terminal through a PTY, with the UI released for
+// the duration
+func (p *ProcessSpawner) runPty(cmd *exec.Cmd) ExecutionResult {
+	var exitCode int
+	err := withTerminal(func() error {
+		var err error
+		exitCode, err = runOnPty(cmd, os.Stdin, os.Stdout)
+		return err
+	})
+	if err != nil {
+		return ExecutionResult{Output: fmt.Sprintf("gosh: %v", unwrapExecError(err)), ExitCode: 1, Error: err}
+	}
+	return ExecutionResult{Output: "", ExitCode: exitCode}
+}
+
+// runOnPty runs cmd with a new PTY as its controlling terminal, copying
+// in to it and its output to out. When in is a terminal it's put in raw
+// mode, so keys such as Ctrl-C reach the program, the PTY follows its
+// size, and its state is restored afterwards.
+func runOnPty(cmd *exec.Cmd, in *os.File, out io.Writer) (int, error) {
+	isTerminal := term.IsTerminal(in.Fd())
+	var size *pty.Winsize
+	if isTerminal {
+		size, _ = pty.GetsizeFull(in)
+	}
+
+	ptmx, err := pty.StartWithSize(cmd, size)
+	if err != nil {
+		return 1, err
+	}
+	defer ptmx.Close()
+
+	if isTerminal {
+		if state, err := term.MakeRaw(in.Fd()); err == nil {
+			defer term.Restore(in.Fd(), state)
+		}
+
+		resized := make(chan os.Signal, 1)
+		signal.Notify(resized, syscall.SIGWINCH)
+		defer func() {
+			signal.Stop(resized)
+			close(resized)
+		}()
+		go func() {
+			for range resized {
+				pty.InheritSize(in, ptmx)
+			}
+		}()
+	}
+
+	// Reading the input must stop when the program exits, or the next key
+	// would be lost to it
+	if input, err := cancelreader.NewReader(in); err == nil {
+		typed := make(chan struct{})
+		go func() {
+			io.Copy(ptmx, input)
+			close(typed)
+		}()
+		// Done with in only once the copy has stopped reading it
+		defer func() {
+			input.Cancel()
+			<-typed
+			input.Close()
+		}()
+	}
+
+	copied := make(chan struct{})
+	go func() {
+		// Ends with an error once the program and its children exit
+		io.Copy(out, ptmx)
+		close(copied)
+	}()
+
+	exitCode := waitForeground(cmd.Process)
+	// Background children of the program can keep the PTY open
+	select {
+	case <-copied:
+	case <-time.After(time.Second):
+	}
+	return exitCode, nil
+}
+
+// waitForeground waits for a process on a PTY to exit. It can't become a
+// job, so if it's stopped, as by Ctrl-Z, it's continued.
+func waitForeground(process *os.Process) int {
+	defer process.Release()
+	for {
+		var status syscall.WaitStatus
+		_, err := syscall.Wait4(process.Pid, &status, syscall.WUNTRACED, nil)
+		switch {
+		case err == syscall.EINTR:
+			continue
+		case err != nil:
+			return 1
+		case status.Stopped():
+			// The program leads its own session and process group
+			syscall.Kill(-process.Pid, syscall.SIGCONT)
+		case status.Signaled():
+			return 128 + int(status.Signal())
+		default:
+			return status.ExitStatus()
+		}
+	}
+}
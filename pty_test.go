@@ -0,0 +1,62 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestIsPtyCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		extra   string
+		want    bool
+	}{
+		{"vim main.go", "", true},
+		{"/usr/bin/less README.md", "", true},
+		{"sudo -E vim /etc/hosts", "", true},
+		{"env TERM=xterm top", "", true},
+		{"python3", "", true},
+		{"python3 script.py", "", false},
+		{"ls -l", "", false},
+		{"sudo ls", "", false},
+		{"sudo", "", false},
+		{"mytui --flag", "other:mytui", true},
+		{"mytui", "other tui", false},
+	}
+
+	for _, tt := range tests {
+		fields := strings.Fields(tt.command)
+		if got := isPtyCommand(fields[0], fields[1:], tt.extra); got != tt.want {
+			t.Errorf("isPtyCommand(%q, %q) = %v, want %v", tt.command, tt.extra, got, tt.want)
+		}
+	}
+}
+
+func TestRunOnPty(t *testing.T) {
+	in, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	defer writer.Close()
+	writer.WriteString("typed\n")
+
+	var out bytes.Buffer
+	cmd := exec.Command("sh", "-c", "read line; echo \"got $line\"; test -t 0 && echo on-a-terminal; exit 3")
+	exitCode, err := runOnPty(cmd, in, &out)
+	if err != nil {
+		t.Fatalf("runOnPty error: %v", err)
+	}
+	if exitCode != 3 {
+		t.Errorf("Expected exit code 3, got %d", exitCode)
+	}
+	output := strings.ReplaceAll(out.String(), "\r\n", "\n")
+	if !strings.Contains(output, "got typed\n") || !strings.Contains(output, "on-a-terminal\n") {
+		t.Errorf("Unexpected output %q", output)
+	}
+}
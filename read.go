@@ -0,0 +1,199 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// readLine reads one line from r a byte at a time, so nothing after the
+// line is taken from a shared stdin. Without raw, a backslash escapes the
+// next character and a backslash before the newline continues the line;
+// escaped reports which characters of the line were escaped. The error is
+// io.EOF if the input ended before a newline.
+func readLine(r io.Reader, raw bool) (line []rune, escaped []bool, err error) {
+	var buf [1]byte
+	var pending []byte // Bytes of a multi-byte character
+	escape := false
+
+	for {
+		n, err := r.Read(buf[:])
+		if n == 0 {
+			if err == nil {
+				continue
+			}
+			return line, escaped, err
+		}
+
+		c := buf[0]
+		if len(pending) == 0 && c < utf8.RuneSelf {
+			switch {
+			case c == '\n' && escape:
+				escape = false
+			case c == '\n':
+				return line, escaped, nil
+			case c == '\\' && !raw && !escape:
+				escape = true
+			default:
+				line = append(line, rune(c))
+				escaped = append(escaped, escape)
+				escape = false
+			}
+			continue
+		}
+
+		pending = append(pending, c)
+		if utf8.FullRune(pending) {
+			for _, ch := range string(pending) {
+				line = append(line, ch)
+				escaped = append(escaped, escape)
+			}
+			pending = pending[:0]
+			escape = false
+		}
+	}
+}
+
+// splitReadFields splits a line read by read into at most n fields at the
+// characters of ifs. The last field keeps the rest of the line, less the
+// separators at its ends.
+func splitReadFields(line []rune, escaped []bool, ifs string, n int) []string {
+	isSeparator := func(i int) bool {
+		return !escaped[i] && strings.ContainsRune(ifs, line[i])
+	}
+
+	var fields []string
+	i := 0
+	for len(fields) < n-1 {
+		for i < len(line) && isSeparator(i) {
+			i++
+		}
+		if i == len(line) {
+			break
+		}
+		start := i
+		for i < len(line) && !isSeparator(i) {
+			i++
+		}
+		fields = append(fields, string(line[start:i]))
+	}
+
+	// The last field runs to the end of the line
+	for i < len(line) && isSeparator(i) {
+		i++
+	}
+	end := len(line)
+	for end > i && isSeparator(end-1) {
+		end--
+	}
+	if i < end {
+		fields = append(fields, string(line[i:end]))
+	}
+	return fields
+}
+
+// read implements the read builtin, which sets shell variables, seen by
+// commands and Go code alike, from a line of input
+func (b *BuiltinHandler) read(args []string) ExecutionResult {
+	usage := func(err error) ExecutionResult {
+		return ExecutionResult{Output: fmt.Sprintf("read: %v\nUsage: read [-r] [-p PROMPT] [NAME...]", err), ExitCode: 2, Error: err}
+	}
+
+	raw := false
+	prompt := ""
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		option := args[0]
+		args = args[1:]
+		if option == "--" {
+			break
+		}
+		switch option {
+		case "-r":
+			raw = true
+		case "-p":
+			if len(args) == 0 {
+				return usage(fmt.Errorf("-p needs a prompt"))
+			}
+			prompt = args[0]
+			args = args[1:]
+		default:
+			return usage(fmt.Errorf("unknown option: %s", option))
+		}
+	}
+
+	names := args
+	if len(names) == 0 {
+		names = []string{"REPLY"}
+	}
+	for _, name := range names {
+		if !isVariableName(name) {
+			return usage(fmt.Errorf("%s: not a valid identifier", name))
+		}
+	}
+
+	var line []rune
+	var escaped []bool
+	var readErr error
+	var err error
+	if b.stdin != nil {
+		// From a < or heredoc, where there's no one to prompt
+		line, escaped, readErr = readLine(b.stdin, raw)
+		if readErr != io.EOF {
+			err = readErr
+		}
+	} else {
+		// The REPL's UI lets go of the terminal while the line is typed
+		err = withTerminal(func() error {
+			if prompt != "" {
+				fmt.Fprint(os.Stderr, prompt)
+			}
+			line, escaped, readErr = readLine(os.Stdin, raw)
+			if readErr == io.EOF {
+				return nil
+			}
+			return readErr
+		})
+	}
+	if err != nil {
+		return ExecutionResult{Output: fmt.Sprintf("read: %v", err), ExitCode: 1, Error: err}
+	}
+
+	ifs, set := b.state.Environment["IFS"]
+	if !set {
+		ifs = " \t\n"
+	}
+	fields := splitReadFields(line, escaped, ifs, len(names))
+	for i, name := range names {
+		value := ""
+		if i < len(fields) {
+			value = fields[i]
+		}
+		b.state.SetEnv(name, value)
+	}
+
+	// As in other shells, read fails at the end of the input, even though
+	// the variables are set from a last line without a newline
+	if readErr == io.EOF {
+		return ExecutionResult{Output: "", ExitCode: 1}
+	}
+	return ExecutionResult{Output: "", ExitCode: 0}
+}
+
+const readHelpText = "read - Read a Line of Input\n\n" +
+	"USAGE:\n" +
+	"    read [-r] [-p PROMPT] [NAME...]\n\n" +
+	"DESCRIPTION:\n" +
+	"    Read a line from standard input and split it into words, setting\n" +
+	"    each NAME to a word and the last NAME to the rest of the line. With\n" +
+	"    no NAME the line is put in REPLY. The variables are seen by commands,\n" +
+	"    by $NAME expansion and by Go code through os.Getenv.\n\n" +
+	"    -p PROMPT  Print PROMPT first, without a newline\n" +
+	"    -r         Keep backslashes rather than treating them as escapes\n\n" +
+	"    read exits with 1 at the end of the input.\n\n" +
+	"EXAMPLES:\n" +
+	"    read -p \"Branch: \" branch\n" +
+	"    read first rest"
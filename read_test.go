@@ -0,0 +1,117 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadLine(t *testing.T) {
+	r := strings.NewReader("a\\ b \\\ncontinued\nnext")
+	line, escaped, err := readLine(r, false)
+	if err != nil || string(line) != "a b continued" || !escaped[1] || escaped[2] {
+		t.Errorf("readLine = %q, %v, %v", string(line), escaped, err)
+	}
+	// The rest is left for the next read
+	if line, _, err := readLine(r, false); err != io.EOF || string(line) != "next" {
+		t.Errorf("Expected the last line and io.EOF, got %q, %v", string(line), err)
+	}
+
+	if line, _, _ := readLine(strings.NewReader("C:\\dir é\n"), true); string(line) != "C:\\dir é" {
+		t.Errorf("Expected -r to keep backslashes, got %q", string(line))
+	}
+}
+
+func TestSplitReadFields(t *testing.T) {
+	tests := []struct {
+		line string
+		n    int
+		want []string
+	}{
+		{"  one  two three  ", 2, []string{"one", "two three"}},
+		{"one two", 3, []string{"one", "two"}},
+		{"  whole line  ", 1, []string{"whole line"}},
+		{"", 1, nil},
+	}
+	for _, tt := range tests {
+		line := []rune(tt.line)
+		if got := splitReadFields(line, make([]bool, len(line)), " \t\n", tt.n); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitReadFields(%q, %d) = %q, want %q", tt.line, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestReadBuiltin(t *testing.T) {
+	t.Setenv("GOSH_TEST_FIRST", "")
+	t.Setenv("GOSH_TEST_REST", "")
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+	w.WriteString("main feature branch\nlast")
+	w.Close()
+
+	state := &ShellState{WorkingDirectory: t.TempDir(), Environment: map[string]string{}}
+	b := NewBuiltinHandler(state)
+
+	if result := b.Execute("read", []string{"GOSH_TEST_FIRST", "GOSH_TEST_REST"}); result.ExitCode != 0 {
+		t.Fatalf("read failed: %q", result.Output)
+	}
+	if state.Environment["GOSH_TEST_FIRST"] != "main" || os.Getenv("GOSH_TEST_REST") != "feature branch" {
+		t.Errorf("Unexpected variables %v", state.Environment)
+	}
+
+	// A last line without a newline is read, but read reports the end
+	if result := b.Execute("read", nil); result.ExitCode != 1 || state.Environment["REPLY"] != "last" {
+		t.Errorf("Expected REPLY=last and exit 1, got %q (exit %d)", state.Environment["REPLY"], result.ExitCode)
+	}
+	if result := b.Execute("read", []string{"1BAD"}); result.ExitCode != 2 {
+		t.Errorf("Expected a bad name to be refused, got %q", result.Output)
+	}
+}
+
+func TestReadBuiltin_Redirects(t *testing.T) {
+	t.Setenv("GOSH_TEST_FIRST", "")
+	t.Setenv("GOSH_TEST_REST", "")
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "in.txt"), []byte("from the file\nsecond line\n"), 0644)
+	state := &ShellState{WorkingDirectory: dir, Environment: map[string]string{}}
+	b := NewBuiltinHandler(state)
+	router := NewRouter(b, state)
+	run := func(line string) ExecutionResult {
+		list, err := router.ParseCommandList(line)
+		if err != nil {
+			t.Fatalf("Parsing %q: %v", line, err)
+		}
+		return runCommandList(state, list, func(cmd ShellCommand) ExecutionResult {
+			return runBuiltin(b, state, cmd)
+		})
+	}
+
+	// Neither reads gosh's own stdin
+	if result := run("read GOSH_TEST_FIRST GOSH_TEST_REST < in.txt"); result.ExitCode != 0 {
+		t.Fatalf("read < in.txt failed: %q", result.Output)
+	}
+	if state.Environment["GOSH_TEST_FIRST"] != "from" || state.Environment["GOSH_TEST_REST"] != "the file" {
+		t.Errorf("Unexpected variables after read < in.txt: %v", state.Environment)
+	}
+	if result := run("read -r GOSH_TEST_FIRST <<EOF\nheredoc \\line\nEOF"); result.ExitCode != 0 {
+		t.Fatalf("read <<EOF failed: %q", result.Output)
+	}
+	if state.Environment["GOSH_TEST_FIRST"] != `heredoc \line` {
+		t.Errorf("Unexpected variable after read <<EOF: %q", state.Environment["GOSH_TEST_FIRST"])
+	}
+
+	if result := run("read < missing.txt"); result.ExitCode != 1 || !strings.Contains(result.Output, "missing.txt") {
+		t.Errorf("Expected a missing input file to fail, got %q (exit %d)", result.Output, result.ExitCode)
+	}
+}
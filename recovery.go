@@ -0,0 +1,116 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/traefik/yaegi/interp"
+)
+
+// interpreterCrash is the error of an evaluation the interpreter itself
+// panicked in, rather than the code it ran, which can leave it half way
+// through defining something. EvalWithRecovery replaces the interpreter
+// after one.
+type interpreterCrash struct {
+	value interface{}
+}
+
+func (c *interpreterCrash) Error() string {
+	return fmt.Sprintf("yaegi evaluation panic: %v", c.value)
+}
+
+// crashError turns what a panic while evaluating code left, recovered
+// either by yaegi or by Eval, into an error. Panics of the code being run
+// are plain errors; those of the interpreter checking or compiling it, or
+// outside yaegi's own recovery, are interpreterCrash errors.
+func crashError(err error, recovered interface{}) error {
+	if recovered != nil {
+		return &interpreterCrash{value: recovered}
+	}
+	var p interp.Panic
+	if !errors.As(err, &p) {
+		return err
+	}
+	// Code only runs once yaegi has compiled it
+	if !bytes.Contains(p.Stack, []byte("interp.(*Interpreter).Execute")) {
+		return &interpreterCrash{value: p.Value}
+	}
+	return fmt.Errorf("yaegi evaluation panic: %v", p.Value)
+}
+
+// recoverInterpreter replaces a crashed interpreter with a fresh one,
+// loading the config files again, injecting the captured variables and
+// replaying the session's declarations in order. It returns what it tells
+// the user: what was and wasn't recovered.
+func (g *GoEvaluator) recoverInterpreter() string {
+	g.evalMu.Lock()
+	declarations := append([]string(nil), g.declarations...)
+	injected := make(map[string]interface{}, len(g.injected))
+	for name, value := range g.injected {
+		injected[name] = value
+	}
+	g.evalMu.Unlock()
+
+	var sb strings.Builder
+	sb.WriteString("The Go interpreter crashed, so gosh started a new one.")
+	if err := g.Reset(true); err != nil {
+		sb.WriteString(fmt.Sprintf("\n  ✗ config: %v", err))
+	} else if configs := g.configOrigins(); len(configs) > 0 {
+		sb.WriteString("\n  ✓ " + strings.Join(configs, ", ") + " loaded again")
+	}
+
+	names := make([]string, 0, len(injected))
+	for name := range injected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var captured []string
+	for _, name := range names {
+		if err := g.InjectVariable(name, injected[name]); err != nil {
+			sb.WriteString(fmt.Sprintf("\n  ✗ captured variable %s: %v", name, err))
+			continue
+		}
+		captured = append(captured, name)
+	}
+	if len(captured) > 0 {
+		sb.WriteString("\n  ✓ captured variables: " + strings.Join(captured, ", "))
+	}
+
+	replayed := 0
+	for _, block := range declarations {
+		if result := g.Eval(block); result.ExitCode != 0 {
+			sb.WriteString(fmt.Sprintf("\n  ✗ %s\n    %s", firstLine(block), strings.ReplaceAll(result.Output, "\n", "\n    ")))
+			continue
+		}
+		replayed++
+	}
+	if len(declarations) > 0 {
+		sb.WriteString(fmt.Sprintf("\n  ✓ %d of %d session declarations replayed", replayed, len(declarations)))
+	}
+
+	sb.WriteString("\nReplaying ran their right-hand sides again. Values changed without a\n" +
+		"declaration or assignment, such as by calling a method, weren't recovered,\n" +
+		"nor was the block that crashed.")
+	return sb.String()
+}
+
+// configOrigins returns the config files and plugins loaded into the
+// interpreter, in the order they were loaded
+func (g *GoEvaluator) configOrigins() []string {
+	g.evalMu.Lock()
+	defer g.evalMu.Unlock()
+	return append([]string(nil), g.configs...)
+}
+
+// firstLine returns the first line of code, marking that there's more
+func firstLine(code string) string {
+	if line, _, more := strings.Cut(code, "\n"); more {
+		return line + " …"
+	}
+	return code
+}
@@ -0,0 +1,45 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGoEvaluator_RecoversFromCrash(t *testing.T) {
+	state := NewShellState()
+	state.WorkingDirectory = t.TempDir()
+	g := NewGoEvaluator()
+	g.SetupWithShell(state, NewProcessSpawner(state))
+	g.InjectVariable("files", []string{"a", "b"})
+	for _, code := range []string{"n := 41", "func double(x int) int { return 2 * x }", "n++", "bad := 1 + nope"} {
+		g.EvalWithRecovery(code)
+	}
+
+	// yaegi is left with nope half defined, and panics checking its use
+	result := g.EvalWithRecovery("strconv.Atoi(nope)")
+	for _, expected := range []string{"crashed", "captured variables: files", "3 of 3 session declarations replayed"} {
+		if !strings.Contains(result.Output, expected) {
+			t.Errorf("Expected %q in the report, got %q", expected, result.Output)
+		}
+	}
+
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{"double(n)", "84"},
+		{"len(files)", "2"},
+	}
+	for _, tt := range tests {
+		if result := g.EvalWithRecovery(tt.code); result.Output != tt.expected {
+			t.Errorf("After recovering, %s = %q, want %q", tt.code, result.Output, tt.expected)
+		}
+	}
+
+	// A panic of the code run isn't a crash
+	if result := g.EvalWithRecovery(`panic("boom")`); strings.Contains(result.Output, "crashed") {
+		t.Errorf("Expected a panic in the code not to replace the interpreter, got %q", result.Output)
+	}
+}
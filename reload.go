@@ -0,0 +1,179 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// declarationSources returns the source of each declaration of a config
+// file by name: functions, variables and constants, types, and methods as
+// Type.Method. init functions, which there can be several of, are left out.
+func declarationSources(filename string, src []byte) map[string]string {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, 0)
+	if err != nil {
+		return nil
+	}
+	source := func(node ast.Node) string {
+		return string(src[fset.Position(node.Pos()).Offset:fset.Position(node.End()).Offset])
+	}
+
+	decls := make(map[string]string)
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			name := decl.Name.Name
+			if decl.Recv != nil && len(decl.Recv.List) == 1 {
+				name = receiverType(decl.Recv.List[0].Type) + "." + name
+			} else if name == "init" {
+				continue
+			}
+			decls[name] = source(decl)
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					decls[spec.Name.Name] = "type " + source(spec)
+				case *ast.ValueSpec:
+					for _, name := range spec.Names {
+						if name.Name != "_" {
+							decls[name.Name] = decl.Tok.String() + " " + source(spec)
+						}
+					}
+				}
+			}
+		}
+	}
+	return decls
+}
+
+// receiverType returns the name of the type of a method's receiver
+func receiverType(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return receiverType(expr.X)
+	case *ast.IndexExpr:
+		return receiverType(expr.X)
+	case *ast.IndexListExpr:
+		return receiverType(expr.X)
+	case *ast.Ident:
+		return expr.Name
+	}
+	return ""
+}
+
+// Reload loads the config files again into the interpreter as it is, so
+// their functions and variables are redefined while the session's own are
+// kept, and returns what changed
+func (g *GoEvaluator) Reload() (string, error) {
+	g.evalMu.Lock()
+	before := g.configDecls
+	g.configDecls = make(map[string]string)
+	g.configFuncs = make(map[string]reflect.Value)
+	g.funcDocs = make(map[string]funcDoc)
+	g.commandFuncs = make(map[string]string)
+	g.configs = nil
+	for name, origin := range g.funcOrigins {
+		if origin != "session" {
+			delete(g.funcOrigins, name)
+		}
+	}
+	g.evalMu.Unlock()
+
+	err := g.LoadConfig()
+
+	g.evalMu.Lock()
+	defer g.evalMu.Unlock()
+	if len(g.configs) == 0 {
+		if err != nil {
+			return "", err
+		}
+		return "No config files to reload", nil
+	}
+	return describeReload(g.configs, before, g.configDecls), err
+}
+
+// describeReload reports the declarations reloading configs added, changed
+// and removed, given them before and after
+func describeReload(configs []string, before, after map[string]string) string {
+	var added, changed, removed, kept []string
+	for name, decl := range after {
+		old, existed := before[name]
+		switch {
+		case !existed:
+			added = append(added, name)
+		case old != decl:
+			changed = append(changed, name)
+			// yaegi keeps the first definition of a type and its methods
+			if strings.HasPrefix(old, "type ") || strings.Contains(name, ".") {
+				kept = append(kept, name)
+			}
+		}
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Reloaded " + strings.Join(configs, ", "))
+	if len(added)+len(changed)+len(removed) == 0 {
+		sb.WriteString(" (no changes)")
+		return sb.String()
+	}
+	for _, group := range []struct {
+		label string
+		names []string
+	}{{"added:  ", added}, {"changed:", changed}, {"removed:", removed}} {
+		if len(group.names) > 0 {
+			sort.Strings(group.names)
+			sb.WriteString(fmt.Sprintf("\n  %s %s", group.label, strings.Join(group.names, ", ")))
+		}
+	}
+	if len(removed) > 0 {
+		sb.WriteString("\nRemoved declarations stay defined until :reset.")
+	}
+	if len(kept) > 0 {
+		sort.Strings(kept)
+		sb.WriteString("\nTypes and methods keep their old definitions until :reset: " + strings.Join(kept, ", "))
+	}
+	return sb.String()
+}
+
+// reload implements the reload builtin
+func (b *BuiltinHandler) reload(args []string) ExecutionResult {
+	if len(args) > 0 {
+		err := errors.New("too many arguments")
+		return ExecutionResult{Output: "reload: too many arguments\nUsage: reload", ExitCode: 1, Error: err}
+	}
+	if b.evaluator == nil {
+		err := errors.New("no Go interpreter")
+		return ExecutionResult{Output: fmt.Sprintf("reload: %v", err), ExitCode: 1, Error: err}
+	}
+
+	report, err := b.evaluator.Reload()
+	if err != nil {
+		return ExecutionResult{Output: strings.TrimSpace(report + "\nreload: " + err.Error()), ExitCode: 1, Error: err}
+	}
+	return ExecutionResult{Output: report, ExitCode: 0}
+}
+
+const reloadHelpText = "reload - Reload the Config Files\n\n" +
+	"USAGE:\n" +
+	"    reload\n\n" +
+	"DESCRIPTION:\n" +
+	"    Loads the home config, plugins and project config again into the\n" +
+	"    running Go interpreter, redefining their functions and variables\n" +
+	"    without losing the session's, and lists the declarations that were\n" +
+	"    added, changed or removed. init functions run again.\n\n" +
+	"    yaegi can't redefine types or methods, or forget a declaration, so\n" +
+	"    those take effect after :reset, which starts a new interpreter."
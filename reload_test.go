@@ -0,0 +1,123 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReload(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Chdir(home)
+	configPath := filepath.Join(home, ".config", "gosh", "config.go")
+	os.MkdirAll(filepath.Dir(configPath), 0755)
+	os.WriteFile(configPath, []byte(`package main
+
+var greeting = "hello"
+
+type Point struct{ X int }
+
+func greet(name string) string { return greeting + " " + name }
+
+func old() {}
+`), 0644)
+
+	state := NewShellState()
+	g := NewGoEvaluator()
+	builtins := NewBuiltinHandler(state)
+	g.SetupWithShell(state, NewProcessSpawner(state))
+	g.SetupWithBuiltins(builtins)
+	if err := g.LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if result := g.Eval("count := 3"); result.ExitCode != 0 {
+		t.Fatalf("Eval error: %s", result.Output)
+	}
+
+	os.WriteFile(configPath, []byte(`package main
+
+var greeting = "hi"
+
+type Point struct{ X, Y int }
+
+// greet greets name loudly.
+func greet(name string) string { return greeting + " " + strings.ToUpper(name) }
+
+func deploy(env string) {}
+`), 0644)
+	result := builtins.Execute("reload", nil)
+	want := "Reloaded home config\n" +
+		"  added:   deploy\n" +
+		"  changed: Point, greet, greeting\n" +
+		"  removed: old\n" +
+		"Removed declarations stay defined until :reset.\n" +
+		"Types and methods keep their old definitions until :reset: Point"
+	if result.ExitCode != 0 || result.Output != want {
+		t.Errorf("reload = %q (exit %d), want %q", result.Output, result.ExitCode, want)
+	}
+
+	// The new definitions are used, and the session's variables are kept
+	if result := g.Eval(`greet("gosh")`); result.Output != "hi GOSH" {
+		t.Errorf("greet = %q", result.Output)
+	}
+	if result := g.Eval("count"); result.Output != "3" {
+		t.Errorf("count = %q", result.Output)
+	}
+	if fn, ok := g.configFuncs["deploy"]; !ok || fn.Type().NumIn() != 1 {
+		t.Errorf("deploy wasn't stored as a config function")
+	}
+	if _, ok := g.configFuncs["old"]; ok {
+		t.Errorf("old is still a config function")
+	}
+	if help, ok := builtins.funcHelp("greet"); !ok || help != "greet - Function (home config)\n\nUSAGE:\n    func greet(name string) string\n\nDESCRIPTION:\n    greet greets name loudly." {
+		t.Errorf("help greet = %q", help)
+	}
+
+	if result := builtins.Execute("reload", nil); result.Output != "Reloaded home config (no changes)" {
+		t.Errorf("reload = %q", result.Output)
+	}
+
+	os.WriteFile(configPath, []byte("package main\n\nfunc broken( {}\n"), 0644)
+	if result := builtins.Execute("reload", nil); result.ExitCode != 1 {
+		t.Errorf("reload of a broken config = %q (exit %d)", result.Output, result.ExitCode)
+	}
+}
+
+func TestDeclarationSources(t *testing.T) {
+	src := []byte(`package main
+
+import "fmt"
+
+const (
+	a = 1
+	b, _ = 2, 3
+)
+
+type T[K comparable] struct{}
+
+func (t *T[K]) Get() {}
+
+func init() {}
+
+func f() { fmt.Println() }
+`)
+	want := map[string]string{
+		"a":     "const a = 1",
+		"b":     "const b, _ = 2, 3",
+		"T":     "type T[K comparable] struct{}",
+		"T.Get": "func (t *T[K]) Get() {}",
+		"f":     "func f() { fmt.Println() }",
+	}
+	got := declarationSources("config.go", src)
+	if len(got) != len(want) {
+		t.Errorf("declarationSources = %q, want %q", got, want)
+	}
+	for name, decl := range want {
+		if got[name] != decl {
+			t.Errorf("%s = %q, want %q", name, got[name], decl)
+		}
+	}
+}
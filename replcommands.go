@@ -0,0 +1,264 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// replCommand runs input if it's one of the REPL's colon commands, such
+// as :vars, which work in both modes
+func (m model) replCommand(input string) (output string, ok bool) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], ":") {
+		return "", false
+	}
+
+	switch fields[0] {
+	case ":save", ":load", ":export":
+		return m.sessionCommand(fields[0][1:], fields[1:]), true
+	case ":reset":
+		if len(fields) > 2 || (len(fields) == 2 && fields[1] != "--no-config") {
+			return "Usage: :reset [--no-config]", true
+		}
+		return m.resetInterpreter(len(fields) == 1), true
+	case ":vars":
+		return m.evaluator.describeVars(), true
+	case ":funcs":
+		return m.evaluator.describeFuncs(), true
+	case ":type":
+		expr := strings.TrimSpace(strings.TrimPrefix(input, ":type"))
+		if expr == "" {
+			return "Usage: :type EXPR", true
+		}
+		return m.evaluator.describeType(expr), true
+	case ":doc":
+		if len(fields) == 1 {
+			return "Usage: :doc [PACKAGE.]SYMBOL", true
+		}
+		return goDoc(m.state, fields[1:]), true
+	case ":goroutines":
+		return describeGoroutines(), true
+	case ":stop":
+		return stopGoroutine(fields[1:]), true
+	}
+	return "", false
+}
+
+// describeVars lists the variables and constants defined in the session
+// and config files with their types and values
+func (g *GoEvaluator) describeVars() string {
+	g.evalMu.Lock()
+	globals := NewSymbolExtractor(g.interp).GetGlobals()
+	g.evalMu.Unlock()
+	if len(globals) == 0 {
+		return "No variables defined"
+	}
+
+	nameWidth, typeWidth := 0, 0
+	for _, global := range globals {
+		nameWidth = max(nameWidth, len(global.Name))
+		typeWidth = max(typeWidth, len(global.Type))
+	}
+	lines := make([]string, len(globals))
+	for i, global := range globals {
+		lines[i] = fmt.Sprintf("%-*s  %-*s  %s", nameWidth, global.Name, typeWidth, global.Type, truncateCell(global.Value, 60))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// describeFuncs lists the functions defined by the config files and in
+// the session with their signatures, grouped by where they came from
+func (g *GoEvaluator) describeFuncs() string {
+	g.evalMu.Lock()
+	defer g.evalMu.Unlock()
+
+	extractor := NewSymbolExtractor(g.interp)
+	groups := map[string][]string{}
+	for name, origin := range g.funcOrigins {
+		value, err := g.interp.Eval(name)
+		if err != nil || value.Kind() != reflect.Func {
+			// Since replaced by something else
+			continue
+		}
+		signature := strings.TrimPrefix(extractor.getFunctionSignature(value), "func")
+		groups[origin] = append(groups[origin], name+signature)
+	}
+	if len(groups) == 0 {
+		return "No functions defined"
+	}
+
+	var sections []string
+	for _, origin := range []string{"home config", "project config", "session"} {
+		if funcs := groups[origin]; len(funcs) > 0 {
+			sort.Strings(funcs)
+			sections = append(sections, origin+":\n  "+strings.Join(funcs, "\n  "))
+		}
+	}
+	return strings.Join(sections, "\n\n")
+}
+
+// describeType evaluates expr and reports its type rather than its value:
+// the type yaegi gives it and, for interfaces, the type of the value held.
+// yaegi hands out some values, such as function literals, wrapped in a
+// *interface {}, which is unwrapped the same way results are.
+func (g *GoEvaluator) describeType(expr string) string {
+	expr = g.processCommandSubstitutions(expr)
+
+	g.evalMu.Lock()
+	defer g.evalMu.Unlock()
+
+	var value reflect.Value
+	var err error
+	// What evaluating it prints isn't shown either
+	g.output.capture(nil, func() {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("yaegi evaluation panic: %v", r)
+			}
+		}()
+		value, err = g.interp.Eval(expr)
+	})
+	if err != nil {
+		return err.Error()
+	}
+	if !value.IsValid() {
+		return "no value"
+	}
+
+	description := value.Type().String()
+	if value.Kind() == reflect.Ptr && description == "*interface {}" {
+		description += " (yaegi wrapper)"
+		if value.IsNil() {
+			return description
+		}
+		value = value.Elem()
+	}
+	if value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return description + "\ndynamic type: nil"
+		}
+		return description + "\ndynamic type: " + value.Elem().Type().String()
+	}
+	return description
+}
+
+// goDoc returns what go doc says about args, run in the working
+// directory so the packages of the project there are found too
+func goDoc(state *ShellState, args []string) string {
+	goCommand, found := FindInPath("go", state.Environment["PATH"])
+	if !found {
+		return ":doc: needs the go command"
+	}
+	cmd := exec.Command(goCommand, append([]string{"doc"}, args...)...)
+	cmd.Dir = state.WorkingDirectory
+	cmd.Env = state.EnvironmentSlice()
+	output, err := cmd.CombinedOutput()
+	text := strings.TrimRight(string(output), "\n")
+	if err != nil && text == "" {
+		return fmt.Sprintf(":doc: %v", err)
+	}
+	return text
+}
+
+// editFinishedMsg reports that the editor :edit opened file in has exited
+type editFinishedMsg struct {
+	file string
+	err  error
+}
+
+// editSnippet opens snippet, the lines typed before :edit, in a .go file
+// in $VISUAL or $EDITOR, with the cursor on its last line. What's saved
+// is evaluated as Go when the editor exits.
+func (m model) editSnippet(snippet string) (tea.Model, tea.Cmd) {
+	file, err := os.CreateTemp("", "gosh-edit-*.go")
+	if err != nil {
+		m.output = fmt.Sprintf(":edit: %v", err)
+		return m, nil
+	}
+	_, err = file.WriteString(snippet)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		m.output = fmt.Sprintf(":edit: %v", err)
+		return m, nil
+	}
+
+	cmd := editorCommand(m.state.Environment, file.Name(), strings.Count(snippet, "\n")+1, 0)
+	cmd.Dir = m.state.WorkingDirectory
+	cmd.Env = m.state.EnvironmentSlice()
+	m.output = ""
+	m.marks = ""
+	return m, tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return editFinishedMsg{file: file.Name(), err: err}
+	})
+}
+
+// finishEdit runs the code saved by the editor :edit opened, as a Go
+// block whichever mode the shell is in. Nothing is run if the editor
+// failed or the file was left empty.
+func (m model) finishEdit(msg editFinishedMsg) (tea.Model, tea.Cmd) {
+	content, err := os.ReadFile(msg.file)
+	os.Remove(msg.file)
+	switch {
+	case msg.err != nil:
+		m.output = fmt.Sprintf(":edit: %v", msg.err)
+		return m, nil
+	case err != nil:
+		m.output = fmt.Sprintf(":edit: %v", err)
+		return m, nil
+	}
+
+	code := strings.TrimSpace(string(content))
+	if code == "" {
+		return m, nil
+	}
+	m.running = code
+	return m, m.runBlock(":go\n" + code)
+}
+
+// lastGoBlock returns the most recent Go block of history, or the most
+// recent that failed if failed is set
+func lastGoBlock(history []HistoryBlock, failed bool) (HistoryBlock, bool) {
+	for i := len(history) - 1; i >= 0; i-- {
+		if block := history[i]; block.Mode == ModeGo && (!failed || block.ExitCode != 0) {
+			return block, true
+		}
+	}
+	return HistoryBlock{}, false
+}
+
+// runAgain evaluates the last Go block again, for :again
+func (m model) runAgain() (tea.Model, tea.Cmd) {
+	block, ok := lastGoBlock(m.session.History, false)
+	if !ok {
+		m.output = ":again: no Go block to run"
+		return m, nil
+	}
+	m.running = block.Input
+	return m, m.runBlock(":go\n" + block.Input)
+}
+
+// fixLast puts the last Go block that failed back in the input, to edit
+// and run again, for :fix
+func (m model) fixLast() model {
+	block, ok := lastGoBlock(m.session.History, true)
+	if !ok {
+		m.output = ":fix: no failed Go block"
+		return m
+	}
+	m.output = ""
+	m.marks = ""
+	m.textarea.SetValue(historyInput(block, m.session.Mode))
+	m.textarea.CursorEnd()
+	return m
+}
@@ -0,0 +1,271 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Result display formats for Go evaluation results
+const (
+	ResultFormatTable = "table" // Slices of structs/maps as tables, other composites pretty-printed
+	ResultFormatJSON  = "json"  // Indented JSON
+	ResultFormatGo    = "go"    // Plain %v
+)
+
+// Table layout limits
+const (
+	tableMaxColumnWidth = 40
+	tableMinColumnWidth = 3
+	tableMaxRows        = 200
+	tableDefaultWidth   = 120
+)
+
+// formatResultAs renders an evaluation result in the given format
+func formatResultAs(v reflect.Value, format string, width int) string {
+	switch format {
+	case ResultFormatJSON:
+		// Scalars keep their plain form so strings aren't shown quoted
+		switch v.Kind() {
+		case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct, reflect.Ptr:
+			if data, err := json.MarshalIndent(v.Interface(), "", "  "); err == nil {
+				return string(data)
+			}
+		}
+	case ResultFormatTable, "":
+		if table, ok := renderTable(v, width); ok {
+			return table
+		}
+		if isPrettyPrinted(v) {
+			return prettyValue(v)
+		}
+	}
+	return formatResult(v)
+}
+
+// renderTable renders a slice or array whose elements are structs or
+// string-keyed maps as an aligned table. It reports false for anything else.
+func renderTable(v reflect.Value, width int) (string, bool) {
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return "", false
+	}
+	if v.Len() == 0 {
+		return "", false
+	}
+	if width <= 0 {
+		width = tableDefaultWidth
+	}
+
+	rows := make([]reflect.Value, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		elem := indirectValue(v.Index(i))
+		if elem.Kind() != reflect.Struct && !(elem.Kind() == reflect.Map && elem.Type().Key().Kind() == reflect.String) {
+			return "", false
+		}
+		rows = append(rows, elem)
+	}
+
+	columns := tableColumns(rows)
+	if len(columns) == 0 {
+		return "", false
+	}
+
+	shown := rows
+	if len(shown) > tableMaxRows {
+		shown = shown[:tableMaxRows]
+	}
+
+	// Cell text, with the row index as the first column
+	header := append([]string{"#"}, columns...)
+	cells := make([][]string, len(shown))
+	for i, row := range shown {
+		cells[i] = append([]string{fmt.Sprint(i)}, tableRowCells(row, columns)...)
+	}
+
+	widths := make([]int, len(header))
+	for c, name := range header {
+		widths[c] = lipgloss.Width(name)
+		for _, row := range cells {
+			if w := lipgloss.Width(row[c]); w > widths[c] {
+				widths[c] = w
+			}
+		}
+		if widths[c] > tableMaxColumnWidth {
+			widths[c] = tableMaxColumnWidth
+		}
+	}
+	fitColumnWidths(widths, width)
+
+	colors := GetColorManager()
+	var sb strings.Builder
+	writeRow := func(values []string, style func(string) string) {
+		parts := make([]string, len(values))
+		for c, value := range values {
+			parts[c] = style(padCell(truncateCell(value, widths[c]), widths[c]))
+		}
+		sb.WriteString(strings.TrimRight(strings.Join(parts, "  "), " "))
+		sb.WriteString("\n")
+	}
+
+	writeRow(header, func(s string) string { return colors.StyleOutput(s, "info") })
+	for _, row := range cells {
+		writeRow(row, func(s string) string { return s })
+	}
+
+	if hidden := len(rows) - len(shown); hidden > 0 {
+		sb.WriteString(fmt.Sprintf("... %d more rows\n", hidden))
+	}
+	sb.WriteString(separatorStyle.Render(fmt.Sprintf("(%d rows)", len(rows))))
+
+	return sb.String(), true
+}
+
+// indirectValue unwraps interfaces and pointers
+func indirectValue(v reflect.Value) reflect.Value {
+	for (v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr) && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v
+}
+
+// tableColumns returns exported struct fields in declaration order, or the
+// sorted union of map keys
+func tableColumns(rows []reflect.Value) []string {
+	seen := make(map[string]bool)
+	var columns []string
+	var mapKeys []string
+
+	for _, row := range rows {
+		switch row.Kind() {
+		case reflect.Struct:
+			t := row.Type()
+			for i := 0; i < t.NumField(); i++ {
+				field := t.Field(i)
+				if field.IsExported() && !seen[field.Name] {
+					seen[field.Name] = true
+					columns = append(columns, field.Name)
+				}
+			}
+		case reflect.Map:
+			for _, key := range row.MapKeys() {
+				if name := key.String(); !seen[name] {
+					seen[name] = true
+					mapKeys = append(mapKeys, name)
+				}
+			}
+		}
+	}
+
+	sort.Strings(mapKeys)
+	return append(columns, mapKeys...)
+}
+
+// tableRowCells formats one row's values for the given columns
+func tableRowCells(row reflect.Value, columns []string) []string {
+	cells := make([]string, len(columns))
+	for i, column := range columns {
+		var value reflect.Value
+		switch row.Kind() {
+		case reflect.Struct:
+			value = row.FieldByName(column)
+		case reflect.Map:
+			value = row.MapIndex(reflect.ValueOf(column).Convert(row.Type().Key()))
+		}
+
+		if !value.IsValid() {
+			continue
+		}
+		value = indirectValue(value)
+		if !value.IsValid() || ((value.Kind() == reflect.Interface || value.Kind() == reflect.Ptr) && value.IsNil()) {
+			cells[i] = "<nil>"
+			continue
+		}
+		if !value.CanInterface() {
+			continue
+		}
+		text := fmt.Sprint(value.Interface())
+		cells[i] = strings.Join(strings.Fields(text), " ")
+	}
+	return cells
+}
+
+// fitColumnWidths shrinks the widest columns until the row fits in width
+func fitColumnWidths(widths []int, width int) {
+	total := func() int {
+		sum := 2 * (len(widths) - 1)
+		for _, w := range widths {
+			sum += w
+		}
+		return sum
+	}
+
+	for total() > width {
+		widest := 0
+		for i, w := range widths {
+			if w > widths[widest] {
+				widest = i
+			}
+		}
+		if widths[widest] <= tableMinColumnWidth {
+			return
+		}
+		widths[widest]--
+	}
+}
+
+// truncateCell shortens s to width display columns, marking the cut with …
+func truncateCell(s string, width int) string {
+	if lipgloss.Width(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && lipgloss.Width(string(runes))+1 > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
+
+func padCell(s string, width int) string {
+	if pad := width - lipgloss.Width(s); pad > 0 {
+		return s + strings.Repeat(" ", pad)
+	}
+	return s
+}
+
+// format implements the format builtin
+func (b *BuiltinHandler) format(args []string) ExecutionResult {
+	if len(args) == 0 {
+		return ExecutionResult{Output: fmt.Sprintf("Result format: %s", b.state.ResultFormat()), ExitCode: 0}
+	}
+
+	switch args[0] {
+	case ResultFormatTable, ResultFormatJSON, ResultFormatGo:
+		b.state.resultFormat = args[0]
+		return ExecutionResult{Output: fmt.Sprintf("Result format set to %s", args[0]), ExitCode: 0}
+	default:
+		return ExecutionResult{
+			Output:   fmt.Sprintf("format: unknown format: %s (use table, json or go)", args[0]),
+			ExitCode: 1,
+			Error:    fmt.Errorf("unknown format: %s", args[0]),
+		}
+	}
+}
+
+const formatHelpText = "format - Go Result Display Format\n\n" +
+	"USAGE:\n" +
+	"    format [table|json|go]\n" +
+	"    :format [table|json|go]     (also works in Go mode)\n\n" +
+	"DESCRIPTION:\n" +
+	"    Choose how Go evaluation results are shown:\n\n" +
+	"    table   Slices of structs or maps as aligned tables, with long\n" +
+	"            columns truncated to fit the terminal, and other structs,\n" +
+	"            maps and slices indented (default)\n" +
+	"    json    Indented JSON\n" +
+	"    go      Go's %v formatting\n\n" +
+	"    Config files can choose the format with gosh.ResultFormat(\"go\")."
@@ -0,0 +1,112 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type tableTestRow struct {
+	Name  string
+	Count int
+	notes string
+}
+
+func TestRenderTable_Structs(t *testing.T) {
+	rows := []tableTestRow{{"alpha", 1, "x"}, {"beta", 22, "y"}}
+
+	table, ok := renderTable(reflect.ValueOf(rows), 80)
+	if !ok {
+		t.Fatal("Expected a table for a slice of structs")
+	}
+
+	lines := strings.Split(stripANSI(table), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected header, 2 rows and a footer, got %q", table)
+	}
+	if !strings.Contains(lines[0], "Name") || !strings.Contains(lines[0], "Count") || strings.Contains(lines[0], "notes") {
+		t.Errorf("Unexpected header: %q", lines[0])
+	}
+	if strings.Index(lines[1], "alpha") != strings.Index(lines[2], "beta") {
+		t.Errorf("Columns should be aligned:\n%s", table)
+	}
+	if !strings.Contains(lines[3], "2 rows") {
+		t.Errorf("Expected a row count footer, got %q", lines[3])
+	}
+}
+
+func TestRenderTable_MapsAndTruncation(t *testing.T) {
+	rows := []any{
+		map[string]any{"id": 1, "desc": strings.Repeat("long ", 30)},
+		map[string]any{"id": 2, "extra": true},
+	}
+
+	table, ok := renderTable(reflect.ValueOf(rows), 40)
+	if !ok {
+		t.Fatal("Expected a table for a slice of maps")
+	}
+
+	for _, line := range strings.Split(stripANSI(table), "\n") {
+		if w := len([]rune(line)); w > 40 {
+			t.Errorf("Line exceeds width limit (%d): %q", w, line)
+		}
+	}
+	if !strings.Contains(table, "…") {
+		t.Error("Long cells should be truncated with an ellipsis")
+	}
+	if header := strings.Split(stripANSI(table), "\n")[0]; !strings.Contains(header, "desc") || !strings.Contains(header, "extra") {
+		t.Errorf("Header should contain the union of keys: %q", header)
+	}
+}
+
+func TestRenderTable_NotTabular(t *testing.T) {
+	for _, v := range []any{[]int{1, 2}, []string{}, "text", map[string]int{"a": 1}} {
+		if _, ok := renderTable(reflect.ValueOf(v), 80); ok {
+			t.Errorf("%#v should not render as a table", v)
+		}
+	}
+}
+
+func TestFormatResultAs(t *testing.T) {
+	v := reflect.ValueOf([]map[string]int{{"a": 1}})
+
+	if got := formatResultAs(v, ResultFormatGo, 80); got != "[map[a:1]]" {
+		t.Errorf("go format = %q", got)
+	}
+	if got := formatResultAs(v, ResultFormatJSON, 80); !strings.Contains(got, "\"a\": 1") {
+		t.Errorf("json format = %q", got)
+	}
+	if got := formatResultAs(reflect.ValueOf("plain"), ResultFormatJSON, 80); got != "plain" {
+		t.Errorf("Strings should stay unquoted in json format, got %q", got)
+	}
+}
+
+func TestFormatBuiltinAndEval(t *testing.T) {
+	state := NewShellState()
+	b := NewBuiltinHandler(state)
+	evaluator := NewGoEvaluator()
+	evaluator.SetupWithShell(state, NewProcessSpawner(state))
+
+	result := evaluator.Eval(`[]struct{ Name string; Age int }{{"ada", 36}, {"alan", 41}}`)
+	if !strings.Contains(stripANSI(result.Output), "Name") || !strings.Contains(result.Output, "(2 rows)") {
+		t.Errorf("Expected a table by default, got %q", result.Output)
+	}
+
+	if r := b.Execute("format", []string{"yaml"}); r.ExitCode == 0 {
+		t.Error("Unknown formats should be rejected")
+	}
+
+	b.Execute("format", []string{"json"})
+	result = evaluator.Eval(`[]struct{ Name string }{{"ada"}}`)
+	if !strings.Contains(result.Output, `"Name": "ada"`) {
+		t.Errorf("Expected JSON output, got %q", result.Output)
+	}
+
+	b.Execute("format", []string{"go"})
+	result = evaluator.Eval(`[]struct{ Name string }{{"ada"}}`)
+	if result.Output != "[{ada}]" {
+		t.Errorf("Expected Go formatting, got %q", result.Output)
+	}
+}
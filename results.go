@@ -0,0 +1,136 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/traefik/yaegi/interp"
+)
+
+// Results shown in the REPL are kept in variables, as in Python's: _1, or
+// just _, is the last, _2 the one before, and so on up to keptResults.
+// The interpreter gets each from the goshresults package, whose Last
+// variable is set to it, keeping its type.
+
+// keptResults is how many results are kept
+const keptResults = 10
+
+// resultsPackage is the package results are handed to the interpreter in
+const resultsPackage = "goshresults/goshresults"
+
+// useResult makes value the goshresults package's Last
+func useResult(i *interp.Interpreter, value reflect.Value) error {
+	last := reflect.New(value.Type()).Elem()
+	last.Set(value)
+	return i.Use(interp.Exports{resultsPackage: {"Last": last}})
+}
+
+// keepResult makes value, a result just shown, _1, moving the earlier
+// results down
+func (g *GoEvaluator) keepResult(value reflect.Value) {
+	if !value.IsValid() || !value.CanInterface() {
+		return
+	}
+	if err := useResult(g.interp, value); err != nil {
+		debugf("Warning: failed to keep result: %v\n", err)
+		return
+	}
+
+	// Only results there are move, since naming an undefined variable
+	// leaves yaegi with it half defined. Each is a statement of its own, as
+	// yaegi makes the new _1 before running any in one evaluation.
+	var statements []string
+	for n := min(g.results, keptResults-1); n >= 1; n-- {
+		statements = append(statements, fmt.Sprintf("_%d := _%d", n+1, n))
+	}
+	statements = append(statements, "_1 := goshresults.Last")
+	for _, statement := range statements {
+		if _, err := g.interp.Eval(statement); err != nil {
+			debugf("Warning: failed to keep result: %v\n", err)
+			return
+		}
+	}
+	g.results = min(g.results+1, keptResults)
+}
+
+// lastResultRefs rewrites the uses of _ as a value in code, which Go
+// doesn't allow, as _1, the last result. The blank identifier where it
+// stands for something discarded is left as it is. Code that doesn't
+// parse is returned as it is.
+func lastResultRefs(code string) string {
+	if !strings.Contains(code, "_") {
+		return code
+	}
+
+	// Parsed the way yaegi will run it
+	prefix, suffix := "", ""
+	switch interpPrefix(code) {
+	case len("package main;"):
+		prefix = "package main;"
+	case len("package main; func main() {"):
+		prefix, suffix = "package main; func main() {", "\n}"
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", prefix+code+suffix, 0)
+	if err != nil {
+		return code
+	}
+
+	blank := map[*ast.Ident]bool{}
+	mark := func(idents ...*ast.Ident) {
+		for _, ident := range idents {
+			blank[ident] = true
+		}
+	}
+	markExprs := func(exprs ...ast.Expr) {
+		for _, expr := range exprs {
+			if ident, ok := expr.(*ast.Ident); ok {
+				blank[ident] = true
+			}
+		}
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			markExprs(n.Lhs...)
+		case *ast.RangeStmt:
+			markExprs(n.Key, n.Value)
+		case *ast.ValueSpec:
+			mark(n.Names...)
+		case *ast.Field:
+			mark(n.Names...)
+		case *ast.ImportSpec:
+			mark(n.Name)
+		case *ast.TypeSpec:
+			mark(n.Name)
+		case *ast.FuncDecl:
+			mark(n.Name)
+		}
+		return true
+	})
+
+	var offsets []int
+	ast.Inspect(file, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && ident.Name == "_" && !blank[ident] {
+			offsets = append(offsets, fset.Position(ident.Pos()).Offset-len(prefix))
+		}
+		return true
+	})
+	if len(offsets) == 0 {
+		return code
+	}
+
+	// From the end, so the offsets before stay right
+	sort.Sort(sort.Reverse(sort.IntSlice(offsets)))
+	for _, offset := range offsets {
+		code = code[:offset] + "_1" + code[offset+1:]
+	}
+	return code
+}
@@ -0,0 +1,65 @@
+//go:build darwin || linux
+
+package main
+
+import "testing"
+
+func TestResultVariables(t *testing.T) {
+	g := NewGoEvaluator()
+
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{`strings.Fields("a b c")`, `["a", "b", "c"]`},
+		{`len(_)`, "3"},
+		{`_2[0]`, "a"},
+		{`_3[1] + _1`, "ba"},
+		// Assignments don't make results, and _ still discards
+		{`n := _ + "!"`, ""},
+		{`_, err := strconv.Atoi(n)`, ""},
+		{`for _, s := range _4 { n += s }`, ""},
+		{`n`, "ba!abc"},
+		{`_1 == _`, "true"},
+	}
+	for _, tt := range tests {
+		if result := g.Eval(tt.code); result.ExitCode != 0 || result.Output != tt.expected {
+			t.Errorf("Eval(%q) = %q (exit %d), want %q", tt.code, result.Output, result.ExitCode, tt.expected)
+		}
+	}
+
+	// Only so many are kept
+	for i := 0; i < keptResults+2; i++ {
+		g.Eval("1")
+	}
+	if g.results != keptResults {
+		t.Errorf("Kept %d results, want %d", g.results, keptResults)
+	}
+
+	if err := g.Reset(false); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	if result := g.Eval(`_1`); result.ExitCode == 0 {
+		t.Errorf("Expected no results after a reset, got %q", result.Output)
+	}
+}
+
+func TestLastResultRefs(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{"_ + f(_)", "_1 + f(_1)"},
+		{"_, b := f(_)", "_, b := f(_1)"},
+		{"for _, v := range _ { _ = v }", "for _, v := range _1 { _ = v }"},
+		{"func f(_ int) int { return _ }", "func f(_ int) int { return _1 }"},
+		{"var _ = _", "var _ = _1"},
+		{"x_y + _z", "x_y + _z"},
+		{"_ +", "_ +"},
+	}
+	for _, tt := range tests {
+		if got := lastResultRefs(tt.code); got != tt.expected {
+			t.Errorf("lastResultRefs(%q) = %q, want %q", tt.code, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// rgiMaxFallbackMatches caps the numbered list shown when fzf isn't installed
+const rgiMaxFallbackMatches = 50
+
+// rgMatch is a single ripgrep hit in --vimgrep format
+type rgMatch struct {
+	File   string
+	Line   int
+	Column int
+	Text   string
+}
+
+// parseRgMatch parses "file:line:column:text" as printed by rg --vimgrep
+func parseRgMatch(line string) (rgMatch, bool) {
+	parts := strings.SplitN(stripANSI(line), ":", 4)
+	if len(parts) < 3 {
+		return rgMatch{}, false
+	}
+
+	lineNo, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return rgMatch{}, false
+	}
+	column, err := strconv.Atoi(parts[2])
+	if err != nil {
+		column = 1
+	}
+
+	match := rgMatch{File: parts[0], Line: lineNo, Column: column}
+	if len(parts) == 4 {
+		match.Text = parts[3]
+	}
+	return match, true
+}
+
+// rgi implements interactive grep: ripgrep results are filtered live in fzf
+// (re-running rg as the query changes) and the chosen match is opened in
+// $EDITOR at the matching line
+func (b *BuiltinHandler) rgi(args []string) ExecutionResult {
+	if len(args) == 0 {
+		return ExecutionResult{Output: "Usage: rgi PATTERN [PATH...]", ExitCode: 1, Error: fmt.Errorf("missing pattern")}
+	}
+
+	pathEnv := b.state.Environment["PATH"]
+	rgPath, found := FindInPath("rg", pathEnv)
+	if !found {
+		return ExecutionResult{Output: "rgi: ripgrep (rg) is not installed", ExitCode: 127, Error: fmt.Errorf("rg not found")}
+	}
+
+	pattern := args[0]
+	paths := args[1:]
+
+	var selection string
+	var err error
+	if fzfPath, ok := FindInPath("fzf", pathEnv); ok {
+		selection, err = b.rgiWithFzf(fzfPath, rgPath, pattern, paths)
+	} else {
+		selection, err = b.rgiWithList(rgPath, pattern, paths)
+	}
+	if err != nil {
+		return ExecutionResult{Output: fmt.Sprintf("rgi: %v", err), ExitCode: 1, Error: err}
+	}
+
+	match, ok := parseRgMatch(selection)
+	if !ok {
+		// Nothing selected
+		return ExecutionResult{Output: "", ExitCode: 0}
+	}
+
+	cmd := editorCommand(b.state.Environment, match.File, match.Line, match.Column)
+	cmd.Dir = b.state.WorkingDirectory
+	cmd.Env = b.state.EnvironmentSlice()
+	if err := runOnTerminal(cmd); err != nil {
+		return ExecutionResult{Output: fmt.Sprintf("rgi: failed to open editor: %v", err), ExitCode: 1, Error: err}
+	}
+
+	return ExecutionResult{Output: "", ExitCode: 0}
+}
+
+// rgiWithFzf uses fzf's reload binding so rg re-runs on every query change
+func (b *BuiltinHandler) rgiWithFzf(fzfPath, rgPath, pattern string, paths []string) (string, error) {
+	rgCommand := shellQuote(rgPath) + " --vimgrep --color=always --smart-case -- "
+	quotedPaths := ""
+	for _, p := range paths {
+		quotedPaths += " " + shellQuote(p)
+	}
+
+	cmd := exec.Command(fzfPath,
+		"--ansi", "--disabled",
+		"--query", pattern,
+		"--delimiter", ":",
+		"--prompt", "rg> ",
+		"--bind", "change:reload:"+rgCommand+"{q}"+quotedPaths+" || true",
+		"--preview-window", "hidden",
+	)
+	cmd.Dir = b.state.WorkingDirectory
+	cmd.Env = append(b.state.EnvironmentSlice(), "FZF_DEFAULT_COMMAND="+rgCommand+shellQuote(pattern)+quotedPaths)
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	err := runOnTerminal(cmd)
+	if exitErr, ok := err.(*exec.ExitError); ok && (exitErr.ExitCode() == 1 || exitErr.ExitCode() == 130) {
+		return "", nil
+	}
+	return strings.TrimSpace(out.String()), err
+}
+
+// rgiWithList is the fallback without fzf: show numbered matches and ask
+func (b *BuiltinHandler) rgiWithList(rgPath, pattern string, paths []string) (string, error) {
+	rgArgs := append([]string{"--vimgrep", "--smart-case", "--", pattern}, paths...)
+	cmd := exec.Command(rgPath, rgArgs...)
+	cmd.Dir = b.state.WorkingDirectory
+	cmd.Env = b.state.EnvironmentSlice()
+	output, err := cmd.Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		return "", fmt.Errorf("no matches for %q", pattern)
+	} else if err != nil {
+		return "", err
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) > rgiMaxFallbackMatches {
+		lines = lines[:rgiMaxFallbackMatches]
+	}
+
+	var selection string
+	err = withTerminal(func() error {
+		for i, line := range lines {
+			fmt.Printf("%3d  %s\n", i+1, line)
+		}
+		fmt.Print("Open match # (enter to cancel): ")
+
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		n, convErr := strconv.Atoi(strings.TrimSpace(answer))
+		if convErr == nil && n >= 1 && n <= len(lines) {
+			selection = lines[n-1]
+		}
+		return nil
+	})
+
+	return selection, err
+}
+
+// shellQuote single-quotes s for safe use in a /bin/sh command line
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// stripANSI removes SGR color sequences such as those from rg --color=always
+func stripANSI(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\033' && i+1 < len(s) && s[i+1] == '[' {
+			j := i + 2
+			for j < len(s) && (s[j] < 0x40 || s[j] > 0x7e) {
+				j++
+			}
+			i = j
+			continue
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+const rgiHelpText = "rgi - Interactive Grep\n\n" +
+	"USAGE:\n" +
+	"    rgi PATTERN [PATH...]\n\n" +
+	"DESCRIPTION:\n" +
+	"    Search with ripgrep and pick a match to open in $EDITOR at the\n" +
+	"    matching line. With fzf installed, results update live as you edit\n" +
+	"    the query; otherwise a numbered list of matches is shown.\n\n" +
+	"EXAMPLES:\n" +
+	"    rgi TODO              # Find TODOs and jump to one\n" +
+	"    rgi 'func main' cmd/  # Search only under cmd/"
@@ -0,0 +1,55 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRgMatch(t *testing.T) {
+	match, ok := parseRgMatch("\033[35mmain.go\033[0m:12:5:func main() {")
+	if !ok {
+		t.Fatal("Expected match to parse")
+	}
+	if match.File != "main.go" || match.Line != 12 || match.Column != 5 || match.Text != "func main() {" {
+		t.Errorf("Unexpected match: %+v", match)
+	}
+
+	if _, ok := parseRgMatch(""); ok {
+		t.Error("Empty selection should not parse")
+	}
+	if _, ok := parseRgMatch("file:notanumber:1:x"); ok {
+		t.Error("Invalid line number should not parse")
+	}
+}
+
+func TestEditorCommand(t *testing.T) {
+	tests := []struct {
+		editor   string
+		expected string
+	}{
+		{"vim", "vim +12 main.go"},
+		{"nvim -p", "nvim -p +12 main.go"},
+		{"code --wait", "code --wait -g main.go:12:3"},
+		{"", "vi +12 main.go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.editor, func(t *testing.T) {
+			cmd := editorCommand(map[string]string{"EDITOR": tt.editor}, "main.go", 12, 3)
+			got := strings.Join(append([]string{cmd.Args[0]}, cmd.Args[1:]...), " ")
+			if got != tt.expected {
+				t.Errorf("editorCommand(%q) = %q, expected %q", tt.editor, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBuiltinRgi_MissingPattern(t *testing.T) {
+	handler := NewBuiltinHandler(&ShellState{Environment: map[string]string{}})
+	result := handler.Execute("rgi", nil)
+	if result.ExitCode == 0 || result.Error == nil {
+		t.Error("rgi without a pattern should fail")
+	}
+}
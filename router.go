@@ -3,6 +3,8 @@
 package main
 
 import (
+	"fmt"
+	"os"
 	"strings"
 )
 
@@ -21,57 +23,125 @@ func NewRouter(builtins *BuiltinHandler, state *ShellState) *Router {
 
 // Route for shell mode only - determines if input is a builtin or command
 func (r *Router) Route(input string) (InputType, string, []string) {
-	input = strings.TrimSpace(input)
-	if input == "" {
-		return InputTypeCommand, "", nil
+	inputType, cmd, _ := r.RouteCommand(input)
+	return inputType, cmd.Name, cmd.Args
+}
+
+// RouteCommand parses input into a command with its redirections, after
+// expanding any alias, and determines whether it's a builtin or an
+// external command
+func (r *Router) RouteCommand(input string) (InputType, ShellCommand, error) {
+	words, err := r.splitWords(strings.TrimSpace(input))
+	if err != nil {
+		return InputTypeCommand, ShellCommand{}, err
+	}
+	cmd, err := commandFromWords(words)
+	if err != nil || cmd.Name == "" {
+		return InputTypeCommand, cmd, err
+	}
+	return r.Classify(cmd), cmd, nil
+}
+
+// ParseCommandList parses a command line into a command list, expanding
+// the aliases at the start of each command
+func (r *Router) ParseCommandList(input string) ([]ChainedCommand, error) {
+	words, err := r.splitWords(input)
+	if err != nil {
+		return nil, err
 	}
+	return commandListFromWords(words)
+}
+
+// splitWords splits input into words and expands aliases. Aliases are
+// expanded before anything else, so their values are quoted and expanded
+// like the rest of the line.
+func (r *Router) splitWords(input string) ([]shellWord, error) {
+	words, err := splitShellWords(input)
+	if err != nil || r.state == nil {
+		return words, err
+	}
+	return r.state.expandAliases(words)
+}
 
-	command, args := r.parseInput(input)
+// Classify determines whether a parsed command is a builtin or an external
+// command
+func (r *Router) Classify(cmd ShellCommand) InputType {
+	// Check for builtins first. rm --really is the system rm, so that
+	// its redirections apply as they would to any command.
+	if r.builtins.IsBuiltin(cmd.Name) {
+		if _, really := withoutReally(cmd.Args); cmd.Name == "rm" && really {
+			return InputTypeCommand
+		}
+		return InputTypeBuiltin
+	}
 
-	// Check for builtins first
-	if r.builtins.IsBuiltin(command) {
-		return InputTypeBuiltin, command, args
+	// With autocd on, a directory that isn't also a command is cd'd into
+	if r.isAutoCd(cmd) {
+		return InputTypeAutoCd
 	}
 
 	// Otherwise treat as shell command
-	return InputTypeCommand, command, args
+	return InputTypeCommand
 }
 
-func (r *Router) parseInput(input string) (string, []string) {
-	var args []string
-	var current strings.Builder
-	inQuote := false
-	quoteChar := rune(0)
+// isAutoCd reports whether cmd is just the name of a directory, to change
+// into with autocd on. A command of the same name in PATH runs instead.
+func (r *Router) isAutoCd(cmd ShellCommand) bool {
+	if r.state == nil || !r.state.autoCd {
+		return false
+	}
+	if len(cmd.Args) > 0 || len(cmd.Redirects) > 0 || cmd.Background || cmd.Subshell != nil || cmd.GoFunc != "" {
+		return false
+	}
+	if !strings.Contains(cmd.Name, "/") && r.state.commandPath(cmd.Name) != cmd.Name {
+		return false
+	}
+	info, err := os.Stat(r.state.ExpandPath(cmd.Name))
+	return err == nil && info.IsDir()
+}
+
+// runCommandList runs a command list with run, skipping commands whose &&
+// or || condition fails; commands after ; always run. Each command's words
+// are expanded just before it runs, so a glob sees files made by an earlier
+// command. Like other shells, the exit code is that of the last command
+// that ran, and the output of every command is kept. Nothing runs after
+// the exit builtin.
+func runCommandList(state *ShellState, list []ChainedCommand, run func(ShellCommand) ExecutionResult) ExecutionResult {
+	var output strings.Builder
+	var last ExecutionResult
 
-	for i, char := range input {
-		switch {
-		case (char == '"' || char == '\'') && (i == 0 || input[i-1] != '\\'):
-			if !inQuote {
-				inQuote = true
-				quoteChar = char
-			} else if char == quoteChar {
-				inQuote = false
-				quoteChar = 0
-			} else {
-				current.WriteRune(char)
+	for _, cmd := range list {
+		// Nothing runs after exit
+		if state.ShouldExit {
+			break
+		}
+		if (cmd.Op == "&&" && last.ExitCode != 0) || (cmd.Op == "||" && last.ExitCode == 0) {
+			continue
+		}
+		if expanded, err := cmd.Expand(state); err != nil {
+			last = ExecutionResult{Output: fmt.Sprintf("gosh: %v", err), ExitCode: 1, Error: err}
+		} else {
+			last = run(expanded)
+			// A background command may still be reading its process
+			// substitutions, which then finish on their own
+			if !expanded.Background {
+				if stderr := expanded.releaseSubstitutions(); stderr != "" {
+					if last.Output != "" && !strings.HasSuffix(last.Output, "\n") {
+						last.Output += "\n"
+					}
+					last.Output += stderr
+				}
 			}
-		case char == ' ' && !inQuote:
-			if current.Len() > 0 {
-				args = append(args, current.String())
-				current.Reset()
+		}
+		state.LastExitCode = last.ExitCode
+		if last.Output != "" {
+			if output.Len() > 0 && !strings.HasSuffix(output.String(), "\n") {
+				output.WriteString("\n")
 			}
-		default:
-			current.WriteRune(char)
+			output.WriteString(last.Output)
 		}
 	}
 
-	if current.Len() > 0 {
-		args = append(args, current.String())
-	}
-
-	if len(args) == 0 {
-		return "", nil
-	}
-
-	return args[0], args[1:]
+	last.Output = output.String()
+	return last
 }
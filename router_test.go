@@ -3,6 +3,8 @@
 package main
 
 import (
+	"os"
+	"reflect"
 	"testing"
 )
 
@@ -71,8 +73,8 @@ func TestRouter_ShellCommandRouting(t *testing.T) {
 			expected: InputTypeCommand,
 		},
 		{
-			name:     "echo command",
-			input:    "echo hello",
+			name:     "grep command",
+			input:    "grep hello notes.txt",
 			expected: InputTypeCommand,
 		},
 		{
@@ -130,6 +132,99 @@ func TestRouter_EdgeCaseRouting(t *testing.T) {
 	}
 }
 
+// Test && and || short-circuiting
+func TestRunCommandList(t *testing.T) {
+	tests := []struct {
+		input    string
+		ran      []string
+		exitCode int
+	}{
+		{"true && echo", []string{"true", "echo"}, 0},
+		{"false && echo", []string{"false"}, 1},
+		{"false || echo", []string{"false", "echo"}, 0},
+		{"true || echo", []string{"true"}, 0},
+		{"false && echo || pwd", []string{"false", "pwd"}, 0},
+		{"true || echo && pwd", []string{"true", "pwd"}, 0},
+		{"false || false", []string{"false", "false"}, 1},
+		{"false; echo", []string{"false", "echo"}, 0},
+		{"echo; false", []string{"echo", "false"}, 1},
+		{"false && echo; pwd", []string{"false", "pwd"}, 0},
+		{"true || echo; false || pwd", []string{"true", "false", "pwd"}, 0},
+	}
+
+	state := &ShellState{WorkingDirectory: t.TempDir()}
+	for _, tt := range tests {
+		list, err := parseCommandList(tt.input)
+		if err != nil {
+			t.Fatalf("parseCommandList(%q) error: %v", tt.input, err)
+		}
+
+		var ran []string
+		result := runCommandList(state, list, func(cmd ShellCommand) ExecutionResult {
+			ran = append(ran, cmd.Name)
+			if cmd.Name == "false" {
+				return ExecutionResult{ExitCode: 1}
+			}
+			return ExecutionResult{Output: cmd.Name, ExitCode: 0}
+		})
+
+		if !reflect.DeepEqual(ran, tt.ran) || result.ExitCode != tt.exitCode {
+			t.Errorf("%q ran %v (exit %d), want %v (exit %d)", tt.input, ran, result.ExitCode, tt.ran, tt.exitCode)
+		}
+	}
+}
+
+func TestRunCommandList_CombinesOutput(t *testing.T) {
+	state := &ShellState{WorkingDirectory: t.TempDir(), Environment: map[string]string{"PATH": os.Getenv("PATH")}}
+	spawner := NewProcessSpawner(state)
+
+	list, _ := parseCommandList("echo one && printf two && echo three")
+	if result := runCommandList(state, list, spawner.Run); result.Output != "one\ntwo\nthree\n" || result.ExitCode != 0 {
+		t.Errorf("Unexpected result %q (exit %d)", result.Output, result.ExitCode)
+	}
+}
+
 // Note: In the new architecture, Go code routing is not tested here because
 // mode is explicit (:go/:sh commands). Go code is sent directly to the
 // evaluator when in Go mode, not routed through this router.
+
+func TestRouter_AutoCd(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(dir+"/project", 0755)
+	os.Mkdir(dir+"/ls", 0755)
+	os.WriteFile(dir+"/notes.txt", nil, 0644)
+
+	state := &ShellState{WorkingDirectory: dir, Environment: map[string]string{"PATH": os.Getenv("PATH"), "HOME": dir}}
+	router := NewRouter(NewBuiltinHandler(state), state)
+
+	tests := []struct {
+		input    string
+		expected InputType
+	}{
+		{"project", InputTypeAutoCd},
+		{"./project", InputTypeAutoCd},
+		{"~/project", InputTypeAutoCd},
+		{"..", InputTypeAutoCd},
+		{"ls", InputTypeCommand},
+		{"project arg", InputTypeCommand},
+		{"notes.txt", InputTypeCommand},
+		{"missing", InputTypeCommand},
+		{"cd", InputTypeBuiltin},
+	}
+
+	if inputType, _, _ := router.RouteCommand("project"); inputType != InputTypeCommand {
+		t.Errorf("Expected autocd to be off by default, got %v", inputType)
+	}
+
+	state.autoCd = true
+	for _, tt := range tests {
+		list, err := router.ParseCommandList(tt.input)
+		if err != nil {
+			t.Fatalf("ParseCommandList(%q) error: %v", tt.input, err)
+		}
+		cmd, _ := list[0].Expand(state)
+		if got := router.Classify(cmd); got != tt.expected {
+			t.Errorf("Classify(%q) = %v, want %v", tt.input, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ScheduledTask is a function registered with gosh.Every
+type ScheduledTask struct {
+	ID        int
+	Name      string
+	Interval  time.Duration
+	Paused    bool
+	Runs      int
+	LastRun   time.Time
+	LastError string
+
+	fn   func()
+	stop chan struct{}
+}
+
+// TaskRunner runs a task function and returns what it printed
+type TaskRunner func(fn func()) (string, error)
+
+// TaskScheduler runs config-defined tasks in the background of an
+// interactive session. Task output is queued and shown before the next
+// prompt rather than drawn over whatever the user is doing.
+type TaskScheduler struct {
+	mu      sync.Mutex
+	tasks   map[int]*ScheduledTask
+	nextID  int
+	queue   []string
+	runner  TaskRunner
+	started bool
+}
+
+// Smallest allowed interval, to keep a typo from pinning the CPU
+const minTaskInterval = time.Second
+
+func NewTaskScheduler() *TaskScheduler {
+	return &TaskScheduler{tasks: make(map[int]*ScheduledTask), nextID: 1}
+}
+
+// SetRunner sets how task functions are executed (normally through the
+// Go evaluator so their output is captured)
+func (s *TaskScheduler) SetRunner(runner TaskRunner) {
+	s.mu.Lock()
+	s.runner = runner
+	s.mu.Unlock()
+}
+
+// Every registers fn to run every interval ("30s", "5m", "1h")
+func (s *TaskScheduler) Every(interval string, fn func()) (int, error) {
+	d, err := time.ParseDuration(interval)
+	if err != nil {
+		return 0, fmt.Errorf("invalid interval %q: %w", interval, err)
+	}
+	if d < minTaskInterval {
+		return 0, fmt.Errorf("interval %s is shorter than %s", d, minTaskInterval)
+	}
+	if fn == nil {
+		return 0, fmt.Errorf("task function is nil")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task := &ScheduledTask{
+		ID:       s.nextID,
+		Name:     fmt.Sprintf("every %s", d),
+		Interval: d,
+		fn:       fn,
+	}
+	s.nextID++
+	s.tasks[task.ID] = task
+
+	if s.started {
+		s.startTask(task)
+	}
+	return task.ID, nil
+}
+
+// Start begins running the registered tasks. Tasks only run in interactive
+// sessions, so `gosh -c` never starts the scheduler.
+func (s *TaskScheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return
+	}
+	s.started = true
+	for _, task := range s.tasks {
+		s.startTask(task)
+	}
+}
+
+// Stop halts all tasks
+func (s *TaskScheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.started = false
+	for _, task := range s.tasks {
+		if task.stop != nil {
+			close(task.stop)
+			task.stop = nil
+		}
+	}
+}
+
+// startTask launches the ticker goroutine for task; s.mu must be held
+func (s *TaskScheduler) startTask(task *ScheduledTask) {
+	stop := make(chan struct{})
+	task.stop = stop
+
+	go func() {
+		ticker := time.NewTicker(task.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				s.mu.Lock()
+				paused := task.Paused
+				s.mu.Unlock()
+				if !paused {
+					s.run(task)
+				}
+			}
+		}
+	}()
+}
+
+// run executes task once and queues its output
+func (s *TaskScheduler) run(task *ScheduledTask) {
+	s.mu.Lock()
+	runner := s.runner
+	s.mu.Unlock()
+
+	var output string
+	var err error
+	if runner != nil {
+		output, err = runner(task.fn)
+	} else {
+		task.fn()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task.Runs++
+	task.LastRun = time.Now()
+	task.LastError = ""
+	if err != nil {
+		task.LastError = err.Error()
+		s.queue = append(s.queue, fmt.Sprintf("[task %d] error: %v", task.ID, err))
+	}
+	if output = strings.TrimRight(output, "\n"); output != "" {
+		s.queue = append(s.queue, fmt.Sprintf("[task %d] %s", task.ID, output))
+	}
+}
+
+// RunNow runs a task immediately, outside its schedule
+func (s *TaskScheduler) RunNow(id int) error {
+	s.mu.Lock()
+	task, ok := s.tasks[id]
+	s.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("no task %d", id)
+	}
+	s.run(task)
+	return nil
+}
+
+// SetPaused pauses or resumes a task
+func (s *TaskScheduler) SetPaused(id int, paused bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.tasks[id]
+	if !ok {
+		return fmt.Errorf("no task %d", id)
+	}
+	task.Paused = paused
+	return nil
+}
+
+// Remove stops and forgets a task
+func (s *TaskScheduler) Remove(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.tasks[id]
+	if !ok {
+		return fmt.Errorf("no task %d", id)
+	}
+	if task.stop != nil {
+		close(task.stop)
+	}
+	delete(s.tasks, id)
+	return nil
+}
+
+// List returns copies of the registered tasks ordered by ID
+func (s *TaskScheduler) List() []ScheduledTask {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tasks := make([]ScheduledTask, 0, len(s.tasks))
+	for _, task := range s.tasks {
+		copied := *task
+		copied.fn = nil
+		copied.stop = nil
+		tasks = append(tasks, copied)
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	return tasks
+}
+
+// DrainOutput returns and clears the queued task output
+func (s *TaskScheduler) DrainOutput() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queued := s.queue
+	s.queue = nil
+	return queued
+}
+
+// tasks implements the tasks builtin
+func (b *BuiltinHandler) tasks(args []string) ExecutionResult {
+	scheduler := b.state.Scheduler()
+
+	if len(args) == 0 || args[0] == "list" {
+		tasks := scheduler.List()
+		if len(tasks) == 0 {
+			return ExecutionResult{Output: "No scheduled tasks (register them with gosh.Every in config.go)", ExitCode: 0}
+		}
+
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("%-4s %-16s %-8s %6s %-10s %s", "ID", "NAME", "STATUS", "RUNS", "LAST RUN", "LAST ERROR"))
+		for _, task := range tasks {
+			status := "active"
+			if task.Paused {
+				status = "paused"
+			}
+			lastRun := "never"
+			if !task.LastRun.IsZero() {
+				lastRun = task.LastRun.Format("15:04:05")
+			}
+			sb.WriteString(fmt.Sprintf("\n%-4d %-16s %-8s %6d %-10s %s", task.ID, task.Name, status, task.Runs, lastRun, task.LastError))
+		}
+		return ExecutionResult{Output: sb.String(), ExitCode: 0}
+	}
+
+	sub := args[0]
+	if len(args) < 2 {
+		return ExecutionResult{Output: fmt.Sprintf("tasks: usage: tasks %s ID|all", sub), ExitCode: 1, Error: fmt.Errorf("missing task id")}
+	}
+
+	var ids []int
+	if args[1] == "all" {
+		for _, task := range scheduler.List() {
+			ids = append(ids, task.ID)
+		}
+	} else {
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			return ExecutionResult{Output: fmt.Sprintf("tasks: invalid task id: %s", args[1]), ExitCode: 1, Error: err}
+		}
+		ids = []int{id}
+	}
+
+	var action func(id int) error
+	var verb string
+	switch sub {
+	case "pause":
+		action, verb = func(id int) error { return scheduler.SetPaused(id, true) }, "Paused"
+	case "resume":
+		action, verb = func(id int) error { return scheduler.SetPaused(id, false) }, "Resumed"
+	case "remove", "rm":
+		action, verb = scheduler.Remove, "Removed"
+	case "run":
+		action, verb = scheduler.RunNow, "Ran"
+	default:
+		return ExecutionResult{
+			Output:   fmt.Sprintf("tasks: unknown subcommand: %s (try list, pause, resume, run or remove)", sub),
+			ExitCode: 1,
+			Error:    fmt.Errorf("unknown subcommand: %s", sub),
+		}
+	}
+
+	for _, id := range ids {
+		if err := action(id); err != nil {
+			return ExecutionResult{Output: fmt.Sprintf("tasks: %v", err), ExitCode: 1, Error: err}
+		}
+	}
+
+	output := fmt.Sprintf("%s %d task(s)", verb, len(ids))
+	if sub == "run" {
+		if queued := scheduler.DrainOutput(); len(queued) > 0 {
+			output = strings.Join(queued, "\n")
+		}
+	}
+	return ExecutionResult{Output: output, ExitCode: 0}
+}
+
+const tasksHelpText = "tasks - Scheduled Background Tasks\n\n" +
+	"USAGE:\n" +
+	"    tasks [list]\n" +
+	"    tasks pause|resume|run|remove ID|all\n\n" +
+	"DESCRIPTION:\n" +
+	"    List and control the tasks registered in config.go with\n" +
+	"    gosh.Every. Tasks run in the background of interactive sessions;\n" +
+	"    anything they print is shown before the next prompt.\n\n" +
+	"EXAMPLE (config.go):\n" +
+	"    gosh.Every(\"5m\", func() {\n" +
+	"        if out, _ := shellapi.RunShell(\"git\", \"fetch\", \"-q\"); out != \"\" {\n" +
+	"            fmt.Println(out)\n" +
+	"        }\n" +
+	"    })"
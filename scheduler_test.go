@@ -0,0 +1,123 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTaskScheduler_EveryValidatesInterval(t *testing.T) {
+	s := NewTaskScheduler()
+
+	if _, err := s.Every("soon", func() {}); err == nil {
+		t.Error("Expected an error for an unparseable interval")
+	}
+	if _, err := s.Every("10ms", func() {}); err == nil {
+		t.Error("Expected an error for an interval under a second")
+	}
+	if id, err := s.Every("5m", func() {}); err != nil || id != 1 {
+		t.Errorf("Expected task 1, got %d, %v", id, err)
+	}
+}
+
+func TestTaskScheduler_RunQueuesOutput(t *testing.T) {
+	s := NewTaskScheduler()
+	s.SetRunner(func(fn func()) (string, error) {
+		fn()
+		return "fetched 3 commits\n", nil
+	})
+
+	ran := 0
+	id, _ := s.Every("1m", func() { ran++ })
+	if err := s.RunNow(id); err != nil {
+		t.Fatalf("RunNow failed: %v", err)
+	}
+
+	if ran != 1 {
+		t.Errorf("Expected the task to run once, ran %d times", ran)
+	}
+	queued := s.DrainOutput()
+	if len(queued) != 1 || queued[0] != "[task 1] fetched 3 commits" {
+		t.Errorf("Unexpected queued output: %q", queued)
+	}
+	if len(s.DrainOutput()) != 0 {
+		t.Error("Expected the queue to be empty after draining")
+	}
+
+	tasks := s.List()
+	if len(tasks) != 1 || tasks[0].Runs != 1 || tasks[0].LastRun.IsZero() {
+		t.Errorf("Expected run bookkeeping to be updated, got %+v", tasks)
+	}
+}
+
+func TestTaskScheduler_RunnerErrorIsRecorded(t *testing.T) {
+	s := NewTaskScheduler()
+	s.SetRunner(func(fn func()) (string, error) {
+		return "", fmt.Errorf("task panic: boom")
+	})
+
+	id, _ := s.Every("1m", func() {})
+	s.RunNow(id)
+
+	if tasks := s.List(); tasks[0].LastError != "task panic: boom" {
+		t.Errorf("Expected the error to be recorded, got %q", tasks[0].LastError)
+	}
+	if queued := s.DrainOutput(); len(queued) != 1 || !strings.Contains(queued[0], "boom") {
+		t.Errorf("Expected the error to be queued, got %q", queued)
+	}
+}
+
+func TestTasksBuiltin(t *testing.T) {
+	state := &ShellState{Environment: map[string]string{}}
+	builtins := NewBuiltinHandler(state)
+
+	result := builtins.Execute("tasks", nil)
+	if result.ExitCode != 0 || !strings.Contains(result.Output, "No scheduled tasks") {
+		t.Errorf("Expected the empty message, got %q", result.Output)
+	}
+
+	state.Scheduler().Every("5m", func() {})
+	state.Scheduler().Every("1h", func() {})
+
+	if result := builtins.Execute("tasks", []string{"pause", "2"}); result.ExitCode != 0 {
+		t.Fatalf("tasks pause failed: %s", result.Output)
+	}
+	list := builtins.Execute("tasks", nil).Output
+	if !strings.Contains(list, "every 5m0s") || !strings.Contains(list, "paused") {
+		t.Errorf("Expected both tasks with task 2 paused, got:\n%s", list)
+	}
+
+	if result := builtins.Execute("tasks", []string{"remove", "all"}); result.ExitCode != 0 {
+		t.Fatalf("tasks remove all failed: %s", result.Output)
+	}
+	if len(state.Scheduler().List()) != 0 {
+		t.Error("Expected all tasks to be removed")
+	}
+
+	if result := builtins.Execute("tasks", []string{"pause", "9"}); result.ExitCode != 1 {
+		t.Error("Expected pausing an unknown task to fail")
+	}
+}
+
+func TestGoEvaluator_RunTaskCapturesOutput(t *testing.T) {
+	g := NewGoEvaluator()
+	// Tasks are functions of the session, printing through the interpreter
+	g.interp.Eval(`import "fmt"`)
+	g.interp.Eval(`func helloTask() { fmt.Println("hello from a task") }`)
+	v, err := g.interp.Eval("helloTask")
+	if err != nil {
+		t.Fatalf("Eval error: %v", err)
+	}
+	task := unwrapInterface(v).Interface().(func())
+
+	output, err := g.runTask(task)
+	if err != nil || output != "hello from a task\n" {
+		t.Errorf("Expected captured output, got %q, %v", output, err)
+	}
+
+	if _, err := g.runTask(func() { panic("boom") }); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("Expected a panic to become an error, got %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SetArgs sets the positional parameters: name is $0 and args are $1 and
+// on. Go code in interpreters made from then on sees them as os.Args.
+func (s *ShellState) SetArgs(name string, args []string) {
+	s.scriptName = name
+	s.args = args
+	os.Args = append([]string{name}, args...)
+}
+
+// positional returns the positional parameter $n, or "" if there's none
+func (s *ShellState) positional(n int) string {
+	if n == 0 {
+		if s.scriptName == "" {
+			return "gosh"
+		}
+		return s.scriptName
+	}
+	if n > len(s.args) {
+		return ""
+	}
+	return s.args[n-1]
+}
+
+// readScript returns the code of the script at path, without a #! line,
+// and whether it's Go code, as files ending in .go are
+func readScript(path string) (string, bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, err
+	}
+	code := string(data)
+	if strings.HasPrefix(code, "#!") {
+		_, code, _ = strings.Cut(code, "\n")
+	}
+	return code, filepath.Ext(path) == ".go", nil
+}
+
+// runNonInteractive runs command for gosh -c or a script, and exits with
+// its exit code: as Go code if goCode is set, and as a command list
+// otherwise. name is $0 and args are $1 and on.
+func runNonInteractive(name, command string, goCode bool, args []string) {
+	state := NewShellState()
+	// Before the interpreter is made, which takes its os.Args from gosh's
+	state.SetArgs(name, args)
+	evaluator := NewGoEvaluator()
+	spawner := NewProcessSpawner(state)
+	builtins := NewBuiltinHandler(state)
+
+	evaluator.SetupWithShell(state, spawner)
+	evaluator.SetupWithBuiltins(builtins)
+
+	if err := evaluator.LoadConfig(); err != nil {
+		fmt.Fprintf(os.Stderr, "Config loading error: %v\n", err)
+	}
+	state.RunChpwdHooks("", state.WorkingDirectory)
+
+	// exit runs the EXIT trap on the way out
+	exit := func(exitCode int) {
+		if output := builtins.runExitTrap(); output != "" {
+			fmt.Println(output)
+		}
+		os.Exit(exitCode)
+	}
+	setupSignals(builtins, exit)
+
+	if goCode {
+		result := evaluator.Eval(command)
+		fmt.Print(result.Output)
+		exit(result.ExitCode)
+	}
+
+	list, err := parseCommandList(command)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gosh: %v\n", err)
+		exit(2)
+	}
+	// runIn returns the function that runs a command in state: the
+	// script's, or a subshell's copy of it
+	var runIn func(state *ShellState) func(ShellCommand) ExecutionResult
+	runIn = func(shell *ShellState) func(ShellCommand) ExecutionResult {
+		builtins, spawner := builtins, spawner
+		if shell != state {
+			builtins, spawner = builtins.withState(shell), spawner.withState(shell)
+		}
+		router := NewRouter(builtins, shell)
+
+		return func(cmd ShellCommand) ExecutionResult {
+			switch {
+			case cmd.GoFunc != "":
+				stream := cmd.Subshell == nil && router.Classify(cmd) != InputTypeBuiltin
+				return runGoPipe(evaluator, spawner, cmd, stream, runIn(shell))
+			case cmd.Subshell != nil:
+				return runSubshell(shell, cmd, runIn)
+			case !cmd.Background && router.Classify(cmd) == InputTypeBuiltin:
+				return runBuiltin(builtins, shell, cmd)
+			}
+			return spawner.Run(cmd)
+		}
+	}
+
+	// Output is written as it's produced, and so are each command's
+	// messages, to keep them in order
+	router := NewRouter(builtins, state)
+	run := runIn(state)
+	result := runCommandList(state, list, func(cmd ShellCommand) ExecutionResult {
+		var result ExecutionResult
+		if cmd.GoFunc == "" && cmd.Subshell == nil && (cmd.Background || router.Classify(cmd) != InputTypeBuiltin) {
+			result = spawner.Stream(cmd, os.Stdout)
+		} else {
+			result = run(cmd)
+		}
+		if result.Output != "" {
+			fmt.Print(result.Output)
+			if !strings.HasSuffix(result.Output, "\n") && !result.partialLine {
+				fmt.Println()
+			}
+			result.Output = ""
+		}
+		return result
+	})
+	fmt.Print(result.Output)
+	exit(result.ExitCode)
+}
@@ -0,0 +1,55 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadScript(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "deploy"), []byte("#!/usr/bin/env gosh\necho $1\n"), 0755)
+	os.WriteFile(filepath.Join(dir, "report.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
+
+	tests := []struct {
+		name   string
+		code   string
+		goCode bool
+	}{
+		{"deploy", "echo $1\n", false},
+		{"report.go", "package main\n\nfunc main() {}\n", true},
+	}
+	for _, tt := range tests {
+		code, goCode, err := readScript(filepath.Join(dir, tt.name))
+		if err != nil || code != tt.code || goCode != tt.goCode {
+			t.Errorf("readScript(%q) = %q, %v, %v; want %q, %v", tt.name, code, goCode, err, tt.code, tt.goCode)
+		}
+	}
+
+	if _, _, err := readScript(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected an error for a missing script")
+	}
+}
+
+func TestRunNonInteractive_Subshell(t *testing.T) {
+	// Run as gosh -c by the test below, in a process of its own
+	if command := os.Getenv("GOSH_TEST_COMMAND"); command != "" {
+		runNonInteractive("gosh", command, false, nil)
+	}
+
+	dir, _ := filepath.EvalSymlinks(t.TempDir())
+	sub := filepath.Join(dir, "sub")
+	os.Mkdir(sub, 0755)
+
+	// cd is a builtin in the subshell too, changing only its directory
+	cmd := exec.Command(os.Args[0], "-test.run=^TestRunNonInteractive_Subshell$")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "HOME="+dir, "GOSH_TEST_COMMAND=(cd sub && pwd); pwd")
+	output, err := cmd.CombinedOutput()
+	if want := sub + "\n" + dir + "\n"; err != nil || string(output) != want {
+		t.Errorf("gosh -c = %q (%v), want %q", output, err, want)
+	}
+}
@@ -68,8 +68,8 @@ func (s *SessionState) saveHistory() error {
 
 func (s *SessionState) AddHistory(block HistoryBlock) {
 	s.History = append(s.History, block)
-	if len(s.History) > 1000 {
-		s.History = s.History[len(s.History)-1000:]
+	if len(s.History) > maxHistory {
+		s.History = s.History[len(s.History)-maxHistory:]
 	}
 	s.saveHistory()
 }
@@ -0,0 +1,353 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// sessionBlockMarker separates the blocks of a saved session file
+const sessionBlockMarker = "//gosh:block"
+
+// declaresState reports whether code changes what later code can use:
+// it imports packages, declares functions, types, variables or constants,
+// or assigns to variables. Blocks that only print or call things aren't
+// worth replaying when a session is loaded.
+func declaresState(code string) bool {
+	if file, err := parser.ParseFile(token.NewFileSet(), "", "package main\n"+code, 0); err == nil {
+		return len(file.Decls) > 0
+	}
+
+	file, err := parser.ParseFile(token.NewFileSet(), "", "package main\nfunc _() {\n"+code+"\n}", 0)
+	if err != nil {
+		return false
+	}
+	for _, stmt := range file.Decls[0].(*ast.FuncDecl).Body.List {
+		switch stmt.(type) {
+		case *ast.AssignStmt, *ast.DeclStmt, *ast.IncDecStmt:
+			return true
+		}
+	}
+	return false
+}
+
+// recordDeclaration remembers code, which evaluated successfully, for the
+// session file if it declares something. The caller holds evalMu.
+func (g *GoEvaluator) recordDeclaration(code string) {
+	if declaresState(code) {
+		g.declarations = append(g.declarations, strings.TrimSpace(code))
+	}
+	for _, name := range declaredFuncs(code) {
+		g.funcOrigins[name] = "session"
+		// Calls go to the new definition rather than the config's
+		delete(g.configFuncs, name)
+	}
+}
+
+// declaredFuncs returns the names of the functions, not methods, that
+// code declares at package level
+func declaredFuncs(code string) []string {
+	file, err := parser.ParseFile(token.NewFileSet(), "", "package main\n"+code, 0)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil {
+			names = append(names, fn.Name.Name)
+		}
+	}
+	return names
+}
+
+// SaveSession writes the successful declarations of the session to path,
+// returning how many there were
+func (g *GoEvaluator) SaveSession(path string) (int, error) {
+	g.evalMu.Lock()
+	blocks := append([]string(nil), g.declarations...)
+	g.evalMu.Unlock()
+
+	var sb strings.Builder
+	sb.WriteString("// gosh session, restored with :load. Each block is evaluated in turn.\n")
+	for _, block := range blocks {
+		sb.WriteString("\n" + sessionBlockMarker + "\n" + block + "\n")
+	}
+	if err := os.WriteFile(path, []byte(sb.String()), 0644); err != nil {
+		return 0, err
+	}
+	return len(blocks), nil
+}
+
+// LoadSession evaluates the blocks of a session file saved by SaveSession,
+// returning how many succeeded. Blocks that fail are reported in the error
+// but don't stop the rest.
+func (g *GoEvaluator) LoadSession(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	loaded := 0
+	var failures []string
+	for i, block := range strings.Split(string(data), "\n"+sessionBlockMarker+"\n") {
+		// The first part is the file's header
+		if i == 0 || strings.TrimSpace(block) == "" {
+			continue
+		}
+		if result := g.Eval(block); result.ExitCode != 0 {
+			failures = append(failures, fmt.Sprintf("block %d: %s", i, result.Output))
+			continue
+		}
+		loaded++
+	}
+	if len(failures) > 0 {
+		return loaded, fmt.Errorf("%s", strings.Join(failures, "\n"))
+	}
+	return loaded, nil
+}
+
+// sessionCommand runs :save, :load or :export with args, resolving the
+// file against the working directory. The file defaults to session.gosh,
+// or main.go for :export, which won't overwrite a file.
+func (m model) sessionCommand(command string, args []string) string {
+	if len(args) > 1 {
+		return fmt.Sprintf("Usage: :%s [FILE]", command)
+	}
+	path := "session.gosh"
+	if command == "export" {
+		path = "main.go"
+	}
+	if len(args) == 1 {
+		path = expandTilde(m.state, args[0])
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(m.state.WorkingDirectory, path)
+	}
+
+	switch command {
+	case "export":
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Sprintf(":export: %s already exists", path)
+		}
+		count, err := m.evaluator.ExportSession(path)
+		if err != nil {
+			return fmt.Sprintf(":export: %v", err)
+		}
+		return fmt.Sprintf("Exported %d declarations to %s", count, path)
+	case "save":
+		count, err := m.evaluator.SaveSession(path)
+		if err != nil {
+			return fmt.Sprintf(":save: %v", err)
+		}
+		return fmt.Sprintf("Saved %d declarations to %s", count, path)
+	}
+
+	count, err := m.evaluator.LoadSession(path)
+	if err != nil && count == 0 {
+		return fmt.Sprintf(":load: %v", err)
+	}
+	output := fmt.Sprintf("Loaded %d declarations from %s", count, path)
+	if err != nil {
+		output += "\n" + err.Error()
+	}
+	return output
+}
+
+// exportDecl is a package-level declaration of an exported program
+type exportDecl struct {
+	key  string // What it declares, so a later definition replaces it
+	text string
+}
+
+// exportProgram turns the declarations of a session into the source of a
+// main package. Functions, types, constants and variables go at package
+// level, := declarations becoming var declarations, and the assignments
+// that followed them go in main, or in init if the session defined main.
+// Later definitions of a name replace earlier ones, as they did in the
+// REPL.
+func exportProgram(blocks []string, packages map[string]string) ([]byte, error) {
+	var decls []exportDecl
+	var body []string
+	imports := map[string]string{} // Package name to import spec
+	used := map[string]bool{}      // Names used as pkg in pkg.Name
+
+	addDecl := func(key, text string) {
+		for i, decl := range decls {
+			if decl.key == key {
+				decls = append(decls[:i], decls[i+1:]...)
+				break
+			}
+		}
+		decls = append(decls, exportDecl{key: key, text: text})
+	}
+	noteSelectors := func(node ast.Node) {
+		ast.Inspect(node, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if ident, ok := sel.X.(*ast.Ident); ok {
+					used[ident.Name] = true
+				}
+			}
+			return true
+		})
+	}
+
+	for _, block := range blocks {
+		src := "package main\n" + block
+		fset := token.NewFileSet()
+		if file, err := parser.ParseFile(fset, "", src, 0); err == nil {
+			for _, spec := range file.Imports {
+				path := strings.Trim(spec.Path.Value, "\"`")
+				name := filepath.Base(path)
+				if spec.Name != nil {
+					name = spec.Name.Name
+				}
+				imports[name] = src[fset.Position(spec.Pos()).Offset:fset.Position(spec.End()).Offset]
+			}
+			for _, decl := range file.Decls {
+				if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+					continue
+				}
+				noteSelectors(decl)
+				addDecl(declKey(decl), src[fset.Position(decl.Pos()).Offset:fset.Position(decl.End()).Offset])
+			}
+			continue
+		}
+
+		src = "package main\nfunc _() {\n" + block + "\n}"
+		file, err := parser.ParseFile(fset, "", src, 0)
+		if err != nil {
+			return nil, fmt.Errorf("can't export %q: %v", block, err)
+		}
+		text := func(node ast.Node) string {
+			return src[fset.Position(node.Pos()).Offset:fset.Position(node.End()).Offset]
+		}
+		for _, stmt := range file.Decls[0].(*ast.FuncDecl).Body.List {
+			noteSelectors(stmt)
+			switch stmt := stmt.(type) {
+			case *ast.DeclStmt:
+				addDecl(declKey(stmt.Decl), text(stmt.Decl))
+			case *ast.AssignStmt:
+				if names, ok := definedNames(stmt); ok {
+					rhs := make([]string, len(stmt.Rhs))
+					for i, expr := range stmt.Rhs {
+						rhs[i] = text(expr)
+					}
+					addDecl("var "+strings.Join(names, ", "), "var "+strings.Join(names, ", ")+" = "+strings.Join(rhs, ", "))
+					continue
+				}
+				body = append(body, text(stmt))
+			default:
+				body = append(body, text(stmt))
+			}
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("// Exported from a gosh session\n\npackage main\n\n")
+	var specs []string
+	for name, spec := range imports {
+		if used[name] || strings.HasPrefix(spec, "_ ") || strings.HasPrefix(spec, ". ") {
+			specs = append(specs, spec)
+		}
+	}
+	for name, path := range packages {
+		if _, imported := imports[name]; used[name] && !imported {
+			specs = append(specs, strconv.Quote(path))
+		}
+	}
+	sort.Strings(specs)
+	switch {
+	case len(specs) == 1:
+		sb.WriteString("import " + specs[0] + "\n\n")
+	case len(specs) > 1:
+		sb.WriteString("import (\n\t" + strings.Join(specs, "\n\t") + "\n)\n\n")
+	}
+	hasMain := false
+	for _, decl := range decls {
+		hasMain = hasMain || decl.key == "func main"
+		sb.WriteString(decl.text + "\n\n")
+	}
+	switch {
+	case !hasMain:
+		sb.WriteString("func main() {\n" + strings.Join(body, "\n") + "\n}\n")
+	case len(body) > 0:
+		sb.WriteString("func init() {\n" + strings.Join(body, "\n") + "\n}\n")
+	}
+
+	formatted, err := format.Source([]byte(sb.String()))
+	if err != nil {
+		return nil, fmt.Errorf("can't format the exported program: %v", err)
+	}
+	return formatted, nil
+}
+
+// declKey identifies what decl declares: a function or method, or the
+// names of a type, variable or constant declaration
+func declKey(decl ast.Decl) string {
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		if decl.Recv != nil && len(decl.Recv.List) > 0 {
+			return fmt.Sprintf("func (%s) %s", types.ExprString(decl.Recv.List[0].Type), decl.Name.Name)
+		}
+		return "func " + decl.Name.Name
+	case *ast.GenDecl:
+		var names []string
+		for _, spec := range decl.Specs {
+			switch spec := spec.(type) {
+			case *ast.TypeSpec:
+				names = append(names, spec.Name.Name)
+			case *ast.ValueSpec:
+				for _, name := range spec.Names {
+					names = append(names, name.Name)
+				}
+			}
+		}
+		return decl.Tok.String() + " " + strings.Join(names, ", ")
+	}
+	return ""
+}
+
+// definedNames returns the variables a := statement declares, if all it
+// assigns to are plain names
+func definedNames(stmt *ast.AssignStmt) ([]string, bool) {
+	if stmt.Tok != token.DEFINE {
+		return nil, false
+	}
+	names := make([]string, len(stmt.Lhs))
+	for i, expr := range stmt.Lhs {
+		ident, ok := expr.(*ast.Ident)
+		if !ok {
+			return nil, false
+		}
+		names[i] = ident.Name
+	}
+	return names, true
+}
+
+// ExportSession writes the session's declarations to path as a main
+// package, returning how many blocks it was made from
+func (g *GoEvaluator) ExportSession(path string) (int, error) {
+	g.evalMu.Lock()
+	blocks := append([]string(nil), g.declarations...)
+	packages := g.packageNames()
+	g.evalMu.Unlock()
+
+	source, err := exportProgram(blocks, packages)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.WriteFile(path, source, 0644); err != nil {
+		return 0, err
+	}
+	return len(blocks), nil
+}
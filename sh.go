@@ -0,0 +1,123 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// shSource defines sh in the interpreter, so Go code can run a command
+// without importing anything
+const shSource = `func sh(name string, args ...string) (string, int, error) { return gosh.Sh(name, args...) }`
+
+// runSh runs a command in the shell's working directory and environment,
+// returning what it writes to stdout untouched, its exit code and an error
+// if it fails, which has what it wrote to stderr. A command that can't be
+// run exits with 127, as in other shells.
+func runSh(name string, args ...string) (string, int, error) {
+	cmd := shellCommand(name, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err == nil {
+		return stdout.String(), 0, nil
+	}
+	exitCode := 127
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	}
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		return stdout.String(), exitCode, fmt.Errorf("%s: %w: %s", name, err, msg)
+	}
+	return stdout.String(), exitCode, fmt.Errorf("%s: %w", name, err)
+}
+
+// shellCommand returns a command that runs as the shell would run it, in
+// its working directory and environment, for Go code
+func shellCommand(name string, args ...string) *exec.Cmd {
+	path, dir, env := name, "", os.Environ()
+	if state := goshAPIState(); state != nil {
+		// Variables the calling code set with os.Setenv count
+		state.importFromProcess()
+		path, dir, env = state.commandPath(name), state.WorkingDirectory, state.EnvironmentSlice()
+	}
+	cmd := exec.Command(path, args...)
+	// Programs see the name they were run by, as in other shells
+	cmd.Args[0] = name
+	cmd.Dir = dir
+	cmd.Env = env
+	return cmd
+}
+
+// shOutput is what $(...) in Go code gives for the results of sh: the
+// output without trailing newlines, as in other shells
+func shOutput(stdout string, exitCode int, err error) string {
+	return strings.TrimRight(stdout, "\n")
+}
+
+// commandSubstitutionCall returns the Go expression $(command) stands for.
+// A command of plain words is run directly; one with pipes, quotes that
+// need a shell, variables or nested substitutions runs with /bin/sh.
+func commandSubstitutionCall(command string) string {
+	args := []string{"/bin/sh", "-c", command}
+	if words, err := splitShellWords(command); err == nil && len(words) > 0 {
+		plain := make([]string, 0, len(words))
+		for _, word := range words {
+			// Pipes aren't operator words, as the router splits them off
+			if word.Operator || word.raw != "" || word.subshell != nil || (!word.quoted && strings.ContainsAny(word.Text, "|`")) {
+				plain = nil
+				break
+			}
+			plain = append(plain, word.Text)
+		}
+		if plain != nil {
+			args = plain
+		}
+	}
+
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = strconv.Quote(arg)
+	}
+	return "gosh.Output(sh(" + strings.Join(quoted, ", ") + "))"
+}
+
+// findGoCommandSubstitution is findCommandSubstitution for Go code, where
+// a $( in a string or rune literal or a comment isn't a substitution
+func findGoCommandSubstitution(code string, from int) (int, int) {
+	for i := from; i < len(code); i++ {
+		switch {
+		case strings.HasPrefix(code[i:], "$("):
+			return findCommandSubstitution(code, i)
+		case strings.HasPrefix(code[i:], "//"):
+			end := strings.IndexByte(code[i:], '\n')
+			if end == -1 {
+				return -1, -1
+			}
+			i += end
+		case strings.HasPrefix(code[i:], "/*"):
+			end := strings.Index(code[i+2:], "*/")
+			if end == -1 {
+				return -1, -1
+			}
+			i += end + 3
+		case code[i] == '"' || code[i] == '\'' || code[i] == '`':
+			quote := code[i]
+			for i++; i < len(code) && code[i] != quote; i++ {
+				if code[i] == '\\' && quote != '`' {
+					i++
+				}
+			}
+		}
+	}
+	return -1, -1
+}
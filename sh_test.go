@@ -0,0 +1,38 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSh(t *testing.T) {
+	state := NewShellState()
+	state.WorkingDirectory = t.TempDir()
+	eval := NewGoEvaluator()
+	eval.SetupWithShell(state, NewProcessSpawner(state))
+
+	if result := eval.Eval(`out, code, err := sh("pwd")`); result.ExitCode != 0 {
+		t.Fatalf("sh failed: %s", result.Output)
+	}
+	if result := eval.Eval(`out == gosh.Output(sh("pwd")) + "\n" && code == 0 && err == nil`); result.Output != "true" {
+		t.Errorf("Expected pwd's output and no error, got %q", result.Output)
+	}
+	if result := eval.Eval(`strings.TrimSpace(out)`); result.Output != state.WorkingDirectory {
+		t.Errorf("Expected sh to run in %s, got %q", state.WorkingDirectory, result.Output)
+	}
+
+	// Failures give the exit code and an error with what went to stderr
+	eval.Eval(`_, code, err = sh("sh", "-c", "echo oops >&2; exit 3")`)
+	if result := eval.Eval(`code`); result.Output != "3" {
+		t.Errorf("Expected exit code 3, got %q", result.Output)
+	}
+	if result := eval.Eval(`err.Error()`); !strings.Contains(result.Output, "oops") {
+		t.Errorf("Expected stderr in the error, got %q", result.Output)
+	}
+	eval.Eval(`_, code, err = sh("gosh-no-such-command")`)
+	if result := eval.Eval(`code`); result.Output != "127" {
+		t.Errorf("Expected exit code 127 for a missing command, got %q", result.Output)
+	}
+}
@@ -0,0 +1,148 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ShellIntegration emits the escape sequences editor terminals use to track
+// the shell: FinalTerm prompt/command marks (OSC 133), the VSCode flavour of
+// the same marks (OSC 633) and working directory reporting (OSC 7). With these
+// VSCode and Neovim can rerun the last command, decorate failed commands and
+// open new terminals in the current directory.
+type ShellIntegration struct {
+	enabled  bool
+	vscode   bool
+	hostname string
+}
+
+// NewShellIntegration detects whether we're running inside an integrated
+// terminal that understands the sequences. GOSH_SHELL_INTEGRATION=1 forces
+// them on for other terminals and GOSH_SHELL_INTEGRATION=0 turns them off.
+func NewShellIntegration(env map[string]string) *ShellIntegration {
+	hostname, _ := os.Hostname()
+
+	si := &ShellIntegration{
+		vscode:   env["TERM_PROGRAM"] == "vscode",
+		hostname: hostname,
+	}
+
+	switch strings.ToLower(env["GOSH_SHELL_INTEGRATION"]) {
+	case "0", "false", "off", "no":
+		si.enabled = false
+	case "1", "true", "on", "yes":
+		si.enabled = true
+	default:
+		// Neovim exports $NVIM to processes started in its terminal
+		si.enabled = si.vscode || env["NVIM"] != ""
+	}
+
+	return si
+}
+
+// Enabled reports whether sequences will be emitted
+func (si *ShellIntegration) Enabled() bool {
+	return si != nil && si.enabled
+}
+
+// PromptStart marks the beginning of the prompt
+func (si *ShellIntegration) PromptStart() string {
+	if !si.Enabled() {
+		return ""
+	}
+	if si.vscode {
+		return osc("633;A") + osc("133;A")
+	}
+	return osc("133;A")
+}
+
+// PromptEnd marks the end of the prompt and the start of user input
+func (si *ShellIntegration) PromptEnd() string {
+	if !si.Enabled() {
+		return ""
+	}
+	if si.vscode {
+		return osc("633;B") + osc("133;B")
+	}
+	return osc("133;B")
+}
+
+// CommandStart is emitted right before a block executes. VSCode additionally
+// receives the command line so "rerun last command" works, unless it's ""
+// because it holds secrets.
+func (si *ShellIntegration) CommandStart(commandLine string) string {
+	if !si.Enabled() {
+		return ""
+	}
+	if si.vscode && commandLine == "" {
+		return osc("633;C") + osc("133;C")
+	}
+	if si.vscode {
+		return osc("633;E;"+escapeVSCodeValue(commandLine)) + osc("633;C") + osc("133;C")
+	}
+	return osc("133;C")
+}
+
+// CommandFinished reports the exit code of the block that just ran
+func (si *ShellIntegration) CommandFinished(exitCode int) string {
+	if !si.Enabled() {
+		return ""
+	}
+	if si.vscode {
+		return osc(fmt.Sprintf("633;D;%d", exitCode)) + osc(fmt.Sprintf("133;D;%d", exitCode))
+	}
+	return osc(fmt.Sprintf("133;D;%d", exitCode))
+}
+
+// ReportCwd tells the terminal about the current working directory
+func (si *ShellIntegration) ReportCwd(dir string) string {
+	if !si.Enabled() || dir == "" {
+		return ""
+	}
+	seq := osc("7;file://" + si.hostname + encodeFileURLPath(dir))
+	if si.vscode {
+		seq += osc("633;P;Cwd=" + escapeVSCodeValue(dir))
+	}
+	return seq
+}
+
+// osc wraps a payload in an Operating System Command sequence terminated by ST
+func osc(payload string) string {
+	return "\033]" + payload + "\033\\"
+}
+
+// escapeVSCodeValue escapes values embedded in OSC 633 sequences: backslashes
+// are doubled, and semicolons, spaces and control characters are written as
+// \xAB hex escapes.
+func escapeVSCodeValue(value string) string {
+	var sb strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == '\\':
+			sb.WriteString(`\\`)
+		case c == ';' || c <= 0x20:
+			sb.WriteString(fmt.Sprintf(`\x%02x`, c))
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// encodeFileURLPath percent-encodes characters that aren't safe in a file:// URL
+func encodeFileURLPath(path string) string {
+	var sb strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if c <= 0x20 || c >= 0x7f || c == '%' || c == '#' || c == '?' {
+			sb.WriteString(fmt.Sprintf("%%%02X", c))
+		} else {
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,85 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestShellIntegration_Detection(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected bool
+	}{
+		{"plain terminal", map[string]string{}, false},
+		{"vscode", map[string]string{"TERM_PROGRAM": "vscode"}, true},
+		{"neovim", map[string]string{"NVIM": "/tmp/nvim.sock"}, true},
+		{"forced on", map[string]string{"GOSH_SHELL_INTEGRATION": "1"}, true},
+		{"forced off", map[string]string{"TERM_PROGRAM": "vscode", "GOSH_SHELL_INTEGRATION": "0"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			si := NewShellIntegration(tt.env)
+			if si.Enabled() != tt.expected {
+				t.Errorf("Enabled() = %v, expected %v", si.Enabled(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestShellIntegration_DisabledEmitsNothing(t *testing.T) {
+	si := NewShellIntegration(map[string]string{"GOSH_SHELL_INTEGRATION": "0"})
+
+	outputs := []string{
+		si.PromptStart(),
+		si.PromptEnd(),
+		si.CommandStart("ls"),
+		si.CommandFinished(1),
+		si.ReportCwd("/tmp"),
+	}
+	for _, out := range outputs {
+		if out != "" {
+			t.Errorf("Expected no output when disabled, got %q", out)
+		}
+	}
+}
+
+func TestShellIntegration_Sequences(t *testing.T) {
+	si := NewShellIntegration(map[string]string{"TERM_PROGRAM": "vscode"})
+
+	if !strings.Contains(si.PromptStart(), "\033]133;A\033\\") {
+		t.Errorf("PromptStart missing OSC 133;A: %q", si.PromptStart())
+	}
+
+	if !strings.Contains(si.CommandFinished(2), "133;D;2") {
+		t.Errorf("CommandFinished missing exit code: %q", si.CommandFinished(2))
+	}
+
+	start := si.CommandStart("echo a;b")
+	if !strings.Contains(start, `633;E;echo\x20a\x3bb`) {
+		t.Errorf("CommandStart should escape command line, got %q", start)
+	}
+
+	cwd := si.ReportCwd("/tmp/my dir")
+	if !strings.Contains(cwd, "\033]7;file://") || !strings.Contains(cwd, "/tmp/my%20dir") {
+		t.Errorf("ReportCwd should emit an encoded OSC 7 URL, got %q", cwd)
+	}
+}
+
+func TestModel_CommandStartHidesSecrets(t *testing.T) {
+	session := &SessionState{Mode: ModeShell}
+	m := model{session: session, integration: NewShellIntegration(map[string]string{"TERM_PROGRAM": "vscode"})}
+
+	if start := m.commandStart("echo hi"); !strings.Contains(start, `633;E;echo\x20hi`) {
+		t.Errorf("Expected the command line to be reported, got %q", start)
+	}
+	for _, input := range []string{"vault set TOKEN s3cret", "echo ok && (vault set TOKEN s3cret)", ":sh vault get TOKEN"} {
+		start := m.commandStart(input)
+		if strings.Contains(start, "633;E") || strings.Contains(start, "s3cret") || !strings.Contains(start, "633;C") {
+			t.Errorf("Expected %q not to be reported, got %q", input, start)
+		}
+	}
+}
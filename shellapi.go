@@ -0,0 +1,502 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// shellapiSymbols is the shellapi package, gosh's build of gosh_lib's
+// shellapi. Commands run in the shell's working directory and environment.
+// RunShell returns a CommandResult, and the other functions the output of
+// their command, stdout and stderr together.
+//
+// Configs that import github.com/rsarv3006/gosh_lib/shellapi get the same
+// package as gosh_lib/shellapi, with gosh_lib's RunShell, which returns the
+// output as a string, so they work as they are.
+func shellapiSymbols() map[string]map[string]reflect.Value {
+	symbols := map[string]reflect.Value{
+		"CommandResult": reflect.ValueOf((*CommandResult)(nil)),
+		"RunShell":      reflect.ValueOf(shellapiRunShell),
+
+		// Development tools
+		"GoBuild":   reflect.ValueOf(func() (string, error) { return shellapiRun("go", "build") }),
+		"GoRun":     reflect.ValueOf(func() (string, error) { return shellapiRun("go", "run", ".") }),
+		"GoTest":    reflect.ValueOf(func() (string, error) { return shellapiRun("go", "test") }),
+		"GoTestRun": reflect.ValueOf(func() (string, error) { return shellapiRun("go", "test", "-v", "./...") }),
+		"GoInstall": reflect.ValueOf(func(pkg string) (string, error) { return shellapiRun("go", "install", pkg) }),
+		"GoFmt":     reflect.ValueOf(func() (string, error) { return shellapiRun("go", "fmt", "./...") }),
+		"GoGet":     reflect.ValueOf(func() (string, error) { return shellapiRun("go", "get", "./...") }),
+		"GoTidy":    reflect.ValueOf(func() (string, error) { return shellapiRun("go", "mod", "tidy") }),
+		"GoVet":     reflect.ValueOf(func() (string, error) { return shellapiRun("go", "vet", "./...") }),
+		"NpmInstall": reflect.ValueOf(func(pkgs ...string) (string, error) {
+			return shellapiRun("npm", append([]string{"install"}, pkgs...)...)
+		}),
+		"NpmRun":       reflect.ValueOf(func(script string) (string, error) { return shellapiRun("npm", "run", script) }),
+		"PipInstall":   reflect.ValueOf(func(pkg string) (string, error) { return shellapiRun("pip", "install", pkg) }),
+		"DockerPs":     reflect.ValueOf(func() (string, error) { return shellapiRun("docker", "ps") }),
+		"DockerImages": reflect.ValueOf(func() (string, error) { return shellapiRun("docker", "images") }),
+		"DockerLogs":   reflect.ValueOf(func(container string) (string, error) { return shellapiRun("docker", "logs", container) }),
+		"DockerStop":   reflect.ValueOf(func(container string) (string, error) { return shellapiRun("docker", "stop", container) }),
+		"DockerRm":     reflect.ValueOf(func(container string) (string, error) { return shellapiRun("docker", "rm", container) }),
+		"KubectlPods":  reflect.ValueOf(func() (string, error) { return shellapiRun("kubectl", "get", "pods") }),
+		"KubectlLogs":  reflect.ValueOf(func(pod string) (string, error) { return shellapiRun("kubectl", "logs", pod) }),
+
+		// File operations. Relative paths are in the shell's working
+		// directory.
+		"Ls":           reflect.ValueOf(func(args ...string) (string, error) { return shellapiRun("ls", args...) }),
+		"LsColor":      reflect.ValueOf(func() (string, error) { return shellapiRun("ls", "--color=auto") }),
+		"LsSortBySize": reflect.ValueOf(func() (string, error) { return shellapiRun("ls", "-S") }),
+		"Tree":         reflect.ValueOf(func() (string, error) { return shellapiRun("tree") }),
+		"Find":         reflect.ValueOf(func(pattern string) (string, error) { return shellapiRun("find", ".", "-name", pattern) }),
+		// Searches the working directory unless given files
+		"Grep": reflect.ValueOf(func(pattern string, args ...string) (string, error) {
+			if len(args) == 0 {
+				args = []string{"-r", "."}
+			}
+			return shellapiRun("grep", append([]string{"-n", pattern}, args...)...)
+		}),
+		"Cat": reflect.ValueOf(func(file string) (string, error) {
+			data, err := os.ReadFile(shellapiPath(file))
+			return string(data), err
+		}),
+		"Head": reflect.ValueOf(func(n int, file string) (string, error) {
+			return shellapiRun("head", "-n", strconv.Itoa(n), file)
+		}),
+		"Tail": reflect.ValueOf(func(n int, file string) (string, error) {
+			return shellapiRun("tail", "-n", strconv.Itoa(n), file)
+		}),
+		"Touch":     reflect.ValueOf(shellapiTouch),
+		"TouchFile": reflect.ValueOf(shellapiTouch),
+		"MakeDir": reflect.ValueOf(func(dir string) (string, error) {
+			path := shellapiPath(dir)
+			return path, os.MkdirAll(path, 0755)
+		}),
+		"RemoveFile": reflect.ValueOf(func(file string) (string, error) {
+			path := shellapiPath(file)
+			return path, os.Remove(path)
+		}),
+		"RemoveDir": reflect.ValueOf(func(dir string) (string, error) {
+			path := shellapiPath(dir)
+			return path, os.RemoveAll(path)
+		}),
+
+		// Git
+		"GitStatus":    reflect.ValueOf(func() (string, error) { return shellapiRun("git", "status") }),
+		"GitLog":       reflect.ValueOf(func() (string, error) { return shellapiRun("git", "log", "--oneline", "-n", "20") }),
+		"GitBranch":    reflect.ValueOf(func() (string, error) { return shellapiRun("git", "branch", "--show-current") }),
+		"GitDiff":      reflect.ValueOf(func() (string, error) { return shellapiRun("git", "diff") }),
+		"GitPull":      reflect.ValueOf(func() (string, error) { return shellapiRun("git", "pull") }),
+		"GitPush":      reflect.ValueOf(func() (string, error) { return shellapiRun("git", "push") }),
+		"GitStashList": reflect.ValueOf(func() (string, error) { return shellapiRun("git", "stash", "list") }),
+		"GitStashPush": reflect.ValueOf(func(message string) (string, error) { return shellapiRun("git", "stash", "push", "-m", message) }),
+		"GitCommit":    reflect.ValueOf(func(message string) (string, error) { return shellapiRun("git", "commit", "-m", message) }),
+		"GitAdd": reflect.ValueOf(func(files ...string) (string, error) {
+			if len(files) == 0 {
+				files = []string{"."}
+			}
+			return shellapiRun("git", append([]string{"add"}, files...)...)
+		}),
+		// Stage everything and commit it
+		"QuickCommit": reflect.ValueOf(func(message string) (string, error) {
+			if output, err := shellapiRun("git", "add", "."); err != nil {
+				return output, err
+			}
+			return shellapiRun("git", "commit", "-m", message)
+		}),
+
+		// System
+		"Uptime":   reflect.ValueOf(func() (string, error) { return shellapiRun("uptime") }),
+		"Whoami":   reflect.ValueOf(func() (string, error) { return shellapiRun("whoami") }),
+		"Date":     reflect.ValueOf(func() (string, error) { return shellapiRun("date") }),
+		"Hostname": reflect.ValueOf(os.Hostname),
+		"OS":       reflect.ValueOf(func() (string, error) { return shellapiRun("uname", "-s") }),
+		"Arch":     reflect.ValueOf(func() (string, error) { return shellapiRun("uname", "-m") }),
+		"Pwd": reflect.ValueOf(func() (string, error) {
+			if state := goshAPIState(); state != nil {
+				return state.WorkingDirectory, nil
+			}
+			return os.Getwd()
+		}),
+		"Df": reflect.ValueOf(func() (string, error) { return shellapiRun("df", "-h") }),
+		// macOS has no free
+		"Free": reflect.ValueOf(func() (string, error) {
+			if runtime.GOOS == "darwin" {
+				return shellapiRun("vm_stat")
+			}
+			return shellapiRun("free", "-h")
+		}),
+		"Ps":   reflect.ValueOf(func() (string, error) { return shellapiRun("ps", "aux") }),
+		"Kill": reflect.ValueOf(func(pid int) (string, error) { return shellapiRun("kill", strconv.Itoa(pid)) }),
+
+		// Formatting. NO_COLOR turns the colors off.
+		"Red":       reflect.ValueOf(func(text string) string { return shellapiColor("31", text) }),
+		"Green":     reflect.ValueOf(func(text string) string { return shellapiColor("32", text) }),
+		"Yellow":    reflect.ValueOf(func(text string) string { return shellapiColor("33", text) }),
+		"Blue":      reflect.ValueOf(func(text string) string { return shellapiColor("34", text) }),
+		"Purple":    reflect.ValueOf(func(text string) string { return shellapiColor("35", text) }),
+		"Cyan":      reflect.ValueOf(func(text string) string { return shellapiColor("36", text) }),
+		"Bold":      reflect.ValueOf(func(text string) string { return shellapiColor("1", text) }),
+		"Italic":    reflect.ValueOf(func(text string) string { return shellapiColor("3", text) }),
+		"Underline": reflect.ValueOf(func(text string) string { return shellapiColor("4", text) }),
+		"Highlight": reflect.ValueOf(func(text string) string { return shellapiColor("7", text) }),
+		"Success":   reflect.ValueOf(func(text string) string { return shellapiColor("32", text) }),
+		"Warning":   reflect.ValueOf(func(text string) string { return shellapiColor("33", text) }),
+		"Error":     reflect.ValueOf(func(text string) string { return shellapiColor("31", text) }),
+		"Info":      reflect.ValueOf(func(text string) string { return shellapiColor("34", text) }),
+		"Question":  reflect.ValueOf(func(text string) string { return shellapiColor("36", "? "+text) }),
+		// Labelled messages, such as SuccessMsg("Build", "done")
+		"SuccessMsg": reflect.ValueOf(func(label, message string) string { return shellapiMessage("32", "✓", label, message) }),
+		"ErrorMsg":   reflect.ValueOf(func(label, message string) string { return shellapiMessage("31", "✗", label, message) }),
+		"WarningMsg": reflect.ValueOf(shellapiWarnMsg),
+		"WarnMsg":    reflect.ValueOf(shellapiWarnMsg),
+		"InfoMsg":    reflect.ValueOf(func(label, message string) string { return shellapiMessage("34", "ℹ", label, message) }),
+		"DebugMsg":   reflect.ValueOf(func(label, message string) string { return shellapiMessage("90", "•", label, message) }),
+
+		// Project helpers
+		"MakeTarget":   reflect.ValueOf(func(target string) (string, error) { return shellapiRun("make", target) }),
+		"MakeBuild":    reflect.ValueOf(func() (string, error) { return shellapiRun("make", "build") }),
+		"MakeClean":    reflect.ValueOf(func() (string, error) { return shellapiRun("make", "clean") }),
+		"MakeTest":     reflect.ValueOf(func() (string, error) { return shellapiRun("make", "test") }),
+		"RunTests":     reflect.ValueOf(shellapiBuildAndTest),
+		"BuildAndTest": reflect.ValueOf(shellapiBuildAndTest),
+		"CreateProjectDir": reflect.ValueOf(func(name string) (string, error) {
+			path := shellapiPath(name)
+			return path, os.MkdirAll(path, 0755)
+		}),
+		"JoinPaths": reflect.ValueOf(filepath.Join),
+		"Basename":  reflect.ValueOf(filepath.Base),
+		"Dirname":   reflect.ValueOf(filepath.Dir),
+		"ExpandUserHome": reflect.ValueOf(func(path string) string {
+			if home := goshAPIEnvironment()["HOME"]; home != "" && (path == "~" || strings.HasPrefix(path, "~/")) {
+				return home + path[1:]
+			}
+			return path
+		}),
+		// The directory of the gosh executable, as dirname $0 gives a
+		// script's
+		"GetScriptDir": reflect.ValueOf(func() (string, error) {
+			exe, err := os.Executable()
+			if err != nil {
+				return "", err
+			}
+			return filepath.Dir(exe), nil
+		}),
+
+		// Text processing
+		"Clean":     reflect.ValueOf(func(text string) string { return strings.Join(strings.Fields(text), " ") }),
+		"Trim":      reflect.ValueOf(strings.TrimSpace),
+		"Upper":     reflect.ValueOf(strings.ToUpper),
+		"Lower":     reflect.ValueOf(strings.ToLower),
+		"Title":     reflect.ValueOf(shellapiTitle),
+		"Replace":   reflect.ValueOf(func(text, old, new string) string { return strings.ReplaceAll(text, old, new) }),
+		"Contains":  reflect.ValueOf(strings.Contains),
+		"HasPrefix": reflect.ValueOf(strings.HasPrefix),
+		"HasSuffix": reflect.ValueOf(strings.HasSuffix),
+
+		// Environment variables, as export and unset change them
+		"EnvVar": reflect.ValueOf(func(name string) string { return goshAPIEnvironment()[name] }),
+		"Getenv": reflect.ValueOf(func(name, fallback string) string {
+			if value, ok := goshAPIEnvironment()[name]; ok && value != "" {
+				return value
+			}
+			return fallback
+		}),
+		"ExportEnv": reflect.ValueOf(func(name, value string) {
+			if state := goshAPIState(); state != nil {
+				state.SetEnv(name, value)
+			} else {
+				os.Setenv(name, value)
+			}
+		}),
+		"UnsetEnv": reflect.ValueOf(func(name string) {
+			if state := goshAPIState(); state != nil {
+				state.UnsetEnv(name)
+			} else {
+				os.Unsetenv(name)
+			}
+		}),
+		"ListEnv": reflect.ValueOf(func() string {
+			env := goshAPIEnvironment()
+			lines := make([]string, 0, len(env))
+			for name, value := range env {
+				lines = append(lines, name+"="+value)
+			}
+			sort.Strings(lines)
+			return strings.Join(lines, "\n")
+		}),
+
+		// Paths
+		"PathExists": reflect.ValueOf(func(path string) bool {
+			_, err := os.Stat(shellapiPath(path))
+			return err == nil
+		}),
+		"FileExists":  reflect.ValueOf(shellapiIsFile),
+		"IsFile":      reflect.ValueOf(shellapiIsFile),
+		"IsDir":       reflect.ValueOf(shellapiIsDir),
+		"IsDirectory": reflect.ValueOf(shellapiIsDir),
+		"Readable":    reflect.ValueOf(func(path string) bool { return shellapiAccess(path, 4) }),
+		"Writable":    reflect.ValueOf(func(path string) bool { return shellapiAccess(path, 2) }),
+		"Executable":  reflect.ValueOf(func(path string) bool { return shellapiAccess(path, 1) }),
+		"FileSize": reflect.ValueOf(func(path string) (int64, error) {
+			info, err := os.Stat(shellapiPath(path))
+			if err != nil {
+				return 0, err
+			}
+			return info.Size(), nil
+		}),
+		"FileModTime": reflect.ValueOf(func(path string) (time.Time, error) {
+			info, err := os.Stat(shellapiPath(path))
+			if err != nil {
+				return time.Time{}, err
+			}
+			return info.ModTime(), nil
+		}),
+
+		// Archives and downloads, implemented in Go so they behave the
+		// same on macOS and Linux
+		"Extract": reflect.ValueOf(func(archive, dest string) (string, error) {
+			count, err := extractArchive(archive, dest)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Extracted %d files to %s", count, dest), nil
+		}),
+		"Compress": reflect.ValueOf(func(src, archive string) (string, error) {
+			if err := compressPath(src, archive); err != nil {
+				return "", err
+			}
+			return archive, nil
+		}),
+		"Download": reflect.ValueOf(downloadFile),
+	}
+
+	compat := maps.Clone(symbols)
+	compat["RunShell"] = reflect.ValueOf(goshLibRunShell)
+	return map[string]map[string]reflect.Value{
+		"shellapi/shellapi":          symbols,
+		"gosh_lib/shellapi/shellapi": compat,
+	}
+}
+
+// CommandResult is what a command run with shellapi.RunShell did
+type CommandResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int // 127 if the command couldn't be run
+	Duration time.Duration
+}
+
+// Output returns what the command wrote to stdout followed by what it
+// wrote to stderr, without surrounding whitespace
+func (r CommandResult) Output() string {
+	stdout, stderr := strings.TrimSpace(r.Stdout), strings.TrimSpace(r.Stderr)
+	if stdout == "" || stderr == "" {
+		return stdout + stderr
+	}
+	return stdout + "\n" + stderr
+}
+
+// String returns the command's output, so the REPL shows a result that way
+func (r CommandResult) String() string {
+	return r.Output()
+}
+
+// Success reports whether the command exited with 0
+func (r CommandResult) Success() bool {
+	return r.ExitCode == 0
+}
+
+// shellapiRunShell runs a command, returning an error if it fails as well
+// as its result. cd changes the shell's working directory, as the cd
+// builtin does.
+func shellapiRunShell(name string, args ...string) (CommandResult, error) {
+	if name != "cd" {
+		return shellapiCommand(name, args...)
+	}
+
+	state := goshAPIState()
+	if state == nil {
+		return CommandResult{ExitCode: 1}, fmt.Errorf("cd: no shell session")
+	}
+	start := time.Now()
+	result := NewBuiltinHandler(state).cd(args)
+	cd := CommandResult{ExitCode: result.ExitCode, Duration: time.Since(start)}
+	if result.ExitCode != 0 {
+		cd.Stderr = result.Output
+		return cd, errors.New(result.Output)
+	}
+	cd.Stdout = result.Output
+	return cd, nil
+}
+
+// goshLibRunShell is RunShell as gosh_lib has it, returning the command's
+// output as a string
+func goshLibRunShell(name string, args ...string) (string, error) {
+	// Handle cd specially - IMMEDIATELY change the directory so it works within functions
+	if name == "cd" && len(args) > 0 {
+		targetPath := args[0]
+
+		// Handle path expansion
+		var expandedPath string
+		if strings.HasPrefix(targetPath, "~") {
+			home := os.Getenv("HOME")
+			if len(targetPath) == 1 {
+				expandedPath = home
+			} else {
+				expandedPath = filepath.Join(home, targetPath[1:])
+			}
+		} else if filepath.IsAbs(targetPath) {
+			expandedPath = targetPath
+		} else {
+			cwd, _ := os.Getwd()
+			expandedPath = filepath.Join(cwd, targetPath)
+		}
+
+		// Perform actual directory change immediately - THIS IS THE FIX!
+		if err := os.Chdir(expandedPath); err != nil {
+			return fmt.Sprintf("cd: %s: %v", targetPath, err), nil
+		}
+
+		// CRITICAL: Update global shell state for ALL cases (interactive and function calls)
+		shellStateMutex.Lock()
+		if globalShellState != nil {
+			globalShellState.SetWorkingDirectory(expandedPath)
+		}
+		shellStateMutex.Unlock()
+
+		// Return the marker for config function calling compatibility
+		return "@GOSH_INTERNAL_CD:" + targetPath, nil
+	}
+
+	return shellapiRun(name, args...)
+}
+
+// shellapiCommand runs a command for RunShell
+func shellapiCommand(name string, args ...string) (CommandResult, error) {
+	cmd := shellCommand(name, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	result := CommandResult{Stdout: stdout.String(), Stderr: stderr.String(), Duration: time.Since(start)}
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	case err != nil:
+		result.ExitCode = 127
+	}
+	return result, err
+}
+
+// shellapiRun runs a command for a shellapi function, returning what it
+// wrote to stdout and stderr without surrounding whitespace
+func shellapiRun(name string, args ...string) (string, error) {
+	output, err := shellCommand(name, args...).CombinedOutput()
+	return strings.TrimSpace(string(output)), err
+}
+
+// shellapiPath resolves path, which may start with ~, against the shell's
+// working directory
+func shellapiPath(path string) string {
+	if state := goshAPIState(); state != nil {
+		return state.ExpandPath(path)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	return abs
+}
+
+// shellapiTouch creates file if it doesn't exist and updates its
+// modification time, returning its path
+func shellapiTouch(file string) (string, error) {
+	path := shellapiPath(file)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", err
+	}
+	f.Close()
+	now := time.Now()
+	return path, os.Chtimes(path, now, now)
+}
+
+// shellapiIsFile reports whether path is a regular file
+func shellapiIsFile(path string) bool {
+	info, err := os.Stat(shellapiPath(path))
+	return err == nil && info.Mode().IsRegular()
+}
+
+// shellapiIsDir reports whether path is a directory
+func shellapiIsDir(path string) bool {
+	info, err := os.Stat(shellapiPath(path))
+	return err == nil && info.IsDir()
+}
+
+// shellapiAccess reports whether the user may access path as mode asks,
+// as test -r, -w and -x do: 4 to read, 2 to write, 1 to execute
+func shellapiAccess(path string, mode uint32) bool {
+	return syscall.Access(shellapiPath(path), mode) == nil
+}
+
+// shellapiBuildAndTest builds and tests every package of the Go module
+func shellapiBuildAndTest() (string, error) {
+	build, err := shellapiRun("go", "build", "./...")
+	if err != nil {
+		return build, err
+	}
+	test, err := shellapiRun("go", "test", "./...")
+	return strings.TrimSpace(build + "\n" + test), err
+}
+
+// shellapiTitle capitalizes the first letter of each word of text
+func shellapiTitle(text string) string {
+	words := strings.Fields(text)
+	for i, word := range words {
+		r, size := utf8.DecodeRuneInString(word)
+		words[i] = string(unicode.ToUpper(r)) + word[size:]
+	}
+	return strings.Join(words, " ")
+}
+
+// shellapiColor wraps text in the ANSI SGR code, unless NO_COLOR is set
+func shellapiColor(code, text string) string {
+	if shouldUseNoColor() {
+		return text
+	}
+	return "\033[" + code + "m" + text + "\033[0m"
+}
+
+// shellapiWarnMsg is WarnMsg, which gosh_lib also calls WarningMsg
+func shellapiWarnMsg(label, message string) string {
+	return shellapiMessage("33", "⚠", label, message)
+}
+
+// shellapiMessage formats a labelled message, with the message on lines of
+// its own if it has several, as command output usually does
+func shellapiMessage(code, symbol, label, message string) string {
+	heading := shellapiColor(code, symbol+" "+label)
+	if strings.Contains(message, "\n") {
+		return heading + ":\n" + message
+	}
+	return heading + ": " + message
+}
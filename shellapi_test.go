@@ -0,0 +1,113 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestShellapiPackage(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	dir, _ := filepath.EvalSymlinks(t.TempDir())
+	state := &ShellState{WorkingDirectory: dir, Environment: map[string]string{"PATH": os.Getenv("PATH"), "HOME": dir, "GOSH_TEST_VAR": "42"}}
+	g := NewGoEvaluator()
+	g.SetupWithShell(state, NewProcessSpawner(state))
+	if result := g.Eval(`import "shellapi/shellapi"`); result.ExitCode != 0 {
+		t.Fatalf("Failed to import shellapi: %s", result.Output)
+	}
+
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		// Files are in the shell's working directory, not the process's
+		{`shellapi.Touch("notes.txt")`, filepath.Join(dir, "notes.txt")},
+		{`shellapi.Ls()`, "notes.txt"},
+		{`shellapi.Find("*.txt")`, "./notes.txt"},
+		{`shellapi.Pwd()`, dir},
+		{`shellapi.EnvVar("GOSH_TEST_VAR")`, "42"},
+		{`shellapi.Success("ok")`, "\033[32mok\033[0m"},
+		{`shellapi.Bold("b")`, "\033[1mb\033[0m"},
+		{`shellapi.SuccessMsg("Build", "done")`, "\033[32m✓ Build\033[0m: done"},
+		{`shellapi.InfoMsg("Pods", "a\nb")`, "\033[34mℹ Pods\033[0m:\na\nb"},
+		{`shellapi.MakeDir("sub/dir")`, filepath.Join(dir, "sub", "dir")},
+		{`shellapi.IsDir("sub") && shellapi.FileExists("notes.txt") && !shellapi.FileExists("sub")`, "true"},
+		{`shellapi.Readable("notes.txt") && !shellapi.Executable("notes.txt")`, "true"},
+		{`shellapi.Getenv("GOSH_NEVER_SET", "fallback")`, "fallback"},
+		{`shellapi.ListEnv()`, "GOSH_TEST_VAR=42"},
+		{`shellapi.ExpandUserHome("~/src")`, filepath.Join(dir, "src")},
+		{`shellapi.Title(shellapi.Clean("  hello   wide  world "))`, "Hello Wide World"},
+		{`shellapi.Basename(shellapi.JoinPaths("a", "b", "c.go"))`, "c.go"},
+	}
+	for _, tt := range tests {
+		if result := g.Eval(tt.code); !strings.Contains(result.Output, tt.expected) {
+			t.Errorf("%s: expected %q in %q", tt.code, tt.expected, result.Output)
+		}
+	}
+
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("remember the milk\n"), 0644)
+	if result := g.Eval(`shellapi.Cat("notes.txt")`); !strings.Contains(result.Output, "remember the milk") {
+		t.Errorf("Expected the file's contents, got %q", result.Output)
+	}
+	if result := g.Eval(`shellapi.Grep("milk")`); !strings.Contains(result.Output, "notes.txt:1:remember the milk") {
+		t.Errorf("Expected grep to find the line, got %q", result.Output)
+	}
+	if result := g.Eval(`shellapi.FileSize("notes.txt")`); !strings.Contains(result.Output, "18") {
+		t.Errorf("Expected a size of 18, got %q", result.Output)
+	}
+	g.Eval(`shellapi.RemoveFile("notes.txt")`)
+	if _, err := os.Stat(filepath.Join(dir, "notes.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected RemoveFile to remove the file, got %v", err)
+	}
+
+	// Failing commands return their output with the error
+	if result := g.Eval(`shellapi.GitStatus()`); !strings.Contains(result.Output, "not a git repository") {
+		t.Errorf("Expected git's error, got %q", result.Output)
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if result := g.Eval(`shellapi.Error("plain")`); result.Output != "plain" {
+		t.Errorf("Expected no color with NO_COLOR set, got %q", result.Output)
+	}
+}
+
+func TestShellapiRunShell(t *testing.T) {
+	dir, _ := filepath.EvalSymlinks(t.TempDir())
+	os.Mkdir(filepath.Join(dir, "sub"), 0755)
+	state := &ShellState{WorkingDirectory: dir, Environment: map[string]string{"PATH": os.Getenv("PATH"), "HOME": dir}}
+	g := NewGoEvaluator()
+	g.SetupWithShell(state, NewProcessSpawner(state))
+	g.Eval(`import "shellapi/shellapi"`)
+
+	if result := g.Eval(`r, err := shellapi.RunShell("sh", "-c", "echo out; echo err >&2; exit 3")`); result.ExitCode != 0 {
+		t.Fatalf("RunShell failed: %s", result.Output)
+	}
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{`r.Stdout`, "out"},
+		{`r.Stderr`, "err"},
+		{`r.ExitCode`, "3"},
+		{`r.Success()`, "false"},
+		{`r.Duration > 0`, "true"},
+		{`err != nil`, "true"},
+		{`r`, "out\nerr"},
+	}
+	for _, tt := range tests {
+		if result := g.Eval(tt.code); result.Output != tt.expected {
+			t.Errorf("%s = %q, want %q", tt.code, result.Output, tt.expected)
+		}
+	}
+
+	if result := g.Eval(`shellapi.RunShell("cd", "sub")`); result.ExitCode != 0 || state.WorkingDirectory != filepath.Join(dir, "sub") {
+		t.Errorf("Expected RunShell to cd into sub, got %q in %s", result.Output, state.WorkingDirectory)
+	}
+	g.Eval(`r, err = shellapi.RunShell("gosh-no-such-command")`)
+	if result := g.Eval(`r.ExitCode`); result.Output != "127" {
+		t.Errorf("Expected 127 for a missing command, got %q", result.Output)
+	}
+}
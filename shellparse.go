@@ -0,0 +1,651 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// shellWord is one word of a shell command line. Operator words are
+// unquoted redirection operators such as >, 2>>, 2>&1, < and <<, the list
+// operators &&, ||, ; and &, or |>. The word after << is the heredoc's body.
+type shellWord struct {
+	Text     string
+	Operator bool
+	// Escaped form of the word (see expand.go), set only when it has
+	// unquoted characters that expansion acts on
+	raw    string
+	quoted bool // Some of the word was quoted
+	// For a heredoc body, the delimiter it was read up to
+	heredocDelimiter string
+	// For a ( ... ) subshell, the words inside
+	subshell []shellWord
+}
+
+// Redirect is an I/O redirection attached to a command
+type Redirect struct {
+	Fd      int    // File descriptor being redirected: 0, 1 or 2
+	Path    string // Target file, relative to the working directory, or the heredoc delimiter
+	Append  bool   // >> rather than >
+	Dup     bool   // N>&M: Fd becomes a copy of Target and Path is unused
+	Target  int
+	Heredoc bool   // <<: stdin reads Body
+	Body    string // Text of a heredoc
+	raw     string // Escaped form of Path, or of Body for heredocs, if it needs expanding
+}
+
+// ShellCommand is a parsed shell command line
+type ShellCommand struct {
+	Name       string
+	Args       []string
+	Redirects  []Redirect
+	Background bool // Followed by &: run without waiting
+	// Escaped forms of Name and Args, for the words that need expanding
+	// (others are ""); nil when none do
+	raw []string
+	// Process substitutions started by Expand
+	substitutions []*processSubstitution
+	// Commands of a ( ... ) subshell, which has no Name or Args
+	Subshell []ChainedCommand
+	// Go function the command's output streams into, after |>
+	GoFunc string
+}
+
+// String returns the command line, quoting words that need it
+func (c ShellCommand) String() string {
+	var words []string
+	if c.Subshell != nil {
+		words = append(words, "("+commandListString(c.Subshell)+")")
+	} else {
+		words = append(words, quoteShellWord(c.Name))
+	}
+	for _, arg := range c.Args {
+		words = append(words, quoteShellWord(arg))
+	}
+	for _, r := range c.Redirects {
+		op := ">"
+		switch {
+		case r.Heredoc:
+			words = append(words, "<<"+quoteShellWord(r.Path))
+			continue
+		case r.Fd == 0:
+			op = "<"
+		case r.Fd == 2:
+			op = "2>"
+		}
+		if r.Append {
+			op += ">"
+		}
+		if r.Dup {
+			words = append(words, fmt.Sprintf("%s&%d", op, r.Target))
+		} else {
+			words = append(words, op+" "+quoteShellWord(r.Path))
+		}
+	}
+	if c.GoFunc != "" {
+		words = append(words, "|>", c.GoFunc)
+	}
+	if c.Background {
+		words = append(words, "&")
+	}
+	return strings.Join(words, " ")
+}
+
+// quoteShellWord single-quotes a word containing spaces, quotes, operator
+// characters or characters that would be expanded
+func quoteShellWord(word string) string {
+	if word != "" && !strings.ContainsAny(word, " \t'\"\\<>&|;$*?[{()") {
+		return word
+	}
+	return "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+}
+
+// ChainedCommand is one command of a command list. Op joins it to the
+// previous command: "" for the first, then "&&", "||", ";" or "&".
+// Background commands are followed by & and don't join on to the next.
+type ChainedCommand struct {
+	Op string
+	ShellCommand
+}
+
+// commandListString returns a command list as a command line
+func commandListString(list []ChainedCommand) string {
+	var sb strings.Builder
+	for i, cmd := range list {
+		switch {
+		case i == 0:
+		case cmd.Op == ";":
+			sb.WriteString("; ")
+		case cmd.Op == "&":
+			// The previous command ends with its &
+			sb.WriteString(" ")
+		default:
+			sb.WriteString(" " + cmd.Op + " ")
+		}
+		sb.WriteString(cmd.String())
+	}
+	return sb.String()
+}
+
+// isListOperator reports whether an operator word separates commands
+func isListOperator(op string) bool {
+	return op == "&&" || op == "||" || op == ";" || op == "&"
+}
+
+// splitShellWords splits a command line into words, following POSIX quoting
+// rules, and recognizes unquoted operators even without surrounding spaces
+// (`ls>out.txt`, `make&&make install`). Single quotes keep everything
+// literal; double quotes keep everything but $ and \ literal, and a
+// backslash there only escapes $, `, ", \ and newline; outside quotes a
+// backslash makes the next character literal. A newline ends a command like
+// ;, and is followed by the bodies of any heredocs started on its line. A
+// ( at the start of a command begins a subshell, whose words are split up
+// to the matching ).
+func splitShellWords(input string) ([]shellWord, error) {
+	var words []shellWord
+	var current strings.Builder
+	inWord := false
+	quote := rune(0) // ' or " while inside quotes
+	var raw strings.Builder
+	expandable := false
+	quoted := false
+	// Nesting of ${...}, inside which spaces and operators don't split words
+	paramDepth := 0
+	// Heredocs whose bodies start after the next newline
+	var heredocs []heredocStart
+
+	reset := func() {
+		current.Reset()
+		raw.Reset()
+		inWord = false
+		expandable = false
+		quoted = false
+	}
+	flush := func() {
+		if inWord {
+			word := shellWord{Text: current.String(), quoted: quoted}
+			if expandable {
+				word.raw = raw.String()
+			}
+			words = append(words, word)
+			reset()
+		}
+	}
+	appendChar := func(char rune, isQuoted bool) {
+		// A ~ only expands at the start of a word
+		if !isQuoted && (strings.ContainsRune("{*?[$", char) || (char == '~' && !inWord)) {
+			expandable = true
+		}
+		inWord = true
+		quoted = quoted || isQuoted
+		current.WriteRune(char)
+		escapeRune(&raw, char, isQuoted)
+	}
+
+	runes := []rune(input)
+	for i := 0; i < len(runes); i++ {
+		char := runes[i]
+		literal := quote != 0 || paramDepth > 0
+		switch {
+		case quote == '\'':
+			if char == '\'' {
+				quote = 0
+			} else {
+				appendChar(char, true)
+			}
+		case char == '\\':
+			inWord = true
+			quoted = true
+			if i+1 >= len(runes) || (quote == '"' && !strings.ContainsRune("$`\"\\\n", runes[i+1])) {
+				appendChar(char, true)
+				continue
+			}
+			// A backslash-newline continues the line
+			if i++; runes[i] != '\n' {
+				appendChar(runes[i], true)
+			}
+		case char == '"' && quote == '"':
+			quote = 0
+		case (char == '"' || char == '\'') && quote == 0:
+			inWord = true
+			quoted = true
+			quote = char
+		case char == '$' && i+1 < len(runes) && runes[i+1] == '{':
+			paramDepth++
+			appendChar(char, false)
+			appendChar('{', false)
+			i++
+		case char == '}' && paramDepth > 0:
+			paramDepth--
+			appendChar(char, false)
+		case char == '$':
+			appendChar(char, false)
+			// Inside double quotes a variable's name is still part of it
+			if quote == '"' && i+1 < len(runes) && runes[i+1] < 0x80 && isSpecialParameter(byte(runes[i+1])) {
+				i++
+				appendChar(runes[i], false)
+			} else if quote == '"' && i+1 < len(runes) && runes[i+1] < 0x80 && isNameStart(byte(runes[i+1])) {
+				for i+1 < len(runes) && runes[i+1] < 0x80 && isNameChar(byte(runes[i+1])) {
+					i++
+					appendChar(runes[i], false)
+				}
+			}
+		case (char == ' ' || char == '\t') && !literal:
+			flush()
+		case char == '\n' && !literal:
+			flush()
+			var err error
+			if i, err = readHeredocs(words, heredocs, runes, i+1); err != nil {
+				return nil, err
+			}
+			i--
+			heredocs = nil
+			if len(words) > 0 && !(words[len(words)-1].Operator && isListOperator(words[len(words)-1].Text)) {
+				words = append(words, shellWord{Text: ";", Operator: true})
+			}
+		case char == '>' && !literal:
+			// An explicit descriptor: 1>, 2>>, 2>&1
+			op := ">"
+			if fd := raw.String(); inWord && (fd == "1" || fd == "2") {
+				if fd == "2" {
+					op = "2>"
+				}
+				reset()
+			}
+			flush()
+			if i+1 < len(runes) && runes[i+1] == '>' {
+				op += ">"
+				i++
+			}
+			if i+2 < len(runes) && runes[i+1] == '&' && runes[i+2] >= '0' && runes[i+2] <= '9' {
+				op += string(runes[i+1 : i+3])
+				i += 2
+			}
+			words = append(words, shellWord{Text: op, Operator: true})
+		case char == '(' && !literal && !inWord && (len(words) == 0 || (words[len(words)-1].Operator && isListOperator(words[len(words)-1].Text))):
+			end := matchingParen(runes, i)
+			if end < 0 {
+				return nil, fmt.Errorf("syntax error: unterminated (")
+			}
+			inner, err := splitShellWords(string(runes[i+1 : end]))
+			if err != nil {
+				return nil, err
+			}
+			if len(inner) == 0 {
+				return nil, fmt.Errorf("syntax error: empty ( )")
+			}
+			words = append(words, shellWord{Text: "(", subshell: inner})
+			i = end
+		case char == '<' && !literal && i+1 < len(runes) && runes[i+1] == '(':
+			// A process substitution, kept whole until it's expanded
+			end := matchingParen(runes, i+1)
+			if end < 0 {
+				return nil, fmt.Errorf("syntax error: unterminated <(")
+			}
+			inner := string(runes[i+2 : end])
+			inWord = true
+			expandable = true
+			current.WriteString("<(" + inner + ")")
+			raw.WriteString("<(" + escapeText(inner) + ")")
+			i = end
+		case char == '<' && !literal:
+			// An explicit stdin descriptor: 0<
+			if inWord && raw.String() == "0" {
+				reset()
+			}
+			flush()
+			op := "<"
+			if i+1 < len(runes) && runes[i+1] == '<' {
+				op = "<<"
+				i++
+				if i+1 < len(runes) && runes[i+1] == '-' {
+					op = "<<-"
+					i++
+				}
+				heredocs = append(heredocs, heredocStart{word: len(words) + 1, stripTabs: op == "<<-"})
+			}
+			words = append(words, shellWord{Text: op, Operator: true})
+		case char == ';' && !literal:
+			flush()
+			words = append(words, shellWord{Text: ";", Operator: true})
+		case char == '|' && !literal && i+1 < len(runes) && runes[i+1] == '>':
+			flush()
+			words = append(words, shellWord{Text: "|>", Operator: true})
+			i++
+		case (char == '&' || char == '|') && !literal && i+1 < len(runes) && runes[i+1] == char:
+			flush()
+			words = append(words, shellWord{Text: string(runes[i : i+2]), Operator: true})
+			i++
+		case char == '&' && !literal:
+			flush()
+			words = append(words, shellWord{Text: "&", Operator: true})
+		default:
+			appendChar(char, quote != 0)
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("syntax error: unterminated %c", quote)
+	}
+	flush()
+	if len(heredocs) > 0 {
+		if _, err := readHeredocs(words, heredocs, runes, len(runes)); err != nil {
+			return nil, err
+		}
+	}
+
+	return words, nil
+}
+
+// parseShellCommand parses a single command into the command, its arguments
+// and its redirections
+func parseShellCommand(input string) (ShellCommand, error) {
+	words, err := splitShellWords(input)
+	if err != nil {
+		return ShellCommand{}, err
+	}
+	return commandFromWords(words)
+}
+
+// parseCommandList parses a command line of one or more commands joined by
+// &&, ||, ; and &. A trailing ; or & is allowed, as in other shells.
+func parseCommandList(input string) ([]ChainedCommand, error) {
+	words, err := splitShellWords(input)
+	if err != nil {
+		return nil, err
+	}
+	return commandListFromWords(words)
+}
+
+// commandListFromWords builds a command list from the words of a line
+func commandListFromWords(words []shellWord) ([]ChainedCommand, error) {
+	var list []ChainedCommand
+	start := 0
+	op := ""
+
+	for i := 0; i <= len(words); i++ {
+		if i < len(words) && !(words[i].Operator && isListOperator(words[i].Text)) {
+			continue
+		}
+		if i == start {
+			switch {
+			case i < len(words):
+				return nil, fmt.Errorf("syntax error: missing command before %s", words[i].Text)
+			case op != "" && op != ";" && op != "&":
+				return nil, fmt.Errorf("syntax error: missing command after %s", op)
+			}
+			break
+		}
+
+		cmd, err := commandFromWords(words[start:i])
+		if err != nil {
+			return nil, err
+		}
+		if i < len(words) {
+			cmd.Background = words[i].Text == "&"
+		}
+		list = append(list, ChainedCommand{Op: op, ShellCommand: cmd})
+		if i < len(words) {
+			op = words[i].Text
+		}
+		start = i + 1
+	}
+	return list, nil
+}
+
+// commandFromWords builds a command from the words between list operators
+func commandFromWords(words []shellWord) (ShellCommand, error) {
+	var cmd ShellCommand
+	var raw []string
+	expandable := false
+
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		if word.Operator && isListOperator(word.Text) {
+			return cmd, fmt.Errorf("syntax error: unexpected %s", word.Text)
+		}
+		if word.subshell != nil {
+			list, err := commandListFromWords(word.subshell)
+			if err != nil {
+				return cmd, err
+			}
+			cmd.Subshell = list
+			continue
+		}
+		if cmd.GoFunc != "" {
+			return cmd, fmt.Errorf("syntax error: unexpected %s after |> %s", word.Text, cmd.GoFunc)
+		}
+		if word.Operator && word.Text == "|>" {
+			if cmd.Name == "" && cmd.Subshell == nil {
+				return cmd, fmt.Errorf("syntax error: missing command before |>")
+			}
+			if i+1 >= len(words) || words[i+1].Operator || !isGoFuncName(strings.TrimSuffix(words[i+1].Text, "()")) {
+				return cmd, fmt.Errorf("syntax error: |> needs a Go function name")
+			}
+			i++
+			cmd.GoFunc = strings.TrimSuffix(words[i].Text, "()")
+			continue
+		}
+		if !word.Operator && cmd.Subshell != nil {
+			return cmd, fmt.Errorf("syntax error: unexpected %s after )", word.Text)
+		}
+		if !word.Operator {
+			if cmd.Name == "" && len(cmd.Args) == 0 {
+				cmd.Name = word.Text
+			} else {
+				cmd.Args = append(cmd.Args, word.Text)
+			}
+			raw = append(raw, word.raw)
+			expandable = expandable || word.raw != ""
+			continue
+		}
+
+		redirect, err := parseRedirectOperator(word.Text)
+		if err != nil {
+			return cmd, err
+		}
+		if !redirect.Dup {
+			if i+1 >= len(words) || words[i+1].Operator {
+				return cmd, fmt.Errorf("syntax error: %s needs a file name", word.Text)
+			}
+			i++
+			if redirect.Heredoc {
+				redirect.Path, redirect.Body, redirect.raw = words[i].heredocDelimiter, words[i].Text, words[i].raw
+			} else {
+				redirect.Path = words[i].Text
+				redirect.raw = words[i].raw
+			}
+		}
+		cmd.Redirects = append(cmd.Redirects, redirect)
+	}
+
+	if cmd.Name == "" && cmd.Subshell == nil && len(cmd.Redirects) > 0 {
+		return cmd, fmt.Errorf("syntax error: missing command before %s", words[0].Text)
+	}
+	if expandable {
+		cmd.raw = raw
+	}
+	return cmd, nil
+}
+
+// parseRedirectOperator turns an operator word from splitShellWords into a
+// Redirect without its Path
+func parseRedirectOperator(op string) (Redirect, error) {
+	switch op {
+	case "<":
+		return Redirect{Fd: 0}, nil
+	case "<<", "<<-":
+		return Redirect{Fd: 0, Heredoc: true}, nil
+	}
+
+	r := Redirect{Fd: 1}
+	rest := op
+	if strings.HasPrefix(rest, "2") {
+		r.Fd = 2
+		rest = rest[1:]
+	}
+	rest = strings.TrimPrefix(rest, ">")
+	if strings.HasPrefix(rest, ">") {
+		r.Append = true
+		rest = rest[1:]
+	}
+	if strings.HasPrefix(rest, "&") {
+		if r.Append {
+			return r, fmt.Errorf("syntax error: unexpected %s", op)
+		}
+		r.Dup = true
+		switch rest[1:] {
+		case "1":
+			r.Target = 1
+		case "2":
+			r.Target = 2
+		default:
+			return r, fmt.Errorf("%s: only 1 and 2 can be duplicated", op)
+		}
+	}
+	return r, nil
+}
+
+// open opens the redirect's file: for reading when redirecting stdin,
+// otherwise for writing, creating it if needed
+func (r Redirect) open(state *ShellState) (*os.File, error) {
+	if r.Heredoc {
+		return heredocFile(r.Body)
+	}
+	if r.Fd == 0 {
+		return os.Open(resolvePath(state.WorkingDirectory, r.Path))
+	}
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if r.Append {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	return os.OpenFile(resolvePath(state.WorkingDirectory, r.Path), flags, 0644)
+}
+
+// redirectedFiles holds the files a command's redirections resolve to.
+// Nil fields aren't redirected.
+type redirectedFiles struct {
+	Stdin  *os.File
+	Stdout *os.File
+	Stderr *os.File
+	opened []*os.File
+}
+
+// Close closes every file opened for the redirections
+func (f *redirectedFiles) Close() {
+	for _, file := range f.opened {
+		file.Close()
+	}
+}
+
+// openRedirects applies redirects in order, like a POSIX shell: all output
+// targets are created (or truncated), every input must exist, the last
+// redirect of each descriptor wins, and N>&M copies M as it is at that
+// point (`> out 2>&1` sends both to out, `2>&1 > out` doesn't)
+func openRedirects(state *ShellState, redirects []Redirect) (*redirectedFiles, error) {
+	return redirectFiles(state, &redirectedFiles{}, redirects)
+}
+
+// redirectFiles applies redirects on top of files, which already hold the
+// descriptors a command starts with, such as the pipe of |>
+func redirectFiles(state *ShellState, files *redirectedFiles, redirects []Redirect) (*redirectedFiles, error) {
+	for _, r := range redirects {
+		if r.Dup {
+			switch {
+			case r.Fd == 2 && r.Target == 1:
+				files.Stderr = files.Stdout
+			case r.Fd == 1 && r.Target == 2:
+				files.Stdout = files.Stderr
+			}
+			continue
+		}
+
+		f, err := r.open(state)
+		if err != nil {
+			files.Close()
+			return &redirectedFiles{}, fmt.Errorf("%s: %w", r.Path, unwrapPathError(err))
+		}
+		files.opened = append(files.opened, f)
+
+		switch r.Fd {
+		case 0:
+			files.Stdin = f
+		case 1:
+			files.Stdout = f
+		case 2:
+			files.Stderr = f
+		}
+	}
+	return files, nil
+}
+
+// unwrapPathError drops the *os.PathError wrapper, whose message repeats
+// the full path
+func unwrapPathError(err error) error {
+	if pathErr, ok := err.(*os.PathError); ok {
+		return pathErr.Err
+	}
+	return err
+}
+
+// runBuiltin runs cmd as a builtin with its redirections: the input of a
+// < or heredoc is what it reads, and its output goes where > and 2> send it
+func runBuiltin(builtins *BuiltinHandler, state *ShellState, cmd ShellCommand) ExecutionResult {
+	var stdin *os.File
+	redirected := false
+	for _, r := range cmd.Redirects {
+		if r.Fd == 1 {
+			redirected = true
+		}
+		if r.Fd != 0 || r.Dup {
+			continue
+		}
+		if stdin != nil {
+			stdin.Close()
+		}
+		f, err := r.open(state)
+		if err != nil {
+			err = fmt.Errorf("%s: %w", r.Path, unwrapPathError(err))
+			return ExecutionResult{Output: fmt.Sprintf("gosh: %v", err), ExitCode: 1, Error: err}
+		}
+		stdin = f
+	}
+	if stdin != nil {
+		defer stdin.Close()
+	}
+	if stdin != nil || redirected {
+		builtins = &BuiltinHandler{state: builtins.state, evaluator: builtins.evaluator, stdin: stdin, redirected: redirected}
+	}
+	return applyOutputRedirects(state, cmd.Redirects, builtins.Execute(cmd.Name, cmd.Args))
+}
+
+// applyOutputRedirects writes a builtin's output to its redirect targets
+// instead of the screen. A failing builtin's output is its error message,
+// so it follows stderr.
+func applyOutputRedirects(state *ShellState, redirects []Redirect, result ExecutionResult) ExecutionResult {
+	files, err := openRedirects(state, redirects)
+	defer files.Close()
+	if err != nil {
+		return ExecutionResult{Output: fmt.Sprintf("gosh: %v", err), ExitCode: 1, Error: err}
+	}
+	stdout := files.Stdout
+	if result.ExitCode != 0 {
+		stdout = files.Stderr
+	}
+	if stdout == nil {
+		return result
+	}
+
+	if result.Output != "" {
+		output := result.Output
+		if !strings.HasSuffix(output, "\n") && !result.partialLine {
+			output += "\n"
+		}
+		if _, err := stdout.WriteString(output); err != nil {
+			return ExecutionResult{Output: fmt.Sprintf("gosh: %v", err), ExitCode: 1, Error: err}
+		}
+	}
+	result.Output = ""
+	return result
+}
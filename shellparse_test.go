@@ -0,0 +1,238 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseShellCommand_OutputRedirects(t *testing.T) {
+	tests := []struct {
+		input     string
+		name      string
+		args      []string
+		redirects []Redirect
+	}{
+		{"ls -la", "ls", []string{"-la"}, nil},
+		{"go test ./... > results.txt", "go", []string{"test", "./..."}, []Redirect{{Fd: 1, Path: "results.txt"}}},
+		{"date >> log.txt", "date", nil, []Redirect{{Fd: 1, Path: "log.txt", Append: true}}},
+		{"echo hi>out.txt", "echo", []string{"hi"}, []Redirect{{Fd: 1, Path: "out.txt"}}},
+		{"echo hi 1>out.txt", "echo", []string{"hi"}, []Redirect{{Fd: 1, Path: "out.txt"}}},
+		{`echo "a > b" '>>'`, "echo", []string{"a > b", ">>"}, nil},
+		{"echo 'it''s'", "echo", []string{"its"}, nil},
+		{"sort < names.txt", "sort", nil, []Redirect{{Fd: 0, Path: "names.txt"}}},
+		{"sort<names.txt>sorted.txt", "sort", nil, []Redirect{{Fd: 0, Path: "names.txt"}, {Fd: 1, Path: "sorted.txt"}}},
+		{"wc -l 0< names.txt", "wc", []string{"-l"}, []Redirect{{Fd: 0, Path: "names.txt"}}},
+		{`echo "a < b"`, "echo", []string{"a < b"}, nil},
+		{"make 2> errors.txt", "make", nil, []Redirect{{Fd: 2, Path: "errors.txt"}}},
+		{"make 2>>errors.txt", "make", nil, []Redirect{{Fd: 2, Path: "errors.txt", Append: true}}},
+		{"make > build.log 2>&1", "make", nil, []Redirect{{Fd: 1, Path: "build.log"}, {Fd: 2, Dup: true, Target: 1}}},
+		{"echo oops 1>&2", "echo", []string{"oops"}, []Redirect{{Fd: 1, Dup: true, Target: 2}}},
+		{"echo 12>x", "echo", []string{"12"}, []Redirect{{Fd: 1, Path: "x"}}},
+	}
+
+	for _, tt := range tests {
+		cmd, err := parseShellCommand(tt.input)
+		if err != nil {
+			t.Errorf("parseShellCommand(%q) error: %v", tt.input, err)
+			continue
+		}
+		if cmd.Name != tt.name || !reflect.DeepEqual(cmd.Args, tt.args) || !reflect.DeepEqual(cmd.Redirects, tt.redirects) {
+			t.Errorf("parseShellCommand(%q) = %+v", tt.input, cmd)
+		}
+	}
+}
+
+func TestParseShellCommand_Quoting(t *testing.T) {
+	tests := []struct {
+		input string
+		args  []string
+	}{
+		{`echo 'a  b' "c  d"`, []string{"a  b", "c  d"}},
+		{`echo 'say "hi"' "it's"`, []string{`say "hi"`, "it's"}},
+		{`echo 'it'\''s'`, []string{"it's"}},
+		{`echo a\ b \"x\" \'y\' \\`, []string{"a b", `"x"`, "'y'", `\`}},
+		{`echo 'a\nb' 'back\'`, []string{`a\nb`, `back\`}},
+		{`echo "a\"b" "c\\d" "e\$f" "g\nh" "i\'j"`, []string{`a"b`, `c\d`, "e$f", `g\nh`, `i\'j`}},
+		{`find . -name '*.go' -exec gofmt -l {} \;`, []string{".", "-name", "*.go", "-exec", "gofmt", "-l", "{}", ";"}},
+		{`echo "" ''`, []string{"", ""}},
+		{"echo one \\\ntwo", []string{"one", "two"}},
+		{`echo "a > b" a\>b a\&\&b`, []string{"a > b", "a>b", "a&&b"}},
+	}
+
+	for _, tt := range tests {
+		cmd, err := parseShellCommand(tt.input)
+		if err != nil {
+			t.Errorf("parseShellCommand(%q) error: %v", tt.input, err)
+			continue
+		}
+		if cmd.Name != "echo" && cmd.Name != "find" || !reflect.DeepEqual(cmd.Args, tt.args) {
+			t.Errorf("parseShellCommand(%q) args = %q, want %q", tt.input, cmd.Args, tt.args)
+		}
+	}
+}
+
+func TestParseShellCommand_SyntaxErrors(t *testing.T) {
+	for _, input := range []string{"echo hi >", "echo hi > >> x", "> out.txt", "sort <", "< names.txt", "make 2>", "make 2>&3", "2>&1", "echo 'unterminated", `echo "unterminated`} {
+		if _, err := parseShellCommand(input); err == nil {
+			t.Errorf("Expected a syntax error for %q", input)
+		}
+	}
+}
+
+func TestProcessSpawner_RunRedirectsStdout(t *testing.T) {
+	dir := t.TempDir()
+	state := &ShellState{WorkingDirectory: dir, Environment: map[string]string{"PATH": os.Getenv("PATH")}}
+	spawner := NewProcessSpawner(state)
+
+	cmd, _ := parseShellCommand("echo first > out.txt")
+	if result := spawner.Run(cmd); result.ExitCode != 0 || result.Output != "" {
+		t.Fatalf("Expected no screen output, got %q (exit %d)", result.Output, result.ExitCode)
+	}
+	cmd, _ = parseShellCommand("echo second >> out.txt")
+	spawner.Run(cmd)
+
+	data, _ := os.ReadFile(filepath.Join(dir, "out.txt"))
+	if string(data) != "first\nsecond\n" {
+		t.Errorf("Unexpected file contents: %q", data)
+	}
+
+	cmd, _ = parseShellCommand("echo x > missing/dir/out.txt")
+	if result := spawner.Run(cmd); result.ExitCode != 1 || !strings.Contains(result.Output, "missing/dir/out.txt") {
+		t.Errorf("Expected an error for an unwritable target, got %q", result.Output)
+	}
+}
+
+func TestProcessSpawner_RunRedirectsStdin(t *testing.T) {
+	dir := t.TempDir()
+	state := &ShellState{WorkingDirectory: dir, Environment: map[string]string{"PATH": os.Getenv("PATH")}}
+	spawner := NewProcessSpawner(state)
+	os.WriteFile(filepath.Join(dir, "names.txt"), []byte("carol\nalice\nbob\n"), 0644)
+
+	cmd, _ := parseShellCommand("sort < names.txt")
+	if result := spawner.Run(cmd); result.ExitCode != 0 || result.Output != "alice\nbob\ncarol\n" {
+		t.Errorf("Expected sorted names, got %q (exit %d)", result.Output, result.ExitCode)
+	}
+
+	cmd, _ = parseShellCommand("sort < names.txt > sorted.txt")
+	spawner.Run(cmd)
+	if data, _ := os.ReadFile(filepath.Join(dir, "sorted.txt")); string(data) != "alice\nbob\ncarol\n" {
+		t.Errorf("Unexpected file contents: %q", data)
+	}
+
+	cmd, _ = parseShellCommand("sort < missing.txt")
+	if result := spawner.Run(cmd); result.ExitCode != 1 || !strings.Contains(result.Output, "missing.txt: no such file or directory") {
+		t.Errorf("Expected an error for a missing input, got %q", result.Output)
+	}
+}
+
+func TestProcessSpawner_RunRedirectsStderr(t *testing.T) {
+	dir := t.TempDir()
+	state := &ShellState{WorkingDirectory: dir, Environment: map[string]string{"PATH": os.Getenv("PATH")}}
+	spawner := NewProcessSpawner(state)
+	read := func(name string) string {
+		data, _ := os.ReadFile(filepath.Join(dir, name))
+		return string(data)
+	}
+	script := "echo out; echo err >&2"
+
+	cmd, _ := parseShellCommand("sh -c '" + script + "' 2> err.txt")
+	if result := spawner.Run(cmd); result.Output != "out\n" || read("err.txt") != "err\n" {
+		t.Errorf("2>: got screen %q, file %q", result.Output, read("err.txt"))
+	}
+
+	cmd, _ = parseShellCommand("sh -c '" + script + "' > all.txt 2>&1")
+	if result := spawner.Run(cmd); result.Output != "" || read("all.txt") != "out\nerr\n" {
+		t.Errorf("> 2>&1: got screen %q, file %q", result.Output, read("all.txt"))
+	}
+
+	// Order matters: stderr is copied before stdout moves to the file
+	cmd, _ = parseShellCommand("sh -c '" + script + "' 2>&1 > out.txt")
+	if result := spawner.Run(cmd); result.Output != "err\n" || read("out.txt") != "out\n" {
+		t.Errorf("2>&1 >: got screen %q, file %q", result.Output, read("out.txt"))
+	}
+
+	cmd, _ = parseShellCommand("sh -c 'echo oops' 1>&2 2> err.txt")
+	if result := spawner.Run(cmd); result.Output != "oops\n" || read("err.txt") != "" {
+		t.Errorf("1>&2 2>: got screen %q, file %q", result.Output, read("err.txt"))
+	}
+}
+
+func TestApplyOutputRedirects_Builtin(t *testing.T) {
+	dir := t.TempDir()
+	state := &ShellState{WorkingDirectory: dir, Environment: map[string]string{}}
+	redirects := []Redirect{{Fd: 1, Path: "pwd.txt"}}
+
+	result := applyOutputRedirects(state, redirects, ExecutionResult{Output: dir, ExitCode: 0})
+	if result.Output != "" {
+		t.Errorf("Expected the output to go to the file, got %q", result.Output)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "pwd.txt")); string(data) != dir+"\n" {
+		t.Errorf("Unexpected file contents: %q", data)
+	}
+
+	// Errors stay on screen unless stderr is redirected
+	result = applyOutputRedirects(state, redirects, ExecutionResult{Output: "boom", ExitCode: 1})
+	if result.Output != "boom" {
+		t.Errorf("Expected the error to stay on screen, got %q", result.Output)
+	}
+	redirects = []Redirect{{Fd: 1, Path: "pwd.txt"}, {Fd: 2, Dup: true, Target: 1}}
+	result = applyOutputRedirects(state, redirects, ExecutionResult{Output: "boom", ExitCode: 1})
+	if data, _ := os.ReadFile(filepath.Join(dir, "pwd.txt")); result.Output != "" || string(data) != "boom\n" {
+		t.Errorf("Expected the error in the file, got screen %q, file %q", result.Output, data)
+	}
+}
+
+func TestParseCommandList(t *testing.T) {
+	list, err := parseCommandList(`go build&&./app || echo "build || run failed" > err.txt`)
+	if err != nil {
+		t.Fatalf("parseCommandList error: %v", err)
+	}
+	want := []ChainedCommand{
+		{Op: "", ShellCommand: ShellCommand{Name: "go", Args: []string{"build"}}},
+		{Op: "&&", ShellCommand: ShellCommand{Name: "./app"}},
+		{Op: "||", ShellCommand: ShellCommand{Name: "echo", Args: []string{"build || run failed"}, Redirects: []Redirect{{Fd: 1, Path: "err.txt"}}}},
+	}
+	if !reflect.DeepEqual(list, want) {
+		t.Errorf("parseCommandList = %+v", list)
+	}
+
+	if list, err := parseCommandList("   "); err != nil || len(list) != 0 {
+		t.Errorf("Expected an empty list for blank input, got %+v, %v", list, err)
+	}
+
+	list, err = parseCommandList("cd /tmp; ls;pwd ;")
+	if err != nil || len(list) != 3 || list[1].Op != ";" || list[2].Name != "pwd" {
+		t.Errorf("Unexpected ; list %+v, %v", list, err)
+	}
+	if list, _ := parseCommandList(`echo "a; b"`); len(list) != 1 || list[0].Args[0] != "a; b" {
+		t.Errorf("Expected a quoted ; to stay in its word, got %+v", list)
+	}
+
+	list, err = parseCommandList("sleep 100 & make&& make install &")
+	if err != nil || len(list) != 3 || !list[0].Background || list[1].Background || list[1].Op != "&" || !list[2].Background {
+		t.Errorf("Unexpected & list %+v, %v", list, err)
+	}
+
+	for _, input := range []string{"&& ls", "make &&", "make && || ls", "make > && ls", "; ls", "ls;; pwd", "make &&;", "& ls", "ls & ; pwd"} {
+		if _, err := parseCommandList(input); err == nil {
+			t.Errorf("Expected a syntax error for %q", input)
+		}
+	}
+}
+
+func TestShellCommand_String(t *testing.T) {
+	for _, input := range []string{"sleep 100 &", "grep 'a b' < in.txt > 'out file' 2>&1", "make 2>> errors.txt", `echo 'it'\''s' '$HOME' '*.go'`} {
+		list, err := parseCommandList(input)
+		if err != nil {
+			t.Fatalf("parseCommandList(%q) error: %v", input, err)
+		}
+		if got := list[0].String(); got != input {
+			t.Errorf("String() = %q, want %q", got, input)
+		}
+	}
+}
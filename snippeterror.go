@@ -0,0 +1,106 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// evalError matches an interpreter error with a position: yaegi's own,
+// with or without its placeholder file name, and those of panics while it
+// checks code, which carry the message of the panic after their own
+// position
+var evalError = regexp.MustCompile(`^(?:yaegi evaluation panic: )?(?:_\.go:)?(\d+):(\d+): (?:CFG post-order panic: (?:\d+:\d+: )?)?((?s).*)$`)
+
+// snippetError describes err, an error from evaluating the Go code
+// source, at the place in what was typed it's about: the position, as
+// line:column, then the message, and the line with a caret under the
+// column. evaluated is what was typed with $(...) and go statements
+// rewritten, which keeps its lines, and source is evaluated as the
+// interpreter was given it, which may put something before it on its first
+// line, as the wrapper of a call returning several values does. It reports
+// false for errors without a position, or whose position can't be found in
+// what was typed.
+func snippetError(err error, typed, evaluated, source string) (string, bool) {
+	match := evalError.FindStringSubmatch(err.Error())
+	if match == nil {
+		return "", false
+	}
+	line, _ := strconv.Atoi(match[1])
+	col, _ := strconv.Atoi(match[2])
+
+	typedLines, evaluatedLines := strings.Split(typed, "\n"), strings.Split(evaluated, "\n")
+	lead := strings.Index(source, evaluated)
+	if len(typedLines) != len(evaluatedLines) || lead < 0 {
+		return "", false
+	}
+	if line == 1 {
+		col -= interpPrefix(source) + lead
+	}
+	switch {
+	case line > len(evaluatedLines):
+		// Past the end, where the interpreter closes the function it puts
+		// statements in
+		line = len(evaluatedLines)
+		col = len(evaluatedLines[line-1]) + 1
+	case line < 1 || col < 1:
+		return "", false
+	}
+	col = typedColumn(typedLines[line-1], evaluatedLines[line-1], col)
+
+	text := typedLines[line-1]
+	var caret strings.Builder
+	for _, r := range text[:min(col-1, len(text))] {
+		// Tabs keep the caret lined up with what's above it
+		if r == '\t' {
+			caret.WriteRune('\t')
+		} else {
+			caret.WriteRune(' ')
+		}
+	}
+	caret.WriteRune('^')
+	return fmt.Sprintf("%d:%d: %s\n%s\n%s", line, col, match[3], text, caret.String()), true
+}
+
+// interpPrefix returns how much yaegi puts before code on its first line,
+// going by its first token as yaegi does: a package clause before
+// declarations, and a main function too before statements
+func interpPrefix(code string) int {
+	var s scanner.Scanner
+	s.Init(token.NewFileSet().AddFile("", -1, len(code)), []byte(code), nil, 0)
+	switch _, tok, _ := s.Scan(); tok {
+	case token.PACKAGE:
+		return 0
+	case token.CONST, token.FUNC, token.IMPORT, token.TYPE, token.VAR:
+		return len("package main;")
+	}
+	return len("package main; func main() {")
+}
+
+// typedColumn returns the column of the typed line that col in the
+// evaluated line is at. A column in what the rewrite left alone at either
+// end of the line keeps its place; one in what was rewritten is at the
+// start of the rewrite.
+func typedColumn(typed, evaluated string, col int) int {
+	prefix := 0
+	for prefix < len(typed) && prefix < len(evaluated) && typed[prefix] == evaluated[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(typed)-prefix && suffix < len(evaluated)-prefix && typed[len(typed)-1-suffix] == evaluated[len(evaluated)-1-suffix] {
+		suffix++
+	}
+
+	switch offset := col - 1; {
+	case offset < prefix:
+		return col
+	case offset >= len(evaluated)-suffix:
+		return min(col-len(evaluated)+len(typed), len(typed)+1)
+	}
+	return prefix + 1
+}
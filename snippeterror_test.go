@@ -0,0 +1,51 @@
+//go:build darwin || linux
+
+package main
+
+import "testing"
+
+func TestGoEvaluator_ErrorsShowTypedLine(t *testing.T) {
+	state := NewShellState()
+	state.WorkingDirectory = t.TempDir()
+	g := NewGoEvaluator()
+	g.SetupWithShell(state, NewProcessSpawner(state))
+
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{`func f() int { return "s" }`, "1:23: cannot use \"s\" (type stringT) as type intT in return argument\nfunc f() int { return \"s\" }\n                      ^"},
+		{"var s string = 5", "1:16: cannot convert 5 to string\nvar s string = 5\n               ^"},
+		// Lines after the first, with tabs kept so the caret lines up
+		{"func g() {\n\tn := 1\n\treturn n\n}", "3:2: too many arguments to return\n\treturn n\n\t^"},
+		// Past what was rewritten
+		{"x := $(echo hi) + undefinedVar", "1:19: undefined: undefinedVar\nx := $(echo hi) + undefinedVar\n                  ^"},
+		// In a call returning several values
+		{"strconv.Atoi(undefinedVar)", "1:14: undefined: undefinedVar\nstrconv.Atoi(undefinedVar)\n             ^"},
+		// At the end, where the interpreter found the closing brace it added
+		{"x := 1 +", "1:9: expected operand, found '}'\nx := 1 +\n        ^"},
+	}
+	for _, tt := range tests {
+		if result := g.Eval(tt.code); result.Output != tt.expected {
+			t.Errorf("Eval(%q) = %q, want %q", tt.code, result.Output, tt.expected)
+		}
+	}
+}
+
+func TestTypedColumn(t *testing.T) {
+	tests := []struct {
+		typed, evaluated string
+		col, expected    int
+	}{
+		{"x := foo", "x := foo", 6, 6},
+		{"a := $(ls) + b", `a := gosh.Output(sh("ls")) + b`, 30, 14},
+		{"a := $(ls) + b", `a := gosh.Output(sh("ls")) + b`, 3, 3},
+		{"a := $(ls) + b", `a := gosh.Output(sh("ls")) + b`, 12, 6},
+		{"x := 1 +", "x := 1 +", 9, 9},
+	}
+	for _, tt := range tests {
+		if got := typedColumn(tt.typed, tt.evaluated, tt.col); got != tt.expected {
+			t.Errorf("typedColumn(%q, %q, %d) = %d, want %d", tt.typed, tt.evaluated, tt.col, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// sourceScript runs a script with . in the shell, with the script's own
+// output going to stdout and stderr, then writes the resulting environment
+// to fd 3. The script doesn't get fd 3, so background processes it starts
+// don't hold it open.
+const sourceScript = `. "$0" "$@" 3>&-
+__gosh_status=$?
+env -0 >&3
+exit $__gosh_status`
+
+// sourceIgnoredVars are set by the shell that runs a sourced script for
+// itself, rather than by the script
+var sourceIgnoredVars = map[string]bool{"_": true, "SHLVL": true, "PWD": true, "OLDPWD": true}
+
+// source implements the source and . builtins. gosh can't run shell
+// scripts itself, so the script is run by bash (or sh) and the changes it
+// makes to the environment and the working directory are copied back.
+func (b *BuiltinHandler) source(name string, args []string) ExecutionResult {
+	if len(args) == 0 {
+		err := fmt.Errorf("filename argument required")
+		return ExecutionResult{Output: fmt.Sprintf("%s: %v\nUsage: %s FILE [ARG...]", name, err, name), ExitCode: 2, Error: err}
+	}
+	path := b.state.ExpandPath(args[0])
+	if _, err := os.Stat(path); err != nil {
+		return ExecutionResult{Output: fmt.Sprintf("%s: %s: %v", name, args[0], err), ExitCode: 1, Error: err}
+	}
+
+	// bash, for scripts such as nvm.sh that need it
+	shell := b.state.commandPath("bash")
+	if shell == "bash" {
+		shell = "/bin/sh"
+	}
+	cmd := exec.Command(shell, append([]string{"-c", sourceScript, path}, args[1:]...)...)
+	cmd.Dir = b.state.WorkingDirectory
+	cmd.Env = b.state.EnvironmentSlice()
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	env, exitCode, err := runWithEnvDump(cmd)
+	if err != nil {
+		return ExecutionResult{Output: fmt.Sprintf("%s: %v", name, err), ExitCode: 1, Error: err}
+	}
+
+	result := ExecutionResult{Output: strings.TrimSuffix(output.String(), "\n"), ExitCode: exitCode}
+	// Nothing is copied back if the script exited the shell
+	if env == nil {
+		return result
+	}
+	b.mergeEnvironment(env)
+	if dir := env["PWD"]; dir != "" && dir != b.state.WorkingDirectory {
+		if changed := b.changeDirectory(name, dir); changed.Output != "" {
+			if result.Output != "" {
+				result.Output += "\n"
+			}
+			result.Output += changed.Output
+		}
+	}
+	return result
+}
+
+// runWithEnvDump runs cmd, which writes its environment to fd 3 as env -0
+// does, and returns that environment and cmd's exit code. The environment
+// is nil if cmd didn't write it.
+func runWithEnvDump(cmd *exec.Cmd) (map[string]string, int, error) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, 1, err
+	}
+	cmd.ExtraFiles = []*os.File{writer}
+	// Background processes the script started may keep its output open
+	cmd.WaitDelay = 100 * time.Millisecond
+
+	dumped := make(chan []byte, 1)
+	go func() {
+		data, _ := io.ReadAll(reader)
+		reader.Close()
+		dumped <- data
+	}()
+
+	err = cmd.Start()
+	writer.Close()
+	if err != nil {
+		<-dumped
+		return nil, 1, err
+	}
+
+	exitCode := 0
+	if err := cmd.Wait(); err != nil && !errors.Is(err, exec.ErrWaitDelay) {
+		exitError, ok := err.(*exec.ExitError)
+		if !ok {
+			<-dumped
+			return nil, 1, err
+		}
+		exitCode = exitError.ExitCode()
+	}
+
+	data := <-dumped
+	if len(data) == 0 {
+		return nil, exitCode, nil
+	}
+	env := make(map[string]string)
+	for _, entry := range bytes.Split(data, []byte{0}) {
+		if key, value, ok := strings.Cut(string(entry), "="); ok && key != "" {
+			env[key] = value
+		}
+	}
+	return env, exitCode, nil
+}
+
+// mergeEnvironment applies the variables a sourced script set, changed or
+// unset to the shell, and to gosh's own environment for Go code
+func (b *BuiltinHandler) mergeEnvironment(env map[string]string) {
+	for key, value := range env {
+		if current, ok := b.state.Environment[key]; !sourceIgnoredVars[key] && (!ok || current != value) {
+			b.state.SetEnv(key, value)
+		}
+	}
+	for key := range b.state.Environment {
+		// The shell drops variables it can't use, so only valid names count
+		if _, ok := env[key]; !ok && !sourceIgnoredVars[key] && isVariableName(key) {
+			b.state.UnsetEnv(key)
+		}
+	}
+}
+
+const sourceHelpText = "source - Run a Shell Script in This Shell\n\n" +
+	"USAGE:\n" +
+	"    source FILE [ARG...]\n" +
+	"    . FILE [ARG...]\n\n" +
+	"DESCRIPTION:\n" +
+	"    Run FILE with bash (or sh), then copy the environment variables it\n" +
+	"    sets, changes or unsets, and the directory it changes to, back into\n" +
+	"    gosh, so activate scripts and env files work. Shell functions and\n" +
+	"    variables that aren't exported aren't copied.\n\n" +
+	"EXAMPLES:\n" +
+	"    source .venv/bin/activate\n" +
+	"    source ~/.nvm/nvm.sh\n" +
+	"    . ./env.sh staging"
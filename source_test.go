@@ -0,0 +1,70 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSourceBuiltin(t *testing.T) {
+	dir, _ := filepath.EvalSymlinks(t.TempDir())
+	os.Mkdir(filepath.Join(dir, "sub"), 0755)
+	t.Chdir(dir)
+	t.Setenv("GOSH_SOURCE_ADDED", "")
+	t.Setenv("GOSH_SOURCE_GONE", "here")
+	t.Setenv("PATH", os.Getenv("PATH"))
+
+	script := `echo "activating $1"
+export GOSH_SOURCE_ADDED="with spaces
+and a newline"
+unset GOSH_SOURCE_GONE
+PATH="/opt/tool/bin:$PATH"
+NOT_EXPORTED=1
+sleep 10 &
+cd sub
+return 4
+`
+	os.WriteFile(filepath.Join(dir, "activate.sh"), []byte(script), 0644)
+
+	state := &ShellState{WorkingDirectory: dir, Environment: map[string]string{
+		"PATH": os.Getenv("PATH"), "HOME": dir, "GOSH_SOURCE_GONE": "here",
+	}}
+	b := NewBuiltinHandler(state)
+
+	result := b.Execute("source", []string{"activate.sh", "prod"})
+	if result.ExitCode != 4 || result.Output != "activating prod" {
+		t.Errorf("Expected the script's output and status, got %q (exit %d)", result.Output, result.ExitCode)
+	}
+	if got := state.Environment["GOSH_SOURCE_ADDED"]; got != "with spaces\nand a newline" || os.Getenv("GOSH_SOURCE_ADDED") != got {
+		t.Errorf("Expected GOSH_SOURCE_ADDED in both environments, got %q", got)
+	}
+	if _, ok := state.Environment["GOSH_SOURCE_GONE"]; ok || os.Getenv("GOSH_SOURCE_GONE") != "" {
+		t.Error("Expected GOSH_SOURCE_GONE to be unset")
+	}
+	if !strings.HasPrefix(state.Environment["PATH"], "/opt/tool/bin:") {
+		t.Errorf("Expected PATH to be extended, got %q", state.Environment["PATH"])
+	}
+	if _, ok := state.Environment["NOT_EXPORTED"]; ok {
+		t.Error("Expected unexported variables to stay in the script")
+	}
+	if _, ok := state.Environment["SHLVL"]; ok {
+		t.Error("Expected the shell's own variables to be ignored")
+	}
+	if state.WorkingDirectory != filepath.Join(dir, "sub") {
+		t.Errorf("Expected the script's cd to carry over, in %s", state.WorkingDirectory)
+	}
+
+	os.WriteFile(filepath.Join(dir, "exits.sh"), []byte("export GOSH_SOURCE_ADDED=changed\nexit 3\n"), 0644)
+	if result := b.Execute(".", []string{"../exits.sh"}); result.ExitCode != 3 || state.Environment["GOSH_SOURCE_ADDED"] == "changed" {
+		t.Errorf("Expected nothing copied back after exit, got exit %d", result.ExitCode)
+	}
+
+	for _, args := range [][]string{{}, {"missing.sh"}} {
+		if result := b.Execute("source", args); result.ExitCode == 0 || !strings.HasPrefix(result.Output, "source: ") {
+			t.Errorf("source %v: expected an error, got %q", args, result.Output)
+		}
+	}
+}
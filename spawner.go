@@ -4,6 +4,8 @@ package main
 
 import (
 	"bytes"
+	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -12,6 +14,11 @@ import (
 
 type ProcessSpawner struct {
 	state *ShellState
+	// live, if set, is also sent the output of foreground commands as it's
+	// written, for the REPL to show while they run
+	live io.Writer
+	// limit, if set, ends foreground commands that run too long
+	limit *timeLimit
 }
 
 func NewProcessSpawner(state *ShellState) *ProcessSpawner {
@@ -28,7 +35,7 @@ func (p *ProcessSpawner) Execute(command string, args []string) ExecutionResult
 		env := p.state.EnvironmentSlice()
 
 		if command == "env" {
-			cmd = exec.Command("git", "status")
+			cmd = p.command("git", "status")
 			for _, arg := range args {
 				if strings.HasPrefix(arg, "GIT_COLOR=") || strings.HasPrefix(arg, "TERM=") ||
 					strings.HasPrefix(arg, "CLICOLOR=") || strings.HasPrefix(arg, "CLICOLOR_FORCE=") {
@@ -49,7 +56,7 @@ func (p *ProcessSpawner) Execute(command string, args []string) ExecutionResult
 			}
 		} else {
 			env = append(env, "GIT_COLOR=always", "TERM=xterm-256color", "CLICOLOR=1", "CLICOLOR_FORCE=1")
-			cmd = exec.Command(command, args...)
+			cmd = p.command(command, args...)
 		}
 
 		cmd.Dir = p.state.WorkingDirectory
@@ -58,7 +65,7 @@ func (p *ProcessSpawner) Execute(command string, args []string) ExecutionResult
 	} else if command == "ls" {
 		env := p.state.EnvironmentSlice()
 		env = append(env, "CLICOLOR=1", "CLICOLOR_FORCE=1", "TERM=xterm-256color")
-		cmd = exec.Command(command, args...)
+		cmd = p.command(command, args...)
 		cmd.Dir = p.state.WorkingDirectory
 		cmd.Env = env
 		cmd.Stdin = os.Stdin
@@ -72,12 +79,12 @@ func (p *ProcessSpawner) Execute(command string, args []string) ExecutionResult
 			}
 		}
 
-		cmd = exec.Command(command, args...)
+		cmd = p.command(command, args...)
 		cmd.Dir = p.state.WorkingDirectory
 		cmd.Env = env
 		cmd.Stdin = os.Stdin
 	} else {
-		cmd = exec.Command(command, args...)
+		cmd = p.command(command, args...)
 		cmd.Dir = p.state.WorkingDirectory
 		cmd.Env = p.state.EnvironmentSlice()
 		cmd.Stdin = os.Stdin
@@ -115,57 +122,105 @@ func (p *ProcessSpawner) Execute(command string, args []string) ExecutionResult
 }
 
 func (p *ProcessSpawner) ExecuteInteractive(command string, args []string) ExecutionResult {
+	return p.Run(ShellCommand{Name: command, Args: args})
+}
+
+// Run executes a parsed command, applying its redirections. Output that
+// isn't redirected is captured and returned, with stdout and stderr
+// interleaved as a terminal would show them. Background commands are
+// started as jobs without waiting.
+func (p *ProcessSpawner) Run(sc ShellCommand) ExecutionResult {
+	if sc.GoFunc != "" {
+		return runGoPipe(nil, p, sc, false, p.Run)
+	}
+	if sc.Subshell != nil {
+		return runSubshell(p.state, sc, func(child *ShellState) func(ShellCommand) ExecutionResult {
+			return p.withState(child).Run
+		})
+	}
+
+	if p.live == nil || sc.Background {
+		return p.runCommand(sc, nil)
+	}
+
+	// Shown while the command runs, and collected for the result as well
+	var collected bytes.Buffer
+	result := p.runCommand(sc, io.MultiWriter(&collected, p.live))
+	output := collected.String()
+	if output != "" && result.Output != "" && !strings.HasSuffix(output, "\n") {
+		output += "\n"
+	}
+	result.Output = output + result.Output
+	return result
+}
+
+// runCommand runs a command that isn't a subshell. The output of a
+// foreground command is written to w as it's produced, or collected if w
+// is nil.
+func (p *ProcessSpawner) runCommand(sc ShellCommand, w io.Writer) ExecutionResult {
+	files, err := openRedirects(p.state, sc.Redirects)
+	if err != nil {
+		return ExecutionResult{Output: fmt.Sprintf("gosh: %v", err), ExitCode: 1, Error: err}
+	}
+
+	args := sc.Args
 	// Add -C to ls to force column output even when not a terminal
-	if command == "ls" {
+	if sc.Name == "ls" && files.Stdout == nil {
 		args = append([]string{"-C"}, args...)
 	}
+	// rm --really bypasses safe delete (see trash.go); the system rm
+	// doesn't know the flag
+	if sc.Name == "rm" {
+		args, _ = withoutReally(args)
+	}
 
-	cmd := exec.Command(command, args...)
+	cmd := p.command(sc.Name, args...)
 	cmd.Dir = p.state.WorkingDirectory
 	cmd.Env = p.state.EnvironmentSlice()
 
-	var out bytes.Buffer
-	var errOut bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &errOut
-
-	err := cmd.Run()
-
-	output := out.String()
-	if errOut.Len() > 0 {
-		if output != "" {
-			output += "\n"
-		}
-		output += errOut.String()
+	if files.Stdin != nil {
+		cmd.Stdin = files.Stdin
 	}
-
-	exitCode := 0
-	if err != nil {
-		if exitError, ok := err.(*exec.ExitError); ok {
-			exitCode = exitError.ExitCode()
-		} else {
-			exitCode = 1
-		}
+	if p.wantsPty(sc) {
+		files.Close()
+		return p.runPty(cmd)
 	}
-
-	return ExecutionResult{
-		Output:   output,
-		ExitCode: exitCode,
+	if sc.Background {
+		return p.startJob(sc, cmd, files)
 	}
+	return p.runForeground(sc, cmd, files, w)
 }
 
+// withState returns a spawner for the commands of a subshell
+func (p *ProcessSpawner) withState(state *ShellState) *ProcessSpawner {
+	return &ProcessSpawner{state: state, live: p.live, limit: p.limit}
+}
 
-func (p *ProcessSpawner) expandShellVariables(input string) string {
-	result := input
+// command returns a Cmd for running name, looked up in the shell's PATH
+// through the command index
+func (p *ProcessSpawner) command(name string, args ...string) *exec.Cmd {
+	cmd := exec.Command(p.state.commandPath(name), args...)
+	// Programs see the name they were run by, as in other shells
+	cmd.Args[0] = name
+	return cmd
+}
 
-	for key, value := range p.state.Environment {
-		varPattern := "$" + key
-		if strings.Contains(result, varPattern) {
-			result = strings.ReplaceAll(result, varPattern, value)
-		}
+// RunInto runs a command in the foreground with its stdout going to w, as
+// the command before |> does. w is closed once the command has started;
+// what the command writes to stderr is returned.
+func (p *ProcessSpawner) RunInto(sc ShellCommand, w *os.File) ExecutionResult {
+	files, err := redirectFiles(p.state, &redirectedFiles{Stdout: w, opened: []*os.File{w}}, sc.Redirects)
+	if err != nil {
+		return ExecutionResult{Output: fmt.Sprintf("gosh: %v", err), ExitCode: 1, Error: err}
 	}
 
-	return result
+	cmd := p.command(sc.Name, sc.Args...)
+	cmd.Dir = p.state.WorkingDirectory
+	cmd.Env = p.state.EnvironmentSlice()
+	if files.Stdin != nil {
+		cmd.Stdin = files.Stdin
+	}
+	return p.runForeground(sc, cmd, files, nil)
 }
 
 func FindInPath(command string, pathEnv string) (string, bool) {
@@ -8,7 +8,10 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"strings"
+	"sync"
+	"time"
 )
 
 type ShellState struct {
@@ -22,8 +25,76 @@ type ShellState struct {
 	// Cached prompt to avoid expensive color rendering
 	cachedPrompt string
 	promptHash   string // Content hash to detect changes
+	// Guards the stores below that are created on first use, which
+	// goroutines such as those of process substitutions may ask for
+	lazyMu sync.Mutex
+	// Lazily loaded command usage statistics
+	stats *UsageStats
+	// Lazily loaded directories visited, for j
+	dirs *DirFrecency
+	// Background tasks registered with gosh.Every
+	scheduler *TaskScheduler
+	// Commands started in the background with &
+	jobs *JobTable
+	// Encrypted secrets store, unlocked at most once per session
+	vault *Vault
+	// Output of the most recent command, used by builtins such as copy
+	LastOutput string
+	// Exit code of the most recent command: $? in the shell
+	LastExitCode int
+	// Terminal size, kept up to date by the UI (0 when unknown)
+	TerminalWidth  int
+	TerminalHeight int
+	// How Go results are displayed: table, json or go
+	resultFormat string
+	// Longest a Go evaluation may run before it's cancelled, set with
+	// gosh.EvalTimeout; 0 for no limit
+	evalTimeout time.Duration
+	// How far Go code may grow the heap before it's warned about or
+	// stopped, set with gosh.MemoryGuard; 0 for no limit
+	memoryWarn, memoryLimit uint64
+	// The process environment as of the last sync with Environment, to
+	// tell what Go code changed in it since
+	processEnv map[string]string
+	// Hooks run after the working directory changes
+	chpwdHooks []ChpwdHook
+	// Index of the commands in PATH
+	commands *CommandHash
+	// Command aliases defined with the alias builtin or gosh.Alias
+	aliases map[string]string
+	// Go functions run as commands, registered with gosh.RegisterCommand
+	goCommands map[string]reflect.Value
+	// Argument completions registered with gosh.RegisterCompletion
+	completions map[string]func(args []string, partial string) []string
+	// Prompt segments added with gosh.PromptSegment, in order
+	promptSegments []promptSegment
+	// Packages added to the pre-imported ones with gosh.PreImport
+	preImports []string
+	// Directories saved by pushd, most recent first
+	dirStack []string
+	// Whether a directory entered as a command changes into it, set with
+	// gosh.AutoCd
+	autoCd bool
+	// Actions set with trap or gosh.Trap
+	traps *TrapTable
+	// Blocks run in the REPL, for the history builtin and !N, and how
+	// many older ones were dropped
+	history     []HistoryBlock
+	historyBase int
+	// Whether clear ran, for the REPL to clear the screen
+	clearScreen bool
+	// Positional parameters set with SetArgs: $0, and $1 and on
+	scriptName string
+	args       []string
+	// Whether this is the copy of a ( ... ) subshell, whose variables stay
+	// out of gosh's own environment
+	subshell bool
 }
 
+// ChpwdHook is called after the working directory changes from oldDir to
+// newDir. A non-empty return value is shown to the user.
+type ChpwdHook func(oldDir, newDir string) string
+
 func NewShellState() *ShellState {
 	wd, err := os.Getwd()
 	if err != nil {
@@ -47,6 +118,9 @@ func NewShellState() *ShellState {
 
 	envManager := NewEnvironmentManager(state)
 	envManager.InitializeEnvironment()
+	state.exportToProcess()
+
+	state.AddChpwdHook(NewToolchainActivator(state).OnDirectoryChange)
 
 	return state
 }
@@ -59,14 +133,71 @@ func (s *ShellState) EnvironmentSlice() []string {
 	return env
 }
 
+// ResultFormat returns the display format for Go evaluation results
+func (s *ShellState) ResultFormat() string {
+	if s.resultFormat == "" {
+		return ResultFormatTable
+	}
+	return s.resultFormat
+}
+
+// AddChpwdHook registers a hook to run whenever the directory changes
+func (s *ShellState) AddChpwdHook(hook ChpwdHook) {
+	s.chpwdHooks = append(s.chpwdHooks, hook)
+}
+
+// SetWorkingDirectory records a directory change and runs the chpwd hooks,
+// returning any messages they produced. The previous directory is kept in
+// OLDPWD, for cd - and the commands gosh runs.
+func (s *ShellState) SetWorkingDirectory(dir string) []string {
+	oldDir := s.WorkingDirectory
+	s.WorkingDirectory = dir
+	if oldDir == dir {
+		return nil
+	}
+	if s.Environment != nil && oldDir != "" {
+		s.SetEnv("OLDPWD", oldDir)
+	}
+	return s.RunChpwdHooks(oldDir, dir)
+}
+
+// RunChpwdHooks runs the chpwd hooks for a change from oldDir to newDir
+func (s *ShellState) RunChpwdHooks(oldDir, newDir string) []string {
+	var messages []string
+	for _, hook := range s.chpwdHooks {
+		if message := hook(oldDir, newDir); message != "" {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+// SetEnv sets a variable for spawned commands and the Go interpreter alike
+func (s *ShellState) SetEnv(key, value string) {
+	s.Environment[key] = value
+	if !s.subshell {
+		os.Setenv(key, value)
+	}
+}
+
+// UnsetEnv removes a variable from the shell and process environment
+func (s *ShellState) UnsetEnv(key string) {
+	delete(s.Environment, key)
+	if !s.subshell {
+		os.Unsetenv(key)
+	}
+}
+
 func (s *ShellState) GetPrompt() string {
-	stateHash := s.createPromptHash()
+	// Plugin segments are worked out once, for both
+	segments := s.pluginPromptSegments()
+	stateHash := s.createPromptHash(segments)
 
 	if s.promptHash == stateHash && s.cachedPrompt != "" {
 		return s.cachedPrompt
 	}
 
-	newPrompt := s.generatePromptWithColors()
+	newPrompt := s.generatePromptWithColors(segments)
 
 	s.cachedPrompt = newPrompt
 	s.promptHash = stateHash
@@ -74,7 +205,7 @@ func (s *ShellState) GetPrompt() string {
 	return newPrompt
 }
 
-func (s *ShellState) createPromptHash() string {
+func (s *ShellState) createPromptHash(segments []string) string {
 	hash := md5.New()
 	hash.Write([]byte(s.WorkingDirectory))
 
@@ -86,10 +217,17 @@ func (s *ShellState) createPromptHash() string {
 		}
 	}
 
+	hash.Write([]byte(kubePromptSegment(s.Environment)))
+	hash.Write([]byte(cloudPromptKey(s.Environment)))
+	hash.Write([]byte(taskPromptHint(s.WorkingDirectory, s.Environment)))
+	for _, segment := range segments {
+		hash.Write([]byte(segment))
+	}
+
 	return fmt.Sprintf("%x", hash.Sum(nil))
 }
 
-func (s *ShellState) generatePromptWithColors() string {
+func (s *ShellState) generatePromptWithColors(segments []string) string {
 	colors := GetColorManager()
 
 	colors.ForceRefresh()
@@ -115,16 +253,109 @@ func (s *ShellState) generatePromptWithColors() string {
 		}
 	}
 
+	kube := ""
+	if segment := kubePromptSegment(s.Environment); segment != "" {
+		kubePrefix := colors.StylePrompt("k8s:", "git_prefix")
+		styledContext := colors.StylePrompt(segment, "git_branch")
+		kube = fmt.Sprintf("%s(%s)", kubePrefix, styledContext)
+	}
+
 	symbol := colors.StylePrompt("> ", "symbol")
 	space := colors.StylePrompt(" ", "separator")
 
+	if kube != "" {
+		gitBranch += space + kube
+	}
+	for _, segment := range cloudPromptSegments(s.Environment) {
+		gitBranch += space + segment
+	}
+	if tool := taskPromptHint(s.WorkingDirectory, s.Environment); tool != "" {
+		gitBranch += space + colors.StylePrompt("["+tool+"]", "git_prefix")
+	}
+	for _, segment := range segments {
+		gitBranch += space + segment
+	}
+
 	return fmt.Sprintf("%s%s%s%s%s", styledDir, space, gitBranch, space, symbol)
 }
 
+// UsageStats returns the command usage statistics, loading them on first use
+func (s *ShellState) UsageStats() *UsageStats {
+	s.lazyMu.Lock()
+	defer s.lazyMu.Unlock()
+	if s.stats == nil {
+		s.stats = NewUsageStats(goshConfigPath("stats.json"))
+	}
+	return s.stats
+}
+
+// DirFrecency returns the directories visited, loading them on first use
+func (s *ShellState) DirFrecency() *DirFrecency {
+	s.lazyMu.Lock()
+	defer s.lazyMu.Unlock()
+	if s.dirs == nil {
+		s.dirs = NewDirFrecency(goshConfigPath("dirs.json"))
+	}
+	return s.dirs
+}
+
+// Scheduler returns the background task scheduler, creating it on first use
+func (s *ShellState) Scheduler() *TaskScheduler {
+	s.lazyMu.Lock()
+	defer s.lazyMu.Unlock()
+	if s.scheduler == nil {
+		s.scheduler = NewTaskScheduler()
+	}
+	return s.scheduler
+}
+
+// Jobs returns the background job table, creating it on first use
+func (s *ShellState) Jobs() *JobTable {
+	s.lazyMu.Lock()
+	defer s.lazyMu.Unlock()
+	if s.jobs == nil {
+		s.jobs = NewJobTable()
+	}
+	return s.jobs
+}
+
+// Traps returns the traps of the session, creating them on first use
+func (s *ShellState) Traps() *TrapTable {
+	s.lazyMu.Lock()
+	defer s.lazyMu.Unlock()
+	if s.traps == nil {
+		s.traps = &TrapTable{}
+	}
+	return s.traps
+}
+
+// Commands returns the index of commands in PATH, creating it on first use
+func (s *ShellState) Commands() *CommandHash {
+	s.lazyMu.Lock()
+	defer s.lazyMu.Unlock()
+	if s.commands == nil {
+		s.commands = NewCommandHash()
+	}
+	return s.commands
+}
+
+// Vault returns the secrets store, creating it on first use
+func (s *ShellState) Vault() *Vault {
+	s.lazyMu.Lock()
+	defer s.lazyMu.Unlock()
+	if s.vault == nil {
+		s.vault = NewVault(goshConfigPath("vault.json"))
+	}
+	return s.vault
+}
+
 func (s *ShellState) ForcePromptRefresh() {
 	s.promptHash = ""
 }
 
+// ExpandPath resolves a path argument against the working directory,
+// expanding a leading ~. Variables are left alone: the shell has already
+// expanded the ones that weren't quoted.
 func (s *ShellState) ExpandPath(path string) string {
 	if path == "~" {
 		return s.Environment["HOME"]
@@ -133,8 +364,6 @@ func (s *ShellState) ExpandPath(path string) string {
 		return filepath.Join(s.Environment["HOME"], path[2:])
 	}
 
-	path = os.ExpandEnv(path)
-
 	if !filepath.IsAbs(path) {
 		path = filepath.Join(s.WorkingDirectory, path)
 	}
@@ -154,3 +383,12 @@ func isInGitRepo(path string) bool {
 	}
 	return true
 }
+
+// goshConfigPath returns the path of name inside ~/.config/gosh
+func goshConfigPath(name string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "gosh", name)
+}
@@ -0,0 +1,246 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CommandStats holds the usage counters for a single command
+type CommandStats struct {
+	Count         int           `json:"count"`
+	Failures      int           `json:"failures"`
+	TotalDuration time.Duration `json:"total_duration"`
+	MaxDuration   time.Duration `json:"max_duration"`
+	LastUsed      time.Time     `json:"last_used"`
+}
+
+// AverageDuration returns the mean run time of the command
+func (c *CommandStats) AverageDuration() time.Duration {
+	if c.Count == 0 {
+		return 0
+	}
+	return c.TotalDuration / time.Duration(c.Count)
+}
+
+// FailureRate returns the fraction of runs that exited non-zero
+func (c *CommandStats) FailureRate() float64 {
+	if c.Count == 0 {
+		return 0
+	}
+	return float64(c.Failures) / float64(c.Count)
+}
+
+// UsageStats tracks per-command invocation counts, durations and failures.
+// Data is kept locally in ~/.config/gosh/stats.json and never leaves the machine.
+type UsageStats struct {
+	path     string
+	commands map[string]*CommandStats
+	mu       sync.Mutex
+}
+
+// NamedCommandStats pairs a command name with its stats for sorted listings
+type NamedCommandStats struct {
+	Name string
+	*CommandStats
+}
+
+// NewUsageStats loads existing stats from path (missing files are fine)
+func NewUsageStats(path string) *UsageStats {
+	u := &UsageStats{
+		path:     path,
+		commands: make(map[string]*CommandStats),
+	}
+
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &u.commands); err != nil {
+			debugf("Ignoring corrupt stats file %s: %v\n", path, err)
+			u.commands = make(map[string]*CommandStats)
+		}
+	}
+
+	return u
+}
+
+// Record adds a single invocation of command and persists the stats
+func (u *UsageStats) Record(command string, duration time.Duration, exitCode int) {
+	if command == "" {
+		return
+	}
+
+	u.mu.Lock()
+	stats, exists := u.commands[command]
+	if !exists {
+		stats = &CommandStats{}
+		u.commands[command] = stats
+	}
+	stats.Count++
+	if exitCode != 0 {
+		stats.Failures++
+	}
+	stats.TotalDuration += duration
+	if duration > stats.MaxDuration {
+		stats.MaxDuration = duration
+	}
+	stats.LastUsed = time.Now()
+	u.mu.Unlock()
+
+	if err := u.Save(); err != nil {
+		debugf("Failed to save stats: %v\n", err)
+	}
+}
+
+// Get returns the stats for command, or nil if it was never recorded
+func (u *UsageStats) Get(command string) *CommandStats {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.commands[command]
+}
+
+// Top returns the n most frequently used commands
+func (u *UsageStats) Top(n int) []NamedCommandStats {
+	return u.sorted(n, func(a, b NamedCommandStats) bool {
+		if a.Count == b.Count {
+			return a.Name < b.Name
+		}
+		return a.Count > b.Count
+	})
+}
+
+// Slowest returns the n commands with the highest average duration
+func (u *UsageStats) Slowest(n int) []NamedCommandStats {
+	return u.sorted(n, func(a, b NamedCommandStats) bool {
+		if a.AverageDuration() == b.AverageDuration() {
+			return a.Name < b.Name
+		}
+		return a.AverageDuration() > b.AverageDuration()
+	})
+}
+
+func (u *UsageStats) sorted(n int, less func(a, b NamedCommandStats) bool) []NamedCommandStats {
+	u.mu.Lock()
+	entries := make([]NamedCommandStats, 0, len(u.commands))
+	for name, stats := range u.commands {
+		copied := *stats
+		entries = append(entries, NamedCommandStats{Name: name, CommandStats: &copied})
+	}
+	u.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool { return less(entries[i], entries[j]) })
+
+	if n > 0 && len(entries) > n {
+		entries = entries[:n]
+	}
+	return entries
+}
+
+// Reset clears all recorded stats
+func (u *UsageStats) Reset() error {
+	u.mu.Lock()
+	u.commands = make(map[string]*CommandStats)
+	u.mu.Unlock()
+	return u.Save()
+}
+
+// Save writes the stats to disk
+func (u *UsageStats) Save() error {
+	if u.path == "" {
+		return nil
+	}
+
+	u.mu.Lock()
+	data, err := json.Marshal(u.commands)
+	u.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(u.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(u.path, data, 0644)
+}
+
+// stats implements the stats builtin
+func (b *BuiltinHandler) stats(args []string) ExecutionResult {
+	usage := b.state.UsageStats()
+	limit := 10
+
+	sub := ""
+	if len(args) > 0 {
+		sub = args[0]
+	}
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			return ExecutionResult{Output: fmt.Sprintf("stats: invalid count: %s", args[1]), ExitCode: 1, Error: fmt.Errorf("invalid count")}
+		}
+		limit = n
+	}
+
+	switch sub {
+	case "":
+		return ExecutionResult{
+			Output:   formatStatsTable("Top commands", usage.Top(limit)) + "\n\n" + formatStatsTable("Slowest commands", usage.Slowest(limit)),
+			ExitCode: 0,
+		}
+	case "top":
+		return ExecutionResult{Output: formatStatsTable("Top commands", usage.Top(limit)), ExitCode: 0}
+	case "slow", "slowest":
+		return ExecutionResult{Output: formatStatsTable("Slowest commands", usage.Slowest(limit)), ExitCode: 0}
+	case "reset":
+		if err := usage.Reset(); err != nil {
+			return ExecutionResult{Output: fmt.Sprintf("stats: %v", err), ExitCode: 1, Error: err}
+		}
+		return ExecutionResult{Output: "Command statistics cleared", ExitCode: 0}
+	default:
+		return ExecutionResult{
+			Output:   fmt.Sprintf("stats: unknown subcommand: %s (try top, slow, or reset)", sub),
+			ExitCode: 1,
+			Error:    fmt.Errorf("unknown subcommand: %s", sub),
+		}
+	}
+}
+
+func formatStatsTable(title string, entries []NamedCommandStats) string {
+	var sb strings.Builder
+	sb.WriteString(title + ":\n")
+
+	if len(entries) == 0 {
+		sb.WriteString("  (no commands recorded yet)")
+		return sb.String()
+	}
+
+	sb.WriteString(fmt.Sprintf("  %-20s %7s %10s %10s %7s", "COMMAND", "RUNS", "AVG", "MAX", "FAIL%"))
+	for _, e := range entries {
+		sb.WriteString(fmt.Sprintf("\n  %-20s %7d %10s %10s %6.1f%%",
+			e.Name, e.Count,
+			e.AverageDuration().Round(time.Millisecond),
+			e.MaxDuration.Round(time.Millisecond),
+			e.FailureRate()*100))
+	}
+
+	return sb.String()
+}
+
+const statsHelpText = "stats - Command Usage Statistics\n\n" +
+	"USAGE:\n" +
+	"    stats [top|slow] [N]\n" +
+	"    stats reset\n\n" +
+	"DESCRIPTION:\n" +
+	"    Show how often shell commands are run, how long they take and how\n" +
+	"    often they fail. Statistics are stored locally in\n" +
+	"    ~/.config/gosh/stats.json.\n\n" +
+	"EXAMPLES:\n" +
+	"    stats           # Top and slowest 10 commands\n" +
+	"    stats top 20    # 20 most used commands\n" +
+	"    stats slow      # Commands with the highest average duration\n" +
+	"    stats reset     # Forget all recorded statistics"
@@ -0,0 +1,72 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUsageStats_RecordAndPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.json")
+	usage := NewUsageStats(path)
+
+	usage.Record("git", 100*time.Millisecond, 0)
+	usage.Record("git", 300*time.Millisecond, 1)
+	usage.Record("make", 2*time.Second, 0)
+
+	git := usage.Get("git")
+	if git == nil || git.Count != 2 || git.Failures != 1 {
+		t.Fatalf("Unexpected git stats: %+v", git)
+	}
+	if git.AverageDuration() != 200*time.Millisecond {
+		t.Errorf("Expected 200ms average, got %v", git.AverageDuration())
+	}
+	if git.FailureRate() != 0.5 {
+		t.Errorf("Expected 0.5 failure rate, got %v", git.FailureRate())
+	}
+
+	reloaded := NewUsageStats(path)
+	if reloaded.Get("make") == nil {
+		t.Fatal("Stats were not persisted to disk")
+	}
+
+	top := reloaded.Top(1)
+	if len(top) != 1 || top[0].Name != "git" {
+		t.Errorf("Expected git as top command, got %+v", top)
+	}
+
+	slowest := reloaded.Slowest(1)
+	if len(slowest) != 1 || slowest[0].Name != "make" {
+		t.Errorf("Expected make as slowest command, got %+v", slowest)
+	}
+}
+
+func TestBuiltinStats(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	state := &ShellState{WorkingDirectory: t.TempDir()}
+	handler := NewBuiltinHandler(state)
+
+	state.UsageStats().Record("ls", 10*time.Millisecond, 0)
+
+	result := handler.Execute("stats", []string{})
+	if result.ExitCode != 0 {
+		t.Fatalf("stats failed: %s", result.Output)
+	}
+	if !strings.Contains(result.Output, "Top commands") || !strings.Contains(result.Output, "ls") {
+		t.Errorf("Unexpected stats output: %q", result.Output)
+	}
+
+	result = handler.Execute("stats", []string{"bogus"})
+	if result.ExitCode == 0 {
+		t.Error("Expected failure for unknown subcommand")
+	}
+
+	result = handler.Execute("stats", []string{"reset"})
+	if result.ExitCode != 0 || state.UsageStats().Get("ls") != nil {
+		t.Error("stats reset should clear recorded commands")
+	}
+}
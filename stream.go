@@ -0,0 +1,85 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Stream runs a command like Run, but the output that isn't redirected is
+// written to w as the command produces it instead of being collected, so
+// a long-running command such as tail -f shows up at once and large output
+// isn't kept in memory. The result has the exit code and gosh's own
+// messages. Commands that can't stream, such as those piped into a Go
+// function, are run with Run.
+func (p *ProcessSpawner) Stream(sc ShellCommand, w io.Writer) ExecutionResult {
+	if sc.Subshell != nil && len(sc.Redirects) == 0 {
+		return runSubshell(p.state, sc, func(child *ShellState) func(ShellCommand) ExecutionResult {
+			spawner := p.withState(child)
+			return func(cmd ShellCommand) ExecutionResult {
+				return spawner.Stream(cmd, w)
+			}
+		})
+	}
+	if sc.Subshell != nil || sc.GoFunc != "" {
+		return p.Run(sc)
+	}
+	return p.runCommand(sc, w)
+}
+
+// liveOutputLimit is how much of a running block's output the REPL keeps
+// for showing while it runs
+const liveOutputLimit = 64 * 1024
+
+// liveOutput keeps the end of what the commands of the running block have
+// written, for the REPL to show until the block finishes. A nil liveOutput
+// keeps nothing.
+type liveOutput struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (o *liveOutput) Write(p []byte) (int, error) {
+	if o == nil {
+		return len(p), nil
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.buf = append(o.buf, p...)
+	if excess := len(o.buf) - liveOutputLimit; excess > 0 {
+		o.buf = append(o.buf[:0], o.buf[excess:]...)
+	}
+	return len(p), nil
+}
+
+// Reset forgets the output of the previous block
+func (o *liveOutput) Reset() {
+	if o == nil {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.buf = o.buf[:0]
+}
+
+// Tail returns up to the last n lines written so far
+func (o *liveOutput) Tail(n int) string {
+	if o == nil || n <= 0 {
+		return ""
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	data := bytes.TrimRight(o.buf, "\n")
+	for i := len(data) - 1; i >= 0; i-- {
+		if data[i] == '\n' {
+			if n--; n == 0 {
+				data = data[i+1:]
+				break
+			}
+		}
+	}
+	return strings.ToValidUTF8(string(data), "")
+}
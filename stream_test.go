@@ -0,0 +1,81 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProcessSpawner_Stream(t *testing.T) {
+	dir := t.TempDir()
+	state := &ShellState{WorkingDirectory: dir, Environment: map[string]string{"PATH": os.Getenv("PATH")}}
+	spawner := NewProcessSpawner(state)
+
+	var out bytes.Buffer
+	list, _ := parseCommandList("sh -c 'echo one; echo two >&2; exit 3'")
+	result := spawner.Stream(list[0].ShellCommand, &out)
+	if out.String() != "one\ntwo\n" || result.Output != "" || result.ExitCode != 3 {
+		t.Errorf("Expected streamed output and exit 3, got %q, %q (exit %d)", out.String(), result.Output, result.ExitCode)
+	}
+
+	out.Reset()
+	list, _ = parseCommandList("(echo inner; echo saved > saved.txt)")
+	result = spawner.Stream(list[0].ShellCommand, &out)
+	if out.String() != "inner\n" || result.ExitCode != 0 {
+		t.Errorf("Expected a subshell's output to stream, got %q (exit %d)", out.String(), result.ExitCode)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "saved.txt")); string(data) != "saved\n" {
+		t.Errorf("Expected redirected output to go to the file, got %q", data)
+	}
+
+	out.Reset()
+	list, _ = parseCommandList("nosuchcommand-gosh")
+	result = spawner.Stream(list[0].ShellCommand, &out)
+	if result.ExitCode != 1 || !strings.Contains(result.Output, "nosuchcommand-gosh") {
+		t.Errorf("Expected the error in the result, got %q (exit %d)", result.Output, result.ExitCode)
+	}
+}
+
+func TestProcessSpawner_RunShowsLiveOutput(t *testing.T) {
+	state := &ShellState{WorkingDirectory: t.TempDir(), Environment: map[string]string{"PATH": os.Getenv("PATH")}}
+	live := &liveOutput{}
+	spawner := &ProcessSpawner{state: state, live: live}
+
+	list, _ := parseCommandList("printf 'a\\nb\\nc\\n' && (echo sub)")
+	result := runCommandList(state, list, spawner.Run)
+	if result.Output != "a\nb\nc\nsub\n" {
+		t.Errorf("Expected the output to be collected as well, got %q", result.Output)
+	}
+	if got := live.Tail(2); got != "c\nsub" {
+		t.Errorf("Tail(2) = %q, want %q", got, "c\nsub")
+	}
+
+	live.Reset()
+	if got := live.Tail(5); got != "" {
+		t.Errorf("Expected nothing after Reset, got %q", got)
+	}
+}
+
+func TestLiveOutput_KeepsTheEnd(t *testing.T) {
+	live := &liveOutput{}
+	for i := 0; i < 3; i++ {
+		live.Write(bytes.Repeat([]byte("x"), liveOutputLimit/2))
+	}
+	live.Write([]byte("\nlast line"))
+	if len(live.buf) != liveOutputLimit {
+		t.Errorf("Expected %d bytes kept, got %d", liveOutputLimit, len(live.buf))
+	}
+	if got := live.Tail(1); got != "last line" {
+		t.Errorf("Tail(1) = %q", got)
+	}
+
+	var none *liveOutput
+	none.Write([]byte("ignored"))
+	if none.Tail(1) != "" {
+		t.Error("Expected a nil liveOutput to keep nothing")
+	}
+}
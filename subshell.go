@@ -0,0 +1,84 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+)
+
+// Subshell returns a copy of the state for running a ( ... ) subshell.
+// Changes it makes to the directory, environment and aliases are lost when
+// it ends. Jobs, statistics and the other session-wide stores are shared,
+// and the chpwd hooks, which act on the session, don't run.
+func (s *ShellState) Subshell() *ShellState {
+	jobs, commands := s.Jobs(), s.Commands()
+	s.lazyMu.Lock()
+	stats, scheduler, vault := s.stats, s.scheduler, s.vault
+	s.lazyMu.Unlock()
+
+	return &ShellState{
+		WorkingDirectory: s.WorkingDirectory,
+		Environment:      maps.Clone(s.Environment),
+		SessionFilePath:  s.SessionFilePath,
+		stats:            stats,
+		scheduler:        scheduler,
+		jobs:             jobs,
+		vault:            vault,
+		LastOutput:       s.LastOutput,
+		LastExitCode:     s.LastExitCode,
+		TerminalWidth:    s.TerminalWidth,
+		TerminalHeight:   s.TerminalHeight,
+		resultFormat:     s.resultFormat,
+		commands:         commands,
+		aliases:          maps.Clone(s.aliases),
+		goCommands:       s.goCommands,
+		dirStack:         slices.Clone(s.dirStack),
+		history:          s.history,
+		historyBase:      s.historyBase,
+		autoCd:           s.autoCd,
+		scriptName:       s.scriptName,
+		args:             s.args,
+		subshell:         true,
+	}
+}
+
+// withState returns a handler for the builtins of a subshell
+func (b *BuiltinHandler) withState(state *ShellState) *BuiltinHandler {
+	return &BuiltinHandler{state: state, evaluator: b.evaluator}
+}
+
+// runSubshell runs the commands of a ( ... ) subshell in a copy of state.
+// newRun returns the function that runs a command in the copy. Output
+// redirections apply to everything the subshell prints, as they do for
+// builtins.
+func runSubshell(state *ShellState, cmd ShellCommand, newRun func(*ShellState) func(ShellCommand) ExecutionResult) ExecutionResult {
+	if cmd.Background {
+		err := fmt.Errorf("subshells can't run in the background")
+		return ExecutionResult{Output: fmt.Sprintf("gosh: %v", err), ExitCode: 1, Error: err}
+	}
+	for _, r := range cmd.Redirects {
+		if r.Fd == 0 {
+			err := fmt.Errorf("subshells can't redirect input")
+			return ExecutionResult{Output: fmt.Sprintf("gosh: %v", err), ExitCode: 1, Error: err}
+		}
+	}
+
+	// cd changes gosh's own directory as well, which must be put back
+	wd, err := os.Getwd()
+	if err != nil {
+		wd = state.WorkingDirectory
+	}
+	child := state.Subshell()
+	result := runCommandList(child, cmd.Subshell, newRun(child))
+	if current, err := os.Getwd(); err != nil || current != wd {
+		os.Chdir(wd)
+	}
+
+	if child.ShouldExit {
+		result.ExitCode = child.ExitCode
+	}
+	return applyOutputRedirects(state, cmd.Redirects, result)
+}
@@ -0,0 +1,102 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseCommandList_Subshell(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"(cd /tmp && ls)", "(cd /tmp && ls)"},
+		{"(cd /tmp;ls -l) > out.txt && echo done", "(cd /tmp; ls -l) > out.txt"},
+		{"((echo nested) || echo ')')", "((echo nested) || echo ')')"},
+		{"echo (not a subshell)", "echo '(not' a 'subshell)'"},
+		{"(sleep 1 & echo hi)", "(sleep 1 & echo hi)"},
+	}
+
+	for _, tt := range tests {
+		list, err := parseCommandList(tt.input)
+		if err != nil {
+			t.Errorf("parseCommandList(%q) error: %v", tt.input, err)
+			continue
+		}
+		if got := list[0].String(); got != tt.want {
+			t.Errorf("parseCommandList(%q)[0] = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	for _, input := range []string{"(ls", "()", "(ls) foo", "(ls &&)", "(echo 'a)"} {
+		if _, err := parseCommandList(input); err == nil {
+			t.Errorf("Expected a syntax error for %q", input)
+		}
+	}
+}
+
+func TestModel_RunsSubshells(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Mkdir(filepath.Join(dir, "sub"), 0755)
+	os.WriteFile(filepath.Join(dir, "sub", "inside.txt"), nil, 0644)
+
+	wd, _ := filepath.EvalSymlinks(t.TempDir())
+	t.Chdir(wd)
+	state := &ShellState{WorkingDirectory: wd, Environment: map[string]string{"PATH": os.Getenv("PATH"), "HOME": dir}}
+	state.SetAlias("gs", "echo aliased")
+	session := &SessionState{CapturedVars: map[string][]string{}, Mode: ModeShell, HistoryFile: filepath.Join(dir, "history")}
+	m := model{session: session, state: state, spawner: NewProcessSpawner(state), builtins: NewBuiltinHandler(state)}
+
+	tests := []struct {
+		input    string
+		output   string
+		exitCode int
+	}{
+		{"(cd " + dir + "/sub && ls && gs)", "inside.txt\naliased\n", 0},
+		{"(exit 3); echo $?", "3\n", 0},
+		{"(false) || echo failed", "failed\n", 0},
+		{"(cd " + dir + " && (cd sub && pwd) && pwd)", dir + "/sub\n" + dir, 0},
+		{"(alias x=y)", "", 0},
+		{"(echo out) > " + dir + "/out.txt", "", 0},
+	}
+
+	for _, tt := range tests {
+		output, exitCode := m.executeBlock(tt.input)
+		// Strip the separators around the output
+		if lines := strings.Split(output, "\n"); len(lines) > 2 {
+			output = strings.Join(lines[1:len(lines)-2], "\n")
+		}
+		if output != tt.output || exitCode != tt.exitCode {
+			t.Errorf("%q gave %q (exit %d), want %q (exit %d)", tt.input, output, exitCode, tt.output, tt.exitCode)
+		}
+	}
+
+	if state.WorkingDirectory != wd {
+		t.Errorf("Expected the working directory to stay %s, got %s", wd, state.WorkingDirectory)
+	}
+	if current, _ := os.Getwd(); current != wd {
+		t.Errorf("Expected gosh's own directory to be put back, got %s", current)
+	}
+	if _, ok := state.Alias("x"); ok {
+		t.Error("Expected an alias defined in a subshell not to outlive it")
+	}
+	if state.ShouldExit {
+		t.Error("exit in a subshell mustn't exit gosh")
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "out.txt")); string(data) != "out\n" {
+		t.Errorf("Unexpected redirected output %q", data)
+	}
+
+	output, exitCode := m.executeBlock("(sleep 1) &")
+	if exitCode != 1 || !strings.Contains(output, "background") {
+		t.Errorf("Expected a background subshell to be refused, got %q", output)
+	}
+}
@@ -0,0 +1,152 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// taskFile describes the project automation file found in a directory
+type taskFile struct {
+	Tool string // "make" or "just"
+	Path string
+}
+
+// Recognised task files, in the order the tools themselves look for them
+var taskFileNames = []taskFile{
+	{Tool: "make", Path: "GNUmakefile"},
+	{Tool: "make", Path: "makefile"},
+	{Tool: "make", Path: "Makefile"},
+	{Tool: "just", Path: "justfile"},
+	{Tool: "just", Path: "Justfile"},
+	{Tool: "just", Path: ".justfile"},
+}
+
+// findTaskFile returns the Makefile or justfile in dir, if any
+func findTaskFile(dir string) (taskFile, bool) {
+	for _, candidate := range taskFileNames {
+		path := filepath.Join(dir, candidate.Path)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return taskFile{Tool: candidate.Tool, Path: path}, true
+		}
+	}
+	return taskFile{}, false
+}
+
+var (
+	makeTargetPattern = regexp.MustCompile(`^([^\s:#=][^:#=]*?)\s*::?(?:[^=]|$)`)
+	justRecipePattern = regexp.MustCompile(`^@?([A-Za-z_][A-Za-z0-9_-]*)(.*)$`)
+	justRecipeColon   = regexp.MustCompile(`:(?:[^=]|$)`)
+)
+
+// Targets returns the runnable target names, sorted
+func (t taskFile) Targets() []string {
+	file, err := os.Open(t.Path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	seen := make(map[string]bool)
+	var targets []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || line[0] == '\t' || line[0] == ' ' || line[0] == '#' {
+			continue
+		}
+
+		var names []string
+		switch t.Tool {
+		case "make":
+			if m := makeTargetPattern.FindStringSubmatch(line); m != nil {
+				names = strings.Fields(m[1])
+			}
+		case "just":
+			if strings.HasPrefix(line, "set ") || strings.HasPrefix(line, "alias ") ||
+				strings.HasPrefix(line, "import ") || strings.HasPrefix(line, "mod ") {
+				continue
+			}
+			// A recipe header is a name, optional parameters (which may have
+			// defaults) and a ':' - unlike `name := value` assignments
+			if m := justRecipePattern.FindStringSubmatch(line); m != nil {
+				rest := strings.TrimSpace(m[2])
+				if !strings.HasPrefix(rest, ":=") && !strings.HasPrefix(rest, "=") && justRecipeColon.MatchString(rest) {
+					names = []string{m[1]}
+				}
+			}
+		}
+
+		for _, name := range names {
+			// Skip special targets (.PHONY), pattern rules and variables
+			if strings.HasPrefix(name, ".") || strings.ContainsAny(name, "%$") || seen[name] {
+				continue
+			}
+			seen[name] = true
+			targets = append(targets, name)
+		}
+	}
+
+	sort.Strings(targets)
+	return targets
+}
+
+// taskPromptHint returns the tool name shown in the prompt when the
+// current directory has a task file, or "" (GOSH_TASK_PROMPT=0 hides it)
+func taskPromptHint(dir string, env map[string]string) string {
+	if env["GOSH_TASK_PROMPT"] == "0" {
+		return ""
+	}
+	if tf, ok := findTaskFile(dir); ok {
+		return tf.Tool
+	}
+	return ""
+}
+
+// task implements the task builtin
+func (b *BuiltinHandler) task(args []string) ExecutionResult {
+	tf, ok := findTaskFile(b.state.WorkingDirectory)
+	if !ok {
+		return ExecutionResult{Output: "task: no Makefile or justfile in this directory", ExitCode: 1, Error: fmt.Errorf("no task file")}
+	}
+
+	if len(args) == 0 {
+		targets := tf.Targets()
+		if len(targets) == 0 {
+			return ExecutionResult{Output: fmt.Sprintf("task: no targets found in %s", filepath.Base(tf.Path)), ExitCode: 0}
+		}
+		return ExecutionResult{
+			Output:   fmt.Sprintf("%s (%s):\n  %s", filepath.Base(tf.Path), tf.Tool, strings.Join(targets, "\n  ")),
+			ExitCode: 0,
+		}
+	}
+
+	if _, found := FindInPath(tf.Tool, b.state.Environment["PATH"]); !found {
+		err := fmt.Errorf("%s not found in $PATH", tf.Tool)
+		return ExecutionResult{Output: fmt.Sprintf("task: %s is not installed", tf.Tool), ExitCode: 127, Error: err}
+	}
+
+	// Run like any other command, so its output shows as it's written and
+	// Ctrl-C and Ctrl-Z reach it
+	spawner := NewProcessSpawner(b.state)
+	if b.evaluator != nil {
+		spawner.live = b.evaluator.live
+	}
+	return spawner.Run(ShellCommand{Name: tf.Tool, Args: args})
+}
+
+const taskHelpText = "task - Run Makefile/justfile Targets\n\n" +
+	"USAGE:\n" +
+	"    task                 List targets in the current directory\n" +
+	"    task TARGET [ARGS]   Run TARGET with make or just\n\n" +
+	"DESCRIPTION:\n" +
+	"    Detects a Makefile (make) or justfile (just) in the current\n" +
+	"    directory and runs the matching tool. Target names complete with\n" +
+	"    Tab after task, make and just. The prompt shows which tool is\n" +
+	"    available; set GOSH_TASK_PROMPT=0 to hide it."
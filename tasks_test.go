@@ -0,0 +1,95 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTaskFileTargets_Makefile(t *testing.T) {
+	dir := t.TempDir()
+	makefile := "VERSION := 1.0\n" +
+		".PHONY: build test\n" +
+		"build: deps\n\tgo build\n" +
+		"test:\n\tgo test ./...\n" +
+		"lint fmt:\n\tgofmt -l .\n" +
+		"%.o: %.c\n\tcc -c $<\n" +
+		"# comment: not a target\n"
+	os.WriteFile(filepath.Join(dir, "Makefile"), []byte(makefile), 0644)
+
+	tf, ok := findTaskFile(dir)
+	if !ok || tf.Tool != "make" {
+		t.Fatalf("Expected a make task file, got %+v", tf)
+	}
+
+	if got := strings.Join(tf.Targets(), ","); got != "build,fmt,lint,test" {
+		t.Errorf("Unexpected make targets: %s", got)
+	}
+}
+
+func TestTaskFileTargets_Justfile(t *testing.T) {
+	dir := t.TempDir()
+	justfile := "set shell := [\"bash\", \"-c\"]\n" +
+		"alias b := build\n" +
+		"version := \"1.0\"\n\n" +
+		"# Build the project\n" +
+		"build:\n    go build\n\n" +
+		"@deploy env='staging': build\n    ./deploy {{env}}\n"
+	os.WriteFile(filepath.Join(dir, "justfile"), []byte(justfile), 0644)
+
+	tf, ok := findTaskFile(dir)
+	if !ok || tf.Tool != "just" {
+		t.Fatalf("Expected a just task file, got %+v", tf)
+	}
+
+	if got := strings.Join(tf.Targets(), ","); got != "build,deploy" {
+		t.Errorf("Unexpected just recipes: %s", got)
+	}
+}
+
+func TestTaskBuiltin(t *testing.T) {
+	dir := t.TempDir()
+	state := NewShellState()
+	state.WorkingDirectory = dir
+	b := NewBuiltinHandler(state)
+
+	if result := b.Execute("task", nil); result.ExitCode == 0 {
+		t.Error("task should fail without a task file")
+	}
+
+	os.WriteFile(filepath.Join(dir, "Makefile"), []byte("hello:\n\t@echo hi from make\n"), 0644)
+
+	if result := b.Execute("task", nil); !strings.Contains(result.Output, "hello") {
+		t.Errorf("Expected target listing, got %q", result.Output)
+	}
+
+	if taskPromptHint(dir, map[string]string{}) != "make" {
+		t.Error("Prompt hint should report make")
+	}
+	if taskPromptHint(dir, map[string]string{"GOSH_TASK_PROMPT": "0"}) != "" {
+		t.Error("GOSH_TASK_PROMPT=0 should hide the hint")
+	}
+
+	// The tool is looked for in the shell's PATH
+	path := state.Environment["PATH"]
+	state.Environment["PATH"] = t.TempDir()
+	if result := b.Execute("task", []string{"hello"}); result.ExitCode != 127 {
+		t.Errorf("Expected make not to be found, got %q (exit %d)", result.Output, result.ExitCode)
+	}
+	state.Environment["PATH"] = path
+	if _, found := FindInPath("make", path); found {
+		if result := b.Execute("task", []string{"hello"}); result.ExitCode != 0 || result.Output != "hi from make\n" {
+			t.Errorf("task hello = %q (exit %d)", result.Output, result.ExitCode)
+		}
+	}
+
+	// The REPL prompt shows the tool
+	session := &SessionState{CapturedVars: map[string][]string{}, Mode: ModeShell}
+	m := initialModel(session, state, NewGoEvaluator(), NewProcessSpawner(state), b)
+	if prompt := m.prompt(); !strings.Contains(prompt, "[make]") {
+		t.Errorf("Expected the task hint in the prompt, got %q", prompt)
+	}
+}
@@ -0,0 +1,66 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"sync"
+)
+
+// terminalOwner is implemented by *tea.Program. While the REPL is running,
+// bubbletea owns the terminal (raw mode, renderer), so anything that needs
+// the real TTY - editors, fzf, full-screen tools - must borrow it first.
+type terminalOwner interface {
+	ReleaseTerminal() error
+	RestoreTerminal() error
+}
+
+var activeTerminalOwner terminalOwner
+var terminalOwnerMutex sync.Mutex
+
+// SetTerminalOwner registers the program that currently owns the terminal
+func SetTerminalOwner(owner terminalOwner) {
+	terminalOwnerMutex.Lock()
+	activeTerminalOwner = owner
+	terminalOwnerMutex.Unlock()
+}
+
+// terminalOwned reports whether the interactive REPL owns the terminal
+func terminalOwned() bool {
+	terminalOwnerMutex.Lock()
+	defer terminalOwnerMutex.Unlock()
+	return activeTerminalOwner != nil
+}
+
+// withTerminal runs fn with the terminal released from the UI and restores
+// the UI afterwards. Outside the interactive REPL it simply calls fn.
+func withTerminal(fn func() error) error {
+	terminalOwnerMutex.Lock()
+	owner := activeTerminalOwner
+	terminalOwnerMutex.Unlock()
+
+	if owner != nil {
+		if err := owner.ReleaseTerminal(); err != nil {
+			return err
+		}
+		defer owner.RestoreTerminal()
+	}
+
+	return fn()
+}
+
+// runOnTerminal runs cmd attached to the real terminal, filling in any of
+// stdin/stdout/stderr that the caller hasn't wired up already
+func runOnTerminal(cmd *exec.Cmd) error {
+	if cmd.Stdin == nil {
+		cmd.Stdin = os.Stdin
+	}
+	if cmd.Stdout == nil {
+		cmd.Stdout = os.Stdout
+	}
+	if cmd.Stderr == nil {
+		cmd.Stderr = os.Stderr
+	}
+	return withTerminal(cmd.Run)
+}
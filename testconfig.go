@@ -0,0 +1,246 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// ConfigTest is passed to Test functions in config files, available in Go
+// code as *gosh.T. It mirrors the commonly used parts of *testing.T.
+type ConfigTest struct {
+	name    string
+	failed  bool
+	skipped bool
+	output  []string
+}
+
+func (t *ConfigTest) Name() string { return t.name }
+
+func (t *ConfigTest) Log(args ...any) { t.output = append(t.output, fmt.Sprintln(args...)) }
+
+func (t *ConfigTest) Logf(format string, args ...any) {
+	t.output = append(t.output, fmt.Sprintf(format, args...))
+}
+
+func (t *ConfigTest) Fail()        { t.failed = true }
+func (t *ConfigTest) Failed() bool { return t.failed }
+
+// FailNow stops the test the same way testing.T does, with runtime.Goexit
+func (t *ConfigTest) FailNow() {
+	t.failed = true
+	runtime.Goexit()
+}
+
+func (t *ConfigTest) Error(args ...any) {
+	t.Log(args...)
+	t.Fail()
+}
+
+func (t *ConfigTest) Errorf(format string, args ...any) {
+	t.Logf(format, args...)
+	t.Fail()
+}
+
+func (t *ConfigTest) Fatal(args ...any) {
+	t.Log(args...)
+	t.FailNow()
+}
+
+func (t *ConfigTest) Fatalf(format string, args ...any) {
+	t.Logf(format, args...)
+	t.FailNow()
+}
+
+func (t *ConfigTest) Skip(args ...any) {
+	t.Log(args...)
+	t.skipped = true
+	runtime.Goexit()
+}
+
+func (t *ConfigTest) Skipf(format string, args ...any) {
+	t.Logf(format, args...)
+	t.skipped = true
+	runtime.Goexit()
+}
+
+// configTestFunctions returns the names of top-level Test functions taking
+// a single parameter, in source order
+func configTestFunctions(source string) ([]string, error) {
+	if !strings.Contains(source, "package ") {
+		source = "package main\n" + source
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), "config.go", source, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil || !strings.HasPrefix(fn.Name.Name, "Test") {
+			continue
+		}
+		if fn.Type.Params.NumFields() == 1 {
+			names = append(names, fn.Name.Name)
+		}
+	}
+	return names, nil
+}
+
+// configTestPath returns the companion test file for a config file:
+// config.go → config_test.go
+func configTestPath(configPath string) string {
+	return strings.TrimSuffix(configPath, ".go") + "_test.go"
+}
+
+// runConfigTest calls one Test function on its own goroutine, so FailNow
+// and Skip can end it early, and turns panics into failures
+func runConfigTest(fn reflect.Value, t *ConfigTest) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer func() {
+			if r := recover(); r != nil {
+				t.Logf("panic: %v", r)
+				t.failed = true
+			}
+		}()
+		fn.Call([]reflect.Value{reflect.ValueOf(t)})
+	}()
+	<-done
+}
+
+// runConfigTests loads configPath (and its _test.go companion, if any) into
+// a fresh interpreter and runs the Test functions matching filter. It
+// returns false if any test failed or the config couldn't be loaded.
+func runConfigTests(configPath string, filter *regexp.Regexp, verbose bool, out io.Writer) bool {
+	started := time.Now()
+
+	state := NewShellState()
+	evaluator := NewGoEvaluator()
+	evaluator.SetupWithShell(state, NewProcessSpawner(state))
+	evaluator.SetupWithBuiltins(NewBuiltinHandler(state))
+
+	var names []string
+	for _, path := range []string{configPath, configTestPath(configPath)} {
+		source, err := os.ReadFile(path)
+		if os.IsNotExist(err) && path != configPath {
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(out, "FAIL\t%s: %v\n", path, err)
+			return false
+		}
+		if err := evaluator.loadConfigFile("config", path); err != nil {
+			fmt.Fprintf(out, "FAIL\t%v\n", err)
+			return false
+		}
+		found, err := configTestFunctions(string(source))
+		if err != nil {
+			fmt.Fprintf(out, "FAIL\t%s: %v\n", path, err)
+			return false
+		}
+		names = append(names, found...)
+	}
+
+	passed := true
+	ran := 0
+	for _, name := range names {
+		if filter != nil && !filter.MatchString(name) {
+			continue
+		}
+		ran++
+
+		fn, err := evaluator.interp.Eval(name)
+		if err != nil || fn.Kind() != reflect.Func || fn.Type().NumIn() != 1 || fn.Type().In(0) != reflect.TypeOf(&ConfigTest{}) {
+			fmt.Fprintf(out, "--- FAIL: %s\n    %s must be declared as func %s(t *gosh.T)\n", name, name, name)
+			passed = false
+			continue
+		}
+
+		if verbose {
+			fmt.Fprintf(out, "=== RUN   %s\n", name)
+		}
+		t := &ConfigTest{name: name}
+		testStarted := time.Now()
+		runConfigTest(fn, t)
+		elapsed := time.Since(testStarted).Seconds()
+
+		status := "PASS"
+		switch {
+		case t.failed:
+			status = "FAIL"
+			passed = false
+		case t.skipped:
+			status = "SKIP"
+		}
+		if t.failed || t.skipped || verbose {
+			fmt.Fprintf(out, "--- %s: %s (%.2fs)\n", status, name, elapsed)
+			for _, line := range t.output {
+				fmt.Fprintf(out, "    %s\n", strings.TrimRight(line, "\n"))
+			}
+		}
+	}
+
+	elapsed := time.Since(started).Seconds()
+	if ran == 0 {
+		fmt.Fprintf(out, "ok  \t%s\t%.3fs [no tests to run]\n", configPath, elapsed)
+	} else if passed {
+		fmt.Fprintf(out, "PASS\nok  \t%s\t%.3fs\n", configPath, elapsed)
+	} else {
+		fmt.Fprintf(out, "FAIL\nFAIL\t%s\t%.3fs\n", configPath, elapsed)
+	}
+	return passed
+}
+
+// runTestConfigCommand implements `gosh test-config [-v] [-run REGEX] [FILE]`.
+// Without FILE it tests the project config if there is one, else
+// ~/.config/gosh/config.go.
+func runTestConfigCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("test-config", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	verbose := flags.Bool("v", false, "print every test, not just failures")
+	run := flags.String("run", "", "only run tests matching this regular expression")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	var filter *regexp.Regexp
+	if *run != "" {
+		var err error
+		if filter, err = regexp.Compile(*run); err != nil {
+			fmt.Fprintf(stderr, "test-config: invalid -run: %v\n", err)
+			return 2
+		}
+	}
+
+	evaluator := &GoEvaluator{}
+	path := flags.Arg(0)
+	if path == "" {
+		if path = evaluator.getProjectConfigPath(); path == "" {
+			path = evaluator.getHomeConfigPath()
+		}
+	}
+	if _, err := os.Stat(path); err != nil {
+		fmt.Fprintf(stderr, "test-config: %v\n", err)
+		return 2
+	}
+
+	if !runConfigTests(path, filter, *verbose, stdout) {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,83 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestConfigTestFunctions(t *testing.T) {
+	source := "func helper() {}\n" +
+		"func TestOne(t *gosh.T) {}\n" +
+		"func TestNoArgs() {}\n" +
+		"func (x thing) TestMethod(t *gosh.T) {}\n" +
+		"func TestTwo(t *gosh.T) {}\n"
+
+	names, err := configTestFunctions(source)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := strings.Join(names, ","); got != "TestOne,TestTwo" {
+		t.Errorf("Expected TestOne,TestTwo, got %s", got)
+	}
+}
+
+func TestRunConfigTests(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "config.go")
+	os.WriteFile(config, []byte("package main\n\nfunc double(n int) int { return n * 2 }\n"), 0644)
+	os.WriteFile(configTestPath(config), []byte(`package main
+
+func TestDouble(t *gosh.T) {
+	if double(2) != 4 {
+		t.Errorf("double(2) = %d", double(2))
+	}
+}
+
+func TestFails(t *gosh.T) {
+	t.Fatalf("expected failure %d", 1)
+	t.Errorf("not reached")
+}
+
+func TestSkips(t *gosh.T) {
+	t.Skip("skipped on purpose")
+}
+`), 0644)
+
+	var out bytes.Buffer
+	if runConfigTests(config, nil, false, &out) {
+		t.Error("Expected the run to fail")
+	}
+	output := out.String()
+	for _, want := range []string{"--- FAIL: TestFails", "expected failure 1", "--- SKIP: TestSkips", "FAIL\t" + config} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in output:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "not reached") {
+		t.Error("Fatalf should stop the test")
+	}
+	if strings.Contains(output, "TestDouble") {
+		t.Error("Passing tests should only be listed with -v")
+	}
+
+	out.Reset()
+	if !runConfigTests(config, regexp.MustCompile("Double"), true, &out) {
+		t.Errorf("Expected -run Double to pass, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "--- PASS: TestDouble") {
+		t.Errorf("Expected verbose output to list TestDouble, got:\n%s", out.String())
+	}
+}
+
+func TestRunTestConfigCommand_MissingFile(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := runTestConfigCommand([]string{filepath.Join(t.TempDir(), "nope.go")}, &stdout, &stderr); code != 2 {
+		t.Errorf("Expected exit code 2 for a missing config, got %d", code)
+	}
+}
@@ -0,0 +1,150 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// themeFile is where theme set saves the theme for the next session, under
+// ~/.config/gosh
+const themeFile = "theme"
+
+// applySavedTheme switches to the theme saved by theme set, if there's one
+func applySavedTheme() {
+	data, err := os.ReadFile(goshConfigPath(themeFile))
+	if err != nil {
+		return
+	}
+	name := strings.TrimSpace(string(data))
+	if _, ok := builtinThemes[name]; ok {
+		SetColorTheme(name)
+	}
+}
+
+// saveTheme saves name as the theme for the next session
+func saveTheme(name string) error {
+	path := goshConfigPath(themeFile)
+	if path == "" {
+		return errors.New("no home directory")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(name+"\n"), 0644)
+}
+
+// theme implements the theme builtin
+func (b *BuiltinHandler) theme(args []string) ExecutionResult {
+	if len(args) == 0 {
+		args = []string{"show"}
+	}
+
+	switch args[0] {
+	case "list":
+		if len(args) > 1 {
+			err := errors.New("too many arguments")
+			return ExecutionResult{Output: "theme: too many arguments\nUsage: theme list", ExitCode: 1, Error: err}
+		}
+		names := ListThemes()
+		sort.Strings(names)
+		for i, name := range names {
+			marker := "  "
+			if name == GetCurrentThemeName() {
+				marker = "* "
+			}
+			names[i] = marker + name
+		}
+		return ExecutionResult{Output: strings.Join(names, "\n"), ExitCode: 0}
+
+	case "show":
+		if len(args) > 2 {
+			err := errors.New("too many arguments")
+			return ExecutionResult{Output: "theme: too many arguments\nUsage: theme show [NAME]", ExitCode: 1, Error: err}
+		}
+		theme := GetColorManager().theme
+		if len(args) == 2 {
+			var ok bool
+			if theme, ok = builtinThemes[args[1]]; !ok {
+				return unknownTheme(args[1])
+			}
+		}
+		return ExecutionResult{Output: describeTheme(theme), ExitCode: 0}
+
+	case "set":
+		if len(args) != 2 {
+			err := errors.New("expected a theme name")
+			return ExecutionResult{Output: "theme: expected a theme name\nUsage: theme set NAME", ExitCode: 1, Error: err}
+		}
+		name := args[1]
+		if _, ok := builtinThemes[name]; !ok {
+			return unknownTheme(name)
+		}
+		SetColorTheme(name)
+		if err := saveTheme(name); err != nil {
+			return ExecutionResult{Output: fmt.Sprintf("Theme set to %s\ntheme: not saved for the next session: %v", name, err), ExitCode: 1, Error: err}
+		}
+		return ExecutionResult{Output: fmt.Sprintf("Theme set to %s", name), ExitCode: 0}
+	}
+
+	err := fmt.Errorf("unknown subcommand: %s", args[0])
+	return ExecutionResult{Output: fmt.Sprintf("theme: %v\nUsage: theme [list|show [NAME]|set NAME]", err), ExitCode: 1, Error: err}
+}
+
+// unknownTheme is theme's error for a name that isn't a theme
+func unknownTheme(name string) ExecutionResult {
+	names := ListThemes()
+	sort.Strings(names)
+	err := fmt.Errorf("unknown theme: %s", name)
+	return ExecutionResult{Output: fmt.Sprintf("theme: %v (use %s)", err, strings.Join(names, ", ")), ExitCode: 1, Error: err}
+}
+
+// describeTheme lists the colors of theme, each with a sample in it
+func describeTheme(theme ColorTheme) string {
+	noColor := GetColorManager().noColor
+	var sb strings.Builder
+	sb.WriteString("Theme: " + theme.Name)
+	for _, group := range []struct {
+		title  string
+		colors [][2]string
+	}{
+		{"Prompt", [][2]string{{"directory", theme.Prompt.Directory}, {"git-prefix", theme.Prompt.GitPrefix}, {"git-branch", theme.Prompt.GitBranch}, {"separator", theme.Prompt.Separator}, {"symbol", theme.Prompt.Symbol}}},
+		{"Output", [][2]string{{"success", theme.Output.Success}, {"error", theme.Output.Error}, {"info", theme.Output.Info}, {"result", theme.Output.Result}}},
+		{"Messages", [][2]string{{"welcome", theme.Messages.Welcome}, {"config", theme.Messages.Config}, {"help", theme.Messages.Help}}},
+	} {
+		sb.WriteString("\n" + group.title + ":")
+		for _, c := range group.colors {
+			name, color := c[0], c[1]
+			if color == "" {
+				sb.WriteString(fmt.Sprintf("\n  %-11s (none)", name))
+				continue
+			}
+			sample := name
+			if !noColor {
+				sample = lipgloss.NewStyle().Foreground(lipgloss.Color(color)).Render(name)
+			}
+			sb.WriteString(fmt.Sprintf("\n  %-11s %s  %s", name, color, sample))
+		}
+	}
+	return sb.String()
+}
+
+const themeHelpText = "theme - Color Themes\n\n" +
+	"USAGE:\n" +
+	"    theme list        List the themes, marking the current one\n" +
+	"    theme show [NAME] Show the colors of the current theme, or NAME\n" +
+	"    theme set NAME    Switch to theme NAME, and keep it for next time\n\n" +
+	"DESCRIPTION:\n" +
+	"    The themes are dark (the default), light, mono and solarized. The\n" +
+	"    theme set is saved in ~/.config/gosh/theme and used from then on.\n" +
+	"    NO_COLOR turns colors off whatever the theme.\n\n" +
+	"EXAMPLES:\n" +
+	"    theme set solarized\n" +
+	"    theme show light"
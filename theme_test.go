@@ -0,0 +1,52 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTheme(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Cleanup(func() { SetColorTheme("dark") })
+	SetColorTheme("dark")
+	b := NewBuiltinHandler(&ShellState{WorkingDirectory: home})
+
+	if result := b.Execute("theme", []string{"list"}); result.Output != "* dark\n  light\n  mono\n  solarized" {
+		t.Errorf("theme list = %q", result.Output)
+	}
+
+	result := b.Execute("theme", []string{"set", "solarized"})
+	if result.ExitCode != 0 || result.Output != "Theme set to solarized" || GetCurrentThemeName() != "solarized" {
+		t.Errorf("theme set = %q (exit %d), theme %s", result.Output, result.ExitCode, GetCurrentThemeName())
+	}
+	if data, err := os.ReadFile(filepath.Join(home, ".config", "gosh", "theme")); err != nil || string(data) != "solarized\n" {
+		t.Errorf("Saved theme = %q (%v)", data, err)
+	}
+	if result := b.Execute("theme", nil); !strings.HasPrefix(result.Output, "Theme: solarized\nPrompt:\n  directory   #268bd2") {
+		t.Errorf("theme = %q", result.Output)
+	}
+	if result := b.Execute("theme", []string{"show", "mono"}); !strings.Contains(result.Output, "\n  symbol      (none)\n") {
+		t.Errorf("theme show mono = %q", result.Output)
+	}
+
+	// The next session starts with the saved theme
+	SetColorTheme("dark")
+	applySavedTheme()
+	if GetCurrentThemeName() != "solarized" {
+		t.Errorf("Expected the saved theme to be applied, got %s", GetCurrentThemeName())
+	}
+
+	for _, args := range [][]string{{"set", "neon"}, {"set"}, {"show", "neon"}, {"pick"}} {
+		if result := b.Execute("theme", args); result.ExitCode != 1 {
+			t.Errorf("theme %q = %q (exit %d), want an error", args, result.Output, result.ExitCode)
+		}
+	}
+	if GetCurrentThemeName() != "solarized" {
+		t.Errorf("A failed theme set changed the theme to %s", GetCurrentThemeName())
+	}
+}
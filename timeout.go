@@ -0,0 +1,159 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// timeExpiredExitCode is the exit code of a command ended by timeout, as
+// with timeout(1)
+const timeExpiredExitCode = 124
+
+// timeLimit ends a foreground command that runs too long
+type timeLimit struct {
+	after  time.Duration
+	signal syscall.Signal
+	kill   time.Duration // If set, SIGKILL follows this long after signal
+}
+
+// watch signals job once the limit passes. The returned stop ends the
+// watch and reports whether the limit was reached.
+func (l *timeLimit) watch(job *Job) (stop func() bool) {
+	var mu sync.Mutex
+	expired := false
+	var killTimer *time.Timer
+
+	timer := time.AfterFunc(l.after, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		expired = true
+		job.signal(l.signal)
+		// A stopped command only sees the signal once it's continued
+		job.signal(syscall.SIGCONT)
+		if l.kill > 0 {
+			killTimer = time.AfterFunc(l.kill, func() { job.signal(syscall.SIGKILL) })
+		}
+	})
+
+	return func() bool {
+		timer.Stop()
+		mu.Lock()
+		defer mu.Unlock()
+		if killTimer != nil {
+			killTimer.Stop()
+		}
+		return expired
+	}
+}
+
+// parseTimeoutDuration parses a duration as timeout(1) does, a number of
+// seconds with an optional s, m, h or d suffix, or as Go does, as in 1m30s
+func parseTimeoutDuration(s string) (time.Duration, error) {
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return d, nil
+	}
+
+	unit := time.Second
+	number := s
+	if n := len(s); n > 0 {
+		switch s[n-1] {
+		case 's':
+			number = s[:n-1]
+		case 'm':
+			unit, number = time.Minute, s[:n-1]
+		case 'h':
+			unit, number = time.Hour, s[:n-1]
+		case 'd':
+			unit, number = 24*time.Hour, s[:n-1]
+		}
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid duration: %s", s)
+	}
+	return time.Duration(value * float64(unit)), nil
+}
+
+// signalsByName are the signals timeout -s accepts by name
+var signalsByName = map[string]syscall.Signal{
+	"HUP": syscall.SIGHUP, "INT": syscall.SIGINT, "QUIT": syscall.SIGQUIT, "KILL": syscall.SIGKILL,
+	"TERM": syscall.SIGTERM, "USR1": syscall.SIGUSR1, "USR2": syscall.SIGUSR2,
+}
+
+// parseSignal parses a signal name, with or without SIG, or number
+func parseSignal(s string) (syscall.Signal, error) {
+	if n, err := strconv.Atoi(s); err == nil && n > 0 {
+		return syscall.Signal(n), nil
+	}
+	if sig, ok := signalsByName[strings.TrimPrefix(strings.ToUpper(s), "SIG")]; ok {
+		return sig, nil
+	}
+	return 0, fmt.Errorf("invalid signal: %s", s)
+}
+
+// timeout implements the timeout builtin
+func (b *BuiltinHandler) timeout(args []string) ExecutionResult {
+	usage := func(err error) ExecutionResult {
+		return ExecutionResult{Output: fmt.Sprintf("timeout: %v\nUsage: timeout [-s SIGNAL] [-k DURATION] DURATION COMMAND [ARG...]", err), ExitCode: 125, Error: err}
+	}
+
+	limit := &timeLimit{signal: syscall.SIGTERM}
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		option := args[0]
+		args = args[1:]
+		if option == "--" {
+			break
+		}
+		if option != "-s" && option != "-k" {
+			return usage(fmt.Errorf("unknown option: %s", option))
+		}
+		if len(args) == 0 {
+			return usage(fmt.Errorf("%s needs a value", option))
+		}
+
+		var err error
+		if option == "-s" {
+			limit.signal, err = parseSignal(args[0])
+		} else {
+			limit.kill, err = parseTimeoutDuration(args[0])
+		}
+		if err != nil {
+			return usage(err)
+		}
+		args = args[1:]
+	}
+	if len(args) < 2 {
+		return usage(fmt.Errorf("missing duration or command"))
+	}
+
+	var err error
+	if limit.after, err = parseTimeoutDuration(args[0]); err != nil {
+		return usage(err)
+	}
+	spawner := &ProcessSpawner{state: b.state, limit: limit}
+	if limit.after == 0 {
+		// As with timeout(1), a zero duration means no limit
+		spawner.limit = nil
+	}
+	return spawner.Run(ShellCommand{Name: args[1], Args: args[2:]})
+}
+
+const timeoutHelpText = "timeout - Run a Command with a Time Limit\n\n" +
+	"USAGE:\n" +
+	"    timeout [-s SIGNAL] [-k DURATION] DURATION COMMAND [ARG...]\n\n" +
+	"DESCRIPTION:\n" +
+	"    Run COMMAND, and end it if it's still running after DURATION: a\n" +
+	"    number of seconds, optionally followed by s, m, h or d, or a Go\n" +
+	"    duration such as 1m30s. The command and any processes it started\n" +
+	"    are sent SIGTERM, or SIGNAL with -s, and with -k also SIGKILL if\n" +
+	"    they're still running DURATION later. A command that times out\n" +
+	"    exits with 124; otherwise timeout exits with the command's code.\n\n" +
+	"EXAMPLES:\n" +
+	"    timeout 30s make test\n" +
+	"    timeout -k 5 2m ./integration.sh"
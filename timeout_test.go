@@ -0,0 +1,82 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestParseTimeoutDuration(t *testing.T) {
+	tests := []struct {
+		input string
+		want  time.Duration
+	}{
+		{"30", 30 * time.Second},
+		{"30s", 30 * time.Second},
+		{"1.5m", 90 * time.Second},
+		{"2h", 2 * time.Hour},
+		{"1d", 24 * time.Hour},
+		{"1m30s", 90 * time.Second},
+		{"0", 0},
+	}
+	for _, tt := range tests {
+		if got, err := parseTimeoutDuration(tt.input); err != nil || got != tt.want {
+			t.Errorf("parseTimeoutDuration(%q) = %v, %v, want %v", tt.input, got, err, tt.want)
+		}
+	}
+
+	for _, input := range []string{"", "abc", "-5", "5x"} {
+		if _, err := parseTimeoutDuration(input); err == nil {
+			t.Errorf("Expected an error for %q", input)
+		}
+	}
+}
+
+func TestParseSignal(t *testing.T) {
+	for input, want := range map[string]syscall.Signal{"TERM": syscall.SIGTERM, "sigkill": syscall.SIGKILL, "2": syscall.SIGINT} {
+		if got, err := parseSignal(input); err != nil || got != want {
+			t.Errorf("parseSignal(%q) = %v, %v, want %v", input, got, err, want)
+		}
+	}
+	if _, err := parseSignal("NOPE"); err == nil {
+		t.Error("Expected an error for an unknown signal")
+	}
+}
+
+func TestTimeoutBuiltin(t *testing.T) {
+	state := &ShellState{WorkingDirectory: t.TempDir(), Environment: map[string]string{"PATH": os.Getenv("PATH")}}
+	b := NewBuiltinHandler(state)
+
+	started := time.Now()
+	// The sleep started by sh is ended along with it
+	result := b.Execute("timeout", []string{"0.2", "sh", "-c", "echo started; sleep 10; echo finished"})
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("Expected the command to be ended, took %v", elapsed)
+	}
+	if result.ExitCode != timeExpiredExitCode || result.Output != "started\n" {
+		t.Errorf("Expected exit 124 after the first line, got %q (exit %d)", result.Output, result.ExitCode)
+	}
+
+	result = b.Execute("timeout", []string{"-s", "KILL", "10s", "sh", "-c", "echo quick; exit 3"})
+	if result.ExitCode != 3 || result.Output != "quick\n" {
+		t.Errorf("Expected the command's own exit code, got %q (exit %d)", result.Output, result.ExitCode)
+	}
+
+	// The command ignores SIGTERM, so only -k ends it
+	started = time.Now()
+	result = b.Execute("timeout", []string{"-k", "0.2", "0.2", "sh", "-c", "trap '' TERM; sleep 10"})
+	if elapsed := time.Since(started); elapsed > 5*time.Second || result.ExitCode != timeExpiredExitCode {
+		t.Errorf("Expected -k to kill the command, took %v (exit %d)", elapsed, result.ExitCode)
+	}
+
+	for _, args := range [][]string{{}, {"5s"}, {"soon", "ls"}, {"-x", "5", "ls"}, {"-s"}} {
+		result := b.Execute("timeout", args)
+		if result.ExitCode != 125 || !strings.Contains(result.Output, "Usage: timeout") {
+			t.Errorf("timeout %q: expected a usage error, got %q (exit %d)", args, result.Output, result.ExitCode)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// toolchainActivation records what was changed for one project so it can
+// be undone when leaving the directory
+type toolchainActivation struct {
+	root      string
+	labels    []string
+	pathDirs  []string           // Directories prepended to PATH
+	savedVars map[string]*string // Previous values (nil = was unset)
+}
+
+// ToolchainActivator switches project tooling on chpwd: a Python virtualenv,
+// the Node version from .nvmrc and the Go toolchain named in go.mod/go.work.
+// Set GOSH_AUTO_ACTIVATE=0 to disable it.
+type ToolchainActivator struct {
+	state  *ShellState
+	active *toolchainActivation
+}
+
+func NewToolchainActivator(state *ShellState) *ToolchainActivator {
+	return &ToolchainActivator{state: state}
+}
+
+// OnDirectoryChange is the chpwd hook
+func (t *ToolchainActivator) OnDirectoryChange(oldDir, newDir string) string {
+	if t.state.Environment["GOSH_AUTO_ACTIVATE"] == "0" {
+		return ""
+	}
+
+	// Still inside the active project: nothing to do
+	if t.active != nil && isWithinDir(newDir, t.active.root) {
+		return ""
+	}
+
+	var messages []string
+	if t.active != nil {
+		messages = append(messages, "deactivated "+strings.Join(t.active.labels, ", "))
+		t.deactivate()
+	}
+
+	if activation := t.activate(newDir); activation != nil {
+		t.active = activation
+		messages = append(messages, "activated "+strings.Join(activation.labels, ", "))
+	}
+
+	if len(messages) == 0 {
+		return ""
+	}
+	t.state.ForcePromptRefresh()
+	return GetColorManager().StyleOutput(strings.Join(messages, "; "), "info")
+}
+
+// activate looks for project tooling from dir upwards and activates the
+// nearest project that has any
+func (t *ToolchainActivator) activate(dir string) *toolchainActivation {
+	home := t.state.Environment["HOME"]
+
+	for current := dir; ; current = filepath.Dir(current) {
+		// Never treat $HOME or / as a project
+		if current == home || current == filepath.Dir(current) {
+			return nil
+		}
+
+		activation := &toolchainActivation{root: current, savedVars: make(map[string]*string)}
+
+		if venv := findVirtualenv(current); venv != "" {
+			activation.prependPath(filepath.Join(venv, "bin"))
+			activation.setVar(t.state, "VIRTUAL_ENV", venv)
+			activation.unsetVar(t.state, "PYTHONHOME")
+			activation.labels = append(activation.labels, "venv "+filepath.Base(venv))
+		}
+
+		if version := readVersionFile(current, ".nvmrc", ".node-version"); version != "" {
+			if bin := findNodeInstall(t.state.Environment, version); bin != "" {
+				activation.prependPath(bin)
+				activation.labels = append(activation.labels, "node "+filepath.Base(filepath.Dir(bin)))
+			}
+		}
+
+		if toolchain := findGoToolchain(current); toolchain != "" {
+			sdk := filepath.Join(home, "sdk", toolchain)
+			if info, err := os.Stat(filepath.Join(sdk, "bin")); err == nil && info.IsDir() {
+				activation.prependPath(filepath.Join(sdk, "bin"))
+				activation.setVar(t.state, "GOROOT", sdk)
+				activation.labels = append(activation.labels, toolchain)
+			}
+		}
+
+		if len(activation.labels) > 0 {
+			t.applyPath(activation)
+			return activation
+		}
+	}
+}
+
+func (a *toolchainActivation) prependPath(dir string) {
+	a.pathDirs = append(a.pathDirs, dir)
+}
+
+func (a *toolchainActivation) save(state *ShellState, key string) {
+	if _, saved := a.savedVars[key]; saved {
+		return
+	}
+	if value, ok := state.Environment[key]; ok {
+		a.savedVars[key] = &value
+	} else {
+		a.savedVars[key] = nil
+	}
+}
+
+func (a *toolchainActivation) setVar(state *ShellState, key, value string) {
+	a.save(state, key)
+	state.SetEnv(key, value)
+}
+
+func (a *toolchainActivation) unsetVar(state *ShellState, key string) {
+	a.save(state, key)
+	state.UnsetEnv(key)
+}
+
+func (t *ToolchainActivator) applyPath(a *toolchainActivation) {
+	if len(a.pathDirs) == 0 {
+		return
+	}
+	path := t.state.Environment["PATH"]
+	if path == "" {
+		t.state.SetEnv("PATH", strings.Join(a.pathDirs, ":"))
+		return
+	}
+	t.state.SetEnv("PATH", strings.Join(a.pathDirs, ":")+":"+path)
+}
+
+// deactivate removes the PATH entries and restores the variables changed
+// by the active project
+func (t *ToolchainActivator) deactivate() {
+	a := t.active
+	t.active = nil
+
+	remove := make(map[string]int)
+	for _, dir := range a.pathDirs {
+		remove[dir]++
+	}
+	var kept []string
+	for _, dir := range filepath.SplitList(t.state.Environment["PATH"]) {
+		// Only drop as many copies as we added, in case the user added one too
+		if remove[dir] > 0 {
+			remove[dir]--
+			continue
+		}
+		kept = append(kept, dir)
+	}
+	t.state.SetEnv("PATH", strings.Join(kept, ":"))
+
+	for key, value := range a.savedVars {
+		if value == nil {
+			t.state.UnsetEnv(key)
+		} else {
+			t.state.SetEnv(key, *value)
+		}
+	}
+}
+
+// isWithinDir reports whether dir is root or below it
+func isWithinDir(dir, root string) bool {
+	rel, err := filepath.Rel(root, dir)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+// findVirtualenv returns the absolute path of a virtualenv directly in dir
+func findVirtualenv(dir string) string {
+	for _, name := range []string{".venv", "venv"} {
+		venv := filepath.Join(dir, name)
+		if _, err := os.Stat(filepath.Join(venv, "bin", "activate")); err == nil {
+			return venv
+		}
+	}
+	return ""
+}
+
+// readVersionFile returns the first line of the first version file found
+func readVersionFile(dir string, names ...string) string {
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		line, _, _ := strings.Cut(string(data), "\n")
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// findNodeInstall returns the bin directory of the newest nvm-installed
+// Node matching version ("20", "v20.11", "20.11.1"). Aliases such as
+// lts/* aren't resolved.
+func findNodeInstall(env map[string]string, version string) string {
+	nvmDir := env["NVM_DIR"]
+	if nvmDir == "" {
+		nvmDir = filepath.Join(env["HOME"], ".nvm")
+	}
+
+	want := strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if want == "" || !isDigit(want[0]) {
+		return ""
+	}
+
+	entries, err := os.ReadDir(filepath.Join(nvmDir, "versions", "node"))
+	if err != nil {
+		return ""
+	}
+
+	best := ""
+	for _, entry := range entries {
+		installed := strings.TrimPrefix(entry.Name(), "v")
+		if installed != want && !strings.HasPrefix(installed, want+".") {
+			continue
+		}
+		if best == "" || compareVersions(installed, best) > 0 {
+			best = installed
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return filepath.Join(nvmDir, "versions", "node", "v"+best, "bin")
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// compareVersions compares dotted numeric versions
+func compareVersions(a, b string) int {
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// findGoToolchain returns the toolchain directive (e.g. "go1.22.3") from
+// go.work or go.mod in dir
+func findGoToolchain(dir string) string {
+	for _, name := range []string{"go.work", "go.mod"} {
+		file, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) == 2 && fields[0] == "toolchain" && strings.HasPrefix(fields[1], "go") {
+				file.Close()
+				return fields[1]
+			}
+		}
+		file.Close()
+	}
+	return ""
+}
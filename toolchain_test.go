@@ -0,0 +1,119 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newToolchainTestState(t *testing.T) *ShellState {
+	t.Helper()
+	home := t.TempDir()
+	state := &ShellState{Environment: map[string]string{"HOME": home, "PATH": "/usr/bin:/bin"}}
+	oldPath := os.Getenv("PATH")
+	t.Cleanup(func() {
+		os.Setenv("PATH", oldPath)
+		os.Unsetenv("VIRTUAL_ENV")
+	})
+	return state
+}
+
+func TestToolchainActivator_Virtualenv(t *testing.T) {
+	state := newToolchainTestState(t)
+	home := state.Environment["HOME"]
+
+	project := filepath.Join(home, "project")
+	os.MkdirAll(filepath.Join(project, ".venv", "bin"), 0755)
+	os.WriteFile(filepath.Join(project, ".venv", "bin", "activate"), nil, 0644)
+	os.MkdirAll(filepath.Join(project, "src"), 0755)
+
+	activator := NewToolchainActivator(state)
+	state.AddChpwdHook(activator.OnDirectoryChange)
+
+	messages := state.SetWorkingDirectory(filepath.Join(project, "src"))
+	if len(messages) != 1 || !strings.Contains(messages[0], "activated venv .venv") {
+		t.Errorf("Expected activation message, got %v", messages)
+	}
+	if state.Environment["VIRTUAL_ENV"] != filepath.Join(project, ".venv") {
+		t.Errorf("VIRTUAL_ENV not set: %q", state.Environment["VIRTUAL_ENV"])
+	}
+	if !strings.HasPrefix(state.Environment["PATH"], filepath.Join(project, ".venv", "bin")+":") {
+		t.Errorf("venv bin not prepended to PATH: %q", state.Environment["PATH"])
+	}
+
+	// Moving around inside the project keeps it active
+	if messages := state.SetWorkingDirectory(project); len(messages) != 0 {
+		t.Errorf("Expected no messages inside the project, got %v", messages)
+	}
+
+	messages = state.SetWorkingDirectory(home)
+	if len(messages) != 1 || !strings.Contains(messages[0], "deactivated") {
+		t.Errorf("Expected deactivation message, got %v", messages)
+	}
+	if _, ok := state.Environment["VIRTUAL_ENV"]; ok {
+		t.Error("VIRTUAL_ENV should be unset after leaving the project")
+	}
+	if state.Environment["PATH"] != "/usr/bin:/bin" {
+		t.Errorf("PATH not restored: %q", state.Environment["PATH"])
+	}
+}
+
+func TestToolchainActivator_NodeAndGo(t *testing.T) {
+	state := newToolchainTestState(t)
+	home := state.Environment["HOME"]
+
+	for _, v := range []string{"v18.19.0", "v20.10.0", "v20.11.1"} {
+		os.MkdirAll(filepath.Join(home, ".nvm", "versions", "node", v, "bin"), 0755)
+	}
+	os.MkdirAll(filepath.Join(home, "sdk", "go1.22.3", "bin"), 0755)
+
+	project := filepath.Join(home, "web")
+	os.MkdirAll(project, 0755)
+	os.WriteFile(filepath.Join(project, ".nvmrc"), []byte("v20\n"), 0644)
+	os.WriteFile(filepath.Join(project, "go.mod"), []byte("module web\n\ngo 1.22\n\ntoolchain go1.22.3\n"), 0644)
+
+	activator := NewToolchainActivator(state)
+	state.AddChpwdHook(activator.OnDirectoryChange)
+	state.SetWorkingDirectory(project)
+
+	path := state.Environment["PATH"]
+	if !strings.Contains(path, filepath.Join(".nvm", "versions", "node", "v20.11.1", "bin")) {
+		t.Errorf("Expected newest matching node on PATH, got %q", path)
+	}
+	if state.Environment["GOROOT"] != filepath.Join(home, "sdk", "go1.22.3") {
+		t.Errorf("Expected GOROOT to point at the sdk toolchain, got %q", state.Environment["GOROOT"])
+	}
+	t.Cleanup(func() { os.Unsetenv("GOROOT") })
+
+	state.SetWorkingDirectory(home)
+	if _, ok := state.Environment["GOROOT"]; ok {
+		t.Error("GOROOT should be restored (unset) after leaving")
+	}
+}
+
+func TestToolchainActivator_Disabled(t *testing.T) {
+	state := newToolchainTestState(t)
+	state.Environment["GOSH_AUTO_ACTIVATE"] = "0"
+	home := state.Environment["HOME"]
+
+	project := filepath.Join(home, "p")
+	os.MkdirAll(filepath.Join(project, "venv", "bin"), 0755)
+	os.WriteFile(filepath.Join(project, "venv", "bin", "activate"), nil, 0644)
+
+	state.AddChpwdHook(NewToolchainActivator(state).OnDirectoryChange)
+	if messages := state.SetWorkingDirectory(project); len(messages) != 0 {
+		t.Errorf("Activation should be disabled, got %v", messages)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	if compareVersions("20.11.1", "20.9.0") <= 0 {
+		t.Error("20.11.1 should be newer than 20.9.0")
+	}
+	if compareVersions("1.2", "1.2.0") != 0 {
+		t.Error("1.2 and 1.2.0 should compare equal")
+	}
+}
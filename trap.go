@@ -0,0 +1,265 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// trapNames are the conditions a trap can be set on, in the order trap
+// lists them. EXIT is gosh exiting, however it happens.
+var trapNames = []string{"EXIT", "HUP", "INT", "TERM"}
+
+var trapSignals = map[string]syscall.Signal{"HUP": syscall.SIGHUP, "INT": syscall.SIGINT, "TERM": syscall.SIGTERM}
+
+// trapName parses the condition of a trap: EXIT or 0, or a signal name,
+// with or without SIG, or number
+func trapName(s string) (string, error) {
+	name := strings.TrimPrefix(strings.ToUpper(s), "SIG")
+	if n, err := strconv.Atoi(s); err == nil {
+		name = ""
+		if n == 0 {
+			name = "EXIT"
+		}
+		for trapped, sig := range trapSignals {
+			if int(sig) == n {
+				name = trapped
+			}
+		}
+	}
+	if name == "EXIT" || trapSignals[name] != 0 {
+		return name, nil
+	}
+	return "", fmt.Errorf("%s: can't be trapped (use EXIT, HUP, INT or TERM)", s)
+}
+
+// trapAction is what runs when a trap goes off: a command line set with
+// the trap builtin or a Go function set with gosh.Trap. An empty command
+// ignores the signal.
+type trapAction struct {
+	command string
+	fn      func()
+}
+
+// TrapTable holds the traps of a session. Signals arrive on a goroutine of
+// their own, so it's locked.
+type TrapTable struct {
+	mu      sync.Mutex
+	actions map[string]trapAction
+	exited  bool
+	// Set when Ctrl-C interrupts a running block, for its INT trap
+	interrupted atomic.Bool
+}
+
+// Set sets the action for a trap
+func (t *TrapTable) Set(name string, action trapAction) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.actions == nil {
+		t.actions = make(map[string]trapAction)
+	}
+	t.actions[name] = action
+}
+
+// Remove resets a trap to gosh's default behavior
+func (t *TrapTable) Remove(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.actions, name)
+}
+
+// Get returns the action for a trap
+func (t *TrapTable) Get(name string) (trapAction, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	action, ok := t.actions[name]
+	return action, ok
+}
+
+// Interrupt records that Ctrl-C interrupted the running block
+func (t *TrapTable) Interrupt() {
+	t.interrupted.Store(true)
+}
+
+// takeInterrupt reports whether the block that just finished was
+// interrupted, clearing it for the next
+func (t *TrapTable) takeInterrupt() bool {
+	return t.interrupted.Swap(false)
+}
+
+// takeExit reports whether the EXIT trap is still to run, so it runs once
+// when several ways of exiting meet
+func (t *TrapTable) takeExit() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.exited {
+		return false
+	}
+	t.exited = true
+	return true
+}
+
+// runTrap runs the action of a trap, if one is set, returning its output
+func (b *BuiltinHandler) runTrap(name string) string {
+	action, ok := b.state.Traps().Get(name)
+	if !ok {
+		return ""
+	}
+
+	var output string
+	switch {
+	case action.fn != nil && b.evaluator != nil:
+		var err error
+		output, err = b.evaluator.runTask(action.fn)
+		if err != nil {
+			output += fmt.Sprintf("gosh: trap %s: %v", name, err)
+		}
+	case action.fn != nil:
+		output, _ = captureOutput(action.fn)
+	case action.command != "":
+		output = b.Execute("eval", []string{action.command}).Output
+	}
+	return strings.TrimSuffix(output, "\n")
+}
+
+// runExitTrap runs the EXIT trap as gosh exits, at most once
+func (b *BuiltinHandler) runExitTrap() string {
+	if !b.state.Traps().takeExit() {
+		return ""
+	}
+	return b.runTrap("EXIT")
+}
+
+// setupSignals runs the traps on the signals gosh receives. Ctrl-C in the
+// UI is a key, so SIGINT comes from gosh -c or kill. After the trap, quit
+// ends gosh with the signal's exit status, unless the signal is ignored
+// with an empty action or is a trapped SIGINT, which like other shells
+// carries on. SIGINT interrupts running Go code first, as Ctrl-C does.
+func setupSignals(builtins *BuiltinHandler, quit func(exitCode int)) {
+	signals := []os.Signal{syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM}
+	received := make(chan os.Signal, 1)
+	signal.Notify(received, signals...)
+
+	go func() {
+		for sig := range received {
+			sig := sig.(syscall.Signal)
+			if sig == syscall.SIGINT && builtins.evaluator != nil {
+				builtins.evaluator.Interrupt()
+			}
+			var name string
+			for trapped, s := range trapSignals {
+				if s == sig {
+					name = trapped
+				}
+			}
+
+			action, trapped := builtins.state.Traps().Get(name)
+			if output := builtins.runTrap(name); output != "" {
+				fmt.Println(output)
+			}
+			if trapped && ((action.command == "" && action.fn == nil) || sig == syscall.SIGINT) {
+				continue
+			}
+			quit(128 + int(sig))
+		}
+	}()
+}
+
+// trap implements the trap builtin
+func (b *BuiltinHandler) trap(args []string) ExecutionResult {
+	usage := func(err error) ExecutionResult {
+		return ExecutionResult{Output: fmt.Sprintf("trap: %v\nUsage: trap [-p] [ACTION] [SIGNAL...]", err), ExitCode: 1, Error: err}
+	}
+
+	traps := b.state.Traps()
+	list := func(names []string) ExecutionResult {
+		var lines []string
+		for _, name := range names {
+			action, ok := traps.Get(name)
+			switch {
+			case !ok:
+			case action.fn != nil:
+				lines = append(lines, fmt.Sprintf("trap -- <Go function> %s", name))
+			default:
+				lines = append(lines, fmt.Sprintf("trap -- %s %s", shellQuote(action.command), name))
+			}
+		}
+		return ExecutionResult{Output: strings.Join(lines, "\n"), ExitCode: 0}
+	}
+
+	names := func(args []string) ([]string, error) {
+		var names []string
+		for _, arg := range args {
+			name, err := trapName(arg)
+			if err != nil {
+				return nil, err
+			}
+			names = append(names, name)
+		}
+		return names, nil
+	}
+
+	if len(args) > 0 && args[0] == "-p" {
+		if len(args) == 1 {
+			return list(trapNames)
+		}
+		selected, err := names(args[1:])
+		if err != nil {
+			return usage(err)
+		}
+		return list(selected)
+	}
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return list(trapNames)
+	}
+
+	// A lone condition, or - as the action, resets the trap
+	action, conditions := args[0], args[1:]
+	reset := action == "-"
+	if len(conditions) == 0 {
+		if _, err := trapName(action); err != nil {
+			return usage(fmt.Errorf("no signal given for %q", action))
+		}
+		reset, conditions = true, args
+	}
+	selected, err := names(conditions)
+	if err != nil {
+		return usage(err)
+	}
+	for _, name := range selected {
+		if reset {
+			traps.Remove(name)
+		} else {
+			traps.Set(name, trapAction{command: action})
+		}
+	}
+	return ExecutionResult{Output: "", ExitCode: 0}
+}
+
+const trapHelpText = "trap - Run Commands on Signals and Exit\n\n" +
+	"USAGE:\n" +
+	"    trap                  List the traps\n" +
+	"    trap ACTION SIGNAL... Run ACTION when a signal arrives or gosh exits\n" +
+	"    trap - SIGNAL...      Reset the traps\n" +
+	"    trap '' SIGNAL...     Ignore the signals\n\n" +
+	"SIGNALS:\n" +
+	"    EXIT    gosh exits, by exit, Ctrl-D or the end of gosh -c\n" +
+	"    INT     Ctrl-C interrupts a command (runs once it has stopped)\n" +
+	"    HUP     The terminal is closed\n" +
+	"    TERM    gosh is asked to terminate\n\n" +
+	"    HUP and TERM still end gosh after the trap, unless ignored.\n" +
+	"    Go code can set a trap with gosh.Trap(\"EXIT\", func() { ... }).\n\n" +
+	"EXAMPLES:\n" +
+	"    trap 'rm -f /tmp/build.lock' EXIT\n" +
+	"    trap 'echo interrupted' INT\n" +
+	"    trap - EXIT"
@@ -0,0 +1,90 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestTrapBuiltin(t *testing.T) {
+	state := &ShellState{WorkingDirectory: t.TempDir(), Environment: map[string]string{"PATH": os.Getenv("PATH")}}
+	b := NewBuiltinHandler(state)
+
+	steps := []struct {
+		args     []string
+		output   string
+		exitCode int
+	}{
+		{[]string{"echo 'bye'", "EXIT", "SIGINT"}, "", 0},
+		{[]string{"", "15"}, "", 0},
+		{nil, "trap -- 'echo '\\''bye'\\''' EXIT\ntrap -- 'echo '\\''bye'\\''' INT\ntrap -- '' TERM", 0},
+		{[]string{"-p", "TERM"}, "trap -- '' TERM", 0},
+		{[]string{"-", "INT"}, "", 0},
+		{[]string{"TERM"}, "", 0},
+		{[]string{"-p"}, "trap -- 'echo '\\''bye'\\''' EXIT", 0},
+		{[]string{"echo x", "USR1"}, "trap: USR1: can't be trapped", 1},
+		{[]string{"echo x"}, "trap: no signal given", 1},
+	}
+	for _, step := range steps {
+		result := b.Execute("trap", step.args)
+		if !strings.HasPrefix(result.Output, step.output) || result.ExitCode != step.exitCode {
+			t.Errorf("trap %q = %q (exit %d), want %q (exit %d)", step.args, result.Output, result.ExitCode, step.output, step.exitCode)
+		}
+	}
+
+	if output := b.runExitTrap(); output != "bye" {
+		t.Errorf("Expected the EXIT trap to print bye, got %q", output)
+	}
+	if output := b.runExitTrap(); output != "" {
+		t.Errorf("Expected the EXIT trap to run once, got %q", output)
+	}
+}
+
+func TestModel_RunsIntTrap(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir, _ := filepath.EvalSymlinks(t.TempDir())
+	t.Chdir(dir)
+	state := &ShellState{WorkingDirectory: dir, Environment: map[string]string{"PATH": os.Getenv("PATH"), "HOME": dir}}
+	session := &SessionState{CapturedVars: map[string][]string{}, Mode: ModeShell, HistoryFile: filepath.Join(dir, "history")}
+	m := model{session: session, state: state, spawner: NewProcessSpawner(state), builtins: NewBuiltinHandler(state)}
+
+	m.executeBlock("trap 'echo cleaned up' INT")
+	output, _ := m.executeBlock("echo working")
+	if strings.Contains(output, "cleaned up") {
+		t.Errorf("Expected the INT trap to run only after Ctrl-C, got %q", output)
+	}
+
+	state.Traps().Interrupt()
+	output, _ = m.executeBlock("echo working")
+	if !strings.Contains(output, "working\ncleaned up\n") {
+		t.Errorf("Expected the INT trap to run after the interrupted block, got %q", output)
+	}
+}
+
+func TestSetupSignals(t *testing.T) {
+	dir := t.TempDir()
+	state := &ShellState{WorkingDirectory: dir, Environment: map[string]string{"PATH": os.Getenv("PATH")}}
+	b := NewBuiltinHandler(state)
+	b.Execute("trap", []string{"touch " + filepath.Join(dir, "hup"), "HUP"})
+
+	quit := make(chan int, 1)
+	setupSignals(b, func(exitCode int) { quit <- exitCode })
+	syscall.Kill(os.Getpid(), syscall.SIGHUP)
+
+	select {
+	case exitCode := <-quit:
+		if exitCode != 129 {
+			t.Errorf("Expected exit code 129 for SIGHUP, got %d", exitCode)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected SIGHUP to end gosh")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "hup")); err != nil {
+		t.Errorf("Expected the HUP trap to run first: %v", err)
+	}
+}
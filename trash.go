@@ -0,0 +1,400 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// How many removals undelete remembers
+const trashLogLimit = 200
+
+// trashEntry records one file moved to the trash by the safe rm
+type trashEntry struct {
+	Original  string    `json:"original"`
+	TrashPath string    `json:"trash_path"`
+	InfoPath  string    `json:"info_path,omitempty"` // Linux .trashinfo file
+	Deleted   time.Time `json:"deleted"`
+}
+
+// safeDeleteEnabled reports whether rm should move files to the trash.
+// It's opt-in: set GOSH_SAFE_RM=1.
+func (b *BuiltinHandler) safeDeleteEnabled() bool {
+	if b.state == nil {
+		return false
+	}
+	switch strings.ToLower(b.state.Environment["GOSH_SAFE_RM"]) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// trashDirs returns the directory trashed files go to and, on Linux, the
+// directory for the freedesktop.org .trashinfo records
+func trashDirs(env map[string]string) (files, info string) {
+	home := env["HOME"]
+	if runtime.GOOS == "darwin" {
+		return filepath.Join(home, ".Trash"), ""
+	}
+
+	dataHome := env["XDG_DATA_HOME"]
+	if dataHome == "" {
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	trash := filepath.Join(dataHome, "Trash")
+	return filepath.Join(trash, "files"), filepath.Join(trash, "info")
+}
+
+// uniqueTrashName picks a name in dir that isn't taken, adding .2, .3, ...
+func uniqueTrashName(dir, name string) string {
+	candidate := name
+	for n := 2; ; n++ {
+		if _, err := os.Lstat(filepath.Join(dir, candidate)); os.IsNotExist(err) {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s.%d", name, n)
+	}
+}
+
+// moveToTrash moves path into the trash and returns the log entry for it
+func moveToTrash(env map[string]string, path string) (trashEntry, error) {
+	filesDir, infoDir := trashDirs(env)
+	if err := os.MkdirAll(filesDir, 0700); err != nil {
+		return trashEntry{}, err
+	}
+
+	name := uniqueTrashName(filesDir, filepath.Base(path))
+	entry := trashEntry{
+		Original:  path,
+		TrashPath: filepath.Join(filesDir, name),
+		Deleted:   time.Now(),
+	}
+
+	// The .trashinfo lets desktop file managers restore the file too
+	if infoDir != "" {
+		if err := os.MkdirAll(infoDir, 0700); err != nil {
+			return trashEntry{}, err
+		}
+		entry.InfoPath = filepath.Join(infoDir, name+".trashinfo")
+		info := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+			(&url.URL{Path: path}).EscapedPath(), entry.Deleted.Format("2006-01-02T15:04:05"))
+		if err := os.WriteFile(entry.InfoPath, []byte(info), 0600); err != nil {
+			return trashEntry{}, err
+		}
+	}
+
+	if err := movePath(path, entry.TrashPath); err != nil {
+		if entry.InfoPath != "" {
+			os.Remove(entry.InfoPath)
+		}
+		return trashEntry{}, err
+	}
+	return entry, nil
+}
+
+// movePath renames src to dst, copying across filesystems when needed
+func movePath(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	if err := copyTree(src, dst); err != nil {
+		os.RemoveAll(dst)
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// copyTree copies a file, symlink or directory tree, keeping permissions
+func copyTree(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, path)
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		default:
+			in, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer in.Close()
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(out, in); err != nil {
+				out.Close()
+				return err
+			}
+			return out.Close()
+		}
+	})
+}
+
+// loadTrashLog returns the recorded removals, newest last, dropping any
+// whose file has since been emptied from the trash
+func loadTrashLog() []trashEntry {
+	data, err := os.ReadFile(goshConfigPath("trash.json"))
+	if err != nil {
+		return nil
+	}
+	var entries []trashEntry
+	json.Unmarshal(data, &entries)
+
+	kept := entries[:0]
+	for _, entry := range entries {
+		if _, err := os.Lstat(entry.TrashPath); err == nil {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+func saveTrashLog(entries []trashEntry) error {
+	if len(entries) > trashLogLimit {
+		entries = entries[len(entries)-trashLogLimit:]
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := goshConfigPath("trash.json")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// realRm runs the system rm, for `rm --really` and when safe delete is off,
+// as any other command runs. A command line's rm --really doesn't get here:
+// Router.Classify leaves it to the spawner, so its redirections apply.
+func (b *BuiltinHandler) realRm(args []string) ExecutionResult {
+	name := "rm"
+	if _, found := FindInPath(name, b.state.Environment["PATH"]); !found {
+		name = "/bin/rm"
+	}
+	spawner := NewProcessSpawner(b.state)
+	if b.evaluator != nil {
+		spawner.live = b.evaluator.live
+	}
+	return spawner.Run(ShellCommand{Name: name, Args: args})
+}
+
+// withoutReally returns rm's arguments without --really, and whether it was
+// there. Arguments after -- are file names, and kept.
+func withoutReally(args []string) ([]string, bool) {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if arg == "--really" {
+			return append(append([]string{}, args[:i]...), args[i+1:]...), true
+		}
+	}
+	return args, false
+}
+
+// rm implements the safe-delete rm: files go to the trash instead of being
+// unlinked. `rm --really ...` runs the system rm.
+func (b *BuiltinHandler) rm(args []string) ExecutionResult {
+	if !b.safeDeleteEnabled() {
+		return b.realRm(args)
+	}
+	if args, really := withoutReally(args); really {
+		return b.realRm(args)
+	}
+
+	recursive, emptyDirs, force, verbose := false, false, false, false
+	var paths []string
+	for i, arg := range args {
+		if arg == "--" {
+			paths = append(paths, args[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			paths = append(paths, arg)
+			continue
+		}
+
+		var flags string
+		switch arg {
+		case "--recursive":
+			flags = "r"
+		case "--dir":
+			flags = "d"
+		case "--force":
+			flags = "f"
+		case "--verbose":
+			flags = "v"
+		default:
+			if strings.HasPrefix(arg, "--") {
+				flags = "?"
+			} else {
+				flags = arg[1:]
+			}
+		}
+		for _, flag := range flags {
+			switch flag {
+			case 'r', 'R':
+				recursive = true
+			case 'f':
+				force = true
+			case 'v':
+				verbose = true
+			case 'd':
+				emptyDirs = true
+			default:
+				// -i and -I too: only the system rm asks before removing
+				err := fmt.Errorf("unsupported option %s in safe-delete mode", arg)
+				return ExecutionResult{Output: fmt.Sprintf("rm: %v (use rm --really %s)", err, strings.Join(args, " ")), ExitCode: 1, Error: err}
+			}
+		}
+	}
+
+	if len(paths) == 0 {
+		if force {
+			return ExecutionResult{Output: "", ExitCode: 0}
+		}
+		return ExecutionResult{Output: "rm: missing operand", ExitCode: 1, Error: fmt.Errorf("missing operand")}
+	}
+
+	log := loadTrashLog()
+	var messages []string
+	exitCode := 0
+	var lastErr error
+
+	for _, arg := range paths {
+		path := b.state.ExpandPath(arg)
+		info, err := os.Lstat(path)
+		if err != nil {
+			if !(force && os.IsNotExist(err)) {
+				messages = append(messages, fmt.Sprintf("rm: %s: No such file or directory", arg))
+				exitCode, lastErr = 1, err
+			}
+			continue
+		}
+		if info.IsDir() && !recursive && !(emptyDirs && isEmptyDir(path)) {
+			messages = append(messages, fmt.Sprintf("rm: %s: is a directory", arg))
+			exitCode, lastErr = 1, fmt.Errorf("%s: is a directory", arg)
+			continue
+		}
+		if path == "/" || path == b.state.Environment["HOME"] {
+			messages = append(messages, fmt.Sprintf("rm: refusing to remove %s", arg))
+			exitCode, lastErr = 1, fmt.Errorf("refusing to remove %s", arg)
+			continue
+		}
+
+		entry, err := moveToTrash(b.state.Environment, path)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("rm: %s: %v", arg, err))
+			exitCode, lastErr = 1, err
+			continue
+		}
+		log = append(log, entry)
+		if verbose {
+			messages = append(messages, fmt.Sprintf("%s -> trash", arg))
+		}
+	}
+
+	if err := saveTrashLog(log); err != nil {
+		messages = append(messages, fmt.Sprintf("rm: couldn't update the undelete log: %v", err))
+	}
+
+	return ExecutionResult{Output: strings.Join(messages, "\n"), ExitCode: exitCode, Error: lastErr}
+}
+
+// isEmptyDir reports whether the directory at path has no entries
+func isEmptyDir(path string) bool {
+	entries, err := os.ReadDir(path)
+	return err == nil && len(entries) == 0
+}
+
+// undelete implements the undelete builtin
+func (b *BuiltinHandler) undelete(args []string) ExecutionResult {
+	log := loadTrashLog()
+
+	if len(args) == 0 {
+		if len(log) == 0 {
+			return ExecutionResult{Output: "Nothing to undelete", ExitCode: 0}
+		}
+		var sb strings.Builder
+		// Newest first, numbered so `undelete N` can pick one
+		for i := len(log) - 1; i >= 0; i-- {
+			n := len(log) - i
+			sb.WriteString(fmt.Sprintf("%3d  %s  %s\n", n, log[i].Deleted.Format("2006-01-02 15:04"), log[i].Original))
+			if n == 20 && i > 0 {
+				sb.WriteString(fmt.Sprintf("     ... %d older\n", i))
+				break
+			}
+		}
+		return ExecutionResult{Output: strings.TrimRight(sb.String(), "\n"), ExitCode: 0}
+	}
+
+	// Find the entry by number or by original path (newest match wins)
+	index := -1
+	if n, err := strconv.Atoi(args[0]); err == nil {
+		if n >= 1 && n <= len(log) {
+			index = len(log) - n
+		}
+	} else {
+		target := b.state.ExpandPath(args[0])
+		for i := len(log) - 1; i >= 0; i-- {
+			if log[i].Original == target {
+				index = i
+				break
+			}
+		}
+	}
+	if index == -1 {
+		err := fmt.Errorf("no removal matching %s", args[0])
+		return ExecutionResult{Output: fmt.Sprintf("undelete: %v", err), ExitCode: 1, Error: err}
+	}
+
+	entry := log[index]
+	if _, err := os.Lstat(entry.Original); err == nil {
+		err := fmt.Errorf("%s already exists", entry.Original)
+		return ExecutionResult{Output: fmt.Sprintf("undelete: %v", err), ExitCode: 1, Error: err}
+	}
+	if err := os.MkdirAll(filepath.Dir(entry.Original), 0755); err != nil {
+		return ExecutionResult{Output: fmt.Sprintf("undelete: %v", err), ExitCode: 1, Error: err}
+	}
+	if err := movePath(entry.TrashPath, entry.Original); err != nil {
+		return ExecutionResult{Output: fmt.Sprintf("undelete: %v", err), ExitCode: 1, Error: err}
+	}
+	if entry.InfoPath != "" {
+		os.Remove(entry.InfoPath)
+	}
+
+	saveTrashLog(append(log[:index], log[index+1:]...))
+	return ExecutionResult{Output: fmt.Sprintf("Restored %s", entry.Original), ExitCode: 0}
+}
+
+const undeleteHelpText = "undelete - Restore Files Removed by rm\n\n" +
+	"USAGE:\n" +
+	"    undelete            List recent removals, newest first\n" +
+	"    undelete N          Restore removal number N\n" +
+	"    undelete PATH       Restore the most recent removal of PATH\n\n" +
+	"DESCRIPTION:\n" +
+	"    With GOSH_SAFE_RM=1, rm moves files to the trash (~/.Trash on macOS,\n" +
+	"    ~/.local/share/Trash on Linux) instead of deleting them, and undelete\n" +
+	"    puts them back. Use rm --really to bypass the trash."
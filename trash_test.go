@@ -0,0 +1,156 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func newSafeRmHandler(t *testing.T) (*BuiltinHandler, string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_DATA_HOME", "")
+
+	work := filepath.Join(home, "work")
+	os.MkdirAll(work, 0755)
+	state := &ShellState{
+		WorkingDirectory: work,
+		Environment:      map[string]string{"HOME": home, "GOSH_SAFE_RM": "1", "PATH": os.Getenv("PATH")},
+	}
+	return NewBuiltinHandler(state), work
+}
+
+func TestSafeRm_OptIn(t *testing.T) {
+	handler, _ := newSafeRmHandler(t)
+	if !handler.IsBuiltin("rm") {
+		t.Error("Expected rm to be a builtin with GOSH_SAFE_RM=1")
+	}
+
+	handler.state.Environment["GOSH_SAFE_RM"] = ""
+	if handler.IsBuiltin("rm") {
+		t.Error("Expected rm to be left to the system without GOSH_SAFE_RM")
+	}
+	if NewBuiltinHandler(nil).IsBuiltin("rm") {
+		t.Error("Expected rm to be left to the system without a shell state")
+	}
+}
+
+func TestSafeRm_TrashAndUndelete(t *testing.T) {
+	handler, work := newSafeRmHandler(t)
+	file := filepath.Join(work, "notes.txt")
+	os.WriteFile(file, []byte("keep me"), 0644)
+
+	if result := handler.Execute("rm", []string{"notes.txt"}); result.ExitCode != 0 {
+		t.Fatalf("rm failed: %s", result.Output)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Fatal("Expected the file to be gone")
+	}
+
+	filesDir, infoDir := trashDirs(handler.state.Environment)
+	if _, err := os.Stat(filepath.Join(filesDir, "notes.txt")); err != nil {
+		t.Errorf("Expected the file in the trash: %v", err)
+	}
+	if runtime.GOOS == "linux" {
+		info, err := os.ReadFile(filepath.Join(infoDir, "notes.txt.trashinfo"))
+		if err != nil || !strings.Contains(string(info), "Path="+file) {
+			t.Errorf("Expected a .trashinfo record, got %q, %v", info, err)
+		}
+	}
+
+	list := handler.Execute("undelete", nil).Output
+	if !strings.Contains(list, file) {
+		t.Errorf("Expected undelete to list %s, got %q", file, list)
+	}
+
+	if result := handler.Execute("undelete", []string{"1"}); result.ExitCode != 0 {
+		t.Fatalf("undelete failed: %s", result.Output)
+	}
+	if data, _ := os.ReadFile(file); string(data) != "keep me" {
+		t.Errorf("Expected the file to be restored, got %q", data)
+	}
+	if result := handler.Execute("undelete", nil); result.Output != "Nothing to undelete" {
+		t.Errorf("Expected the log to be empty, got %q", result.Output)
+	}
+}
+
+func TestSafeRm_Flags(t *testing.T) {
+	handler, work := newSafeRmHandler(t)
+	os.MkdirAll(filepath.Join(work, "build", "out"), 0755)
+
+	if result := handler.Execute("rm", []string{"build"}); result.ExitCode != 1 || !strings.Contains(result.Output, "is a directory") {
+		t.Errorf("Expected rm without -r to refuse a directory, got %q", result.Output)
+	}
+	if result := handler.Execute("rm", []string{"-rf", "build", "missing"}); result.ExitCode != 0 {
+		t.Errorf("Expected rm -rf to succeed and ignore missing files, got %q", result.Output)
+	}
+	if _, err := os.Stat(filepath.Join(work, "build")); !os.IsNotExist(err) {
+		t.Error("Expected build to be trashed")
+	}
+
+	// -d removes empty directories only
+	os.Mkdir(filepath.Join(work, "empty"), 0755)
+	os.MkdirAll(filepath.Join(work, "full", "sub"), 0755)
+	if result := handler.Execute("rm", []string{"-d", "empty"}); result.ExitCode != 0 {
+		t.Errorf("Expected rm -d to trash an empty directory, got %q", result.Output)
+	}
+	if result := handler.Execute("rm", []string{"--dir", "full"}); result.ExitCode != 1 || !strings.Contains(result.Output, "is a directory") {
+		t.Errorf("Expected rm --dir to refuse a directory with entries, got %q", result.Output)
+	}
+
+	// Options safe delete doesn't implement, prompting included, are refused
+	for _, flag := range []string{"-x", "-i", "-I", "-ri"} {
+		if result := handler.Execute("rm", []string{flag, "file"}); result.ExitCode != 1 || !strings.Contains(result.Output, "--really") {
+			t.Errorf("Expected rm %s to suggest --really, got %q", flag, result.Output)
+		}
+	}
+}
+
+func TestSafeRm_Really(t *testing.T) {
+	handler, work := newSafeRmHandler(t)
+	file := filepath.Join(work, "big.iso")
+	os.WriteFile(file, []byte("data"), 0644)
+
+	if result := handler.Execute("rm", []string{"--really", "big.iso"}); result.ExitCode != 0 {
+		t.Fatalf("rm --really failed: %s", result.Output)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Error("Expected the file to be deleted")
+	}
+	if len(loadTrashLog()) != 0 {
+		t.Error("Expected rm --really to bypass the trash")
+	}
+	if result := handler.Execute("rm", []string{"--really", "big.iso"}); result.ExitCode != 1 {
+		t.Errorf("Expected the system rm's exit code for a missing file, got %d", result.ExitCode)
+	}
+
+	// On a command line, the system rm gets the redirections
+	os.WriteFile(file, []byte("data"), 0644)
+	if result := handler.Execute("eval", []string{"rm --really -v big.iso > removed.log"}); result.ExitCode != 0 || result.Output != "" {
+		t.Errorf("rm --really -v > removed.log = %q (exit %d)", result.Output, result.ExitCode)
+	}
+	if data, _ := os.ReadFile(filepath.Join(work, "removed.log")); !strings.Contains(string(data), "big.iso") {
+		t.Errorf("Expected rm -v's output in the log, got %q", data)
+	}
+	if result := handler.Execute("eval", []string{"rm --really big.iso 2>/dev/null"}); result.ExitCode != 1 || result.Output != "" {
+		t.Errorf("rm --really 2>/dev/null = %q (exit %d), want no output", result.Output, result.ExitCode)
+	}
+}
+
+func TestUniqueTrashName(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.txt"), nil, 0644)
+	os.WriteFile(filepath.Join(dir, "a.txt.2"), nil, 0644)
+
+	if got := uniqueTrashName(dir, "a.txt"); got != "a.txt.3" {
+		t.Errorf("Expected a.txt.3, got %s", got)
+	}
+	if got := uniqueTrashName(dir, "b.txt"); got != "b.txt" {
+		t.Errorf("Expected b.txt, got %s", got)
+	}
+}
@@ -10,10 +10,11 @@ const (
 )
 
 type HistoryBlock struct {
-	Mode    BlockMode
-	Input   string
-	Output  string
-	Capture string // Variable name if -> was used, empty otherwise
+	Mode     BlockMode
+	Input    string
+	Output   string
+	Capture  string // Variable name if -> was used, empty otherwise
+	ExitCode int
 }
 
 type InputType int
@@ -23,10 +24,14 @@ const (
 	InputTypeCommand
 	InputTypeBuiltin
 	InputTypeModeSwitch
+	InputTypeAutoCd
 )
 
 type ExecutionResult struct {
 	Output   string
 	ExitCode int
 	Error    error
+	// partialLine is set when Output deliberately doesn't end its last
+	// line, as with echo -n, so no newline is added when it's written out
+	partialLine bool
 }
@@ -0,0 +1,378 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+// scrypt parameters for deriving the vault key from the passphrase
+const (
+	vaultScryptN = 1 << 15
+	vaultScryptR = 8
+	vaultScryptP = 1
+)
+
+// vaultFile is the on-disk format. The whole name→value map is sealed with
+// NaCl secretbox, so neither secret names nor values are stored in plaintext.
+type vaultFile struct {
+	Version int    `json:"version"`
+	KDF     string `json:"kdf"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// Vault is an encrypted local secrets store. The key is derived once per
+// session and kept in memory until Lock is called.
+type Vault struct {
+	path string
+	salt []byte
+	key  *[32]byte
+
+	// prompt asks for hidden input; confirm asks twice when setting up a
+	// new vault
+	prompt func(label string, confirm bool) (string, error)
+}
+
+func NewVault(path string) *Vault {
+	return &Vault{path: path, prompt: promptHidden}
+}
+
+// promptHidden reads a line from the terminal without echoing it
+func promptHidden(label string, confirm bool) (string, error) {
+	if !term.IsTerminal(os.Stdin.Fd()) {
+		return "", fmt.Errorf("vault is locked and there is no terminal to ask for the passphrase (set GOSH_VAULT_PASSPHRASE)")
+	}
+
+	var value string
+	err := withTerminal(func() error {
+		read := func(label string) (string, error) {
+			fmt.Fprint(os.Stderr, label)
+			data, err := term.ReadPassword(os.Stdin.Fd())
+			fmt.Fprintln(os.Stderr)
+			return string(data), err
+		}
+
+		first, err := read(label + ": ")
+		if err != nil {
+			return err
+		}
+		if confirm {
+			second, err := read("Confirm " + strings.ToLower(label) + ": ")
+			if err != nil {
+				return err
+			}
+			if first != second {
+				return fmt.Errorf("entries don't match")
+			}
+		}
+		value = first
+		return nil
+	})
+	return value, err
+}
+
+// passphrase returns GOSH_VAULT_PASSPHRASE or asks for one
+func (v *Vault) passphrase(confirm bool) (string, error) {
+	if pass := goshAPIEnvironment()["GOSH_VAULT_PASSPHRASE"]; pass != "" {
+		return pass, nil
+	}
+	pass, err := v.prompt("Vault passphrase", confirm)
+	if err != nil {
+		return "", err
+	}
+	if pass == "" {
+		return "", fmt.Errorf("empty passphrase")
+	}
+	return pass, nil
+}
+
+func deriveVaultKey(passphrase string, salt []byte) (*[32]byte, error) {
+	derived, err := scrypt.Key([]byte(passphrase), salt, vaultScryptN, vaultScryptR, vaultScryptP, 32)
+	if err != nil {
+		return nil, err
+	}
+	var key [32]byte
+	copy(key[:], derived)
+	return &key, nil
+}
+
+// Exists reports whether a vault file has been created
+func (v *Vault) Exists() bool {
+	_, err := os.Stat(v.path)
+	return err == nil
+}
+
+// Lock forgets the cached key, so the next access asks for the passphrase
+func (v *Vault) Lock() {
+	v.key = nil
+	v.salt = nil
+}
+
+// load decrypts the vault, asking for the passphrase if it isn't unlocked.
+// A missing vault is empty.
+func (v *Vault) load() (map[string]string, error) {
+	data, err := os.ReadFile(v.path)
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file vaultFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: corrupt vault: %w", v.path, err)
+	}
+	if len(file.Nonce) != 24 {
+		return nil, fmt.Errorf("%s: corrupt vault: bad nonce", v.path)
+	}
+
+	key := v.key
+	if key == nil || string(v.salt) != string(file.Salt) {
+		pass, err := v.passphrase(false)
+		if err != nil {
+			return nil, err
+		}
+		if key, err = deriveVaultKey(pass, file.Salt); err != nil {
+			return nil, err
+		}
+	}
+
+	var nonce [24]byte
+	copy(nonce[:], file.Nonce)
+	plain, ok := secretbox.Open(nil, file.Data, &nonce, key)
+	if !ok {
+		return nil, fmt.Errorf("wrong passphrase")
+	}
+
+	secrets := map[string]string{}
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		return nil, fmt.Errorf("%s: corrupt vault: %w", v.path, err)
+	}
+
+	v.key, v.salt = key, file.Salt
+	return secrets, nil
+}
+
+// save encrypts secrets with a fresh nonce and replaces the vault file
+func (v *Vault) save(secrets map[string]string) error {
+	if v.key == nil {
+		// New vault: pick a salt and ask for the passphrase twice
+		pass, err := v.passphrase(true)
+		if err != nil {
+			return err
+		}
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return err
+		}
+		key, err := deriveVaultKey(pass, salt)
+		if err != nil {
+			return err
+		}
+		v.key, v.salt = key, salt
+	}
+
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+	var nonce [24]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(vaultFile{
+		Version: 1,
+		KDF:     "scrypt",
+		Salt:    v.salt,
+		Nonce:   nonce[:],
+		Data:    secretbox.Seal(nil, plain, &nonce, v.key),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(v.path), 0700); err != nil {
+		return err
+	}
+	tmp := v.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, v.path)
+}
+
+// Get returns a secret's value
+func (v *Vault) Get(name string) (string, error) {
+	secrets, err := v.load()
+	if err != nil {
+		return "", err
+	}
+	value, ok := secrets[name]
+	if !ok {
+		return "", fmt.Errorf("no secret named %q", name)
+	}
+	return value, nil
+}
+
+// Set stores a secret, creating the vault if needed
+func (v *Vault) Set(name, value string) error {
+	secrets, err := v.load()
+	if err != nil {
+		return err
+	}
+	secrets[name] = value
+	return v.save(secrets)
+}
+
+// Delete removes a secret
+func (v *Vault) Delete(name string) error {
+	secrets, err := v.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := secrets[name]; !ok {
+		return fmt.Errorf("no secret named %q", name)
+	}
+	delete(secrets, name)
+	return v.save(secrets)
+}
+
+// Names returns the stored secret names, sorted
+func (v *Vault) Names() ([]string, error) {
+	secrets, err := v.load()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// goshSecret implements gosh.Secret
+func goshSecret(name string) (string, error) {
+	state := goshAPIState()
+	if state == nil {
+		return NewVault(goshConfigPath("vault.json")).Get(name)
+	}
+	return state.Vault().Get(name)
+}
+
+// vault implements the vault builtin
+func (b *BuiltinHandler) vault(args []string) ExecutionResult {
+	v := b.state.Vault()
+	fail := func(err error) ExecutionResult {
+		return ExecutionResult{Output: fmt.Sprintf("vault: %v", err), ExitCode: 1, Error: err}
+	}
+	usage := func(text string) ExecutionResult {
+		return ExecutionResult{Output: "vault: usage: " + text, ExitCode: 1, Error: fmt.Errorf("invalid arguments")}
+	}
+
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+
+	switch args[0] {
+	case "list", "ls":
+		names, err := v.Names()
+		if err != nil {
+			return fail(err)
+		}
+		if len(names) == 0 {
+			return ExecutionResult{Output: "Vault is empty (add a secret with: vault set NAME)", ExitCode: 0}
+		}
+		return ExecutionResult{Output: strings.Join(names, "\n"), ExitCode: 0}
+
+	case "get":
+		if len(args) != 2 {
+			return usage("vault get NAME")
+		}
+		value, err := v.Get(args[1])
+		if err != nil {
+			return fail(err)
+		}
+		return ExecutionResult{Output: value, ExitCode: 0}
+
+	case "set":
+		if len(args) < 2 || len(args) > 3 {
+			return usage("vault set NAME [VALUE]")
+		}
+		var value string
+		if len(args) == 3 {
+			value = args[2]
+		} else {
+			// Prompting keeps the value out of the terminal scrollback
+			var err error
+			if value, err = v.prompt("Value for "+args[1], false); err != nil {
+				return fail(err)
+			}
+		}
+		if err := v.Set(args[1], value); err != nil {
+			return fail(err)
+		}
+		return ExecutionResult{Output: fmt.Sprintf("Stored %s", args[1]), ExitCode: 0}
+
+	case "rm", "delete":
+		if len(args) != 2 {
+			return usage("vault rm NAME")
+		}
+		if err := v.Delete(args[1]); err != nil {
+			return fail(err)
+		}
+		return ExecutionResult{Output: fmt.Sprintf("Removed %s", args[1]), ExitCode: 0}
+
+	case "export":
+		if len(args) < 2 || len(args) > 3 {
+			return usage("vault export NAME [VAR]")
+		}
+		value, err := v.Get(args[1])
+		if err != nil {
+			return fail(err)
+		}
+		variable := args[1]
+		if len(args) == 3 {
+			variable = args[2]
+		}
+		b.state.SetEnv(variable, value)
+		return ExecutionResult{Output: fmt.Sprintf("Exported %s as $%s for this session", args[1], variable), ExitCode: 0}
+
+	case "lock":
+		v.Lock()
+		return ExecutionResult{Output: "Vault locked", ExitCode: 0}
+
+	default:
+		return fail(fmt.Errorf("unknown subcommand: %s (try list, get, set, rm, export or lock)", args[0]))
+	}
+}
+
+const vaultHelpText = "vault - Encrypted Secrets Store\n\n" +
+	"USAGE:\n" +
+	"    vault [list]                 List secret names\n" +
+	"    vault get NAME               Print a secret\n" +
+	"    vault set NAME [VALUE]       Store a secret (prompts when VALUE is omitted)\n" +
+	"    vault rm NAME                Delete a secret\n" +
+	"    vault export NAME [VAR]      Set $VAR (default $NAME) for this session\n" +
+	"    vault lock                   Forget the passphrase until next use\n\n" +
+	"DESCRIPTION:\n" +
+	"    Secrets live in ~/.config/gosh/vault.json, encrypted with NaCl\n" +
+	"    secretbox using a key derived from your passphrase (scrypt). The\n" +
+	"    passphrase is asked for once per session, or read from\n" +
+	"    GOSH_VAULT_PASSPHRASE. vault commands are never saved to history.\n\n" +
+	"    In Go code and config.go:\n" +
+	"        token, err := gosh.Secret(\"github\")"
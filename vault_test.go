@@ -0,0 +1,97 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testVault returns a vault whose passphrase prompt answers with pass
+func testVault(t *testing.T, path, pass string) *Vault {
+	t.Helper()
+	v := NewVault(path)
+	v.prompt = func(label string, confirm bool) (string, error) { return pass, nil }
+	return v
+}
+
+func TestVault_SetGetRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.json")
+
+	v := testVault(t, path, "correct horse")
+	if err := v.Set("github", "ghp_supersecret"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "ghp_supersecret") || strings.Contains(string(data), "github") {
+		t.Error("Vault file contains plaintext")
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected vault mode 0600, got %v", info.Mode().Perm())
+	}
+
+	// A fresh session must derive the key again from the passphrase
+	value, err := testVault(t, path, "correct horse").Get("github")
+	if err != nil || value != "ghp_supersecret" {
+		t.Errorf("Get = %q, %v", value, err)
+	}
+
+	if _, err := testVault(t, path, "wrong").Get("github"); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Errorf("Expected a wrong passphrase error, got %v", err)
+	}
+}
+
+func TestVault_LockForgetsKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.json")
+	v := testVault(t, path, "pass")
+	v.Set("a", "1")
+
+	prompts := 0
+	v.prompt = func(label string, confirm bool) (string, error) {
+		prompts++
+		return "pass", nil
+	}
+
+	v.Get("a")
+	if prompts != 0 {
+		t.Errorf("Expected the unlocked vault not to prompt, prompted %d times", prompts)
+	}
+	v.Lock()
+	v.Get("a")
+	if prompts != 1 {
+		t.Errorf("Expected one prompt after locking, got %d", prompts)
+	}
+}
+
+func TestVaultBuiltin(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	state := &ShellState{Environment: map[string]string{}}
+	state.vault = testVault(t, filepath.Join(home, "vault.json"), "pass")
+	builtins := NewBuiltinHandler(state)
+
+	if result := builtins.Execute("vault", []string{"set", "api", "s3cret"}); result.ExitCode != 0 {
+		t.Fatalf("vault set failed: %s", result.Output)
+	}
+	if result := builtins.Execute("vault", nil); result.Output != "api" {
+		t.Errorf("Expected vault list to show api, got %q", result.Output)
+	}
+	if result := builtins.Execute("vault", []string{"get", "api"}); result.Output != "s3cret" {
+		t.Errorf("Expected vault get to print the secret, got %q", result.Output)
+	}
+
+	builtins.Execute("vault", []string{"export", "api", "API_TOKEN"})
+	if state.Environment["API_TOKEN"] != "s3cret" {
+		t.Errorf("Expected vault export to set API_TOKEN, got %q", state.Environment["API_TOKEN"])
+	}
+	os.Unsetenv("API_TOKEN")
+
+	builtins.Execute("vault", []string{"rm", "api"})
+	if result := builtins.Execute("vault", []string{"get", "api"}); result.ExitCode != 1 {
+		t.Error("Expected get of a removed secret to fail")
+	}
+}
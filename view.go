@@ -0,0 +1,230 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/charmbracelet/x/term"
+)
+
+// viewOptions are the flags accepted by the view builtin
+type viewOptions struct {
+	lineNumbers bool
+	paging      bool
+	language    string
+}
+
+// chromaStyleForTheme picks a highlighting style matching the gosh theme
+func chromaStyleForTheme(theme string) string {
+	switch theme {
+	case "light":
+		return "github"
+	case "solarized":
+		return "solarized-dark"
+	default:
+		return "monokai"
+	}
+}
+
+// highlightSource renders source with syntax highlighting and, optionally,
+// line numbers. An empty style disables colors.
+func highlightSource(filename, source, language, style string, lineNumbers bool) (string, error) {
+	var lexer chroma.Lexer
+	if language != "" {
+		lexer = lexers.Get(language)
+	}
+	if lexer == nil {
+		lexer = lexers.Match(filepath.Base(filename))
+	}
+	if lexer == nil {
+		lexer = lexers.Analyse(source)
+	}
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	lexer = chroma.Coalesce(lexer)
+
+	iterator, err := lexer.Tokenise(nil, source)
+	if err != nil {
+		return "", err
+	}
+
+	formatter := formatters.Get("terminal256")
+	chromaStyle := styles.Get(style)
+
+	lines := chroma.SplitTokensIntoLines(iterator.Tokens())
+	// A trailing newline produces an empty last line; don't number it
+	if len(lines) > 0 && strings.TrimSpace(tokensText(lines[len(lines)-1])) == "" && strings.HasSuffix(source, "\n") {
+		lines = lines[:len(lines)-1]
+	}
+
+	digits := len(fmt.Sprint(len(lines)))
+	var out strings.Builder
+	for i, line := range lines {
+		if lineNumbers {
+			number := fmt.Sprintf("%*d │ ", digits, i+1)
+			if style != "" {
+				number = separatorStyle.Render(number)
+			}
+			out.WriteString(number)
+		}
+
+		// Each line is formatted on its own so multi-line tokens (comments,
+		// strings) keep their color after the line number
+		text := strings.TrimRight(tokensText(line), "\n")
+		if style == "" {
+			out.WriteString(text)
+		} else {
+			var buf bytes.Buffer
+			if err := formatter.Format(&buf, chromaStyle, chroma.Literator(trimLineTokens(line)...)); err != nil {
+				return "", err
+			}
+			out.WriteString(buf.String())
+		}
+		out.WriteString("\n")
+	}
+
+	return out.String(), nil
+}
+
+func tokensText(tokens []chroma.Token) string {
+	var sb strings.Builder
+	for _, token := range tokens {
+		sb.WriteString(token.Value)
+	}
+	return sb.String()
+}
+
+// trimLineTokens drops the trailing newline from a line's last token
+func trimLineTokens(tokens []chroma.Token) []chroma.Token {
+	if len(tokens) == 0 {
+		return tokens
+	}
+	trimmed := append([]chroma.Token(nil), tokens...)
+	last := &trimmed[len(trimmed)-1]
+	last.Value = strings.TrimRight(last.Value, "\n")
+	return trimmed
+}
+
+// pageOutput shows text through $PAGER (default less -R) on the terminal
+func pageOutput(env map[string]string, text string) error {
+	pager := env["PAGER"]
+	if pager == "" {
+		pager = "less"
+	}
+	fields := strings.Fields(pager)
+	path, found := FindInPath(fields[0], env["PATH"])
+	if !found {
+		return fmt.Errorf("pager not found: %s", fields[0])
+	}
+
+	args := fields[1:]
+	if filepath.Base(path) == "less" && len(args) == 0 {
+		args = []string{"-R", "-F", "-X"}
+	}
+
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = strings.NewReader(text)
+	return runOnTerminal(cmd)
+}
+
+// view implements the view builtin
+func (b *BuiltinHandler) view(args []string) ExecutionResult {
+	opts := viewOptions{lineNumbers: true, paging: true}
+	var files []string
+
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; arg {
+		case "-n", "--no-numbers":
+			opts.lineNumbers = false
+		case "-P", "--no-pager":
+			opts.paging = false
+		case "-l", "--language":
+			if i+1 >= len(args) {
+				return ExecutionResult{Output: "view: -l needs a language", ExitCode: 1, Error: fmt.Errorf("missing language")}
+			}
+			i++
+			opts.language = args[i]
+		default:
+			files = append(files, arg)
+		}
+	}
+
+	if len(files) == 0 {
+		return ExecutionResult{Output: "view: usage: view [-n] [-P] [-l LANG] FILE...", ExitCode: 1, Error: fmt.Errorf("no file given")}
+	}
+
+	style := b.state.Environment["GOSH_VIEW_STYLE"]
+	colors := GetColorManager()
+	if style == "" {
+		style = chromaStyleForTheme(colors.currentName)
+	}
+	if colors.noColor || colors.currentName == "mono" {
+		style = ""
+	}
+	// Output for a file or another program is plain, and isn't paged
+	toTerminal := !b.redirected && term.IsTerminal(os.Stdout.Fd())
+	if !toTerminal {
+		style, opts.paging = "", false
+	}
+
+	var out strings.Builder
+	for _, file := range files {
+		path := b.state.ExpandPath(file)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return ExecutionResult{Output: fmt.Sprintf("view: %s: %v", file, err), ExitCode: 1, Error: err}
+		}
+		if bytes.IndexByte(data, 0) != -1 {
+			return ExecutionResult{Output: fmt.Sprintf("view: %s: binary file", file), ExitCode: 1, Error: fmt.Errorf("binary file")}
+		}
+
+		if len(files) > 1 {
+			header := "==> " + file + " <=="
+			if toTerminal {
+				header = colors.StyleOutput(header, "info")
+			}
+			out.WriteString(header + "\n")
+		}
+		highlighted, err := highlightSource(path, string(data), opts.language, style, opts.lineNumbers)
+		if err != nil {
+			return ExecutionResult{Output: fmt.Sprintf("view: %s: %v", file, err), ExitCode: 1, Error: err}
+		}
+		out.WriteString(highlighted)
+	}
+
+	text := out.String()
+	height := b.state.TerminalHeight
+	if opts.paging && height > 0 && strings.Count(text, "\n") > height-2 {
+		if err := pageOutput(b.state.Environment, text); err == nil {
+			return ExecutionResult{Output: "", ExitCode: 0}
+		}
+		// Fall through and print it all if the pager can't run
+	}
+
+	return ExecutionResult{Output: strings.TrimRight(text, "\n"), ExitCode: 0}
+}
+
+const viewHelpText = "view - Show Files with Syntax Highlighting\n\n" +
+	"USAGE:\n" +
+	"    view [-n] [-P] [-l LANG] FILE...\n\n" +
+	"DESCRIPTION:\n" +
+	"    Print files with syntax highlighting and line numbers. Output longer\n" +
+	"    than the terminal is shown through $PAGER (default: less -R).\n" +
+	"    Output redirected to a file or piped is neither colored nor paged.\n\n" +
+	"OPTIONS:\n" +
+	"    -n, --no-numbers    Hide line numbers\n" +
+	"    -P, --no-pager      Never page\n" +
+	"    -l, --language      Force the language (go, python, yaml, ...)\n\n" +
+	"    The color style follows the gosh theme; set GOSH_VIEW_STYLE to any\n" +
+	"    chroma style name (e.g. dracula, nord) to override it."
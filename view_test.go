@@ -0,0 +1,98 @@
+//go:build darwin || linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const viewTestSource = "package main\n\n/* a\n   multi-line comment */\nfunc main() {}\n"
+
+func TestHighlightSource_Plain(t *testing.T) {
+	out, err := highlightSource("main.go", viewTestSource, "", "", true)
+	if err != nil {
+		t.Fatalf("highlightSource failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("Expected 5 numbered lines, got %d: %q", len(lines), out)
+	}
+	if lines[0] != "1 │ package main" || lines[4] != "5 │ func main() {}" {
+		t.Errorf("Unexpected numbering: %q", lines)
+	}
+	if strings.Contains(out, "\x1b[") {
+		t.Error("An empty style should produce no escape codes")
+	}
+}
+
+func TestHighlightSource_Colored(t *testing.T) {
+	out, err := highlightSource("main.go", viewTestSource, "", "monokai", false)
+	if err != nil {
+		t.Fatalf("highlightSource failed: %v", err)
+	}
+	if !strings.Contains(out, "\x1b[") {
+		t.Error("Expected ANSI colors in highlighted output")
+	}
+	if got := stripANSI(out); got != viewTestSource {
+		t.Errorf("Highlighting should not change the text:\n%q\n%q", got, viewTestSource)
+	}
+}
+
+func TestViewBuiltin(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte(viewTestSource), 0644)
+	os.WriteFile(filepath.Join(dir, "blob.bin"), []byte{0x7f, 0x00, 0x01}, 0644)
+
+	state := NewShellState()
+	state.WorkingDirectory = dir
+	b := NewBuiltinHandler(state)
+
+	result := b.Execute("view", []string{"-P", "main.go"})
+	if result.ExitCode != 0 || !strings.Contains(stripANSI(result.Output), "func main() {}") {
+		t.Errorf("Unexpected view output: %q", result.Output)
+	}
+
+	if result := b.Execute("view", []string{"blob.bin"}); result.ExitCode == 0 {
+		t.Error("Binary files should be rejected")
+	}
+	if result := b.Execute("view", []string{"missing.go"}); result.ExitCode == 0 {
+		t.Error("Missing files should fail")
+	}
+	if result := b.Execute("view", nil); result.ExitCode == 0 {
+		t.Error("view without a file should fail")
+	}
+}
+
+func TestViewBuiltin_Redirected(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte(viewTestSource), 0644)
+	// A pager that leaves a mark if it's run
+	pager := filepath.Join(dir, "pager.sh")
+	os.WriteFile(pager, []byte("#!/bin/sh\ntouch \"$(dirname \"$0\")/paged\"\n"), 0755)
+
+	state := &ShellState{WorkingDirectory: dir, Environment: map[string]string{"PATH": os.Getenv("PATH"), "PAGER": pager, "GOSH_VIEW_STYLE": "monokai"}}
+	state.TerminalHeight = 3
+	b := NewBuiltinHandler(state)
+	list, err := NewRouter(b, state).ParseCommandList("view main.go > out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := runCommandList(state, list, func(cmd ShellCommand) ExecutionResult {
+		return runBuiltin(b, state, cmd)
+	})
+	if result.ExitCode != 0 {
+		t.Fatalf("view > out failed: %q", result.Output)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "out"))
+	if !strings.Contains(string(data), "5 │ func main() {}") || strings.Contains(string(data), "\x1b[") {
+		t.Errorf("Expected the plain listing in the file, got %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "paged")); err == nil {
+		t.Error("Expected redirected output not to be paged")
+	}
+}
@@ -4,7 +4,10 @@ package main
 
 import (
 	"fmt"
+	"go/constant"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -276,3 +279,45 @@ func (s *SymbolExtractor) GetPackageSymbols(pkgName string) []CompletionItem {
 
 	return s.symbolCache[pkgName]
 }
+
+// SessionVariable is a variable or constant of the interpreter's main
+// package
+type SessionVariable struct {
+	Name  string
+	Type  string
+	Value string
+}
+
+// GetGlobals returns the variables and constants defined in the REPL and
+// config files, sorted by name, with their values formatted on one line
+func (s *SymbolExtractor) GetGlobals() (globals []SessionVariable) {
+	defer func() {
+		if r := recover(); r != nil {
+			// A symbol yaegi can't hand out shouldn't hide the others
+			debugf("Warning: failed to list globals: %v\n", r)
+		}
+	}()
+
+	for name, value := range s.interp.Globals() {
+		if !value.IsValid() || name == "_" {
+			continue
+		}
+		global := SessionVariable{Name: name}
+		if c, ok := value.Interface().(constant.Value); ok {
+			// Constants are kept as their untyped value
+			global.Type = "const"
+			global.Value = c.ExactString()
+		} else {
+			global.Type = value.Type().String()
+			if value.Kind() == reflect.String {
+				global.Value = strconv.Quote(value.String())
+			} else {
+				global.Value = strings.ReplaceAll(formatResult(value), "\n", " ")
+			}
+		}
+		globals = append(globals, global)
+	}
+
+	sort.Slice(globals, func(i, j int) bool { return globals[i].Name < globals[j].Name })
+	return globals
+}